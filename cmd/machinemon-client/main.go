@@ -29,7 +29,11 @@ func main() {
 	insecure := flag.Bool("insecure", false, "allow self-signed TLS certificates")
 	serviceInstall := flag.Bool("service-install", false, "install as a system service (auto-detects init system)")
 	serviceUninstall := flag.Bool("service-uninstall", false, "remove the system service")
+	serviceStatus := flag.Bool("service-status", false, "show system service status")
+	serviceLogs := flag.Bool("service-logs", false, "show recent system service logs")
+	selfTest := flag.Bool("self-test", false, "collect metrics, match processes, and run checks once, print a report, and exit without contacting the server")
 	upgrade := flag.Bool("upgrade", false, "upgrade client from configured server and restart service if installed")
+	update := flag.Bool("update", false, "replace this binary with the server's newer client build after verifying its checksum, then restart the service if running")
 	versionFlag := flag.Bool("version", false, "print version and exit")
 	flag.Parse()
 
@@ -62,9 +66,25 @@ func main() {
 		}
 		os.Exit(0)
 	}
+	if *serviceStatus {
+		if err := service.Status("machinemon-client"); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if *serviceLogs {
+		if err := service.Logs("machinemon-client"); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
 
+	// The daemon can switch to debug logging at runtime (SIGUSR1).
+	logLevel := new(slog.LevelVar)
 	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
+		Level: logLevel,
 	}))
 
 	cfg, err := client.LoadConfig(*configPath)
@@ -84,6 +104,13 @@ func main() {
 		cfg.InsecureSkipTLS = true
 	}
 
+	if *selfTest {
+		if !client.SelfTest(cfg, os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+
 	if *upgrade {
 		if strings.TrimSpace(cfg.ServerURL) == "" {
 			logger.Error("upgrade requires server URL in config or --server flag")
@@ -97,6 +124,18 @@ func main() {
 		return
 	}
 
+	if *update {
+		if strings.TrimSpace(cfg.ServerURL) == "" {
+			logger.Error("update requires server URL in config or --server flag")
+			os.Exit(1)
+		}
+		if err := runUpdate(cfg, logger); err != nil {
+			logger.Error("update failed", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if *setup {
 		updatedCfg, err := wizard.Run(cfg)
 		if err != nil {
@@ -194,7 +233,7 @@ func main() {
 		return
 	}
 
-	client.RunDaemon(cfg, *configPath, logger)
+	client.RunDaemon(cfg, *configPath, logger, logLevel)
 }
 
 func printServiceNextSteps() {
@@ -270,6 +309,38 @@ func runUpgrade(cfg *client.Config, logger *slog.Logger) error {
 	return nil
 }
 
+// runUpdate swaps in the server's client build in place, without the install
+// script, and restarts the service if it was running.
+func runUpdate(cfg *client.Config, logger *slog.Logger) error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locate running binary: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exePath); err == nil {
+		exePath = resolved
+	}
+
+	res, err := client.SelfUpdate(cfg, exePath, logger)
+	if err != nil {
+		return err
+	}
+	if !res.Updated {
+		logger.Info("client is up to date", "version", res.FromVersion, "server_version", res.ToVersion)
+		return nil
+	}
+	logger.Info("client updated", "path", exePath, "from", res.FromVersion, "to", res.ToVersion)
+
+	if running, err := service.IsRunning("machinemon-client"); err != nil {
+		logger.Warn("could not determine service status after update", "err", err)
+	} else if running {
+		if err := service.Restart("machinemon-client"); err != nil {
+			return fmt.Errorf("restart service after update: %w", err)
+		}
+		logger.Info("service restarted", "service", "machinemon-client")
+	}
+	return nil
+}
+
 func installScriptURL(serverURL string) string {
 	base := strings.TrimRight(strings.TrimSpace(serverURL), "/")
 	if strings.HasSuffix(base, "/download") {
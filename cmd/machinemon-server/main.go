@@ -29,7 +29,10 @@ func main() {
 	setup := flag.Bool("setup", false, "run initial setup")
 	serviceInstall := flag.Bool("service-install", false, "install as a system service (auto-detects init system)")
 	serviceUninstall := flag.Bool("service-uninstall", false, "remove the system service")
+	serviceStatus := flag.Bool("service-status", false, "show system service status")
+	serviceLogs := flag.Bool("service-logs", false, "show recent system service logs")
 	versionFlag := flag.Bool("version", false, "print version and exit")
+	checkConfig := flag.Bool("check-config", false, "validate the config file and exit without starting the server")
 	flag.Parse()
 
 	if *versionFlag {
@@ -37,6 +40,10 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *checkConfig {
+		os.Exit(runCheckConfig(*configPath))
+	}
+
 	if *serviceInstall {
 		binPath, _ := os.Executable()
 		cfgAbs, _ := filepath.Abs(*configPath)
@@ -53,9 +60,25 @@ func main() {
 		}
 		os.Exit(0)
 	}
+	if *serviceStatus {
+		if err := service.Status("machinemon-server"); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if *serviceLogs {
+		if err := service.Logs("machinemon-server"); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
 
+	// Admins can switch to debug logging at runtime via /api/v1/admin/loglevel.
+	logLevel := new(slog.LevelVar)
 	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
+		Level: logLevel,
 	}))
 
 	cfg, err := server.LoadServerConfig(*configPath)
@@ -71,25 +94,33 @@ func main() {
 		}
 	}
 
+	storeCfg := store.Config{Driver: cfg.DatabaseDriver, Path: cfg.DatabasePath, DSN: cfg.DatabaseDSN}
+
 	// Ensure database and binaries directories exist
-	dbDir := filepath.Dir(cfg.DatabasePath)
-	if err := os.MkdirAll(dbDir, 0750); err != nil {
-		logger.Error("failed to create database directory", "path", dbDir, "err", err)
-		os.Exit(1)
+	if storeCfg.IsSQLite() {
+		dbDir := filepath.Dir(cfg.DatabasePath)
+		if err := os.MkdirAll(dbDir, 0750); err != nil {
+			logger.Error("failed to create database directory", "path", dbDir, "err", err)
+			os.Exit(1)
+		}
 	}
 	if err := os.MkdirAll(cfg.BinariesDir, 0755); err != nil {
 		logger.Error("failed to create binaries directory", "path", cfg.BinariesDir, "err", err)
 		os.Exit(1)
 	}
 
-	st, err := store.NewSQLiteStore(cfg.DatabasePath)
+	st, err := store.New(storeCfg)
 	if err != nil {
-		logger.Error("failed to open database", "path", cfg.DatabasePath, "err", err)
+		logger.Error("failed to open database", "driver", cfg.DatabaseDriver, "path", cfg.DatabasePath, "err", err)
 		os.Exit(1)
 	}
 	defer st.Close()
 
-	logger.Info("database ready", "path", cfg.DatabasePath)
+	if storeCfg.IsSQLite() {
+		logger.Info("database ready", "driver", store.DriverSQLite, "path", cfg.DatabasePath)
+	} else {
+		logger.Info("database ready", "driver", store.DriverPostgres)
+	}
 
 	// Set up embedded web filesystem
 	webFS, err := fs.Sub(webDistEmbed, "web_dist")
@@ -101,12 +132,14 @@ func main() {
 
 	// Start alert engine
 	alertEngine := alerting.NewEngine(st, logger)
+	srv := server.New(cfg, st, alertEngine, logger)
+	srv.SetLogLevelVar(logLevel)
+	alertEngine.OnAlert(srv.PublishAlert)
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	go alertEngine.Run(ctx)
 
-	srv := server.New(cfg, st, alertEngine, logger)
-
 	logger.Info("MachineMon Server starting",
 		"version", version.Version,
 		"addr", cfg.ListenAddr,
@@ -135,6 +168,29 @@ func main() {
 	}
 }
 
+// runCheckConfig prints every problem found in the config at path and
+// returns the process exit code.
+func runCheckConfig(path string) int {
+	if _, err := os.Stat(path); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	cfg, err := server.LoadServerConfig(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	problems := cfg.Check()
+	if len(problems) == 0 {
+		fmt.Printf("%s: OK\n", path)
+		return 0
+	}
+	for _, p := range problems {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, p)
+	}
+	return 1
+}
+
 func runSetup(cfg *server.Config, configPath string) error {
 	fmt.Println("=== MachineMon Server Setup ===")
 	fmt.Println()
@@ -147,7 +203,7 @@ func runSetup(cfg *server.Config, configPath string) error {
 		if pw == "" {
 			return fmt.Errorf("admin password is required")
 		}
-		hash, err := server.HashPassword(pw)
+		hash, err := server.HashPassword(pw, cfg.PasswordCost())
 		if err != nil {
 			return fmt.Errorf("hash password: %w", err)
 		}
@@ -162,7 +218,7 @@ func runSetup(cfg *server.Config, configPath string) error {
 		if pw == "" {
 			return fmt.Errorf("client password is required")
 		}
-		hash, err := server.HashPassword(pw)
+		hash, err := server.HashPassword(pw, cfg.PasswordCost())
 		if err != nil {
 			return fmt.Errorf("hash password: %w", err)
 		}
@@ -0,0 +1,85 @@
+package alerting
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+// businessSchedule is a parsed models.BusinessHours: a daily window (reusing
+// the quiet hours window type) on a set of weekdays.
+type businessSchedule struct {
+	window *quietHours
+	days   map[time.Weekday]bool
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseBusinessHours validates a client's schedule. It returns nil for a nil
+// schedule. Days default to Monday-Friday.
+func parseBusinessHours(bh *models.BusinessHours) (*businessSchedule, error) {
+	if bh == nil {
+		return nil, nil
+	}
+	startMin, err := parseClockMinutes(strings.TrimSpace(bh.Start))
+	if err != nil {
+		return nil, fmt.Errorf("start: %w", err)
+	}
+	endMin, err := parseClockMinutes(strings.TrimSpace(bh.End))
+	if err != nil {
+		return nil, fmt.Errorf("end: %w", err)
+	}
+	if startMin == endMin {
+		return nil, fmt.Errorf("start and end must differ")
+	}
+	loc := time.Local
+	if tz := strings.TrimSpace(bh.Timezone); tz != "" {
+		loc, err = time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("timezone: %w", err)
+		}
+	}
+
+	days := make(map[time.Weekday]bool)
+	for _, d := range bh.Days {
+		key := strings.ToLower(strings.TrimSpace(d))
+		if len(key) > 3 {
+			key = key[:3]
+		}
+		wd, ok := weekdayNames[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown day %q", d)
+		}
+		days[wd] = true
+	}
+	if len(days) == 0 {
+		for wd := time.Monday; wd <= time.Friday; wd++ {
+			days[wd] = true
+		}
+	}
+	return &businessSchedule{
+		window: &quietHours{Start: startMin, End: endMin, Location: loc},
+		days:   days,
+	}, nil
+}
+
+// ValidateBusinessHours reports whether bh is a usable schedule.
+func ValidateBusinessHours(bh *models.BusinessHours) error {
+	_, err := parseBusinessHours(bh)
+	return err
+}
+
+// Contains reports whether t is within business hours. For windows that wrap
+// past midnight, the weekday is taken from the local date at t.
+func (b *businessSchedule) Contains(t time.Time) bool {
+	if b == nil {
+		return true
+	}
+	local := t.In(b.window.Location)
+	return b.days[local.Weekday()] && b.window.Contains(t)
+}
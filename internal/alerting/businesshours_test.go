@@ -0,0 +1,56 @@
+package alerting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestBusinessHoursDefaultsToWeekdays(t *testing.T) {
+	s, err := parseBusinessHours(&models.BusinessHours{Start: "09:00", End: "17:00", Timezone: "UTC"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	// 2026-01-05 is a Monday.
+	if !s.Contains(time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected Monday 10:00 to be within business hours")
+	}
+	if s.Contains(time.Date(2026, 1, 5, 20, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected Monday 20:00 to be outside business hours")
+	}
+	if s.Contains(time.Date(2026, 1, 10, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected Saturday to be outside business hours")
+	}
+}
+
+func TestBusinessHoursCustomDaysAndTimezone(t *testing.T) {
+	s, err := parseBusinessHours(&models.BusinessHours{
+		Start: "08:00", End: "12:00", Timezone: "America/New_York", Days: []string{"Saturday"},
+	})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	// Saturday 14:00 UTC is 09:00 in New York (EST).
+	if !s.Contains(time.Date(2026, 1, 10, 14, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected Saturday 09:00 New York to be within business hours")
+	}
+	if s.Contains(time.Date(2026, 1, 5, 14, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected Monday to be outside a Saturday-only schedule")
+	}
+}
+
+func TestBusinessHoursRejectsInvalid(t *testing.T) {
+	cases := []models.BusinessHours{
+		{Start: "9am", End: "17:00"},
+		{Start: "09:00", End: "09:00"},
+		{Start: "09:00", End: "17:00", Timezone: "Nowhere/Special"},
+		{Start: "09:00", End: "17:00", Days: []string{"funday"}},
+	}
+	for _, bh := range cases {
+		bh := bh
+		if err := ValidateBusinessHours(&bh); err == nil {
+			t.Fatalf("expected error for %+v", bh)
+		}
+	}
+}
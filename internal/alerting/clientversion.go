@@ -0,0 +1,51 @@
+package alerting
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/machinemon/machinemon/internal/models"
+	"github.com/machinemon/machinemon/internal/store"
+	"github.com/machinemon/machinemon/internal/version"
+)
+
+// MinClientVersion returns the min_client_version setting; empty disables
+// outdated-version tracking.
+func MinClientVersion(st store.Store) string {
+	raw, _ := st.GetSetting("min_client_version")
+	return strings.TrimSpace(raw)
+}
+
+// ClientOutdated reports whether clientVersion is older than minVersion.
+// Versions that can't be compared (e.g. "dev" builds) are never outdated.
+func ClientOutdated(clientVersion, minVersion string) bool {
+	if minVersion == "" {
+		return false
+	}
+	cmp, ok := version.Compare(clientVersion, minVersion)
+	return ok && cmp < 0
+}
+
+// checkClientVersion fires client_outdated once when a client reports a
+// version older than min_client_version, and client_updated once it reports
+// a current one again.
+func (e *Engine) checkClientVersion(client *models.Client, hostname, minVersion string) {
+	last, _ := e.store.GetLastAlertByTypes(client.ID, models.AlertTypeClientOutdated, models.AlertTypeClientUpdated)
+	flagged := last != nil && last.AlertType == models.AlertTypeClientOutdated
+	outdated := ClientOutdated(client.ClientVersion, minVersion)
+	switch {
+	case outdated && !flagged:
+		e.fireAlert(client.ID, models.AlertTypeClientOutdated, models.SeverityWarning,
+			fmt.Sprintf("Client '%s' is running %s, older than the minimum %s", hostname, client.ClientVersion, minVersion))
+	case !outdated && flagged:
+		e.fireAlert(client.ID, models.AlertTypeClientUpdated, models.SeverityInfo,
+			fmt.Sprintf("Client '%s' is now running %s", hostname, displayVersion(client.ClientVersion)))
+	}
+}
+
+func displayVersion(v string) string {
+	if v == "" {
+		return "an unknown version"
+	}
+	return v
+}
@@ -0,0 +1,229 @@
+package alerting
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+	"github.com/machinemon/machinemon/internal/store"
+)
+
+// maxDigestClients caps how many clients are listed in one digest so SMS and
+// push notifications stay readable.
+const maxDigestClients = 20
+
+// parseDigestInterval parses the alert_digest_interval setting. "hourly" and
+// "daily" are accepted along with Go durations of at least a minute. An empty
+// value, "0", or "off" disables the digest and returns 0.
+func parseDigestInterval(raw string) (time.Duration, error) {
+	switch v := strings.ToLower(strings.TrimSpace(raw)); v {
+	case "", "0", "off":
+		return 0, nil
+	case "hourly":
+		return time.Hour, nil
+	case "daily":
+		return 24 * time.Hour, nil
+	default:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, fmt.Errorf("alert_digest_interval: %q is not hourly, daily, or a duration", raw)
+		}
+		if d < time.Minute {
+			return 0, fmt.Errorf("alert_digest_interval: %s is shorter than 1m", d)
+		}
+		return d, nil
+	}
+}
+
+// digestInterval returns the configured digest interval, or 0 when alerts are
+// dispatched individually.
+func digestInterval(st store.Store) (time.Duration, error) {
+	raw, _ := st.GetSetting("alert_digest_interval")
+	return parseDigestInterval(raw)
+}
+
+// pendingDigestAlerts returns the non-critical alerts fired after since that
+// have not been delivered yet, oldest first.
+func pendingDigestAlerts(alerts []models.Alert, since time.Time) []models.Alert {
+	var out []models.Alert
+	for _, a := range alerts {
+		if a.Notified || a.Severity == models.SeverityCritical || !a.FiredAt.After(since) {
+			continue
+		}
+		out = append(out, a)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].FiredAt.Before(out[j].FiredAt) })
+	return out
+}
+
+// buildDigestMessage summarizes alerts per client: how many fired and
+// recovered, and the alert types seen with their counts. labels maps client
+// IDs to display names; unknown clients fall back to their ID.
+func buildDigestMessage(alerts []models.Alert, labels map[string]string, interval time.Duration) string {
+	type clientSummary struct {
+		label     string
+		fired     int
+		recovered int
+		types     []string
+		counts    map[string]int
+	}
+	byClient := make(map[string]*clientSummary)
+	var fired, recovered int
+	for _, a := range alerts {
+		cs := byClient[a.ClientID]
+		if cs == nil {
+			label := labels[a.ClientID]
+			if label == "" {
+				label = a.ClientID
+			}
+			cs = &clientSummary{label: label, counts: make(map[string]int)}
+			byClient[a.ClientID] = cs
+		}
+		// Recoveries (online, *_recover, check_recovered, ...) are info alerts.
+		if a.Severity == models.SeverityInfo {
+			cs.recovered++
+			recovered++
+		} else {
+			cs.fired++
+			fired++
+		}
+		if cs.counts[a.AlertType] == 0 {
+			cs.types = append(cs.types, a.AlertType)
+		}
+		cs.counts[a.AlertType]++
+	}
+
+	summaries := make([]*clientSummary, 0, len(byClient))
+	for _, cs := range byClient {
+		summaries = append(summaries, cs)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return strings.ToLower(summaries[i].label) < strings.ToLower(summaries[j].label)
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Alert digest (last %s): %d fired, %d recovered across %d client(s)",
+		formatDigestInterval(interval), fired, recovered, len(summaries))
+	for i, cs := range summaries {
+		if i == maxDigestClients {
+			fmt.Fprintf(&b, "\n...and %d more client(s)", len(summaries)-maxDigestClients)
+			break
+		}
+		parts := make([]string, len(cs.types))
+		for j, t := range cs.types {
+			parts[j] = t
+			if n := cs.counts[t]; n > 1 {
+				parts[j] = fmt.Sprintf("%s x%d", t, n)
+			}
+		}
+		fmt.Fprintf(&b, "\n%s: %d fired, %d recovered (%s)", cs.label, cs.fired, cs.recovered, strings.Join(parts, ", "))
+	}
+	return b.String()
+}
+
+func formatDigestInterval(d time.Duration) string {
+	switch {
+	case d == 24*time.Hour:
+		return "24h"
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	default:
+		return d.String()
+	}
+}
+
+// sendDigest delivers a summary of the non-critical alerts queued since the
+// last digest once the configured interval has elapsed. Each set of providers
+// gets its own summary of the alerts routed to it. Alerts included in a
+// delivered digest are marked notified; on failure they stay queued for the
+// next tick, and alerts routed to no provider are never marked.
+func (e *Engine) sendDigest(now time.Time) {
+	interval, err := digestInterval(e.store)
+	if err != nil || interval == 0 {
+		return
+	}
+	last := e.digestSentAt
+	if last.IsZero() {
+		// First digest after startup: wait a full interval, but pick up
+		// anything queued before the restart.
+		if now.Sub(e.startedAt) < interval {
+			return
+		}
+		last = e.startedAt.Add(-interval)
+	} else if now.Sub(last) < interval {
+		return
+	}
+	if e.dispatcher.inQuietHours(now) {
+		return
+	}
+	if e.dryRun() {
+		// Drop the window so alerts seen during the dry run aren't
+		// delivered in the first digest after it ends.
+		e.logger.Info("dry run: digest not sent")
+		e.digestSentAt = now
+		return
+	}
+
+	all, err := e.store.GetUnnotifiedAlerts()
+	if err != nil {
+		e.logger.Error("failed to get unnotified alerts", "err", err)
+		return
+	}
+	pending := pendingDigestAlerts(all, last)
+	if len(pending) == 0 {
+		e.digestSentAt = now
+		return
+	}
+
+	batches, err := e.dispatcher.digestBatches(pending)
+	if err != nil {
+		e.logger.Error("failed to route alert digest", "err", err)
+		return
+	}
+
+	labels := make(map[string]string)
+	for _, a := range pending {
+		if _, ok := labels[a.ClientID]; ok {
+			continue
+		}
+		client, _ := e.store.GetClient(a.ClientID)
+		labels[a.ClientID] = clientLabel(client)
+	}
+
+	failed := false
+	sent := 0
+	for _, b := range batches {
+		digest := &models.Alert{
+			AlertType: models.AlertTypeDigest,
+			Severity:  models.SeverityInfo,
+			Message:   buildDigestMessage(b.alerts, labels, interval),
+			FiredAt:   now,
+		}
+		if errs := e.dispatcher.sendTo(b.providers, digest); len(errs) > 0 {
+			e.logger.Error("failed to send alert digest", "alerts", len(b.alerts), "err", errors.Join(errs...))
+			failed = true
+			continue
+		}
+		for _, a := range b.alerts {
+			if err := e.store.MarkAlertNotified(a.ID); err != nil {
+				e.logger.Error("failed to mark alert notified", "alert_id", a.ID, "err", err)
+			}
+		}
+		sent += len(b.alerts)
+	}
+	if failed {
+		return
+	}
+	e.digestSentAt = now
+	if sent < len(pending) {
+		e.logger.Info("alert digest skipped alerts not routed to any provider", "alerts", len(pending)-sent)
+	}
+	if sent > 0 {
+		e.logger.Info("alert digest sent", "alerts", sent, "digests", len(batches))
+	}
+}
@@ -0,0 +1,142 @@
+package alerting
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestParseDigestInterval(t *testing.T) {
+	cases := []struct {
+		raw     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"0", 0, false},
+		{"off", 0, false},
+		{"hourly", time.Hour, false},
+		{" Daily ", 24 * time.Hour, false},
+		{"30m", 30 * time.Minute, false},
+		{"10s", 0, true},
+		{"weekly", 0, true},
+	}
+	for _, tc := range cases {
+		got, err := parseDigestInterval(tc.raw)
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("parseDigestInterval(%q) err = %v, wantErr %v", tc.raw, err, tc.wantErr)
+		}
+		if got != tc.want {
+			t.Fatalf("parseDigestInterval(%q) = %s, want %s", tc.raw, got, tc.want)
+		}
+	}
+}
+
+func TestPendingDigestAlerts(t *testing.T) {
+	since := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	alerts := []models.Alert{
+		{ID: 1, Severity: models.SeverityWarning, FiredAt: since.Add(2 * time.Minute)},
+		{ID: 2, Severity: models.SeverityCritical, FiredAt: since.Add(time.Minute)},
+		{ID: 3, Severity: models.SeverityInfo, FiredAt: since.Add(time.Minute)},
+		{ID: 4, Severity: models.SeverityWarning, FiredAt: since.Add(-time.Minute)},
+		{ID: 5, Severity: models.SeverityWarning, FiredAt: since.Add(time.Minute), Notified: true},
+	}
+	got := pendingDigestAlerts(alerts, since)
+	if len(got) != 2 || got[0].ID != 3 || got[1].ID != 1 {
+		t.Fatalf("pendingDigestAlerts = %+v, want alerts 3 then 1", got)
+	}
+}
+
+func TestBuildDigestMessage(t *testing.T) {
+	alerts := []models.Alert{
+		{ClientID: "b", AlertType: models.AlertTypeCPUWarn, Severity: models.SeverityWarning},
+		{ClientID: "b", AlertType: models.AlertTypeCPURecover, Severity: models.SeverityInfo},
+		{ClientID: "b", AlertType: models.AlertTypeCPUWarn, Severity: models.SeverityWarning},
+		{ClientID: "a", AlertType: models.AlertTypeOnline, Severity: models.SeverityInfo},
+		{ClientID: "gone", AlertType: models.AlertTypeProcessDied, Severity: models.SeverityWarning},
+	}
+	labels := map[string]string{"a": "alpha", "b": "beta"}
+	got := buildDigestMessage(alerts, labels, time.Hour)
+	want := strings.Join([]string{
+		"Alert digest (last 1h): 3 fired, 2 recovered across 3 client(s)",
+		"alpha: 0 fired, 1 recovered (online)",
+		"beta: 2 fired, 1 recovered (cpu_warn x2, cpu_recover)",
+		"gone: 1 fired, 0 recovered (process_died)",
+	}, "\n")
+	if got != want {
+		t.Fatalf("buildDigestMessage =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSendDigestFollowsRoutes(t *testing.T) {
+	st := newTestStore(t)
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+
+	received := make(map[string][]string)
+	newProvider := func(name string) *models.AlertProvider {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var msg struct {
+				Message string `json:"message"`
+			}
+			json.NewDecoder(r.Body).Decode(&msg)
+			received[name] = append(received[name], msg.Message)
+		}))
+		t.Cleanup(srv.Close)
+		p := &models.AlertProvider{Type: "gotify", Name: name, Enabled: true,
+			Config: `{"server_url":"` + srv.URL + `","app_token":"t"}`}
+		if err := st.CreateProvider(p); err != nil {
+			t.Fatalf("create provider: %v", err)
+		}
+		return p
+	}
+	storage, chat := newProvider("storage"), newProvider("chat")
+	if err := SaveAlertRoutes(st, []models.AlertRoute{
+		{AlertType: "disk_*", ProviderIDs: []int64{storage.ID}},
+		{MinSeverity: models.SeverityWarning, ProviderIDs: []int64{chat.ID}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SetSetting("alert_digest_interval", "hourly"); err != nil {
+		t.Fatal(err)
+	}
+
+	alerts := map[string]*models.Alert{}
+	for _, a := range []models.Alert{
+		{AlertType: models.AlertTypeDiskRecover, Severity: models.SeverityInfo, Message: "disk ok"},
+		{AlertType: models.AlertTypeCPUWarn, Severity: models.SeverityWarning, Message: "cpu high"},
+		{AlertType: models.AlertTypeOnline, Severity: models.SeverityInfo, Message: "back"},
+	} {
+		a.ClientID = res.ClientID
+		if err := st.InsertAlert(&a); err != nil {
+			t.Fatalf("insert alert: %v", err)
+		}
+		alerts[a.AlertType] = &a
+	}
+
+	e := newTestEngine(st)
+	e.startedAt = time.Now().UTC().Add(-2 * time.Hour)
+	e.sendDigest(time.Now().UTC())
+
+	if got := received["storage"]; len(got) != 1 || !strings.Contains(got[0], "disk_recover") || strings.Contains(got[0], "cpu_warn") {
+		t.Errorf("storage provider got %q, want one digest with only the disk alert", got)
+	}
+	if got := received["chat"]; len(got) != 1 || !strings.Contains(got[0], "cpu_warn") || strings.Contains(got[0], "disk_recover") {
+		t.Errorf("chat provider got %q, want one digest with only the cpu alert", got)
+	}
+
+	unnotified, err := st.GetUnnotifiedAlerts()
+	if err != nil {
+		t.Fatalf("unnotified alerts: %v", err)
+	}
+	if len(unnotified) != 1 || unnotified[0].ID != alerts[models.AlertTypeOnline].ID {
+		t.Fatalf("expected only the unrouted alert to stay pending, got %+v", unnotified)
+	}
+}
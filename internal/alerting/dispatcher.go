@@ -5,11 +5,19 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/machinemon/machinemon/internal/models"
 	"github.com/machinemon/machinemon/internal/store"
 )
 
+// errNoProviders is returned by send when no enabled provider is selected for
+// the alert, either because none are configured or because the routing table
+// sends it nowhere. Nothing was delivered.
+var errNoProviders = errors.New("no alert providers selected")
+
 type Dispatcher struct {
 	store  store.Store
 	logger *slog.Logger
@@ -20,39 +28,211 @@ func NewDispatcher(st store.Store, logger *slog.Logger) *Dispatcher {
 }
 
 func (d *Dispatcher) Dispatch(alert *models.Alert) error {
-	providers, err := d.store.GetEnabledProviders()
+	// Critical alerts always go through; everything else waits out quiet hours.
+	// The alert row is already stored, so it still shows up in the dashboard.
+	if alert.Severity != models.SeverityCritical && d.inQuietHours(time.Now()) {
+		d.logger.Info("quiet hours active, suppressing notification",
+			"alert_id", alert.ID, "alert_type", alert.AlertType, "severity", alert.Severity)
+		return nil
+	}
+	// In digest mode non-critical alerts stay unnotified until the engine
+	// sends the next summary.
+	if alert.Severity != models.SeverityCritical {
+		if interval, err := digestInterval(d.store); err != nil {
+			d.logger.Warn("ignoring invalid alert digest setting", "err", err)
+		} else if interval > 0 {
+			d.logger.Debug("alert queued for digest", "alert_id", alert.ID, "alert_type", alert.AlertType)
+			return nil
+		}
+	}
+
+	providers, err := d.providersFor(alert)
 	if err != nil {
-		return fmt.Errorf("get providers: %w", err)
+		return err
 	}
 
 	if len(providers) == 0 {
-		d.logger.Debug("no alert providers configured, skipping dispatch")
+		d.logger.Debug("no alert providers configured for alert, skipping dispatch", "alert_type", alert.AlertType)
 		return nil
 	}
 
+	errs := d.sendTo(providers, alert)
+	if len(errs) == 0 {
+		if err := d.store.MarkAlertNotified(alert.ID); err != nil {
+			d.logger.Error("failed to mark alert notified", "alert_id", alert.ID, "err", err)
+		}
+	} else if err := d.store.RecordAlertNotifyAttempt(alert.ID, time.Now().UTC().Add(retryBackoff(alert.NotifyAttempts+1))); err != nil {
+		d.logger.Error("failed to record notify attempt", "alert_id", alert.ID, "err", err)
+	}
+	return errors.Join(errs...)
+}
+
+// send delivers alert through the providers the routing table selects for it
+// without recording anything on an alert row. It is used for notifications
+// that aren't stored, such as system alerts. When no provider is selected it
+// returns errNoProviders.
+func (d *Dispatcher) send(alert *models.Alert) error {
+	providers, err := d.providersFor(alert)
+	if err != nil {
+		return err
+	}
+	if len(providers) == 0 {
+		return errNoProviders
+	}
+	return errors.Join(d.sendTo(providers, alert)...)
+}
+
+// providersFor returns the enabled providers the routing table sends alert to.
+func (d *Dispatcher) providersFor(alert *models.Alert) ([]models.AlertProvider, error) {
+	providers, routes, err := d.routing()
+	if err != nil {
+		return nil, err
+	}
+	return routeProviders(routes, providers, alert), nil
+}
+
+// routing returns the enabled providers and the routing table. A routing
+// table that can't be parsed is ignored so alerts still go out.
+func (d *Dispatcher) routing() ([]models.AlertProvider, []models.AlertRoute, error) {
+	providers, err := d.store.GetEnabledProviders()
+	if err != nil {
+		return nil, nil, fmt.Errorf("get providers: %w", err)
+	}
+	routes, err := LoadAlertRoutes(d.store)
+	if err != nil {
+		d.logger.Warn("ignoring invalid alert routes, sending to all providers", "err", err)
+		return providers, nil, nil
+	}
+	return providers, routes, nil
+}
+
+// digestBatch is the queued alerts that route to the same set of providers.
+type digestBatch struct {
+	providers []models.AlertProvider
+	alerts    []models.Alert
+}
+
+// digestBatches groups queued alerts by the providers the routing table sends
+// each of them to, so every provider only hears about the alerts routed to
+// it. Alerts that route to no provider are left out and stay unnotified.
+func (d *Dispatcher) digestBatches(alerts []models.Alert) ([]digestBatch, error) {
+	providers, routes, err := d.routing()
+	if err != nil {
+		return nil, err
+	}
+	var batches []digestBatch
+	index := make(map[string]int)
+	for _, a := range alerts {
+		selected := routeProviders(routes, providers, &a)
+		if len(selected) == 0 {
+			continue
+		}
+		ids := make([]string, len(selected))
+		for i, p := range selected {
+			ids[i] = strconv.FormatInt(p.ID, 10)
+		}
+		key := strings.Join(ids, ",")
+		i, ok := index[key]
+		if !ok {
+			i = len(batches)
+			index[key] = i
+			batches = append(batches, digestBatch{providers: selected})
+		}
+		batches[i].alerts = append(batches[i].alerts, a)
+	}
+	return batches, nil
+}
+
+func (d *Dispatcher) sendTo(providers []models.AlertProvider, alert *models.Alert) []error {
+	d.attachClient(alert)
 	var errs []error
+	var data *messageData
 	for _, ap := range providers {
-		provider, err := d.resolveProvider(ap)
+		provider, err := resolveProvider(ap)
 		if err != nil {
 			d.logger.Error("failed to resolve provider", "name", ap.Name, "type", ap.Type, "err", err)
 			errs = append(errs, fmt.Errorf("provider %s: %w", ap.Name, err))
 			continue
 		}
-		if err := provider.Send(alert); err != nil {
+		if data == nil && ap.MessageTemplate != "" {
+			data = newMessageData(alert)
+		}
+		if err := provider.Send(d.applyTemplate(ap, alert, data)); err != nil {
 			d.logger.Error("failed to send alert", "provider", ap.Name, "err", err)
 			errs = append(errs, fmt.Errorf("provider %s: %w", ap.Name, err))
 		} else {
 			d.logger.Info("alert sent", "provider", ap.Name, "alert_type", alert.AlertType)
 		}
 	}
+	return errs
+}
 
-	if len(errs) == 0 {
-		d.store.MarkAlertNotified(alert.ID)
+// attachClient fills in alert.Client from the store. Alerts without a client,
+// or whose client has since been deleted, are sent without it.
+func (d *Dispatcher) attachClient(alert *models.Alert) {
+	if alert.Client != nil || alert.ClientID == "" {
+		return
+	}
+	c, err := d.store.GetClient(alert.ClientID)
+	if err != nil || c == nil {
+		return
+	}
+	alert.Client = &models.AlertClient{
+		Hostname:     c.Hostname,
+		CustomName:   c.CustomName,
+		PublicIP:     c.PublicIP,
+		InterfaceIPs: c.InterfaceIPs,
+		Tags:         c.Tags,
 	}
-	return errors.Join(errs...)
 }
 
-func (d *Dispatcher) resolveProvider(ap models.AlertProvider) (Provider, error) {
+// applyTemplate returns alert with its message rendered through the
+// provider's template, or alert itself when there is no template or it fails.
+func (d *Dispatcher) applyTemplate(ap models.AlertProvider, alert *models.Alert, data *messageData) *models.Alert {
+	if ap.MessageTemplate == "" {
+		return alert
+	}
+	msg, err := renderMessage(ap.MessageTemplate, data)
+	if err != nil {
+		d.logger.Warn("message template failed, sending default message", "provider", ap.Name, "err", err)
+		return alert
+	}
+	if msg == "" {
+		return alert
+	}
+	out := *alert
+	out.Message = msg
+	return &out
+}
+
+// inQuietHours reports whether now falls inside the configured quiet hours window.
+func (d *Dispatcher) inQuietHours(now time.Time) bool {
+	start, _ := d.store.GetSetting("quiet_hours_start")
+	end, _ := d.store.GetSetting("quiet_hours_end")
+	tz, _ := d.store.GetSetting("quiet_hours_tz")
+	q, err := parseQuietHours(start, end, tz)
+	if err != nil {
+		d.logger.Warn("ignoring invalid quiet hours settings", "err", err)
+		return false
+	}
+	return q.Contains(now)
+}
+
+// ValidateProvider parses a stored provider's config and runs the provider's
+// own Validate, so bad settings are rejected when saved instead of at the
+// first alert.
+func ValidateProvider(ap models.AlertProvider) error {
+	provider, err := resolveProvider(ap)
+	if err != nil {
+		return err
+	}
+	if err := provider.Validate(); err != nil {
+		return fmt.Errorf("invalid %s config: %w", ap.Type, err)
+	}
+	return validateMessageTemplate(ap.MessageTemplate)
+}
+
+func resolveProvider(ap models.AlertProvider) (Provider, error) {
 	switch ap.Type {
 	case "twilio":
 		var p TwilioProvider
@@ -72,6 +252,18 @@ func (d *Dispatcher) resolveProvider(ap models.AlertProvider) (Provider, error)
 			return nil, fmt.Errorf("parse smtp config: %w", err)
 		}
 		return &p, nil
+	case "pagerduty":
+		var p PagerDutyProvider
+		if err := json.Unmarshal([]byte(ap.Config), &p); err != nil {
+			return nil, fmt.Errorf("parse pagerduty config: %w", err)
+		}
+		return &p, nil
+	case "gotify":
+		var p GotifyProvider
+		if err := json.Unmarshal([]byte(ap.Config), &p); err != nil {
+			return nil, fmt.Errorf("parse gotify config: %w", err)
+		}
+		return &p, nil
 	default:
 		return nil, fmt.Errorf("unknown provider type: %s", ap.Type)
 	}
@@ -87,7 +279,7 @@ func (d *Dispatcher) SendTestAlert(providerID int64) (*models.TestAlertResult, e
 		return nil, fmt.Errorf("provider not found")
 	}
 
-	provider, err := d.resolveProvider(*ap)
+	provider, err := resolveProvider(*ap)
 	if err != nil {
 		return nil, err
 	}
@@ -99,7 +291,9 @@ func (d *Dispatcher) SendTestAlert(providerID int64) (*models.TestAlertResult, e
 		AlertType: "test",
 		Severity:  models.SeverityInfo,
 		Message:   "This is a test alert from MachineMon.",
+		FiredAt:   time.Now().UTC(),
 	}
+	testAlert = d.applyTemplate(*ap, testAlert, newMessageData(testAlert))
 
 	if p, ok := provider.(*PushoverProvider); ok {
 		sendResult, err := p.send(testAlert)
@@ -0,0 +1,107 @@
+package alerting
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+// defaultCheckInInterval is assumed for clients that don't report theirs.
+const defaultCheckInInterval = 120 * time.Second
+
+type driftState int
+
+const (
+	driftUnknown driftState = iota
+	driftHealthy
+	driftDegraded
+)
+
+// reportingDriftSettings returns how many times the expected interval a gap
+// may reach before it counts as drift, and how many consecutive drifting gaps
+// raise the alert. A factor of 0 disables drift detection.
+func (e *Engine) reportingDriftSettings() (float64, int) {
+	factor := 3.0
+	if raw, _ := e.store.GetSetting("checkin_drift_factor"); raw != "" {
+		if parsed, err := strconv.ParseFloat(strings.TrimSpace(raw), 64); err == nil && parsed >= 0 {
+			factor = parsed
+		}
+	}
+	samples := 3
+	if raw, _ := e.store.GetSetting("checkin_drift_samples"); raw != "" {
+		if parsed, err := strconv.Atoi(strings.TrimSpace(raw)); err == nil && parsed >= 1 {
+			samples = parsed
+		}
+	}
+	return factor, samples
+}
+
+// recordCheckInTime remembers when a client checked in, keeping only the
+// last keep timestamps. History lives in memory and restarts with the server.
+func (e *Engine) recordCheckInTime(clientID string, at time.Time, keep int) {
+	times := e.checkInTimes[clientID]
+	if n := len(times); n > 0 && !at.After(times[n-1]) {
+		return
+	}
+	times = append(times, at)
+	if len(times) > keep {
+		times = times[len(times)-keep:]
+	}
+	e.checkInTimes[clientID] = times
+}
+
+// checkReportingDrift fires a "reporting degraded" alert when a client keeps
+// checking in, but far less often than its interval says it should, and a
+// recovery alert once its cadence is back to normal.
+func (e *Engine) checkReportingDrift(client *models.Client, hostname string, factor float64, samples int) {
+	expected := defaultCheckInInterval
+	if client.ReportedIntervalSeconds != nil && *client.ReportedIntervalSeconds > 0 {
+		expected = time.Duration(*client.ReportedIntervalSeconds) * time.Second
+	}
+	state, typical := reportingDrift(e.checkInTimes[client.ID], expected, factor, samples)
+	if state == driftUnknown {
+		return
+	}
+
+	last, _ := e.store.GetLastAlertByTypes(client.ID, models.AlertTypeReportingDegraded, models.AlertTypeReportingRecovered)
+	degraded := last != nil && last.AlertType == models.AlertTypeReportingDegraded
+	switch {
+	case state == driftDegraded && !degraded:
+		e.fireAlert(client.ID, models.AlertTypeReportingDegraded, models.SeverityWarning,
+			fmt.Sprintf("Client '%s' is checking in only every ~%s instead of every %s (reporting degraded; the daemon may be stuck)",
+				hostname, typical.Round(time.Second), expected))
+	case state == driftHealthy && degraded:
+		e.fireAlert(client.ID, models.AlertTypeReportingRecovered, models.SeverityInfo,
+			fmt.Sprintf("Client '%s' is checking in every %s again", hostname, expected))
+	}
+}
+
+// reportingDrift classifies the gaps between check-in times (oldest first).
+// The latest gap within factor*expected means healthy; the last samples gaps
+// all beyond it means degraded, with their average as the typical gap.
+// Anything in between leaves the current state alone.
+func reportingDrift(times []time.Time, expected time.Duration, factor float64, samples int) (driftState, time.Duration) {
+	if factor <= 0 || len(times) < 2 {
+		return driftUnknown, 0
+	}
+	limit := time.Duration(float64(expected) * factor)
+	n := len(times)
+	if times[n-1].Sub(times[n-2]) <= limit {
+		return driftHealthy, 0
+	}
+	if n < samples+1 {
+		return driftUnknown, 0
+	}
+	var total time.Duration
+	for i := n - samples; i < n; i++ {
+		gap := times[i].Sub(times[i-1])
+		if gap <= limit {
+			return driftUnknown, 0
+		}
+		total += gap
+	}
+	return driftDegraded, total / time.Duration(samples)
+}
@@ -2,8 +2,10 @@ package alerting
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -17,6 +19,19 @@ type Engine struct {
 	dispatcher *Dispatcher
 	logger     *slog.Logger
 	checkInCh  chan string
+	// checkResultCh carries clients with externally pushed check results.
+	checkResultCh chan string
+	startedAt     time.Time
+	// Recent check-in times per client for drift detection; only touched
+	// from the Run loop.
+	checkInTimes map[string][]time.Time
+	// Newest process snapshot time already evaluated per client, so a
+	// check-in that carried no new snapshots doesn't replay transitions.
+	processesSeenAt map[string]time.Time
+	// onAlert, if set, is called after each alert is recorded.
+	onAlert func(models.Alert)
+	// When the last alert digest went out; zero until the first one.
+	digestSentAt time.Time
 }
 
 type scopedMuteState struct {
@@ -27,10 +42,14 @@ type scopedMuteState struct {
 
 func NewEngine(st store.Store, logger *slog.Logger) *Engine {
 	return &Engine{
-		store:      st,
-		dispatcher: NewDispatcher(st, logger),
-		logger:     logger,
-		checkInCh:  make(chan string, 100),
+		store:           st,
+		dispatcher:      NewDispatcher(st, logger),
+		logger:          logger,
+		checkInCh:       make(chan string, 100),
+		checkResultCh:   make(chan string, 100),
+		startedAt:       time.Now().UTC(),
+		checkInTimes:    make(map[string][]time.Time),
+		processesSeenAt: make(map[string]time.Time),
 	}
 }
 
@@ -43,11 +62,55 @@ func (e *Engine) NotifyCheckIn(clientID string) {
 	}
 }
 
+// NotifyCheckResult tells the engine that a check result was pushed for a
+// client from outside its agent. Only check alerts are evaluated: the client
+// itself didn't check in, so online state and metrics are left alone.
+func (e *Engine) NotifyCheckResult(clientID string) {
+	select {
+	case e.checkResultCh <- clientID:
+	default:
+		e.logger.Warn("check result notification channel full, dropping", "client_id", clientID)
+	}
+}
+
+// OnAlert registers fn to be called with every alert after it is recorded,
+// e.g. to push it to live dashboards. Call before Run.
+func (e *Engine) OnAlert(fn func(models.Alert)) {
+	e.onAlert = fn
+}
+
 // SendTestAlert dispatches a test alert through a specific provider.
 func (e *Engine) SendTestAlert(providerID int64) (*models.TestAlertResult, error) {
 	return e.dispatcher.SendTestAlert(providerID)
 }
 
+// NotifySystem sends a notification about the server itself. It isn't tied to
+// a client, so like a digest it goes straight to the providers without being
+// stored. Non-critical notifications wait out quiet hours; the result reports
+// whether the notification went out.
+func (e *Engine) NotifySystem(alertType, severity, message string) bool {
+	now := time.Now().UTC()
+	if severity != models.SeverityCritical && e.dispatcher.inQuietHours(now) {
+		e.logger.Info("quiet hours active, holding system notification", "type", alertType)
+		return false
+	}
+	alert := &models.Alert{AlertType: alertType, Severity: severity, Message: message, FiredAt: now}
+	e.logger.Info("system alert", "type", alertType, "severity", severity, "message", message)
+	if e.dryRun() {
+		// Report it as handled so callers don't retry every check.
+		e.logger.Info("dry run: system notification not sent", "type", alertType)
+		return true
+	}
+	if err := e.dispatcher.send(alert); errors.Is(err, errNoProviders) {
+		e.logger.Info("system alert not routed to any provider", "type", alertType)
+		return false
+	} else if err != nil {
+		e.logger.Error("failed to send system alert", "type", alertType, "err", err)
+		return false
+	}
+	return true
+}
+
 // NotifyRestart fires an alert when a client session_id changes.
 func (e *Engine) NotifyRestart(clientID, hostname string) {
 	client, err := e.store.GetClient(clientID)
@@ -58,14 +121,38 @@ func (e *Engine) NotifyRestart(clientID, hostname string) {
 		fmt.Sprintf("Client '%s' has a new session (session change detected)", hostname))
 }
 
+// NotifyIdentityConflict fires an alert when check-ins for one client_id look
+// like they come from more than one machine. Repeats are throttled to one per
+// hour since a shared ID keeps flipping on every check-in.
+func (e *Engine) NotifyIdentityConflict(clientID, previousHostname, hostname string) {
+	if lastFiredAt, err := e.store.GetLastAlertFiredAt(clientID, models.AlertTypeDuplicateClient, ""); err == nil &&
+		lastFiredAt != nil && time.Since(*lastFiredAt) < time.Hour {
+		return
+	}
+	hosts := fmt.Sprintf("'%s'", hostname)
+	if !strings.EqualFold(previousHostname, hostname) {
+		hosts = fmt.Sprintf("'%s' and '%s'", previousHostname, hostname)
+	}
+	e.fireAlert(clientID, models.AlertTypeDuplicateClient, models.SeverityWarning,
+		fmt.Sprintf("Client ID %s appears to be shared by multiple machines (%s alternating check-ins); give each machine its own client_id",
+			clientID, hosts))
+}
+
 // Run starts the alert engine background loop.
 func (e *Engine) Run(ctx context.Context) {
-	offlineTicker := time.NewTicker(30 * time.Second)
+	offlineInterval := e.offlineCheckInterval()
+	offlineTicker := time.NewTicker(offlineInterval)
+	retryTicker := time.NewTicker(30 * time.Second)
 	cleanupTicker := time.NewTicker(24 * time.Hour)
+	digestTicker := time.NewTicker(time.Minute)
 	defer offlineTicker.Stop()
+	defer retryTicker.Stop()
 	defer cleanupTicker.Stop()
+	defer digestTicker.Stop()
 
-	e.logger.Info("alert engine started")
+	e.startedAt = time.Now().UTC()
+	e.logger.Info("alert engine started", "offline_startup_grace_seconds", e.offlineStartupGraceSeconds(),
+		"offline_check_interval", offlineInterval)
 	// Run cleanup once at startup so stale data is pruned immediately.
 	e.cleanupOldData()
 
@@ -76,10 +163,22 @@ func (e *Engine) Run(ctx context.Context) {
 			return
 		case clientID := <-e.checkInCh:
 			e.evaluateCheckIn(clientID)
+		case clientID := <-e.checkResultCh:
+			e.evaluateCheckResults(clientID)
 		case <-offlineTicker.C:
 			e.checkOfflineClients()
+			// Pick up changes to offline_check_interval_seconds without a restart.
+			if next := e.offlineCheckInterval(); next != offlineInterval {
+				e.logger.Info("offline check interval changed", "from", offlineInterval, "to", next)
+				offlineInterval = next
+				offlineTicker.Reset(offlineInterval)
+			}
+		case <-retryTicker.C:
+			e.retryFailedNotifications()
 		case <-cleanupTicker.C:
 			e.cleanupOldData()
+		case <-digestTicker.C:
+			e.sendDigest(time.Now().UTC())
 		}
 	}
 }
@@ -93,12 +192,19 @@ func (e *Engine) checkOfflineClients() {
 	}
 
 	now := time.Now().UTC()
+	graceUntil := e.startedAt.Add(time.Duration(e.offlineStartupGraceSeconds()) * time.Second)
 	for _, c := range clients {
 		thresholdSecs := globalThresholdSecs
 		if c.OfflineThresholdSeconds != nil && *c.OfflineThresholdSeconds > 0 {
 			thresholdSecs = *c.OfflineThresholdSeconds
 		}
-		if now.Sub(c.LastSeenAt) < time.Duration(thresholdSecs)*time.Second {
+		lastSeen := offlineReferenceTime(c.LastSeenAt, e.startedAt, graceUntil)
+		if now.Sub(lastSeen) < time.Duration(thresholdSecs)*time.Second {
+			continue
+		}
+		// Leave the client marked online during maintenance so it is still
+		// reported offline if it hasn't come back once the window ends.
+		if e.inMaintenance(c.ID, now) {
 			continue
 		}
 
@@ -111,6 +217,10 @@ func (e *Engine) checkOfflineClients() {
 		e.logger.Warn("client went offline", "client_id", c.ID, "hostname", hostLabel,
 			"last_seen", c.LastSeenAt, "threshold_seconds", thresholdSecs)
 		e.store.SetClientOnline(c.ID, false)
+		// Suspended clients still show as offline, but nobody is paged.
+		if c.Suspended {
+			continue
+		}
 		e.fireAlert(c.ID, models.AlertTypeOffline, models.SeverityCritical,
 			fmt.Sprintf("Client '%s' has gone offline (no check-in for %d+ seconds)",
 				hostLabel, thresholdSecs))
@@ -118,13 +228,55 @@ func (e *Engine) checkOfflineClients() {
 }
 
 func (e *Engine) globalOfflineThresholdSeconds() int {
-	thresholdSecs := 240 // default 4 minutes
-	if raw, _ := e.store.GetSetting("offline_threshold_seconds"); raw != "" {
-		if parsed, err := strconv.Atoi(strings.TrimSpace(raw)); err == nil && parsed > 0 {
-			thresholdSecs = parsed
+	return int(ResolveEffectiveThresholds(e.store, nil).OfflineThresholdSeconds.Value)
+}
+
+// Bounds for offline_check_interval_seconds. Very short intervals would query
+// every online client several times a second; very long ones delay alerts
+// well past the offline threshold.
+const (
+	minOfflineCheckInterval = 5 * time.Second
+	maxOfflineCheckInterval = 10 * time.Minute
+)
+
+// offlineCheckInterval returns how often offline clients are looked for.
+func (e *Engine) offlineCheckInterval() time.Duration {
+	raw, _ := e.store.GetSetting("offline_check_interval_seconds")
+	return parseOfflineCheckInterval(raw)
+}
+
+// parseOfflineCheckInterval reads offline_check_interval_seconds, defaulting
+// to 30s and clamping to [minOfflineCheckInterval, maxOfflineCheckInterval].
+func parseOfflineCheckInterval(raw string) time.Duration {
+	secs, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || secs <= 0 {
+		return 30 * time.Second
+	}
+	d := time.Duration(secs) * time.Second
+	return min(max(d, minOfflineCheckInterval), maxOfflineCheckInterval)
+}
+
+// offlineStartupGraceSeconds returns how long after server start clients that
+// have not yet checked in are spared from offline alerting.
+func (e *Engine) offlineStartupGraceSeconds() int {
+	graceSecs := 120 // default: one standard check-in interval
+	if raw, _ := e.store.GetSetting("offline_startup_grace_seconds"); raw != "" {
+		if parsed, err := strconv.Atoi(strings.TrimSpace(raw)); err == nil && parsed >= 0 {
+			graceSecs = parsed
 		}
 	}
-	return thresholdSecs
+	return graceSecs
+}
+
+// offlineReferenceTime returns the timestamp the offline threshold is measured
+// from. Clients that have not checked in since the server started are measured
+// from the end of the startup grace period instead of their stale last_seen_at,
+// so a server restart does not trigger a burst of false offline alerts.
+func offlineReferenceTime(lastSeen, startedAt, graceUntil time.Time) time.Time {
+	if lastSeen.Before(startedAt) {
+		return graceUntil
+	}
+	return lastSeen
 }
 
 func (e *Engine) evaluateCheckIn(clientID string) {
@@ -133,14 +285,11 @@ func (e *Engine) evaluateCheckIn(clientID string) {
 		e.logger.Error("failed to get client for evaluation", "client_id", clientID, "err", err)
 		return
 	}
+	driftFactor, driftSamples := e.reportingDriftSettings()
+	e.recordCheckInTime(clientID, client.LastSeenAt, driftSamples+1)
 
-	// Check mute status
-	if client.AlertsMuted {
-		if client.MutedUntil == nil || client.MutedUntil.After(time.Now()) {
-			return // Still muted
-		}
-		// Mute expired, unmute
-		e.store.SetClientMute(clientID, false, nil, "")
+	if e.alertsSuppressed(client) {
+		return
 	}
 
 	scopedMutes := e.loadScopedMutes(clientID)
@@ -154,6 +303,10 @@ func (e *Engine) evaluateCheckIn(clientID string) {
 		e.fireAlert(clientID, models.AlertTypeOnline, models.SeverityInfo,
 			fmt.Sprintf("Client '%s' is back online", hostLabel))
 	}
+	e.checkReportingDrift(client, hostLabel, driftFactor, driftSamples)
+	if minVersion := MinClientVersion(e.store); minVersion != "" {
+		e.checkClientVersion(client, hostLabel, minVersion)
+	}
 
 	// 2. Threshold checks
 	consecutiveRequired := e.resolveMetricConsecutiveCheckins(client)
@@ -164,83 +317,120 @@ func (e *Engine) evaluateCheckIn(clientID string) {
 	latest := recentMetrics[0]
 
 	thresholds := e.resolveThresholds(client)
+	recoveryMargin := e.metricRecoveryMargin()
 	if !scopedMutes.metrics["cpu"] {
-		e.checkThreshold(clientID, hostLabel, "cpu", latest.CPUPercent, thresholds.CPUWarnPct, thresholds.CPUCritPct, recentMetrics, consecutiveRequired)
+		e.checkThreshold(clientID, hostLabel, "cpu", latest.CPUPercent, thresholds.CPUWarnPct, thresholds.CPUCritPct, recentMetrics, consecutiveRequired, recoveryMargin)
 	}
 	if !scopedMutes.metrics["mem"] {
-		e.checkThreshold(clientID, hostLabel, "mem", latest.MemPercent, thresholds.MemWarnPct, thresholds.MemCritPct, recentMetrics, consecutiveRequired)
+		e.checkThreshold(clientID, hostLabel, "mem", latest.MemPercent, thresholds.MemWarnPct, thresholds.MemCritPct, recentMetrics, consecutiveRequired, recoveryMargin)
+	}
+	// Swap alerts are opt-in and skipped on hosts without swap. They share
+	// the memory mute scope.
+	if warn, crit := e.swapThresholds(); (warn > 0 || crit > 0) && latest.SwapTotalBytes > 0 && !scopedMutes.metrics["mem"] {
+		e.checkThreshold(clientID, hostLabel, "swap", latest.SwapPercent, warn, crit, recentMetrics, consecutiveRequired, recoveryMargin)
+	}
+	// Temperature is only evaluated when the host reports a CPU sensor. It
+	// shares the CPU mute scope.
+	if warn, crit := e.tempThresholds(); latest.CPUTempC != nil && !math.IsInf(warn, 1) && !scopedMutes.metrics["cpu"] {
+		e.checkThreshold(clientID, hostLabel, "temp", *latest.CPUTempC, warn, crit, recentMetrics, consecutiveRequired, recoveryMargin)
+	}
+	// Open file and process count alerts are opt-in and skipped when the host
+	// doesn't report the count. They are absolute counts, so the percentage
+	// recovery margin doesn't apply. Both share the CPU mute scope.
+	if warn, crit := e.countThresholds("open_fds"); warn > 0 && latest.OpenFDs != nil && !scopedMutes.metrics["cpu"] {
+		e.checkThreshold(clientID, hostLabel, "open_fds", float64(*latest.OpenFDs), warn, crit, recentMetrics, consecutiveRequired, 0)
 	}
+	if warn, crit := e.countThresholds("process_count"); warn > 0 && latest.ProcessCount != nil && !scopedMutes.metrics["cpu"] {
+		e.checkThreshold(clientID, hostLabel, "process_count", float64(*latest.ProcessCount), warn, crit, recentMetrics, consecutiveRequired, 0)
+	}
+	if !scopedMutes.metrics["disk"] {
+		e.checkThreshold(clientID, hostLabel, "disk", latest.DiskPercent, thresholds.DiskWarnPct, thresholds.DiskCritPct, recentMetrics, consecutiveRequired, recoveryMargin)
+	}
+	// Inode usage is only evaluated when the host reports it (never on
+	// Windows). It shares the disk mute scope.
+	if warn, crit := e.inodeThresholds(); latest.DiskInodesUsedPercent != nil && !math.IsInf(warn, 1) && !scopedMutes.metrics["disk"] {
+		e.checkThreshold(clientID, hostLabel, "inode", *latest.DiskInodesUsedPercent, warn, crit, recentMetrics, consecutiveRequired, recoveryMargin)
+	}
+
 	if !scopedMutes.metrics["disk"] {
-		e.checkThreshold(clientID, hostLabel, "disk", latest.DiskPercent, thresholds.DiskWarnPct, thresholds.DiskCritPct, recentMetrics, consecutiveRequired)
+		e.checkDiskMounts(clientID, hostLabel, thresholds, recoveryMargin)
+	}
+	// Fill-rate alerts are opt-in and share the disk mute scope.
+	if rate, window := e.diskFillRateSettings(); rate > 0 && !scopedMutes.metrics["disk"] {
+		e.checkDiskFillRate(clientID, hostLabel, rate, window)
 	}
 
 	// 3. Process checks
-	e.checkProcesses(clientID, hostLabel, scopedMutes)
+	e.checkProcesses(clientID, hostLabel, scopedMutes, consecutiveRequired)
 
 	// 4. Check results (script, http, file_touch, ...)
 	e.checkChecks(clientID, hostLabel, scopedMutes)
 }
 
-func (e *Engine) resolveThresholds(client *models.Client) models.Thresholds {
-	t := models.DefaultThresholds
-
-	// Load global settings overrides
-	if v, _ := e.store.GetSetting("cpu_warn_pct_default"); v != "" {
-		fmt.Sscanf(v, "%f", &t.CPUWarnPct)
-	}
-	if v, _ := e.store.GetSetting("cpu_crit_pct_default"); v != "" {
-		fmt.Sscanf(v, "%f", &t.CPUCritPct)
-	}
-	if v, _ := e.store.GetSetting("mem_warn_pct_default"); v != "" {
-		fmt.Sscanf(v, "%f", &t.MemWarnPct)
-	}
-	if v, _ := e.store.GetSetting("mem_crit_pct_default"); v != "" {
-		fmt.Sscanf(v, "%f", &t.MemCritPct)
-	}
-	if v, _ := e.store.GetSetting("disk_warn_pct_default"); v != "" {
-		fmt.Sscanf(v, "%f", &t.DiskWarnPct)
+// evaluateCheckResults re-evaluates only check alerts for a client, after an
+// external check result was stored.
+func (e *Engine) evaluateCheckResults(clientID string) {
+	client, err := e.store.GetClient(clientID)
+	if err != nil || client == nil {
+		e.logger.Error("failed to get client for check evaluation", "client_id", clientID, "err", err)
+		return
 	}
-	if v, _ := e.store.GetSetting("disk_crit_pct_default"); v != "" {
-		fmt.Sscanf(v, "%f", &t.DiskCritPct)
+	if e.alertsSuppressed(client) {
+		return
 	}
+	e.checkChecks(clientID, clientLabel(client), e.loadScopedMutes(clientID))
+}
 
-	// Per-client overrides
-	if client.CPUWarnPct != nil {
-		t.CPUWarnPct = *client.CPUWarnPct
-	}
-	if client.CPUCritPct != nil {
-		t.CPUCritPct = *client.CPUCritPct
-	}
-	if client.MemWarnPct != nil {
-		t.MemWarnPct = *client.MemWarnPct
-	}
-	if client.MemCritPct != nil {
-		t.MemCritPct = *client.MemCritPct
+// alertsSuppressed reports whether the client is suspended, muted, or in a
+// maintenance window. An expired mute is cleared as a side effect.
+func (e *Engine) alertsSuppressed(client *models.Client) bool {
+	if client.Suspended {
+		return true
 	}
-	if client.DiskWarnPct != nil {
-		t.DiskWarnPct = *client.DiskWarnPct
-	}
-	if client.DiskCritPct != nil {
-		t.DiskCritPct = *client.DiskCritPct
+	if client.AlertsMuted {
+		if client.MutedUntil == nil || client.MutedUntil.After(time.Now()) {
+			return true // Still muted
+		}
+		// Mute expired, unmute
+		e.store.SetClientMute(client.ID, false, nil, "")
 	}
+	return e.inMaintenance(client.ID, time.Now())
+}
 
-	return t
+func (e *Engine) resolveThresholds(client *models.Client) models.Thresholds {
+	return ResolveEffectiveThresholds(e.store, client).Thresholds()
 }
 
 func (e *Engine) resolveMetricConsecutiveCheckins(client *models.Client) int {
-	required := 1
-	if raw, _ := e.store.GetSetting("metric_consecutive_checkins_default"); raw != "" {
-		if parsed, err := strconv.Atoi(strings.TrimSpace(raw)); err == nil && parsed > 0 {
-			required = parsed
-		}
+	return int(ResolveEffectiveThresholds(e.store, client).MetricConsecutiveCheckins.Value)
+}
+
+func (e *Engine) resolveAlertCooldownSeconds(client *models.Client) int {
+	return int(ResolveEffectiveThresholds(e.store, client).AlertCooldownSeconds.Value)
+}
+
+// inAlertCooldown reports whether an alert of the same type already fired for
+// the same target (process, check, or mount; "" for client-wide alerts) on
+// this client within the configured cooldown window. This keeps flapping
+// processes and checks from producing an alert storm, critical ones included,
+// without hiding a different process or check failing.
+func (e *Engine) inAlertCooldown(client *models.Client, clientID, alertType, target string) bool {
+	cooldown := e.resolveAlertCooldownSeconds(client)
+	if cooldown <= 0 {
+		return false
 	}
-	if client.MetricConsecutiveCheckins != nil && *client.MetricConsecutiveCheckins > 0 {
-		required = *client.MetricConsecutiveCheckins
+	lastFiredAt, err := e.store.GetLastAlertFiredAt(clientID, alertType, target)
+	if err != nil {
+		e.logger.Error("failed to get last alert time", "client_id", clientID, "type", alertType, "err", err)
+		return false
 	}
-	return required
+	return lastFiredAt != nil && time.Since(*lastFiredAt) < time.Duration(cooldown)*time.Second
 }
 
-func (e *Engine) checkThreshold(clientID, hostname, metric string, value, warnPct, critPct float64, recent []models.Metric, consecutiveRequired int) {
+// checkThreshold fires warn/crit alerts once the value has been over the
+// threshold for consecutiveRequired check-ins, and a recovery once it drops
+// recoveryMargin below the warning threshold.
+func (e *Engine) checkThreshold(clientID, hostname, metric string, value, warnPct, critPct float64, recent []models.Metric, consecutiveRequired int, recoveryMargin float64) {
 	warnType := metric + "_warn"
 	critType := metric + "_crit"
 	recoverType := metric + "_recover"
@@ -248,28 +438,170 @@ func (e *Engine) checkThreshold(clientID, hostname, metric string, value, warnPc
 	lastAlert, _ := e.store.GetLastAlertByTypes(clientID, warnType, critType, recoverType)
 
 	metricLabel := strings.ToUpper(metric)
+	switch metric {
+	case "temp":
+		metricLabel = "CPU temperature"
+	case "open_fds":
+		metricLabel = "Open files"
+	case "process_count":
+		metricLabel = "Process count"
+	case "inode":
+		metricLabel = "Disk inode usage"
+	}
 	critStreak := consecutiveThresholdStreak(recent, metric, critPct)
 	warnStreak := consecutiveThresholdStreak(recent, metric, warnPct)
 
 	if value >= critPct {
 		if critStreak >= consecutiveRequired && (lastAlert == nil || lastAlert.AlertType != critType) {
 			e.fireAlert(clientID, critType, models.SeverityCritical,
-				fmt.Sprintf("%s at %.1f%% on '%s' (critical threshold: %.1f%%)",
-					metricLabel, value, hostname, critPct))
+				fmt.Sprintf("%s at %s on '%s' (critical threshold: %s)",
+					metricLabel, formatMetricValue(metric, value), hostname, formatMetricValue(metric, critPct)))
 		}
 	} else if value >= warnPct {
 		if warnStreak >= consecutiveRequired && (lastAlert == nil || lastAlert.AlertType != warnType) {
 			e.fireAlert(clientID, warnType, models.SeverityWarning,
-				fmt.Sprintf("%s at %.1f%% on '%s' (warning threshold: %.1f%%)",
-					metricLabel, value, hostname, warnPct))
+				fmt.Sprintf("%s at %s on '%s' (warning threshold: %s)",
+					metricLabel, formatMetricValue(metric, value), hostname, formatMetricValue(metric, warnPct)))
 		}
-	} else if lastAlert != nil && (lastAlert.AlertType == critType || lastAlert.AlertType == warnType) {
+	} else if value < warnPct-recoveryMargin && lastAlert != nil && (lastAlert.AlertType == critType || lastAlert.AlertType == warnType) {
 		e.fireAlert(clientID, recoverType, models.SeverityInfo,
-			fmt.Sprintf("%s recovered to %.1f%% on '%s'",
-				metricLabel, value, hostname))
+			fmt.Sprintf("%s recovered to %s on '%s'",
+				metricLabel, formatMetricValue(metric, value), hostname))
+	}
+}
+
+// formatMetricValue renders a threshold metric with its unit: degrees Celsius
+// for temperature, a plain number for counts, percent for everything else.
+func formatMetricValue(metric string, v float64) string {
+	switch metric {
+	case "temp":
+		return fmt.Sprintf("%.1f°C", v)
+	case "open_fds", "process_count":
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.1f%%", v)
+}
+
+// checkDiskMounts alerts on threshold transitions for each extra mount. The
+// active state is stored per mount since alert types alone can't tell mounts apart.
+func (e *Engine) checkDiskMounts(clientID, hostname string, thresholds models.Thresholds, recoveryMargin float64) {
+	mounts, err := e.store.GetLatestDiskMounts(clientID)
+	if err != nil {
+		e.logger.Error("failed to get disk mounts", "client_id", clientID, "err", err)
+		return
+	}
+	for _, m := range mounts {
+		if m.Error != "" {
+			continue
+		}
+		warnPct, critPct := thresholds.DiskWarnPct, thresholds.DiskCritPct
+		if m.WarnPct != nil {
+			warnPct = *m.WarnPct
+		}
+		if m.CritPct != nil {
+			critPct = *m.CritPct
+		}
+
+		state := ""
+		if m.UsedPercent >= critPct {
+			state = "crit"
+		} else if m.UsedPercent >= warnPct {
+			state = "warn"
+		} else if m.AlertState != "" && m.UsedPercent >= warnPct-recoveryMargin {
+			// Not far enough under the threshold to count as recovered.
+			state = m.AlertState
+		}
+		if state == m.AlertState {
+			continue
+		}
+
+		switch state {
+		case "crit":
+			e.fireTargetAlert(clientID, models.AlertTypeMountCrit, m.Path, models.SeverityCritical,
+				fmt.Sprintf("Disk %s at %.1f%% on '%s' (critical threshold: %.1f%%)", m.Path, m.UsedPercent, hostname, critPct))
+		case "warn":
+			// Dropping from crit to warn is an improvement; don't re-alert.
+			if m.AlertState != "crit" {
+				e.fireTargetAlert(clientID, models.AlertTypeMountWarn, m.Path, models.SeverityWarning,
+					fmt.Sprintf("Disk %s at %.1f%% on '%s' (warning threshold: %.1f%%)", m.Path, m.UsedPercent, hostname, warnPct))
+			}
+		default:
+			e.fireTargetAlert(clientID, models.AlertTypeMountRecover, m.Path, models.SeverityInfo,
+				fmt.Sprintf("Disk %s recovered to %.1f%% on '%s'", m.Path, m.UsedPercent, hostname))
+		}
+		if err := e.store.SetDiskMountAlertState(clientID, m.Path, state); err != nil {
+			e.logger.Error("failed to update disk mount alert state", "client_id", clientID, "path", m.Path, "err", err)
+		}
+	}
+}
+
+// diskFillRateSettings returns the root disk growth, in percentage points per
+// hour, that raises a disk_fill_rate alert (0 disables it) and the window the
+// rate is measured over.
+func (e *Engine) diskFillRateSettings() (float64, time.Duration) {
+	rate := 0.0
+	if raw, _ := e.store.GetSetting("disk_fill_rate_pct_per_hour"); raw != "" {
+		if parsed, err := strconv.ParseFloat(strings.TrimSpace(raw), 64); err == nil && parsed >= 0 {
+			rate = parsed
+		}
+	}
+	window := 60
+	if raw, _ := e.store.GetSetting("disk_fill_rate_window_minutes"); raw != "" {
+		if parsed, err := strconv.Atoi(strings.TrimSpace(raw)); err == nil && parsed >= 1 {
+			window = parsed
+		}
+	}
+	return rate, time.Duration(window) * time.Minute
+}
+
+// checkDiskFillRate alerts when the root disk grows faster than limit
+// points per hour over the window, and again once growth slows back down.
+func (e *Engine) checkDiskFillRate(clientID, hostname string, limit float64, window time.Duration) {
+	delta, err := e.store.GetDiskUsageDelta(clientID, time.Now().Add(-window))
+	if err != nil {
+		e.logger.Error("failed to get disk usage delta", "client_id", clientID, "err", err)
+		return
+	}
+	filling, known := diskFilling(delta, limit, window)
+	if !known {
+		return
+	}
+
+	last, _ := e.store.GetLastAlertByTypes(clientID, models.AlertTypeDiskFillRate, models.AlertTypeDiskFillRecover)
+	active := last != nil && last.AlertType == models.AlertTypeDiskFillRate
+	switch {
+	case filling && !active:
+		rate := delta.PerHour()
+		msg := fmt.Sprintf("Disk on '%s' filling at %.1f%%/h (%.1f%% → %.1f%% over %s; threshold: %.1f%%/h)",
+			hostname, rate, delta.FromPercent, delta.ToPercent, delta.ToAt.Sub(delta.FromAt).Round(time.Minute), limit)
+		if remaining := 100 - delta.ToPercent; remaining > 0 {
+			msg += fmt.Sprintf(", full in ~%s", formatHours(remaining/rate))
+		}
+		e.fireAlert(clientID, models.AlertTypeDiskFillRate, models.SeverityWarning, msg)
+	case !filling && active:
+		e.fireAlert(clientID, models.AlertTypeDiskFillRecover, models.SeverityInfo,
+			fmt.Sprintf("Disk on '%s' is no longer filling quickly (%.1f%%/h)", hostname, delta.PerHour()))
 	}
 }
 
+// diskFilling reports whether delta shows growth above limit points per hour.
+// known is false until the samples span at least half the window, so a
+// couple of close-together samples from a new client can't trip the alert.
+func diskFilling(delta *models.UsageDelta, limit float64, window time.Duration) (filling, known bool) {
+	if delta == nil || delta.ToAt.Sub(delta.FromAt) < window/2 {
+		return false, false
+	}
+	return delta.PerHour() > limit, true
+}
+
+// formatHours renders a duration given in hours, e.g. "45m" or "6.5h".
+func formatHours(h float64) string {
+	if h < 1 {
+		return fmt.Sprintf("%.0fm", h*60)
+	}
+	return fmt.Sprintf("%.1fh", h)
+}
+
 func consecutiveThresholdStreak(recent []models.Metric, metric string, threshold float64) int {
 	streak := 0
 	for _, m := range recent {
@@ -290,16 +622,48 @@ func metricValue(m models.Metric, metric string) float64 {
 		return m.MemPercent
 	case "disk":
 		return m.DiskPercent
+	case "swap":
+		return m.SwapPercent
+	case "temp":
+		if m.CPUTempC == nil {
+			return 0
+		}
+		return *m.CPUTempC
+	case "open_fds":
+		if m.OpenFDs == nil {
+			return 0
+		}
+		return float64(*m.OpenFDs)
+	case "process_count":
+		if m.ProcessCount == nil {
+			return 0
+		}
+		return float64(*m.ProcessCount)
+	case "inode":
+		if m.DiskInodesUsedPercent == nil {
+			return 0
+		}
+		return *m.DiskInodesUsedPercent
 	default:
 		return 0
 	}
 }
 
-func (e *Engine) checkProcesses(clientID, hostname string, mutes scopedMuteState) {
+func (e *Engine) checkProcesses(clientID, hostname string, mutes scopedMuteState, consecutiveRequired int) {
 	current, err := e.store.GetLatestProcessSnapshots(clientID)
 	if err != nil || len(current) == 0 {
 		return
 	}
+	var newest time.Time
+	for _, p := range current {
+		if p.RecordedAt.After(newest) {
+			newest = p.RecordedAt
+		}
+	}
+	if !newest.After(e.processesSeenAt[clientID]) {
+		return
+	}
+	e.processesSeenAt[clientID] = newest
 	previous, err := e.store.GetPreviousProcessSnapshots(clientID)
 	if err != nil || len(previous) == 0 {
 		return // No previous data to compare
@@ -309,6 +673,21 @@ func (e *Engine) checkProcesses(clientID, hostname string, mutes scopedMuteState
 	for _, p := range previous {
 		prevMap[p.FriendlyName] = p
 	}
+	fdLimit := e.processResourceLimit("process_fd_warn")
+	threadLimit := e.processResourceLimit("process_thread_warn")
+	growthPct, growthSamples := e.processMemGrowthSettings()
+	startAlerts, _ := e.store.GetSetting("process_started_alerts")
+	alertAllStarts, _ := strconv.ParseBool(strings.TrimSpace(startAlerts))
+	restartLimit, restartWindow := e.processRestartSettings()
+	var restartCounts map[string]int
+	watchedByName := make(map[string]models.WatchedProcess)
+	if watched, err := e.store.GetWatchedProcesses(clientID); err != nil {
+		e.logger.Error("failed to get watched processes", "client_id", clientID, "err", err)
+	} else {
+		for _, w := range watched {
+			watchedByName[w.FriendlyName] = w
+		}
+	}
 
 	for _, curr := range current {
 		if mutes.processes[curr.FriendlyName] {
@@ -319,17 +698,359 @@ func (e *Engine) checkProcesses(clientID, hostname string, mutes scopedMuteState
 			continue
 		}
 
+		watched := watchedByName[curr.FriendlyName]
+		grouped := groupedProcess(watched.MinInstances, prev, curr)
 		if prev.IsRunning && !curr.IsRunning {
-			e.fireAlert(clientID, models.AlertTypeProcessDied, models.SeverityCritical,
+			if watched.ExpectedState == models.ProcessExpectStopped {
+				continue // an operator marked this process as expected to be down
+			}
+			e.fireTargetAlert(clientID, models.AlertTypeProcessDied, curr.FriendlyName, models.SeverityCritical,
 				fmt.Sprintf("Process '%s' has stopped on '%s'", curr.FriendlyName, hostname))
-		} else if prev.IsRunning && curr.IsRunning && prev.PID != nil && curr.PID != nil && *prev.PID != *curr.PID {
-			e.fireAlert(clientID, models.AlertTypePIDChange, models.SeverityWarning,
+		} else if !grouped && prev.IsRunning && curr.IsRunning && prev.PID != nil && curr.PID != nil && *prev.PID != *curr.PID {
+			e.fireTargetAlert(clientID, models.AlertTypePIDChange, curr.FriendlyName, models.SeverityWarning,
 				fmt.Sprintf("Process '%s' PID changed: %d -> %d on '%s'",
 					curr.FriendlyName, *prev.PID, *curr.PID, hostname))
+		} else if !prev.IsRunning && curr.IsRunning && (watched.AlertOnStart || alertAllStarts) {
+			// Processes are only compared once they have a previous snapshot,
+			// so adding one to the config that is already running stays quiet.
+			msg := fmt.Sprintf("Process '%s' started on '%s'", curr.FriendlyName, hostname)
+			if curr.PID != nil {
+				msg += fmt.Sprintf(" (PID %d)", *curr.PID)
+			}
+			e.fireTargetAlert(clientID, models.AlertTypeProcessStarted, curr.FriendlyName, models.SeverityWarning, msg)
+		}
+		if watched.MinInstances > 0 && watched.ExpectedState != models.ProcessExpectStopped {
+			e.checkProcessInstances(clientID, hostname, watched.MinInstances, prev, curr)
+		}
+
+		if restartLimit > 0 && processRestarted(prev, curr, grouped) {
+			if restartCounts == nil {
+				restartCounts, err = e.store.CountProcessRestarts(clientID, time.Now().Add(-restartWindow))
+				if err != nil {
+					e.logger.Error("failed to count process restarts", "client_id", clientID, "err", err)
+					restartCounts = map[string]int{}
+				}
+			}
+			// Fire once as the count crosses the limit; further restarts in
+			// the same window are already covered by this alert.
+			if n := restartCounts[curr.FriendlyName]; n == restartLimit+1 {
+				e.fireTargetAlert(clientID, models.AlertTypeProcessRestartLoop, curr.FriendlyName, models.SeverityCritical,
+					fmt.Sprintf("Process '%s' restarted %d times in the last %d minutes on '%s' (possible crash loop)",
+						curr.FriendlyName, n, int(restartWindow.Minutes()), hostname))
+			}
+		}
+
+		if curr.IsRunning {
+			if crossedResourceLimit(prev.NumFDs, curr.NumFDs, fdLimit) {
+				e.fireTargetAlert(clientID, models.AlertTypeProcessFDs, curr.FriendlyName, models.SeverityWarning,
+					fmt.Sprintf("Process '%s' has %d open file descriptors on '%s' (threshold: %d)",
+						curr.FriendlyName, *curr.NumFDs, hostname, fdLimit))
+			}
+			if crossedResourceLimit(prev.NumThreads, curr.NumThreads, threadLimit) {
+				e.fireTargetAlert(clientID, models.AlertTypeProcessThreads, curr.FriendlyName, models.SeverityWarning,
+					fmt.Sprintf("Process '%s' has %d threads on '%s' (threshold: %d)",
+						curr.FriendlyName, *curr.NumThreads, hostname, threadLimit))
+			}
+			if growthPct > 0 {
+				e.checkProcessMemGrowth(clientID, hostname, curr.FriendlyName, watched.MinInstances, growthPct, growthSamples)
+			}
+			if watched.CPUAlertPct > 0 || watched.MemAlertPct > 0 {
+				e.checkProcessUsage(clientID, hostname, watched, consecutiveRequired)
+			}
 		}
 	}
 }
 
+// checkProcessInstances alerts when an aggregated process drops below its
+// minimum instance count and again when it recovers. A group that stops
+// entirely is reported by process_died instead.
+func (e *Engine) checkProcessInstances(clientID, hostname string, minInstances int, prev, curr models.ProcessSnapshot) {
+	prevLow := prev.IsRunning && prev.InstanceCount < minInstances
+	currLow := curr.IsRunning && curr.InstanceCount < minInstances
+	switch {
+	case currLow && !prevLow:
+		e.fireTargetAlert(clientID, models.AlertTypeInstancesLow, curr.FriendlyName, models.SeverityWarning,
+			fmt.Sprintf("Process '%s' has %d of %d required instances running on '%s'",
+				curr.FriendlyName, curr.InstanceCount, minInstances, hostname))
+	case prevLow && curr.IsRunning && !currLow:
+		e.fireTargetAlert(clientID, models.AlertTypeInstancesRestored, curr.FriendlyName, models.SeverityInfo,
+			fmt.Sprintf("Process '%s' is back to %d instances on '%s'",
+				curr.FriendlyName, curr.InstanceCount, hostname))
+	}
+}
+
+// groupedProcess reports whether snapshots of a watched process stand for an
+// aggregated group. A group reports its lowest PID, so a PID change only
+// means one worker was replaced, not that the group restarted.
+func groupedProcess(minInstances int, snaps ...models.ProcessSnapshot) bool {
+	if minInstances > 0 {
+		return true
+	}
+	for _, s := range snaps {
+		if s.InstanceCount > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// processRestarted reports whether curr is a new instance of the process:
+// it came back after being stopped, or its PID changed while running. A
+// grouped process only restarts by coming back.
+func processRestarted(prev, curr models.ProcessSnapshot, grouped bool) bool {
+	if !curr.IsRunning {
+		return false
+	}
+	if !prev.IsRunning {
+		return true
+	}
+	return !grouped && prev.PID != nil && curr.PID != nil && *prev.PID != *curr.PID
+}
+
+// processRestartSettings returns how many restarts within the window are
+// tolerated before a crash-loop alert. A limit of 0 disables the alert.
+func (e *Engine) processRestartSettings() (int, time.Duration) {
+	limit := 3
+	if raw, _ := e.store.GetSetting("process_restart_limit"); raw != "" {
+		if parsed, err := strconv.Atoi(strings.TrimSpace(raw)); err == nil && parsed >= 0 {
+			limit = parsed
+		}
+	}
+	windowMins := 10
+	if raw, _ := e.store.GetSetting("process_restart_window_minutes"); raw != "" {
+		if parsed, err := strconv.Atoi(strings.TrimSpace(raw)); err == nil && parsed > 0 {
+			windowMins = parsed
+		}
+	}
+	return limit, time.Duration(windowMins) * time.Minute
+}
+
+// processMemGrowthSettings returns the memory growth (percentage points) that
+// counts as a leak and how many consecutive snapshots it must span. A growth
+// of 0 disables leak detection.
+func (e *Engine) processMemGrowthSettings() (float64, int) {
+	var growth float64
+	if raw, _ := e.store.GetSetting("process_mem_growth_pct"); raw != "" {
+		if parsed, err := strconv.ParseFloat(strings.TrimSpace(raw), 64); err == nil && parsed > 0 {
+			growth = parsed
+		}
+	}
+	samples := 10
+	if raw, _ := e.store.GetSetting("process_mem_growth_samples"); raw != "" {
+		if parsed, err := strconv.Atoi(strings.TrimSpace(raw)); err == nil && parsed >= 2 {
+			samples = parsed
+		}
+	}
+	return growth, samples
+}
+
+func (e *Engine) checkProcessMemGrowth(clientID, hostname, friendlyName string, minInstances int, growthPct float64, samples int) {
+	// One extra snapshot lets us tell a new leak from one already reported.
+	recent, err := e.store.GetRecentProcessSnapshots(clientID, friendlyName, samples+1)
+	if err != nil || len(recent) < samples {
+		return
+	}
+	grouped := groupedProcess(minInstances, recent...)
+	grew, from, to := memGrowthOverWindow(recent[:samples], growthPct, grouped)
+	if !grew {
+		return
+	}
+	if len(recent) > samples {
+		if already, _, _ := memGrowthOverWindow(recent[1:], growthPct, grouped); already {
+			return
+		}
+	}
+	e.fireTargetAlert(clientID, models.AlertTypeProcessMemGrowth, friendlyName, models.SeverityWarning,
+		fmt.Sprintf("Process '%s' memory grew steadily from %.1f%% to %.1f%% over the last %d check-ins on '%s' (possible leak)",
+			friendlyName, from, to, samples, hostname))
+}
+
+// checkProcessUsage fires process_high_cpu/process_high_mem once the process
+// has been at or above its configured threshold for consecutiveRequired
+// snapshots in a row.
+func (e *Engine) checkProcessUsage(clientID, hostname string, w models.WatchedProcess, consecutiveRequired int) {
+	if consecutiveRequired < 1 {
+		consecutiveRequired = 1
+	}
+	// One extra snapshot tells a new breach from one already reported.
+	recent, err := e.store.GetRecentProcessSnapshots(clientID, w.FriendlyName, consecutiveRequired+1)
+	if err != nil {
+		e.logger.Error("failed to get process snapshots", "client_id", clientID, "process", w.FriendlyName, "err", err)
+		return
+	}
+	cpu := func(s models.ProcessSnapshot) float64 { return s.CPUPercent }
+	mem := func(s models.ProcessSnapshot) float64 { return s.MemPercent }
+	if w.CPUAlertPct > 0 && processUsageCrossed(recent, consecutiveRequired, cpu, w.CPUAlertPct) {
+		e.fireTargetAlert(clientID, models.AlertTypeProcessHighCPU, w.FriendlyName, models.SeverityWarning,
+			fmt.Sprintf("Process '%s' CPU at %.1f%% on '%s' (threshold: %.1f%%)",
+				w.FriendlyName, recent[0].CPUPercent, hostname, w.CPUAlertPct))
+	}
+	if w.MemAlertPct > 0 && processUsageCrossed(recent, consecutiveRequired, mem, w.MemAlertPct) {
+		e.fireTargetAlert(clientID, models.AlertTypeProcessHighMem, w.FriendlyName, models.SeverityWarning,
+			fmt.Sprintf("Process '%s' memory at %.1f%% on '%s' (threshold: %.1f%%)",
+				w.FriendlyName, recent[0].MemPercent, hostname, w.MemAlertPct))
+	}
+}
+
+// processUsageCrossed reports whether the newest n snapshots (newest first)
+// are all running at or above limit while the one before them was not, so a
+// sustained breach alerts once.
+func processUsageCrossed(snaps []models.ProcessSnapshot, n int, value func(models.ProcessSnapshot) float64, limit float64) bool {
+	if len(snaps) < n {
+		return false
+	}
+	over := func(s models.ProcessSnapshot) bool { return s.IsRunning && value(s) >= limit }
+	for _, s := range snaps[:n] {
+		if !over(s) {
+			return false
+		}
+	}
+	return len(snaps) == n || !over(snaps[n])
+}
+
+// memGrowthOverWindow reports whether memory never decreased across snaps
+// (newest first) within a single process instance, or a grouped process at a
+// steady instance count, and rose by at least growthPct overall.
+func memGrowthOverWindow(snaps []models.ProcessSnapshot, growthPct float64, grouped bool) (bool, float64, float64) {
+	if len(snaps) < 2 {
+		return false, 0, 0
+	}
+	newest, oldest := snaps[0], snaps[len(snaps)-1]
+	for i, s := range snaps {
+		if !s.IsRunning {
+			return false, 0, 0
+		}
+		// A group's PID changes whenever its lowest worker is replaced, so
+		// require the same number of instances instead.
+		if grouped {
+			if s.InstanceCount != newest.InstanceCount {
+				return false, 0, 0
+			}
+		} else if s.PID == nil || newest.PID == nil || *s.PID != *newest.PID {
+			return false, 0, 0
+		}
+		if i > 0 && s.MemPercent > snaps[i-1].MemPercent {
+			return false, 0, 0
+		}
+	}
+	return newest.MemPercent-oldest.MemPercent >= growthPct, oldest.MemPercent, newest.MemPercent
+}
+
+// crossedResourceLimit reports whether a per-process counter went from below
+// limit to at/above it between two snapshots, so a leak alerts once per crossing.
+func crossedResourceLimit(prev, curr *int32, limit int) bool {
+	if limit <= 0 || curr == nil || int(*curr) < limit {
+		return false
+	}
+	return prev == nil || int(*prev) < limit
+}
+
+// swapThresholds returns the global swap warn/crit percentages; both 0 (the
+// default) disables swap alerts. If only one is set, the other never fires.
+func (e *Engine) swapThresholds() (warn, crit float64) {
+	read := func(key string) float64 {
+		raw, _ := e.store.GetSetting(key)
+		v, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil || v <= 0 {
+			return 0
+		}
+		return v
+	}
+	warn, crit = read("swap_warn_pct_default"), read("swap_crit_pct_default")
+	switch {
+	case warn > 0 && crit <= 0:
+		crit = math.Inf(1)
+	case crit > 0 && warn <= 0:
+		warn = crit
+	}
+	return warn, crit
+}
+
+// countThresholds reads the global <metric>_warn_default and
+// <metric>_crit_default count thresholds; 0 disables a level. warn is 0 when
+// both are disabled.
+func (e *Engine) countThresholds(metric string) (warn, crit float64) {
+	read := func(key string) float64 {
+		raw, _ := e.store.GetSetting(key)
+		v, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+		if err != nil || v <= 0 {
+			return 0
+		}
+		return float64(v)
+	}
+	warn, crit = read(metric+"_warn_default"), read(metric+"_crit_default")
+	switch {
+	case warn > 0 && crit <= 0:
+		crit = math.Inf(1)
+	case crit > 0 && (warn <= 0 || warn > crit):
+		warn = crit
+	}
+	return warn, crit
+}
+
+// metricRecoveryMargin is how far (in percentage points, or degrees for
+// temperature) a metric must fall below its warning threshold before the
+// recovery alert fires. Values in between keep the alert active so a metric
+// hovering at the threshold doesn't flap.
+func (e *Engine) metricRecoveryMargin() float64 {
+	raw, _ := e.store.GetSetting("metric_recovery_margin")
+	v, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil || v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0
+	}
+	return v
+}
+
+// tempThresholds returns the global CPU temperature warn/crit levels in
+// Celsius (defaults 80/90). Setting either to 0 disables that level; warn is
+// +Inf when both are disabled.
+func (e *Engine) tempThresholds() (warn, crit float64) {
+	return e.defaultedThresholds("temp_warn_c_default", 80, "temp_crit_c_default", 90)
+}
+
+// inodeThresholds returns the global disk inode usage warn/crit percentages
+// (defaults 90/95), disabled the same way as tempThresholds.
+func (e *Engine) inodeThresholds() (warn, crit float64) {
+	return e.defaultedThresholds("disk_inodes_warn_pct_default", 90, "disk_inodes_crit_pct_default", 95)
+}
+
+// defaultedThresholds reads a warn/crit setting pair that falls back to
+// defaults when unset and treats 0 as disabled (+Inf).
+func (e *Engine) defaultedThresholds(warnKey string, warnDef float64, critKey string, critDef float64) (warn, crit float64) {
+	read := func(key string, def float64) float64 {
+		raw, _ := e.store.GetSetting(key)
+		if strings.TrimSpace(raw) == "" {
+			return def
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil || v < 0 {
+			return def
+		}
+		if v == 0 {
+			return math.Inf(1)
+		}
+		return v
+	}
+	warn, crit = read(warnKey, warnDef), read(critKey, critDef)
+	if warn > crit {
+		warn = crit
+	}
+	return warn, crit
+}
+
+// processResourceLimit reads a global per-process count threshold; 0 disables it.
+func (e *Engine) processResourceLimit(key string) int {
+	raw, _ := e.store.GetSetting(key)
+	if raw == "" {
+		return 0
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || limit < 0 {
+		return 0
+	}
+	return limit
+}
+
 func (e *Engine) checkChecks(clientID, hostname string, mutes scopedMuteState) {
 	current, err := e.store.GetLatestCheckSnapshots(clientID)
 	if err != nil || len(current) == 0 {
@@ -344,6 +1065,7 @@ func (e *Engine) checkChecks(clientID, hostname string, mutes scopedMuteState) {
 	for _, cs := range previous {
 		prevMap[checkMuteTarget(cs.FriendlyName, cs.CheckType)] = cs
 	}
+	defaultStreak := e.checkConsecutiveFailuresDefault()
 
 	for _, curr := range current {
 		if mutes.checks[checkMuteTarget(curr.FriendlyName, curr.CheckType)] {
@@ -351,25 +1073,83 @@ func (e *Engine) checkChecks(clientID, hostname string, mutes scopedMuteState) {
 		}
 		prev, exists := prevMap[checkMuteTarget(curr.FriendlyName, curr.CheckType)]
 
-		if !curr.Healthy {
+		required := defaultStreak
+		if curr.ConsecutiveFailures > 0 {
+			required = curr.ConsecutiveFailures
+		}
+		var failed, recovered bool
+		if required <= 1 {
 			// Only alert if it was previously healthy or is first time failing
-			if !exists || prev.Healthy {
-				msg := fmt.Sprintf("Check '%s' (%s) failed on '%s'",
-					curr.FriendlyName, curr.CheckType, hostname)
-				if curr.Message != "" {
-					msg += ": " + curr.Message
-				}
-				e.fireAlert(clientID, models.AlertTypeCheckFailed, models.SeverityCritical, msg)
+			failed = !curr.Healthy && (!exists || prev.Healthy)
+			recovered = curr.Healthy && exists && !prev.Healthy
+		} else {
+			recent, err := e.store.GetRecentCheckSnapshots(clientID, curr.FriendlyName, curr.CheckType, required+1)
+			if err != nil {
+				e.logger.Error("failed to get recent check snapshots", "client_id", clientID, "check", curr.FriendlyName, "err", err)
+				continue
+			}
+			// Fire once the failure streak reaches the requirement, and only
+			// report a recovery from a streak that was long enough to alert.
+			failed = !curr.Healthy && failureStreak(recent) == required
+			recovered = curr.Healthy && len(recent) > 1 && failureStreak(recent[1:]) >= required
+		}
+
+		if failed {
+			msg := fmt.Sprintf("Check '%s' (%s) failed on '%s'",
+				curr.FriendlyName, curr.CheckType, hostname)
+			if required > 1 {
+				msg += fmt.Sprintf(" %d times in a row", required)
 			}
-		} else if exists && !prev.Healthy {
+			if curr.Message != "" {
+				msg += ": " + curr.Message
+			}
+			e.fireTargetAlert(clientID, models.AlertTypeCheckFailed, checkMuteTarget(curr.FriendlyName, curr.CheckType), checkFailureSeverity(curr.Severity), msg)
+		} else if recovered {
 			// Was failing, now healthy
-			e.fireAlert(clientID, models.AlertTypeCheckRecovered, models.SeverityInfo,
+			e.fireTargetAlert(clientID, models.AlertTypeCheckRecovered, checkMuteTarget(curr.FriendlyName, curr.CheckType), models.SeverityInfo,
 				fmt.Sprintf("Check '%s' (%s) recovered on '%s'",
 					curr.FriendlyName, curr.CheckType, hostname))
 		}
 	}
 }
 
+// failureStreak counts the unhealthy snapshots at the start of a newest-first
+// list.
+func failureStreak(snaps []models.CheckSnapshot) int {
+	n := 0
+	for _, cs := range snaps {
+		if cs.Healthy {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// checkConsecutiveFailuresDefault is how many failed runs in a row a check
+// needs before check_failed fires, for checks that don't set their own.
+func (e *Engine) checkConsecutiveFailuresDefault() int {
+	if raw, _ := e.store.GetSetting("check_consecutive_failures_default"); raw != "" {
+		if n, err := strconv.Atoi(strings.TrimSpace(raw)); err == nil && n >= 1 {
+			return n
+		}
+	}
+	return 1
+}
+
+// checkFailureSeverity maps a check's configured severity to an alert
+// severity. Unset or unrecognized values keep the historical critical default.
+func checkFailureSeverity(configured string) string {
+	switch strings.ToLower(strings.TrimSpace(configured)) {
+	case models.SeverityInfo:
+		return models.SeverityInfo
+	case models.SeverityWarning, "warn":
+		return models.SeverityWarning
+	default:
+		return models.SeverityCritical
+	}
+}
+
 func (e *Engine) loadScopedMutes(clientID string) scopedMuteState {
 	out := scopedMuteState{
 		metrics:   map[string]bool{},
@@ -406,10 +1186,61 @@ func checkMuteTarget(friendlyName, checkType string) string {
 	return strings.TrimSpace(friendlyName) + "::" + strings.TrimSpace(checkType)
 }
 
+// outsideBusinessHours reports whether the client has a business-hours
+// schedule and now falls outside it. An invalid schedule never suppresses.
+func (e *Engine) outsideBusinessHours(client *models.Client, now time.Time) bool {
+	if client == nil || client.BusinessHours == nil {
+		return false
+	}
+	schedule, err := parseBusinessHours(client.BusinessHours)
+	if err != nil {
+		e.logger.Warn("invalid business hours; alerting normally", "client_id", client.ID, "err", err)
+		return false
+	}
+	return !schedule.Contains(now)
+}
+
 func (e *Engine) fireAlert(clientID, alertType, severity, message string) {
+	e.fireTargetAlert(clientID, alertType, "", severity, message)
+}
+
+// fireTargetAlert fires an alert about one process, check, or mount of a
+// client. The target keeps the cooldown for one from hiding another.
+func (e *Engine) fireTargetAlert(clientID, alertType, target, severity, message string) {
+	client, _ := e.store.GetClient(clientID)
+	if client != nil && client.Suspended {
+		e.logger.Info("alert suppressed for suspended client",
+			"client_id", clientID,
+			"type", alertType,
+			"message", message)
+		return
+	}
+	if e.outsideBusinessHours(client, time.Now()) {
+		e.logger.Info("alert suppressed outside business hours",
+			"client_id", clientID,
+			"type", alertType,
+			"message", message)
+		return
+	}
+	if e.inMaintenance(clientID, time.Now()) {
+		e.logger.Info("alert suppressed by maintenance window",
+			"client_id", clientID,
+			"type", alertType,
+			"message", message)
+		return
+	}
+	if e.inAlertCooldown(client, clientID, alertType, target) {
+		e.logger.Info("alert suppressed by cooldown",
+			"client_id", clientID,
+			"type", alertType,
+			"message", message)
+		return
+	}
+
 	alert := &models.Alert{
 		ClientID:  clientID,
 		AlertType: alertType,
+		Target:    target,
 		Severity:  severity,
 		Message:   message,
 		FiredAt:   time.Now().UTC(),
@@ -426,28 +1257,116 @@ func (e *Engine) fireAlert(clientID, alertType, severity, message string) {
 		"severity", severity,
 		"message", message)
 
+	if e.onAlert != nil {
+		e.onAlert(*alert)
+	}
+
+	if e.dryRun() {
+		e.logger.Info("dry run: alert recorded, notification not sent",
+			"alert_id", alert.ID, "client_id", clientID, "type", alertType, "severity", severity)
+		return
+	}
 	if err := e.dispatcher.Dispatch(alert); err != nil {
 		e.logger.Error("failed to dispatch alert", "err", err)
 	}
 }
 
-func (e *Engine) cleanupOldData() {
-	metricsRetentionDays := 14 // default
-	if v, _ := e.store.GetSetting("metrics_retention_days"); v != "" {
-		if days, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && days > 0 {
-			metricsRetentionDays = days
+// retryFailedNotifications re-dispatches alerts whose delivery failed, backing
+// off exponentially between attempts and giving up after alert_retry_max_attempts.
+// Alerts that were never attempted (no providers, quiet hours) are left alone.
+func (e *Engine) retryFailedNotifications() {
+	maxAttempts := e.alertRetryMaxAttempts()
+	if maxAttempts == 0 || e.dryRun() {
+		return
+	}
+	alerts, err := e.store.GetAlertsDueForRetry(maxAttempts, time.Now().UTC())
+	if err != nil {
+		e.logger.Error("failed to get alerts due for retry", "err", err)
+		return
+	}
+
+	// Non-critical alerts go out with the next digest instead.
+	digest, _ := digestInterval(e.store)
+
+	for i := range alerts {
+		a := &alerts[i]
+		if digest > 0 && a.Severity != models.SeverityCritical {
+			continue
+		}
+
+		e.logger.Info("retrying alert notification",
+			"alert_id", a.ID, "alert_type", a.AlertType, "attempt", a.NotifyAttempts+1, "max_attempts", maxAttempts)
+		if err := e.dispatcher.Dispatch(a); err != nil {
+			if a.NotifyAttempts+1 >= maxAttempts {
+				e.logger.Error("giving up on alert notification", "alert_id", a.ID, "attempts", a.NotifyAttempts+1, "err", err)
+			} else {
+				e.logger.Warn("alert notification retry failed", "alert_id", a.ID, "err", err)
+			}
 		}
 	}
-	alertsRetentionDays := metricsRetentionDays // default: follow global data retention
-	if v, _ := e.store.GetSetting("alerts_retention_days"); v != "" {
-		if days, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && days > 0 {
-			alertsRetentionDays = days
+}
+
+// DryRun reports whether alert_dry_run is on: alerts are still evaluated and
+// stored, but no notifications go out.
+func DryRun(st store.Store) bool {
+	raw, _ := st.GetSetting("alert_dry_run")
+	on, _ := strconv.ParseBool(strings.TrimSpace(raw))
+	return on
+}
+
+func (e *Engine) dryRun() bool {
+	return DryRun(e.store)
+}
+
+func (e *Engine) alertRetryMaxAttempts() int {
+	maxAttempts := 5
+	if raw, _ := e.store.GetSetting("alert_retry_max_attempts"); raw != "" {
+		if parsed, err := strconv.Atoi(strings.TrimSpace(raw)); err == nil && parsed >= 0 {
+			maxAttempts = parsed
 		}
 	}
-	metricsRetention := time.Duration(metricsRetentionDays) * 24 * time.Hour
-	alertsRetention := time.Duration(alertsRetentionDays) * 24 * time.Hour
+	return maxAttempts
+}
 
-	deleted, err := e.store.PruneOldData(metricsRetention, alertsRetention)
+// retryBackoff returns the delay before the next delivery attempt after the
+// given number of failed attempts: 30s, 1m, 2m, ... capped at 1h.
+func retryBackoff(attempts int) time.Duration {
+	const (
+		base    = 30 * time.Second
+		maxWait = time.Hour
+	)
+	if attempts < 1 {
+		return 0
+	}
+	d := base
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= maxWait {
+			return maxWait
+		}
+	}
+	return d
+}
+
+func (e *Engine) cleanupOldData() {
+	metricsRetentionDays := e.retentionDays("metrics_retention_days", 14)
+	// Alerts follow the global data retention unless set on their own.
+	alertsRetentionDays := e.retentionDays("alerts_retention_days", metricsRetentionDays)
+	// Keep a year of admin history by default.
+	auditRetentionDays := e.retentionDays("audit_retention_days", 365)
+	// Restart history feeds restart-loop alerts; ended maintenance windows
+	// explain past quiet periods in alert history.
+	restartsRetentionDays := e.retentionDays("process_restarts_retention_days", metricsRetentionDays)
+	maintenanceRetentionDays := e.retentionDays("maintenance_retention_days", alertsRetentionDays)
+	days := func(n int) time.Duration { return time.Duration(n) * 24 * time.Hour }
+
+	deleted, err := e.store.PruneOldData(store.RetentionWindows{
+		Metrics:         days(metricsRetentionDays),
+		Alerts:          days(alertsRetentionDays),
+		Audit:           days(auditRetentionDays),
+		ProcessRestarts: days(restartsRetentionDays),
+		Maintenance:     days(maintenanceRetentionDays),
+	})
 	if err != nil {
 		e.logger.Error("failed to prune old data", "err", err)
 		return
@@ -456,8 +1375,38 @@ func (e *Engine) cleanupOldData() {
 		e.logger.Info("pruned old data",
 			"rows_deleted", deleted,
 			"metrics_retention_days", metricsRetentionDays,
-			"alerts_retention_days", alertsRetentionDays)
+			"alerts_retention_days", alertsRetentionDays,
+			"audit_retention_days", auditRetentionDays,
+			"process_restarts_retention_days", restartsRetentionDays,
+			"maintenance_retention_days", maintenanceRetentionDays)
+	}
+
+	// Deleted rows leave free pages behind; reclaim them once enough pile up.
+	minRows := int64(10000)
+	if v, _ := e.store.GetSetting("vacuum_min_pruned_rows"); v != "" {
+		if n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil && n >= 0 {
+			minRows = n
+		}
+	}
+	if minRows > 0 && deleted >= minRows {
+		start := time.Now()
+		if err := e.store.Vacuum(); err != nil {
+			e.logger.Error("failed to vacuum database", "err", err)
+			return
+		}
+		e.logger.Info("vacuumed database", "rows_pruned", deleted, "duration", time.Since(start).Round(time.Millisecond))
+	}
+}
+
+// retentionDays reads a retention window in days from a global setting,
+// returning def when it is unset or not a positive number.
+func (e *Engine) retentionDays(key string, def int) int {
+	if v, _ := e.store.GetSetting(key); v != "" {
+		if days, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && days > 0 {
+			return days
+		}
 	}
+	return def
 }
 
 func clientLabel(c *models.Client) string {
@@ -0,0 +1,919 @@
+package alerting
+
+import (
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+	"github.com/machinemon/machinemon/internal/store"
+)
+
+// newTestStore opens a fresh SQLite store that is closed when the test ends.
+func newTestStore(t *testing.T) *store.SQLiteStore {
+	t.Helper()
+	st, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+// newTestEngine builds an engine on st that discards its logs.
+func newTestEngine(st store.Store) *Engine {
+	return NewEngine(st, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestRetryBackoffDoublesAndCaps(t *testing.T) {
+	cases := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{50, time.Hour},
+	}
+	for _, tc := range cases {
+		if got := retryBackoff(tc.attempts); got != tc.want {
+			t.Fatalf("retryBackoff(%d) = %s, want %s", tc.attempts, got, tc.want)
+		}
+	}
+}
+
+func TestCrossedResourceLimit(t *testing.T) {
+	i32 := func(v int32) *int32 { return &v }
+	cases := []struct {
+		name       string
+		prev, curr *int32
+		limit      int
+		want       bool
+	}{
+		{"disabled", i32(10), i32(5000), 0, false},
+		{"crossing", i32(900), i32(1000), 1000, true},
+		{"already above", i32(1100), i32(1200), 1000, false},
+		{"no previous sample", nil, i32(1500), 1000, true},
+		{"not collected", i32(10), nil, 1000, false},
+		{"below", i32(10), i32(20), 1000, false},
+	}
+	for _, tc := range cases {
+		if got := crossedResourceLimit(tc.prev, tc.curr, tc.limit); got != tc.want {
+			t.Fatalf("%s: crossedResourceLimit = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestMemGrowthOverWindow(t *testing.T) {
+	pid := int32(42)
+	other := int32(43)
+	snap := func(mem float64, p *int32) models.ProcessSnapshot {
+		return models.ProcessSnapshot{IsRunning: true, PID: p, MemPercent: mem}
+	}
+
+	// Newest first.
+	leaking := []models.ProcessSnapshot{snap(20, &pid), snap(18, &pid), snap(15, &pid), snap(10, &pid)}
+	if grew, from, to := memGrowthOverWindow(leaking, 5, false); !grew || from != 10 || to != 20 {
+		t.Fatalf("expected growth 10 -> 20, got grew=%v from=%v to=%v", grew, from, to)
+	}
+
+	dipped := []models.ProcessSnapshot{snap(20, &pid), snap(12, &pid), snap(15, &pid), snap(10, &pid)}
+	if grew, _, _ := memGrowthOverWindow(dipped, 5, false); grew {
+		t.Fatalf("expected no growth when memory dropped inside the window")
+	}
+
+	restarted := []models.ProcessSnapshot{snap(20, &pid), snap(18, &other), snap(10, &other)}
+	if grew, _, _ := memGrowthOverWindow(restarted, 5, false); grew {
+		t.Fatalf("expected no growth across a PID change")
+	}
+
+	small := []models.ProcessSnapshot{snap(11, &pid), snap(10, &pid)}
+	if grew, _, _ := memGrowthOverWindow(small, 5, false); grew {
+		t.Fatalf("expected no growth below threshold")
+	}
+
+	// A group keeps growing across recycled workers, but not when it just
+	// gained an instance.
+	group := func(mem float64, p *int32, n int) models.ProcessSnapshot {
+		s := snap(mem, p)
+		s.InstanceCount = n
+		return s
+	}
+	recycled := []models.ProcessSnapshot{group(20, &other, 4), group(15, &pid, 4), group(10, &pid, 4)}
+	if grew, _, _ := memGrowthOverWindow(recycled, 5, true); !grew {
+		t.Fatalf("expected growth across a recycled worker in a group")
+	}
+	scaled := []models.ProcessSnapshot{group(20, &pid, 5), group(15, &pid, 4), group(10, &pid, 4)}
+	if grew, _, _ := memGrowthOverWindow(scaled, 5, true); grew {
+		t.Fatalf("expected no growth when the group gained an instance")
+	}
+}
+
+func TestCheckFailureSeverity(t *testing.T) {
+	cases := map[string]string{
+		"":         models.SeverityCritical,
+		"critical": models.SeverityCritical,
+		"Warning":  models.SeverityWarning,
+		"warn":     models.SeverityWarning,
+		"info":     models.SeverityInfo,
+		"bogus":    models.SeverityCritical,
+	}
+	for in, want := range cases {
+		if got := checkFailureSeverity(in); got != want {
+			t.Fatalf("checkFailureSeverity(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestReportingDrift(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(mins ...int) []time.Time {
+		out := make([]time.Time, len(mins))
+		for i, m := range mins {
+			out[i] = t0.Add(time.Duration(m) * time.Minute)
+		}
+		return out
+	}
+	expected := 2 * time.Minute
+
+	if state, _ := reportingDrift(at(0, 2, 4, 6), expected, 3, 3); state != driftHealthy {
+		t.Fatalf("expected healthy cadence, got %v", state)
+	}
+	state, typical := reportingDrift(at(0, 20, 40, 60), expected, 3, 3)
+	if state != driftDegraded || typical != 20*time.Minute {
+		t.Fatalf("expected degraded at ~20m, got %v %v", state, typical)
+	}
+	// A single long gap (e.g. a brief outage) is not enough on its own.
+	if state, _ := reportingDrift(at(0, 2, 4, 24), expected, 3, 3); state != driftUnknown {
+		t.Fatalf("expected unknown after a single long gap, got %v", state)
+	}
+	if state, _ := reportingDrift(at(0, 20, 40, 60), expected, 0, 3); state != driftUnknown {
+		t.Fatalf("expected drift detection disabled with factor 0, got %v", state)
+	}
+}
+
+func TestTempStreakBreaksOnMissingReading(t *testing.T) {
+	hot := 92.0
+	recent := []models.Metric{{CPUTempC: &hot}, {}, {CPUTempC: &hot}}
+	if got := consecutiveThresholdStreak(recent, "temp", 90); got != 1 {
+		t.Fatalf("streak = %d, want 1", got)
+	}
+	if got := formatMetricValue("temp", hot); got != "92.0°C" {
+		t.Fatalf("formatMetricValue(temp) = %q", got)
+	}
+	if got := formatMetricValue("cpu", 50); got != "50.0%" {
+		t.Fatalf("formatMetricValue(cpu) = %q", got)
+	}
+}
+
+func TestProcessRestarted(t *testing.T) {
+	i32 := func(v int32) *int32 { return &v }
+	cases := []struct {
+		name       string
+		prev, curr models.ProcessSnapshot
+		grouped    bool
+		want       bool
+	}{
+		{"same pid", models.ProcessSnapshot{IsRunning: true, PID: i32(1)}, models.ProcessSnapshot{IsRunning: true, PID: i32(1)}, false, false},
+		{"pid changed", models.ProcessSnapshot{IsRunning: true, PID: i32(1)}, models.ProcessSnapshot{IsRunning: true, PID: i32(2)}, false, true},
+		{"came back", models.ProcessSnapshot{IsRunning: false}, models.ProcessSnapshot{IsRunning: true, PID: i32(2)}, false, true},
+		{"stopped", models.ProcessSnapshot{IsRunning: true, PID: i32(1)}, models.ProcessSnapshot{IsRunning: false}, false, false},
+		{"pid unknown", models.ProcessSnapshot{IsRunning: true}, models.ProcessSnapshot{IsRunning: true, PID: i32(2)}, false, false},
+		{"group worker recycled", models.ProcessSnapshot{IsRunning: true, PID: i32(1)}, models.ProcessSnapshot{IsRunning: true, PID: i32(2)}, true, false},
+		{"group came back", models.ProcessSnapshot{IsRunning: false}, models.ProcessSnapshot{IsRunning: true, PID: i32(2)}, true, true},
+	}
+	for _, tc := range cases {
+		if got := processRestarted(tc.prev, tc.curr, tc.grouped); got != tc.want {
+			t.Fatalf("%s: processRestarted = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestDiskFilling(t *testing.T) {
+	now := time.Now()
+	delta := func(from, to float64, span time.Duration) *models.UsageDelta {
+		return &models.UsageDelta{FromPercent: from, ToPercent: to, FromAt: now.Add(-span), ToAt: now}
+	}
+	cases := []struct {
+		name          string
+		delta         *models.UsageDelta
+		filling, know bool
+	}{
+		{"no samples", nil, false, false},
+		{"span too short", delta(50, 60, 10*time.Minute), false, false},
+		{"fast growth", delta(50, 60, time.Hour), true, true},
+		{"slow growth", delta(50, 51, time.Hour), false, true},
+		{"shrinking", delta(60, 50, time.Hour), false, true},
+	}
+	for _, tc := range cases {
+		filling, known := diskFilling(tc.delta, 5, time.Hour)
+		if filling != tc.filling || known != tc.know {
+			t.Fatalf("%s: diskFilling = (%v, %v), want (%v, %v)", tc.name, filling, known, tc.filling, tc.know)
+		}
+	}
+	if got := formatHours(0.5); got != "30m" {
+		t.Fatalf("formatHours(0.5) = %q", got)
+	}
+	if got := formatHours(6.5); got != "6.5h" {
+		t.Fatalf("formatHours(6.5) = %q", got)
+	}
+}
+
+func TestProcessUsageCrossed(t *testing.T) {
+	snap := func(running bool, cpu float64) models.ProcessSnapshot {
+		return models.ProcessSnapshot{IsRunning: running, CPUPercent: cpu}
+	}
+	cpu := func(s models.ProcessSnapshot) float64 { return s.CPUPercent }
+	cases := []struct {
+		name  string
+		snaps []models.ProcessSnapshot // newest first
+		n     int
+		want  bool
+	}{
+		{"new breach", []models.ProcessSnapshot{snap(true, 95), snap(true, 92), snap(true, 40)}, 2, true},
+		{"already reported", []models.ProcessSnapshot{snap(true, 95), snap(true, 92), snap(true, 91)}, 2, false},
+		{"not sustained", []models.ProcessSnapshot{snap(true, 95), snap(true, 40), snap(true, 40)}, 2, false},
+		{"stopped", []models.ProcessSnapshot{snap(false, 0), snap(true, 95)}, 1, false},
+		{"first samples", []models.ProcessSnapshot{snap(true, 95)}, 1, true},
+		{"too few samples", []models.ProcessSnapshot{snap(true, 95)}, 2, false},
+		{"restarted after breach", []models.ProcessSnapshot{snap(true, 95), snap(false, 0)}, 1, true},
+	}
+	for _, tc := range cases {
+		if got := processUsageCrossed(tc.snaps, tc.n, cpu, 90); got != tc.want {
+			t.Fatalf("%s: processUsageCrossed = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParseOfflineCheckInterval(t *testing.T) {
+	cases := map[string]time.Duration{
+		"":      30 * time.Second,
+		"abc":   30 * time.Second,
+		"0":     30 * time.Second,
+		"-5":    30 * time.Second,
+		"1":     5 * time.Second,
+		" 15 ":  15 * time.Second,
+		"120":   2 * time.Minute,
+		"86400": 10 * time.Minute,
+	}
+	for raw, want := range cases {
+		if got := parseOfflineCheckInterval(raw); got != want {
+			t.Fatalf("parseOfflineCheckInterval(%q) = %s, want %s", raw, got, want)
+		}
+	}
+}
+
+func TestSuspendedClientDoesNotAlert(t *testing.T) {
+	st := newTestStore(t)
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := st.SetClientSuspended(res.ClientID, true, "decommissioning"); err != nil {
+		t.Fatalf("suspend: %v", err)
+	}
+
+	e := newTestEngine(st)
+	e.fireAlert(res.ClientID, models.AlertTypeOffline, models.SeverityCritical, "gone")
+	if last, err := st.GetLastAlertByTypes(res.ClientID, models.AlertTypeOffline); err != nil || last != nil {
+		t.Fatalf("expected no alert for suspended client, got %+v (err %v)", last, err)
+	}
+
+	if err := st.SetClientSuspended(res.ClientID, false, ""); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	e.fireAlert(res.ClientID, models.AlertTypeOffline, models.SeverityCritical, "gone")
+	if last, err := st.GetLastAlertByTypes(res.ClientID, models.AlertTypeOffline); err != nil || last == nil {
+		t.Fatalf("expected an alert after resuming, got %v (err %v)", last, err)
+	}
+}
+
+func TestDryRunStoresAlertsWithoutNotifying(t *testing.T) {
+	st := newTestStore(t)
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+
+	var sent int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { sent++ }))
+	defer srv.Close()
+	if err := st.CreateProvider(&models.AlertProvider{Type: "gotify", Name: "push", Enabled: true,
+		Config: `{"server_url":"` + srv.URL + `","app_token":"t"}`}); err != nil {
+		t.Fatalf("create provider: %v", err)
+	}
+	if err := st.SetSetting("alert_dry_run", "true"); err != nil {
+		t.Fatal(err)
+	}
+
+	e := newTestEngine(st)
+	e.fireAlert(res.ClientID, models.AlertTypeOffline, models.SeverityCritical, "gone")
+	last, err := st.GetLastAlertByTypes(res.ClientID, models.AlertTypeOffline)
+	if err != nil || last == nil {
+		t.Fatalf("expected the alert to be stored in dry run, got %v (err %v)", last, err)
+	}
+	if sent != 0 || last.Notified {
+		t.Fatalf("dry run sent %d notifications (notified=%v)", sent, last.Notified)
+	}
+
+	if err := st.SetSetting("alert_dry_run", "false"); err != nil {
+		t.Fatal(err)
+	}
+	e.fireAlert(res.ClientID, models.AlertTypeOnline, models.SeverityInfo, "back")
+	if sent != 1 {
+		t.Fatalf("expected a notification once dry run is off, got %d", sent)
+	}
+}
+
+// checkHistoryStore serves check snapshots from memory so a test can step
+// through check-ins without waiting for recorded_at to advance.
+type checkHistoryStore struct {
+	store.Store
+	history []models.CheckSnapshot // oldest first
+}
+
+func (s *checkHistoryStore) GetLatestCheckSnapshots(string) ([]models.CheckSnapshot, error) {
+	return s.history[len(s.history)-1:], nil
+}
+
+func (s *checkHistoryStore) GetPreviousCheckSnapshots(string) ([]models.CheckSnapshot, error) {
+	if len(s.history) < 2 {
+		return nil, nil
+	}
+	return s.history[len(s.history)-2 : len(s.history)-1], nil
+}
+
+func (s *checkHistoryStore) GetRecentCheckSnapshots(_, _, _ string, limit int) ([]models.CheckSnapshot, error) {
+	var out []models.CheckSnapshot
+	for i := len(s.history) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, s.history[i])
+	}
+	return out, nil
+}
+
+func TestCheckFailedWaitsForConsecutiveFailures(t *testing.T) {
+	sqlite := newTestStore(t)
+	res, err := sqlite.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := sqlite.SetSetting("check_consecutive_failures_default", "3"); err != nil {
+		t.Fatal(err)
+	}
+	st := &checkHistoryStore{Store: sqlite}
+	e := newTestEngine(st)
+
+	count := func(alertType string) int {
+		alerts, _, err := sqlite.ListAlerts(store.AlertFilter{ClientID: res.ClientID, AlertType: alertType}, 100, 0)
+		if err != nil {
+			t.Fatalf("list alerts: %v", err)
+		}
+		return len(alerts)
+	}
+	step := func(healthy bool, perCheck int) {
+		st.history = append(st.history, models.CheckSnapshot{
+			ClientID: res.ClientID, FriendlyName: "api", CheckType: models.CheckTypeHTTP,
+			Healthy: healthy, ConsecutiveFailures: perCheck,
+		})
+		e.checkChecks(res.ClientID, "web-1", scopedMuteState{checks: map[string]bool{}})
+	}
+
+	// A blip shorter than the streak neither fails nor "recovers".
+	step(true, 0)
+	step(false, 0)
+	step(false, 0)
+	step(true, 0)
+	if f, r := count(models.AlertTypeCheckFailed), count(models.AlertTypeCheckRecovered); f != 0 || r != 0 {
+		t.Fatalf("after a 2-run blip: %d failed, %d recovered alerts; want none", f, r)
+	}
+
+	// The third failure in a row fires once; later failures don't repeat it.
+	step(false, 0)
+	step(false, 0)
+	step(false, 0)
+	step(false, 0)
+	if f := count(models.AlertTypeCheckFailed); f != 1 {
+		t.Fatalf("check_failed alerts = %d, want 1", f)
+	}
+	step(true, 0)
+	if r := count(models.AlertTypeCheckRecovered); r != 1 {
+		t.Fatalf("check_recovered alerts = %d, want 1", r)
+	}
+
+	// A per-check value overrides the server default.
+	step(false, 1)
+	if f := count(models.AlertTypeCheckFailed); f != 2 {
+		t.Fatalf("check_failed alerts with consecutive_failures=1: %d, want 2", f)
+	}
+}
+
+func TestMetricRecoveryMargin(t *testing.T) {
+	st := newTestStore(t)
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := st.SetSetting("metric_recovery_margin", "5"); err != nil {
+		t.Fatal(err)
+	}
+	e := newTestEngine(st)
+	margin := e.metricRecoveryMargin()
+
+	last := func() string {
+		a, err := st.GetLastAlertByTypes(res.ClientID, models.AlertTypeCPUWarn, models.AlertTypeCPUCrit, models.AlertTypeCPURecover)
+		if err != nil || a == nil {
+			t.Fatalf("last alert: %v (err %v)", a, err)
+		}
+		return a.AlertType
+	}
+	check := func(cpu float64) {
+		recent := []models.Metric{{CPUPercent: cpu}}
+		e.checkThreshold(res.ClientID, "web-1", "cpu", cpu, 80, 95, recent, 1, margin)
+	}
+
+	check(85)
+	if got := last(); got != models.AlertTypeCPUWarn {
+		t.Fatalf("after 85%%: last alert %q, want cpu_warn", got)
+	}
+	check(78) // under warn, but inside the margin
+	if got := last(); got != models.AlertTypeCPUWarn {
+		t.Fatalf("after 78%%: last alert %q, want still cpu_warn", got)
+	}
+	check(74)
+	if got := last(); got != models.AlertTypeCPURecover {
+		t.Fatalf("after 74%%: last alert %q, want cpu_recover", got)
+	}
+}
+
+// processHistoryStore serves the latest and previous process snapshots from
+// memory, like checkHistoryStore does for checks.
+type processHistoryStore struct {
+	store.Store
+	previous, current []models.ProcessSnapshot
+}
+
+func (s *processHistoryStore) GetLatestProcessSnapshots(string) ([]models.ProcessSnapshot, error) {
+	return s.current, nil
+}
+
+func (s *processHistoryStore) GetPreviousProcessSnapshots(string) ([]models.ProcessSnapshot, error) {
+	return s.previous, nil
+}
+
+func TestCheckProcessesSkipsAlreadySeenSnapshots(t *testing.T) {
+	sqlite := newTestStore(t)
+	res, err := sqlite.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	pid := int32(100)
+	at := time.Now().UTC()
+	st := &processHistoryStore{
+		Store:    sqlite,
+		previous: []models.ProcessSnapshot{{ClientID: res.ClientID, FriendlyName: "nginx", RecordedAt: at, IsRunning: true, PID: &pid}},
+		current:  []models.ProcessSnapshot{{ClientID: res.ClientID, FriendlyName: "nginx", RecordedAt: at.Add(time.Minute)}},
+	}
+	// Built with NewEngine so the per-client state maps are the real ones.
+	e := newTestEngine(st)
+	mutes := scopedMuteState{processes: map[string]bool{}}
+	e.checkProcesses(res.ClientID, "web-1", mutes, 1)
+	// A heartbeat carries no new snapshots; the same transition must not
+	// alert twice.
+	e.checkProcesses(res.ClientID, "web-1", mutes, 1)
+
+	alerts, _, err := sqlite.ListAlerts(store.AlertFilter{ClientID: res.ClientID, AlertType: models.AlertTypeProcessDied}, 10, 0)
+	if err != nil {
+		t.Fatalf("list alerts: %v", err)
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("want one process_died alert, got %d", len(alerts))
+	}
+}
+
+func TestCheckProcessesIgnoresRecycledGroupWorkers(t *testing.T) {
+	sqlite := newTestStore(t)
+	res, err := sqlite.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := sqlite.UpsertWatchedProcesses(res.ClientID, []models.ProcessPayload{
+		{FriendlyName: "workers", MatchPattern: "gunicorn", MinInstances: 2},
+		{FriendlyName: "nginx", MatchPattern: "nginx"},
+	}); err != nil {
+		t.Fatalf("upsert watched: %v", err)
+	}
+	pid := func(v int32) *int32 { return &v }
+	snap := func(name string, p int32, n int, at time.Time) models.ProcessSnapshot {
+		return models.ProcessSnapshot{ClientID: res.ClientID, FriendlyName: name, RecordedAt: at, IsRunning: true, PID: pid(p), InstanceCount: n}
+	}
+	at := time.Now().UTC()
+	st := &processHistoryStore{
+		Store:    sqlite,
+		previous: []models.ProcessSnapshot{snap("workers", 200, 4, at), snap("nginx", 100, 1, at)},
+		current:  []models.ProcessSnapshot{snap("workers", 201, 4, at.Add(time.Minute)), snap("nginx", 101, 1, at.Add(time.Minute))},
+	}
+	e := newTestEngine(st)
+	e.checkProcesses(res.ClientID, "web-1", scopedMuteState{processes: map[string]bool{}}, 1)
+
+	alerts, _, err := sqlite.ListAlerts(store.AlertFilter{ClientID: res.ClientID, AlertType: models.AlertTypePIDChange}, 10, 0)
+	if err != nil {
+		t.Fatalf("list alerts: %v", err)
+	}
+	if len(alerts) != 1 || alerts[0].Target != "nginx" {
+		t.Fatalf("want one pid_change alert for nginx only, got %+v", alerts)
+	}
+}
+
+func TestProcessStartedIsOptIn(t *testing.T) {
+	sqlite := newTestStore(t)
+	res, err := sqlite.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := sqlite.UpsertWatchedProcesses(res.ClientID, []models.ProcessPayload{
+		{FriendlyName: "sshd", MatchPattern: "sshd"},
+		{FriendlyName: "telnetd", MatchPattern: "telnetd", AlertOnStart: true},
+	}); err != nil {
+		t.Fatalf("sync watched processes: %v", err)
+	}
+	st := &processHistoryStore{Store: sqlite}
+	e := newTestEngine(st)
+
+	pid := int32(4242)
+	at := time.Now().UTC()
+	step := func(running bool) {
+		st.previous = st.current
+		at = at.Add(time.Minute)
+		st.current = nil
+		for _, name := range []string{"sshd", "telnetd"} {
+			snap := models.ProcessSnapshot{ClientID: res.ClientID, FriendlyName: name, RecordedAt: at, IsRunning: running}
+			if running {
+				snap.PID = &pid
+			}
+			st.current = append(st.current, snap)
+		}
+		e.checkProcesses(res.ClientID, "web-1", scopedMuteState{processes: map[string]bool{}}, 1)
+	}
+	started := func() []models.Alert {
+		alerts, _, err := sqlite.ListAlerts(store.AlertFilter{ClientID: res.ClientID, AlertType: models.AlertTypeProcessStarted}, 10, 0)
+		if err != nil {
+			t.Fatalf("list alerts: %v", err)
+		}
+		return alerts
+	}
+
+	step(false)
+	step(true)
+	alerts := started()
+	if len(alerts) != 1 || !strings.Contains(alerts[0].Message, "'telnetd'") {
+		t.Fatalf("want one process_started alert for telnetd, got %+v", alerts)
+	}
+
+	if err := sqlite.SetSetting("process_started_alerts", "true"); err != nil {
+		t.Fatal(err)
+	}
+	step(false)
+	step(true)
+	if n := len(started()); n != 3 {
+		t.Fatalf("with process_started_alerts on, want 3 alerts in total, got %d", n)
+	}
+}
+
+func TestClientVersionAlerts(t *testing.T) {
+	st := newTestStore(t)
+	var clientID string
+	checkIn := func(v string) *models.Client {
+		res, err := st.UpsertClient(models.CheckInRequest{ClientID: clientID, Hostname: "web-1", SessionID: "boot-a", ClientVersion: v}, "")
+		if err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+		clientID = res.ClientID
+		c, err := st.GetClient(res.ClientID)
+		if err != nil {
+			t.Fatalf("get client: %v", err)
+		}
+		return c
+	}
+	count := func(alertType string) int {
+		_, n, err := st.ListAlerts(store.AlertFilter{AlertType: alertType}, 10, 0)
+		if err != nil {
+			t.Fatalf("list alerts: %v", err)
+		}
+		return n
+	}
+	e := newTestEngine(st)
+
+	for _, v := range []string{"v1.2.0", "v1.2.1", "dev"} {
+		e.checkClientVersion(checkIn(v), "web-1", "v1.3.0")
+	}
+	if got := count(models.AlertTypeClientOutdated); got != 1 {
+		t.Fatalf("client_outdated alerts = %d, want 1", got)
+	}
+	if got := count(models.AlertTypeClientUpdated); got != 1 {
+		t.Fatalf("a dev build should clear the outdated flag, got %d client_updated alerts", got)
+	}
+
+	e.checkClientVersion(checkIn("v1.1.0"), "web-1", "v1.3.0")
+	e.checkClientVersion(checkIn("v1.3.0"), "web-1", "v1.3.0")
+	e.checkClientVersion(checkIn("v1.3.0"), "web-1", "v1.3.0")
+	if got := count(models.AlertTypeClientOutdated); got != 2 {
+		t.Fatalf("client_outdated alerts = %d, want 2", got)
+	}
+	if got := count(models.AlertTypeClientUpdated); got != 2 {
+		t.Fatalf("client_updated alerts = %d, want 2", got)
+	}
+}
+
+func TestCountThresholds(t *testing.T) {
+	st := newTestStore(t)
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	e := newTestEngine(st)
+	if warn, _ := e.countThresholds("open_fds"); warn != 0 {
+		t.Fatalf("open file alerts should be off by default, warn = %v", warn)
+	}
+	st.SetSetting("open_fds_warn_default", "50000")
+	st.SetSetting("open_fds_crit_default", "100000")
+	warn, crit := e.countThresholds("open_fds")
+	if warn != 50000 || crit != 100000 {
+		t.Fatalf("thresholds = %v/%v, want 50000/100000", warn, crit)
+	}
+
+	last := func() *models.Alert {
+		a, err := st.GetLastAlertByTypes(res.ClientID, models.AlertTypeOpenFDsWarn, models.AlertTypeOpenFDsCrit, models.AlertTypeOpenFDsRecover)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	check := func(fds int64) {
+		recent := []models.Metric{{OpenFDs: &fds}}
+		e.checkThreshold(res.ClientID, "web-1", "open_fds", float64(fds), warn, crit, recent, 1, 0)
+	}
+	check(60000)
+	a := last()
+	if a == nil || a.AlertType != models.AlertTypeOpenFDsWarn || !strings.Contains(a.Message, "Open files at 60000") {
+		t.Fatalf("want open_fds_warn, got %+v", a)
+	}
+	check(1000)
+	if a := last(); a == nil || a.AlertType != models.AlertTypeOpenFDsRecover {
+		t.Fatalf("want open_fds_recover, got %+v", a)
+	}
+	// A host that stops reporting the count breaks the streak instead of
+	// counting as zero.
+	if got := consecutiveThresholdStreak([]models.Metric{{}}, "open_fds", 1); got != 0 {
+		t.Fatalf("streak with no reading = %d, want 0", got)
+	}
+}
+
+func TestCountThresholdsRespectCPUMute(t *testing.T) {
+	st := newTestStore(t)
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	for key, v := range map[string]string{"open_fds_warn_default": "50000", "process_count_warn_default": "500"} {
+		if err := st.SetSetting(key, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fds, procs := int64(60000), int64(800)
+	if err := st.InsertMetrics(res.ClientID, models.MetricsPayload{OpenFDs: &fds, ProcessCount: &procs}); err != nil {
+		t.Fatalf("insert metrics: %v", err)
+	}
+	if err := st.SetClientAlertMute(res.ClientID, "cpu", "", true); err != nil {
+		t.Fatal(err)
+	}
+	e := newTestEngine(st)
+	fired := func() int {
+		alerts, _, err := st.ListAlerts(store.AlertFilter{ClientID: res.ClientID}, 20, 0)
+		if err != nil {
+			t.Fatalf("list alerts: %v", err)
+		}
+		n := 0
+		for _, a := range alerts {
+			if a.AlertType == models.AlertTypeOpenFDsWarn || a.AlertType == models.AlertTypeProcCountWarn {
+				n++
+			}
+		}
+		return n
+	}
+
+	e.evaluateCheckIn(res.ClientID)
+	if n := fired(); n != 0 {
+		t.Fatalf("muted cpu alerts still fired %d count alerts", n)
+	}
+	if err := st.SetClientAlertMute(res.ClientID, "cpu", "", false); err != nil {
+		t.Fatal(err)
+	}
+	e.evaluateCheckIn(res.ClientID)
+	if n := fired(); n != 2 {
+		t.Fatalf("want open_fds_warn and process_count_warn once unmuted, got %d", n)
+	}
+}
+
+func TestInodeThresholds(t *testing.T) {
+	st := newTestStore(t)
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	e := newTestEngine(st)
+	warn, crit := e.inodeThresholds()
+	if warn != 90 || crit != 95 {
+		t.Fatalf("default thresholds = %v/%v, want 90/95", warn, crit)
+	}
+
+	pct := 96.0
+	recent := []models.Metric{{DiskInodesUsedPercent: &pct}}
+	e.checkThreshold(res.ClientID, "web-1", "inode", pct, warn, crit, recent, 1, 0)
+	a, err := st.GetLastAlertByTypes(res.ClientID, models.AlertTypeInodeWarn, models.AlertTypeInodeCrit, models.AlertTypeInodeRecover)
+	if err != nil || a == nil || a.AlertType != models.AlertTypeInodeCrit || !strings.Contains(a.Message, "Disk inode usage at 96.0%") {
+		t.Fatalf("want inode_crit, got %+v (err %v)", a, err)
+	}
+	if got := consecutiveThresholdStreak([]models.Metric{{}}, "inode", 1); got != 0 {
+		t.Fatalf("streak with no reading = %d, want 0", got)
+	}
+
+	st.SetSetting("disk_inodes_warn_pct_default", "0")
+	st.SetSetting("disk_inodes_crit_pct_default", "0")
+	if warn, _ := e.inodeThresholds(); !math.IsInf(warn, 1) {
+		t.Fatalf("zero settings should disable inode alerts, warn = %v", warn)
+	}
+}
+
+func TestProcessInstancesBelowMinimum(t *testing.T) {
+	sqlite := newTestStore(t)
+	res, err := sqlite.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := sqlite.UpsertWatchedProcesses(res.ClientID, []models.ProcessPayload{
+		{FriendlyName: "workers", MatchPattern: "gunicorn", MinInstances: 4},
+	}); err != nil {
+		t.Fatalf("sync watched processes: %v", err)
+	}
+	st := &processHistoryStore{Store: sqlite}
+	e := newTestEngine(st)
+
+	pid := int32(300)
+	at := time.Now().UTC()
+	step := func(count int) {
+		st.previous = st.current
+		at = at.Add(time.Minute)
+		snap := models.ProcessSnapshot{ClientID: res.ClientID, FriendlyName: "workers", RecordedAt: at, IsRunning: count > 0, InstanceCount: count}
+		if count > 0 {
+			snap.PID = &pid
+		}
+		st.current = []models.ProcessSnapshot{snap}
+		e.checkProcesses(res.ClientID, "web-1", scopedMuteState{processes: map[string]bool{}}, 1)
+	}
+	// Alerts fired in the same second have no stable order, so compare counts.
+	counts := func() map[string]int {
+		alerts, _, err := sqlite.ListAlerts(store.AlertFilter{ClientID: res.ClientID}, 20, 0)
+		if err != nil {
+			t.Fatalf("list alerts: %v", err)
+		}
+		out := make(map[string]int)
+		for _, a := range alerts {
+			out[a.AlertType]++
+		}
+		return out
+	}
+
+	step(4)
+	step(3)
+	step(2) // still low: no repeat
+	if got := counts(); got[models.AlertTypeInstancesLow] != 1 || len(got) != 1 {
+		t.Fatalf("after dropping below the minimum, alerts = %v", got)
+	}
+	step(4)
+	if got := counts(); got[models.AlertTypeInstancesRestored] != 1 {
+		t.Fatalf("want process_instances_recovered, alerts = %v", got)
+	}
+	step(0)
+	step(2) // back from a full stop, but short of the minimum
+	got := counts()
+	if got[models.AlertTypeProcessDied] != 1 || got[models.AlertTypeInstancesLow] != 2 || len(got) != 3 {
+		t.Fatalf("alerts = %v", got)
+	}
+}
+
+func TestAlertCooldownIsPerTarget(t *testing.T) {
+	st := newTestStore(t)
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := st.SetSetting("alert_cooldown_seconds", "300"); err != nil {
+		t.Fatal(err)
+	}
+	e := newTestEngine(st)
+	count := func(alertType string) int {
+		alerts, _, err := st.ListAlerts(store.AlertFilter{ClientID: res.ClientID, AlertType: alertType}, 20, 0)
+		if err != nil {
+			t.Fatalf("list alerts: %v", err)
+		}
+		return len(alerts)
+	}
+
+	e.fireTargetAlert(res.ClientID, models.AlertTypePIDChange, "api", models.SeverityWarning, "Process 'api' PID changed")
+	e.fireTargetAlert(res.ClientID, models.AlertTypePIDChange, "worker", models.SeverityWarning, "Process 'worker' PID changed")
+	e.fireTargetAlert(res.ClientID, models.AlertTypePIDChange, "api", models.SeverityWarning, "Process 'api' PID changed again")
+	if n := count(models.AlertTypePIDChange); n != 2 {
+		t.Fatalf("want one pid_change per process inside the cooldown, got %d", n)
+	}
+
+	e.fireTargetAlert(res.ClientID, models.AlertTypeProcessDied, "api", models.SeverityCritical, "Process 'api' has stopped")
+	e.fireTargetAlert(res.ClientID, models.AlertTypeProcessDied, "api", models.SeverityCritical, "Process 'api' has stopped")
+	if n := count(models.AlertTypeProcessDied); n != 1 {
+		t.Fatalf("critical alerts should be cooled down too, got %d", n)
+	}
+	alerts, _, _ := st.ListAlerts(store.AlertFilter{ClientID: res.ClientID, AlertType: models.AlertTypeProcessDied}, 1, 0)
+	if len(alerts) != 1 || alerts[0].Target != "api" {
+		t.Fatalf("target not stored: %+v", alerts)
+	}
+
+	// Checks sharing a name but not a type are cooled down separately.
+	e.fireTargetAlert(res.ClientID, models.AlertTypeCheckFailed, checkMuteTarget("site", "http"), models.SeverityCritical, "Check 'site' (http) failed")
+	e.fireTargetAlert(res.ClientID, models.AlertTypeCheckFailed, checkMuteTarget("site", "dns"), models.SeverityCritical, "Check 'site' (dns) failed")
+	e.fireTargetAlert(res.ClientID, models.AlertTypeCheckFailed, checkMuteTarget("site", "http"), models.SeverityCritical, "Check 'site' (http) failed")
+	if n := count(models.AlertTypeCheckFailed); n != 2 {
+		t.Fatalf("want one check_failed per check inside the cooldown, got %d", n)
+	}
+}
+
+func TestNotifySystemReportsUnroutedAlerts(t *testing.T) {
+	st := newTestStore(t)
+
+	var sent int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { sent++ }))
+	defer srv.Close()
+	p := &models.AlertProvider{Type: "gotify", Name: "push", Enabled: true,
+		Config: `{"server_url":"` + srv.URL + `","app_token":"t"}`}
+	if err := st.CreateProvider(p); err != nil {
+		t.Fatalf("create provider: %v", err)
+	}
+	if err := SaveAlertRoutes(st, []models.AlertRoute{{MinSeverity: models.SeverityCritical, ProviderIDs: []int64{p.ID}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	e := newTestEngine(st)
+	if e.NotifySystem(models.AlertTypeCertExpiry, models.SeverityWarning, "expires soon") {
+		t.Fatal("a notification routed to no provider should not be reported as sent")
+	}
+	if !e.NotifySystem(models.AlertTypeCertExpiry, models.SeverityCritical, "expires today") || sent != 1 {
+		t.Fatalf("expected the critical notification to be sent, got %d sends", sent)
+	}
+}
+
+// pruneStore records the retention windows cleanupOldData asks for.
+type pruneStore struct {
+	store.Store
+	keep store.RetentionWindows
+}
+
+func (s *pruneStore) PruneOldData(keep store.RetentionWindows) (int64, error) {
+	s.keep = keep
+	return 0, nil
+}
+
+func TestCleanupOldDataRetentionSettings(t *testing.T) {
+	st := newTestStore(t)
+	fake := &pruneStore{Store: st}
+	e := newTestEngine(fake)
+	day := 24 * time.Hour
+
+	e.cleanupOldData()
+	want := store.RetentionWindows{Metrics: 14 * day, Alerts: 14 * day, Audit: 365 * day, ProcessRestarts: 14 * day, Maintenance: 14 * day}
+	if fake.keep != want {
+		t.Fatalf("defaults: got %+v, want %+v", fake.keep, want)
+	}
+
+	for key, v := range map[string]string{
+		"metrics_retention_days":          "7",
+		"alerts_retention_days":           "30",
+		"process_restarts_retention_days": "3",
+		"maintenance_retention_days":      "90",
+	} {
+		if err := st.SetSetting(key, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e.cleanupOldData()
+	want = store.RetentionWindows{Metrics: 7 * day, Alerts: 30 * day, Audit: 365 * day, ProcessRestarts: 3 * day, Maintenance: 90 * day}
+	if fake.keep != want {
+		t.Fatalf("settings: got %+v, want %+v", fake.keep, want)
+	}
+}
@@ -0,0 +1,83 @@
+package alerting
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+type GotifyProvider struct {
+	ServerURL string `json:"server_url"`
+	AppToken  string `json:"app_token"`
+}
+
+type gotifyMessage struct {
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+	Priority int    `json:"priority"`
+}
+
+func (g *GotifyProvider) Name() string {
+	return "gotify"
+}
+
+func (g *GotifyProvider) Validate() error {
+	if g.ServerURL == "" {
+		return fmt.Errorf("server_url is required")
+	}
+	u, err := url.Parse(g.ServerURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("server_url must be an http(s) URL")
+	}
+	if g.AppToken == "" {
+		return fmt.Errorf("app_token is required")
+	}
+	return nil
+}
+
+func (g *GotifyProvider) Send(alert *models.Alert) error {
+	body, err := json.Marshal(gotifyMessage{
+		Title:    fmt.Sprintf("MachineMon %s", strings.ToUpper(alert.Severity)),
+		Message:  alert.Message,
+		Priority: gotifyPriority(alert.Severity),
+	})
+	if err != nil {
+		return fmt.Errorf("encode gotify message: %w", err)
+	}
+
+	apiURL := strings.TrimRight(g.ServerURL, "/") + "/message?token=" + url.QueryEscape(g.AppToken)
+	resp, err := http.Post(apiURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// The URL carries the token; don't let it end up in the logs.
+		if uerr, ok := err.(*url.Error); ok {
+			err = uerr.Err
+		}
+		return fmt.Errorf("send gotify: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("gotify API error (status %d): %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// gotifyPriority maps severities onto Gotify's 0-10 scale. The Android app
+// pops up a notification from 4 and plays a sound from 8.
+func gotifyPriority(severity string) int {
+	switch severity {
+	case models.SeverityCritical:
+		return 8
+	case models.SeverityWarning:
+		return 5
+	default:
+		return 2
+	}
+}
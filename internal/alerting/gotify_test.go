@@ -0,0 +1,54 @@
+package alerting
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestGotifySend(t *testing.T) {
+	var got gotifyMessage
+	var token string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/gotify/message" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		token = r.URL.Query().Get("token")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		w.Write([]byte(`{"id":1}`))
+	}))
+	defer srv.Close()
+
+	g := &GotifyProvider{ServerURL: srv.URL + "/gotify/", AppToken: "A1b2+c"}
+	if err := g.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	err := g.Send(&models.Alert{AlertType: models.AlertTypeOffline, Severity: models.SeverityCritical, Message: "web-1 is offline"})
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if token != "A1b2+c" {
+		t.Errorf("token = %q", token)
+	}
+	if got.Title != "MachineMon CRITICAL" || got.Message != "web-1 is offline" || got.Priority != 8 {
+		t.Errorf("message = %+v", got)
+	}
+}
+
+func TestGotifyValidate(t *testing.T) {
+	for _, g := range []GotifyProvider{
+		{AppToken: "t"},
+		{ServerURL: "gotify.local", AppToken: "t"},
+		{ServerURL: "ftp://gotify.local", AppToken: "t"},
+		{ServerURL: "https://gotify.local"},
+	} {
+		if err := g.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want error", g)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package alerting
+
+import (
+	"time"
+)
+
+// inMaintenance reports whether the client has a maintenance window covering
+// now. Lookup errors are logged and treated as no window so alerts still flow.
+func (e *Engine) inMaintenance(clientID string, now time.Time) bool {
+	w, err := e.store.GetActiveMaintenanceWindow(clientID, now)
+	if err != nil {
+		e.logger.Error("failed to check maintenance windows", "client_id", clientID, "err", err)
+		return false
+	}
+	return w != nil
+}
@@ -0,0 +1,158 @@
+package alerting
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+// pagerDutyEventsURL is a variable so tests can point it at a local server.
+var pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+type PagerDutyProvider struct {
+	RoutingKey string `json:"routing_key"`
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string         `json:"summary"`
+	Source        string         `json:"source"`
+	Severity      string         `json:"severity"`
+	Timestamp     string         `json:"timestamp,omitempty"`
+	Class         string         `json:"class"`
+	CustomDetails map[string]any `json:"custom_details,omitempty"`
+}
+
+// pagerDutyRecoveries maps recovery alert types that don't follow the
+// <metric>_recover naming to the alert type they resolve.
+var pagerDutyRecoveries = map[string]string{
+	models.AlertTypeOnline:             models.AlertTypeOffline,
+	models.AlertTypeReportingRecovered: models.AlertTypeReportingDegraded,
+	models.AlertTypeCheckRecovered:     models.AlertTypeCheckFailed,
+	models.AlertTypeDiskFillRecover:    models.AlertTypeDiskFillRate,
+}
+
+func (p *PagerDutyProvider) Name() string {
+	return "pagerduty"
+}
+
+func (p *PagerDutyProvider) Validate() error {
+	if p.RoutingKey == "" {
+		return fmt.Errorf("routing_key is required")
+	}
+	// Events API v2 integration keys are 32 characters.
+	if len(p.RoutingKey) != 32 {
+		return fmt.Errorf("routing_key must be a 32-character Events API v2 integration key")
+	}
+	return nil
+}
+
+func (p *PagerDutyProvider) Send(alert *models.Alert) error {
+	incident, resolve := pagerDutyIncident(alert.AlertType)
+	source := alert.ClientID
+	if source == "" {
+		source = "machinemon-server"
+	}
+
+	event := pagerDutyEvent{
+		RoutingKey:  p.RoutingKey,
+		EventAction: "trigger",
+		DedupKey:    fmt.Sprintf("machinemon:%s:%s", source, incident),
+	}
+	if resolve {
+		event.EventAction = "resolve"
+	} else {
+		summary := alert.Message
+		if len(summary) > 1024 {
+			summary = summary[:1024]
+		}
+		event.Payload = &pagerDutyPayload{
+			Summary:  summary,
+			Source:   source,
+			Severity: pagerDutySeverity(alert.Severity),
+			Class:    alert.AlertType,
+		}
+		if !alert.FiredAt.IsZero() {
+			event.Payload.Timestamp = alert.FiredAt.UTC().Format(time.RFC3339)
+		}
+		details := map[string]any{}
+		if alert.Details != "" {
+			details["details"] = alert.Details
+		}
+		if c := alert.Client; c != nil {
+			event.Payload.Source = c.Name()
+			details["client_id"] = alert.ClientID
+			details["hostname"] = c.Hostname
+			if c.CustomName != "" {
+				details["custom_name"] = c.CustomName
+			}
+			if c.PublicIP != "" {
+				details["public_ip"] = c.PublicIP
+			}
+			if len(c.InterfaceIPs) > 0 {
+				details["interface_ips"] = c.InterfaceIPs
+			}
+			if len(c.Tags) > 0 {
+				details["tags"] = c.Tags
+			}
+		}
+		if len(details) > 0 {
+			event.Payload.CustomDetails = details
+		}
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode pagerduty event: %w", err)
+	}
+	resp, err := http.Post(pagerDutyEventsURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("send pagerduty event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("pagerduty API error (status %d): %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// pagerDutyIncident returns the incident an alert type belongs to and whether
+// the alert resolves it. Warning and critical levels of the same metric share
+// an incident, so escalation updates it rather than opening a second one.
+func pagerDutyIncident(alertType string) (incident string, resolve bool) {
+	if base, ok := pagerDutyRecoveries[alertType]; ok {
+		return base, true
+	}
+	for _, suffix := range []string{"_warn", "_crit"} {
+		if base, ok := strings.CutSuffix(alertType, suffix); ok {
+			return base, false
+		}
+	}
+	if base, ok := strings.CutSuffix(alertType, "_recover"); ok {
+		return base, true
+	}
+	return alertType, false
+}
+
+func pagerDutySeverity(severity string) string {
+	switch severity {
+	case models.SeverityCritical, models.SeverityWarning:
+		return severity
+	default:
+		return "info"
+	}
+}
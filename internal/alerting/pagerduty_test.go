@@ -0,0 +1,105 @@
+package alerting
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestPagerDutyIncident(t *testing.T) {
+	cases := []struct {
+		alertType string
+		incident  string
+		resolve   bool
+	}{
+		{models.AlertTypeCPUWarn, "cpu", false},
+		{models.AlertTypeCPUCrit, "cpu", false},
+		{models.AlertTypeCPURecover, "cpu", true},
+		{models.AlertTypeMountCrit, "disk_mount", false},
+		{models.AlertTypeMountRecover, "disk_mount", true},
+		{models.AlertTypeOffline, models.AlertTypeOffline, false},
+		{models.AlertTypeOnline, models.AlertTypeOffline, true},
+		{models.AlertTypeCheckRecovered, models.AlertTypeCheckFailed, true},
+		{models.AlertTypeDiskFillRate, models.AlertTypeDiskFillRate, false},
+		{models.AlertTypeDiskFillRecover, models.AlertTypeDiskFillRate, true},
+		{models.AlertTypeProcessDied, models.AlertTypeProcessDied, false},
+	}
+	for _, tc := range cases {
+		incident, resolve := pagerDutyIncident(tc.alertType)
+		if incident != tc.incident || resolve != tc.resolve {
+			t.Errorf("pagerDutyIncident(%q) = %q, %v; want %q, %v", tc.alertType, incident, resolve, tc.incident, tc.resolve)
+		}
+	}
+}
+
+func TestPagerDutySendTriggerAndResolve(t *testing.T) {
+	var events []pagerDutyEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev pagerDutyEvent
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			t.Errorf("decode event: %v", err)
+		}
+		events = append(events, ev)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	defer func(u string) { pagerDutyEventsURL = u }(pagerDutyEventsURL)
+	pagerDutyEventsURL = srv.URL
+
+	p := &PagerDutyProvider{RoutingKey: "0123456789abcdef0123456789abcdef"}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	for _, a := range []*models.Alert{
+		{ClientID: "c1", AlertType: models.AlertTypeCPUWarn, Severity: models.SeverityWarning, Message: "CPU 85%",
+			Client: &models.AlertClient{Hostname: "web-1", CustomName: "Web", PublicIP: "198.51.100.7", Tags: []string{"prod"}}},
+		{ClientID: "c1", AlertType: models.AlertTypeCPUCrit, Severity: models.SeverityCritical, Message: "CPU 97%"},
+		{ClientID: "c1", AlertType: models.AlertTypeCPURecover, Severity: models.SeverityInfo, Message: "CPU ok"},
+	} {
+		if err := p.Send(a); err != nil {
+			t.Fatalf("send %s: %v", a.AlertType, err)
+		}
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+	for _, ev := range events {
+		if ev.DedupKey != events[0].DedupKey {
+			t.Errorf("dedup key %q differs from %q", ev.DedupKey, events[0].DedupKey)
+		}
+	}
+	if pl := events[0].Payload; pl == nil || pl.Source != "Web" || pl.CustomDetails["hostname"] != "web-1" ||
+		pl.CustomDetails["public_ip"] != "198.51.100.7" || pl.CustomDetails["client_id"] != "c1" {
+		t.Errorf("client context missing from payload: %+v", events[0].Payload)
+	}
+	if events[1].Payload == nil || events[1].Payload.Source != "c1" {
+		t.Errorf("alert without client context should use the client ID as source: %+v", events[1].Payload)
+	}
+	if events[1].EventAction != "trigger" || events[1].Payload == nil || events[1].Payload.Severity != "critical" {
+		t.Errorf("escalation event = %+v", events[1])
+	}
+	if events[2].EventAction != "resolve" || events[2].Payload != nil {
+		t.Errorf("recovery event = %+v", events[2])
+	}
+}
+
+func TestPagerDutySendRejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"status":"invalid event"}`, http.StatusBadRequest)
+	}))
+	defer srv.Close()
+	defer func(u string) { pagerDutyEventsURL = u }(pagerDutyEventsURL)
+	pagerDutyEventsURL = srv.URL
+
+	p := &PagerDutyProvider{RoutingKey: "0123456789abcdef0123456789abcdef"}
+	if err := p.Send(&models.Alert{ClientID: "c1", AlertType: models.AlertTypeOffline, Severity: models.SeverityCritical}); err == nil {
+		t.Fatal("expected an error for a rejected event")
+	}
+	if err := (&PagerDutyProvider{RoutingKey: "short"}).Validate(); err == nil {
+		t.Fatal("expected a short routing key to fail validation")
+	}
+}
@@ -0,0 +1,70 @@
+package alerting
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// quietHours describes a daily window during which non-critical notifications
+// are suppressed. Start and End are minutes since midnight in Location.
+type quietHours struct {
+	Start    int
+	End      int
+	Location *time.Location
+}
+
+// parseQuietHours builds a quietHours window from the quiet_hours_start,
+// quiet_hours_end and quiet_hours_tz settings. It returns nil when quiet
+// hours are not configured (either bound empty, or start equal to end).
+func parseQuietHours(start, end, tz string) (*quietHours, error) {
+	start = strings.TrimSpace(start)
+	end = strings.TrimSpace(end)
+	if start == "" || end == "" {
+		return nil, nil
+	}
+
+	startMin, err := parseClockMinutes(start)
+	if err != nil {
+		return nil, fmt.Errorf("quiet_hours_start: %w", err)
+	}
+	endMin, err := parseClockMinutes(end)
+	if err != nil {
+		return nil, fmt.Errorf("quiet_hours_end: %w", err)
+	}
+	if startMin == endMin {
+		return nil, nil
+	}
+
+	loc := time.Local
+	if tz = strings.TrimSpace(tz); tz != "" {
+		loc, err = time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("quiet_hours_tz: %w", err)
+		}
+	}
+	return &quietHours{Start: startMin, End: endMin, Location: loc}, nil
+}
+
+// Contains reports whether t falls inside the quiet window. Windows where
+// Start is after End wrap past midnight (e.g. 22:00-07:00).
+func (q *quietHours) Contains(t time.Time) bool {
+	if q == nil {
+		return false
+	}
+	local := t.In(q.Location)
+	m := local.Hour()*60 + local.Minute()
+	if q.Start < q.End {
+		return m >= q.Start && m < q.End
+	}
+	return m >= q.Start || m < q.End
+}
+
+// parseClockMinutes parses an "HH:MM" 24-hour clock value into minutes since midnight.
+func parseClockMinutes(v string) (int, error) {
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM, got %q", v)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,94 @@
+package alerting
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuietHoursSameDayWindow(t *testing.T) {
+	q, err := parseQuietHours("09:00", "17:30", "UTC")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	cases := []struct {
+		at   time.Time
+		want bool
+	}{
+		{time.Date(2026, 3, 1, 8, 59, 0, 0, time.UTC), false},
+		{time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC), true},
+		{time.Date(2026, 3, 1, 17, 29, 0, 0, time.UTC), true},
+		{time.Date(2026, 3, 1, 17, 30, 0, 0, time.UTC), false},
+	}
+	for _, tc := range cases {
+		if got := q.Contains(tc.at); got != tc.want {
+			t.Fatalf("Contains(%s) = %v, want %v", tc.at.Format("15:04"), got, tc.want)
+		}
+	}
+}
+
+func TestQuietHoursWrapsPastMidnight(t *testing.T) {
+	q, err := parseQuietHours("22:00", "07:00", "UTC")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	cases := []struct {
+		at   time.Time
+		want bool
+	}{
+		{time.Date(2026, 3, 1, 21, 59, 0, 0, time.UTC), false},
+		{time.Date(2026, 3, 1, 22, 0, 0, 0, time.UTC), true},
+		{time.Date(2026, 3, 1, 23, 59, 0, 0, time.UTC), true},
+		{time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), true},
+		{time.Date(2026, 3, 2, 3, 0, 0, 0, time.UTC), true},
+		{time.Date(2026, 3, 2, 6, 59, 0, 0, time.UTC), true},
+		{time.Date(2026, 3, 2, 7, 0, 0, 0, time.UTC), false},
+		{time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC), false},
+	}
+	for _, tc := range cases {
+		if got := q.Contains(tc.at); got != tc.want {
+			t.Fatalf("Contains(%s) = %v, want %v", tc.at.Format("15:04"), got, tc.want)
+		}
+	}
+}
+
+func TestQuietHoursUsesConfiguredTimezone(t *testing.T) {
+	q, err := parseQuietHours("22:00", "07:00", "America/New_York")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	// 03:00 UTC is 22:00 EST the previous evening.
+	if !q.Contains(time.Date(2026, 1, 15, 3, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected 03:00 UTC to be inside New York quiet hours")
+	}
+	// 13:00 UTC is 08:00 EST.
+	if q.Contains(time.Date(2026, 1, 15, 13, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected 13:00 UTC to be outside New York quiet hours")
+	}
+}
+
+func TestQuietHoursDisabled(t *testing.T) {
+	for _, tc := range [][2]string{{"", ""}, {"22:00", ""}, {"", "07:00"}, {"08:00", "08:00"}} {
+		q, err := parseQuietHours(tc[0], tc[1], "")
+		if err != nil {
+			t.Fatalf("parse %v: %v", tc, err)
+		}
+		if q != nil {
+			t.Fatalf("expected quiet hours disabled for %v", tc)
+		}
+		if q.Contains(time.Now()) {
+			t.Fatalf("disabled quiet hours should never contain a time")
+		}
+	}
+}
+
+func TestQuietHoursInvalidInput(t *testing.T) {
+	if _, err := parseQuietHours("25:00", "07:00", ""); err == nil {
+		t.Fatalf("expected error for invalid start")
+	}
+	if _, err := parseQuietHours("22:00", "7am", ""); err == nil {
+		t.Fatalf("expected error for invalid end")
+	}
+	if _, err := parseQuietHours("22:00", "07:00", "Not/AZone"); err == nil {
+		t.Fatalf("expected error for invalid timezone")
+	}
+}
@@ -0,0 +1,108 @@
+package alerting
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/machinemon/machinemon/internal/models"
+	"github.com/machinemon/machinemon/internal/store"
+)
+
+// alertRoutesSetting holds the routing table as a JSON array of
+// models.AlertRoute. Empty means every alert goes to every enabled provider.
+const alertRoutesSetting = "alert_routes"
+
+var severityRank = map[string]int{
+	models.SeverityInfo:     0,
+	models.SeverityWarning:  1,
+	models.SeverityCritical: 2,
+}
+
+// LoadAlertRoutes returns the configured routing table.
+func LoadAlertRoutes(st store.Store) ([]models.AlertRoute, error) {
+	raw, err := st.GetSetting(alertRoutesSetting)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var routes []models.AlertRoute
+	if err := json.Unmarshal([]byte(raw), &routes); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", alertRoutesSetting, err)
+	}
+	return routes, nil
+}
+
+// SaveAlertRoutes stores the routing table; nil or empty clears it.
+func SaveAlertRoutes(st store.Store, routes []models.AlertRoute) error {
+	if len(routes) == 0 {
+		return st.SetSetting(alertRoutesSetting, "")
+	}
+	b, err := json.Marshal(routes)
+	if err != nil {
+		return err
+	}
+	return st.SetSetting(alertRoutesSetting, string(b))
+}
+
+// ValidateAlertRoutes checks each route's pattern and severity and that every
+// provider it names exists.
+func ValidateAlertRoutes(routes []models.AlertRoute, providers []models.AlertProvider) error {
+	known := make(map[int64]bool, len(providers))
+	for _, p := range providers {
+		known[p.ID] = true
+	}
+	for i, r := range routes {
+		if _, err := path.Match(r.AlertType, ""); err != nil {
+			return fmt.Errorf("route %d: invalid alert_type pattern %q", i+1, r.AlertType)
+		}
+		if _, ok := severityRank[r.MinSeverity]; r.MinSeverity != "" && !ok {
+			return fmt.Errorf("route %d: min_severity must be info, warning, or critical", i+1)
+		}
+		if len(r.ProviderIDs) == 0 {
+			return fmt.Errorf("route %d: provider_ids is required", i+1)
+		}
+		for _, id := range r.ProviderIDs {
+			if !known[id] {
+				return fmt.Errorf("route %d: provider %d does not exist", i+1, id)
+			}
+		}
+	}
+	return nil
+}
+
+func routeMatches(r models.AlertRoute, alert *models.Alert) bool {
+	if r.AlertType != "" {
+		if ok, _ := path.Match(r.AlertType, alert.AlertType); !ok {
+			return false
+		}
+	}
+	return severityRank[alert.Severity] >= severityRank[r.MinSeverity]
+}
+
+// routeProviders narrows providers to those the routing table sends alert to.
+// Without routes every provider is used; with routes, an alert that matches
+// none of them isn't sent anywhere.
+func routeProviders(routes []models.AlertRoute, providers []models.AlertProvider, alert *models.Alert) []models.AlertProvider {
+	if len(routes) == 0 {
+		return providers
+	}
+	wanted := make(map[int64]bool)
+	for _, r := range routes {
+		if routeMatches(r, alert) {
+			for _, id := range r.ProviderIDs {
+				wanted[id] = true
+			}
+		}
+	}
+	var out []models.AlertProvider
+	for _, p := range providers {
+		if wanted[p.ID] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,60 @@
+package alerting
+
+import (
+	"testing"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestRouteProviders(t *testing.T) {
+	providers := []models.AlertProvider{{ID: 1, Name: "pager"}, {ID: 2, Name: "chat"}, {ID: 3, Name: "email"}}
+	routes := []models.AlertRoute{
+		{MinSeverity: models.SeverityCritical, ProviderIDs: []int64{1}},
+		{MinSeverity: models.SeverityWarning, ProviderIDs: []int64{2}},
+		{AlertType: "disk_*", ProviderIDs: []int64{3}},
+	}
+	names := func(ps []models.AlertProvider) string {
+		out := ""
+		for _, p := range ps {
+			out += p.Name + " "
+		}
+		return out
+	}
+
+	cases := []struct {
+		alert models.Alert
+		want  string
+	}{
+		{models.Alert{AlertType: models.AlertTypeOffline, Severity: models.SeverityCritical}, "pager chat "},
+		{models.Alert{AlertType: models.AlertTypeCPUWarn, Severity: models.SeverityWarning}, "chat "},
+		{models.Alert{AlertType: models.AlertTypeDiskRecover, Severity: models.SeverityInfo}, "email "},
+		{models.Alert{AlertType: models.AlertTypeDiskCrit, Severity: models.SeverityCritical}, "pager chat email "},
+		{models.Alert{AlertType: models.AlertTypeOnline, Severity: models.SeverityInfo}, ""},
+	}
+	for _, tc := range cases {
+		if got := names(routeProviders(routes, providers, &tc.alert)); got != tc.want {
+			t.Errorf("%s/%s routed to %q, want %q", tc.alert.AlertType, tc.alert.Severity, got, tc.want)
+		}
+	}
+
+	if got := routeProviders(nil, providers, &models.Alert{Severity: models.SeverityInfo}); len(got) != 3 {
+		t.Errorf("without routes every provider should be used, got %d", len(got))
+	}
+}
+
+func TestValidateAlertRoutes(t *testing.T) {
+	providers := []models.AlertProvider{{ID: 1}}
+	if err := ValidateAlertRoutes([]models.AlertRoute{{AlertType: "cpu_*", MinSeverity: "warning", ProviderIDs: []int64{1}}}, providers); err != nil {
+		t.Fatalf("valid route: %v", err)
+	}
+	for _, r := range []models.AlertRoute{
+		{AlertType: "cpu_[", ProviderIDs: []int64{1}},
+		{MinSeverity: "urgent", ProviderIDs: []int64{1}},
+		{AlertType: "cpu_*"},
+		{ProviderIDs: []int64{2}},
+	} {
+		if err := ValidateAlertRoutes([]models.AlertRoute{r}, providers); err == nil {
+			t.Errorf("ValidateAlertRoutes(%+v) = nil, want error", r)
+		}
+	}
+}
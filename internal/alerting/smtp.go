@@ -1,10 +1,14 @@
 package alerting
 
 import (
+	"bytes"
 	"crypto/tls"
 	"fmt"
+	"html"
+	"mime/multipart"
 	"net"
 	"net/smtp"
+	"net/textproto"
 	"strings"
 
 	"github.com/machinemon/machinemon/internal/models"
@@ -18,6 +22,22 @@ type SMTPProvider struct {
 	From     string `json:"from"`
 	To       string `json:"to"`
 	UseTLS   bool   `json:"use_tls"`
+	// UseStartTLS connects in plaintext and upgrades with STARTTLS before
+	// authenticating, as required by most relays on port 587.
+	UseStartTLS bool `json:"use_starttls"`
+	// HTML adds a styled text/html part next to the plain-text body.
+	HTML bool `json:"html"`
+}
+
+// recipients splits the comma-separated To field, dropping blanks.
+func (s *SMTPProvider) recipients() []string {
+	var out []string
+	for _, addr := range strings.Split(s.To, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			out = append(out, addr)
+		}
+	}
+	return out
 }
 
 func (s *SMTPProvider) Name() string {
@@ -34,16 +54,21 @@ func (s *SMTPProvider) Validate() error {
 	if s.From == "" {
 		return fmt.Errorf("from address is required")
 	}
-	if s.To == "" {
+	if len(s.recipients()) == 0 {
 		return fmt.Errorf("to address is required")
 	}
+	if s.UseStartTLS && s.implicitTLS() {
+		return fmt.Errorf("use_starttls cannot be combined with implicit TLS (use_tls or port 465)")
+	}
 	return nil
 }
 
 func (s *SMTPProvider) Send(alert *models.Alert) error {
-	subject := fmt.Sprintf("[MachineMon %s] %s", strings.ToUpper(alert.Severity), alert.AlertType)
-	body := fmt.Sprintf("Subject: %s\r\nFrom: MachineMon <%s>\r\nTo: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n\r\nFired at: %s\r\n",
-		subject, s.From, s.To, alert.Message, alert.FiredAt.Format("2006-01-02 15:04:05 UTC"))
+	body, err := s.buildMessage(alert)
+	if err != nil {
+		return err
+	}
+	to := s.recipients()
 
 	addr := fmt.Sprintf("%s:%d", s.Host, s.Port)
 
@@ -52,14 +77,83 @@ func (s *SMTPProvider) Send(alert *models.Alert) error {
 		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
 	}
 
-	if s.UseTLS || s.Port == 465 {
-		return s.sendTLS(addr, auth, []byte(body))
+	switch {
+	case s.UseStartTLS:
+		return s.sendStartTLS(addr, auth, to, body)
+	case s.implicitTLS():
+		return s.sendTLS(addr, auth, to, body)
 	}
 
-	return smtp.SendMail(addr, auth, s.From, []string{s.To}, []byte(body))
+	return smtp.SendMail(addr, auth, s.From, to, body)
+}
+
+func (s *SMTPProvider) implicitTLS() bool {
+	return s.UseTLS || s.Port == 465
 }
 
-func (s *SMTPProvider) sendTLS(addr string, auth smtp.Auth, msg []byte) error {
+// buildMessage renders the alert as a plain-text email, or as a
+// multipart/alternative one with an HTML part when HTML is enabled.
+func (s *SMTPProvider) buildMessage(alert *models.Alert) ([]byte, error) {
+	subject := fmt.Sprintf("[MachineMon %s] %s", strings.ToUpper(alert.Severity), alert.AlertType)
+	firedAt := alert.FiredAt.Format("2006-01-02 15:04:05 UTC")
+	text := fmt.Sprintf("%s\r\n\r\nFired at: %s\r\n", alert.Message, firedAt)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Subject: %s\r\nFrom: MachineMon <%s>\r\nTo: %s\r\nMIME-Version: 1.0\r\n",
+		subject, s.From, strings.Join(s.recipients(), ", "))
+	if !s.HTML {
+		fmt.Fprintf(&buf, "Content-Type: text/plain; charset=utf-8\r\n\r\n%s", text)
+		return buf.Bytes(), nil
+	}
+
+	var parts bytes.Buffer
+	mw := multipart.NewWriter(&parts)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", alertHTML(alert, firedAt)},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return nil, fmt.Errorf("build email: %w", err)
+		}
+		if _, err := pw.Write([]byte(part.body)); err != nil {
+			return nil, fmt.Errorf("build email: %w", err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("build email: %w", err)
+	}
+	buf.Write(parts.Bytes())
+	return buf.Bytes(), nil
+}
+
+func severityColor(severity string) string {
+	switch severity {
+	case models.SeverityCritical:
+		return "#dc2626"
+	case models.SeverityWarning:
+		return "#d97706"
+	default:
+		return "#2563eb"
+	}
+}
+
+func alertHTML(alert *models.Alert, firedAt string) string {
+	color := severityColor(alert.Severity)
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html><body style="font-family:-apple-system,Segoe UI,Helvetica,Arial,sans-serif;color:#111827;">
+<div style="border-left:4px solid %s;padding:12px 16px;">
+<div style="color:%s;font-weight:600;text-transform:uppercase;font-size:12px;">%s &middot; %s</div>
+<p style="font-size:15px;margin:8px 0;">%s</p>
+<div style="color:#6b7280;font-size:12px;">Fired at %s</div>
+</div>
+</body></html>
+`, color, color, html.EscapeString(alert.Severity), html.EscapeString(alert.AlertType),
+		html.EscapeString(alert.Message), html.EscapeString(firedAt))
+}
+
+func (s *SMTPProvider) sendTLS(addr string, auth smtp.Auth, to []string, msg []byte) error {
 	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: s.Host})
 	if err != nil {
 		return fmt.Errorf("tls dial: %w", err)
@@ -68,10 +162,32 @@ func (s *SMTPProvider) sendTLS(addr string, auth smtp.Auth, msg []byte) error {
 	host, _, _ := net.SplitHostPort(addr)
 	c, err := smtp.NewClient(conn, host)
 	if err != nil {
+		conn.Close()
 		return fmt.Errorf("smtp client: %w", err)
 	}
 	defer c.Close()
+	return s.deliver(c, auth, to, msg)
+}
 
+func (s *SMTPProvider) sendStartTLS(addr string, auth smtp.Auth, to []string, msg []byte) error {
+	c, err := smtp.Dial(addr)
+	if err != nil {
+		return fmt.Errorf("smtp dial: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); !ok {
+		return fmt.Errorf("smtp server does not support STARTTLS")
+	}
+	if err := c.StartTLS(&tls.Config{ServerName: s.Host}); err != nil {
+		return fmt.Errorf("smtp starttls: %w", err)
+	}
+	return s.deliver(c, auth, to, msg)
+}
+
+// deliver authenticates on an established (and already encrypted) session
+// and sends msg to every recipient.
+func (s *SMTPProvider) deliver(c *smtp.Client, auth smtp.Auth, to []string, msg []byte) error {
 	if auth != nil {
 		if err := c.Auth(auth); err != nil {
 			return fmt.Errorf("smtp auth: %w", err)
@@ -80,8 +196,10 @@ func (s *SMTPProvider) sendTLS(addr string, auth smtp.Auth, msg []byte) error {
 	if err := c.Mail(s.From); err != nil {
 		return fmt.Errorf("smtp mail: %w", err)
 	}
-	if err := c.Rcpt(s.To); err != nil {
-		return fmt.Errorf("smtp rcpt: %w", err)
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("smtp rcpt %s: %w", rcpt, err)
+		}
 	}
 	w, err := c.Data()
 	if err != nil {
@@ -0,0 +1,126 @@
+package alerting
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestSMTPRecipients(t *testing.T) {
+	p := &SMTPProvider{To: " ops@example.com, ,oncall@example.com ,"}
+	got := p.recipients()
+	if len(got) != 2 || got[0] != "ops@example.com" || got[1] != "oncall@example.com" {
+		t.Fatalf("recipients = %q", got)
+	}
+
+	p = &SMTPProvider{Host: "smtp.example.com", Port: 587, From: "mm@example.com", To: " , "}
+	if err := p.Validate(); err == nil {
+		t.Fatal("expected a blank recipient list to fail validation")
+	}
+}
+
+func TestSMTPBuildMessage(t *testing.T) {
+	alert := &models.Alert{
+		AlertType: models.AlertTypeCPUCrit,
+		Severity:  models.SeverityCritical,
+		Message:   "web-1 CPU at 97% <load>",
+		FiredAt:   time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	p := &SMTPProvider{From: "mm@example.com", To: "a@example.com,b@example.com"}
+
+	msg, err := p.buildMessage(alert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := string(msg)
+	if !strings.Contains(plain, "To: a@example.com, b@example.com\r\n") {
+		t.Errorf("missing joined To header:\n%s", plain)
+	}
+	if !strings.Contains(plain, "Content-Type: text/plain") || strings.Contains(plain, "multipart") {
+		t.Errorf("expected a plain-text message by default:\n%s", plain)
+	}
+
+	p.HTML = true
+	msg, err = p.buildMessage(alert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rich := string(msg)
+	for _, want := range []string{
+		"Content-Type: multipart/alternative; boundary=",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Type: text/html; charset=utf-8",
+		"web-1 CPU at 97% &lt;load&gt;",
+		severityColor(models.SeverityCritical),
+	} {
+		if !strings.Contains(rich, want) {
+			t.Errorf("HTML message missing %q:\n%s", want, rich)
+		}
+	}
+}
+
+func TestSMTPStartTLSValidation(t *testing.T) {
+	base := SMTPProvider{Host: "smtp.example.com", Port: 587, From: "mm@example.com", To: "ops@example.com", UseStartTLS: true}
+	if err := base.Validate(); err != nil {
+		t.Fatalf("STARTTLS on 587: %v", err)
+	}
+
+	withTLS := base
+	withTLS.UseTLS = true
+	if err := withTLS.Validate(); err == nil {
+		t.Error("expected use_starttls with use_tls to fail validation")
+	}
+
+	on465 := base
+	on465.Port = 465
+	if err := on465.Validate(); err == nil {
+		t.Error("expected use_starttls on port 465 to fail validation")
+	}
+}
+
+func TestSMTPStartTLSRequiresServerSupport(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// A relay that never advertises STARTTLS must not receive credentials or
+	// mail over the plaintext connection.
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		conn.Write([]byte("220 test ESMTP\r\n"))
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch strings.ToUpper(strings.Fields(line)[0]) {
+			case "EHLO":
+				conn.Write([]byte("250-test\r\n250 AUTH PLAIN\r\n"))
+			case "QUIT":
+				conn.Write([]byte("221 bye\r\n"))
+				return
+			default:
+				t.Errorf("unexpected command over plaintext: %q", line)
+				conn.Write([]byte("502 no\r\n"))
+			}
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	p := &SMTPProvider{Host: "127.0.0.1", Port: addr.Port, From: "mm@example.com", To: "ops@example.com", UseStartTLS: true}
+	err = p.Send(&models.Alert{AlertType: models.AlertTypeCPUCrit, Severity: models.SeverityCritical, Message: "x"})
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("Send error = %v, want missing STARTTLS", err)
+	}
+}
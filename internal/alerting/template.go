@@ -0,0 +1,82 @@
+package alerting
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+// messageData is what a provider's message template is executed against.
+// Client fields are empty for alerts that aren't tied to a client.
+type messageData struct {
+	AlertType string
+	Severity  string
+	Message   string
+	Details   string
+	FiredAt   time.Time
+	ClientID  string
+	// Name is the custom name when one is set, otherwise the hostname.
+	Name       string
+	Hostname   string
+	CustomName string
+	PublicIP   string
+}
+
+// sampleMessageData is used to check templates when a provider is saved.
+var sampleMessageData = messageData{
+	AlertType:  models.AlertTypeCPUCrit,
+	Severity:   models.SeverityCritical,
+	Message:    "CPU at 97%",
+	FiredAt:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	ClientID:   "00000000-0000-0000-0000-000000000000",
+	Name:       "web-1",
+	Hostname:   "web-1",
+	CustomName: "",
+	PublicIP:   "203.0.113.10",
+}
+
+func newMessageData(alert *models.Alert) *messageData {
+	data := &messageData{
+		AlertType: alert.AlertType,
+		Severity:  alert.Severity,
+		Message:   alert.Message,
+		Details:   alert.Details,
+		FiredAt:   alert.FiredAt,
+		ClientID:  alert.ClientID,
+	}
+	if c := alert.Client; c != nil {
+		data.Name = c.Name()
+		data.Hostname = c.Hostname
+		data.CustomName = c.CustomName
+		data.PublicIP = c.PublicIP
+	}
+	return data
+}
+
+// renderMessage executes a provider's message template. Unknown fields are
+// errors rather than "<no value>" so typos show up when the provider is saved.
+func renderMessage(text string, data *messageData) (string, error) {
+	tmpl, err := template.New("message").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+func validateMessageTemplate(text string) error {
+	if text == "" {
+		return nil
+	}
+	data := sampleMessageData
+	if _, err := renderMessage(text, &data); err != nil {
+		return fmt.Errorf("invalid message_template: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package alerting
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestValidateMessageTemplate(t *testing.T) {
+	if err := validateMessageTemplate(""); err != nil {
+		t.Fatalf("empty template: %v", err)
+	}
+	if err := validateMessageTemplate("{{.Name}} ({{.PublicIP}}): {{.Message}}"); err != nil {
+		t.Fatalf("valid template: %v", err)
+	}
+	for _, bad := range []string{"{{.Name", "{{.Hostnme}}"} {
+		if err := validateMessageTemplate(bad); err == nil {
+			t.Errorf("validateMessageTemplate(%q) = nil, want error", bad)
+		}
+	}
+}
+
+func TestDispatchRendersProviderTemplate(t *testing.T) {
+	st := newTestStore(t)
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a"}, "198.51.100.7")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := st.SetClientCustomName(res.ClientID, "Web Frontend"); err != nil {
+		t.Fatalf("custom name: %v", err)
+	}
+
+	got := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m gotifyMessage
+		json.NewDecoder(r.Body).Decode(&m)
+		got[r.URL.Query().Get("token")] = m.Message
+	}))
+	defer srv.Close()
+
+	for _, p := range []models.AlertProvider{
+		{Type: "gotify", Name: "plain", Enabled: true, Config: `{"server_url":"` + srv.URL + `","app_token":"plain"}`},
+		{Type: "gotify", Name: "templated", Enabled: true, Config: `{"server_url":"` + srv.URL + `","app_token":"templated"}`,
+			MessageTemplate: "{{.Name}} [{{.Hostname}} {{.PublicIP}}] {{.Severity}}: {{.Message}}"},
+		{Type: "gotify", Name: "broken", Enabled: true, Config: `{"server_url":"` + srv.URL + `","app_token":"broken"}`,
+			MessageTemplate: "{{.Missing}}"},
+	} {
+		if err := st.CreateProvider(&p); err != nil {
+			t.Fatalf("create provider: %v", err)
+		}
+	}
+
+	d := NewDispatcher(st, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	alert := &models.Alert{ClientID: res.ClientID, AlertType: models.AlertTypeCPUCrit, Severity: models.SeverityCritical, Message: "CPU at 97%"}
+	if err := d.send(alert); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	if got["plain"] != "CPU at 97%" {
+		t.Errorf("plain provider message = %q", got["plain"])
+	}
+	if want := "Web Frontend [web-1 198.51.100.7] critical: CPU at 97%"; got["templated"] != want {
+		t.Errorf("templated provider message = %q, want %q", got["templated"], want)
+	}
+	if got["broken"] != "CPU at 97%" {
+		t.Errorf("a failing template should fall back to the default message, got %q", got["broken"])
+	}
+	if alert.Message != "CPU at 97%" {
+		t.Errorf("shared alert was modified: %q", alert.Message)
+	}
+}
@@ -0,0 +1,69 @@
+package alerting
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/machinemon/machinemon/internal/models"
+	"github.com/machinemon/machinemon/internal/store"
+)
+
+// Built-in defaults for the non-percentage thresholds.
+const (
+	defaultOfflineThresholdSeconds   = 240 // 4 minutes
+	defaultMetricConsecutiveCheckins = 1
+	defaultAlertCooldownSeconds      = 0 // disabled
+)
+
+// ResolveEffectiveThresholds resolves every threshold the engine applies to
+// client: built-in default, then the global setting, then the per-client
+// override. client may be nil to resolve global values only.
+func ResolveEffectiveThresholds(st store.Store, client *models.Client) models.EffectiveThresholds {
+	if client == nil {
+		client = &models.Client{}
+	}
+	pct := func(def float64, key string, override *float64) models.ResolvedThreshold {
+		r := models.ResolvedThreshold{Value: def, Source: models.ThresholdSourceDefault}
+		if raw, _ := st.GetSetting(key); raw != "" {
+			if v, err := strconv.ParseFloat(strings.TrimSpace(raw), 64); err == nil {
+				r = models.ResolvedThreshold{Value: v, Source: models.ThresholdSourceGlobal}
+			}
+		}
+		if override != nil {
+			r = models.ResolvedThreshold{Value: *override, Source: models.ThresholdSourceClient}
+		}
+		return r
+	}
+	// count resolves an integer setting; values below min are ignored at both
+	// the global and client level.
+	count := func(def int, key string, override *int, min int) models.ResolvedThreshold {
+		r := models.ResolvedThreshold{Value: float64(def), Source: models.ThresholdSourceDefault}
+		if raw, _ := st.GetSetting(key); raw != "" {
+			if v, err := strconv.Atoi(strings.TrimSpace(raw)); err == nil && v >= min {
+				r = models.ResolvedThreshold{Value: float64(v), Source: models.ThresholdSourceGlobal}
+			}
+		}
+		if override != nil && *override >= min {
+			r = models.ResolvedThreshold{Value: float64(*override), Source: models.ThresholdSourceClient}
+		}
+		return r
+	}
+
+	d := models.DefaultThresholds
+	et := models.EffectiveThresholds{
+		CPUWarnPct:                pct(d.CPUWarnPct, "cpu_warn_pct_default", client.CPUWarnPct),
+		CPUCritPct:                pct(d.CPUCritPct, "cpu_crit_pct_default", client.CPUCritPct),
+		MemWarnPct:                pct(d.MemWarnPct, "mem_warn_pct_default", client.MemWarnPct),
+		MemCritPct:                pct(d.MemCritPct, "mem_crit_pct_default", client.MemCritPct),
+		DiskWarnPct:               pct(d.DiskWarnPct, "disk_warn_pct_default", client.DiskWarnPct),
+		DiskCritPct:               pct(d.DiskCritPct, "disk_crit_pct_default", client.DiskCritPct),
+		OfflineThresholdSeconds:   count(defaultOfflineThresholdSeconds, "offline_threshold_seconds", client.OfflineThresholdSeconds, 1),
+		MetricConsecutiveCheckins: count(defaultMetricConsecutiveCheckins, "metric_consecutive_checkins_default", client.MetricConsecutiveCheckins, 1),
+		AlertCooldownSeconds:      count(defaultAlertCooldownSeconds, "alert_cooldown_seconds", nil, 1),
+	}
+	// A client cooldown of 0 is a deliberate "no cooldown" override.
+	if client.AlertCooldownSeconds != nil && *client.AlertCooldownSeconds >= 0 {
+		et.AlertCooldownSeconds = models.ResolvedThreshold{Value: float64(*client.AlertCooldownSeconds), Source: models.ThresholdSourceClient}
+	}
+	return et
+}
@@ -0,0 +1,41 @@
+package alerting
+
+import (
+	"testing"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestResolveEffectiveThresholdsSources(t *testing.T) {
+	st := newTestStore(t)
+	if err := st.SetSetting("cpu_warn_pct_default", "70"); err != nil {
+		t.Fatalf("set setting: %v", err)
+	}
+	if err := st.SetSetting("offline_threshold_seconds", "600"); err != nil {
+		t.Fatalf("set setting: %v", err)
+	}
+
+	memWarn := 60.0
+	cooldown := 0
+	client := &models.Client{MemWarnPct: &memWarn, AlertCooldownSeconds: &cooldown}
+	et := ResolveEffectiveThresholds(st, client)
+
+	checks := []struct {
+		name   string
+		got    models.ResolvedThreshold
+		value  float64
+		source string
+	}{
+		{"cpu_warn_pct", et.CPUWarnPct, 70, models.ThresholdSourceGlobal},
+		{"cpu_crit_pct", et.CPUCritPct, models.DefaultThresholds.CPUCritPct, models.ThresholdSourceDefault},
+		{"mem_warn_pct", et.MemWarnPct, 60, models.ThresholdSourceClient},
+		{"offline_threshold_seconds", et.OfflineThresholdSeconds, 600, models.ThresholdSourceGlobal},
+		{"metric_consecutive_checkins", et.MetricConsecutiveCheckins, 1, models.ThresholdSourceDefault},
+		{"alert_cooldown_seconds", et.AlertCooldownSeconds, 0, models.ThresholdSourceClient},
+	}
+	for _, c := range checks {
+		if c.got.Value != c.value || c.got.Source != c.source {
+			t.Fatalf("%s: got %v from %s, want %v from %s", c.name, c.got.Value, c.got.Source, c.value, c.source)
+		}
+	}
+}
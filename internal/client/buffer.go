@@ -0,0 +1,56 @@
+package client
+
+import "github.com/machinemon/machinemon/internal/models"
+
+// checkInBatchSize caps how many buffered samples are sent per batch request.
+const checkInBatchSize = 50
+
+// checkInBuffer holds check-ins that could not be delivered, oldest first.
+// When full, the oldest sample is dropped. It is only used from the daemon
+// loop and is not safe for concurrent use.
+type checkInBuffer struct {
+	max   int
+	items []models.CheckInRequest
+}
+
+func newCheckInBuffer(max int) *checkInBuffer {
+	return &checkInBuffer{max: max}
+}
+
+// Add appends a sample and reports how many old samples were dropped.
+func (b *checkInBuffer) Add(p models.CheckInRequest) int {
+	b.items = append(b.items, p)
+	return b.trim()
+}
+
+// Len returns the number of buffered samples.
+func (b *checkInBuffer) Len() int {
+	return len(b.items)
+}
+
+// Take removes and returns up to n of the oldest samples.
+func (b *checkInBuffer) Take(n int) []models.CheckInRequest {
+	if n > len(b.items) {
+		n = len(b.items)
+	}
+	out := make([]models.CheckInRequest, n)
+	copy(out, b.items[:n])
+	b.items = b.items[n:]
+	return out
+}
+
+// Requeue puts samples returned by Take back at the front after a failed
+// flush, dropping the oldest if newer samples have filled the buffer since.
+func (b *checkInBuffer) Requeue(items []models.CheckInRequest) int {
+	b.items = append(append([]models.CheckInRequest{}, items...), b.items...)
+	return b.trim()
+}
+
+func (b *checkInBuffer) trim() int {
+	over := len(b.items) - b.max
+	if over <= 0 {
+		return 0
+	}
+	b.items = b.items[over:]
+	return over
+}
@@ -0,0 +1,33 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestCheckInBufferDropsOldest(t *testing.T) {
+	b := newCheckInBuffer(3)
+	dropped := 0
+	for i := int64(1); i <= 5; i++ {
+		dropped += b.Add(models.CheckInRequest{CapturedAt: i})
+	}
+	if dropped != 2 || b.Len() != 3 {
+		t.Fatalf("expected 3 buffered and 2 dropped, got %d buffered and %d dropped", b.Len(), dropped)
+	}
+
+	got := b.Take(2)
+	if len(got) != 2 || got[0].CapturedAt != 3 || got[1].CapturedAt != 4 {
+		t.Fatalf("expected oldest samples 3,4 first, got %+v", got)
+	}
+
+	// A failed flush puts samples back ahead of anything newer.
+	b.Add(models.CheckInRequest{CapturedAt: 6})
+	if dropped := b.Requeue(got); dropped != 1 {
+		t.Fatalf("expected requeue to drop 1 sample, dropped %d", dropped)
+	}
+	rest := b.Take(10)
+	if len(rest) != 3 || rest[0].CapturedAt != 4 || rest[2].CapturedAt != 6 {
+		t.Fatalf("unexpected buffer order after requeue: %+v", rest)
+	}
+}
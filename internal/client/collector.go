@@ -2,27 +2,50 @@ package client
 
 import (
 	"fmt"
+	"os"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/shirou/gopsutil/v4/cpu"
 	"github.com/shirou/gopsutil/v4/disk"
 	"github.com/shirou/gopsutil/v4/mem"
+	"github.com/shirou/gopsutil/v4/net"
+	"github.com/shirou/gopsutil/v4/process"
+	"github.com/shirou/gopsutil/v4/sensors"
 )
 
 type SystemMetrics struct {
-	CPUPercent     float64
-	MemPercent     float64
-	MemTotal       uint64
-	MemUsed        uint64
-	DiskPercent    float64
-	DiskTotal      uint64
-	DiskUsed       uint64
-}
-
-// CollectSystemMetrics gathers CPU (1-second sample), memory, and root disk usage.
-func CollectSystemMetrics() (*SystemMetrics, error) {
-	cpuPcts, err := cpu.Percent(time.Second, false)
+	CPUPercent            float64
+	MemPercent            float64
+	MemTotal              uint64
+	MemUsed               uint64
+	DiskPercent           float64
+	DiskTotal             uint64
+	DiskUsed              uint64
+	NetRxBytes            uint64 // cumulative, non-loopback interfaces
+	NetTxBytes            uint64
+	SwapPercent           float64 // all zero on hosts without swap
+	SwapTotal             uint64
+	SwapUsed              uint64
+	CPUTempC              *float64 // nil when no CPU sensor is available
+	OpenFDs               *int64   // system-wide open files; nil where unavailable (Linux only)
+	ProcessCount          *int64   // nil if the process list can't be read
+	DiskInodesUsedPercent *float64 // primary disk; nil on Windows or filesystems without inodes
+}
+
+// CollectSystemMetrics gathers CPU, memory, swap, disk
+// and inode usage, CPU temperature and open file count where available, the number of
+// processes, and cumulative network byte counters.
+// CPU usage is sampled over cpuSample, blocking for that long. A zero
+// cpuSample returns immediately with the average since the previous call (or
+// since the client started, on the first call), which smooths out bursts
+// instead of catching whatever the last second happened to be.
+// Disk usage is reported for the first non-empty path, or the root disk when
+// none is given.
+func CollectSystemMetrics(cpuSample time.Duration, paths ...string) (*SystemMetrics, error) {
+	cpuPcts, err := cpu.Percent(cpuSample, false)
 	if err != nil {
 		return nil, fmt.Errorf("cpu: %w", err)
 	}
@@ -36,22 +59,198 @@ func CollectSystemMetrics() (*SystemMetrics, error) {
 		return nil, fmt.Errorf("memory: %w", err)
 	}
 
-	diskPath := "/"
-	if runtime.GOOS == "windows" {
-		diskPath = "C:\\"
-	}
+	diskPath := primaryDiskPath(paths)
 	diskStat, err := disk.Usage(diskPath)
 	if err != nil {
-		return nil, fmt.Errorf("disk: %w", err)
+		return nil, fmt.Errorf("disk usage %s: %w", diskPath, err)
 	}
 
+	// Network counters are best-effort; a failure here shouldn't drop the check-in.
+	rx, tx := netByteCounters()
+	swapPct, swapTotal, swapUsed := swapUsage()
+
 	return &SystemMetrics{
-		CPUPercent:  cpuPct,
-		MemPercent:  vmem.UsedPercent,
-		MemTotal:    vmem.Total,
-		MemUsed:     vmem.Used,
-		DiskPercent: diskStat.UsedPercent,
-		DiskTotal:   diskStat.Total,
-		DiskUsed:    diskStat.Used,
+		CPUPercent:            cpuPct,
+		MemPercent:            vmem.UsedPercent,
+		MemTotal:              vmem.Total,
+		MemUsed:               vmem.Used,
+		DiskPercent:           diskStat.UsedPercent,
+		DiskTotal:             diskStat.Total,
+		DiskUsed:              diskStat.Used,
+		NetRxBytes:            rx,
+		NetTxBytes:            tx,
+		SwapPercent:           swapPct,
+		SwapTotal:             swapTotal,
+		SwapUsed:              swapUsed,
+		CPUTempC:              cpuTemperature(),
+		OpenFDs:               openFileCount(),
+		ProcessCount:          processCount(),
+		DiskInodesUsedPercent: inodeUsage(runtime.GOOS, diskStat),
 	}, nil
 }
+
+// inodeUsage returns the used inode percentage from a disk usage reading.
+// Windows has no inodes, and some filesystems (btrfs, many network mounts)
+// report a zero inode total; both yield nil.
+func inodeUsage(goos string, st *disk.UsageStat) *float64 {
+	if goos == "windows" || st == nil || st.InodesTotal == 0 {
+		return nil
+	}
+	pct := st.InodesUsedPercent
+	return &pct
+}
+
+// primaryDiskPath returns the first non-empty path, defaulting to / (C:\ on
+// Windows).
+func primaryDiskPath(paths []string) string {
+	for _, p := range paths {
+		if p = strings.TrimSpace(p); p != "" {
+			return p
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return "C:\\"
+	}
+	return "/"
+}
+
+// swapUsage reports swap utilization. Hosts without swap (or where it can't
+// be read) report zeros rather than failing the check-in.
+func swapUsage() (pct float64, total, used uint64) {
+	sw, err := mem.SwapMemory()
+	if err != nil || sw.Total == 0 {
+		return 0, 0, 0
+	}
+	return sw.UsedPercent, sw.Total, sw.Used
+}
+
+// cpuTempSensorPreference lists sensor key fragments, most specific first:
+// Intel package, AMD Tctl/Tdie, Raspberry Pi SoC, then generic CPU sensors.
+var cpuTempSensorPreference = []string{
+	"package_id", "tctl", "tdie", "cpu_thermal", "cpu-thermal", "x86_pkg_temp", "coretemp", "cpu", "soc",
+}
+
+// cpuTemperature reads the CPU package temperature in Celsius, or nil when
+// the host exposes no recognizable CPU sensor.
+func cpuTemperature() *float64 {
+	// Some platforms return partial readings alongside a warning error.
+	stats, _ := sensors.SensorsTemperatures()
+	return pickCPUTemp(stats)
+}
+
+// pickCPUTemp returns the hottest plausible reading from the most preferred
+// sensor group present. Unrelated sensors (disks, ACPI zones) are ignored.
+func pickCPUTemp(stats []sensors.TemperatureStat) *float64 {
+	for _, want := range cpuTempSensorPreference {
+		var best *float64
+		for _, st := range stats {
+			if !strings.Contains(strings.ToLower(st.SensorKey), want) {
+				continue
+			}
+			if st.Temperature <= 0 || st.Temperature > 150 {
+				continue
+			}
+			if best == nil || st.Temperature > *best {
+				t := st.Temperature
+				best = &t
+			}
+		}
+		if best != nil {
+			return best
+		}
+	}
+	return nil
+}
+
+// openFileCount reads the number of open file handles system-wide. Only
+// Linux exposes this cheaply; elsewhere it returns nil.
+func openFileCount() *int64 {
+	if runtime.GOOS != "linux" {
+		return nil
+	}
+	data, err := os.ReadFile("/proc/sys/fs/file-nr")
+	if err != nil {
+		return nil
+	}
+	return parseFileNr(string(data))
+}
+
+// parseFileNr parses /proc/sys/fs/file-nr ("allocated free max") into the
+// number of handles in use.
+func parseFileNr(s string) *int64 {
+	fields := strings.Fields(s)
+	if len(fields) < 2 {
+		return nil
+	}
+	allocated, err1 := strconv.ParseInt(fields[0], 10, 64)
+	free, err2 := strconv.ParseInt(fields[1], 10, 64)
+	if err1 != nil || err2 != nil || free > allocated {
+		return nil
+	}
+	n := allocated - free
+	return &n
+}
+
+// processCount returns how many processes are running, or nil if the
+// process list can't be read.
+func processCount() *int64 {
+	pids, err := process.Pids()
+	if err != nil {
+		return nil
+	}
+	n := int64(len(pids))
+	return &n
+}
+
+// netByteCounters sums received/sent bytes across all non-loopback interfaces.
+func netByteCounters() (rx, tx uint64) {
+	counters, err := net.IOCounters(true)
+	if err != nil {
+		return 0, 0
+	}
+	for _, c := range counters {
+		if isLoopbackInterface(c.Name) {
+			continue
+		}
+		rx += c.BytesRecv
+		tx += c.BytesSent
+	}
+	return rx, tx
+}
+
+func isLoopbackInterface(name string) bool {
+	return name == "lo" || strings.HasPrefix(name, "lo0") || strings.HasPrefix(strings.ToLower(name), "loopback")
+}
+
+type DiskMountStatus struct {
+	Path        string
+	Total       uint64
+	Used        uint64
+	UsedPercent float64
+	WarnPct     float64
+	CritPct     float64
+	Err         string
+}
+
+// CollectDiskMounts reports usage for each configured mount path. A path that
+// can't be read is still reported, with Err set, so the server can show it.
+func CollectDiskMounts(mounts []DiskMountConfig) []DiskMountStatus {
+	results := make([]DiskMountStatus, 0, len(mounts))
+	for _, m := range mounts {
+		path := strings.TrimSpace(m.Path)
+		if path == "" {
+			continue
+		}
+		status := DiskMountStatus{Path: path, WarnPct: m.WarnPct, CritPct: m.CritPct}
+		usage, err := disk.Usage(path)
+		if err != nil {
+			status.Err = err.Error()
+		} else {
+			status.Total = usage.Total
+			status.Used = usage.Used
+			status.UsedPercent = usage.UsedPercent
+		}
+		results = append(results, status)
+	}
+	return results
+}
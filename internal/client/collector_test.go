@@ -0,0 +1,87 @@
+package client
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/sensors"
+)
+
+func TestPickCPUTempPrefersPackageSensor(t *testing.T) {
+	stats := []sensors.TemperatureStat{
+		{SensorKey: "nvme_composite", Temperature: 70},
+		{SensorKey: "coretemp_core_0", Temperature: 55},
+		{SensorKey: "coretemp_package_id_0", Temperature: 58},
+		{SensorKey: "acpitz", Temperature: 27.8},
+	}
+	got := pickCPUTemp(stats)
+	if got == nil || *got != 58 {
+		t.Fatalf("pickCPUTemp = %v, want 58", got)
+	}
+}
+
+func TestPickCPUTempFallsBackToSoC(t *testing.T) {
+	got := pickCPUTemp([]sensors.TemperatureStat{{SensorKey: "cpu_thermal", Temperature: 48.3}})
+	if got == nil || *got != 48.3 {
+		t.Fatalf("pickCPUTemp = %v, want 48.3", got)
+	}
+}
+
+func TestPickCPUTempNilWithoutCPUSensor(t *testing.T) {
+	stats := []sensors.TemperatureStat{
+		{SensorKey: "nvme_composite", Temperature: 40},
+		{SensorKey: "coretemp_package_id_0", Temperature: 0},
+	}
+	if got := pickCPUTemp(stats); got != nil {
+		t.Fatalf("pickCPUTemp = %v, want nil", *got)
+	}
+	if got := pickCPUTemp(nil); got != nil {
+		t.Fatalf("pickCPUTemp(nil) = %v, want nil", *got)
+	}
+}
+
+func TestParseFileNr(t *testing.T) {
+	if got := parseFileNr("12160\t0\t9223372036854775807\n"); got == nil || *got != 12160 {
+		t.Fatalf("parseFileNr = %v, want 12160", got)
+	}
+	// Older kernels report freed-but-allocated handles in the second field.
+	if got := parseFileNr("3391 969 52427"); got == nil || *got != 2422 {
+		t.Fatalf("parseFileNr = %v, want 2422", got)
+	}
+	for _, bad := range []string{"", "12160", "a b c", "5 10 100"} {
+		if got := parseFileNr(bad); got != nil {
+			t.Fatalf("parseFileNr(%q) = %v, want nil", bad, *got)
+		}
+	}
+}
+
+func TestInodeUsage(t *testing.T) {
+	st := &disk.UsageStat{InodesTotal: 1000, InodesUsed: 250, InodesUsedPercent: 25}
+	if got := inodeUsage("linux", st); got == nil || *got != 25 {
+		t.Fatalf("inodeUsage = %v, want 25", got)
+	}
+	if got := inodeUsage("windows", st); got != nil {
+		t.Fatalf("windows should report no inode usage, got %v", *got)
+	}
+	// btrfs and similar report no fixed inode table.
+	if got := inodeUsage("linux", &disk.UsageStat{}); got != nil {
+		t.Fatalf("zero inode total should report nil, got %v", *got)
+	}
+}
+
+func TestPrimaryDiskPath(t *testing.T) {
+	root := "/"
+	if runtime.GOOS == "windows" {
+		root = "C:\\"
+	}
+	if got := primaryDiskPath(nil); got != root {
+		t.Fatalf("no paths: got %q, want %q", got, root)
+	}
+	if got := primaryDiskPath([]string{"  "}); got != root {
+		t.Fatalf("blank path: got %q, want %q", got, root)
+	}
+	if got := primaryDiskPath([]string{"", "/data", "/backup"}); got != "/data" {
+		t.Fatalf("got %q, want /data", got)
+	}
+}
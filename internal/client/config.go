@@ -6,54 +6,179 @@ import (
 	"os/user"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/machinemon/machinemon/internal/models"
 )
 
 type Config struct {
-	ClientID        string          `toml:"client_id"`
-	ServerURL       string          `toml:"server_url"`
-	Password        string          `toml:"password"`
-	CheckInInterval int             `toml:"check_in_interval"` // seconds
-	InsecureSkipTLS bool            `toml:"insecure_skip_tls"` // allow self-signed certs
-	Processes       []ProcessConfig `toml:"process"`
-	Checks          []CheckConfig   `toml:"check"`
+	ClientID         string            `toml:"client_id"`
+	ServerURL        string            `toml:"server_url"`
+	Password         string            `toml:"password"`
+	CheckInInterval  int               `toml:"check_in_interval"`           // seconds
+	CheckInJitterPct int               `toml:"check_in_jitter_pct"`         // ±percent randomization of each interval; 0 disables
+	InsecureSkipTLS  bool              `toml:"insecure_skip_tls"`           // allow self-signed certs
+	ClientCert       string            `toml:"client_cert,omitempty"`       // PEM certificate presented to the server for mutual TLS
+	ClientKey        string            `toml:"client_key,omitempty"`        // PEM private key for client_cert
+	UseMachineID     bool              `toml:"use_machine_id"`              // report a hashed machine ID so a reinstall re-attaches
+	BufferSize       int               `toml:"buffer_size"`                 // check-ins kept while the server is unreachable; 0 disables
+	SpoolDir         string            `toml:"spool_dir,omitempty"`         // keep undelivered check-ins on disk here; relative to the config file; empty keeps them in memory
+	SpoolMaxSamples  int               `toml:"spool_max_samples,omitempty"` // cap on spooled check-ins; 0 means 2000
+	DiskPath         string            `toml:"disk_path,omitempty"`         // volume reported as the main disk; empty means / (C:\ on Windows)
+	HeartbeatOnly    bool              `toml:"heartbeat_only,omitempty"`    // only report that the host is alive; no metrics, processes, or checks
+	CPUSampleSeconds int               `toml:"cpu_sample_seconds"`          // CPU measurement window per check-in; 0 averages since the previous check-in
+	Processes        []ProcessConfig   `toml:"process"`
+	Checks           []CheckConfig     `toml:"check"`
+	DiskMounts       []DiskMountConfig `toml:"disk_mount"`
+	Initial          *InitialSettings  `toml:"initial,omitempty"` // applied by the server when it first registers this client
 
 	path string `toml:"-"` // file path, not serialized
 }
 
 // CheckConfig defines a client-side check. The Type field determines what
-// kind of check is run. Currently supported: "script". Future types like
-// "http" and "file_touch" use their own fields.
+// kind of check is run: "script", "http", "dns", or "systemd_unit". Each
+// type uses its own fields; "file_touch" is reserved for a future type.
 type CheckConfig struct {
 	FriendlyName string `toml:"friendly_name"`
-	Type         string `toml:"type"` // "script", "http", "file_touch", ...
+	Type         string `toml:"type"`               // "script", "http", "file_touch", ...
+	Severity     string `toml:"severity,omitempty"` // "info", "warning", or "critical" (default)
+	// Failed runs in a row before the server alerts; 0 uses the server's
+	// check_consecutive_failures_default.
+	ConsecutiveFailures int `toml:"consecutive_failures,omitempty"`
 
 	// Script check fields
 	ScriptPath string `toml:"script_path,omitempty"`
 	RunAsUser  string `toml:"run_as_user,omitempty"`
 
-	// HTTP check fields (future)
+	// HTTP check fields
 	URL            string `toml:"url,omitempty"`
 	ExpectedStatus int    `toml:"expected_status,omitempty"`
 
 	// File touch check fields (future)
 	FilePath   string `toml:"file_path,omitempty"`
 	MaxAgeSecs int    `toml:"max_age_secs,omitempty"`
+
+	// DNS check fields
+	Hostname   string `toml:"hostname,omitempty"`
+	RecordType string `toml:"record_type,omitempty"` // A, AAAA, CNAME, MX, TXT, NS; empty = any address
+	Resolver   string `toml:"resolver,omitempty"`    // host[:port]; empty = system resolver
+
+	// systemd unit check fields (Linux only)
+	Unit string `toml:"unit,omitempty"`
+}
+
+// DiskMountConfig adds a mount path to monitor alongside the root disk.
+// WarnPct/CritPct are optional; zero uses the server-side disk thresholds.
+type DiskMountConfig struct {
+	Path    string  `toml:"path"`
+	WarnPct float64 `toml:"warn_pct,omitempty"`
+	CritPct float64 `toml:"crit_pct,omitempty"`
+}
+
+// InitialSettings is the display name and metric thresholds sent with the
+// first check-in. The server applies them only when it creates the client;
+// after that, change them in the dashboard. Zero thresholds are not sent.
+type InitialSettings struct {
+	Name        string  `toml:"name,omitempty"`
+	CPUWarnPct  float64 `toml:"cpu_warn_pct,omitempty"`
+	CPUCritPct  float64 `toml:"cpu_crit_pct,omitempty"`
+	MemWarnPct  float64 `toml:"mem_warn_pct,omitempty"`
+	MemCritPct  float64 `toml:"mem_crit_pct,omitempty"`
+	DiskWarnPct float64 `toml:"disk_warn_pct,omitempty"`
+	DiskCritPct float64 `toml:"disk_crit_pct,omitempty"`
+}
+
+// HasThresholds reports whether any threshold is set.
+func (s *InitialSettings) HasThresholds() bool {
+	return s.CPUWarnPct != 0 || s.CPUCritPct != 0 || s.MemWarnPct != 0 ||
+		s.MemCritPct != 0 || s.DiskWarnPct != 0 || s.DiskCritPct != 0
+}
+
+// ValidateThresholds reports why the thresholds can't be sent: the server
+// needs all six, each in (0,100], with warning no higher than critical.
+func (s *InitialSettings) ValidateThresholds() error {
+	pairs := []struct {
+		name       string
+		warn, crit float64
+	}{
+		{"cpu", s.CPUWarnPct, s.CPUCritPct},
+		{"mem", s.MemWarnPct, s.MemCritPct},
+		{"disk", s.DiskWarnPct, s.DiskCritPct},
+	}
+	for _, p := range pairs {
+		if p.warn <= 0 || p.warn > 100 || p.crit <= 0 || p.crit > 100 {
+			return fmt.Errorf("%s_warn_pct and %s_crit_pct must be between 0 and 100", p.name, p.name)
+		}
+		if p.warn > p.crit {
+			return fmt.Errorf("%s_warn_pct must not exceed %s_crit_pct", p.name, p.name)
+		}
+	}
+	return nil
+}
+
+// initialPayload returns the settings to attach to a check-in, or nil once
+// the client has a client_id or there is nothing to send. Invalid thresholds
+// are left out so they can't get the check-in rejected.
+func (c *Config) initialPayload() *models.InitialClientSettings {
+	if c.ClientID != "" || c.Initial == nil {
+		return nil
+	}
+	in := c.Initial
+	p := &models.InitialClientSettings{CustomName: strings.TrimSpace(in.Name)}
+	if in.HasThresholds() && in.ValidateThresholds() == nil {
+		p.Thresholds = &models.Thresholds{
+			CPUWarnPct:  in.CPUWarnPct,
+			CPUCritPct:  in.CPUCritPct,
+			MemWarnPct:  in.MemWarnPct,
+			MemCritPct:  in.MemCritPct,
+			DiskWarnPct: in.DiskWarnPct,
+			DiskCritPct: in.DiskCritPct,
+		}
+	}
+	if p.CustomName == "" && p.Thresholds == nil {
+		return nil
+	}
+	return p
 }
 
 type ProcessConfig struct {
 	FriendlyName string `toml:"friendly_name"`
 	MatchPattern string `toml:"match_pattern"`
 	MatchType    string `toml:"match_type"` // "substring" or "regex"
+	// Optional usage thresholds; the server alerts when the process stays at
+	// or above them. CPU is per core, so it can exceed 100. 0 disables.
+	CPUAlertPct float64 `toml:"cpu_alert_pct,omitempty"`
+	MemAlertPct float64 `toml:"mem_alert_pct,omitempty"`
+	// AlertOnStart asks the server to alert when the process starts running
+	// after being reported as not running.
+	AlertOnStart bool `toml:"alert_on_start,omitempty"`
+	// Aggregate counts every matching process instead of stopping at the
+	// first, for worker pools. MinInstances implies Aggregate and asks the
+	// server to alert when fewer instances are running.
+	Aggregate    bool `toml:"aggregate,omitempty"`
+	MinInstances int  `toml:"min_instances,omitempty"`
 }
 
 func DefaultConfig() *Config {
 	return &Config{
-		CheckInInterval: 120,
+		CheckInInterval:  120,
+		CheckInJitterPct: 10,
+		BufferSize:       60,
+		CPUSampleSeconds: 1,
 	}
 }
 
+// CPUSampleDuration is how long each collection samples CPU usage. Zero
+// selects since-last-call mode; negative values fall back to one second.
+func (c *Config) CPUSampleDuration() time.Duration {
+	if c.CPUSampleSeconds < 0 {
+		return time.Second
+	}
+	return time.Duration(c.CPUSampleSeconds) * time.Second
+}
+
 func LoadConfig(path string) (*Config, error) {
 	cfg := DefaultConfig()
 	cfg.path = path
@@ -77,6 +202,15 @@ func LoadConfig(path string) (*Config, error) {
 	return cfg, nil
 }
 
+// spoolPath returns SpoolDir, resolved against the config file's directory
+// when relative, or "" when spooling is off.
+func (c *Config) spoolPath() string {
+	if c.SpoolDir == "" || filepath.IsAbs(c.SpoolDir) || c.path == "" {
+		return c.SpoolDir
+	}
+	return filepath.Join(filepath.Dir(c.path), c.SpoolDir)
+}
+
 func SaveConfig(cfg *Config, path string) error {
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0700); err != nil {
@@ -91,7 +225,7 @@ func SaveConfig(cfg *Config, path string) error {
 }
 
 func (c *Config) IsConfigured() bool {
-	return c.ServerURL != "" && c.Password != ""
+	return c.ServerURL != "" && (c.Password != "" || c.ClientCert != "")
 }
 
 func DefaultConfigPath() string {
@@ -1,8 +1,10 @@
 package client
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestConfigRoundTripProcesses(t *testing.T) {
@@ -41,3 +43,61 @@ func TestConfigRoundTripProcesses(t *testing.T) {
 		t.Fatalf("unexpected check run_as_user: %+v", loaded.Checks[0])
 	}
 }
+
+func TestCPUSampleSeconds(t *testing.T) {
+	dir := t.TempDir()
+	load := func(body string) *Config {
+		t.Helper()
+		path := filepath.Join(dir, "client.toml")
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			t.Fatalf("load config: %v", err)
+		}
+		return cfg
+	}
+	if got := load(`server_url = "https://example.com"`).CPUSampleDuration(); got != time.Second {
+		t.Fatalf("default sample = %v, want 1s", got)
+	}
+	// 0 is a real setting (since-last-call mode), not "unset".
+	if got := load("cpu_sample_seconds = 0").CPUSampleDuration(); got != 0 {
+		t.Fatalf("sample = %v, want 0", got)
+	}
+	if got := load("cpu_sample_seconds = 3").CPUSampleDuration(); got != 3*time.Second {
+		t.Fatalf("sample = %v, want 3s", got)
+	}
+	if got := load("cpu_sample_seconds = -2").CPUSampleDuration(); got != time.Second {
+		t.Fatalf("negative sample = %v, want the 1s default", got)
+	}
+}
+
+func TestInitialSettingsSentUntilRegistered(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "client.toml")
+
+	cfg := DefaultConfig()
+	cfg.Initial = &InitialSettings{Name: " Web ", CPUWarnPct: 70, CPUCritPct: 90,
+		MemWarnPct: 80, MemCritPct: 95, DiskWarnPct: 85, DiskCritPct: 95}
+	if err := SaveConfig(cfg, path); err != nil {
+		t.Fatalf("save config: %v", err)
+	}
+	loaded, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	p := loaded.initialPayload()
+	if p == nil || p.CustomName != "Web" || p.Thresholds == nil || p.Thresholds.CPUWarnPct != 70 {
+		t.Fatalf("unexpected initial payload: %+v", p)
+	}
+	loaded.Initial.DiskCritPct = 0
+	if p := loaded.initialPayload(); p == nil || p.Thresholds != nil {
+		t.Fatalf("expected incomplete thresholds to be left out, got %+v", p)
+	}
+	loaded.ClientID = "abc"
+	if p := loaded.initialPayload(); p != nil {
+		t.Fatalf("expected no initial payload once registered, got %+v", p)
+	}
+}
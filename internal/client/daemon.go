@@ -6,22 +6,128 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
 )
 
-func RunDaemon(cfg *Config, configPath string, logger *slog.Logger) {
+// RunDaemon checks in on a timer until SIGTERM or SIGINT. SIGHUP reloads the
+// config and SIGUSR1 toggles logLevel between debug and its starting level.
+func RunDaemon(cfg *Config, configPath string, logger *slog.Logger, logLevel *slog.LevelVar) {
 	sessionID := bootSessionID()
-	reporter := NewReporter(cfg.ServerURL, cfg.Password, cfg.InsecureSkipTLS)
-	interval := time.Duration(cfg.CheckInInterval) * time.Second
+	var machineID string
+	if cfg.UseMachineID {
+		machineID = stableMachineID()
+		if machineID == "" {
+			logger.Warn("use_machine_id is set but no machine ID is available on this host")
+		}
+	}
+	reporter, err := NewReporter(cfg.ServerURL, cfg.Password, cfg.InsecureSkipTLS, cfg.ClientCert, cfg.ClientKey)
+	if err != nil {
+		logger.Error("failed to set up TLS", "err", err)
+		os.Exit(1)
+	}
+	configuredInterval := time.Duration(cfg.CheckInInterval) * time.Second
+	interval := configuredInterval
+	jitter := newIntervalJitter(cfg.CheckInJitterPct, time.Now().UnixNano())
+	var buffer checkInQueue
+	if dir := cfg.spoolPath(); dir != "" {
+		spool, err := newCheckInSpool(dir, cfg.SpoolMaxSamples)
+		if err != nil {
+			logger.Error("failed to open check-in spool, keeping check-ins in memory", "dir", dir, "err", err)
+		} else {
+			buffer = spool
+			if n := spool.Len(); n > 0 {
+				logger.Info("found spooled check-ins from a previous run", "dir", dir, "spooled", n)
+			}
+		}
+	}
+	if buffer == nil && cfg.BufferSize > 0 {
+		buffer = newCheckInBuffer(cfg.BufferSize)
+	}
 
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	debugCh := make(chan os.Signal, 1)
+	notifyDebugToggle(debugCh)
+	baseLevel := logLevel.Level()
+
+	// lastLatency is the round-trip time of the previous successful
+	// check-in, reported with the next one.
+	var lastLatency time.Duration
+
+	// sendCheckIn delivers payload and applies the server's response;
+	// bufferable payloads are kept for replay when the server is unreachable.
+	sendCheckIn := func(payload models.CheckInRequest, bufferable bool) {
+		payload.CheckInInterval = int(interval / time.Second)
+		payload.CheckInLatencyMs = lastLatency.Milliseconds()
+		payload.Initial = cfg.initialPayload()
+		resp, latency, err := reporter.CheckIn(payload)
+		if err != nil {
+			logger.Error("check-in failed", "err", err, "latency_ms", latency.Milliseconds())
+			if buffer != nil && bufferable {
+				if dropped := buffer.Add(payload); dropped > 0 {
+					logger.Warn("check-in buffer full, dropped oldest sample", "dropped", dropped)
+				}
+				logger.Info("buffered check-in for later delivery", "buffered", buffer.Len())
+			}
+			return
+		}
+
+		lastLatency = latency
+		logger.Info("check-in successful", "client_id", resp.ClientID, "latency_ms", latency.Milliseconds())
+
+		// Save client_id if this was first check-in, or if the server re-attached
+		// us to an existing record by machine ID.
+		if resp.ClientID != "" && resp.ClientID != cfg.ClientID {
+			cfg.ClientID = resp.ClientID
+			if err := SaveConfig(cfg, configPath); err != nil {
+				logger.Error("failed to save config with client_id", "err", err)
+			} else {
+				logger.Info("saved client_id to config", "client_id", resp.ClientID)
+			}
+		}
+
+		if buffer != nil && buffer.Len() > 0 {
+			flushBuffer(reporter, buffer, cfg.ClientID, logger)
+		}
+
+		// Follow the server's interval override; 0 means use our own config.
+		newInterval := configuredInterval
+		if resp.NextCheckInSeconds > 0 {
+			newInterval = time.Duration(resp.NextCheckInSeconds) * time.Second
+		}
+		if newInterval != interval {
+			interval = newInterval
+			logger.Info("adjusted check-in interval", "seconds", int(interval/time.Second))
+		}
+	}
 
 	doCheckIn := func() {
+		if cfg.HeartbeatOnly {
+			logger.Info("sending heartbeat")
+			sendCheckIn(reporter.BuildHeartbeat(cfg.ClientID, sessionID, machineID), false)
+			return
+		}
+
 		logger.Info("collecting metrics")
-		metrics, err := CollectSystemMetrics()
+		// Collection failures are reported to the server rather than
+		// skipping the check-in, so the gap shows up with a reason.
+		var collectionErrs []models.CollectionError
+		metrics, err := CollectSystemMetrics(cfg.CPUSampleDuration(), cfg.DiskPath)
+		metricsOK := err == nil
 		if err != nil {
 			logger.Error("failed to collect metrics", "err", err)
-			return
+			collectionErrs = append(collectionErrs, models.CollectionError{Source: models.CollectionSourceMetrics, Message: err.Error()})
+			metrics = &SystemMetrics{}
+		}
+
+		mounts := CollectDiskMounts(cfg.DiskMounts)
+		for _, m := range mounts {
+			if m.Err != "" {
+				logger.Warn("failed to read disk mount", "path", m.Path, "err", m.Err)
+			}
 		}
 
 		var procs []ProcessStatus
@@ -29,6 +135,7 @@ func RunDaemon(cfg *Config, configPath string, logger *slog.Logger) {
 			procs, err = MatchProcesses(cfg.Processes)
 			if err != nil {
 				logger.Error("failed to match processes", "err", err)
+				collectionErrs = append(collectionErrs, models.CollectionError{Source: models.CollectionSourceProcesses, Message: err.Error()})
 			}
 		}
 
@@ -47,34 +154,25 @@ func RunDaemon(cfg *Config, configPath string, logger *slog.Logger) {
 			"cpu", metrics.CPUPercent,
 			"mem", metrics.MemPercent,
 			"disk", metrics.DiskPercent,
+			"disk_mounts", len(mounts),
 			"processes", len(procs),
-			"checks", len(checks))
-
-		resp, err := reporter.CheckIn(cfg.ClientID, sessionID, metrics, procs, checks)
-		if err != nil {
-			logger.Error("check-in failed", "err", err)
-			return
-		}
+			"checks", len(checks),
+			"errors", len(collectionErrs))
 
-		logger.Info("check-in successful", "client_id", resp.ClientID)
+		payload := reporter.BuildCheckIn(cfg.ClientID, sessionID, machineID, metrics, mounts, procs, checks)
+		payload.Errors = collectionErrs
+		// A sample without metrics has nothing worth replaying later.
+		sendCheckIn(payload, metricsOK)
+	}
 
-		// Save client_id if this was first check-in
-		if cfg.ClientID == "" && resp.ClientID != "" {
-			cfg.ClientID = resp.ClientID
-			if err := SaveConfig(cfg, configPath); err != nil {
-				logger.Error("failed to save config with client_id", "err", err)
-			} else {
-				logger.Info("saved client_id to config", "client_id", resp.ClientID)
-			}
-		}
+	warnInvalidProcesses(cfg.Processes, logger)
+	if cfg.HeartbeatOnly && (len(cfg.Processes) > 0 || len(cfg.Checks) > 0 || len(cfg.DiskMounts) > 0) {
+		logger.Warn("heartbeat_only is set; configured processes, checks, and disk mounts are not monitored")
+	}
 
-		// Adjust interval if server requests it
-		if resp.NextCheckInSeconds > 0 {
-			newInterval := time.Duration(resp.NextCheckInSeconds) * time.Second
-			if newInterval != interval {
-				interval = newInterval
-				logger.Info("adjusted check-in interval", "seconds", resp.NextCheckInSeconds)
-			}
+	if in := cfg.Initial; in != nil && cfg.ClientID == "" && in.HasThresholds() {
+		if err := in.ValidateThresholds(); err != nil {
+			logger.Error("initial thresholds will not be sent; fix the [initial] table", "err", err)
 		}
 	}
 
@@ -82,24 +180,101 @@ func RunDaemon(cfg *Config, configPath string, logger *slog.Logger) {
 		"server", cfg.ServerURL,
 		"interval", interval,
 		"session_id", sessionID,
+		"heartbeat_only", cfg.HeartbeatOnly,
 		"processes", len(cfg.Processes),
 		"checks", len(cfg.Checks))
 
 	// Immediate first check-in
 	doCheckIn()
 
-	ticker := time.NewTicker(interval)
+	ticker := time.NewTicker(jitter.Apply(interval))
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
 			doCheckIn()
-			// Reset ticker in case interval changed
-			ticker.Reset(interval)
+			// Reset ticker in case interval changed; jitter each tick so
+			// clients installed together drift apart.
+			ticker.Reset(jitter.Apply(interval))
+		case <-hupCh:
+			next, err := LoadConfig(configPath)
+			if err != nil {
+				logger.Error("failed to reload config; keeping current settings", "path", configPath, "err", err)
+				continue
+			}
+			changed, needRestart := applyReload(cfg, next)
+			if len(needRestart) > 0 {
+				logger.Warn("config changes that need a restart were not applied", "settings", needRestart)
+			}
+			if len(changed) == 0 {
+				logger.Info("reloaded config, nothing changed")
+				continue
+			}
+			logger.Info("reloaded config", "changes", changed)
+			warnInvalidProcesses(cfg.Processes, logger)
+			jitter = newIntervalJitter(cfg.CheckInJitterPct, time.Now().UnixNano())
+			// Keep a server-assigned interval; otherwise use the new one.
+			if interval == configuredInterval {
+				interval = time.Duration(cfg.CheckInInterval) * time.Second
+			}
+			configuredInterval = time.Duration(cfg.CheckInInterval) * time.Second
+			ticker.Reset(jitter.Apply(interval))
+		case <-debugCh:
+			next := toggledLogLevel(logLevel.Level(), baseLevel)
+			logLevel.Set(next)
+			// Logged at warn so the change is visible at either level.
+			logger.Warn("log level changed", "level", next)
 		case sig := <-sigCh:
 			logger.Info("received signal, shutting down", "signal", sig)
 			return
 		}
 	}
 }
+
+// toggledLogLevel switches to debug, or back to base when already at debug. A
+// base of debug toggles to info.
+func toggledLogLevel(current, base slog.Level) slog.Level {
+	if current != slog.LevelDebug {
+		return slog.LevelDebug
+	}
+	if base == slog.LevelDebug {
+		return slog.LevelInfo
+	}
+	return base
+}
+
+func warnInvalidProcesses(procs []ProcessConfig, logger *slog.Logger) {
+	for _, p := range procs {
+		if err := ValidateProcessMatch(p.MatchPattern, p.MatchType); err != nil {
+			logger.Error("process will never match; fix its match_pattern/match_type", "name", p.FriendlyName, "err", err)
+		}
+	}
+}
+
+// flushBuffer sends buffered check-ins in batches after the server becomes
+// reachable again. Samples collected before the first successful check-in
+// have no client ID yet and are assigned the one the server just returned.
+func flushBuffer(reporter *Reporter, buffer checkInQueue, clientID string, logger *slog.Logger) {
+	sent := buffer.Len()
+	accepted, rejected := 0, 0
+	for buffer.Len() > 0 {
+		batch := buffer.Take(checkInBatchSize)
+		for i := range batch {
+			if batch[i].ClientID == "" {
+				batch[i].ClientID = clientID
+			}
+		}
+		result, err := reporter.CheckInBatch(batch)
+		if err != nil {
+			if dropped := buffer.Requeue(batch); dropped > 0 {
+				logger.Warn("check-in buffer full, dropped oldest sample", "dropped", dropped)
+			}
+			logger.Error("failed to flush buffered check-ins", "buffered", buffer.Len(), "err", err)
+			return
+		}
+		accepted += result.Accepted
+		rejected += result.Rejected
+	}
+	logger.Info("flushed buffered check-ins", "samples", sent, "accepted", accepted, "rejected", rejected)
+}
@@ -0,0 +1,23 @@
+package client
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestToggledLogLevel(t *testing.T) {
+	cases := []struct {
+		current, base, want slog.Level
+	}{
+		{slog.LevelInfo, slog.LevelInfo, slog.LevelDebug},
+		{slog.LevelDebug, slog.LevelInfo, slog.LevelInfo},
+		{slog.LevelDebug, slog.LevelWarn, slog.LevelWarn},
+		{slog.LevelDebug, slog.LevelDebug, slog.LevelInfo},
+		{slog.LevelInfo, slog.LevelDebug, slog.LevelDebug},
+	}
+	for _, tc := range cases {
+		if got := toggledLogLevel(tc.current, tc.base); got != tc.want {
+			t.Fatalf("toggledLogLevel(%s, %s) = %s, want %s", tc.current, tc.base, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+const dnsCheckTimeout = 10 * time.Second
+
+// runDNSCheck resolves the configured hostname and is healthy only if the
+// lookup succeeds and returns at least one record.
+func runDNSCheck(check CheckConfig) CheckResult {
+	result := CheckResult{
+		FriendlyName: check.FriendlyName,
+		CheckType:    models.CheckTypeDNS,
+	}
+	state := models.DNSCheckState{
+		Hostname:   strings.TrimSpace(check.Hostname),
+		RecordType: strings.ToUpper(strings.TrimSpace(check.RecordType)),
+		Resolver:   strings.TrimSpace(check.Resolver),
+	}
+	if state.RecordType == "" {
+		state.RecordType = "A/AAAA"
+	}
+
+	if state.Hostname == "" {
+		result.Message = "hostname is empty"
+		state.Error = result.Message
+		result.State = marshalDNSState(state)
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dnsCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	records, err := lookupDNS(ctx, dnsResolver(state.Resolver), state.Hostname, strings.TrimSpace(check.RecordType))
+	state.DurationMs = time.Since(start).Milliseconds()
+	state.Records = records
+
+	switch {
+	case err != nil:
+		state.Error = err.Error()
+		result.Message = "lookup failed: " + err.Error()
+	case len(records) == 0:
+		result.Message = fmt.Sprintf("no %s records for %s", state.RecordType, state.Hostname)
+	default:
+		result.Healthy = true
+		result.Message = fmt.Sprintf("%d %s record(s) in %dms", len(records), state.RecordType, state.DurationMs)
+	}
+	result.State = marshalDNSState(state)
+	return result
+}
+
+// dnsResolver returns the system resolver, or one pinned to a specific
+// server when resolver is set (port 53 if omitted).
+func dnsResolver(resolver string) *net.Resolver {
+	if resolver == "" {
+		return net.DefaultResolver
+	}
+	if _, _, err := net.SplitHostPort(resolver); err != nil {
+		resolver = net.JoinHostPort(strings.Trim(resolver, "[]"), "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, resolver)
+		},
+	}
+}
+
+func lookupDNS(ctx context.Context, r *net.Resolver, hostname, recordType string) ([]string, error) {
+	switch strings.ToUpper(recordType) {
+	case "":
+		return r.LookupHost(ctx, hostname)
+	case "A", "AAAA":
+		network := "ip4"
+		if strings.EqualFold(recordType, "AAAA") {
+			network = "ip6"
+		}
+		ips, err := r.LookupIP(ctx, network, hostname)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]string, len(ips))
+		for i, ip := range ips {
+			out[i] = ip.String()
+		}
+		return out, nil
+	case "CNAME":
+		cname, err := r.LookupCNAME(ctx, hostname)
+		if err != nil {
+			return nil, err
+		}
+		return []string{cname}, nil
+	case "MX":
+		mxs, err := r.LookupMX(ctx, hostname)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]string, len(mxs))
+		for i, mx := range mxs {
+			out[i] = fmt.Sprintf("%d %s", mx.Pref, mx.Host)
+		}
+		return out, nil
+	case "TXT":
+		return r.LookupTXT(ctx, hostname)
+	case "NS":
+		nss, err := r.LookupNS(ctx, hostname)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]string, len(nss))
+		for i, ns := range nss {
+			out[i] = ns.Host
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unsupported record_type %q", recordType)
+	}
+}
+
+func marshalDNSState(state models.DNSCheckState) string {
+	data, _ := json.Marshal(state)
+	return string(data)
+}
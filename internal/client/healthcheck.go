@@ -22,6 +22,9 @@ type CheckResult struct {
 	Healthy      bool
 	Message      string
 	State        string // JSON blob
+	Severity     string // alert severity when failing; empty means critical
+	// Failed runs in a row before the server alerts; 0 means the server default.
+	ConsecutiveFailures int
 }
 
 // RunChecks executes all configured checks and returns payloads ready for the server.
@@ -29,6 +32,8 @@ func RunChecks(checks []CheckConfig) []CheckResult {
 	results := make([]CheckResult, len(checks))
 	for i, check := range checks {
 		results[i] = runCheck(check)
+		results[i].Severity = strings.ToLower(strings.TrimSpace(check.Severity))
+		results[i].ConsecutiveFailures = check.ConsecutiveFailures
 	}
 	return results
 }
@@ -41,6 +46,10 @@ func runCheck(check CheckConfig) CheckResult {
 		return runHTTPCheck(check)
 	case models.CheckTypeFileTouch:
 		return runFileTouchCheck(check)
+	case models.CheckTypeDNS:
+		return runDNSCheck(check)
+	case models.CheckTypeSystemdUnit:
+		return runSystemdUnitCheck(check)
 	default:
 		return CheckResult{
 			FriendlyName: check.FriendlyName,
@@ -162,17 +171,6 @@ func lookupUser(name string) (*user.User, error) {
 	return nil, fmt.Errorf("user not found")
 }
 
-// runHTTPCheck performs an HTTP check (placeholder for future implementation).
-func runHTTPCheck(check CheckConfig) CheckResult {
-	// TODO: implement HTTP check
-	return CheckResult{
-		FriendlyName: check.FriendlyName,
-		CheckType:    models.CheckTypeHTTP,
-		Healthy:      false,
-		Message:      "HTTP checks not yet implemented",
-	}
-}
-
 // runFileTouchCheck performs a file-touch check (placeholder for future implementation).
 func runFileTouchCheck(check CheckConfig) CheckResult {
 	// TODO: implement file touch check
@@ -66,3 +66,65 @@ func TestRunScriptCheckUnknownRunAsUserFails(t *testing.T) {
 		t.Fatalf("unexpected state run_as_user: %+v", state)
 	}
 }
+
+func TestRunDNSCheckInvalidTLDIsUnhealthy(t *testing.T) {
+	result := runCheck(CheckConfig{
+		FriendlyName: "dns",
+		Type:         models.CheckTypeDNS,
+		Hostname:     "machinemon-check.invalid",
+	})
+	if result.Healthy {
+		t.Fatalf("expected unhealthy result for .invalid hostname, got %+v", result)
+	}
+	if result.CheckType != models.CheckTypeDNS {
+		t.Fatalf("expected dns check type, got %q", result.CheckType)
+	}
+
+	var state models.DNSCheckState
+	if err := json.Unmarshal([]byte(result.State), &state); err != nil {
+		t.Fatalf("unmarshal state: %v", err)
+	}
+	if state.Hostname != "machinemon-check.invalid" || state.Error == "" {
+		t.Fatalf("expected hostname and error in state, got %+v", state)
+	}
+}
+
+func TestRunDNSCheckUnsupportedRecordType(t *testing.T) {
+	result := runDNSCheck(CheckConfig{
+		FriendlyName: "dns",
+		Hostname:     "example.com",
+		RecordType:   "SRVX",
+	})
+	if result.Healthy || !strings.Contains(result.Message, "unsupported record_type") {
+		t.Fatalf("expected unsupported record type failure, got %+v", result)
+	}
+}
+
+func TestRunSystemdUnitCheckEmptyUnitIsUnhealthy(t *testing.T) {
+	result := runCheck(CheckConfig{
+		FriendlyName: "unit",
+		Type:         models.CheckTypeSystemdUnit,
+	})
+	if result.Healthy {
+		t.Fatalf("expected unhealthy result, got %+v", result)
+	}
+	if result.CheckType != models.CheckTypeSystemdUnit {
+		t.Fatalf("expected systemd_unit check type, got %q", result.CheckType)
+	}
+	var state models.SystemdUnitCheckState
+	if err := json.Unmarshal([]byte(result.State), &state); err != nil {
+		t.Fatalf("unmarshal state: %v", err)
+	}
+	if state.Error == "" {
+		t.Fatalf("expected error in state, got %+v", state)
+	}
+}
+
+func TestParseSystemctlValue(t *testing.T) {
+	if got := parseSystemctlValue([]byte("active\n")); got != "active" {
+		t.Fatalf("expected active, got %q", got)
+	}
+	if got := parseSystemctlValue([]byte("")); got != "" {
+		t.Fatalf("expected empty, got %q", got)
+	}
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+const httpCheckTimeout = 10 * time.Second
+
+// runHTTPCheck requests the configured URL and is healthy only if the final
+// response (after redirects) has the expected status, 200 by default.
+func runHTTPCheck(check CheckConfig) CheckResult {
+	result := CheckResult{
+		FriendlyName: check.FriendlyName,
+		CheckType:    models.CheckTypeHTTP,
+	}
+	state := models.HTTPCheckState{
+		URL:            strings.TrimSpace(check.URL),
+		ExpectedStatus: check.ExpectedStatus,
+	}
+	if state.ExpectedStatus == 0 {
+		state.ExpectedStatus = http.StatusOK
+	}
+
+	if state.URL == "" {
+		result.Message = "url is empty"
+		state.Error = result.Message
+		result.State = marshalHTTPState(state)
+		return result
+	}
+
+	httpClient := &http.Client{Timeout: httpCheckTimeout}
+	start := time.Now()
+	resp, err := httpClient.Get(state.URL)
+	state.ResponseTimeMs = time.Since(start).Milliseconds()
+	if err != nil {
+		state.Error = err.Error()
+		result.Message = "request failed: " + err.Error()
+		result.State = marshalHTTPState(state)
+		return result
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	state.ActualStatus = resp.StatusCode
+
+	if resp.StatusCode == state.ExpectedStatus {
+		result.Healthy = true
+		result.Message = fmt.Sprintf("HTTP %d in %dms", resp.StatusCode, state.ResponseTimeMs)
+	} else {
+		result.Message = fmt.Sprintf("HTTP %d (expected %d)", resp.StatusCode, state.ExpectedStatus)
+	}
+	result.State = marshalHTTPState(state)
+	return result
+}
+
+func marshalHTTPState(state models.HTTPCheckState) string {
+	data, _ := json.Marshal(state)
+	return string(data)
+}
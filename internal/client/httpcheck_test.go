@@ -0,0 +1,47 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestRunHTTPCheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	ok := runHTTPCheck(CheckConfig{FriendlyName: "web", URL: srv.URL + "/health"})
+	if !ok.Healthy {
+		t.Fatalf("expected healthy result, got %+v", ok)
+	}
+
+	wrong := runHTTPCheck(CheckConfig{FriendlyName: "web", URL: srv.URL + "/missing"})
+	if wrong.Healthy {
+		t.Fatalf("404 should be unhealthy when expecting 200: %+v", wrong)
+	}
+	var state models.HTTPCheckState
+	if err := json.Unmarshal([]byte(wrong.State), &state); err != nil {
+		t.Fatalf("unmarshal state: %v", err)
+	}
+	if state.ActualStatus != http.StatusNotFound || state.ExpectedStatus != http.StatusOK {
+		t.Fatalf("unexpected state: %+v", state)
+	}
+
+	expected := runHTTPCheck(CheckConfig{FriendlyName: "web", URL: srv.URL + "/missing", ExpectedStatus: http.StatusNotFound})
+	if !expected.Healthy {
+		t.Fatalf("expected status 404 should be healthy: %+v", expected)
+	}
+
+	if down := runHTTPCheck(CheckConfig{FriendlyName: "web", URL: "http://127.0.0.1:1/"}); down.Healthy {
+		t.Fatalf("connection failure should be unhealthy: %+v", down)
+	}
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"math/rand"
+	"time"
+)
+
+// maxJitterPct bounds check-in jitter so the interval never collapses.
+const maxJitterPct = 50
+
+// intervalJitter spreads check-ins from many clients across the interval
+// instead of having them all fire on the same boundary.
+type intervalJitter struct {
+	pct int
+	rng *rand.Rand
+}
+
+// newIntervalJitter returns a jitter source of ±pct percent. A fixed seed
+// gives a reproducible sequence for tests.
+func newIntervalJitter(pct int, seed int64) *intervalJitter {
+	if pct < 0 {
+		pct = 0
+	}
+	if pct > maxJitterPct {
+		pct = maxJitterPct
+	}
+	return &intervalJitter{pct: pct, rng: rand.New(rand.NewSource(seed))}
+}
+
+// Apply returns d adjusted by a random offset within ±pct percent of d.
+func (j *intervalJitter) Apply(d time.Duration) time.Duration {
+	spread := int64(d) * int64(j.pct) / 100
+	if spread <= 0 {
+		return d
+	}
+	return d + time.Duration(j.rng.Int63n(2*spread+1)-spread)
+}
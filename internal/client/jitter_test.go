@@ -0,0 +1,41 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIntervalJitterStaysWithinBounds(t *testing.T) {
+	j := newIntervalJitter(10, 42)
+	interval := 120 * time.Second
+	varied := false
+	for i := 0; i < 100; i++ {
+		got := j.Apply(interval)
+		if got < 108*time.Second || got > 132*time.Second {
+			t.Fatalf("jittered interval %v outside ±10%% of %v", got, interval)
+		}
+		if got != interval {
+			varied = true
+		}
+	}
+	if !varied {
+		t.Fatalf("expected jitter to vary the interval")
+	}
+}
+
+func TestIntervalJitterIsDeterministicForSeed(t *testing.T) {
+	a := newIntervalJitter(10, 7)
+	b := newIntervalJitter(10, 7)
+	for i := 0; i < 10; i++ {
+		if x, y := a.Apply(time.Minute), b.Apply(time.Minute); x != y {
+			t.Fatalf("same seed produced different jitter: %v vs %v", x, y)
+		}
+	}
+}
+
+func TestIntervalJitterDisabled(t *testing.T) {
+	j := newIntervalJitter(0, 1)
+	if got := j.Apply(time.Minute); got != time.Minute {
+		t.Fatalf("expected no jitter, got %v", got)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package client
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyDebugToggle relays SIGUSR1, which switches debug logging on and off.
+func notifyDebugToggle(ch chan<- os.Signal) {
+	signal.Notify(ch, syscall.SIGUSR1)
+}
@@ -0,0 +1,6 @@
+package client
+
+import "os"
+
+// notifyDebugToggle does nothing on Windows, which has no SIGUSR1.
+func notifyDebugToggle(ch chan<- os.Signal) {}
@@ -1,6 +1,8 @@
 package client
 
 import (
+	"fmt"
+	"math"
 	"path/filepath"
 	"regexp"
 	"sort"
@@ -9,14 +11,30 @@ import (
 	"github.com/shirou/gopsutil/v4/process"
 )
 
+// Process match types.
+const (
+	MatchTypeSubstring = "substring"
+	MatchTypeRegex     = "regex"
+)
+
 type ProcessStatus struct {
 	FriendlyName string
 	MatchPattern string
+	MatchType    string
 	IsRunning    bool
 	PID          int32
 	CPUPercent   float64
 	MemPercent   float64
 	Cmdline      string
+	NumFDs       int32
+	NumThreads   int32
+	CPUAlertPct  float64
+	MemAlertPct  float64
+	AlertOnStart bool
+	// InstanceCount is how many processes matched; at most 1 unless the
+	// process is aggregated.
+	InstanceCount int
+	MinInstances  int
 }
 
 // MatchProcesses scans running processes and matches against watched process patterns.
@@ -31,20 +49,28 @@ func MatchProcesses(watched []ProcessConfig) ([]ProcessStatus, error) {
 		results[i] = ProcessStatus{
 			FriendlyName: w.FriendlyName,
 			MatchPattern: w.MatchPattern,
+			MatchType:    w.MatchType,
+			CPUAlertPct:  w.CPUAlertPct,
+			MemAlertPct:  w.MemAlertPct,
+			AlertOnStart: w.AlertOnStart,
+			MinInstances: w.MinInstances,
 		}
+		aggregate := w.Aggregate || w.MinInstances > 0
 		for _, p := range allProcs {
 			cmdline, ok := processSearchText(p)
 			if !ok {
 				continue
 			}
-			if matchesCmdline(w.MatchPattern, w.MatchType, cmdline) {
-				results[i].IsRunning = true
-				results[i].PID = p.Pid
-				results[i].Cmdline = cmdline
-				cpuPct, _ := p.CPUPercent()
-				results[i].CPUPercent = cpuPct
-				memPct, _ := p.MemoryPercent()
-				results[i].MemPercent = float64(memPct)
+			if !matchesCmdline(w.MatchPattern, w.MatchType, cmdline) {
+				continue
+			}
+			cpuPct, _ := p.CPUPercent()
+			memPct, _ := p.MemoryPercent()
+			// Not every platform/permission level exposes these; leave 0 on error.
+			fds, _ := p.NumFDs()
+			threads, _ := p.NumThreads()
+			results[i].addInstance(p.Pid, cmdline, cpuPct, float64(memPct), fds, threads)
+			if !aggregate {
 				break
 			}
 		}
@@ -52,6 +78,47 @@ func MatchProcesses(watched []ProcessConfig) ([]ProcessStatus, error) {
 	return results, nil
 }
 
+// addInstance folds one matching process into s. Usage and counts are summed
+// across instances; the lowest PID (usually the parent of a worker pool)
+// stands in for the group's PID and command line.
+func (s *ProcessStatus) addInstance(pid int32, cmdline string, cpuPct, memPct float64, fds, threads int32) {
+	if !s.IsRunning || pid < s.PID {
+		s.PID = pid
+		s.Cmdline = cmdline
+	}
+	s.IsRunning = true
+	s.InstanceCount++
+	s.CPUPercent += cpuPct
+	// Shared pages are counted once per process, so the sum can overshoot.
+	s.MemPercent = math.Min(s.MemPercent+memPct, 100)
+	if fds > 0 {
+		s.NumFDs += fds
+	}
+	if threads > 0 {
+		s.NumThreads += threads
+	}
+}
+
+// ValidateProcessMatch reports whether a match pattern/type pair can ever
+// match: the type must be known, the pattern non-empty, and a regex pattern
+// must compile.
+func ValidateProcessMatch(pattern, matchType string) error {
+	if strings.TrimSpace(pattern) == "" {
+		return fmt.Errorf("match pattern cannot be empty")
+	}
+	switch matchType {
+	case "", MatchTypeSubstring:
+		return nil
+	case MatchTypeRegex:
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid regular expression: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown match type %q (use %q or %q)", matchType, MatchTypeSubstring, MatchTypeRegex)
+	}
+}
+
 func matchesCmdline(pattern, matchType, cmdline string) bool {
 	switch matchType {
 	case "regex":
@@ -0,0 +1,48 @@
+package client
+
+import "testing"
+
+func TestValidateProcessMatch(t *testing.T) {
+	cases := []struct {
+		pattern, matchType string
+		ok                 bool
+	}{
+		{"nginx", "", true},
+		{"nginx", MatchTypeSubstring, true},
+		{`^/usr/bin/python3 .*worker\.py`, MatchTypeRegex, true},
+		{`worker(`, MatchTypeRegex, false},
+		{"nginx", "glob", false},
+		{"  ", MatchTypeSubstring, false},
+	}
+	for _, tc := range cases {
+		err := ValidateProcessMatch(tc.pattern, tc.matchType)
+		if (err == nil) != tc.ok {
+			t.Fatalf("ValidateProcessMatch(%q, %q) = %v, want ok=%v", tc.pattern, tc.matchType, err, tc.ok)
+		}
+	}
+}
+
+func TestMatchesCmdlineRegex(t *testing.T) {
+	if !matchesCmdline(`^node .*index\.js$`, MatchTypeRegex, "node /srv/app/index.js") {
+		t.Fatalf("expected regex to match")
+	}
+	if matchesCmdline(`^node .*index\.js$`, MatchTypeRegex, "node /srv/app/index.jsx") {
+		t.Fatalf("expected anchored regex not to match")
+	}
+}
+
+func TestAddInstanceAggregates(t *testing.T) {
+	var s ProcessStatus
+	s.addInstance(310, "gunicorn: worker", 12.5, 60, 20, 2)
+	s.addInstance(300, "gunicorn: master", 1, 50, 0, 1)
+	s.addInstance(320, "gunicorn: worker", 10, 3, 15, 2)
+	if !s.IsRunning || s.InstanceCount != 3 {
+		t.Fatalf("running=%v count=%d, want running with 3 instances", s.IsRunning, s.InstanceCount)
+	}
+	if s.PID != 300 || s.Cmdline != "gunicorn: master" {
+		t.Fatalf("representative = %d %q, want the lowest PID", s.PID, s.Cmdline)
+	}
+	if s.CPUPercent != 23.5 || s.MemPercent != 100 || s.NumFDs != 35 || s.NumThreads != 5 {
+		t.Fatalf("unexpected totals: %+v", s)
+	}
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"fmt"
+	"slices"
+)
+
+// applyReload copies the settings the daemon can change without a restart
+// from next into cfg. It returns a description of each applied change and the
+// names of changed settings that still need a restart. cfg keeps its
+// client_id, which the daemon may have saved since the file was read.
+func applyReload(cfg, next *Config) (changed, needRestart []string) {
+	if next.CheckInInterval > 0 && next.CheckInInterval != cfg.CheckInInterval {
+		changed = append(changed, fmt.Sprintf("check_in_interval %ds -> %ds", cfg.CheckInInterval, next.CheckInInterval))
+		cfg.CheckInInterval = next.CheckInInterval
+	}
+	if next.CheckInJitterPct != cfg.CheckInJitterPct {
+		changed = append(changed, fmt.Sprintf("check_in_jitter_pct %d -> %d", cfg.CheckInJitterPct, next.CheckInJitterPct))
+		cfg.CheckInJitterPct = next.CheckInJitterPct
+	}
+	if next.DiskPath != cfg.DiskPath {
+		changed = append(changed, fmt.Sprintf("disk_path %q -> %q", cfg.DiskPath, next.DiskPath))
+		cfg.DiskPath = next.DiskPath
+	}
+	if next.CPUSampleSeconds != cfg.CPUSampleSeconds {
+		changed = append(changed, fmt.Sprintf("cpu_sample_seconds %d -> %d", cfg.CPUSampleSeconds, next.CPUSampleSeconds))
+		cfg.CPUSampleSeconds = next.CPUSampleSeconds
+	}
+	if next.HeartbeatOnly != cfg.HeartbeatOnly {
+		changed = append(changed, fmt.Sprintf("heartbeat_only %t -> %t", cfg.HeartbeatOnly, next.HeartbeatOnly))
+		cfg.HeartbeatOnly = next.HeartbeatOnly
+	}
+	if !slices.Equal(next.Processes, cfg.Processes) {
+		changed = append(changed, fmt.Sprintf("processes (%d -> %d)", len(cfg.Processes), len(next.Processes)))
+		cfg.Processes = next.Processes
+	}
+	if !slices.Equal(next.Checks, cfg.Checks) {
+		changed = append(changed, fmt.Sprintf("checks (%d -> %d)", len(cfg.Checks), len(next.Checks)))
+		cfg.Checks = next.Checks
+	}
+	if !slices.Equal(next.DiskMounts, cfg.DiskMounts) {
+		changed = append(changed, fmt.Sprintf("disk mounts (%d -> %d)", len(cfg.DiskMounts), len(next.DiskMounts)))
+		cfg.DiskMounts = next.DiskMounts
+	}
+
+	if next.ServerURL != cfg.ServerURL {
+		needRestart = append(needRestart, "server_url")
+	}
+	if next.Password != cfg.Password {
+		needRestart = append(needRestart, "password")
+	}
+	if next.InsecureSkipTLS != cfg.InsecureSkipTLS || next.ClientCert != cfg.ClientCert || next.ClientKey != cfg.ClientKey {
+		needRestart = append(needRestart, "TLS settings")
+	}
+	if next.UseMachineID != cfg.UseMachineID {
+		needRestart = append(needRestart, "use_machine_id")
+	}
+	if next.BufferSize != cfg.BufferSize {
+		needRestart = append(needRestart, "buffer_size")
+	}
+	if next.SpoolDir != cfg.SpoolDir || next.SpoolMaxSamples != cfg.SpoolMaxSamples {
+		needRestart = append(needRestart, "spool settings")
+	}
+	return changed, needRestart
+}
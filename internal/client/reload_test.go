@@ -0,0 +1,37 @@
+package client
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestApplyReload(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ClientID = "saved-id"
+	cfg.ServerURL = "https://a.example.com"
+	cfg.Processes = []ProcessConfig{{FriendlyName: "nginx", MatchPattern: "nginx", MatchType: "substring"}}
+
+	next := DefaultConfig()
+	next.ServerURL = "https://b.example.com"
+	next.CheckInInterval = 60
+	next.CPUSampleSeconds = 0
+	next.Processes = append(slices.Clone(cfg.Processes), ProcessConfig{FriendlyName: "api", MatchPattern: "api", MatchType: "substring"})
+
+	changed, needRestart := applyReload(cfg, next)
+	if len(changed) != 3 {
+		t.Fatalf("expected interval, CPU sample, and process changes, got %v", changed)
+	}
+	if !slices.Equal(needRestart, []string{"server_url"}) {
+		t.Fatalf("expected server_url to need a restart, got %v", needRestart)
+	}
+	if cfg.CheckInInterval != 60 || cfg.CPUSampleSeconds != 0 || len(cfg.Processes) != 2 {
+		t.Fatalf("reloadable settings not applied: %+v", cfg)
+	}
+	if cfg.ServerURL != "https://a.example.com" || cfg.ClientID != "saved-id" {
+		t.Fatalf("restart-only settings or client_id changed: %+v", cfg)
+	}
+
+	if changed, _ := applyReload(cfg, cfg); len(changed) != 0 {
+		t.Fatalf("expected no changes reloading the same config, got %v", changed)
+	}
+}
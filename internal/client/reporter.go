@@ -2,15 +2,19 @@ package client
 
 import (
 	"bytes"
+	"compress/gzip"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/machinemon/machinemon/internal/models"
+	"github.com/machinemon/machinemon/internal/netinfo"
 	"github.com/machinemon/machinemon/internal/version"
 )
 
@@ -18,37 +22,83 @@ type Reporter struct {
 	httpClient *http.Client
 	serverURL  string
 	password   string
+	// noGzip is set once the server turns out not to accept compressed bodies.
+	noGzip bool
 }
 
-func NewReporter(serverURL, password string, insecureSkipTLS bool) *Reporter {
-	transport := &http.Transport{}
-	if insecureSkipTLS {
-		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+// NewReporter returns a Reporter for serverURL. When clientCert and clientKey
+// are set, the key pair is presented to the server for mutual TLS.
+func NewReporter(serverURL, password string, insecureSkipTLS bool, clientCert, clientKey string) (*Reporter, error) {
+	tlsCfg, err := clientTLSConfig(insecureSkipTLS, clientCert, clientKey)
+	if err != nil {
+		return nil, err
 	}
 	return &Reporter{
 		httpClient: &http.Client{
 			Timeout:   30 * time.Second,
-			Transport: transport,
+			Transport: &http.Transport{TLSClientConfig: tlsCfg},
 		},
 		serverURL: serverURL,
 		password:  password,
+	}, nil
+}
+
+// clientTLSConfig returns nil when the defaults will do.
+func clientTLSConfig(insecureSkipTLS bool, clientCert, clientKey string) (*tls.Config, error) {
+	if (clientCert == "") != (clientKey == "") {
+		return nil, fmt.Errorf("client_cert and client_key must be set together")
+	}
+	if !insecureSkipTLS && clientCert == "" {
+		return nil, nil
 	}
+	cfg := &tls.Config{InsecureSkipVerify: insecureSkipTLS}
+	if clientCert != "" {
+		pair, err := tls.LoadX509KeyPair(clientCert, clientKey)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{pair}
+	}
+	return cfg, nil
 }
 
-func (r *Reporter) CheckIn(clientID, sessionID string, metrics *SystemMetrics, procs []ProcessStatus, checks []CheckResult) (*models.CheckInResponse, error) {
+// BuildCheckIn assembles a check-in payload stamped with the time it was
+// collected, so it can be sent now or buffered and sent later.
+func (r *Reporter) BuildCheckIn(clientID, sessionID, machineID string, metrics *SystemMetrics, mounts []DiskMountStatus, procs []ProcessStatus, checks []CheckResult) models.CheckInRequest {
 	hostname, _ := os.Hostname()
-	interfaceIPs := ListInterfaceIPs()
+	interfaceIPs := netinfo.InterfaceIPs()
 
 	processes := make([]models.ProcessPayload, len(procs))
 	for i, p := range procs {
 		processes[i] = models.ProcessPayload{
-			FriendlyName: p.FriendlyName,
-			MatchPattern: p.MatchPattern,
-			IsRunning:    p.IsRunning,
-			PID:          p.PID,
-			CPUPercent:   p.CPUPercent,
-			MemPercent:   p.MemPercent,
-			Cmdline:      p.Cmdline,
+			FriendlyName:  p.FriendlyName,
+			MatchPattern:  p.MatchPattern,
+			MatchType:     p.MatchType,
+			IsRunning:     p.IsRunning,
+			PID:           p.PID,
+			CPUPercent:    p.CPUPercent,
+			MemPercent:    p.MemPercent,
+			Cmdline:       p.Cmdline,
+			NumFDs:        p.NumFDs,
+			NumThreads:    p.NumThreads,
+			CPUAlertPct:   p.CPUAlertPct,
+			MemAlertPct:   p.MemAlertPct,
+			AlertOnStart:  p.AlertOnStart,
+			InstanceCount: p.InstanceCount,
+			MinInstances:  p.MinInstances,
+		}
+	}
+
+	diskMounts := make([]models.DiskMountPayload, len(mounts))
+	for i, m := range mounts {
+		diskMounts[i] = models.DiskMountPayload{
+			Path:        m.Path,
+			TotalBytes:  m.Total,
+			UsedBytes:   m.Used,
+			UsedPercent: m.UsedPercent,
+			WarnPct:     m.WarnPct,
+			CritPct:     m.CritPct,
+			Error:       m.Err,
 		}
 	}
 
@@ -59,58 +109,179 @@ func (r *Reporter) CheckIn(clientID, sessionID string, metrics *SystemMetrics, p
 		ClientVersion: version.Version,
 		ClientID:      clientID,
 		SessionID:     sessionID,
+		MachineID:     machineID,
 		BootTimeUnix:  bootTimeUnix(),
 		InterfaceIPs:  interfaceIPs,
+		CapturedAt:    time.Now().Unix(),
 		Metrics: models.MetricsPayload{
-			CPUPercent:     metrics.CPUPercent,
-			MemPercent:     metrics.MemPercent,
-			MemTotalBytes:  metrics.MemTotal,
-			MemUsedBytes:   metrics.MemUsed,
-			DiskPercent:    metrics.DiskPercent,
-			DiskTotalBytes: metrics.DiskTotal,
-			DiskUsedBytes:  metrics.DiskUsed,
+			CPUPercent:            metrics.CPUPercent,
+			MemPercent:            metrics.MemPercent,
+			MemTotalBytes:         metrics.MemTotal,
+			MemUsedBytes:          metrics.MemUsed,
+			SwapPercent:           metrics.SwapPercent,
+			SwapTotalBytes:        metrics.SwapTotal,
+			SwapUsedBytes:         metrics.SwapUsed,
+			CPUTempC:              metrics.CPUTempC,
+			OpenFDs:               metrics.OpenFDs,
+			ProcessCount:          metrics.ProcessCount,
+			DiskInodesUsedPercent: metrics.DiskInodesUsedPercent,
+			DiskPercent:           metrics.DiskPercent,
+			DiskTotalBytes:        metrics.DiskTotal,
+			DiskUsedBytes:         metrics.DiskUsed,
+			NetRxBytes:            metrics.NetRxBytes,
+			NetTxBytes:            metrics.NetTxBytes,
 		},
-		Processes: processes,
+		Processes:  processes,
+		DiskMounts: diskMounts,
 	}
 
 	for _, c := range checks {
 		payload.Checks = append(payload.Checks, models.CheckPayload{
-			FriendlyName: c.FriendlyName,
-			CheckType:    c.CheckType,
-			Healthy:      c.Healthy,
-			Message:      c.Message,
-			State:        c.State,
+			FriendlyName:        c.FriendlyName,
+			CheckType:           c.CheckType,
+			Healthy:             c.Healthy,
+			Message:             c.Message,
+			State:               c.State,
+			Severity:            c.Severity,
+			ConsecutiveFailures: c.ConsecutiveFailures,
 		})
 	}
 
+	return payload
+}
+
+// BuildHeartbeat creates a check-in that only reports the host is alive. The
+// server records the check-in but stores no metrics, and the empty process,
+// disk mount, and check lists clear anything left from an earlier config.
+func (r *Reporter) BuildHeartbeat(clientID, sessionID, machineID string) models.CheckInRequest {
+	payload := r.BuildCheckIn(clientID, sessionID, machineID, &SystemMetrics{}, nil, nil, nil)
+	payload.HeartbeatOnly = true
+	return payload
+}
+
+// CheckIn sends a live check-in.
+// CheckIn sends one sample and returns the server's response along with the
+// round-trip time of the request.
+func (r *Reporter) CheckIn(payload models.CheckInRequest) (*models.CheckInResponse, time.Duration, error) {
+	var result models.CheckInResponse
+	start := time.Now()
+	err := r.post("/api/v1/checkin", payload, &result)
+	latency := time.Since(start)
+	if err != nil {
+		return nil, latency, err
+	}
+	return &result, latency, nil
+}
+
+// CheckInBatch sends samples buffered while the server was unreachable.
+func (r *Reporter) CheckInBatch(payloads []models.CheckInRequest) (*models.CheckInBatchResponse, error) {
+	var result models.CheckInBatchResponse
+	if err := r.post("/api/v1/checkin/batch", payloads, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// gzipMinBytes is the smallest request body worth compressing.
+const gzipMinBytes = 1024
+
+func (r *Reporter) post(path string, payload, result interface{}) error {
 	body, err := json.Marshal(payload)
 	if err != nil {
-		return nil, fmt.Errorf("marshal payload: %w", err)
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	gzipped := !r.noGzip && len(body) >= gzipMinBytes
+	status, respBody, err := r.send(path, body, gzipped)
+	if err != nil {
+		return err
+	}
+	if gzipped && rejectedGzip(status, respBody) {
+		// Servers before gzip support can't read the body; stop compressing.
+		r.noGzip = true
+		if status, respBody, err = r.send(path, body, false); err != nil {
+			return err
+		}
 	}
 
-	req, err := http.NewRequest("POST", r.serverURL+"/api/v1/checkin", bytes.NewReader(body))
+	if status == http.StatusUnauthorized {
+		return fmt.Errorf("authentication failed: check your password")
+	}
+	if status == http.StatusBadRequest {
+		// The server lists what it rejected; surface it so the config can be fixed.
+		var rejected struct {
+			Error   string   `json:"error"`
+			Details []string `json:"details"`
+		}
+		if json.Unmarshal(respBody, &rejected) == nil && rejected.Error != "" {
+			if len(rejected.Details) > 0 {
+				return fmt.Errorf("server rejected check-in: %s: %s", rejected.Error, strings.Join(rejected.Details, "; "))
+			}
+			return fmt.Errorf("server rejected check-in: %s", rejected.Error)
+		}
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("server returned status %d", status)
+	}
+
+	if err := json.Unmarshal(respBody, result); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// send posts body, gzip-compressed if requested, and returns the response
+// status and body.
+func (r *Reporter) send(path string, body []byte, gzipped bool) (int, []byte, error) {
+	if gzipped {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err != nil {
+			return 0, nil, fmt.Errorf("compress payload: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return 0, nil, fmt.Errorf("compress payload: %w", err)
+		}
+		body = buf.Bytes()
+	}
+
+	req, err := http.NewRequest("POST", r.serverURL+path, bytes.NewReader(body))
 	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+		return 0, nil, fmt.Errorf("create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Client-Password", r.password)
+	if gzipped {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	// With a client certificate the password is optional.
+	if r.password != "" {
+		req.Header.Set("X-Client-Password", r.password)
+	}
 
 	resp, err := r.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("send check-in: %w", err)
+		return 0, nil, fmt.Errorf("send check-in: %w", err)
 	}
 	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, fmt.Errorf("authentication failed: check your password")
-	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("server returned status %d", resp.StatusCode)
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return 0, nil, fmt.Errorf("read response: %w", err)
 	}
+	return resp.StatusCode, respBody, nil
+}
 
-	var result models.CheckInResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("decode response: %w", err)
+// rejectedGzip reports whether a response means the server couldn't read a
+// compressed body: either it refused the encoding, or it is too old to know
+// about it and failed to parse the body as JSON.
+func rejectedGzip(status int, body []byte) bool {
+	switch status {
+	case http.StatusUnsupportedMediaType:
+		return true
+	case http.StatusBadRequest:
+		var resp struct {
+			Error string `json:"error"`
+		}
+		return json.Unmarshal(body, &resp) == nil && resp.Error == "invalid request body"
 	}
-	return &result, nil
+	return false
 }
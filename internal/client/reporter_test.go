@@ -0,0 +1,95 @@
+package client
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestClientTLSConfig(t *testing.T) {
+	if cfg, err := clientTLSConfig(false, "", ""); err != nil || cfg != nil {
+		t.Fatalf("expected default TLS settings, got %+v, %v", cfg, err)
+	}
+	if cfg, err := clientTLSConfig(true, "", ""); err != nil || cfg == nil || !cfg.InsecureSkipVerify {
+		t.Fatalf("expected InsecureSkipVerify, got %+v, %v", cfg, err)
+	}
+	if _, err := clientTLSConfig(false, "/etc/machinemon/client.crt", ""); err == nil {
+		t.Fatalf("expected an error when client_key is missing")
+	}
+	if _, err := clientTLSConfig(false, "/nonexistent.crt", "/nonexistent.key"); err == nil {
+		t.Fatalf("expected an error for unreadable key pair")
+	}
+}
+
+func TestBuildHeartbeat(t *testing.T) {
+	r, err := NewReporter("http://localhost:8080", "pw", false, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := r.BuildHeartbeat("client-1", "session-1", "")
+	if !p.HeartbeatOnly || p.ClientID != "client-1" || p.SessionID != "session-1" || p.Hostname == "" {
+		t.Fatalf("unexpected heartbeat identity: %+v", p)
+	}
+	if len(p.Processes) != 0 || len(p.DiskMounts) != 0 || len(p.Checks) != 0 || p.Metrics.CPUPercent != 0 {
+		t.Fatalf("expected an empty heartbeat payload, got %+v", p)
+	}
+}
+
+func TestPostCompressesAndFallsBack(t *testing.T) {
+	var encodings []string
+	acceptGzip := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+		body := io.Reader(r.Body)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			if !acceptGzip {
+				// Behave like a server without gzip support.
+				w.WriteHeader(http.StatusBadRequest)
+				io.WriteString(w, `{"error":"invalid request body"}`)
+				return
+			}
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Errorf("gzip reader: %v", err)
+				return
+			}
+			body = zr
+		}
+		var req models.CheckInRequest
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		json.NewEncoder(w).Encode(models.CheckInResponse{ClientID: req.ClientID})
+	}))
+	defer srv.Close()
+
+	r, err := NewReporter(srv.URL, "pw", false, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	large := models.CheckInRequest{Hostname: "web-1", ClientID: strings.Repeat("x", 2*gzipMinBytes)}
+
+	if _, _, err := r.CheckIn(models.CheckInRequest{Hostname: "web-1", ClientID: "small"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.CheckIn(large); err != nil {
+		t.Fatal(err)
+	}
+	acceptGzip = false
+	if _, _, err := r.CheckIn(large); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.CheckIn(large); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"", "gzip", "gzip", "", ""}
+	if strings.Join(encodings, ",") != strings.Join(want, ",") {
+		t.Fatalf("encodings = %q, want %q", encodings, want)
+	}
+}
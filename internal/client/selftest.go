@@ -0,0 +1,135 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// SelfTest runs one collection cycle (metrics, disk mounts, processes, and
+// checks) without contacting the server and writes a report to w. It reports
+// whether everything was collected, every process matched, and every check
+// passed.
+func SelfTest(cfg *Config, w io.Writer) bool {
+	// Since-last-call mode would only cover the few milliseconds since
+	// startup, so the self-test always takes a real sample.
+	sample := cfg.CPUSampleDuration()
+	if sample == 0 {
+		sample = time.Second
+	}
+	metrics, metricsErr := CollectSystemMetrics(sample, cfg.DiskPath)
+	mounts := CollectDiskMounts(cfg.DiskMounts)
+	var procs []ProcessStatus
+	var procsErr error
+	if len(cfg.Processes) > 0 {
+		procs, procsErr = MatchProcesses(cfg.Processes)
+	}
+	checks := RunChecks(cfg.Checks)
+	return writeSelfTestReport(w, metrics, metricsErr, mounts, procs, procsErr, checks)
+}
+
+func writeSelfTestReport(w io.Writer, metrics *SystemMetrics, metricsErr error, mounts []DiskMountStatus,
+	procs []ProcessStatus, procsErr error, checks []CheckResult) bool {
+	ok := true
+
+	fmt.Fprintln(w, "System metrics:")
+	if metricsErr != nil {
+		ok = false
+		fmt.Fprintf(w, "  FAIL  %v\n", metricsErr)
+	} else {
+		fmt.Fprintf(w, "  CPU:    %.1f%%\n", metrics.CPUPercent)
+		fmt.Fprintf(w, "  Memory: %.1f%% (%s of %s)\n", metrics.MemPercent, formatBytes(metrics.MemUsed), formatBytes(metrics.MemTotal))
+		fmt.Fprintf(w, "  Disk:   %.1f%% (%s of %s)\n", metrics.DiskPercent, formatBytes(metrics.DiskUsed), formatBytes(metrics.DiskTotal))
+		if metrics.SwapTotal > 0 {
+			fmt.Fprintf(w, "  Swap:   %.1f%% (%s of %s)\n", metrics.SwapPercent, formatBytes(metrics.SwapUsed), formatBytes(metrics.SwapTotal))
+		}
+		if metrics.DiskInodesUsedPercent != nil {
+			fmt.Fprintf(w, "  Inodes: %.1f%%\n", *metrics.DiskInodesUsedPercent)
+		}
+		if metrics.CPUTempC != nil {
+			fmt.Fprintf(w, "  Temp:   %.1f°C\n", *metrics.CPUTempC)
+		}
+		if metrics.ProcessCount != nil {
+			fmt.Fprintf(w, "  Procs:  %d\n", *metrics.ProcessCount)
+		}
+		if metrics.OpenFDs != nil {
+			fmt.Fprintf(w, "  Files:  %d open\n", *metrics.OpenFDs)
+		}
+	}
+
+	if len(mounts) > 0 {
+		fmt.Fprintln(w, "\nDisk mounts:")
+		for _, m := range mounts {
+			if m.Err != "" {
+				ok = false
+				fmt.Fprintf(w, "  FAIL  %s: %s\n", m.Path, m.Err)
+				continue
+			}
+			fmt.Fprintf(w, "  ok    %s: %.1f%% (%s of %s)\n", m.Path, m.UsedPercent, formatBytes(m.Used), formatBytes(m.Total))
+		}
+	}
+
+	fmt.Fprintln(w, "\nProcesses:")
+	switch {
+	case procsErr != nil:
+		ok = false
+		fmt.Fprintf(w, "  FAIL  could not list processes: %v\n", procsErr)
+	case len(procs) == 0:
+		fmt.Fprintln(w, "  <none configured>")
+	}
+	for _, p := range procs {
+		if !p.IsRunning {
+			ok = false
+			fmt.Fprintf(w, "  FAIL  %s: no process matches %s %q\n", p.FriendlyName, p.MatchType, p.MatchPattern)
+			continue
+		}
+		fmt.Fprintf(w, "  ok    %s: pid %d, cpu %.1f%%, mem %.1f%%\n", p.FriendlyName, p.PID, p.CPUPercent, p.MemPercent)
+		fmt.Fprintf(w, "        %s\n", truncateCmdline(p.Cmdline, 100))
+	}
+
+	fmt.Fprintln(w, "\nChecks:")
+	if len(checks) == 0 {
+		fmt.Fprintln(w, "  <none configured>")
+	}
+	for _, c := range checks {
+		status := "ok  "
+		if !c.Healthy {
+			ok = false
+			status = "FAIL"
+		}
+		fmt.Fprintf(w, "  %s  %s (%s)", status, c.FriendlyName, c.CheckType)
+		if c.Message != "" {
+			fmt.Fprintf(w, ": %s", c.Message)
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintln(w)
+	if ok {
+		fmt.Fprintln(w, "Self-test passed. Nothing was sent to the server.")
+	} else {
+		fmt.Fprintln(w, "Self-test found problems. Nothing was sent to the server.")
+	}
+	return ok
+}
+
+// formatBytes renders n using binary units, e.g. "1.5 GiB".
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func truncateCmdline(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max-3] + "..."
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWriteSelfTestReport(t *testing.T) {
+	metrics := &SystemMetrics{CPUPercent: 12.5, MemPercent: 50, MemUsed: 4 << 30, MemTotal: 8 << 30, DiskPercent: 30}
+	procs := []ProcessStatus{
+		{FriendlyName: "nginx", MatchPattern: "nginx", MatchType: "substring", IsRunning: true, PID: 42, Cmdline: "nginx: master process"},
+		{FriendlyName: "api", MatchPattern: "node.*api", MatchType: "regex"},
+	}
+	checks := []CheckResult{
+		{FriendlyName: "health", CheckType: "script", Healthy: true},
+		{FriendlyName: "backup", CheckType: "script", Message: "permission denied"},
+	}
+
+	var b strings.Builder
+	if writeSelfTestReport(&b, metrics, nil, nil, procs, nil, checks) {
+		t.Fatalf("expected failure with an unmatched process and a failing check")
+	}
+	out := b.String()
+	for _, want := range []string{
+		"CPU:    12.5%",
+		"Memory: 50.0% (4.0 GiB of 8.0 GiB)",
+		"ok    nginx: pid 42",
+		`FAIL  api: no process matches regex "node.*api"`,
+		"ok    health (script)",
+		"FAIL  backup (script): permission denied",
+		"Self-test found problems",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("report missing %q:\n%s", want, out)
+		}
+	}
+
+	b.Reset()
+	if writeSelfTestReport(&b, nil, errors.New("cpu: boom"), nil, nil, nil, nil) {
+		t.Fatalf("expected failure when metrics collection fails")
+	}
+	if !strings.Contains(b.String(), "FAIL  cpu: boom") {
+		t.Fatalf("report missing metrics error:\n%s", b.String())
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[uint64]string{512: "512 B", 1536: "1.5 KiB", 5 << 30: "5.0 GiB"} {
+		if got := formatBytes(n); got != want {
+			t.Fatalf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package client
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"runtime"
 	"strings"
 
 	"github.com/google/uuid"
@@ -35,3 +38,60 @@ func bootSessionID() string {
 	identity := fmt.Sprintf("%s:%d", strings.TrimSpace(hostname), bootTime)
 	return bootSessionIDFromIdentity(identity)
 }
+
+func machineIDFromHostID(hostID string) string {
+	hostID = strings.ToLower(strings.TrimSpace(hostID))
+	if hostID == "" {
+		return ""
+	}
+	// Hash so the raw machine ID (which some tools treat as confidential)
+	// never leaves the host.
+	sum := sha256.Sum256([]byte("machinemon:" + hostID))
+	return hex.EncodeToString(sum[:])
+}
+
+// stableMachineID returns a hashed, install-independent identifier for this
+// host that survives reboots, or "" if unavailable. On Linux it is the DMI
+// product UUID, skipping firmware placeholders, or else the systemd/dbus
+// machine ID (see linuxHostID); elsewhere it is the OS host ID, such as
+// IOPlatformUUID on macOS.
+func stableMachineID() string {
+	if runtime.GOOS == "linux" {
+		return machineIDFromHostID(linuxHostID("/sys/class/dmi/id/product_uuid", "/etc/machine-id", "/var/lib/dbus/machine-id"))
+	}
+	hostID, err := host.HostID()
+	if err != nil {
+		return ""
+	}
+	return machineIDFromHostID(hostID)
+}
+
+// placeholderProductUUIDs are firmware defaults shared by many boards; using
+// them would merge unrelated hosts into one client.
+var placeholderProductUUIDs = map[string]bool{
+	"00000000-0000-0000-0000-000000000000": true,
+	"ffffffff-ffff-ffff-ffff-ffffffffffff": true,
+	"03000200-0400-0500-0006-000700080009": true,
+}
+
+// linuxHostID prefers the DMI product UUID (readable by root, survives a
+// reimage), then the systemd/dbus machine ID. Unlike host.HostID it never
+// falls back to the kernel boot_id, which changes on every reboot. Machine IDs
+// are dashed the same way host.HostID does so existing hashes don't change.
+func linuxHostID(productUUIDPath string, machineIDPaths ...string) string {
+	if b, err := os.ReadFile(productUUIDPath); err == nil {
+		if id := strings.ToLower(strings.TrimSpace(string(b))); id != "" && !placeholderProductUUIDs[id] {
+			return id
+		}
+	}
+	for _, path := range machineIDPaths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if id := strings.TrimSpace(string(b)); len(id) == 32 {
+			return fmt.Sprintf("%s-%s-%s-%s-%s", id[0:8], id[8:12], id[12:16], id[16:20], id[20:32])
+		}
+	}
+	return ""
+}
@@ -1,6 +1,10 @@
 package client
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func TestBootSessionIDFromIdentityDeterministic(t *testing.T) {
 	identity := "host-a:1708200000"
@@ -18,3 +22,43 @@ func TestBootSessionIDFromIdentityChanges(t *testing.T) {
 		t.Fatalf("expected different session ids for different boot identities; both were %q", a)
 	}
 }
+
+func TestMachineIDFromHostIDNormalizesAndHashes(t *testing.T) {
+	a := machineIDFromHostID("4C4C4544-0042-3510-8052-B2C04F4A3132\n")
+	b := machineIDFromHostID("4c4c4544-0042-3510-8052-b2c04f4a3132")
+	if a == "" || a != b {
+		t.Fatalf("expected same hashed id for equivalent host ids; got %q vs %q", a, b)
+	}
+	if machineIDFromHostID("  ") != "" {
+		t.Fatalf("expected empty machine id for blank host id")
+	}
+}
+
+func TestLinuxHostIDFallbacks(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	product := write("product_uuid", "4C4C4544-0042-3510-8052-B2C04F4A3132\n")
+	machine := write("machine-id", "0123456789abcdef0123456789abcdef\n")
+	missing := filepath.Join(dir, "missing")
+
+	if got := linuxHostID(product, machine); got != "4c4c4544-0042-3510-8052-b2c04f4a3132" {
+		t.Fatalf("product uuid: got %q", got)
+	}
+	if got := linuxHostID(missing, missing, machine); got != "01234567-89ab-cdef-0123-456789abcdef" {
+		t.Fatalf("machine-id fallback: got %q", got)
+	}
+	placeholder := write("placeholder_uuid", "03000200-0400-0500-0006-000700080009\n")
+	if got := linuxHostID(placeholder, machine); got != "01234567-89ab-cdef-0123-456789abcdef" {
+		t.Fatalf("placeholder product uuid should be skipped, got %q", got)
+	}
+	// Without a persistent ID there is nothing stable to report.
+	if got := linuxHostID(missing, write("short", "abc")); got != "" {
+		t.Fatalf("expected no host id, got %q", got)
+	}
+}
@@ -0,0 +1,191 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+const (
+	// defaultSpoolMaxSamples is used when spool_max_samples is unset.
+	defaultSpoolMaxSamples = 2000
+	// maxSpoolBytes caps the spool's total size whatever spool_max_samples says.
+	maxSpoolBytes = 64 << 20
+	// maxSpoolAge matches the oldest sample the server's batch endpoint accepts.
+	maxSpoolAge = 7 * 24 * time.Hour
+)
+
+// checkInQueue holds check-ins that could not be delivered, oldest first.
+// checkInBuffer keeps them in memory; checkInSpool keeps them on disk so they
+// survive a restart.
+type checkInQueue interface {
+	Add(p models.CheckInRequest) int
+	Len() int
+	Take(n int) []models.CheckInRequest
+	Requeue(items []models.CheckInRequest) int
+}
+
+// checkInSpool stores one JSON file per check-in in dir. File names sort in
+// the order samples were queued. It is only used from the daemon loop and is
+// not safe for concurrent use.
+type checkInSpool struct {
+	dir string
+	max int
+	seq int
+	// taken holds the file names of samples returned by the last Take, so
+	// Requeue can restore them in their original position.
+	taken []string
+}
+
+// newCheckInSpool opens (creating if needed) a spool directory that keeps at
+// most max samples; max <= 0 uses defaultSpoolMaxSamples.
+func newCheckInSpool(dir string, max int) (*checkInSpool, error) {
+	if max <= 0 {
+		max = defaultSpoolMaxSamples
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create spool dir: %w", err)
+	}
+	// Drop partial writes left by a crash.
+	if tmps, err := filepath.Glob(filepath.Join(dir, "*.json.tmp")); err == nil {
+		for _, tmp := range tmps {
+			os.Remove(tmp)
+		}
+	}
+	return &checkInSpool{dir: dir, max: max}, nil
+}
+
+// Add writes a sample and reports how many old samples were pruned.
+func (s *checkInSpool) Add(p models.CheckInRequest) int {
+	s.seq++
+	name := fmt.Sprintf("%019d-%06d.json", time.Now().UnixNano(), s.seq%1000000)
+	if err := s.write(name, p); err != nil {
+		return 1 // the new sample is the one lost
+	}
+	return s.trim()
+}
+
+// Len returns the number of spooled samples.
+func (s *checkInSpool) Len() int {
+	return len(s.files())
+}
+
+// Take removes and returns up to n of the oldest samples. Unreadable files
+// are deleted and skipped.
+func (s *checkInSpool) Take(n int) []models.CheckInRequest {
+	s.taken = s.taken[:0]
+	var out []models.CheckInRequest
+	for _, f := range s.files() {
+		if len(out) == n {
+			break
+		}
+		path := filepath.Join(s.dir, f.name)
+		data, err := os.ReadFile(path)
+		os.Remove(path)
+		if err != nil {
+			continue
+		}
+		var p models.CheckInRequest
+		if err := json.Unmarshal(data, &p); err != nil {
+			continue
+		}
+		out = append(out, p)
+		s.taken = append(s.taken, f.name)
+	}
+	return out
+}
+
+// Requeue writes samples returned by the last Take back under their original
+// names, so they stay ahead of anything spooled since, then prunes.
+func (s *checkInSpool) Requeue(items []models.CheckInRequest) int {
+	lost := 0
+	for i, p := range items {
+		if i >= len(s.taken) {
+			lost += s.Add(p)
+			continue
+		}
+		if err := s.write(s.taken[i], p); err != nil {
+			lost++
+		}
+	}
+	s.taken = s.taken[:0]
+	return lost + s.trim()
+}
+
+func (s *checkInSpool) write(name string, p models.CheckInRequest) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	// Write then rename so a crash never leaves a truncated sample behind.
+	tmp := filepath.Join(s.dir, name+".tmp")
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, name))
+}
+
+type spoolFile struct {
+	name     string
+	size     int64
+	queuedAt time.Time // from the name; zero if it can't be parsed
+}
+
+// files lists spooled samples oldest first.
+func (s *checkInSpool) files() []spoolFile {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil
+	}
+	var files []spoolFile
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		f := spoolFile{name: e.Name(), size: info.Size()}
+		if prefix, _, ok := strings.Cut(e.Name(), "-"); ok {
+			if nanos, err := strconv.ParseInt(prefix, 10, 64); err == nil {
+				f.queuedAt = time.Unix(0, nanos)
+			}
+		}
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+	return files
+}
+
+// trim deletes the oldest samples until the spool is within its sample and
+// byte limits and holds nothing the server would reject as too old. It
+// returns how many were deleted.
+func (s *checkInSpool) trim() int {
+	files := s.files()
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+	cutoff := time.Now().Add(-maxSpoolAge)
+	removed := 0
+	for _, f := range files {
+		if len(files)-removed <= s.max && total <= maxSpoolBytes && f.queuedAt.After(cutoff) {
+			break
+		}
+		if err := os.Remove(filepath.Join(s.dir, f.name)); err != nil && !os.IsNotExist(err) {
+			break
+		}
+		total -= f.size
+		removed++
+	}
+	return removed
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestCheckInSpoolKeepsOrderAcrossRestarts(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "spool")
+	s, err := newCheckInSpool(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dropped := 0
+	for i := int64(1); i <= 5; i++ {
+		dropped += s.Add(models.CheckInRequest{CapturedAt: i})
+	}
+	if dropped != 2 || s.Len() != 3 {
+		t.Fatalf("expected 3 spooled and 2 dropped, got %d spooled and %d dropped", s.Len(), dropped)
+	}
+
+	// A new daemon picks up where the old one left off.
+	os.WriteFile(filepath.Join(dir, "0-1.json.tmp"), []byte("{"), 0600)
+	s, err = newCheckInSpool(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "0-1.json.tmp")); !os.IsNotExist(err) {
+		t.Fatalf("expected partial write to be removed, stat err = %v", err)
+	}
+
+	got := s.Take(2)
+	if len(got) != 2 || got[0].CapturedAt != 3 || got[1].CapturedAt != 4 {
+		t.Fatalf("expected oldest samples 3,4 first, got %+v", got)
+	}
+	if s.Len() != 1 {
+		t.Fatalf("expected 1 sample left after take, got %d", s.Len())
+	}
+
+	// A failed flush puts samples back ahead of anything newer.
+	s.Add(models.CheckInRequest{CapturedAt: 6})
+	if dropped := s.Requeue(got); dropped != 1 {
+		t.Fatalf("expected requeue to drop 1 sample, dropped %d", dropped)
+	}
+	rest := s.Take(10)
+	if len(rest) != 3 || rest[0].CapturedAt != 4 || rest[1].CapturedAt != 5 || rest[2].CapturedAt != 6 {
+		t.Fatalf("expected samples 4,5,6, got %+v", rest)
+	}
+	if s.Len() != 0 {
+		t.Fatalf("expected empty spool, got %d", s.Len())
+	}
+}
+
+func TestSpoolPathRelativeToConfig(t *testing.T) {
+	cfg := &Config{path: "/etc/machinemon/client.toml"}
+	if got := cfg.spoolPath(); got != "" {
+		t.Fatalf("expected spooling off, got %q", got)
+	}
+	cfg.SpoolDir = "spool"
+	if got := cfg.spoolPath(); got != "/etc/machinemon/spool" {
+		t.Fatalf("relative spool_dir = %q", got)
+	}
+	cfg.SpoolDir = "/var/lib/machinemon/spool"
+	if got := cfg.spoolPath(); got != "/var/lib/machinemon/spool" {
+		t.Fatalf("absolute spool_dir = %q", got)
+	}
+}
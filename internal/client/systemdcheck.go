@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+const systemctlTimeout = 10 * time.Second
+
+// runSystemdUnitCheck reports healthy when `systemctl is-active <unit>` says
+// the unit is active. Non-Linux clients report the check as unsupported.
+func runSystemdUnitCheck(check CheckConfig) CheckResult {
+	result := CheckResult{
+		FriendlyName: check.FriendlyName,
+		CheckType:    models.CheckTypeSystemdUnit,
+	}
+	state := models.SystemdUnitCheckState{Unit: strings.TrimSpace(check.Unit)}
+
+	switch {
+	case runtime.GOOS != "linux":
+		result.Message = "systemd unit checks are unsupported on this platform"
+	case state.Unit == "":
+		result.Message = "unit is empty"
+	default:
+		state.ActiveState, state.SubState, state.Error = systemdUnitState(state.Unit)
+		result.Healthy = state.ActiveState == "active"
+		result.Message = systemdUnitMessage(state)
+	}
+	if state.Error == "" && !result.Healthy && state.ActiveState == "" {
+		state.Error = result.Message
+	}
+	data, _ := json.Marshal(state)
+	result.State = string(data)
+	return result
+}
+
+func systemdUnitState(unit string) (activeState, subState, errMsg string) {
+	ctx, cancel := context.WithTimeout(context.Background(), systemctlTimeout)
+	defer cancel()
+
+	// is-active exits non-zero for anything but "active" but still prints the
+	// state, so only treat empty output as a failure.
+	out, err := exec.CommandContext(ctx, "systemctl", "is-active", unit).Output()
+	activeState = parseSystemctlValue(out)
+	if activeState == "" {
+		if err != nil {
+			return "", "", "systemctl is-active: " + err.Error()
+		}
+		return "", "", "systemctl is-active returned no state"
+	}
+
+	out, err = exec.CommandContext(ctx, "systemctl", "show", "--property=SubState", "--value", unit).Output()
+	if err == nil {
+		subState = parseSystemctlValue(out)
+	}
+	return activeState, subState, ""
+}
+
+func parseSystemctlValue(out []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	return strings.TrimSpace(lines[0])
+}
+
+func systemdUnitMessage(state models.SystemdUnitCheckState) string {
+	if state.Error != "" {
+		return state.Error
+	}
+	msg := state.Unit + " is " + state.ActiveState
+	if state.SubState != "" {
+		msg += " (" + state.SubState + ")"
+	}
+	return msg
+}
+
+// ListSystemdServices returns loaded service unit names for the wizard picker.
+// It returns nil on non-Linux hosts or when systemctl is unavailable.
+func ListSystemdServices() []string {
+	if runtime.GOOS != "linux" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), systemctlTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "systemctl", "list-units", "--type=service", "--all", "--no-legend", "--plain", "--no-pager").Output()
+	if err != nil {
+		return nil
+	}
+	var units []string
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || !strings.HasSuffix(fields[0], ".service") {
+			continue
+		}
+		units = append(units, fields[0])
+	}
+	sort.Strings(units)
+	return units
+}
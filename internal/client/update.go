@@ -0,0 +1,240 @@
+package client
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/version"
+)
+
+// maxUpdateArchiveBytes bounds a downloaded client archive.
+const maxUpdateArchiveBytes = 256 << 20
+
+// UpdateResult describes what SelfUpdate did.
+type UpdateResult struct {
+	FromVersion string
+	ToVersion   string
+	// Updated is false when the running build was already current.
+	Updated bool
+}
+
+// downloadListing is the subset of the server's /download/ listing the
+// updater needs.
+type downloadListing struct {
+	Version  string `json:"version"`
+	Binaries []struct {
+		Filename string `json:"filename"`
+		URL      string `json:"url"`
+		SHA256   string `json:"sha256"`
+	} `json:"binaries"`
+}
+
+// SelfUpdate asks the server for its client build for this platform and, if
+// the server runs a newer version, downloads it, checks it against the
+// server's SHA-256, and atomically replaces exePath. Restarting the service is
+// left to the caller. A checksum mismatch leaves exePath untouched.
+func SelfUpdate(cfg *Config, exePath string, logger *slog.Logger) (*UpdateResult, error) {
+	tlsCfg, err := clientTLSConfig(cfg.InsecureSkipTLS, cfg.ClientCert, cfg.ClientKey)
+	if err != nil {
+		return nil, err
+	}
+	httpClient := &http.Client{
+		Timeout:   10 * time.Minute,
+		Transport: &http.Transport{TLSClientConfig: tlsCfg},
+	}
+
+	platform := runtime.GOOS + "-" + releaseArch()
+	listing, err := fetchDownloadListing(httpClient, cfg.ServerURL, platform)
+	if err != nil {
+		return nil, err
+	}
+	res := &UpdateResult{FromVersion: version.Version, ToVersion: listing.Version}
+	if !updateAvailable(version.Version, listing.Version) {
+		return res, nil
+	}
+
+	name := "machinemon-client-" + platform
+	var archiveURL, sum string
+	for _, b := range listing.Binaries {
+		if b.Filename == name+".tar.gz" {
+			archiveURL, sum = b.URL, strings.ToLower(b.SHA256)
+		}
+	}
+	if archiveURL == "" {
+		return nil, fmt.Errorf("server has no client build for %s", platform)
+	}
+	if sum == "" {
+		return nil, fmt.Errorf("server did not provide a checksum for %s.tar.gz", name)
+	}
+
+	logger.Info("downloading client update", "url", archiveURL, "from", res.FromVersion, "to", res.ToVersion)
+	dir := filepath.Dir(exePath)
+	archive, err := downloadVerified(httpClient, archiveURL, sum, dir)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(archive)
+	logger.Info("verified client update checksum", "sha256", sum)
+
+	if err := replaceBinary(archive, name, exePath); err != nil {
+		return nil, err
+	}
+	res.Updated = true
+	return res, nil
+}
+
+// updateAvailable reports whether the server's version should replace the
+// running one. Versions that can't be ordered (e.g. "dev") update whenever
+// they differ; comparable ones never downgrade.
+func updateAvailable(running, server string) bool {
+	if server == "" || server == running {
+		return false
+	}
+	if cmp, ok := version.Compare(server, running); ok {
+		return cmp > 0
+	}
+	return true
+}
+
+// releaseArch names this build's architecture the way release archives do:
+// GOARCH, except 32-bit ARM is armv6 or armv7.
+func releaseArch() string {
+	if runtime.GOARCH != "arm" {
+		return runtime.GOARCH
+	}
+	goarm := "6"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "GOARM" && s.Value != "" {
+				goarm = s.Value[:1]
+			}
+		}
+	}
+	return "armv" + goarm
+}
+
+func downloadBaseURL(serverURL string) string {
+	base := strings.TrimRight(strings.TrimSpace(serverURL), "/")
+	if strings.HasSuffix(base, "/download") {
+		return base
+	}
+	return base + "/download"
+}
+
+func fetchDownloadListing(httpClient *http.Client, serverURL, platform string) (*downloadListing, error) {
+	goos, arch, _ := strings.Cut(platform, "-")
+	q := url.Values{"component": {"client"}, "os": {goos}, "arch": {arch}}
+	resp, err := httpClient.Get(downloadBaseURL(serverURL) + "/?" + q.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("fetch download listing: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch download listing: HTTP %d", resp.StatusCode)
+	}
+	var listing downloadListing
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&listing); err != nil {
+		return nil, fmt.Errorf("decode download listing: %w", err)
+	}
+	return &listing, nil
+}
+
+// downloadVerified saves url to a temporary file in dir and returns its path
+// if its SHA-256 matches wantSum.
+func downloadVerified(httpClient *http.Client, url, wantSum, dir string) (string, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("download update: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download update: HTTP %d", resp.StatusCode)
+	}
+
+	f, err := os.CreateTemp(dir, ".machinemon-client-update-*.tar.gz")
+	if err != nil {
+		return "", fmt.Errorf("create temp file: %w", err)
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), io.LimitReader(resp.Body, maxUpdateArchiveBytes+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > maxUpdateArchiveBytes {
+		err = fmt.Errorf("archive exceeds %d bytes", maxUpdateArchiveBytes)
+	}
+	if err == nil {
+		if got := hex.EncodeToString(h.Sum(nil)); got != wantSum {
+			err = fmt.Errorf("checksum mismatch: expected %s, got %s", wantSum, got)
+		}
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("download update: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// replaceBinary extracts the executable called name from archive next to
+// exePath and renames it over exePath, so the swap is atomic.
+func replaceBinary(archive, name, exePath string) error {
+	src, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	gz, err := gzip.NewReader(src)
+	if err != nil {
+		return fmt.Errorf("read update archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return fmt.Errorf("update archive does not contain %s", name)
+		}
+		if err != nil {
+			return fmt.Errorf("read update archive: %w", err)
+		}
+		if hdr.Typeflag == tar.TypeReg && filepath.Base(hdr.Name) == name {
+			break
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(exePath), ".machinemon-client-new-*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, io.LimitReader(tr, maxUpdateArchiveBytes))
+	if err == nil {
+		err = tmp.Chmod(0755)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("write new binary: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), exePath); err != nil {
+		return fmt.Errorf("replace binary: %w", err)
+	}
+	return nil
+}
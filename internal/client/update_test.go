@@ -0,0 +1,115 @@
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/machinemon/machinemon/internal/version"
+)
+
+func TestUpdateAvailable(t *testing.T) {
+	cases := []struct {
+		running, server string
+		want            bool
+	}{
+		{"v1.2.0", "v1.3.0", true},
+		{"v1.3.0", "v1.3.0", false},
+		{"v1.3.0", "v1.2.0", false},
+		{"dev", "v1.3.0", true},
+		{"v1.3.0", "", false},
+	}
+	for _, c := range cases {
+		if got := updateAvailable(c.running, c.server); got != c.want {
+			t.Errorf("updateAvailable(%q, %q) = %v, want %v", c.running, c.server, got, c.want)
+		}
+	}
+}
+
+func TestSelfUpdate(t *testing.T) {
+	name := "machinemon-client-" + runtime.GOOS + "-" + releaseArch()
+	newBinary := []byte("#!/bin/sh\necho new\n")
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(newBinary)), Typeflag: tar.TypeReg})
+	tw.Write(newBinary)
+	tw.Close()
+	gz.Close()
+	sum := sha256.Sum256(archive.Bytes())
+
+	advertised := hex.EncodeToString(sum[:])
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download/":
+			if r.URL.Query().Get("component") != "client" || r.URL.Query().Get("os") != runtime.GOOS {
+				t.Errorf("unexpected listing query %q", r.URL.RawQuery)
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"version": "v9.0.0",
+				"binaries": []map[string]any{{
+					"filename": name + ".tar.gz",
+					"url":      srv.URL + "/download/" + name + ".tar.gz",
+					"sha256":   advertised,
+				}},
+			})
+		case "/download/" + name + ".tar.gz":
+			w.Write(archive.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	oldVersion := version.Version
+	version.Version = "v1.0.0"
+	defer func() { version.Version = oldVersion }()
+
+	exePath := filepath.Join(t.TempDir(), "machinemon-client")
+	if err := os.WriteFile(exePath, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &Config{ServerURL: srv.URL}
+
+	advertised = hex.EncodeToString(make([]byte, sha256.Size))
+	if _, err := SelfUpdate(cfg, exePath, logger); err == nil {
+		t.Fatal("expected a checksum mismatch error")
+	}
+	if got, _ := os.ReadFile(exePath); string(got) != "old" {
+		t.Fatalf("binary replaced despite checksum mismatch: %q", got)
+	}
+
+	advertised = hex.EncodeToString(sum[:])
+	res, err := SelfUpdate(cfg, exePath, logger)
+	if err != nil {
+		t.Fatalf("SelfUpdate: %v", err)
+	}
+	if !res.Updated || res.ToVersion != "v9.0.0" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if got, _ := os.ReadFile(exePath); !bytes.Equal(got, newBinary) {
+		t.Fatalf("binary not replaced, got %q", got)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(exePath))
+	if len(entries) != 1 {
+		t.Fatalf("temporary files left behind: %v", entries)
+	}
+
+	version.Version = "v9.0.0"
+	if res, err := SelfUpdate(cfg, exePath, logger); err != nil || res.Updated {
+		t.Fatalf("expected no update at the same version, got %+v, %v", res, err)
+	}
+}
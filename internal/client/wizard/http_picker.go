@@ -0,0 +1,230 @@
+package wizard
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/machinemon/machinemon/internal/client"
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func runHTTPCheckPicker(cfg *client.Config) error {
+	for {
+		entries := httpCheckEntries(cfg.Checks)
+		fmt.Println("  Configured HTTP checks:")
+		if len(entries) == 0 {
+			fmt.Println("    <none>")
+		}
+		for _, entry := range entries {
+			fmt.Printf("    - %s (%s, expect %d)\n", entry.Check.FriendlyName, entry.Check.URL, expectedStatus(entry.Check))
+		}
+		fmt.Println()
+
+		options := []huh.Option[string]{
+			huh.NewOption("Add HTTP check", "add"),
+		}
+		if len(entries) > 0 {
+			options = append(options, huh.NewOption("Delete HTTP check", "remove"))
+		}
+		options = append(options, huh.NewOption("Back to setup menu", "done"))
+
+		var action string
+		form := huh.NewForm(
+			huh.NewGroup(
+				huh.NewSelect[string]().
+					Title("HTTP checks").
+					Description("Alert when a URL doesn't return the expected status code.").
+					Options(options...).
+					Value(&action),
+			),
+		)
+		if err := form.Run(); err != nil {
+			return err
+		}
+
+		switch action {
+		case "add":
+			if err := addHTTPCheck(cfg); err != nil {
+				return err
+			}
+		case "remove":
+			if err := removeHTTPCheck(cfg); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+func addHTTPCheck(cfg *client.Config) error {
+	var rawURL string
+	statusStr := "200"
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title("URL").
+				Description("Requested with GET; redirects are followed.").
+				Placeholder("http://localhost:8080/health").
+				Validate(validateCheckURL).
+				Value(&rawURL),
+			huh.NewInput().
+				Title("Expected status code").
+				Validate(validateStatusCode).
+				Value(&statusStr),
+		),
+	)
+	if err := form.Run(); err != nil {
+		return err
+	}
+	rawURL = strings.TrimSpace(rawURL)
+	status, _ := strconv.Atoi(strings.TrimSpace(statusStr))
+
+	existingNames := make(map[string]bool, len(cfg.Checks))
+	for _, c := range cfg.Checks {
+		existingNames[strings.ToLower(strings.TrimSpace(c.FriendlyName))] = true
+	}
+	friendlyName := suggestHTTPCheckName(rawURL)
+	var testNow bool
+	detailsForm := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Friendly name").
+				Description("Shown in dashboard and alerts.").
+				Value(&friendlyName),
+			huh.NewConfirm().
+				Title("Test the check now?").
+				Value(&testNow),
+		),
+	)
+	if err := detailsForm.Run(); err != nil {
+		return err
+	}
+
+	check := client.CheckConfig{
+		FriendlyName: uniqueFriendlyName(friendlyName, existingNames),
+		Type:         models.CheckTypeHTTP,
+		URL:          rawURL,
+	}
+	// 200 is the default; leave it out of the TOML.
+	if status != 200 {
+		check.ExpectedStatus = status
+	}
+
+	if testNow {
+		fmt.Printf("\n  Requesting %s... ", rawURL)
+		result := client.RunChecks([]client.CheckConfig{check})[0]
+		if result.Healthy {
+			fmt.Printf("OK (%s)\n\n", result.Message)
+		} else {
+			fmt.Printf("FAILED\n  %s\n\n", result.Message)
+			var keep bool
+			keepForm := huh.NewForm(
+				huh.NewGroup(
+					huh.NewConfirm().
+						Title("The check failed. Save it anyway?").
+						Value(&keep),
+				),
+			)
+			if err := keepForm.Run(); err != nil {
+				return err
+			}
+			if !keep {
+				fmt.Println("  Discarded.")
+				fmt.Println()
+				return nil
+			}
+		}
+	}
+
+	cfg.Checks = append(cfg.Checks, check)
+	fmt.Printf("  Added HTTP check: %s\n\n", check.FriendlyName)
+	return nil
+}
+
+func removeHTTPCheck(cfg *client.Config) error {
+	entries := httpCheckEntries(cfg.Checks)
+	options := make([]huh.Option[string], 0, len(entries)+1)
+	options = append(options, huh.NewOption("< Back >", "back"))
+	for _, entry := range entries {
+		label := fmt.Sprintf("%s (%s)", entry.Check.FriendlyName, entry.Check.URL)
+		options = append(options, huh.NewOption(label, strconv.Itoa(entry.Index)))
+	}
+
+	var choice string
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Select one HTTP check to delete").
+				Options(options...).
+				Value(&choice),
+		),
+	)
+	if err := form.Run(); err != nil {
+		return err
+	}
+	idx, err := strconv.Atoi(choice)
+	if err != nil || idx < 0 || idx >= len(cfg.Checks) {
+		return nil
+	}
+	removed := cfg.Checks[idx]
+	cfg.Checks = append(cfg.Checks[:idx], cfg.Checks[idx+1:]...)
+	fmt.Printf("  Removed: %s\n\n", removed.FriendlyName)
+	return nil
+}
+
+func httpCheckEntries(checks []client.CheckConfig) []scriptCheckEntry {
+	var entries []scriptCheckEntry
+	for i, check := range checks {
+		if strings.TrimSpace(strings.ToLower(check.Type)) == models.CheckTypeHTTP {
+			entries = append(entries, scriptCheckEntry{Index: i, Check: check})
+		}
+	}
+	return entries
+}
+
+func expectedStatus(check client.CheckConfig) int {
+	if check.ExpectedStatus == 0 {
+		return 200
+	}
+	return check.ExpectedStatus
+}
+
+// validateCheckURL requires an absolute http(s) URL with a host.
+func validateCheckURL(raw string) error {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return fmt.Errorf("invalid URL: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("URL must start with http:// or https://")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("URL must include a host")
+	}
+	return nil
+}
+
+func validateStatusCode(raw string) error {
+	n, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || n < 100 || n > 599 {
+		return fmt.Errorf("enter an HTTP status code between 100 and 599")
+	}
+	return nil
+}
+
+// suggestHTTPCheckName uses the URL's host, plus its path when there is one.
+func suggestHTTPCheckName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "http-check"
+	}
+	name := u.Hostname()
+	if path := strings.Trim(u.Path, "/"); path != "" {
+		name += "/" + path
+	}
+	return name
+}
@@ -0,0 +1,115 @@
+package wizard
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/machinemon/machinemon/internal/client"
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+// runInitialSettingsForm asks for the display name and metric thresholds the
+// server applies when it first registers this client.
+func runInitialSettingsForm(cfg *client.Config) error {
+	if cfg.ClientID != "" {
+		fmt.Println("  This client is already registered; these settings only apply to a")
+		fmt.Println("  new registration. Change them in the dashboard instead.")
+		fmt.Println()
+	}
+
+	in := client.InitialSettings{}
+	if cfg.Initial != nil {
+		in = *cfg.Initial
+	}
+	name := in.Name
+	setThresholds := in.HasThresholds()
+	if !setThresholds {
+		// Suggest the server's built-in defaults.
+		d := models.DefaultThresholds
+		in.CPUWarnPct, in.CPUCritPct = d.CPUWarnPct, d.CPUCritPct
+		in.MemWarnPct, in.MemCritPct = d.MemWarnPct, d.MemCritPct
+		in.DiskWarnPct, in.DiskCritPct = d.DiskWarnPct, d.DiskCritPct
+	}
+
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Display name").
+				Description("Shown in the dashboard instead of the hostname. Leave blank to use the hostname.").
+				Validate(func(s string) error {
+					if len(strings.TrimSpace(s)) > 255 {
+						return fmt.Errorf("must be 255 characters or fewer")
+					}
+					return nil
+				}).
+				Value(&name),
+			huh.NewConfirm().
+				Title("Set custom alert thresholds?").
+				Description("No uses the server's global thresholds.").
+				Value(&setThresholds),
+		),
+	)
+	if err := form.Run(); err != nil {
+		return err
+	}
+
+	if setThresholds {
+		fields := []struct {
+			title      string
+			warn, crit *float64
+		}{
+			{"CPU", &in.CPUWarnPct, &in.CPUCritPct},
+			{"Memory", &in.MemWarnPct, &in.MemCritPct},
+			{"Disk", &in.DiskWarnPct, &in.DiskCritPct},
+		}
+		warnStrs := make([]string, len(fields))
+		critStrs := make([]string, len(fields))
+		var inputs []huh.Field
+		for i, f := range fields {
+			warnStrs[i] = formatPct(*f.warn)
+			critStrs[i] = formatPct(*f.crit)
+			inputs = append(inputs,
+				huh.NewInput().Title(f.title+" warning %").Validate(validatePct).Value(&warnStrs[i]),
+				huh.NewInput().Title(f.title+" critical %").Validate(validatePct).Value(&critStrs[i]),
+			)
+		}
+		if err := huh.NewForm(huh.NewGroup(inputs...)).Run(); err != nil {
+			return err
+		}
+		for i, f := range fields {
+			warn, _ := strconv.ParseFloat(strings.TrimSpace(warnStrs[i]), 64)
+			crit, _ := strconv.ParseFloat(strings.TrimSpace(critStrs[i]), 64)
+			if warn > crit {
+				fmt.Printf("  %s warning is above critical; using %s for both.\n\n", f.title, formatPct(crit))
+				warn = crit
+			}
+			*f.warn, *f.crit = warn, crit
+		}
+	} else {
+		in.CPUWarnPct, in.CPUCritPct = 0, 0
+		in.MemWarnPct, in.MemCritPct = 0, 0
+		in.DiskWarnPct, in.DiskCritPct = 0, 0
+	}
+
+	in.Name = strings.TrimSpace(name)
+	if in.Name == "" && !in.HasThresholds() {
+		cfg.Initial = nil
+	} else {
+		cfg.Initial = &in
+	}
+	return nil
+}
+
+func validatePct(s string) error {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || v <= 0 || v > 100 {
+		return fmt.Errorf("enter a percentage between 0 and 100")
+	}
+	return nil
+}
+
+func formatPct(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
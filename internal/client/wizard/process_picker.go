@@ -3,6 +3,7 @@ package wizard
 import (
 	"fmt"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -16,6 +17,7 @@ func runProcessPicker(cfg *client.Config) error {
 
 		options := []huh.Option[string]{
 			huh.NewOption("Add process to monitor", "add"),
+			huh.NewOption("Add process by match pattern (not running yet)", "manual"),
 		}
 		if len(cfg.Processes) > 0 {
 			options = append(options, huh.NewOption("Stop monitoring existing process(es)", "remove"))
@@ -41,6 +43,10 @@ func runProcessPicker(cfg *client.Config) error {
 			if err := maybeAddProcesses(cfg); err != nil {
 				return err
 			}
+		case "manual":
+			if err := addProcessManually(cfg); err != nil {
+				return err
+			}
 		case "remove":
 			if err := maybeRemoveProcesses(cfg); err != nil {
 				return err
@@ -119,7 +125,7 @@ func maybeAddProcesses(cfg *client.Config) error {
 		return fmt.Errorf("list processes: %w", err)
 	}
 	if len(candidates) == 0 {
-		fmt.Println("  No suitable processes found.")
+		fmt.Println("  No suitable processes found. Use \"Add process by match pattern\" for services that aren't running yet.")
 		return nil
 	}
 
@@ -176,7 +182,45 @@ func maybeAddProcesses(cfg *client.Config) error {
 		c := candidates[idx]
 		suggestedName := client.SuggestFriendlyName(c)
 		matchPattern := client.SuggestMatchPattern(c)
-		if isAlreadyMonitored(cfg.Processes, matchPattern) {
+
+		matchType := client.MatchTypeSubstring
+		typeForm := huh.NewForm(
+			huh.NewGroup(
+				huh.NewSelect[string]().
+					Title("How should this process be matched?").
+					Description(fmt.Sprintf("Suggested substring: %s", truncate(matchPattern, 60))).
+					Options(
+						huh.NewOption("Substring (use suggested pattern)", client.MatchTypeSubstring),
+						huh.NewOption("Regular expression", client.MatchTypeRegex),
+					).
+					Value(&matchType),
+			),
+		)
+		if err := typeForm.Run(); err != nil {
+			return err
+		}
+		if matchType == client.MatchTypeRegex {
+			matchPattern = "^" + regexp.QuoteMeta(matchPattern)
+			regexForm := huh.NewForm(
+				huh.NewGroup(
+					huh.NewInput().
+						Title("Regular expression").
+						Description(fmt.Sprintf("Matched against: %s", truncate(c.Cmdline, 60))).
+						Value(&matchPattern).
+						Validate(func(pattern string) error {
+							return client.ValidateProcessMatch(pattern, client.MatchTypeRegex)
+						}),
+				),
+			)
+			if err := regexForm.Run(); err != nil {
+				return err
+			}
+			if re := regexp.MustCompile(matchPattern); !re.MatchString(c.Cmdline) {
+				fmt.Printf("  Note: %s does not match the selected process right now.\n", matchPattern)
+			}
+		}
+
+		if isAlreadyMonitored(cfg.Processes, matchPattern, matchType) {
 			fmt.Printf("  Already monitored: %s\n\n", matchPattern)
 			continue
 		}
@@ -202,7 +246,7 @@ func maybeAddProcesses(cfg *client.Config) error {
 		cfg.Processes = append(cfg.Processes, client.ProcessConfig{
 			FriendlyName: friendlyName,
 			MatchPattern: matchPattern,
-			MatchType:    "substring",
+			MatchType:    matchType,
 		})
 		added++
 		fmt.Printf("  Added: %s (%s)\n\n", friendlyName, matchPattern)
@@ -232,9 +276,64 @@ func maybeAddProcesses(cfg *client.Config) error {
 	return nil
 }
 
-func isAlreadyMonitored(processes []client.ProcessConfig, matchPattern string) bool {
+// addProcessManually adds a process from a typed-in pattern, for services
+// that aren't running during setup and so can't be picked from the scan.
+func addProcessManually(cfg *client.Config) error {
+	var friendlyName, matchPattern string
+	matchType := client.MatchTypeSubstring
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Friendly name").
+				Description("Shown in dashboard and alerts.").
+				Value(&friendlyName),
+			huh.NewSelect[string]().
+				Title("Match type").
+				Options(
+					huh.NewOption("Substring", client.MatchTypeSubstring),
+					huh.NewOption("Regular expression", client.MatchTypeRegex),
+				).
+				Value(&matchType),
+			huh.NewInput().
+				Title("Match pattern").
+				Description("Matched against the full command line, e.g. \"node server.js\".").
+				Value(&matchPattern).
+				Validate(func(pattern string) error {
+					return client.ValidateProcessMatch(strings.TrimSpace(pattern), matchType)
+				}),
+		),
+	)
+	if err := form.Run(); err != nil {
+		return err
+	}
+	matchPattern = strings.TrimSpace(matchPattern)
+
+	if isAlreadyMonitored(cfg.Processes, matchPattern, matchType) {
+		fmt.Printf("  Already monitored: %s\n\n", matchPattern)
+		return nil
+	}
+
+	existingNames := make(map[string]bool, len(cfg.Processes))
+	for _, p := range cfg.Processes {
+		existingNames[strings.ToLower(strings.TrimSpace(p.FriendlyName))] = true
+	}
+	if strings.TrimSpace(friendlyName) == "" {
+		friendlyName = matchPattern
+	}
+	friendlyName = uniqueFriendlyName(friendlyName, existingNames)
+
+	cfg.Processes = append(cfg.Processes, client.ProcessConfig{
+		FriendlyName: friendlyName,
+		MatchPattern: matchPattern,
+		MatchType:    matchType,
+	})
+	fmt.Printf("  Added: %s (%s)\n\n", friendlyName, matchPattern)
+	return nil
+}
+
+func isAlreadyMonitored(processes []client.ProcessConfig, matchPattern, matchType string) bool {
 	for _, p := range processes {
-		if normalizeMatchType(p.MatchType) == "substring" && p.MatchPattern == matchPattern {
+		if normalizeMatchType(p.MatchType) == matchType && p.MatchPattern == matchPattern {
 			return true
 		}
 	}
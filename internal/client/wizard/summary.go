@@ -14,8 +14,24 @@ func runSummary(cfg *client.Config) (bool, error) {
 	fmt.Printf("  │ Password: %-28s │\n", "********")
 	fmt.Printf("  │ TLS Skip: %-28v │\n", cfg.InsecureSkipTLS)
 	fmt.Printf("  │ Interval: %-28s │\n", fmt.Sprintf("%d seconds", cfg.CheckInInterval))
+	if in := cfg.Initial; in != nil {
+		if in.Name != "" {
+			fmt.Printf("  │ Name:     %-28s │\n", truncate(in.Name, 28))
+		}
+		if in.HasThresholds() {
+			fmt.Printf("  │ Thresholds: %-26s │\n", fmt.Sprintf("cpu %s/%s mem %s/%s disk %s/%s",
+				formatPct(in.CPUWarnPct), formatPct(in.CPUCritPct), formatPct(in.MemWarnPct),
+				formatPct(in.MemCritPct), formatPct(in.DiskWarnPct), formatPct(in.DiskCritPct)))
+		}
+	}
 	fmt.Printf("  │ Processes: %-27d │\n", len(cfg.Processes))
 	fmt.Printf("  │ Script checks: %-23d │\n", scriptCheckCount(cfg.Checks))
+	if https := httpCheckEntries(cfg.Checks); len(https) > 0 {
+		fmt.Printf("  │ HTTP checks: %-25d │\n", len(https))
+	}
+	if units := systemdUnitCheckEntries(cfg.Checks); len(units) > 0 {
+		fmt.Printf("  │ systemd units: %-23d │\n", len(units))
+	}
 
 	for _, p := range cfg.Processes {
 		fmt.Printf("  │   - %-33s │\n", truncate(p.FriendlyName, 33))
@@ -27,6 +43,12 @@ func runSummary(cfg *client.Config) (bool, error) {
 		}
 		fmt.Printf("  │   * %-33s │\n", truncate(display, 33))
 	}
+	for _, check := range httpCheckEntries(cfg.Checks) {
+		fmt.Printf("  │   * %-33s │\n", truncate(check.Check.FriendlyName+" ("+check.Check.URL+")", 33))
+	}
+	for _, check := range systemdUnitCheckEntries(cfg.Checks) {
+		fmt.Printf("  │   * %-33s │\n", truncate(check.Check.FriendlyName+" ("+check.Check.Unit+")", 33))
+	}
 
 	fmt.Println("  └────────────────────────────────────────┘")
 	fmt.Println()
@@ -0,0 +1,178 @@
+package wizard
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/machinemon/machinemon/internal/client"
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func runSystemdUnitPicker(cfg *client.Config) error {
+	if runtime.GOOS != "linux" {
+		fmt.Println("  systemd unit checks are only supported on Linux.")
+		fmt.Println()
+		return nil
+	}
+	for {
+		entries := systemdUnitCheckEntries(cfg.Checks)
+		fmt.Println("  Configured systemd unit checks:")
+		if len(entries) == 0 {
+			fmt.Println("    <none>")
+		}
+		for _, entry := range entries {
+			fmt.Printf("    - %s (%s)\n", entry.Check.FriendlyName, entry.Check.Unit)
+		}
+		fmt.Println()
+
+		options := []huh.Option[string]{
+			huh.NewOption("Add systemd unit check", "add"),
+		}
+		if len(entries) > 0 {
+			options = append(options, huh.NewOption("Delete systemd unit check", "remove"))
+		}
+		options = append(options, huh.NewOption("Back to setup menu", "done"))
+
+		var action string
+		form := huh.NewForm(
+			huh.NewGroup(
+				huh.NewSelect[string]().
+					Title("systemd unit checks").
+					Description("Alert when a unit is not active (systemctl is-active).").
+					Options(options...).
+					Value(&action),
+			),
+		)
+		if err := form.Run(); err != nil {
+			return err
+		}
+
+		switch action {
+		case "add":
+			if err := addSystemdUnitCheck(cfg); err != nil {
+				return err
+			}
+		case "remove":
+			if err := removeSystemdUnitCheck(cfg); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+func addSystemdUnitCheck(cfg *client.Config) error {
+	var unit string
+	units := client.ListSystemdServices()
+	if len(units) > 0 {
+		options := make([]huh.Option[string], 0, len(units)+1)
+		options = append(options, huh.NewOption("< Enter a unit name manually >", ""))
+		for _, u := range units {
+			options = append(options, huh.NewOption(u, u))
+		}
+		form := huh.NewForm(
+			huh.NewGroup(
+				huh.NewSelect[string]().
+					Title("Select a unit").
+					Description("Type to filter. Enter to select.").
+					Filtering(true).
+					Height(14).
+					Options(options...).
+					Value(&unit),
+			),
+		)
+		if err := form.Run(); err != nil {
+			return err
+		}
+	}
+	if unit == "" {
+		form := huh.NewForm(
+			huh.NewGroup(
+				huh.NewInput().
+					Title("Unit name").
+					Placeholder("nginx.service").
+					Value(&unit),
+			),
+		)
+		if err := form.Run(); err != nil {
+			return err
+		}
+	}
+	unit = strings.TrimSpace(unit)
+	if unit == "" {
+		fmt.Println("  Unit name cannot be empty.")
+		fmt.Println()
+		return nil
+	}
+
+	existingNames := make(map[string]bool, len(cfg.Checks))
+	for _, c := range cfg.Checks {
+		existingNames[strings.ToLower(strings.TrimSpace(c.FriendlyName))] = true
+	}
+	friendlyName := strings.TrimSuffix(unit, ".service")
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Friendly name").
+				Description("Shown in dashboard and alerts.").
+				Value(&friendlyName),
+		),
+	)
+	if err := form.Run(); err != nil {
+		return err
+	}
+
+	friendlyName = uniqueFriendlyName(friendlyName, existingNames)
+	cfg.Checks = append(cfg.Checks, client.CheckConfig{
+		FriendlyName: friendlyName,
+		Type:         models.CheckTypeSystemdUnit,
+		Unit:         unit,
+	})
+	fmt.Printf("  Added systemd unit check: %s\n\n", friendlyName)
+	return nil
+}
+
+func removeSystemdUnitCheck(cfg *client.Config) error {
+	entries := systemdUnitCheckEntries(cfg.Checks)
+	options := make([]huh.Option[string], 0, len(entries)+1)
+	options = append(options, huh.NewOption("< Back >", "back"))
+	for _, entry := range entries {
+		label := fmt.Sprintf("%s (%s)", entry.Check.FriendlyName, entry.Check.Unit)
+		options = append(options, huh.NewOption(label, strconv.Itoa(entry.Index)))
+	}
+
+	var choice string
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Select one systemd unit check to delete").
+				Options(options...).
+				Value(&choice),
+		),
+	)
+	if err := form.Run(); err != nil {
+		return err
+	}
+	idx, err := strconv.Atoi(choice)
+	if err != nil || idx < 0 || idx >= len(cfg.Checks) {
+		return nil
+	}
+	removed := cfg.Checks[idx]
+	cfg.Checks = append(cfg.Checks[:idx], cfg.Checks[idx+1:]...)
+	fmt.Printf("  Removed: %s\n\n", removed.FriendlyName)
+	return nil
+}
+
+func systemdUnitCheckEntries(checks []client.CheckConfig) []scriptCheckEntry {
+	var entries []scriptCheckEntry
+	for i, check := range checks {
+		if strings.TrimSpace(strings.ToLower(check.Type)) == models.CheckTypeSystemdUnit {
+			entries = append(entries, scriptCheckEntry{Index: i, Check: check})
+		}
+	}
+	return entries
+}
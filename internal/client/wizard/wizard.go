@@ -38,6 +38,10 @@ func Run(existingConfig *client.Config) (*client.Config, error) {
 			if err := runServerForm(cfg); err != nil {
 				return nil, fmt.Errorf("server setup: %w", err)
 			}
+		case "initial":
+			if err := runInitialSettingsForm(cfg); err != nil {
+				return nil, fmt.Errorf("initial settings: %w", err)
+			}
 		case "processes":
 			if err := runProcessPicker(cfg); err != nil {
 				return nil, fmt.Errorf("process picker: %w", err)
@@ -46,6 +50,14 @@ func Run(existingConfig *client.Config) (*client.Config, error) {
 			if err := runScriptCheckPicker(cfg); err != nil {
 				return nil, fmt.Errorf("script check picker: %w", err)
 			}
+		case "http":
+			if err := runHTTPCheckPicker(cfg); err != nil {
+				return nil, fmt.Errorf("http check picker: %w", err)
+			}
+		case "systemd":
+			if err := runSystemdUnitPicker(cfg); err != nil {
+				return nil, fmt.Errorf("systemd unit picker: %w", err)
+			}
 		case "save":
 			if !cfg.IsConfigured() {
 				fmt.Println("  Server URL and client password are required before saving.")
@@ -81,8 +93,11 @@ func runSetupMenu(cfg *client.Config) (string, error) {
 				Description(fmt.Sprintf("Server: %s | Processes: %s | Scripts: %s", truncate(serverLabel, 26), procLabel, checkLabel)).
 				Options(
 					huh.NewOption("Configure server settings", "server"),
+					huh.NewOption("Set display name and thresholds", "initial"),
 					huh.NewOption("Configure monitored processes", "processes"),
 					huh.NewOption("Configure script checks", "checks"),
+					huh.NewOption("Configure HTTP checks", "http"),
+					huh.NewOption("Configure systemd unit checks", "systemd"),
 					huh.NewOption("Save and exit", "save"),
 					huh.NewOption("Cancel setup", "cancel"),
 				).
@@ -4,17 +4,61 @@ import "time"
 
 // CheckInRequest is sent by the client to the server every check-in interval.
 type CheckInRequest struct {
-	Hostname      string           `json:"hostname"`
-	OS            string           `json:"os"`
-	Arch          string           `json:"arch"`
-	ClientVersion string           `json:"client_version"`
-	ClientID      string           `json:"client_id,omitempty"`
-	SessionID     string           `json:"session_id,omitempty"`
-	BootTimeUnix  int64            `json:"boot_time_unix,omitempty"`
-	InterfaceIPs  []string         `json:"interface_ips,omitempty"`
-	Metrics       MetricsPayload   `json:"metrics"`
-	Processes     []ProcessPayload `json:"processes"`
-	Checks        []CheckPayload   `json:"checks,omitempty"`
+	Hostname         string             `json:"hostname"`
+	OS               string             `json:"os"`
+	Arch             string             `json:"arch"`
+	ClientVersion    string             `json:"client_version"`
+	ClientID         string             `json:"client_id,omitempty"`
+	SessionID        string             `json:"session_id,omitempty"`
+	MachineID        string             `json:"machine_id,omitempty"` // hashed stable host identity, optional
+	BootTimeUnix     int64              `json:"boot_time_unix,omitempty"`
+	InterfaceIPs     []string           `json:"interface_ips,omitempty"`
+	CapturedAt       int64              `json:"captured_at,omitempty"`        // unix seconds when the sample was collected
+	CheckInInterval  int                `json:"check_in_interval,omitempty"`  // seconds between check-ins the client is currently using
+	CheckInLatencyMs int64              `json:"checkin_latency_ms,omitempty"` // round-trip time of the client's previous successful check-in
+	Metrics          MetricsPayload     `json:"metrics"`
+	Processes        []ProcessPayload   `json:"processes"`
+	Checks           []CheckPayload     `json:"checks,omitempty"`
+	DiskMounts       []DiskMountPayload `json:"disk_mounts,omitempty"`
+	Errors           []CollectionError  `json:"errors,omitempty"`         // parts of this check-in the client failed to collect
+	HeartbeatOnly    bool               `json:"heartbeat_only,omitempty"` // client collected nothing; Metrics is empty
+	// Initial is sent until the client has a client_id; the server applies it
+	// only when the check-in creates the client.
+	Initial *InitialClientSettings `json:"initial,omitempty"`
+}
+
+// InitialClientSettings carries the display name and metric thresholds chosen
+// in the client's setup wizard. Thresholds uses only the cpu/mem/disk values.
+type InitialClientSettings struct {
+	CustomName string      `json:"custom_name,omitempty"`
+	Thresholds *Thresholds `json:"thresholds,omitempty"`
+}
+
+// CollectionError describes data the client could not collect for a
+// check-in. Source is one of the CollectionSource constants.
+type CollectionError struct {
+	Source  string `json:"source"`
+	Message string `json:"message"`
+}
+
+// Collection error sources. The server skips storing data from a failed
+// source rather than recording zeros or an empty process list.
+const (
+	CollectionSourceMetrics   = "metrics"
+	CollectionSourceProcesses = "processes"
+)
+
+// DiskMountPayload reports usage for one additional monitored mount path.
+// WarnPct/CritPct are optional per-mount thresholds from the client config;
+// zero means fall back to the client's disk thresholds.
+type DiskMountPayload struct {
+	Path        string  `json:"path"`
+	TotalBytes  uint64  `json:"total_bytes"`
+	UsedBytes   uint64  `json:"used_bytes"`
+	UsedPercent float64 `json:"used_pct"`
+	WarnPct     float64 `json:"warn_pct,omitempty"`
+	CritPct     float64 `json:"crit_pct,omitempty"`
+	Error       string  `json:"error,omitempty"` // set when the path couldn't be read
 }
 
 // CheckPayload reports the result of a client-side check.
@@ -26,15 +70,20 @@ type CheckPayload struct {
 	FriendlyName string `json:"friendly_name"`
 	CheckType    string `json:"check_type"` // "script", "http", "file_touch", ...
 	Healthy      bool   `json:"healthy"`
-	Message      string `json:"message,omitempty"` // human-readable status summary
-	State        string `json:"state,omitempty"`   // JSON blob with type-specific details
+	Message      string `json:"message,omitempty"`  // human-readable status summary
+	State        string `json:"state,omitempty"`    // JSON blob with type-specific details
+	Severity     string `json:"severity,omitempty"` // failure alert severity; empty means critical
+	// Failed runs in a row before check_failed fires; 0 uses the server default.
+	ConsecutiveFailures int `json:"consecutive_failures,omitempty"`
 }
 
 // Well-known check types. New types can be added without changing the server.
 const (
-	CheckTypeScript    = "script"
-	CheckTypeHTTP      = "http"
-	CheckTypeFileTouch = "file_touch"
+	CheckTypeScript      = "script"
+	CheckTypeHTTP        = "http"
+	CheckTypeFileTouch   = "file_touch"
+	CheckTypeDNS         = "dns"
+	CheckTypeSystemdUnit = "systemd_unit"
 )
 
 // ScriptCheckState is the state blob for CheckTypeScript checks.
@@ -45,7 +94,7 @@ type ScriptCheckState struct {
 	Output     string `json:"output,omitempty"`
 }
 
-// HTTPCheckState is the state blob for CheckTypeHTTP checks (future).
+// HTTPCheckState is the state blob for CheckTypeHTTP checks.
 type HTTPCheckState struct {
 	URL            string `json:"url"`
 	ExpectedStatus int    `json:"expected_status,omitempty"`
@@ -62,33 +111,90 @@ type FileTouchCheckState struct {
 	AgeSecs      int    `json:"age_secs,omitempty"`
 }
 
+// SystemdUnitCheckState is the state blob for CheckTypeSystemdUnit checks.
+type SystemdUnitCheckState struct {
+	Unit        string `json:"unit"`
+	ActiveState string `json:"active_state,omitempty"` // e.g. active, inactive, failed
+	SubState    string `json:"sub_state,omitempty"`    // e.g. running, exited, dead
+	Error       string `json:"error,omitempty"`
+}
+
+// DNSCheckState is the state blob for CheckTypeDNS checks.
+type DNSCheckState struct {
+	Hostname   string   `json:"hostname"`
+	RecordType string   `json:"record_type"`
+	Resolver   string   `json:"resolver,omitempty"`
+	Records    []string `json:"records,omitempty"`
+	DurationMs int64    `json:"duration_ms"`
+	Error      string   `json:"error,omitempty"`
+}
+
 type MetricsPayload struct {
-	CPUPercent     float64 `json:"cpu_pct"`
-	MemPercent     float64 `json:"mem_pct"`
-	MemTotalBytes  uint64  `json:"mem_total_bytes"`
-	MemUsedBytes   uint64  `json:"mem_used_bytes"`
-	DiskPercent    float64 `json:"disk_pct"`
-	DiskTotalBytes uint64  `json:"disk_total_bytes"`
-	DiskUsedBytes  uint64  `json:"disk_used_bytes"`
+	CPUPercent     float64  `json:"cpu_pct"`
+	MemPercent     float64  `json:"mem_pct"`
+	MemTotalBytes  uint64   `json:"mem_total_bytes"`
+	MemUsedBytes   uint64   `json:"mem_used_bytes"`
+	DiskPercent    float64  `json:"disk_pct"`
+	DiskTotalBytes uint64   `json:"disk_total_bytes"`
+	DiskUsedBytes  uint64   `json:"disk_used_bytes"`
+	NetRxBytes     uint64   `json:"net_rx_bytes,omitempty"` // cumulative since boot, all non-loopback interfaces
+	NetTxBytes     uint64   `json:"net_tx_bytes,omitempty"`
+	SwapPercent    float64  `json:"swap_pct"` // all zero on hosts without swap
+	SwapTotalBytes uint64   `json:"swap_total_bytes"`
+	SwapUsedBytes  uint64   `json:"swap_used_bytes"`
+	CPUTempC       *float64 `json:"cpu_temp_c,omitempty"` // nil when the host has no CPU sensor
+	OpenFDs        *int64   `json:"open_fds,omitempty"`   // system-wide; nil where the platform can't report it
+	ProcessCount   *int64   `json:"process_count,omitempty"`
+	// DiskInodesUsedPercent is inode usage on the primary disk; nil on Windows
+	// and filesystems without fixed inode tables.
+	DiskInodesUsedPercent *float64 `json:"disk_inodes_pct,omitempty"`
 }
 
 type ProcessPayload struct {
 	FriendlyName string  `json:"friendly_name"`
 	MatchPattern string  `json:"match_pattern"`
+	MatchType    string  `json:"match_type,omitempty"` // "substring" (default) or "regex"
 	IsRunning    bool    `json:"is_running"`
 	PID          int32   `json:"pid,omitempty"`
 	CPUPercent   float64 `json:"cpu_pct,omitempty"`
 	MemPercent   float64 `json:"mem_pct,omitempty"`
 	Cmdline      string  `json:"cmdline,omitempty"`
+	NumFDs       int32   `json:"num_fds,omitempty"`     // open file descriptors/handles; 0 if unavailable
+	NumThreads   int32   `json:"num_threads,omitempty"` // 0 if unavailable
+	// Optional per-process alert thresholds from the client config; 0 disables.
+	CPUAlertPct float64 `json:"cpu_alert_pct,omitempty"`
+	MemAlertPct float64 `json:"mem_alert_pct,omitempty"`
+	// AlertOnStart opts this process into process_started alerts.
+	AlertOnStart bool `json:"alert_on_start,omitempty"`
+	// InstanceCount is how many processes matched. Aggregated processes sum
+	// usage across all of them; older clients omit it.
+	InstanceCount int `json:"instance_count,omitempty"`
+	// MinInstances alerts when fewer instances are running; 0 disables.
+	MinInstances int `json:"min_instances,omitempty"`
 }
 
 // CheckInResponse is returned to the client after a successful check-in.
+// NextCheckInSeconds is the admin-set interval for the client, or 0 to keep
+// the client's configured interval.
 type CheckInResponse struct {
 	ClientID           string    `json:"client_id"`
 	NextCheckInSeconds int       `json:"next_checkin_seconds"`
 	ServerTime         time.Time `json:"server_time"`
 }
 
+// ExternalCheckRequest is a single check result pushed from outside the
+// client agent (CI jobs, external schedulers) for an existing client.
+type ExternalCheckRequest struct {
+	ClientID string `json:"client_id"`
+	CheckPayload
+}
+
+// CheckInBatchResponse reports how many buffered samples the server stored.
+type CheckInBatchResponse struct {
+	Accepted int `json:"accepted"`
+	Rejected int `json:"rejected"`
+}
+
 // ClientAlertMute stores per-client scoped alert mute rules.
 // Scope values: "cpu", "memory", "disk", "process", "check".
 type ClientAlertMute struct {
@@ -99,6 +205,112 @@ type ClientAlertMute struct {
 	CreatedAt time.Time `json:"created_at,omitempty"`
 }
 
+// MaintenanceWindow suppresses all alerts for a client between StartsAt and
+// EndsAt, e.g. around a planned reboot.
+type MaintenanceWindow struct {
+	ID        int64     `json:"id"`
+	ClientID  string    `json:"client_id"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Active reports whether the window covers t.
+func (w MaintenanceWindow) Active(t time.Time) bool {
+	return !t.Before(w.StartsAt) && t.Before(w.EndsAt)
+}
+
+// ClientToken is a per-enrollment credential accepted in place of the shared
+// client password. Only a hash of the token is stored.
+type ClientToken struct {
+	ID        int64      `json:"id"`
+	Label     string     `json:"label"`
+	CreatedAt time.Time  `json:"created_at"`
+	Revoked   bool       `json:"revoked"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIToken is a bearer credential accepted by the admin API in place of the
+// admin password. Only a hash of the token is stored.
+type APIToken struct {
+	ID         int64      `json:"id"`
+	Label      string     `json:"label"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Revoked    bool       `json:"revoked"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// ClientCounts summarizes the (non-deleted) client fleet.
+type ClientCounts struct {
+	Total     int `json:"total"`
+	Online    int `json:"online"`
+	Offline   int `json:"offline"`
+	Muted     int `json:"muted"`
+	Suspended int `json:"suspended"`
+}
+
+// ClientVersionInfo is the version a (non-deleted) client last reported.
+type ClientVersionInfo struct {
+	ClientID      string    `json:"client_id"`
+	Hostname      string    `json:"hostname"`
+	CustomName    string    `json:"custom_name,omitempty"`
+	ClientVersion string    `json:"client_version"`
+	IsOnline      bool      `json:"is_online"`
+	LastSeenAt    time.Time `json:"last_seen_at"`
+}
+
+// ClientVersionGroup collects the clients running one version. Outdated is
+// set when the version is older than the min_client_version setting.
+type ClientVersionGroup struct {
+	Version  string              `json:"version"`
+	Outdated bool                `json:"outdated"`
+	Clients  []ClientVersionInfo `json:"clients"`
+}
+
+// ServerStats is a snapshot of the server's own health and workload.
+type ServerStats struct {
+	Version       string       `json:"version"`
+	StartedAt     time.Time    `json:"started_at"`
+	UptimeSeconds int64        `json:"uptime_seconds"`
+	Clients       ClientCounts `json:"clients"`
+	// Alerts fired in the last 24 hours, keyed by severity.
+	Alerts24h         map[string]int `json:"alerts_24h"`
+	DatabaseDriver    string         `json:"database_driver"`
+	DatabaseSizeBytes int64          `json:"database_size_bytes"`
+	// AlertDryRun is true while notifications are switched off.
+	AlertDryRun bool `json:"alert_dry_run"`
+}
+
+// DatabaseStats reports how much space the database uses and where.
+type DatabaseStats struct {
+	Driver    string `json:"driver"`
+	SizeBytes int64  `json:"size_bytes"`
+	// FreeBytes is space inside the file that VACUUM would return to the OS
+	// (SQLite only).
+	FreeBytes int64 `json:"free_bytes"`
+	// WALBytes is the size of the write-ahead log file (SQLite only).
+	WALBytes int64        `json:"wal_bytes"`
+	Tables   []TableStats `json:"tables"`
+}
+
+// TableStats is the row count of one database table.
+type TableStats struct {
+	Name string `json:"name"`
+	Rows int64  `json:"rows"`
+}
+
+// AuditEntry records one mutating admin action.
+type AuditEntry struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+	SourceIP  string    `json:"source_ip,omitempty"`
+}
+
 // Client represents a monitored machine.
 type Client struct {
 	ID               string    `json:"id"`
@@ -106,6 +318,7 @@ type Client struct {
 	CustomName       string    `json:"custom_name,omitempty"`
 	PublicIP         string    `json:"public_ip,omitempty"`
 	InterfaceIPs     []string  `json:"interface_ips,omitempty"`
+	Tags             []string  `json:"tags,omitempty"`
 	OS               string    `json:"os"`
 	Arch             string    `json:"arch"`
 	ClientVersion    string    `json:"client_version"`
@@ -126,10 +339,31 @@ type Client struct {
 	// Optional per-client override for metric alert streak length.
 	// Nil means use global default.
 	MetricConsecutiveCheckins *int `json:"metric_consecutive_checkins,omitempty"`
+	// Optional per-client override for the duplicate alert cooldown (seconds).
+	// Nil means use global default.
+	AlertCooldownSeconds *int `json:"alert_cooldown_seconds,omitempty"`
+	// Optional schedule restricting alerts to business hours. Nil means always alert.
+	BusinessHours *BusinessHours `json:"business_hours,omitempty"`
+	// Check-in interval the client last reported (seconds). Nil for older clients.
+	ReportedIntervalSeconds *int `json:"reported_interval_seconds,omitempty"`
+	// Admin-set check-in interval sent back to the client (seconds). Nil
+	// means the client uses its own configured interval.
+	CheckInIntervalSeconds *int `json:"check_in_interval_seconds,omitempty"`
+	// Round-trip time of the client's check-ins as it last reported them
+	// (milliseconds). Nil for older clients.
+	CheckInLatencyMs *int `json:"checkin_latency_ms,omitempty"`
+	// Most recent collection failure the client reported, kept until replaced.
+	LastCollectionError   string     `json:"last_collection_error,omitempty"`
+	LastCollectionErrorAt *time.Time `json:"last_collection_error_at,omitempty"`
 
 	AlertsMuted bool       `json:"alerts_muted"`
 	MutedUntil  *time.Time `json:"muted_until,omitempty"`
 	MuteReason  string     `json:"mute_reason,omitempty"`
+
+	// Suspended clients are known to be down or being retired. They keep
+	// reporting metrics but never alert, not even for going offline.
+	Suspended     bool   `json:"suspended"`
+	SuspendReason string `json:"suspend_reason,omitempty"`
 }
 
 // ClientWithMetrics is a client with its most recent metrics attached.
@@ -139,6 +373,25 @@ type ClientWithMetrics struct {
 	ProcessCount  int     `json:"process_count"`
 }
 
+// UsageDelta is the change in a usage percentage between the oldest and
+// newest samples in a window.
+type UsageDelta struct {
+	FromPercent float64
+	ToPercent   float64
+	FromAt      time.Time
+	ToAt        time.Time
+}
+
+// PerHour returns the change in percentage points per hour, or 0 when the
+// samples don't span any time.
+func (d UsageDelta) PerHour() float64 {
+	hours := d.ToAt.Sub(d.FromAt).Hours()
+	if hours <= 0 {
+		return 0
+	}
+	return (d.ToPercent - d.FromPercent) / hours
+}
+
 // Metric is a single point-in-time metric reading.
 type Metric struct {
 	ID             int64     `json:"id,omitempty"`
@@ -151,6 +404,25 @@ type Metric struct {
 	MemUsedBytes   uint64    `json:"mem_used_bytes"`
 	DiskTotalBytes uint64    `json:"disk_total_bytes"`
 	DiskUsedBytes  uint64    `json:"disk_used_bytes"`
+	NetRxBytes     uint64    `json:"net_rx_bytes"`
+	NetTxBytes     uint64    `json:"net_tx_bytes"`
+	SwapPercent    float64   `json:"swap_pct"`
+	SwapTotalBytes uint64    `json:"swap_total_bytes"`
+	SwapUsedBytes  uint64    `json:"swap_used_bytes"`
+	CPUTempC       *float64  `json:"cpu_temp_c,omitempty"`
+	OpenFDs        *int64    `json:"open_fds"`
+	ProcessCount   *int64    `json:"process_count"`
+	// Nil on Windows and filesystems that don't report inodes.
+	DiskInodesUsedPercent *float64 `json:"disk_inodes_pct"`
+	// Per-second rates derived from the previous sample; only set by GetMetrics.
+	NetRxBytesPerSec float64 `json:"net_rx_bytes_per_sec"`
+	NetTxBytesPerSec float64 `json:"net_tx_bytes_per_sec"`
+	// Set only for downsampled results: the percentages above are bucket
+	// averages, these are the bucket peaks, and Samples is the raw point count.
+	CPUPeakPercent  float64 `json:"cpu_peak_pct,omitempty"`
+	MemPeakPercent  float64 `json:"mem_peak_pct,omitempty"`
+	DiskPeakPercent float64 `json:"disk_peak_pct,omitempty"`
+	Samples         int     `json:"samples,omitempty"`
 }
 
 // WatchedProcess is a process definition configured for monitoring.
@@ -160,8 +432,25 @@ type WatchedProcess struct {
 	FriendlyName string `json:"friendly_name"`
 	MatchPattern string `json:"match_pattern"`
 	MatchType    string `json:"match_type"` // "substring" or "regex"
+	// Usage thresholds for process_high_cpu/process_high_mem; 0 disables.
+	CPUAlertPct float64 `json:"cpu_alert_pct,omitempty"`
+	MemAlertPct float64 `json:"mem_alert_pct,omitempty"`
+	// AlertOnStart fires process_started when the process comes up.
+	AlertOnStart bool `json:"alert_on_start,omitempty"`
+	// MinInstances fires process_instances_low when fewer are running.
+	MinInstances int `json:"min_instances,omitempty"`
+	// Note and ExpectedState are set from the dashboard, not the client.
+	Note          string `json:"note,omitempty"`
+	ExpectedState string `json:"expected_state"`
 }
 
+// Expected states for a watched process. When a process is expected to be
+// stopped, it going down does not alert.
+const (
+	ProcessExpectRunning = "running"
+	ProcessExpectStopped = "stopped"
+)
+
 // ProcessSnapshot is a point-in-time status of a watched process.
 type ProcessSnapshot struct {
 	ID            int64     `json:"id,omitempty"`
@@ -174,6 +463,32 @@ type ProcessSnapshot struct {
 	CPUPercent    float64   `json:"cpu_pct,omitempty"`
 	MemPercent    float64   `json:"mem_pct,omitempty"`
 	Cmdline       string    `json:"cmdline,omitempty"`
+	NumFDs        *int32    `json:"num_fds,omitempty"`
+	NumThreads    *int32    `json:"num_threads,omitempty"`
+	// InstanceCount is how many processes matched (0 when not running).
+	InstanceCount int `json:"instance_count"`
+	// Restarts24h counts new instances (PID changes or start after a stop)
+	// seen in the last 24 hours. Only set by the admin API.
+	Restarts24h int `json:"restarts_24h"`
+	// Note and ExpectedState are copied from the watched process by the
+	// admin API.
+	Note          string `json:"note,omitempty"`
+	ExpectedState string `json:"expected_state,omitempty"`
+}
+
+// DiskMount is the latest reported usage of a monitored mount path.
+// AlertState tracks which threshold alert ("", "warn", "crit") is active.
+type DiskMount struct {
+	ClientID    string    `json:"client_id,omitempty"`
+	Path        string    `json:"path"`
+	RecordedAt  time.Time `json:"recorded_at"`
+	TotalBytes  uint64    `json:"total_bytes"`
+	UsedBytes   uint64    `json:"used_bytes"`
+	UsedPercent float64   `json:"used_pct"`
+	WarnPct     *float64  `json:"warn_pct,omitempty"`
+	CritPct     *float64  `json:"crit_pct,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	AlertState  string    `json:"alert_state"`
 }
 
 // CheckSnapshot is a point-in-time result of any typed client check.
@@ -190,26 +505,69 @@ type CheckSnapshot struct {
 	Healthy       bool      `json:"healthy"`
 	Message       string    `json:"message,omitempty"`
 	State         string    `json:"state,omitempty"` // JSON blob, type-specific
+	Severity      string    `json:"severity,omitempty"`
+	// ConsecutiveFailures is the check's own failure streak requirement as
+	// reported by the client; 0 means the server default.
+	ConsecutiveFailures int `json:"consecutive_failures,omitempty"`
 }
 
 // Alert types.
 const (
-	AlertTypeOffline         = "offline"
-	AlertTypeOnline          = "online"
-	AlertTypePIDChange       = "pid_change"
-	AlertTypeProcessDied     = "process_died"
-	AlertTypeCheckFailed     = "check_failed"
-	AlertTypeCheckRecovered  = "check_recovered"
-	AlertTypeClientRestarted = "client_restarted"
-	AlertTypeCPUWarn         = "cpu_warn"
-	AlertTypeCPUCrit         = "cpu_crit"
-	AlertTypeCPURecover      = "cpu_recover"
-	AlertTypeMemWarn         = "mem_warn"
-	AlertTypeMemCrit         = "mem_crit"
-	AlertTypeMemRecover      = "mem_recover"
-	AlertTypeDiskWarn        = "disk_warn"
-	AlertTypeDiskCrit        = "disk_crit"
-	AlertTypeDiskRecover     = "disk_recover"
+	AlertTypeOffline            = "offline"
+	AlertTypeOnline             = "online"
+	AlertTypePIDChange          = "pid_change"
+	AlertTypeProcessDied        = "process_died"
+	AlertTypeCheckFailed        = "check_failed"
+	AlertTypeCheckRecovered     = "check_recovered"
+	AlertTypeClientRestarted    = "client_restarted"
+	AlertTypeClientOutdated     = "client_outdated"
+	AlertTypeClientUpdated      = "client_updated"
+	AlertTypeDuplicateClient    = "duplicate_client_id"
+	AlertTypeProcessFDs         = "process_fds_high"
+	AlertTypeProcessThreads     = "process_threads_high"
+	AlertTypeProcessMemGrowth   = "process_mem_growth"
+	AlertTypeProcessRestartLoop = "process_restart_loop"
+	AlertTypeProcessStarted     = "process_started"
+	AlertTypeInstancesLow       = "process_instances_low"
+	AlertTypeInstancesRestored  = "process_instances_recovered"
+	AlertTypeProcessHighCPU     = "process_high_cpu"
+	AlertTypeProcessHighMem     = "process_high_mem"
+	AlertTypeReportingDegraded  = "reporting_degraded"
+	AlertTypeReportingRecovered = "reporting_recovered"
+	AlertTypeCPUWarn            = "cpu_warn"
+	AlertTypeCPUCrit            = "cpu_crit"
+	AlertTypeCPURecover         = "cpu_recover"
+	AlertTypeMemWarn            = "mem_warn"
+	AlertTypeMemCrit            = "mem_crit"
+	AlertTypeMemRecover         = "mem_recover"
+	AlertTypeSwapWarn           = "swap_warn"
+	AlertTypeSwapCrit           = "swap_crit"
+	AlertTypeSwapRecover        = "swap_recover"
+	AlertTypeTempWarn           = "temp_warn"
+	AlertTypeTempCrit           = "temp_crit"
+	AlertTypeTempRecover        = "temp_recover"
+	AlertTypeInodeWarn          = "inode_warn"
+	AlertTypeInodeCrit          = "inode_crit"
+	AlertTypeInodeRecover       = "inode_recover"
+	AlertTypeOpenFDsWarn        = "open_fds_warn"
+	AlertTypeOpenFDsCrit        = "open_fds_crit"
+	AlertTypeOpenFDsRecover     = "open_fds_recover"
+	AlertTypeProcCountWarn      = "process_count_warn"
+	AlertTypeProcCountCrit      = "process_count_crit"
+	AlertTypeProcCountRecover   = "process_count_recover"
+	AlertTypeDiskWarn           = "disk_warn"
+	AlertTypeDiskCrit           = "disk_crit"
+	AlertTypeDiskRecover        = "disk_recover"
+	AlertTypeMountWarn          = "disk_mount_warn"
+	AlertTypeMountCrit          = "disk_mount_crit"
+	AlertTypeMountRecover       = "disk_mount_recover"
+	AlertTypeDiskFillRate       = "disk_fill_rate"
+	AlertTypeDiskFillRecover    = "disk_fill_rate_recover"
+	// AlertTypeDigest marks a summary notification; digests are not stored.
+	AlertTypeDigest = "digest"
+	// AlertTypeCertExpiry warns that the server's own TLS certificate is
+	// about to expire. It is not tied to a client and is not stored.
+	AlertTypeCertExpiry = "cert_expiry"
 )
 
 // Alert severities.
@@ -224,22 +582,52 @@ type Alert struct {
 	ID         int64      `json:"id"`
 	ClientID   string     `json:"client_id"`
 	AlertType  string     `json:"alert_type"`
+	Target     string     `json:"target,omitempty"` // process name, check ("name::type"), or mount path; empty for client-wide alerts
 	Severity   string     `json:"severity"`
 	Message    string     `json:"message"`
 	Details    string     `json:"details,omitempty"`
 	FiredAt    time.Time  `json:"fired_at"`
 	Notified   bool       `json:"notified"`
 	NotifiedAt *time.Time `json:"notified_at,omitempty"`
+	// Number of failed delivery attempts so far; used by the retry loop.
+	NotifyAttempts      int        `json:"notify_attempts,omitempty"`
+	LastNotifyAttemptAt *time.Time `json:"last_notify_attempt_at,omitempty"`
+	// Set when an operator acknowledges the alert; independent of delivery.
+	Acked   bool       `json:"acked"`
+	AckedAt *time.Time `json:"acked_at,omitempty"`
+	// Client is attached by the dispatcher before sending; it is not stored.
+	Client *AlertClient `json:"client,omitempty"`
+}
+
+// AlertClient is the client context sent along with an alert so structured
+// providers don't have to parse the hostname out of the message.
+type AlertClient struct {
+	Hostname     string   `json:"hostname"`
+	CustomName   string   `json:"custom_name,omitempty"`
+	PublicIP     string   `json:"public_ip,omitempty"`
+	InterfaceIPs []string `json:"interface_ips,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+}
+
+// Name returns the custom name when one is set, otherwise the hostname.
+func (c *AlertClient) Name() string {
+	if c.CustomName != "" {
+		return c.CustomName
+	}
+	return c.Hostname
 }
 
 // AlertProvider represents a configured notification channel.
 type AlertProvider struct {
-	ID        int64     `json:"id"`
-	Type      string    `json:"type"` // "twilio", "pushover", "smtp"
-	Name      string    `json:"name"`
-	Enabled   bool      `json:"enabled"`
-	Config    string    `json:"config"` // JSON blob
-	CreatedAt time.Time `json:"created_at"`
+	ID      int64  `json:"id"`
+	Type    string `json:"type"` // "twilio", "pushover", "smtp", "pagerduty", "gotify"
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Config  string `json:"config"` // JSON blob
+	// MessageTemplate is an optional text/template that replaces the alert
+	// message for this provider.
+	MessageTemplate string    `json:"message_template,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
 }
 
 // TestAlertResult carries delivery details for a provider test-send request.
@@ -250,6 +638,24 @@ type TestAlertResult struct {
 	APIResponse   string `json:"api_response,omitempty"`
 }
 
+// AlertRoute sends alerts whose type matches AlertType (a glob such as
+// "cpu_*"; empty matches everything) and whose severity is at least
+// MinSeverity to the listed providers.
+type AlertRoute struct {
+	AlertType   string  `json:"alert_type,omitempty"`
+	MinSeverity string  `json:"min_severity,omitempty"`
+	ProviderIDs []int64 `json:"provider_ids"`
+}
+
+// BusinessHours is a weekly window outside of which a client's alerts are
+// suppressed (e.g. office workstations that are expected to be off overnight).
+type BusinessHours struct {
+	Start    string   `json:"start"`              // HH:MM, 24-hour
+	End      string   `json:"end"`                // HH:MM, 24-hour
+	Timezone string   `json:"timezone,omitempty"` // IANA name; empty means server local time
+	Days     []string `json:"days,omitempty"`     // mon..sun; empty means Monday-Friday
+}
+
 // Thresholds holds warn/crit thresholds for a client.
 type Thresholds struct {
 	CPUWarnPct  float64 `json:"cpu_warn_pct"`
@@ -262,12 +668,56 @@ type Thresholds struct {
 	MetricThresholdsEnabled  *bool `json:"metric_thresholds_enabled,omitempty"`
 	OfflineThresholdEnabled  *bool `json:"offline_threshold_enabled,omitempty"`
 	MetricConsecutiveEnabled *bool `json:"metric_consecutive_enabled,omitempty"`
+	AlertCooldownEnabled     *bool `json:"alert_cooldown_enabled,omitempty"`
 	// Optional per-client offline alert delay override in minutes.
 	// Nil means keep current value; use clear-thresholds endpoint to reset to global.
 	OfflineThresholdMinutes *int `json:"offline_threshold_minutes,omitempty"`
 	// Optional per-client override: number of consecutive check-ins above threshold
 	// required before metric alerts fire.
 	MetricConsecutiveCheckins *int `json:"metric_consecutive_checkins,omitempty"`
+	// Optional per-client override: suppress repeats of the same alert type
+	// within this many seconds. 0 disables the cooldown for this client.
+	AlertCooldownSeconds *int `json:"alert_cooldown_seconds,omitempty"`
+}
+
+// Sources a resolved threshold value can come from, lowest precedence first.
+const (
+	ThresholdSourceDefault = "default"
+	ThresholdSourceGlobal  = "global"
+	ThresholdSourceClient  = "client"
+)
+
+// ResolvedThreshold is an effective threshold value and where it came from.
+type ResolvedThreshold struct {
+	Value  float64 `json:"value"`
+	Source string  `json:"source"`
+}
+
+// EffectiveThresholds are the values the alert engine actually uses for a
+// client after applying built-in defaults, global settings, and per-client
+// overrides.
+type EffectiveThresholds struct {
+	CPUWarnPct                ResolvedThreshold `json:"cpu_warn_pct"`
+	CPUCritPct                ResolvedThreshold `json:"cpu_crit_pct"`
+	MemWarnPct                ResolvedThreshold `json:"mem_warn_pct"`
+	MemCritPct                ResolvedThreshold `json:"mem_crit_pct"`
+	DiskWarnPct               ResolvedThreshold `json:"disk_warn_pct"`
+	DiskCritPct               ResolvedThreshold `json:"disk_crit_pct"`
+	OfflineThresholdSeconds   ResolvedThreshold `json:"offline_threshold_seconds"`
+	MetricConsecutiveCheckins ResolvedThreshold `json:"metric_consecutive_checkins"`
+	AlertCooldownSeconds      ResolvedThreshold `json:"alert_cooldown_seconds"`
+}
+
+// Thresholds returns just the metric percentages.
+func (et EffectiveThresholds) Thresholds() Thresholds {
+	return Thresholds{
+		CPUWarnPct:  et.CPUWarnPct.Value,
+		CPUCritPct:  et.CPUCritPct.Value,
+		MemWarnPct:  et.MemWarnPct.Value,
+		MemCritPct:  et.MemCritPct.Value,
+		DiskWarnPct: et.DiskWarnPct.Value,
+		DiskCritPct: et.DiskCritPct.Value,
+	}
 }
 
 // Default thresholds if nothing else is configured.
@@ -1,12 +1,14 @@
-package client
+// Package netinfo reports facts about the local machine's network setup that
+// both the client and the server need.
+package netinfo
 
 import (
 	"net"
 	"sort"
 )
 
-// ListInterfaceIPs returns non-loopback IP addresses from active interfaces.
-func ListInterfaceIPs() []string {
+// InterfaceIPs returns non-loopback IP addresses from active interfaces.
+func InterfaceIPs() []string {
 	ifaces, err := net.Interfaces()
 	if err != nil {
 		return nil
@@ -1,19 +1,78 @@
 package server
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+// clientTokenPrefix marks per-client tokens so they can be told apart from
+// the shared client password without a bcrypt comparison.
+const clientTokenPrefix = "mmt_"
+
+// apiTokenPrefix marks admin API tokens.
+const apiTokenPrefix = "mma_"
+
+// newClientToken returns a random per-client token and the hash to store.
+func newClientToken() (token, hash string, err error) {
+	return newToken(clientTokenPrefix)
+}
+
+// newAPIToken returns a random admin API token and the hash to store.
+func newAPIToken() (token, hash string, err error) {
+	return newToken(apiTokenPrefix)
+}
+
+func newToken(prefix string) (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = prefix + hex.EncodeToString(b)
+	return token, hashClientToken(token), nil
+}
+
+// hashClientToken uses SHA-256 rather than bcrypt: tokens are high-entropy,
+// and a deterministic hash lets requests look them up directly. Admin API
+// tokens are hashed the same way.
+func hashClientToken(token string) string {
+	return sha256Hex([]byte(token))
+}
+
+// clientPasswordAuth accepts either the shared client password or an
+// unrevoked per-client token in X-Client-Password. Clients that presented a
+// certificate signed by client_ca_file need neither.
 func (s *Server) clientPasswordAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hasVerifiedClientCert(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
 		pw := r.Header.Get("X-Client-Password")
 		if pw == "" {
 			http.Error(w, `{"error":"missing X-Client-Password header"}`, http.StatusUnauthorized)
 			return
 		}
-		if err := bcrypt.CompareHashAndPassword([]byte(s.cfg.ClientPasswordHash), []byte(pw)); err != nil {
+		if strings.HasPrefix(pw, clientTokenPrefix) {
+			ok, err := s.store.ClientTokenValid(hashClientToken(pw))
+			if err != nil {
+				s.logger.Error("failed to check client token", "err", err)
+				http.Error(w, `{"error":"internal error"}`, http.StatusInternalServerError)
+				return
+			}
+			if ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		if !s.verifyPassword(&s.cfg.ClientPasswordHash, pw) {
 			http.Error(w, `{"error":"invalid password"}`, http.StatusUnauthorized)
 			return
 		}
@@ -21,24 +80,121 @@ func (s *Server) clientPasswordAuth(next http.Handler) http.Handler {
 	})
 }
 
+// hasVerifiedClientCert reports whether the TLS handshake verified a client
+// certificate against client_ca_file.
+func hasVerifiedClientCert(r *http.Request) bool {
+	return r.TLS != nil && len(r.TLS.VerifiedChains) > 0
+}
+
+// Five failed admin logins from one IP, each within a minute of the last,
+// block that IP for a minute.
+const (
+	adminMaxFailures   = 5
+	adminLockoutWindow = time.Minute
+)
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// adminDummyHash returns a bcrypt hash compared against when the username is
+// wrong, so a bad username costs the same as a bad password.
+func adminDummyHash(cost int) []byte {
+	dummyHashOnce.Do(func() {
+		b := make([]byte, 16)
+		_, _ = rand.Read(b)
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte(hex.EncodeToString(b)), cost)
+	})
+	return dummyHash
+}
+
+// verifyPassword compares pw with the hash in *stored. On a match whose
+// bcrypt cost differs from the configured one, *stored is replaced with a
+// hash at the configured cost and the config file is rewritten, so tuning
+// bcrypt_cost never invalidates existing passwords.
+func (s *Server) verifyPassword(stored *string, pw string) bool {
+	s.passwordMu.RLock()
+	hash := *stored
+	s.passwordMu.RUnlock()
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(pw)) != nil {
+		return false
+	}
+
+	want := s.cfg.PasswordCost()
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil || cost == want {
+		return true
+	}
+	rehashed, err := HashPassword(pw, want)
+	if err != nil {
+		s.logger.Error("failed to rehash password", "err", err)
+		return true
+	}
+	s.passwordMu.Lock()
+	defer s.passwordMu.Unlock()
+	if *stored != hash {
+		return true // changed concurrently
+	}
+	*stored = rehashed
+	s.logger.Info("rehashed password at configured bcrypt cost", "from", cost, "to", want)
+	if s.cfg.path != "" {
+		if err := SaveServerConfig(s.cfg, s.cfg.path); err != nil {
+			s.logger.Error("failed to save rehashed password", "path", s.cfg.path, "err", err)
+		}
+	}
+	return true
+}
+
+// adminBasicAuth accepts admin Basic Auth or an unrevoked admin API token
+// as a bearer token. Failures of either kind count toward the lockout.
 func (s *Server) adminBasicAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := lockoutIP(r)
+		if remaining, locked := s.authLockout.blocked(ip); locked {
+			w.Header().Set("Retry-After", strconv.Itoa(int(remaining.Seconds())+1))
+			http.Error(w, `{"error":"too many failed login attempts"}`, http.StatusTooManyRequests)
+			return
+		}
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			valid, err := s.store.UseAPIToken(hashClientToken(token))
+			if err != nil {
+				s.logger.Error("failed to check api token", "err", err)
+				http.Error(w, `{"error":"internal error"}`, http.StatusInternalServerError)
+				return
+			}
+			if !valid {
+				s.authLockout.fail(ip)
+				http.Error(w, `{"error":"invalid token"}`, http.StatusUnauthorized)
+				return
+			}
+			s.authLockout.succeed(ip)
+			next.ServeHTTP(w, r)
+			return
+		}
 		user, pass, ok := r.BasicAuth()
-		if !ok || user != "admin" {
+		if !ok {
 			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
 			return
 		}
-		if err := bcrypt.CompareHashAndPassword([]byte(s.cfg.AdminPasswordHash), []byte(pass)); err != nil {
-			http.Error(w, `{"error":"invalid password"}`, http.StatusUnauthorized)
+		userOK := subtle.ConstantTimeCompare([]byte(user), []byte("admin")) == 1
+		if !userOK {
+			_ = bcrypt.CompareHashAndPassword(adminDummyHash(s.cfg.PasswordCost()), []byte(pass))
+		}
+		if !userOK || !s.verifyPassword(&s.cfg.AdminPasswordHash, pass) {
+			s.authLockout.fail(ip)
+			http.Error(w, `{"error":"invalid credentials"}`, http.StatusUnauthorized)
 			return
 		}
+		s.authLockout.succeed(ip)
 		next.ServeHTTP(w, r)
 	})
 }
 
-// HashPassword hashes a plaintext password with bcrypt.
-func HashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// HashPassword hashes a plaintext password with bcrypt at the given cost
+// (see Config.PasswordCost).
+func HashPassword(password string, cost int) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAdminBasicAuthLockout(t *testing.T) {
+	hash, err := HashPassword("secret", bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{
+		cfg:         &Config{AdminPasswordHash: hash},
+		logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		authLockout: newAuthLockout(3, time.Minute),
+	}
+	h := s.adminBasicAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	try := func(user, pass, addr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/clients", nil)
+		req.RemoteAddr = addr
+		req.SetBasicAuth(user, pass)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := try("admin", "secret", "10.0.0.1:1000"); code != http.StatusOK {
+		t.Fatalf("valid login: got %d", code)
+	}
+	if code := try("root", "secret", "10.0.0.1:1001"); code != http.StatusUnauthorized {
+		t.Fatalf("wrong user: got %d", code)
+	}
+	if code := try("admin", "nope", "10.0.0.1:1002"); code != http.StatusUnauthorized {
+		t.Fatalf("wrong password: got %d", code)
+	}
+	if code := try("admin", "nope", "10.0.0.1:1003"); code != http.StatusUnauthorized {
+		t.Fatalf("third failure: got %d", code)
+	}
+	// Locked out now, even with the right password and a new source port.
+	if code := try("admin", "secret", "10.0.0.1:1004"); code != http.StatusTooManyRequests {
+		t.Fatalf("locked out: got %d", code)
+	}
+	if code := try("admin", "secret", "10.0.0.2:1000"); code != http.StatusOK {
+		t.Fatalf("other IP: got %d", code)
+	}
+}
+
+func TestAdminLockoutIgnoresSpoofedForwardingHeaders(t *testing.T) {
+	hash, err := HashPassword("secret", bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	newHandler := func(trusted ...string) http.Handler {
+		s := &Server{
+			cfg:         &Config{AdminPasswordHash: hash},
+			logger:      logger,
+			authLockout: newAuthLockout(3, time.Minute),
+		}
+		h := s.adminBasicAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		return realIPMiddleware(parseTrustedProxies(trusted, logger), logger)(h)
+	}
+	try := func(h http.Handler, pass, peer, forwarded string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/clients", nil)
+		req.RemoteAddr = peer
+		req.Header.Set("X-Forwarded-For", forwarded)
+		req.SetBasicAuth("admin", pass)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Without trusted proxies, a fresh X-Forwarded-For per attempt must not
+	// reset the count for the peer.
+	h := newHandler()
+	for i, fwd := range []string{"198.51.100.1", "198.51.100.2", "198.51.100.3"} {
+		if code := try(h, "nope", "203.0.113.9:1000", fwd); code != http.StatusUnauthorized {
+			t.Fatalf("failure %d: got %d", i+1, code)
+		}
+	}
+	if code := try(h, "secret", "203.0.113.9:1000", "198.51.100.4"); code != http.StatusTooManyRequests {
+		t.Fatalf("spoofed header escaped the lockout: got %d", code)
+	}
+
+	// Behind a trusted proxy, clients are still locked out individually.
+	h = newHandler("10.0.0.1")
+	for i := 0; i < 3; i++ {
+		if code := try(h, "nope", "10.0.0.1:1000", "198.51.100.1"); code != http.StatusUnauthorized {
+			t.Fatalf("proxied failure %d: got %d", i+1, code)
+		}
+	}
+	if code := try(h, "secret", "10.0.0.1:1000", "198.51.100.1"); code != http.StatusTooManyRequests {
+		t.Fatalf("proxied client not locked out: got %d", code)
+	}
+	if code := try(h, "secret", "10.0.0.1:1000", "198.51.100.2"); code != http.StatusOK {
+		t.Fatalf("other client behind the proxy: got %d", code)
+	}
+}
+
+func TestVerifyPasswordRehashesAtConfiguredCost(t *testing.T) {
+	hash, err := HashPassword("secret", bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "server.toml")
+	s := &Server{
+		cfg:    &Config{AdminPasswordHash: hash, BcryptCost: bcrypt.MinCost + 1, path: path},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	if s.verifyPassword(&s.cfg.AdminPasswordHash, "wrong") {
+		t.Fatal("wrong password accepted")
+	}
+	if s.cfg.AdminPasswordHash != hash {
+		t.Fatal("failed login should not rehash")
+	}
+	if !s.verifyPassword(&s.cfg.AdminPasswordHash, "secret") {
+		t.Fatal("correct password rejected")
+	}
+	if cost, _ := bcrypt.Cost([]byte(s.cfg.AdminPasswordHash)); cost != bcrypt.MinCost+1 {
+		t.Fatalf("expected rehash at cost %d, got %d", bcrypt.MinCost+1, cost)
+	}
+
+	saved, err := LoadServerConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.AdminPasswordHash != s.cfg.AdminPasswordHash {
+		t.Fatal("rehashed password was not persisted")
+	}
+}
+
+func TestRealIPWarnsOnceAboutUntrustedForwarding(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	h := realIPMiddleware(nil, logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, fwd := range []string{"", "198.51.100.1", "198.51.100.2"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if fwd != "" {
+			req.Header.Set("X-Forwarded-For", fwd)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if n := strings.Count(buf.String(), "trusted_proxies is empty"); n != 1 {
+		t.Fatalf("expected one warning, got %d:\n%s", n, buf.String())
+	}
+}
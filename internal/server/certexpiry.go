@@ -0,0 +1,103 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+const (
+	// defaultCertExpiryWarnDays applies when cert_expiry_warn_days is unset.
+	defaultCertExpiryWarnDays = 14
+	// certExpiryCritDays escalates the warning to a critical alert.
+	certExpiryCritDays = 3
+	// How often the serving certificate is looked at, and how often a
+	// still-expiring certificate is alerted on again.
+	certExpiryCheckInterval = time.Hour
+	certExpiryRepeat        = 24 * time.Hour
+)
+
+// certExpiryWarnDays reads cert_expiry_warn_days; 0 turns the check off.
+func (s *Server) certExpiryWarnDays() int {
+	raw, _ := s.store.GetSetting("cert_expiry_warn_days")
+	days, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || days < 0 {
+		return defaultCertExpiryWarnDays
+	}
+	return days
+}
+
+// certExpiryAlert decides whether leaf is close enough to expiry to alert on
+// and, if so, with which severity and message.
+func certExpiryAlert(leaf *x509.Certificate, now time.Time, warnDays int) (severity, message string, ok bool) {
+	if warnDays <= 0 {
+		return "", "", false
+	}
+	left := leaf.NotAfter.Sub(now)
+	if left > time.Duration(warnDays)*24*time.Hour {
+		return "", "", false
+	}
+	name := leaf.Subject.CommonName
+	if len(leaf.DNSNames) > 0 {
+		name = leaf.DNSNames[0]
+	}
+	expires := leaf.NotAfter.UTC().Format("2006-01-02 15:04 MST")
+	if left <= 0 {
+		return models.SeverityCritical, fmt.Sprintf("Server TLS certificate for '%s' expired at %s; clients can no longer connect", name, expires), true
+	}
+	severity = models.SeverityWarning
+	if left <= certExpiryCritDays*24*time.Hour {
+		severity = models.SeverityCritical
+	}
+	days := int(left.Hours() / 24)
+	return severity, fmt.Sprintf("Server TLS certificate for '%s' expires in %d day(s), at %s; check that renewal is working", name, days, expires), true
+}
+
+// watchCertExpiry periodically checks the certificate returned by activeCert
+// and alerts through the providers while it is within the warning window.
+// It runs for the life of the process.
+func (s *Server) watchCertExpiry(activeCert func() (*x509.Certificate, error)) {
+	if s.alerts == nil {
+		return
+	}
+	var lastSent time.Time
+	// Give autocert a moment to load or obtain a certificate first.
+	timer := time.NewTimer(time.Minute)
+	for range timer.C {
+		timer.Reset(certExpiryCheckInterval)
+
+		leaf, err := activeCert()
+		if err != nil {
+			s.logger.Warn("could not read serving certificate for expiry check", "err", err)
+			continue
+		}
+		now := time.Now()
+		severity, message, ok := certExpiryAlert(leaf, now, s.certExpiryWarnDays())
+		if !ok {
+			lastSent = time.Time{}
+			continue
+		}
+		if now.Sub(lastSent) < certExpiryRepeat {
+			continue
+		}
+		if s.alerts.NotifySystem(models.AlertTypeCertExpiry, severity, message) {
+			lastSent = now
+		}
+	}
+}
+
+// leafOf returns the parsed end-entity certificate of cert.
+func leafOf(cert *tls.Certificate) (*x509.Certificate, error) {
+	if cert == nil || len(cert.Certificate) == 0 {
+		return nil, fmt.Errorf("no certificate")
+	}
+	if cert.Leaf != nil {
+		return cert.Leaf, nil
+	}
+	return x509.ParseCertificate(cert.Certificate[0])
+}
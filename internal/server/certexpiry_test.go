@@ -0,0 +1,46 @@
+package server
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestCertExpiryAlert(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	leaf := func(left time.Duration) *x509.Certificate {
+		return &x509.Certificate{
+			Subject:  pkix.Name{CommonName: "MachineMon Server"},
+			DNSNames: []string{"monitor.example.com"},
+			NotAfter: now.Add(left),
+		}
+	}
+	day := 24 * time.Hour
+
+	cases := []struct {
+		name     string
+		left     time.Duration
+		warnDays int
+		severity string
+		ok       bool
+	}{
+		{"plenty of time", 60 * day, 14, "", false},
+		{"inside window", 10 * day, 14, models.SeverityWarning, true},
+		{"nearly expired", 2 * day, 14, models.SeverityCritical, true},
+		{"expired", -time.Hour, 14, models.SeverityCritical, true},
+		{"disabled", 2 * day, 0, "", false},
+	}
+	for _, c := range cases {
+		severity, message, ok := certExpiryAlert(leaf(c.left), now, c.warnDays)
+		if ok != c.ok || severity != c.severity {
+			t.Errorf("%s: got (%q, %v), want (%q, %v)", c.name, severity, ok, c.severity, c.ok)
+		}
+		if ok && !strings.Contains(message, "monitor.example.com") {
+			t.Errorf("%s: message %q does not name the certificate", c.name, message)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+// Upper bounds for check-in payloads. These are generous for real clients
+// but stop a buggy or hostile client from filling the database.
+const (
+	maxCheckInBodyBytes   = 4 << 20
+	maxShortFieldLen      = 64
+	maxIDFieldLen         = 128
+	maxNameFieldLen       = 255
+	maxPatternLen         = 1024
+	maxCmdlineLen         = 4096
+	maxCheckMessageLen    = 2048
+	maxCheckStateLen      = 64 << 10
+	maxInterfaceIPs       = 64
+	maxCheckInInterval    = 24 * 60 * 60 // seconds
+	minCheckInInterval    = 10           // lowest interval an admin may assign
+	maxCheckInLatencyMs   = 10 * 60 * 1000
+	maxProcessesPerCheck  = 500
+	maxChecksPerCheckIn   = 200
+	maxCheckFailureStreak = 100
+	maxDiskMountsPerCheck = 64
+	maxCollectionErrors   = 20
+	maxProcessCPUPercent  = 100 * 1024 // per-process CPU is summed across cores
+	minCPUTempC           = -50
+	maxCPUTempC           = 200
+	// Buffered samples sent to /checkin/batch.
+	maxCheckInBatchBodyBytes = 16 << 20
+	maxCheckInBatchSize      = 100
+	maxBufferedSampleAge     = 7 * 24 * time.Hour
+	maxCapturedAtSkew        = 5 * time.Minute
+	// Percentages within this margin outside [0,100] are rounding noise: they
+	// are accepted, logged, and clamped on insert instead of rejected.
+	percentTolerance = 5
+)
+
+// checkTypePattern keeps check types to simple identifiers. Unknown types are
+// allowed so new client-side check types don't need a server upgrade.
+var checkTypePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// validateCheckIn returns every problem found in req; an empty result means
+// the payload is safe to store.
+func validateCheckIn(req *models.CheckInRequest) []string {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	checkLen := func(field, value string, max int) {
+		if len(value) > max {
+			add("%s exceeds %d characters", field, max)
+		}
+	}
+	checkPct := func(field string, value float64) {
+		if math.IsNaN(value) || math.IsInf(value, 0) || value < -percentTolerance || value > 100+percentTolerance {
+			add("%s must be between 0 and 100 (got %v)", field, value)
+		}
+	}
+
+	if strings.TrimSpace(req.Hostname) == "" {
+		add("hostname is required")
+	}
+	checkLen("hostname", req.Hostname, maxNameFieldLen)
+	checkLen("os", req.OS, maxShortFieldLen)
+	checkLen("arch", req.Arch, maxShortFieldLen)
+	checkLen("client_version", req.ClientVersion, maxShortFieldLen)
+	checkLen("client_id", req.ClientID, maxIDFieldLen)
+	checkLen("session_id", req.SessionID, maxIDFieldLen)
+	checkLen("machine_id", req.MachineID, maxIDFieldLen)
+	if req.BootTimeUnix < 0 {
+		add("boot_time_unix must not be negative")
+	}
+	if req.CheckInInterval < 0 || req.CheckInInterval > maxCheckInInterval {
+		add("check_in_interval must be between 0 and %d seconds (got %d)", maxCheckInInterval, req.CheckInInterval)
+	}
+	if req.CheckInLatencyMs < 0 || req.CheckInLatencyMs > maxCheckInLatencyMs {
+		add("checkin_latency_ms must be between 0 and %d (got %d)", maxCheckInLatencyMs, req.CheckInLatencyMs)
+	}
+	if len(req.InterfaceIPs) > maxInterfaceIPs {
+		add("interface_ips has more than %d entries", maxInterfaceIPs)
+	}
+	for i, ip := range req.InterfaceIPs {
+		checkLen(fmt.Sprintf("interface_ips[%d]", i), ip, maxShortFieldLen)
+	}
+
+	m := req.Metrics
+	checkPct("metrics.cpu_pct", m.CPUPercent)
+	checkPct("metrics.mem_pct", m.MemPercent)
+	checkPct("metrics.disk_pct", m.DiskPercent)
+	checkPct("metrics.swap_pct", m.SwapPercent)
+	if m.DiskInodesUsedPercent != nil {
+		checkPct("metrics.disk_inodes_pct", *m.DiskInodesUsedPercent)
+	}
+	if m.MemUsedBytes > m.MemTotalBytes && m.MemTotalBytes > 0 {
+		add("metrics.mem_used_bytes exceeds mem_total_bytes")
+	}
+	if m.DiskUsedBytes > m.DiskTotalBytes && m.DiskTotalBytes > 0 {
+		add("metrics.disk_used_bytes exceeds disk_total_bytes")
+	}
+	if m.SwapUsedBytes > m.SwapTotalBytes {
+		add("metrics.swap_used_bytes exceeds swap_total_bytes")
+	}
+	if t := m.CPUTempC; t != nil && (math.IsNaN(*t) || *t < minCPUTempC || *t > maxCPUTempC) {
+		add("metrics.cpu_temp_c is out of range (got %v)", *t)
+	}
+	if n := m.OpenFDs; n != nil && *n < 0 {
+		add("metrics.open_fds must not be negative")
+	}
+	if n := m.ProcessCount; n != nil && *n < 0 {
+		add("metrics.process_count must not be negative")
+	}
+
+	if len(req.Errors) > maxCollectionErrors {
+		add("errors has more than %d entries", maxCollectionErrors)
+	}
+	for i, e := range req.Errors {
+		checkLen(fmt.Sprintf("errors[%d].source", i), e.Source, maxShortFieldLen)
+		checkLen(fmt.Sprintf("errors[%d].message", i), e.Message, maxCheckMessageLen)
+	}
+
+	if len(req.Processes) > maxProcessesPerCheck {
+		add("processes has more than %d entries", maxProcessesPerCheck)
+	}
+	for i, p := range req.Processes {
+		field := fmt.Sprintf("processes[%d]", i)
+		if strings.TrimSpace(p.FriendlyName) == "" {
+			add("%s.friendly_name is required", field)
+		}
+		checkLen(field+".friendly_name", p.FriendlyName, maxNameFieldLen)
+		checkLen(field+".match_pattern", p.MatchPattern, maxPatternLen)
+		if strings.TrimSpace(p.MatchPattern) == "" {
+			add("%s.match_pattern is required", field)
+		}
+		switch p.MatchType {
+		case "", "substring":
+		case "regex":
+			if len(p.MatchPattern) <= maxPatternLen {
+				if _, err := regexp.Compile(p.MatchPattern); err != nil {
+					add("%s.match_pattern is not a valid regex: %v", field, err)
+				}
+			}
+		default:
+			add("%s.match_type must be \"substring\" or \"regex\" (got %q)", field, p.MatchType)
+		}
+		checkLen(field+".cmdline", p.Cmdline, maxCmdlineLen)
+		if math.IsNaN(p.CPUPercent) || p.CPUPercent < 0 || p.CPUPercent > maxProcessCPUPercent {
+			add("%s.cpu_pct is out of range (got %v)", field, p.CPUPercent)
+		}
+		checkPct(field+".mem_pct", p.MemPercent)
+		if p.InstanceCount < 0 {
+			add("%s.instance_count must not be negative", field)
+		}
+		if p.MinInstances < 0 {
+			add("%s.min_instances must not be negative", field)
+		}
+		if math.IsNaN(p.CPUAlertPct) || p.CPUAlertPct < 0 || p.CPUAlertPct > maxProcessCPUPercent {
+			add("%s.cpu_alert_pct is out of range (got %v)", field, p.CPUAlertPct)
+		}
+		checkPct(field+".mem_alert_pct", p.MemAlertPct)
+		if p.PID < 0 || p.NumFDs < 0 || p.NumThreads < 0 {
+			add("%s pid/num_fds/num_threads must not be negative", field)
+		}
+	}
+
+	if len(req.Checks) > maxChecksPerCheckIn {
+		add("checks has more than %d entries", maxChecksPerCheckIn)
+	}
+	for i, c := range req.Checks {
+		problems = append(problems, validateCheckPayload(fmt.Sprintf("checks[%d]", i), c)...)
+	}
+
+	if len(req.DiskMounts) > maxDiskMountsPerCheck {
+		add("disk_mounts has more than %d entries", maxDiskMountsPerCheck)
+	}
+	for i, d := range req.DiskMounts {
+		field := fmt.Sprintf("disk_mounts[%d]", i)
+		if strings.TrimSpace(d.Path) == "" {
+			add("%s.path is required", field)
+		}
+		checkLen(field+".path", d.Path, maxPatternLen)
+		checkLen(field+".error", d.Error, maxCheckMessageLen)
+		checkPct(field+".used_pct", d.UsedPercent)
+		checkPct(field+".warn_pct", d.WarnPct)
+		checkPct(field+".crit_pct", d.CritPct)
+	}
+
+	if in := req.Initial; in != nil {
+		checkLen("initial.custom_name", in.CustomName, maxNameFieldLen)
+		if t := in.Thresholds; t != nil {
+			pairs := []struct {
+				name       string
+				warn, crit float64
+			}{
+				{"cpu", t.CPUWarnPct, t.CPUCritPct},
+				{"mem", t.MemWarnPct, t.MemCritPct},
+				{"disk", t.DiskWarnPct, t.DiskCritPct},
+			}
+			for _, p := range pairs {
+				if !validThresholdPct(p.warn) || !validThresholdPct(p.crit) {
+					add("initial.thresholds.%s_warn_pct and %s_crit_pct must be between 0 and 100", p.name, p.name)
+				} else if p.warn > p.crit {
+					add("initial.thresholds.%s_warn_pct must not exceed %s_crit_pct", p.name, p.name)
+				}
+			}
+		}
+	}
+
+	return problems
+}
+
+// validThresholdPct reports whether v is usable as a metric threshold.
+func validThresholdPct(v float64) bool {
+	return !math.IsNaN(v) && v > 0 && v <= 100
+}
+
+// validateCheckPayload returns the problems with a single check result,
+// prefixing each with field.
+func validateCheckPayload(field string, c models.CheckPayload) []string {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	checkLen := func(field, value string, max int) {
+		if len(value) > max {
+			add("%s exceeds %d characters", field, max)
+		}
+	}
+
+	if strings.TrimSpace(c.FriendlyName) == "" {
+		add("%s.friendly_name is required", field)
+	}
+	checkLen(field+".friendly_name", c.FriendlyName, maxNameFieldLen)
+	if len(c.CheckType) > maxShortFieldLen || !checkTypePattern.MatchString(c.CheckType) {
+		add("%s.check_type %q is not a valid check type", field, c.CheckType)
+	}
+	checkLen(field+".message", c.Message, maxCheckMessageLen)
+	checkLen(field+".state", c.State, maxCheckStateLen)
+	switch c.Severity {
+	case "", models.SeverityInfo, models.SeverityWarning, "warn", models.SeverityCritical:
+	default:
+		add("%s.severity must be info, warning, or critical", field)
+	}
+	if c.ConsecutiveFailures < 0 || c.ConsecutiveFailures > maxCheckFailureStreak {
+		add("%s.consecutive_failures must be between 0 and %d", field, maxCheckFailureStreak)
+	}
+	return problems
+}
+
+// checkInAnomalies lists soft-invalid values that validation let through and
+// the store will clamp, so they show up in logs rather than silently vanish.
+func checkInAnomalies(req *models.CheckInRequest) []string {
+	var anomalies []string
+	flag := func(field string, value float64) {
+		if value < 0 || value > 100 {
+			anomalies = append(anomalies, fmt.Sprintf("%s=%v", field, value))
+		}
+	}
+	flag("cpu_pct", req.Metrics.CPUPercent)
+	flag("mem_pct", req.Metrics.MemPercent)
+	flag("disk_pct", req.Metrics.DiskPercent)
+	flag("swap_pct", req.Metrics.SwapPercent)
+	for i, p := range req.Processes {
+		flag(fmt.Sprintf("processes[%d].mem_pct", i), p.MemPercent)
+	}
+	for i, d := range req.DiskMounts {
+		flag(fmt.Sprintf("disk_mounts[%d].used_pct", i), d.UsedPercent)
+	}
+	return anomalies
+}
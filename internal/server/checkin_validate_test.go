@@ -0,0 +1,129 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestValidateCheckInAcceptsTypicalPayload(t *testing.T) {
+	req := models.CheckInRequest{
+		Hostname: "web-1",
+		OS:       "linux",
+		Arch:     "amd64",
+		Metrics:  models.MetricsPayload{CPUPercent: 12.5, MemPercent: 40, DiskPercent: 70, MemTotalBytes: 100, MemUsedBytes: 40},
+		Processes: []models.ProcessPayload{
+			{FriendlyName: "nginx", MatchPattern: "nginx", IsRunning: true, CPUPercent: 350, MemPercent: 2},
+		},
+		Checks: []models.CheckPayload{{FriendlyName: "api", CheckType: models.CheckTypeScript, Healthy: true}},
+	}
+	if problems := validateCheckIn(&req); len(problems) != 0 {
+		t.Fatalf("expected no problems, got %v", problems)
+	}
+}
+
+func TestValidateCheckInRejectsOutOfRangeValues(t *testing.T) {
+	req := models.CheckInRequest{
+		Hostname: "web-1",
+		Metrics:  models.MetricsPayload{CPUPercent: 3000, MemPercent: -20},
+		Checks:   []models.CheckPayload{{FriendlyName: "api", CheckType: "script; rm -rf"}},
+	}
+	problems := validateCheckIn(&req)
+	joined := strings.Join(problems, "\n")
+	for _, want := range []string{"metrics.cpu_pct", "metrics.mem_pct", "checks[0].check_type"} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("expected a problem mentioning %q, got %v", want, problems)
+		}
+	}
+}
+
+func TestValidateCheckInRejectsOversizedFields(t *testing.T) {
+	req := models.CheckInRequest{Hostname: strings.Repeat("h", maxNameFieldLen+1)}
+	problems := validateCheckIn(&req)
+	if len(problems) != 1 || !strings.Contains(problems[0], "hostname exceeds") {
+		t.Fatalf("expected a single hostname length problem, got %v", problems)
+	}
+}
+
+func TestValidateCheckInAcceptsSlightOvershootAndFlagsIt(t *testing.T) {
+	req := models.CheckInRequest{
+		Hostname: "web-1",
+		Metrics:  models.MetricsPayload{CPUPercent: 100.4, MemPercent: 50, DiskPercent: 50},
+	}
+	if problems := validateCheckIn(&req); len(problems) != 0 {
+		t.Fatalf("expected slight overshoot to be accepted, got %v", problems)
+	}
+	anomalies := checkInAnomalies(&req)
+	if len(anomalies) != 1 || !strings.HasPrefix(anomalies[0], "cpu_pct=") {
+		t.Fatalf("expected cpu_pct anomaly, got %v", anomalies)
+	}
+}
+
+func TestValidateCheckInRejectsBadProcessMatch(t *testing.T) {
+	req := models.CheckInRequest{
+		Hostname: "web-1",
+		Processes: []models.ProcessPayload{
+			{FriendlyName: "ok", MatchPattern: `^nginx: master`, MatchType: "regex"},
+			{FriendlyName: "bad-regex", MatchPattern: `worker(`, MatchType: "regex"},
+			{FriendlyName: "bad-type", MatchPattern: "java", MatchType: "glob"},
+			{FriendlyName: "empty", MatchPattern: " "},
+		},
+	}
+	problems := validateCheckIn(&req)
+	joined := strings.Join(problems, "\n")
+	for _, want := range []string{"processes[1].match_pattern is not a valid regex", "processes[2].match_type", "processes[3].match_pattern is required"} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("expected a problem mentioning %q, got %v", want, problems)
+		}
+	}
+	if strings.Contains(joined, "processes[0]") {
+		t.Fatalf("valid regex should not be flagged, got %v", problems)
+	}
+}
+
+func TestValidateCheckInInitialSettings(t *testing.T) {
+	valid := &models.Thresholds{CPUWarnPct: 70, CPUCritPct: 90, MemWarnPct: 80, MemCritPct: 95, DiskWarnPct: 85, DiskCritPct: 95}
+	req := models.CheckInRequest{
+		Hostname: "web-1",
+		Initial:  &models.InitialClientSettings{CustomName: "Web", Thresholds: valid},
+	}
+	if problems := validateCheckIn(&req); len(problems) != 0 {
+		t.Fatalf("expected no problems, got %v", problems)
+	}
+
+	bad := *valid
+	bad.CPUWarnPct = 95
+	bad.DiskCritPct = 0
+	req.Initial = &models.InitialClientSettings{CustomName: strings.Repeat("x", maxNameFieldLen+1), Thresholds: &bad}
+	joined := strings.Join(validateCheckIn(&req), "\n")
+	for _, want := range []string{"initial.custom_name", "initial.thresholds.cpu_warn_pct must not exceed", "initial.thresholds.disk_warn_pct and disk_crit_pct"} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("expected a problem mentioning %q, got %s", want, joined)
+		}
+	}
+}
+
+func TestValidateCheckPayloadRequiresNameAndType(t *testing.T) {
+	if problems := validateCheckPayload("check", models.CheckPayload{FriendlyName: "backup", CheckType: "ci"}); len(problems) != 0 {
+		t.Fatalf("expected valid payload, got %v", problems)
+	}
+	problems := validateCheckPayload("check", models.CheckPayload{Severity: "loud"})
+	if len(problems) != 3 {
+		t.Fatalf("expected name, type, and severity problems, got %v", problems)
+	}
+}
+
+func TestCollectionErrorHelpers(t *testing.T) {
+	errs := []models.CollectionError{
+		{Source: models.CollectionSourceMetrics, Message: "disk usage /: permission denied"},
+		{Message: "sensors unavailable"},
+	}
+	if !collectionFailed(errs, models.CollectionSourceMetrics) || collectionFailed(errs, models.CollectionSourceProcesses) {
+		t.Fatalf("collectionFailed misreported sources")
+	}
+	want := "metrics: disk usage /: permission denied; sensors unavailable"
+	if got := summarizeCollectionErrors(errs); got != want {
+		t.Fatalf("summary = %q, want %q", got, want)
+	}
+}
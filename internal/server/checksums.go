@@ -0,0 +1,119 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// checksumsFilename is the sha256sum-compatible manifest of the binaries dir.
+const checksumsFilename = "SHA256SUMS"
+
+// checksumCache remembers binary hashes so the manifest isn't recomputed on
+// every install. Entries are invalidated when a file's size or mtime changes.
+type checksumCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedChecksum
+}
+
+type cachedChecksum struct {
+	size    int64
+	modTime time.Time
+	sum     string
+}
+
+func newChecksumCache() *checksumCache {
+	return &checksumCache{entries: make(map[string]cachedChecksum)}
+}
+
+// sum returns the hex SHA-256 of path. A "<path>.sha256" sidecar published
+// alongside the archive (sha256sum output, or just the digest) is used as-is;
+// otherwise the file is hashed, reusing the cached value if it is unchanged.
+func (c *checksumCache) sum(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if sum, ok := readSidecarSum(path + ".sha256"); ok {
+		return sum, nil
+	}
+
+	c.mu.Lock()
+	cached, ok := c.entries[path]
+	c.mu.Unlock()
+	if ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+		return cached.sum, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	c.mu.Lock()
+	c.entries[path] = cachedChecksum{size: info.Size(), modTime: info.ModTime(), sum: sum}
+	c.mu.Unlock()
+	return sum, nil
+}
+
+// manifest returns "<sha256>  <name>" lines for every .tar.gz in dir, sorted
+// by name, in the format `sha256sum -c` accepts.
+func (c *checksumCache) manifest(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".tar.gz") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		sum, err := c.sum(filepath.Join(dir, name))
+		if err != nil {
+			return "", fmt.Errorf("hash %s: %w", name, err)
+		}
+		fmt.Fprintf(&b, "%s  %s\n", sum, name)
+	}
+	return b.String(), nil
+}
+
+// readSidecarSum reads the digest from a .sha256 file, ignoring it unless it
+// starts with a well-formed hex SHA-256.
+func readSidecarSum(path string) (string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 || len(fields[0]) != sha256.Size*2 {
+		return "", false
+	}
+	sum := strings.ToLower(fields[0])
+	if _, err := hex.DecodeString(sum); err != nil {
+		return "", false
+	}
+	return sum, true
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -5,40 +5,108 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/BurntSushi/toml"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type Config struct {
 	ListenAddr   string `toml:"listen_addr"`
-	ExternalURL  string `toml:"external_url"`  // public URL (e.g. https://monitor.example.com) — used for install scripts, dashboard links
-	BasePath     string `toml:"base_path"`     // URL path prefix when behind a reverse proxy subpath (e.g. "/machinemon")
+	ExternalURL  string `toml:"external_url"` // public URL (e.g. https://monitor.example.com) — used for install scripts, dashboard links
+	BasePath     string `toml:"base_path"`    // URL path prefix when behind a reverse proxy subpath (e.g. "/machinemon")
 	DatabasePath string `toml:"database_path"`
-	BinariesDir  string `toml:"binaries_dir"`  // directory containing client .tar.gz binaries
+	// "sqlite" (default, uses database_path) or "postgres" (uses database_dsn)
+	DatabaseDriver string `toml:"database_driver"`
+	DatabaseDSN    string `toml:"database_dsn"` // e.g. postgres://machinemon:secret@db:5432/machinemon?sslmode=require
+	BinariesDir    string `toml:"binaries_dir"` // directory containing client .tar.gz binaries
 
 	// TLS
-	TLSMode      string `toml:"tls_mode"` // "autocert", "selfsigned", "manual", "none"
+	TLSMode      string `toml:"tls_mode"`  // "autocert", "selfsigned", "manual", "none"
 	Domain       string `toml:"domain"`    // for autocert
 	CertFile     string `toml:"cert_file"` // for manual
 	KeyFile      string `toml:"key_file"`  // for manual
 	CertCacheDir string `toml:"cert_cache_dir"`
+	// Extra host names and IPs for the self-signed certificate, on top of the
+	// machine's hostname and interface addresses.
+	SelfSignedSANs []string `toml:"selfsigned_sans"`
+	// Listener for the autocert HTTP-01 challenge. "off" disables it, leaving
+	// TLS-ALPN-01 on listen_addr, which must then be reachable on port 443.
+	AutocertHTTPAddr string `toml:"autocert_http_addr"`
+	// Contact address registered with the ACME CA for expiry notices.
+	AutocertEmail string `toml:"autocert_email"`
+	// ACME directory; empty means Let's Encrypt production. Use the staging
+	// directory while testing to stay clear of rate limits.
+	AutocertDirectoryURL string `toml:"autocert_directory_url"`
+	// Serve a self-signed certificate while ACME can't provide one, so
+	// clients with insecure_skip_tls keep checking in. On by default.
+	AutocertSelfSignedFallback bool `toml:"autocert_selfsigned_fallback"`
+	// PEM CA bundle for verifying client certificates (mutual TLS). Clients
+	// presenting a certificate signed by it skip the client password check.
+	ClientCAFile string `toml:"client_ca_file"`
 
 	// Auth
 	AdminPasswordHash  string `toml:"admin_password_hash"`
 	ClientPasswordHash string `toml:"client_password_hash"`
+	// Shared secret for the inbound command webhook (X-Webhook-Token). The
+	// server replaces it with command_webhook_token_hash on startup.
+	CommandWebhookToken string `toml:"command_webhook_token"`
+	// Hex SHA-256 of the command webhook token. Empty disables the webhook.
+	CommandWebhookTokenHash string `toml:"command_webhook_token_hash"`
+	// Bearer token accepted on /metrics in addition to admin Basic Auth. The
+	// server replaces it with metrics_token_hash on startup.
+	MetricsToken string `toml:"metrics_token"`
+	// Hex SHA-256 of the metrics token. Empty means admin auth only.
+	MetricsTokenHash string `toml:"metrics_token_hash"`
+	// bcrypt cost for new password hashes; 0 means bcrypt's default (10).
+	// Hashes at a different cost are rehashed on the next successful login.
+	BcryptCost int `toml:"bcrypt_cost"`
+
+	// Reverse proxies (IPs or CIDRs) whose X-Forwarded-For / X-Real-IP headers
+	// are trusted. Empty trusts those headers from any peer.
+	TrustedProxies []string `toml:"trusted_proxies"`
 
 	// Dev mode
-	DevMode       bool   `toml:"dev_mode"`
-	DevProxyURL   string `toml:"dev_proxy_url"`
+	DevMode     bool   `toml:"dev_mode"`
+	DevProxyURL string `toml:"dev_proxy_url"`
+
+	// path is the file the config was loaded from, used to persist rehashed
+	// passwords and the hashed command webhook token. Empty when the config
+	// did not come from a file.
+	path string
+}
+
+// PasswordCost returns the bcrypt cost for new password hashes, falling back
+// to bcrypt's default when bcrypt_cost is unset or out of range.
+func (c *Config) PasswordCost() int {
+	if c.BcryptCost < bcrypt.MinCost || c.BcryptCost > bcrypt.MaxCost {
+		return bcrypt.DefaultCost
+	}
+	return c.BcryptCost
 }
 
 func DefaultServerConfig() *Config {
 	return &Config{
-		ListenAddr:   ":8080",
-		DatabasePath: defaultDatabasePath(),
-		BinariesDir:  defaultBinariesDir(),
-		TLSMode:      "none",
-		CertCacheDir: defaultCertCacheDir(),
+		ListenAddr:                 ":8080",
+		DatabasePath:               defaultDatabasePath(),
+		BinariesDir:                defaultBinariesDir(),
+		TLSMode:                    "none",
+		CertCacheDir:               defaultCertCacheDir(),
+		AutocertHTTPAddr:           ":80",
+		AutocertSelfSignedFallback: true,
+	}
+}
+
+// autocertChallengeAddr returns the HTTP-01 challenge listen address, or ""
+// when the HTTP challenge is turned off.
+func (c *Config) autocertChallengeAddr() string {
+	switch strings.ToLower(strings.TrimSpace(c.AutocertHTTPAddr)) {
+	case "":
+		return ":80"
+	case "off", "none", "false":
+		return ""
+	default:
+		return strings.TrimSpace(c.AutocertHTTPAddr)
 	}
 }
 
@@ -54,9 +122,28 @@ func LoadServerConfig(path string) (*Config, error) {
 	if err := toml.Unmarshal(data, cfg); err != nil {
 		return nil, fmt.Errorf("parse server config: %w", err)
 	}
+	cfg.path = path
 	return cfg, nil
 }
 
+// hashPlaintextTokens moves a plaintext command_webhook_token or metrics_token
+// into its _hash field so the secrets aren't kept in the config file. It
+// reports whether the config changed.
+func (c *Config) hashPlaintextTokens() bool {
+	changed := false
+	if c.CommandWebhookToken != "" {
+		c.CommandWebhookTokenHash = hashClientToken(c.CommandWebhookToken)
+		c.CommandWebhookToken = ""
+		changed = true
+	}
+	if c.MetricsToken != "" {
+		c.MetricsTokenHash = hashClientToken(c.MetricsToken)
+		c.MetricsToken = ""
+		changed = true
+	}
+	return changed
+}
+
 func SaveServerConfig(cfg *Config, path string) error {
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0700); err != nil {
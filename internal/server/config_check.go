@@ -0,0 +1,143 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/machinemon/machinemon/internal/store"
+)
+
+// Check reports every problem that would stop the server from starting, or
+// that it would otherwise only discover later, without opening the database
+// or binding any ports. An empty result means the config looks usable.
+func (c *Config) Check() []string {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if _, _, err := net.SplitHostPort(c.ListenAddr); err != nil {
+		add("listen_addr %q is not host:port: %v", c.ListenAddr, err)
+	}
+	if c.ExternalURL != "" {
+		if u, err := url.Parse(c.ExternalURL); err != nil || u.Scheme == "" || u.Host == "" {
+			add("external_url %q is not an absolute URL", c.ExternalURL)
+		}
+	}
+	if c.AdminPasswordHash == "" || c.ClientPasswordHash == "" {
+		add("admin_password_hash and client_password_hash must be set (run with --setup)")
+	}
+	for _, f := range []struct{ key, hash string }{
+		{"command_webhook_token_hash", c.CommandWebhookTokenHash},
+		{"metrics_token_hash", c.MetricsTokenHash},
+	} {
+		if h := strings.TrimSpace(f.hash); h != "" {
+			if b, err := hex.DecodeString(h); err != nil || len(b) != sha256.Size {
+				add("%s is not a hex SHA-256 digest", f.key)
+			}
+		}
+	}
+
+	switch c.TLSMode {
+	case "", "none":
+	case "autocert":
+		if strings.TrimSpace(c.Domain) == "" {
+			add("domain is required for autocert TLS mode")
+		}
+		if err := checkWritableDir(c.CertCacheDir); err != nil {
+			add("cert_cache_dir: %v", err)
+		}
+		if addr := c.autocertChallengeAddr(); addr != "" {
+			if _, _, err := net.SplitHostPort(addr); err != nil {
+				add("autocert_http_addr %q is not host:port or \"off\": %v", c.AutocertHTTPAddr, err)
+			}
+		}
+		if c.AutocertDirectoryURL != "" {
+			if u, err := url.Parse(c.AutocertDirectoryURL); err != nil || u.Scheme != "https" || u.Host == "" {
+				add("autocert_directory_url %q is not an https URL", c.AutocertDirectoryURL)
+			}
+		}
+	case "selfsigned":
+		if err := checkWritableDir(c.CertCacheDir); err != nil {
+			add("cert_cache_dir: %v", err)
+		}
+	case "manual":
+		if c.CertFile == "" || c.KeyFile == "" {
+			add("cert_file and key_file are required for manual TLS mode")
+		} else if _, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile); err != nil {
+			add("cert_file/key_file: %v", err)
+		}
+	default:
+		add("unknown tls_mode %q (want autocert, selfsigned, manual, or none)", c.TLSMode)
+	}
+	if c.ClientCAFile != "" {
+		if _, err := loadCertPool(c.ClientCAFile); err != nil {
+			add("client_ca_file: %v", err)
+		}
+	}
+
+	storeCfg := store.Config{Driver: c.DatabaseDriver, Path: c.DatabasePath, DSN: c.DatabaseDSN}
+	if err := storeCfg.Validate(); err != nil {
+		add("%v", err)
+	} else if storeCfg.IsSQLite() {
+		if err := checkWritableDir(filepath.Dir(c.DatabasePath)); err != nil {
+			add("database_path: %v", err)
+		}
+	}
+
+	for _, e := range c.TrustedProxies {
+		if !validProxyEntry(strings.TrimSpace(e)) {
+			add("trusted_proxies entry %q is neither an IP nor a CIDR", e)
+		}
+	}
+	return problems
+}
+
+// checkWritableDir confirms dir, or the nearest existing parent the server
+// would create it under, accepts new files.
+func checkWritableDir(dir string) error {
+	existing := dir
+	for {
+		info, err := os.Stat(existing)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", existing)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return fmt.Errorf("no existing parent directory for %s", dir)
+		}
+		existing = parent
+	}
+	f, err := os.CreateTemp(existing, ".machinemon-check-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %w", existing, err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return nil
+}
+
+// validProxyEntry mirrors what parseTrustedProxies accepts.
+func validProxyEntry(e string) bool {
+	if e == "" {
+		return true
+	}
+	if !strings.Contains(e, "/") && net.ParseIP(e) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(e)
+	return err == nil
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigCheck(t *testing.T) {
+	dir := t.TempDir()
+	valid := func() *Config {
+		cfg := DefaultServerConfig()
+		cfg.DatabasePath = filepath.Join(dir, "data", "machinemon.db")
+		cfg.CertCacheDir = filepath.Join(dir, "certs")
+		cfg.AdminPasswordHash = "x"
+		cfg.ClientPasswordHash = "x"
+		return cfg
+	}
+
+	if problems := valid().Check(); len(problems) != 0 {
+		t.Fatalf("default config: unexpected problems %q", problems)
+	}
+
+	cfg := valid()
+	cfg.TLSMode = "manual"
+	cfg.CertFile = filepath.Join(dir, "missing.crt")
+	cfg.KeyFile = filepath.Join(dir, "missing.key")
+	cfg.TrustedProxies = []string{"10.0.0.0/8", "not-an-ip"}
+	cfg.DatabaseDriver = "postgres"
+	problems := cfg.Check()
+	for _, want := range []string{"cert_file/key_file", "not-an-ip", "database_dsn is required"} {
+		if !containsProblem(problems, want) {
+			t.Errorf("expected a problem mentioning %q, got %q", want, problems)
+		}
+	}
+	if len(problems) != 3 {
+		t.Errorf("got %d problems, want 3: %q", len(problems), problems)
+	}
+
+	cfg = valid()
+	cfg.TLSMode = "autocert"
+	if problems := cfg.Check(); !containsProblem(problems, "domain is required") {
+		t.Errorf("autocert without domain: got %q", problems)
+	}
+
+	cfg = valid()
+	cfg.TLSMode = "autocert"
+	cfg.Domain = "monitor.example.com"
+	cfg.AutocertHTTPAddr = "off"
+	if problems := cfg.Check(); len(problems) != 0 {
+		t.Errorf("autocert with TLS-ALPN only: unexpected problems %q", problems)
+	}
+	cfg.AutocertHTTPAddr = "8080"
+	cfg.AutocertDirectoryURL = "http://acme.internal/directory"
+	if problems := cfg.Check(); !containsProblem(problems, "autocert_http_addr") || !containsProblem(problems, "autocert_directory_url") {
+		t.Errorf("bad autocert settings: got %q", problems)
+	}
+
+	cfg = valid()
+	cfg.TLSMode = "letsencrypt"
+	if problems := cfg.Check(); !containsProblem(problems, "unknown tls_mode") {
+		t.Errorf("unknown mode: got %q", problems)
+	}
+
+	cfg = valid()
+	cfg.CommandWebhookTokenHash = "s3cret"
+	if problems := cfg.Check(); !containsProblem(problems, "command_webhook_token_hash") {
+		t.Errorf("plaintext in command_webhook_token_hash: got %q", problems)
+	}
+
+	blocker := filepath.Join(dir, "file")
+	if err := os.WriteFile(blocker, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg = valid()
+	cfg.DatabasePath = filepath.Join(blocker, "machinemon.db")
+	if problems := cfg.Check(); !containsProblem(problems, "not a directory") {
+		t.Errorf("database under a file: got %q", problems)
+	}
+}
+
+func containsProblem(problems []string, substr string) bool {
+	for _, p := range problems {
+		if strings.Contains(p, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAutocertChallengeAddr(t *testing.T) {
+	cases := map[string]string{
+		"":               ":80",
+		":80":            ":80",
+		"127.0.0.1:8081": "127.0.0.1:8081",
+		"off":            "",
+		"OFF":            "",
+		"none":           "",
+	}
+	for in, want := range cases {
+		cfg := &Config{AutocertHTTPAddr: in}
+		if got := cfg.autocertChallengeAddr(); got != want {
+			t.Errorf("autocertChallengeAddr(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -8,17 +8,44 @@ import (
 	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/machinemon/machinemon/internal/version"
 )
 
 func (s *Server) handleDownloadInstallScript(w http.ResponseWriter, r *http.Request) {
-	baseURL := s.getBaseURL(r)
-	script := generateInstallScript(baseURL)
+	script := s.installScript(r)
 
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.Header().Set("Content-Disposition", "inline; filename=install.sh")
 	w.Write([]byte(script))
 }
 
+// handleInstallScriptChecksum serves a detached checksum of install.sh so it
+// can be verified before it is run. The script embeds the base URL, so the
+// checksum must be fetched from the same URL as the script (and after any
+// change to the client archives, whose names are baked in too).
+func (s *Server) handleInstallScriptChecksum(w http.ResponseWriter, r *http.Request) {
+	script := s.installScript(r)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "%s  install.sh\n", sha256Hex([]byte(script)))
+}
+
+// handleDownloadChecksums serves the SHA256SUMS manifest the install script
+// verifies downloaded archives against.
+func (s *Server) handleDownloadChecksums(w http.ResponseWriter, r *http.Request) {
+	manifest, err := s.checksums.manifest(s.cfg.BinariesDir)
+	if os.IsNotExist(err) {
+		http.Error(w, "binary distribution not configured", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Error("failed to build checksum manifest", "err", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(manifest))
+}
+
 func (s *Server) handleDownloadBinary(w http.ResponseWriter, r *http.Request) {
 	filename := chi.URLParam(r, "filename")
 
@@ -41,46 +68,122 @@ func (s *Server) handleDownloadBinary(w http.ResponseWriter, r *http.Request) {
 	}
 
 	filePath := filepath.Join(s.cfg.BinariesDir, filename)
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+	f, err := os.Open(filePath)
+	if os.IsNotExist(err) {
 		http.Error(w, "binary not found", http.StatusNotFound)
 		return
 	}
+	if err != nil {
+		s.logger.Error("failed to open binary", "filename", filename, "err", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
 
-	s.logger.Info("serving binary download", "filename", filename, "remote_addr", r.RemoteAddr)
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.Error(w, "binary not found", http.StatusNotFound)
+		return
+	}
+
+	if r.Header.Get("Range") != "" {
+		s.logger.Info("resuming binary download", "filename", filename, "range", r.Header.Get("Range"), "remote_addr", r.RemoteAddr)
+	} else {
+		s.logger.Info("serving binary download", "filename", filename, "remote_addr", r.RemoteAddr)
+	}
+
+	// The archive is already gzip-compressed: serve it as-is (no
+	// Content-Encoding) so byte ranges refer to the file on disk.
+	// ServeContent streams from the file, handles Range/If-Range, and sets
+	// Content-Length; the ETag lets resumed downloads detect a replaced file.
 	w.Header().Set("Content-Type", "application/gzip")
-	http.ServeFile(w, r, filePath)
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+	http.ServeContent(w, r, filename, info.ModTime(), f)
 }
 
+// downloadBinary describes one archive in BinariesDir. Component, OS and Arch
+// are parsed from the conventional machinemon-<component>-<os>-<arch>.tar.gz
+// name and are empty for files that don't follow it.
+type downloadBinary struct {
+	Filename  string `json:"filename"`
+	URL       string `json:"url"`
+	Size      int64  `json:"size"`
+	SHA256    string `json:"sha256,omitempty"`
+	Component string `json:"component,omitempty"`
+	OS        string `json:"os,omitempty"`
+	Arch      string `json:"arch,omitempty"`
+}
+
+// parseBinaryName splits "machinemon-client-linux-arm64.tar.gz" into
+// ("client", "linux", "arm64").
+func parseBinaryName(filename string) (component, goos, arch string, ok bool) {
+	name, ok := strings.CutSuffix(filename, ".tar.gz")
+	if !ok {
+		return "", "", "", false
+	}
+	name, ok = strings.CutPrefix(name, "machinemon-")
+	if !ok {
+		return "", "", "", false
+	}
+	parts := strings.Split(name, "-")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], true
+}
+
+// handleListDownloads lists the archives in BinariesDir along with the
+// server's version, which the archives are expected to match. ?os=, ?arch=
+// and ?component= narrow the list to archives whose parsed name matches.
 func (s *Server) handleListDownloads(w http.ResponseWriter, r *http.Request) {
 	baseURL := s.getBaseURL(r)
 
 	entries, err := os.ReadDir(s.cfg.BinariesDir)
 	if err != nil {
 		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"version":        version.Version,
 			"install_script": baseURL + "/download/install.sh",
-			"binaries":       []string{},
+			"binaries":       []downloadBinary{},
 			"note":           "no binaries available — place client .tar.gz files in " + s.cfg.BinariesDir,
 		})
 		return
 	}
 
-	var binaries []map[string]string
+	q := r.URL.Query()
+	wantOS := strings.ToLower(strings.TrimSpace(q.Get("os")))
+	wantArch := strings.ToLower(strings.TrimSpace(q.Get("arch")))
+	wantComponent := strings.ToLower(strings.TrimSpace(q.Get("component")))
+
+	binaries := []downloadBinary{}
 	for _, e := range entries {
-		if !e.IsDir() && strings.HasSuffix(e.Name(), ".tar.gz") {
-			info, _ := e.Info()
-			binaries = append(binaries, map[string]string{
-				"name": e.Name(),
-				"url":  baseURL + "/download/" + e.Name(),
-				"size": fmt.Sprintf("%d", info.Size()),
-			})
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".tar.gz") {
+			continue
 		}
-	}
-	if binaries == nil {
-		binaries = []map[string]string{}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		binary := downloadBinary{
+			Filename: e.Name(),
+			URL:      baseURL + "/download/" + e.Name(),
+			Size:     info.Size(),
+		}
+		binary.Component, binary.OS, binary.Arch, _ = parseBinaryName(e.Name())
+		if (wantOS != "" && binary.OS != wantOS) || (wantArch != "" && binary.Arch != wantArch) ||
+			(wantComponent != "" && binary.Component != wantComponent) {
+			continue
+		}
+		if sum, err := s.checksums.sum(filepath.Join(s.cfg.BinariesDir, e.Name())); err == nil {
+			binary.SHA256 = sum
+		}
+		binaries = append(binaries, binary)
 	}
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"version":        version.Version,
 		"install_script": baseURL + "/download/install.sh",
+		"checksums":      baseURL + "/download/" + checksumsFilename,
 		"binaries":       binaries,
 	})
 }
@@ -108,28 +211,67 @@ func (s *Server) getBaseURL(r *http.Request) string {
 	return fmt.Sprintf("%s://%s", scheme, host)
 }
 
-func generateInstallScript(baseURL string) string {
+// installScript renders install.sh for this server: its external URL, its
+// version, and the client archives currently in BinariesDir.
+func (s *Server) installScript(r *http.Request) string {
+	return generateInstallScript(s.getBaseURL(r), version.Version, s.clientArchives())
+}
+
+// clientArchives lists the client builds in BinariesDir by name, without the
+// .tar.gz suffix (e.g. "machinemon-client-linux-amd64"), sorted.
+func (s *Server) clientArchives() []string {
+	entries, err := os.ReadDir(s.cfg.BinariesDir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		if component, _, _, ok := parseBinaryName(e.Name()); ok && component == "client" && !e.IsDir() {
+			names = append(names, strings.TrimSuffix(e.Name(), ".tar.gz"))
+		}
+	}
+	return names
+}
+
+// generateInstallScript renders the client installer. archives limits the
+// platforms the script will try to download; when empty, any is attempted.
+func generateInstallScript(baseURL, serverVersion string, archives []string) string {
 	return fmt.Sprintf(`#!/bin/sh
 set -e
 
 # MachineMon Client Installer
-# Generated by MachineMon Server at %[1]s
+# Generated by MachineMon Server %[2]s at %[1]s
 #
 # Usage:
 #   curl -sSL %[1]s/download/install.sh | sh
 #   curl -sSL %[1]s/download/install.sh | sh -s -- --insecure   (for self-signed certs)
 #   curl -sSL %[1]s/download/install.sh | sh -s -- --upgrade    (upgrade + restart service)
+#   curl -sSL %[1]s/download/install.sh | sh -s -- --service-install  (also install the service)
+#
+# To verify this script before running it:
+#   curl -sSLO %[1]s/download/install.sh
+#   curl -sSL %[1]s/download/install.sh.sha256 | sha256sum -c -
+#   sh install.sh
+#
+# The downloaded archive is always checked against %[1]s/download/SHA256SUMS.
+# Pass --skip-verify only if no sha256sum/shasum/openssl is available.
 
 INSTALL_DIR="/usr/local/bin"
 BINARY="machinemon-client"
 BASE_URL="%[1]s"
+SERVER_VERSION="%[2]s"
+AVAILABLE="%[3]s"
 INSECURE=""
 UPGRADE=0
+SKIP_VERIFY=0
+SERVICE_INSTALL=0
 
 for arg in "$@"; do
     case "$arg" in
         --insecure) INSECURE="--insecure" ;;
         --upgrade) UPGRADE=1 ;;
+        --skip-verify) SKIP_VERIFY=1 ;;
+        --service-install) SERVICE_INSTALL=1 ;;
     esac
 done
 
@@ -189,8 +331,67 @@ run_launchctl_user() {
     fi
 }
 
+fetch() {
+    if command -v curl >/dev/null 2>&1; then
+        curl $CURL_OPTS -f "$1" -o "$2"
+    else
+        wget $WGET_OPTS "$1" -O "$2"
+    fi
+}
+
+sha256_of() {
+    if command -v sha256sum >/dev/null 2>&1; then
+        sha256sum "$1" | awk '{print $1}'
+    elif command -v shasum >/dev/null 2>&1; then
+        shasum -a 256 "$1" | awk '{print $1}'
+    elif command -v openssl >/dev/null 2>&1; then
+        openssl dgst -sha256 "$1" | awk '{print $NF}'
+    else
+        return 1
+    fi
+}
+
+verify_archive() {
+    if [ "$SKIP_VERIFY" -eq 1 ]; then
+        echo "Warning: --skip-verify given, not verifying the download checksum."
+        return 0
+    fi
+
+    if ! fetch "${BASE_URL}/download/SHA256SUMS" "$TMP_DIR/SHA256SUMS"; then
+        echo "Error: could not download checksum manifest from ${BASE_URL}/download/SHA256SUMS"
+        exit 1
+    fi
+    EXPECTED=$(awk -v name="${DOWNLOAD_NAME}.tar.gz" '$2 == name {print $1}' "$TMP_DIR/SHA256SUMS")
+    if [ -z "$EXPECTED" ]; then
+        echo "Error: ${DOWNLOAD_NAME}.tar.gz is not listed in SHA256SUMS"
+        exit 1
+    fi
+    if ! ACTUAL=$(sha256_of "$TMP_DIR/archive.tar.gz"); then
+        echo "Error: sha256sum, shasum, or openssl is required to verify the download"
+        echo "(re-run with --skip-verify to install without verification)"
+        exit 1
+    fi
+    if [ "$ACTUAL" != "$EXPECTED" ]; then
+        echo "Error: checksum mismatch for ${DOWNLOAD_NAME}.tar.gz"
+        echo "  expected: ${EXPECTED}"
+        echo "  actual:   ${ACTUAL}"
+        exit 1
+    fi
+    echo "Verified SHA-256 checksum: ${ACTUAL}"
+}
+
 download_binary() {
     DOWNLOAD_NAME="${BINARY}-${PLATFORM}"
+    if [ -n "$AVAILABLE" ]; then
+        case " $AVAILABLE " in
+            *" $DOWNLOAD_NAME "*) ;;
+            *)
+                echo "Error: this server has no client build for ${PLATFORM}"
+                echo "Available: ${AVAILABLE}"
+                exit 1
+                ;;
+        esac
+    fi
     URL="${BASE_URL}/download/${DOWNLOAD_NAME}.tar.gz"
 
     echo "Downloading from ${URL}..."
@@ -205,15 +406,30 @@ download_binary() {
         WGET_OPTS="$WGET_OPTS --no-check-certificate"
     fi
 
-    if command -v curl >/dev/null 2>&1; then
-        curl $CURL_OPTS "$URL" -o "$TMP_DIR/archive.tar.gz"
-    elif command -v wget >/dev/null 2>&1; then
-        wget $WGET_OPTS "$URL" -O "$TMP_DIR/archive.tar.gz"
-    else
+    if ! command -v curl >/dev/null 2>&1 && ! command -v wget >/dev/null 2>&1; then
         echo "Error: curl or wget is required"
         exit 1
     fi
 
+    # Retry interrupted downloads, resuming from where the last attempt stopped.
+    ATTEMPT=1
+    while :; do
+        if command -v curl >/dev/null 2>&1; then
+            curl $CURL_OPTS -f -C - "$URL" -o "$TMP_DIR/archive.tar.gz" && break
+        else
+            wget $WGET_OPTS -c "$URL" -O "$TMP_DIR/archive.tar.gz" && break
+        fi
+        if [ "$ATTEMPT" -ge 5 ]; then
+            echo "Error: download failed after ${ATTEMPT} attempts"
+            exit 1
+        fi
+        ATTEMPT=$((ATTEMPT + 1))
+        echo "Download interrupted, resuming (attempt ${ATTEMPT})..."
+        sleep 2
+    done
+
+    verify_archive
+
     cd "$TMP_DIR"
     tar xzf archive.tar.gz
 
@@ -314,9 +530,17 @@ restart_service_if_present() {
     return 1
 }
 
+install_service() {
+    if [ "$OS" = "darwin" ]; then
+        "${INSTALL_DIR}/${BINARY}" --service-install
+    else
+        run_privileged "${INSTALL_DIR}/${BINARY}" --service-install
+    fi
+}
+
 main() {
     echo "=== MachineMon Client Installer ==="
-    echo "Server: %[1]s"
+    echo "Server: %[1]s (version ${SERVER_VERSION})"
     echo ""
 
     detect_platform
@@ -336,21 +560,30 @@ main() {
         INSECURE_FLAG=" --insecure"
     fi
 
+    if [ "$SERVICE_INSTALL" -eq 1 ]; then
+        echo ""
+        echo "Installing service..."
+        install_service
+    fi
+
     echo ""
     echo "Installation complete!"
     echo ""
     echo "Next steps:"
     echo "  1. Configure:          machinemon-client --setup --server=%[1]s${INSECURE_FLAG}"
-    if [ "$OS" = "darwin" ]; then
+    if [ "$SERVICE_INSTALL" -eq 1 ]; then
+        echo "     (setup offers to start the installed service)"
+    elif [ "$OS" = "darwin" ]; then
         echo "  2. Install as service: machinemon-client --service-install"
+        echo "     (auto-detects systemd, sysvinit, openrc, upstart, or launchd)"
     else
         echo "  2. Install as service: sudo machinemon-client --service-install"
+        echo "     (auto-detects systemd, sysvinit, openrc, upstart, or launchd)"
     fi
-    echo "     (auto-detects systemd, sysvinit, openrc, upstart, or launchd)"
     echo ""
-    echo "  3. Verify on the dashboard: %[1]s"
+    echo "  Verify on the dashboard: %[1]s"
 }
 
 main "$@"
-`, baseURL)
+`, baseURL, serverVersion, strings.Join(archives, " "))
 }
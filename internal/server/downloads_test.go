@@ -0,0 +1,169 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/machinemon/machinemon/internal/version"
+)
+
+func TestDownloadBinarySupportsRanges(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte("0123456789abcdef")
+	if err := os.WriteFile(filepath.Join(dir, "machinemon-client-linux-amd64.tar.gz"), content, 0644); err != nil {
+		t.Fatalf("write binary: %v", err)
+	}
+	s := &Server{cfg: &Config{BinariesDir: dir}, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	r := chi.NewRouter()
+	r.Get("/download/{filename}", s.handleDownloadBinary)
+
+	req := httptest.NewRequest(http.MethodGet, "/download/machinemon-client-linux-amd64.tar.gz", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if rec.Header().Get("Accept-Ranges") != "bytes" || rec.Header().Get("Content-Length") != "16" {
+		t.Fatalf("unexpected headers: %v", rec.Header())
+	}
+	if rec.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("archive must not be re-encoded, got Content-Encoding %q", rec.Header().Get("Content-Encoding"))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/download/machinemon-client-linux-amd64.tar.gz", nil)
+	req.Header.Set("Range", "bytes=10-")
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("expected 206, got %d", rec.Code)
+	}
+	if got := rec.Body.String(); got != "abcdef" {
+		t.Fatalf("expected resumed tail, got %q", got)
+	}
+	if rec.Header().Get("Content-Range") != "bytes 10-15/16" || rec.Header().Get("Content-Length") != "6" {
+		t.Fatalf("unexpected range headers: %v", rec.Header())
+	}
+}
+
+func TestDownloadBinaryMissingFile(t *testing.T) {
+	s := &Server{cfg: &Config{BinariesDir: t.TempDir()}, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	r := chi.NewRouter()
+	r.Get("/download/{filename}", s.handleDownloadBinary)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/download/missing.tar.gz", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestDownloadChecksumsManifest(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte("archive bytes")
+	if err := os.WriteFile(filepath.Join(dir, "machinemon-client-linux-amd64.tar.gz"), content, 0644); err != nil {
+		t.Fatalf("write binary: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0644); err != nil {
+		t.Fatalf("write notes: %v", err)
+	}
+	s := &Server{
+		cfg:       &Config{BinariesDir: dir, ExternalURL: "https://monitor.example.com"},
+		logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		checksums: newChecksumCache(),
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleDownloadChecksums(rec, httptest.NewRequest(http.MethodGet, "/download/SHA256SUMS", nil))
+	sum := sha256.Sum256(content)
+	want := hex.EncodeToString(sum[:]) + "  machinemon-client-linux-amd64.tar.gz\n"
+	if rec.Code != http.StatusOK || rec.Body.String() != want {
+		t.Fatalf("unexpected manifest (%d): %q", rec.Code, rec.Body.String())
+	}
+
+	script := httptest.NewRecorder()
+	s.handleDownloadInstallScript(script, httptest.NewRequest(http.MethodGet, "/download/install.sh", nil))
+	rec = httptest.NewRecorder()
+	s.handleInstallScriptChecksum(rec, httptest.NewRequest(http.MethodGet, "/download/install.sh.sha256", nil))
+	scriptSum := sha256.Sum256(script.Body.Bytes())
+	if !strings.HasPrefix(rec.Body.String(), hex.EncodeToString(scriptSum[:])+"  install.sh") {
+		t.Fatalf("install script checksum does not match script: %q", rec.Body.String())
+	}
+}
+
+func TestInstallScriptBakesInServerDetails(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"machinemon-client-linux-arm64.tar.gz", "machinemon-client-linux-amd64.tar.gz", "machinemon-server-linux-amd64.tar.gz", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	s := &Server{cfg: &Config{BinariesDir: dir, ExternalURL: "https://monitor.example.com/"}, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	rec := httptest.NewRecorder()
+	s.handleDownloadInstallScript(rec, httptest.NewRequest(http.MethodGet, "http://10.0.0.5:8080/download/install.sh", nil))
+	script := rec.Body.String()
+	for _, want := range []string{
+		`BASE_URL="https://monitor.example.com"`,
+		`SERVER_VERSION="` + version.Version + `"`,
+		`AVAILABLE="machinemon-client-linux-amd64 machinemon-client-linux-arm64"`,
+		`--service-install) SERVICE_INSTALL=1 ;;`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("install script is missing %q", want)
+		}
+	}
+}
+
+func TestListDownloadsParsesAndFilters(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"machinemon-client-linux-amd64.tar.gz", "machinemon-client-darwin-arm64.tar.gz", "machinemon-server-linux-amd64.tar.gz", "custom-build.tar.gz"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	sidecar := strings.Repeat("ab", 32)
+	if err := os.WriteFile(filepath.Join(dir, "machinemon-client-darwin-arm64.tar.gz.sha256"), []byte(sidecar+"  machinemon-client-darwin-arm64.tar.gz\n"), 0644); err != nil {
+		t.Fatalf("write sidecar: %v", err)
+	}
+	s := &Server{
+		cfg:       &Config{BinariesDir: dir, ExternalURL: "https://monitor.example.com"},
+		logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		checksums: newChecksumCache(),
+	}
+	list := func(query string) []downloadBinary {
+		rec := httptest.NewRecorder()
+		s.handleListDownloads(rec, httptest.NewRequest(http.MethodGet, "/download/"+query, nil))
+		var resp struct {
+			Binaries []downloadBinary `json:"binaries"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp.Binaries
+	}
+
+	all := list("")
+	if len(all) != 4 {
+		t.Fatalf("want 4 binaries, got %+v", all)
+	}
+	got := list("?os=linux&arch=amd64&component=client")
+	if len(got) != 1 || got[0].Filename != "machinemon-client-linux-amd64.tar.gz" || got[0].OS != "linux" || got[0].Arch != "amd64" {
+		t.Fatalf("unexpected filtered list: %+v", got)
+	}
+	if got[0].Size != int64(len(got[0].Filename)) || got[0].SHA256 != sha256Hex([]byte(got[0].Filename)) {
+		t.Fatalf("unexpected size or checksum: %+v", got[0])
+	}
+	if got := list("?os=Darwin"); len(got) != 1 || got[0].SHA256 != sidecar {
+		t.Fatalf("want the sidecar checksum for the darwin build, got %+v", got)
+	}
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+const (
+	// eventBufferSize bounds each connection's backlog. A subscriber that
+	// falls this far behind is disconnected rather than allowed to block
+	// publishers; the browser reconnects and reloads current state.
+	eventBufferSize = 64
+	// eventKeepAlive keeps idle streams from being closed by proxies.
+	eventKeepAlive = 25 * time.Second
+)
+
+// Event is one message on the admin live-update stream.
+type Event struct {
+	Type     string        `json:"type"` // "checkin" or "alert"
+	ClientID string        `json:"client_id,omitempty"`
+	Alert    *models.Alert `json:"alert,omitempty"`
+	Time     time.Time     `json:"time"`
+}
+
+// eventBroker fans events out to every connected subscriber.
+type eventBroker struct {
+	mu   sync.Mutex
+	subs map[chan Event]struct{}
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{subs: make(map[chan Event]struct{})}
+}
+
+// subscribe registers a new subscriber. The returned channel is closed when
+// the subscriber is dropped for being too slow or cancel is called.
+func (b *eventBroker) subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, eventBufferSize)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch, func() { b.remove(ch) }
+}
+
+func (b *eventBroker) remove(ch chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+// publish never blocks: subscribers with a full buffer are dropped.
+func (b *eventBroker) publish(ev Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- ev:
+		default:
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// PublishAlert pushes a fired alert to connected dashboards. It is registered
+// with the alert engine and safe to call from any goroutine.
+func (s *Server) PublishAlert(a models.Alert) {
+	s.events.publish(Event{Type: "alert", ClientID: a.ClientID, Alert: &a, Time: time.Now().UTC()})
+}
+
+// handleEvents streams check-in and alert events as Server-Sent Events.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "streaming unsupported"})
+		return
+	}
+
+	ch, cancel := s.events.subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 5000\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				s.logger.Warn("dropped slow event stream subscriber", "remote", r.RemoteAddr)
+				return
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				s.logger.Error("failed to encode event", "type", ev.Type, "err", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventBrokerFanOutAndDropsSlowSubscriber(t *testing.T) {
+	b := newEventBroker()
+	fast, cancelFast := b.subscribe()
+	defer cancelFast()
+	slow, cancelSlow := b.subscribe()
+	defer cancelSlow()
+
+	// Fill both buffers, draining only the fast subscriber.
+	for i := 0; i < eventBufferSize; i++ {
+		b.publish(Event{Type: "checkin", ClientID: "a"})
+		<-fast
+	}
+	b.publish(Event{Type: "alert", ClientID: "b"})
+
+	select {
+	case ev := <-fast:
+		if ev.Type != "alert" || ev.ClientID != "b" {
+			t.Fatalf("unexpected event %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("fast subscriber did not receive event")
+	}
+
+	// The slow subscriber keeps its buffered events, then sees the close.
+	n := 0
+	for range slow {
+		n++
+	}
+	if n != eventBufferSize {
+		t.Fatalf("expected %d buffered events before close, got %d", eventBufferSize, n)
+	}
+
+	// Cancelling an already dropped subscriber must not panic.
+	cancelSlow()
+}
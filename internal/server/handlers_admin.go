@@ -2,17 +2,43 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/machinemon/machinemon/internal/alerting"
 	"github.com/machinemon/machinemon/internal/models"
+	"github.com/machinemon/machinemon/internal/store"
+	"github.com/machinemon/machinemon/internal/version"
 )
 
+// handleListClients returns every client by default; limit/offset page the
+// list, q filters on hostname or custom name, and tag on a client tag.
 func (s *Server) handleListClients(w http.ResponseWriter, r *http.Request) {
-	clients, err := s.store.ListClients()
+	filter := store.ClientFilter{
+		Query: r.URL.Query().Get("q"),
+		Tag:   r.URL.Query().Get("tag"),
+	}
+	limit := 0
+	offset := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			limit = n
+		}
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			offset = n
+		}
+	}
+
+	clients, total, err := s.store.ListClients(filter, limit, offset)
 	if err != nil {
 		s.logger.Error("failed to list clients", "err", err)
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
@@ -21,7 +47,12 @@ func (s *Server) handleListClients(w http.ResponseWriter, r *http.Request) {
 	if clients == nil {
 		clients = []models.ClientWithMetrics{}
 	}
-	writeJSON(w, http.StatusOK, map[string]interface{}{"clients": clients})
+	resp := map[string]interface{}{"clients": clients, "total": total}
+	if limit > 0 {
+		resp["limit"] = limit
+		resp["offset"] = offset
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 func (s *Server) handleGetClient(w http.ResponseWriter, r *http.Request) {
@@ -44,25 +75,87 @@ func (s *Server) handleGetClient(w http.ResponseWriter, r *http.Request) {
 	if procs == nil {
 		procs = []models.ProcessSnapshot{}
 	}
+	s.attachRestartCounts(id, procs)
+	s.attachProcessAnnotations(id, procs)
 	// Get latest check snapshots
 	checks, _ := s.store.GetLatestCheckSnapshots(id)
 	if checks == nil {
 		checks = []models.CheckSnapshot{}
 	}
+	diskMounts, _ := s.store.GetLatestDiskMounts(id)
+	if diskMounts == nil {
+		diskMounts = []models.DiskMount{}
+	}
 	alertMutes, _ := s.store.ListClientAlertMutes(id)
 	if alertMutes == nil {
 		alertMutes = []models.ClientAlertMute{}
 	}
+	maintenanceWindows, _ := s.store.ListMaintenanceWindows(id)
+	if maintenanceWindows == nil {
+		maintenanceWindows = []models.MaintenanceWindow{}
+	}
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"client":      client,
-		"metrics":     metrics,
-		"processes":   procs,
-		"checks":      checks,
-		"alert_mutes": alertMutes,
+		"client":              client,
+		"metrics":             metrics,
+		"disk_mounts":         diskMounts,
+		"processes":           procs,
+		"checks":              checks,
+		"alert_mutes":         alertMutes,
+		"maintenance_windows": maintenanceWindows,
+	})
+}
+
+// handleListClientVersions groups clients by the version they last reported,
+// newest first, and flags versions older than min_client_version.
+func (s *Server) handleListClientVersions(w http.ResponseWriter, r *http.Request) {
+	infos, err := s.store.ListClientVersions()
+	if err != nil {
+		s.logger.Error("failed to list client versions", "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	minVersion := alerting.MinClientVersion(s.store)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"min_version": minVersion,
+		"versions":    groupClientVersions(infos, minVersion),
 	})
 }
 
+// groupClientVersions buckets clients by version. Comparable versions sort
+// newest first; the rest (e.g. "dev" builds) follow in name order.
+func groupClientVersions(infos []models.ClientVersionInfo, minVersion string) []models.ClientVersionGroup {
+	groups := []models.ClientVersionGroup{}
+	index := map[string]int{}
+	for _, info := range infos {
+		i, ok := index[info.ClientVersion]
+		if !ok {
+			i = len(groups)
+			index[info.ClientVersion] = i
+			groups = append(groups, models.ClientVersionGroup{
+				Version:  info.ClientVersion,
+				Outdated: alerting.ClientOutdated(info.ClientVersion, minVersion),
+			})
+		}
+		groups[i].Clients = append(groups[i].Clients, info)
+	}
+	slices.SortStableFunc(groups, func(a, b models.ClientVersionGroup) int {
+		if c, ok := version.Compare(a.Version, b.Version); ok {
+			return -c
+		}
+		_, aOK := version.Compare(a.Version, a.Version)
+		_, bOK := version.Compare(b.Version, b.Version)
+		switch {
+		case aOK && !bOK:
+			return -1
+		case !aOK && bOK:
+			return 1
+		}
+		return strings.Compare(a.Version, b.Version)
+	})
+	return groups
+}
+
 func (s *Server) handleDeleteClient(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if err := s.store.DeleteClient(id); err != nil {
@@ -70,9 +163,106 @@ func (s *Server) handleDeleteClient(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
 		return
 	}
+	s.audit(r, "client.delete", id, "")
 	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 }
 
+// handlePurgeClient permanently removes a client and all of its history, for
+// decommissioned hosts. Unlike DELETE it can't be undone by a check-in.
+func (s *Server) handlePurgeClient(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !s.requireClient(w, id) {
+		return
+	}
+	if err := s.store.PurgeClient(id); err != nil {
+		s.logger.Error("failed to purge client", "id", id, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	s.logger.Info("purged client", "id", id)
+	s.audit(r, "client.purge", id, "")
+	writeJSON(w, http.StatusOK, map[string]string{"status": "purged"})
+}
+
+// handleClearClientMetrics wipes a client's metric and snapshot history but
+// keeps the client, e.g. after test data polluted its charts.
+func (s *Server) handleClearClientMetrics(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !s.requireClient(w, id) {
+		return
+	}
+	if err := s.store.ClearClientMetrics(id); err != nil {
+		s.logger.Error("failed to clear client metrics", "id", id, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	s.audit(r, "client.clear_metrics", id, "")
+	writeJSON(w, http.StatusOK, map[string]string{"status": "cleared"})
+}
+
+// requireClient writes a 404 (or 500) and returns false unless the client
+// exists. Soft-deleted clients count as existing.
+func (s *Server) requireClient(w http.ResponseWriter, id string) bool {
+	c, err := s.store.GetClient(id)
+	if err != nil {
+		s.logger.Error("failed to get client", "id", id, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return false
+	}
+	if c == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "client not found"})
+		return false
+	}
+	return true
+}
+
+type mergeClientsRequest struct {
+	SourceID string `json:"source_id"`
+}
+
+// handleMergeClients folds the history of source_id into the client in the URL
+// and soft-deletes the source. Used to consolidate duplicates after a reinstall.
+func (s *Server) handleMergeClients(w http.ResponseWriter, r *http.Request) {
+	targetID := chi.URLParam(r, "id")
+
+	var req mergeClientsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	sourceID := strings.TrimSpace(req.SourceID)
+	if sourceID == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "source_id is required"})
+		return
+	}
+	if sourceID == targetID {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "cannot merge a client into itself"})
+		return
+	}
+
+	for _, id := range []string{targetID, sourceID} {
+		c, err := s.store.GetClient(id)
+		if err != nil {
+			s.logger.Error("failed to get client", "id", id, "err", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+			return
+		}
+		if c == nil || c.IsDeleted {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "client not found: " + id})
+			return
+		}
+	}
+
+	if err := s.store.MergeClients(sourceID, targetID); err != nil {
+		s.logger.Error("failed to merge clients", "source_id", sourceID, "target_id", targetID, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	s.logger.Info("merged clients", "source_id", sourceID, "target_id", targetID)
+	s.audit(r, "client.merge", targetID, "source_id="+sourceID)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "merged"})
+}
+
 func (s *Server) handleSetThresholds(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
@@ -101,12 +291,21 @@ func (s *Server) handleSetThresholds(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "metric_consecutive_checkins must be >= 1"})
 		return
 	}
+	if t.AlertCooldownEnabled != nil && *t.AlertCooldownEnabled && t.AlertCooldownSeconds == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "alert_cooldown_seconds is required when alert_cooldown_enabled is true"})
+		return
+	}
+	if t.AlertCooldownSeconds != nil && *t.AlertCooldownSeconds < 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "alert_cooldown_seconds must be >= 0"})
+		return
+	}
 
 	if err := s.store.SetClientThresholds(id, &t); err != nil {
 		s.logger.Error("failed to set thresholds", "id", id, "err", err)
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
 		return
 	}
+	s.audit(r, "client.thresholds.set", id, auditDetail(t))
 	writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
 }
 
@@ -117,9 +316,113 @@ func (s *Server) handleClearThresholds(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
 		return
 	}
+	s.audit(r, "client.thresholds.clear", id, "")
 	writeJSON(w, http.StatusOK, map[string]string{"status": "reset"})
 }
 
+// handleGetEffectiveThresholds returns the thresholds the alert engine
+// actually applies to a client, with where each value came from.
+func (s *Server) handleGetEffectiveThresholds(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	client, err := s.store.GetClient(id)
+	if err != nil {
+		s.logger.Error("failed to get client", "id", id, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if client == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "client not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, alerting.ResolveEffectiveThresholds(s.store, client))
+}
+
+type businessHoursRequest struct {
+	Enabled bool `json:"enabled"`
+	models.BusinessHours
+}
+
+// handleSetBusinessHours sets or clears the client's business-hours alerting
+// schedule. Outside the window all of the client's alerts are suppressed.
+func (s *Server) handleSetBusinessHours(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req businessHoursRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	var bh *models.BusinessHours
+	if req.Enabled {
+		bh = &req.BusinessHours
+		if err := alerting.ValidateBusinessHours(bh); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid business hours: " + err.Error()})
+			return
+		}
+	}
+
+	client, err := s.store.GetClient(id)
+	if err != nil {
+		s.logger.Error("failed to get client", "id", id, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if client == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "client not found"})
+		return
+	}
+
+	if err := s.store.SetClientBusinessHours(id, bh); err != nil {
+		s.logger.Error("failed to set business hours", "id", id, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	s.audit(r, "client.business_hours.set", id, auditDetail(req))
+	writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+}
+
+type checkInIntervalRequest struct {
+	Seconds *int `json:"check_in_interval_seconds"`
+}
+
+// handleSetCheckInInterval sets or clears (null) how often the client is told
+// to check in. Clients pick up the change on their next check-in.
+func (s *Server) handleSetCheckInInterval(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req checkInIntervalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if req.Seconds != nil && (*req.Seconds < minCheckInInterval || *req.Seconds > maxCheckInInterval) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": fmt.Sprintf("check_in_interval_seconds must be between %d and %d", minCheckInInterval, maxCheckInInterval),
+		})
+		return
+	}
+
+	client, err := s.store.GetClient(id)
+	if err != nil {
+		s.logger.Error("failed to get client", "id", id, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if client == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "client not found"})
+		return
+	}
+
+	if err := s.store.SetClientCheckInInterval(id, req.Seconds); err != nil {
+		s.logger.Error("failed to set check-in interval", "id", id, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	s.audit(r, "client.check_in_interval.set", id, auditDetail(req))
+	writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+}
+
 type setClientNameRequest struct {
 	Name string `json:"name"`
 }
@@ -144,6 +447,54 @@ func (s *Server) handleSetClientName(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
 		return
 	}
+	s.audit(r, "client.rename", id, name)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+}
+
+type setClientTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+const maxClientTags = 20
+
+// validClientTag allows lowercase letters, digits, '.', '_' and '-'.
+var validClientTag = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,31}$`)
+
+func (s *Server) handleSetClientTags(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req setClientTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if len(req.Tags) > maxClientTags {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "too many tags (max 20)"})
+		return
+	}
+	for _, tag := range req.Tags {
+		if !validClientTag.MatchString(strings.ToLower(strings.TrimSpace(tag))) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid tag: use up to 32 letters, digits, '.', '_' or '-'"})
+			return
+		}
+	}
+
+	client, err := s.store.GetClient(id)
+	if err != nil {
+		s.logger.Error("failed to get client", "id", id, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if client == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "client not found"})
+		return
+	}
+	if err := s.store.SetClientTags(id, req.Tags); err != nil {
+		s.logger.Error("failed to set client tags", "id", id, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	s.audit(r, "client.tags.set", id, strings.Join(req.Tags, ","))
 	writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
 }
 
@@ -179,6 +530,134 @@ func (s *Server) handleSetMute(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
 		return
 	}
+	s.audit(r, "client.mute", id, auditDetail(req))
+	writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+}
+
+type bulkMuteRequest struct {
+	ClientIDs       []string `json:"client_ids"`
+	All             bool     `json:"all"`
+	DurationMinutes int      `json:"duration_minutes"`
+	Reason          string   `json:"reason"`
+}
+
+// handleBulkMute mutes many clients, or all of them, in one call, e.g. ahead
+// of datacenter-wide maintenance.
+func (s *Server) handleBulkMute(w http.ResponseWriter, r *http.Request) {
+	s.bulkSetMute(w, r, true)
+}
+
+// handleBulkUnmute is the counterpart of handleBulkMute.
+func (s *Server) handleBulkUnmute(w http.ResponseWriter, r *http.Request) {
+	s.bulkSetMute(w, r, false)
+}
+
+func (s *Server) bulkSetMute(w http.ResponseWriter, r *http.Request, muted bool) {
+	var req bulkMuteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if req.All == (len(req.ClientIDs) > 0) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "give either client_ids or all, not both"})
+		return
+	}
+	if req.DurationMinutes < 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "duration_minutes must not be negative"})
+		return
+	}
+
+	ids := req.ClientIDs
+	if req.All {
+		clients, _, err := s.store.ListClients(store.ClientFilter{}, 0, 0)
+		if err != nil {
+			s.logger.Error("failed to list clients", "err", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+			return
+		}
+		ids = make([]string, 0, len(clients))
+		for _, c := range clients {
+			ids = append(ids, c.ID)
+		}
+	}
+
+	var until *time.Time
+	reason := req.Reason
+	if muted && req.DurationMinutes > 0 {
+		t := time.Now().Add(time.Duration(req.DurationMinutes) * time.Minute)
+		until = &t
+	}
+	if !muted {
+		reason = ""
+	}
+
+	missing, err := s.store.SetClientsMute(ids, muted, until, reason)
+	if err != nil {
+		s.logger.Error("failed to set bulk mute", "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if len(missing) > 0 {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error":   "unknown clients",
+			"details": missing,
+		})
+		return
+	}
+
+	action := "clients.mute"
+	if !muted {
+		action = "clients.unmute"
+	}
+	target := strings.Join(ids, ",")
+	if req.All {
+		target = "all"
+	}
+	s.audit(r, action, target, auditDetail(req))
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "updated", "updated": len(ids)})
+}
+
+type suspendRequest struct {
+	Suspended bool   `json:"suspended"`
+	Reason    string `json:"reason"`
+}
+
+// maxSuspendReasonLen bounds the free-text reason shown on the client page.
+const maxSuspendReasonLen = 500
+
+// handleSetSuspended suspends or resumes a client. Unlike a mute it has no
+// expiry; it is meant for hosts known to be down or being retired.
+func (s *Server) handleSetSuspended(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req suspendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if len(req.Reason) > maxSuspendReasonLen {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("reason must be at most %d characters", maxSuspendReasonLen)})
+		return
+	}
+
+	client, err := s.store.GetClient(id)
+	if err != nil {
+		s.logger.Error("failed to get client", "id", id, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if client == nil || client.IsDeleted {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "client not found"})
+		return
+	}
+
+	if err := s.store.SetClientSuspended(id, req.Suspended, req.Reason); err != nil {
+		s.logger.Error("failed to set suspended", "id", id, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	s.audit(r, "client.suspend", id, auditDetail(req))
 	writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
 }
 
@@ -210,9 +689,14 @@ func (s *Server) handleSetScopedMute(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
 		return
 	}
+	s.audit(r, "client.scoped_mute", id, auditDetail(req))
 	writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
 }
 
+// minMetricsBucket is the smallest downsampling bucket accepted; finer than
+// this is no smaller than the raw check-in cadence.
+const minMetricsBucket = time.Minute
+
 func (s *Server) handleGetMetrics(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
@@ -236,7 +720,23 @@ func (s *Server) handleGetMetrics(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	metrics, err := s.store.GetMetrics(id, from, to, limit)
+	var bucket time.Duration
+	if v := r.URL.Query().Get("bucket"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < minMetricsBucket {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "bucket must be a duration of at least 1m (e.g. 5m, 1h)"})
+			return
+		}
+		bucket = d
+	}
+
+	var metrics []models.Metric
+	var err error
+	if bucket > 0 {
+		metrics, err = s.store.GetMetricsBucketed(id, from, to, bucket, limit)
+	} else {
+		metrics, err = s.store.GetMetrics(id, from, to, limit)
+	}
 	if err != nil {
 		s.logger.Error("failed to get metrics", "id", id, "err", err)
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
@@ -270,6 +770,8 @@ func (s *Server) handleGetProcesses(w http.ResponseWriter, r *http.Request) {
 	if snapshots == nil {
 		snapshots = []models.ProcessSnapshot{}
 	}
+	s.attachRestartCounts(id, snapshots)
+	s.attachProcessAnnotations(id, snapshots)
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"watched":   watched,
@@ -277,6 +779,90 @@ func (s *Server) handleGetProcesses(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// attachRestartCounts fills each snapshot's 24-hour restart count. Failures
+// are logged and leave the counts at zero.
+func (s *Server) attachRestartCounts(clientID string, snaps []models.ProcessSnapshot) {
+	counts, err := s.store.CountProcessRestarts(clientID, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		s.logger.Error("failed to count process restarts", "id", clientID, "err", err)
+		return
+	}
+	for i := range snaps {
+		snaps[i].Restarts24h = counts[snaps[i].FriendlyName]
+	}
+}
+
+// attachProcessAnnotations copies each watched process's dashboard note and
+// expected state onto its snapshot. Failures are logged and leave them empty.
+func (s *Server) attachProcessAnnotations(clientID string, snaps []models.ProcessSnapshot) {
+	watched, err := s.store.GetWatchedProcesses(clientID)
+	if err != nil {
+		s.logger.Error("failed to get watched processes", "id", clientID, "err", err)
+		return
+	}
+	byName := make(map[string]models.WatchedProcess, len(watched))
+	for _, w := range watched {
+		byName[w.FriendlyName] = w
+	}
+	for i := range snaps {
+		if w, ok := byName[snaps[i].FriendlyName]; ok {
+			snaps[i].Note = w.Note
+			snaps[i].ExpectedState = w.ExpectedState
+		}
+	}
+}
+
+type updateProcessRequest struct {
+	Note          string `json:"note"`
+	ExpectedState string `json:"expected_state"`
+}
+
+const maxProcessNoteLen = 500
+
+// handleUpdateProcess sets the note and expected state of a watched process.
+// The process itself stays defined by the client config.
+func (s *Server) handleUpdateProcess(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	name, err := url.PathUnescape(chi.URLParam(r, "name"))
+	if err != nil || strings.TrimSpace(name) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid process name"})
+		return
+	}
+
+	var req updateProcessRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	note := strings.TrimSpace(req.Note)
+	if len(note) > maxProcessNoteLen {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("note too long (max %d chars)", maxProcessNoteLen)})
+		return
+	}
+	expected := strings.TrimSpace(req.ExpectedState)
+	switch expected {
+	case "":
+		expected = models.ProcessExpectRunning
+	case models.ProcessExpectRunning, models.ProcessExpectStopped:
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": `expected_state must be "running" or "stopped"`})
+		return
+	}
+
+	found, err := s.store.UpdateWatchedProcess(id, name, note, expected)
+	if err != nil {
+		s.logger.Error("failed to update watched process", "id", id, "friendly_name", name, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if !found {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "watched process not found"})
+		return
+	}
+	s.audit(r, "client.process.update", id, fmt.Sprintf("%s: expected %s", name, expected))
+	writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+}
+
 func (s *Server) handleDeleteProcess(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	friendlyName := strings.TrimSpace(r.URL.Query().Get("friendly_name"))
@@ -290,6 +876,7 @@ func (s *Server) handleDeleteProcess(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
 		return
 	}
+	s.audit(r, "client.process.delete", id, friendlyName)
 	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 }
 
@@ -307,5 +894,6 @@ func (s *Server) handleDeleteCheck(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
 		return
 	}
+	s.audit(r, "client.check.delete", id, strings.TrimSpace(friendlyName+" "+checkType))
 	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 }
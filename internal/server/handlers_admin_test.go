@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/machinemon/machinemon/internal/models"
+	"github.com/machinemon/machinemon/internal/store"
+)
+
+// newTestServer returns a server backed by a fresh SQLite store that is
+// closed when the test ends. Tests set any config they need on the result.
+func newTestServer(t *testing.T) (*Server, *store.SQLiteStore) {
+	t.Helper()
+	st, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return &Server{store: st, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}, st
+}
+
+func TestBulkMute(t *testing.T) {
+	s, st := newTestServer(t)
+
+	var ids []string
+	for _, host := range []string{"web-1", "web-2"} {
+		res, err := st.UpsertClient(models.CheckInRequest{Hostname: host, SessionID: "boot-a"}, "")
+		if err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+		ids = append(ids, res.ClientID)
+	}
+	muted := func(id string) bool {
+		c, err := st.GetClient(id)
+		if err != nil {
+			t.Fatalf("get client: %v", err)
+		}
+		return c.AlertsMuted
+	}
+	post := func(handler http.HandlerFunc, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodPost, "/api/v1/admin/mute", strings.NewReader(body)))
+		return w
+	}
+
+	w := post(s.handleBulkMute, `{"client_ids":["`+ids[0]+`","nope"],"reason":"dc move"}`)
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "nope") {
+		t.Fatalf("unknown id: got %d %s", w.Code, w.Body.String())
+	}
+	if muted(ids[0]) {
+		t.Fatalf("a rejected batch must not mute anything")
+	}
+
+	if w := post(s.handleBulkMute, `{"client_ids":["a"],"all":true}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("ids and all together: got %d", w.Code)
+	}
+
+	if w := post(s.handleBulkMute, `{"all":true,"duration_minutes":60,"reason":"dc move"}`); w.Code != http.StatusOK {
+		t.Fatalf("mute all: got %d %s", w.Code, w.Body.String())
+	}
+	if !muted(ids[0]) || !muted(ids[1]) {
+		t.Fatalf("expected every client muted")
+	}
+
+	if w := post(s.handleBulkUnmute, `{"client_ids":["`+ids[1]+`"]}`); w.Code != http.StatusOK {
+		t.Fatalf("unmute: got %d %s", w.Code, w.Body.String())
+	}
+	if !muted(ids[0]) || muted(ids[1]) {
+		t.Fatalf("expected only %s unmuted", ids[1])
+	}
+}
+
+func TestMergeClientsRejectsDeletedClients(t *testing.T) {
+	s, st := newTestServer(t)
+
+	var ids []string
+	for _, host := range []string{"web-1", "web-2", "web-3"} {
+		res, err := st.UpsertClient(models.CheckInRequest{Hostname: host, SessionID: "boot-a"}, "")
+		if err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+		ids = append(ids, res.ClientID)
+	}
+	merge := func(sourceID, targetID string) int {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", targetID)
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"source_id":"`+sourceID+`"}`))
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		s.handleMergeClients(w, req)
+		return w.Code
+	}
+
+	if code := merge(ids[1], ids[0]); code != http.StatusOK {
+		t.Fatalf("merge: got %d", code)
+	}
+	// ids[1] is now soft-deleted and can be neither side of another merge.
+	if code := merge(ids[2], ids[1]); code != http.StatusNotFound {
+		t.Fatalf("merge into a deleted client: got %d", code)
+	}
+	if code := merge(ids[1], ids[2]); code != http.StatusNotFound {
+		t.Fatalf("merge from a deleted client: got %d", code)
+	}
+}
+
+func TestGroupClientVersions(t *testing.T) {
+	infos := []models.ClientVersionInfo{
+		{ClientID: "a", ClientVersion: "dev"},
+		{ClientID: "b", ClientVersion: "v1.2.0"},
+		{ClientID: "c", ClientVersion: "v1.10.0"},
+		{ClientID: "d", ClientVersion: "v1.2.0"},
+		{ClientID: "e", ClientVersion: ""},
+	}
+	groups := groupClientVersions(infos, "v1.3.0")
+	var got []string
+	for _, g := range groups {
+		got = append(got, fmt.Sprintf("%s:%d:%v", g.Version, len(g.Clients), g.Outdated))
+	}
+	want := "v1.10.0:1:false v1.2.0:2:true :1:false dev:1:false"
+	if strings.Join(got, " ") != want {
+		t.Fatalf("groups = %q, want %q", strings.Join(got, " "), want)
+	}
+}
@@ -4,15 +4,72 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/machinemon/machinemon/internal/alerting"
 	"github.com/machinemon/machinemon/internal/models"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/machinemon/machinemon/internal/store"
 )
 
 func (s *Server) handleListAlerts(w http.ResponseWriter, r *http.Request) {
-	clientID := r.URL.Query().Get("client_id")
-	severity := r.URL.Query().Get("severity")
+	filter := store.AlertFilter{
+		ClientID:  r.URL.Query().Get("client_id"),
+		Severity:  r.URL.Query().Get("severity"),
+		AlertType: r.URL.Query().Get("alert_type"),
+	}
+	if v := r.URL.Query().Get("acked"); v != "" {
+		acked, err := strconv.ParseBool(v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "acked must be true or false"})
+			return
+		}
+		filter.Acked = &acked
+	}
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "from must be an RFC 3339 timestamp"})
+			return
+		}
+		filter.From = t
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "to must be an RFC 3339 timestamp"})
+			return
+		}
+		filter.To = t
+	}
+	// since=session limits the list to the client's current boot.
+	if v := r.URL.Query().Get("since"); v != "" {
+		if v != "session" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "since must be session"})
+			return
+		}
+		if filter.ClientID == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "since=session requires client_id"})
+			return
+		}
+		client, err := s.store.GetClient(filter.ClientID)
+		if err != nil {
+			s.logger.Error("failed to get client", "id", filter.ClientID, "err", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+			return
+		}
+		if client == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "client not found"})
+			return
+		}
+		if client.SessionStartedAt.After(filter.From) {
+			filter.From = client.SessionStartedAt
+		}
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "to must not be before from"})
+		return
+	}
 	limit := 100
 	offset := 0
 
@@ -27,7 +84,7 @@ func (s *Server) handleListAlerts(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	alerts, total, err := s.store.ListAlerts(clientID, severity, limit, offset)
+	alerts, total, err := s.store.ListAlerts(filter, limit, offset)
 	if err != nil {
 		s.logger.Error("failed to list alerts", "err", err)
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
@@ -44,6 +101,26 @@ func (s *Server) handleListAlerts(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (s *Server) handleAckAlert(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid alert id"})
+		return
+	}
+	found, err := s.store.AckAlert(id)
+	if err != nil {
+		s.logger.Error("failed to ack alert", "id", id, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if !found {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "alert not found"})
+		return
+	}
+	s.audit(r, "alert.ack", strconv.FormatInt(id, 10), "")
+	writeJSON(w, http.StatusOK, map[string]string{"status": "acked"})
+}
+
 func (s *Server) handleListProviders(w http.ResponseWriter, r *http.Request) {
 	providers, err := s.store.ListProviders()
 	if err != nil {
@@ -67,11 +144,16 @@ func (s *Server) handleCreateProvider(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "type and name are required"})
 		return
 	}
+	if err := alerting.ValidateProvider(p); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
 	if err := s.store.CreateProvider(&p); err != nil {
 		s.logger.Error("failed to create provider", "err", err)
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
 		return
 	}
+	s.audit(r, "provider.create", strconv.FormatInt(p.ID, 10), p.Type+" "+p.Name)
 	writeJSON(w, http.StatusCreated, p)
 }
 
@@ -89,12 +171,17 @@ func (s *Server) handleUpdateProvider(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	p.ID = id
+	if err := alerting.ValidateProvider(p); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
 
 	if err := s.store.UpdateProvider(&p); err != nil {
 		s.logger.Error("failed to update provider", "id", id, "err", err)
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
 		return
 	}
+	s.audit(r, "provider.update", strconv.FormatInt(id, 10), p.Type+" "+p.Name)
 	writeJSON(w, http.StatusOK, p)
 }
 
@@ -110,6 +197,7 @@ func (s *Server) handleDeleteProvider(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
 		return
 	}
+	s.audit(r, "provider.delete", strconv.FormatInt(id, 10), "")
 	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 }
 
@@ -136,6 +224,50 @@ func (s *Server) handleTestProvider(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+type alertRoutesRequest struct {
+	Routes []models.AlertRoute `json:"routes"`
+}
+
+func (s *Server) handleGetAlertRoutes(w http.ResponseWriter, r *http.Request) {
+	routes, err := alerting.LoadAlertRoutes(s.store)
+	if err != nil {
+		s.logger.Error("failed to load alert routes", "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if routes == nil {
+		routes = []models.AlertRoute{}
+	}
+	writeJSON(w, http.StatusOK, alertRoutesRequest{Routes: routes})
+}
+
+// handleSetAlertRoutes replaces the routing table. An empty list restores
+// the default of sending every alert to every enabled provider.
+func (s *Server) handleSetAlertRoutes(w http.ResponseWriter, r *http.Request) {
+	var req alertRoutesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	providers, err := s.store.ListProviders()
+	if err != nil {
+		s.logger.Error("failed to list providers", "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if err := alerting.ValidateAlertRoutes(req.Routes, providers); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if err := alerting.SaveAlertRoutes(s.store, req.Routes); err != nil {
+		s.logger.Error("failed to save alert routes", "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	s.audit(r, "alert_routes.update", "", auditDetail(req))
+	writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+}
+
 func (s *Server) handleGetSettings(w http.ResponseWriter, r *http.Request) {
 	settings, err := s.store.GetAllSettings()
 	if err != nil {
@@ -167,6 +299,7 @@ func (s *Server) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	s.audit(r, "settings.update", "", auditDetail(settings))
 	writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
 }
 
@@ -187,22 +320,26 @@ func (s *Server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hash, err := HashPassword(req.Password, s.cfg.PasswordCost())
 	if err != nil {
 		s.logger.Error("failed to hash password", "err", err)
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
 		return
 	}
 
+	s.passwordMu.Lock()
 	switch req.Type {
 	case "admin":
-		s.cfg.AdminPasswordHash = string(hash)
+		s.cfg.AdminPasswordHash = hash
 	case "client":
-		s.cfg.ClientPasswordHash = string(hash)
+		s.cfg.ClientPasswordHash = hash
 	default:
+		s.passwordMu.Unlock()
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "type must be 'admin' or 'client'"})
 		return
 	}
+	s.passwordMu.Unlock()
 
+	s.audit(r, "password.change", req.Type, "")
 	writeJSON(w, http.StatusOK, map[string]string{"status": "password updated"})
 }
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestProviderConfigValidatedOnSave(t *testing.T) {
+	s, st := newTestServer(t)
+
+	create := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.handleCreateProvider(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		return w
+	}
+
+	w := create(`{"type":"smtp","name":"Email","enabled":true,"config":"{\"host\":\"smtp.example.com\",\"port\":587,\"to\":\"ops@example.com\"}"}`)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "from address is required") {
+		t.Fatalf("missing from: status %d body %s", w.Code, w.Body.String())
+	}
+	w = create(`{"type":"carrier-pigeon","name":"Birds","config":"{}"}`)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "unknown provider type") {
+		t.Fatalf("unknown type: status %d body %s", w.Code, w.Body.String())
+	}
+	w = create(`{"type":"pushover","name":"Push","config":"not json"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("malformed config: status %d body %s", w.Code, w.Body.String())
+	}
+
+	w = create(`{"type":"pushover","name":"Push","enabled":true,"config":"{\"user_key\":\"u\",\"app_token\":\"t\"}"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("valid provider: status %d body %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+
+	// An update that drops a required field is rejected and the stored
+	// config is left alone.
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"type":"pushover","name":"Push","enabled":true,"config":"{\"user_key\":\"u\"}"}`))
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	w = httptest.NewRecorder()
+	s.handleUpdateProvider(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid update: status %d body %s", w.Code, w.Body.String())
+	}
+	p, err := st.GetProvider(created.ID)
+	if err != nil || p == nil {
+		t.Fatalf("get provider: %v", err)
+	}
+	if !strings.Contains(p.Config, "app_token") {
+		t.Fatalf("stored config changed: %s", p.Config)
+	}
+}
+
+func TestListAlertsSinceSession(t *testing.T) {
+	s, st := newTestServer(t)
+
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := st.InsertAlert(&models.Alert{ClientID: res.ClientID, AlertType: models.AlertTypeOffline, Severity: models.SeverityCritical, Message: "previous boot"}); err != nil {
+		t.Fatalf("insert alert: %v", err)
+	}
+	// A reboot starts a new session; only alerts after it belong to this boot.
+	time.Sleep(1100 * time.Millisecond)
+	if _, err := st.UpsertClient(models.CheckInRequest{ClientID: res.ClientID, Hostname: "web-1", SessionID: "boot-b", BootTimeUnix: time.Now().Unix()}, ""); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := st.InsertAlert(&models.Alert{ClientID: res.ClientID, AlertType: models.AlertTypeOnline, Severity: models.SeverityInfo, Message: "this boot"}); err != nil {
+		t.Fatalf("insert alert: %v", err)
+	}
+
+	list := func(query string) (*httptest.ResponseRecorder, []models.Alert) {
+		w := httptest.NewRecorder()
+		s.handleListAlerts(w, httptest.NewRequest(http.MethodGet, "/?"+query, nil))
+		var resp struct {
+			Alerts []models.Alert `json:"alerts"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp.Alerts
+	}
+	if w, alerts := list("client_id=" + res.ClientID); w.Code != http.StatusOK || len(alerts) != 2 {
+		t.Fatalf("full history: status %d, %+v", w.Code, alerts)
+	}
+	if w, alerts := list("client_id=" + res.ClientID + "&since=session"); w.Code != http.StatusOK || len(alerts) != 1 || alerts[0].Message != "this boot" {
+		t.Fatalf("since=session: status %d, %+v", w.Code, alerts)
+	}
+	if w, _ := list("since=session"); w.Code != http.StatusBadRequest {
+		t.Fatalf("since=session without client_id: status %d", w.Code)
+	}
+	if w, _ := list("client_id=missing&since=session"); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown client: status %d", w.Code)
+	}
+}
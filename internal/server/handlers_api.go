@@ -1,7 +1,7 @@
 package server
 
 import (
-	"encoding/json"
+	"fmt"
 	"net"
 	"net/http"
 	"strings"
@@ -12,8 +12,7 @@ import (
 
 func (s *Server) handleCheckIn(w http.ResponseWriter, r *http.Request) {
 	var req models.CheckInRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	if !decodeClientBody(w, r, maxCheckInBodyBytes, &req) {
 		return
 	}
 
@@ -21,25 +20,62 @@ func (s *Server) handleCheckIn(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "hostname is required"})
 		return
 	}
+	if problems := validateCheckIn(&req); len(problems) > 0 {
+		s.logger.Warn("rejected invalid check-in", "hostname", req.Hostname, "problems", len(problems), "first", problems[0])
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":   "invalid check-in payload",
+			"details": problems,
+		})
+		return
+	}
+	if anomalies := checkInAnomalies(&req); len(anomalies) > 0 {
+		s.logger.Warn("clamping out-of-range check-in values", "hostname", req.Hostname, "client_id", req.ClientID,
+			"values", strings.Join(anomalies, ", "))
+	}
 
-	clientID, wasOffline, sessionChanged, err := s.store.UpsertClient(req, clientIPFromRequest(r))
+	upsert, err := s.store.UpsertClient(req, clientIPFromRequest(r))
 	if err != nil {
 		s.logger.Error("failed to upsert client", "err", err)
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
 		return
 	}
+	clientID := upsert.ClientID
+
+	if upsert.Created && req.Initial != nil {
+		s.applyInitialSettings(clientID, req.Initial)
+	}
 
-	if err := s.store.InsertMetrics(clientID, req.Metrics); err != nil {
-		s.logger.Error("failed to insert metrics", "client_id", clientID, "err", err)
+	if len(req.Errors) > 0 {
+		summary := summarizeCollectionErrors(req.Errors)
+		s.logger.Warn("client reported collection errors", "client_id", clientID, "hostname", req.Hostname, "errors", summary)
+		if err := s.store.SetClientCollectionError(clientID, summary); err != nil {
+			s.logger.Error("failed to store collection error", "client_id", clientID, "err", err)
+		}
+	}
+
+	// Skip data the client failed (or didn't try) to collect instead of
+	// storing zeros.
+	if !req.HeartbeatOnly && !collectionFailed(req.Errors, models.CollectionSourceMetrics) {
+		if err := s.store.InsertMetrics(clientID, req.Metrics); err != nil {
+			s.logger.Error("failed to insert metrics", "client_id", clientID, "err", err)
+		}
 	}
 
-	// Always sync watched processes so removed processes stop being monitored.
-	if err := s.store.UpsertWatchedProcesses(clientID, req.Processes); err != nil {
-		s.logger.Error("failed to upsert watched processes", "client_id", clientID, "err", err)
+	// Always sync disk mounts so mounts removed from the client config disappear.
+	if err := s.store.InsertDiskMounts(clientID, req.DiskMounts); err != nil {
+		s.logger.Error("failed to insert disk mounts", "client_id", clientID, "err", err)
 	}
-	if len(req.Processes) > 0 {
-		if err := s.store.InsertProcessSnapshots(clientID, req.Processes); err != nil {
-			s.logger.Error("failed to insert process snapshots", "client_id", clientID, "err", err)
+
+	// Always sync watched processes so removed processes stop being monitored,
+	// unless the client couldn't list processes this time.
+	if !collectionFailed(req.Errors, models.CollectionSourceProcesses) {
+		if err := s.store.UpsertWatchedProcesses(clientID, req.Processes); err != nil {
+			s.logger.Error("failed to upsert watched processes", "client_id", clientID, "err", err)
+		}
+		if len(req.Processes) > 0 {
+			if err := s.store.InsertProcessSnapshots(clientID, req.Processes); err != nil {
+				s.logger.Error("failed to insert process snapshots", "client_id", clientID, "err", err)
+			}
 		}
 	}
 
@@ -50,26 +86,199 @@ func (s *Server) handleCheckIn(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// If client was offline, mark it online and notify alert engine
-	if wasOffline {
+	if upsert.WasOffline {
 		s.logger.Info("client came back online", "client_id", clientID, "hostname", req.Hostname)
 	}
 
+	s.events.publish(Event{Type: "checkin", ClientID: clientID, Time: time.Now().UTC()})
+
 	// Notify alert engine
 	if s.alerts != nil {
 		s.alerts.NotifyCheckIn(clientID)
-		if sessionChanged {
+		if upsert.IdentityConflict {
+			s.logger.Warn("client identity conflict (possible duplicated client_id)", "client_id", clientID,
+				"previous_hostname", upsert.PreviousHostname, "hostname", req.Hostname)
+			s.alerts.NotifyIdentityConflict(clientID, upsert.PreviousHostname, req.Hostname)
+		} else if upsert.SessionChanged {
 			s.logger.Info("client session changed (restart detected)", "client_id", clientID, "hostname", req.Hostname)
 			s.alerts.NotifyRestart(clientID, req.Hostname)
 		}
 	}
 
+	var nextCheckIn int
+	if secs, err := s.store.GetClientCheckInInterval(clientID); err != nil {
+		s.logger.Error("failed to get check-in interval", "client_id", clientID, "err", err)
+	} else if secs != nil {
+		nextCheckIn = *secs
+	}
+
 	writeJSON(w, http.StatusOK, models.CheckInResponse{
 		ClientID:           clientID,
-		NextCheckInSeconds: 120,
+		NextCheckInSeconds: nextCheckIn,
 		ServerTime:         time.Now().UTC(),
 	})
 }
 
+// applyInitialSettings stores the name and thresholds a newly registered
+// client chose during setup. Failures are logged; the check-in still succeeds.
+func (s *Server) applyInitialSettings(clientID string, in *models.InitialClientSettings) {
+	if name := strings.TrimSpace(in.CustomName); name != "" {
+		if err := s.store.SetClientCustomName(clientID, name); err != nil {
+			s.logger.Error("failed to set initial client name", "client_id", clientID, "err", err)
+		}
+	}
+	if t := in.Thresholds; t != nil {
+		enabled := true
+		if err := s.store.SetClientThresholds(clientID, &models.Thresholds{
+			CPUWarnPct:              t.CPUWarnPct,
+			CPUCritPct:              t.CPUCritPct,
+			MemWarnPct:              t.MemWarnPct,
+			MemCritPct:              t.MemCritPct,
+			DiskWarnPct:             t.DiskWarnPct,
+			DiskCritPct:             t.DiskCritPct,
+			MetricThresholdsEnabled: &enabled,
+		}); err != nil {
+			s.logger.Error("failed to set initial client thresholds", "client_id", clientID, "err", err)
+		}
+	}
+	s.logger.Info("applied initial client settings", "client_id", clientID)
+}
+
+// handleCheckInBatch stores samples the client buffered while the server was
+// unreachable. Only metrics are recorded, at their original capture time; the
+// regular check-in that preceded the flush already updated client state and
+// alerts, so historical samples never trigger alerts.
+func (s *Server) handleCheckInBatch(w http.ResponseWriter, r *http.Request) {
+	var batch []models.CheckInRequest
+	if !decodeClientBody(w, r, maxCheckInBatchBodyBytes, &batch) {
+		return
+	}
+	if len(batch) > maxCheckInBatchSize {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": fmt.Sprintf("batch exceeds %d samples", maxCheckInBatchSize),
+		})
+		return
+	}
+
+	now := time.Now().UTC()
+	known := make(map[string]bool)
+	var result models.CheckInBatchResponse
+	for i := range batch {
+		req := &batch[i]
+		if req.ClientID == "" || req.CapturedAt <= 0 || len(validateCheckIn(req)) > 0 ||
+			collectionFailed(req.Errors, models.CollectionSourceMetrics) {
+			result.Rejected++
+			continue
+		}
+		captured := time.Unix(req.CapturedAt, 0).UTC()
+		if captured.After(now.Add(maxCapturedAtSkew)) || captured.Before(now.Add(-maxBufferedSampleAge)) {
+			result.Rejected++
+			continue
+		}
+
+		exists, checked := known[req.ClientID]
+		if !checked {
+			client, err := s.store.GetClient(req.ClientID)
+			if err != nil {
+				s.logger.Error("failed to get client", "id", req.ClientID, "err", err)
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+				return
+			}
+			exists = client != nil
+			known[req.ClientID] = exists
+		}
+		if !exists {
+			result.Rejected++
+			continue
+		}
+
+		if err := s.store.InsertMetricsAt(req.ClientID, captured, req.Metrics); err != nil {
+			s.logger.Error("failed to insert buffered metrics", "client_id", req.ClientID, "err", err)
+			result.Rejected++
+			continue
+		}
+		result.Accepted++
+	}
+
+	if result.Rejected > 0 {
+		s.logger.Warn("rejected buffered check-in samples", "accepted", result.Accepted, "rejected", result.Rejected)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// collectionFailed reports whether the client flagged source as uncollected.
+func collectionFailed(errs []models.CollectionError, source string) bool {
+	for _, e := range errs {
+		if e.Source == source {
+			return true
+		}
+	}
+	return false
+}
+
+// summarizeCollectionErrors joins reported errors into the single line stored
+// on the client, e.g. "metrics: disk usage /: permission denied".
+func summarizeCollectionErrors(errs []models.CollectionError) string {
+	parts := make([]string, 0, len(errs))
+	for _, e := range errs {
+		if e.Source == "" {
+			parts = append(parts, e.Message)
+			continue
+		}
+		parts = append(parts, e.Source+": "+e.Message)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// handleExternalCheck stores one check result for an existing client and
+// evaluates check alerts, so results from external systems alert exactly like
+// client-run checks. It does not count as a check-in from the client.
+func (s *Server) handleExternalCheck(w http.ResponseWriter, r *http.Request) {
+	var req models.ExternalCheckRequest
+	if !decodeClientBody(w, r, maxCheckInBodyBytes, &req) {
+		return
+	}
+	req.ClientID = strings.TrimSpace(req.ClientID)
+	if req.ClientID == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "client_id is required"})
+		return
+	}
+	if strings.TrimSpace(req.CheckType) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "check_type is required"})
+		return
+	}
+	if problems := validateCheckPayload("check", req.CheckPayload); len(problems) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":   "invalid check payload",
+			"details": problems,
+		})
+		return
+	}
+
+	client, err := s.store.GetClient(req.ClientID)
+	if err != nil {
+		s.logger.Error("failed to get client", "id", req.ClientID, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if client == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "client not found"})
+		return
+	}
+
+	if err := s.store.InsertCheckSnapshots(req.ClientID, []models.CheckPayload{req.CheckPayload}); err != nil {
+		s.logger.Error("failed to insert check snapshot", "client_id", req.ClientID, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+
+	s.events.publish(Event{Type: "check", ClientID: req.ClientID, Time: time.Now().UTC()})
+	if s.alerts != nil {
+		s.alerts.NotifyCheckResult(req.ClientID)
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "recorded"})
+}
+
 func clientIPFromRequest(r *http.Request) string {
 	raw := strings.TrimSpace(r.RemoteAddr)
 	if raw == "" {
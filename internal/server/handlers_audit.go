@@ -0,0 +1,59 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+// audit records a mutating admin action. Admin auth is a single shared
+// account, so the source IP is the only useful identifier. Failures are
+// logged and never fail the request.
+func (s *Server) audit(r *http.Request, action, target, detail string) {
+	e := &models.AuditEntry{Action: action, Target: target, Detail: detail, SourceIP: clientIPFromRequest(r)}
+	if err := s.store.InsertAuditEntry(e); err != nil {
+		s.logger.Error("failed to record audit entry", "action", action, "target", target, "err", err)
+	}
+}
+
+func (s *Server) handleListAudit(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	offset := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			limit = n
+		}
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			offset = n
+		}
+	}
+
+	entries, total, err := s.store.ListAuditEntries(limit, offset)
+	if err != nil {
+		s.logger.Error("failed to list audit entries", "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if entries == nil {
+		entries = []models.AuditEntry{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"entries": entries,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// auditDetail renders v as compact JSON for an audit entry's detail.
+func auditDetail(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+	"github.com/machinemon/machinemon/internal/store"
+)
+
+// commandRequest is the inbound ChatOps command schema. Client may be a client
+// ID, hostname, or custom name.
+//
+//	{"command": "mute", "client": "web-1", "duration_minutes": 60, "reason": "deploying"}
+//	{"command": "unmute", "client": "web-1"}
+//	{"command": "ack", "alert_id": 1234}
+type commandRequest struct {
+	Command         string `json:"command"`
+	Client          string `json:"client"`
+	AlertID         int64  `json:"alert_id"`
+	DurationMinutes int    `json:"duration_minutes"`
+	Reason          string `json:"reason"`
+}
+
+// commandWebhookAuth checks the shared X-Webhook-Token against its stored
+// hash. The endpoint is disabled (404) unless a command webhook token is set
+// in the server config.
+func (s *Server) commandWebhookAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		want := strings.ToLower(strings.TrimSpace(s.cfg.CommandWebhookTokenHash))
+		if want == "" {
+			http.NotFound(w, r)
+			return
+		}
+		token := r.Header.Get("X-Webhook-Token")
+		if token == "" {
+			http.Error(w, `{"error":"missing X-Webhook-Token header"}`, http.StatusUnauthorized)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(hashClientToken(token)), []byte(want)) != 1 {
+			http.Error(w, `{"error":"invalid token"}`, http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleCommand applies an inbound command and replies with a short message
+// suitable for echoing back into chat.
+func (s *Server) handleCommand(w http.ResponseWriter, r *http.Request) {
+	var req commandRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	command := strings.ToLower(strings.TrimSpace(req.Command))
+	if command == "ack" {
+		s.handleAckCommand(w, r, req)
+		return
+	}
+	if command != "mute" && command != "unmute" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "command must be mute, unmute, or ack"})
+		return
+	}
+	if req.DurationMinutes < 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "duration_minutes must be >= 0"})
+		return
+	}
+
+	client, err := s.resolveCommandClient(req.Client)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if client == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "client not found"})
+		return
+	}
+	label := client.Hostname
+	if client.CustomName != "" {
+		label = client.CustomName
+	}
+
+	var until *time.Time
+	message := fmt.Sprintf("Unmuted alerts for '%s'", label)
+	if command == "mute" {
+		message = fmt.Sprintf("Muted alerts for '%s' indefinitely", label)
+		if req.DurationMinutes > 0 {
+			t := time.Now().Add(time.Duration(req.DurationMinutes) * time.Minute)
+			until = &t
+			message = fmt.Sprintf("Muted alerts for '%s' for %d minutes", label, req.DurationMinutes)
+		}
+	}
+	if err := s.store.SetClientMute(client.ID, command == "mute", until, req.Reason); err != nil {
+		s.logger.Error("failed to set mute from command", "id", client.ID, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	s.logger.Info("applied inbound command", "command", command, "client_id", client.ID, "remote", r.RemoteAddr)
+	s.audit(r, "command."+command, client.ID, message)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "client_id": client.ID, "message": message})
+}
+
+func (s *Server) handleAckCommand(w http.ResponseWriter, r *http.Request, req commandRequest) {
+	if req.AlertID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "alert_id is required for ack"})
+		return
+	}
+	found, err := s.store.AckAlert(req.AlertID)
+	if err != nil {
+		s.logger.Error("failed to ack alert from command", "id", req.AlertID, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if !found {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "alert not found"})
+		return
+	}
+	s.logger.Info("applied inbound command", "command", "ack", "alert_id", req.AlertID, "remote", r.RemoteAddr)
+	s.audit(r, "command.ack", strconv.FormatInt(req.AlertID, 10), "")
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "message": fmt.Sprintf("Acknowledged alert #%d", req.AlertID)})
+}
+
+// resolveCommandClient finds a client by ID, then by hostname or custom name
+// (case-insensitive). Ambiguous names are rejected rather than guessed.
+func (s *Server) resolveCommandClient(ref string) (*models.Client, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil, fmt.Errorf("client is required")
+	}
+	if client, err := s.store.GetClient(ref); err == nil && client != nil && !client.IsDeleted {
+		return client, nil
+	}
+	clients, _, err := s.store.ListClients(store.ClientFilter{}, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clients")
+	}
+	var match *models.Client
+	for i := range clients {
+		c := &clients[i].Client
+		if strings.EqualFold(c.Hostname, ref) || (c.CustomName != "" && strings.EqualFold(c.CustomName, ref)) {
+			if match != nil {
+				return nil, fmt.Errorf("client %q is ambiguous; use the client ID", ref)
+			}
+			match = c
+		}
+	}
+	return match, nil
+}
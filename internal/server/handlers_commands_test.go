@@ -0,0 +1,94 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestCommandWebhook(t *testing.T) {
+	s, st := newTestServer(t)
+	cfg := &Config{CommandWebhookToken: "s3cret"}
+	if !cfg.hashPlaintextTokens() || cfg.CommandWebhookToken != "" || cfg.CommandWebhookTokenHash != hashClientToken("s3cret") {
+		t.Fatalf("expected the token to be replaced by its hash, got %+v", cfg)
+	}
+	s.cfg = cfg
+	h := s.commandWebhookAuth(http.HandlerFunc(s.handleCommand))
+
+	var ids []string
+	for _, host := range []string{"web-1", "web-2", "db-1"} {
+		res, err := st.UpsertClient(models.CheckInRequest{Hostname: host, SessionID: "boot-a"}, "")
+		if err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+		ids = append(ids, res.ClientID)
+	}
+	if err := st.SetClientCustomName(ids[1], "WEB-1"); err != nil {
+		t.Fatal(err)
+	}
+	post := func(token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/commands", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("X-Webhook-Token", token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	client := func(id string) *models.Client {
+		c, err := st.GetClient(id)
+		if err != nil || c == nil {
+			t.Fatalf("get client: %v", err)
+		}
+		return c
+	}
+
+	if w := post("", `{"command":"mute","client":"db-1"}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("missing token: got %d", w.Code)
+	}
+	if w := post("wrong", `{"command":"mute","client":"db-1"}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong token: got %d", w.Code)
+	}
+
+	if w := post("s3cret", `{"command":"mute","client":"db-1","reason":"deploying"}`); w.Code != http.StatusOK {
+		t.Fatalf("mute: got %d %s", w.Code, w.Body.String())
+	}
+	if c := client(ids[2]); !c.AlertsMuted || c.MutedUntil != nil || c.MuteReason != "deploying" {
+		t.Fatalf("expected db-1 muted indefinitely, got muted=%v until=%v reason=%q", c.AlertsMuted, c.MutedUntil, c.MuteReason)
+	}
+
+	if w := post("s3cret", `{"command":"unmute","client":"DB-1"}`); w.Code != http.StatusOK {
+		t.Fatalf("unmute: got %d %s", w.Code, w.Body.String())
+	}
+	if client(ids[2]).AlertsMuted {
+		t.Fatal("expected db-1 unmuted")
+	}
+
+	before := time.Now()
+	w := post("s3cret", `{"command":"mute","client":"`+ids[0]+`","duration_minutes":30}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "for 30 minutes") {
+		t.Fatalf("timed mute: got %d %s", w.Code, w.Body.String())
+	}
+	c := client(ids[0])
+	if !c.AlertsMuted || c.MutedUntil == nil || c.MutedUntil.Before(before.Add(29*time.Minute)) || c.MutedUntil.After(time.Now().Add(31*time.Minute)) {
+		t.Fatalf("expected web-1 muted for 30 minutes, got muted=%v until=%v", c.AlertsMuted, c.MutedUntil)
+	}
+
+	// web-1 is one client's hostname and the other's custom name.
+	w = post("s3cret", `{"command":"mute","client":"web-1"}`)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "ambiguous") {
+		t.Fatalf("ambiguous name: got %d %s", w.Code, w.Body.String())
+	}
+	if client(ids[1]).AlertsMuted {
+		t.Fatal("an ambiguous command must not mute anything")
+	}
+
+	cfg.CommandWebhookTokenHash = ""
+	if w := post("s3cret", `{"command":"mute","client":"db-1"}`); w.Code != http.StatusNotFound {
+		t.Fatalf("disabled webhook: got %d", w.Code)
+	}
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// handleHealthz reports whether this instance can serve requests. It is
+// unauthenticated, so the failure body names the component but not the error.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if err := s.store.Ping(); err != nil {
+		s.logger.Error("health check failed: database unavailable", "err", err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{
+			"status": "error",
+			"error":  "database unavailable",
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleDatabaseStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.store.DatabaseStats()
+	if err != nil {
+		s.logger.Error("failed to get database stats", "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// handleVacuumDatabase reclaims free space on demand and returns the stats
+// afterwards. It can take a while on a large SQLite file.
+func (s *Server) handleVacuumDatabase(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	if err := s.store.Vacuum(); err != nil {
+		s.logger.Error("failed to vacuum database", "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	elapsed := time.Since(start).Round(time.Millisecond)
+	s.logger.Info("vacuumed database", "duration", elapsed)
+	s.audit(r, "database.vacuum", "", fmt.Sprintf("duration=%s", elapsed))
+	s.handleDatabaseStats(w, r)
+}
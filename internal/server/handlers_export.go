@@ -0,0 +1,148 @@
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+var metricsCSVHeader = []string{
+	"recorded_at", "cpu_pct", "mem_pct", "disk_pct",
+	"mem_total_bytes", "mem_used_bytes", "disk_total_bytes", "disk_used_bytes",
+	"net_rx_bytes", "net_tx_bytes", "net_rx_bytes_per_sec", "net_tx_bytes_per_sec",
+	"swap_pct", "swap_total_bytes", "swap_used_bytes", "cpu_temp_c",
+	"open_fds", "process_count", "disk_inodes_pct",
+}
+
+// handleExportMetrics streams a client's metric history as CSV or a JSON
+// array. Rows are written as they are read so long ranges aren't buffered.
+func (s *Server) handleExportMetrics(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "json" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "format must be csv or json"})
+		return
+	}
+
+	to := time.Now().UTC()
+	from := to.Add(-24 * time.Hour)
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "from must be an RFC 3339 timestamp"})
+			return
+		}
+		from = t
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "to must be an RFC 3339 timestamp"})
+			return
+		}
+		to = t
+	}
+	if to.Before(from) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "to must not be before from"})
+		return
+	}
+
+	client, err := s.store.GetClient(id)
+	if err != nil {
+		s.logger.Error("failed to get client", "id", id, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if client == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "client not found"})
+		return
+	}
+
+	filename := fmt.Sprintf("machinemon-%s-metrics-%s-%s.%s",
+		id, from.UTC().Format("20060102T150405Z"), to.UTC().Format("20060102T150405Z"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if format == "csv" {
+		err = s.streamMetricsCSV(w, id, from, to)
+	} else {
+		err = s.streamMetricsJSON(w, id, from, to)
+	}
+	if err != nil {
+		// Headers are already sent; all we can do is log and stop.
+		s.logger.Error("failed to export metrics", "id", id, "format", format, "err", err)
+	}
+}
+
+func (s *Server) streamMetricsCSV(w http.ResponseWriter, id string, from, to time.Time) error {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	cw := csv.NewWriter(w)
+	if err := cw.Write(metricsCSVHeader); err != nil {
+		return err
+	}
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	u := func(v uint64) string { return strconv.FormatUint(v, 10) }
+	opt := func(v *float64) string {
+		if v == nil {
+			return ""
+		}
+		return f(*v)
+	}
+	optInt := func(v *int64) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatInt(*v, 10)
+	}
+	err := s.store.ForEachMetric(id, from, to, func(m models.Metric) error {
+		return cw.Write([]string{
+			m.RecordedAt.UTC().Format(time.RFC3339),
+			f(m.CPUPercent), f(m.MemPercent), f(m.DiskPercent),
+			u(m.MemTotalBytes), u(m.MemUsedBytes), u(m.DiskTotalBytes), u(m.DiskUsedBytes),
+			u(m.NetRxBytes), u(m.NetTxBytes), f(m.NetRxBytesPerSec), f(m.NetTxBytesPerSec),
+			f(m.SwapPercent), u(m.SwapTotalBytes), u(m.SwapUsedBytes), opt(m.CPUTempC),
+			optInt(m.OpenFDs), optInt(m.ProcessCount), opt(m.DiskInodesUsedPercent),
+		})
+	})
+	cw.Flush()
+	if err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+func (s *Server) streamMetricsJSON(w http.ResponseWriter, id string, from, to time.Time) error {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write([]byte("[")); err != nil {
+		return err
+	}
+	first := true
+	err := s.store.ForEachMetric(id, from, to, func(m models.Metric) error {
+		data, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		if !first {
+			if _, err := w.Write([]byte(",\n")); err != nil {
+				return err
+			}
+		}
+		first = false
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write([]byte("]\n"))
+	return err
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// SetLogLevelVar lets admins change the server's log level at runtime through
+// /api/v1/admin/loglevel. Without it the endpoint reports 501.
+func (s *Server) SetLogLevelVar(lv *slog.LevelVar) {
+	s.logLevel = lv
+}
+
+type logLevelRequest struct {
+	Level string `json:"level"`
+}
+
+func (s *Server) handleGetLogLevel(w http.ResponseWriter, r *http.Request) {
+	if s.logLevel == nil {
+		writeJSON(w, http.StatusNotImplemented, map[string]string{"error": "log level is not adjustable"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"level": strings.ToLower(s.logLevel.Level().String())})
+}
+
+// handleSetLogLevel sets the level to debug, info, warn, or error. The change
+// lasts until the server restarts.
+func (s *Server) handleSetLogLevel(w http.ResponseWriter, r *http.Request) {
+	if s.logLevel == nil {
+		writeJSON(w, http.StatusNotImplemented, map[string]string{"error": "log level is not adjustable"})
+		return
+	}
+	var req logLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	var level slog.Level
+	switch strings.ToLower(strings.TrimSpace(req.Level)) {
+	case "debug":
+		level = slog.LevelDebug
+	case "info":
+		level = slog.LevelInfo
+	case "warn", "warning":
+		level = slog.LevelWarn
+	case "error":
+		level = slog.LevelError
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "level must be debug, info, warn, or error"})
+		return
+	}
+
+	previous := s.logLevel.Level()
+	s.logLevel.Set(level)
+	// Logged at warn so the change is visible at any level.
+	s.logger.Warn("log level changed", "from", previous, "to", level)
+	name := strings.ToLower(level.String())
+	s.audit(r, "server.loglevel", "", name)
+	writeJSON(w, http.StatusOK, map[string]string{"level": name})
+}
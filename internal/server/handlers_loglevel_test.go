@@ -0,0 +1,37 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSetLogLevel(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.handleSetLogLevel(w, httptest.NewRequest(http.MethodPut, "/api/v1/admin/loglevel", strings.NewReader(body)))
+		return w
+	}
+	if w := put(`{"level":"debug"}`); w.Code != http.StatusNotImplemented {
+		t.Fatalf("without a LevelVar: got %d", w.Code)
+	}
+
+	lv := new(slog.LevelVar)
+	s.SetLogLevelVar(lv)
+	if w := put(`{"level":"DEBUG"}`); w.Code != http.StatusOK || lv.Level() != slog.LevelDebug {
+		t.Fatalf("set debug: got %d %s, level %s", w.Code, w.Body.String(), lv.Level())
+	}
+	if w := put(`{"level":"verbose"}`); w.Code != http.StatusBadRequest || lv.Level() != slog.LevelDebug {
+		t.Fatalf("invalid level: got %d, level %s", w.Code, lv.Level())
+	}
+
+	w := httptest.NewRecorder()
+	s.handleGetLogLevel(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/loglevel", nil))
+	if !strings.Contains(w.Body.String(), `"debug"`) {
+		t.Fatalf("get level: %s", w.Body.String())
+	}
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+// maxMaintenanceWindow caps a single window so a typo can't silence a client
+// for years.
+const maxMaintenanceWindow = 30 * 24 * time.Hour
+
+// maintenanceWindowRequest schedules a window. starts_at defaults to now; the
+// end is ends_at or starts_at + duration_minutes.
+type maintenanceWindowRequest struct {
+	StartsAt        *time.Time `json:"starts_at"`
+	EndsAt          *time.Time `json:"ends_at"`
+	DurationMinutes int        `json:"duration_minutes"`
+	Reason          string     `json:"reason"`
+}
+
+func (s *Server) handleListMaintenanceWindows(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	client, err := s.store.GetClient(id)
+	if err != nil {
+		s.logger.Error("failed to get client", "id", id, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if client == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "client not found"})
+		return
+	}
+	windows, err := s.store.ListMaintenanceWindows(id)
+	if err != nil {
+		s.logger.Error("failed to list maintenance windows", "id", id, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if windows == nil {
+		windows = []models.MaintenanceWindow{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"maintenance_windows": windows})
+}
+
+func (s *Server) handleCreateMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req maintenanceWindowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	now := time.Now().UTC()
+	startsAt := now
+	if req.StartsAt != nil {
+		startsAt = req.StartsAt.UTC()
+	}
+	var endsAt time.Time
+	switch {
+	case req.EndsAt != nil && req.DurationMinutes != 0:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "set ends_at or duration_minutes, not both"})
+		return
+	case req.EndsAt != nil:
+		endsAt = req.EndsAt.UTC()
+	case req.DurationMinutes > 0:
+		endsAt = startsAt.Add(time.Duration(req.DurationMinutes) * time.Minute)
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "ends_at or a positive duration_minutes is required"})
+		return
+	}
+	if !endsAt.After(startsAt) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "window must end after it starts"})
+		return
+	}
+	if !endsAt.After(now) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "window has already ended"})
+		return
+	}
+	if endsAt.Sub(startsAt) > maxMaintenanceWindow {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "window may be at most 30 days long"})
+		return
+	}
+
+	client, err := s.store.GetClient(id)
+	if err != nil {
+		s.logger.Error("failed to get client", "id", id, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if client == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "client not found"})
+		return
+	}
+	window := &models.MaintenanceWindow{ClientID: id, StartsAt: startsAt, EndsAt: endsAt, Reason: req.Reason}
+	if err := s.store.CreateMaintenanceWindow(window); err != nil {
+		s.logger.Error("failed to create maintenance window", "id", id, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	s.audit(r, "client.maintenance.create", id, auditDetail(window))
+	writeJSON(w, http.StatusCreated, window)
+}
+
+func (s *Server) handleDeleteMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	windowID, err := strconv.ParseInt(chi.URLParam(r, "windowID"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid maintenance window id"})
+		return
+	}
+	found, err := s.store.DeleteMaintenanceWindow(id, windowID)
+	if err != nil {
+		s.logger.Error("failed to delete maintenance window", "id", id, "window_id", windowID, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if !found {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "maintenance window not found"})
+		return
+	}
+	s.audit(r, "client.maintenance.delete", id, strconv.FormatInt(windowID, 10))
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
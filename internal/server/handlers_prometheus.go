@@ -0,0 +1,162 @@
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/machinemon/machinemon/internal/models"
+	"github.com/machinemon/machinemon/internal/store"
+)
+
+// metricsAuth accepts either "Authorization: Bearer <metrics_token>" (when
+// metrics_token_hash is configured) or the admin credentials (Basic Auth or
+// an API token), so a Prometheus scrape config doesn't need the admin password.
+func (s *Server) metricsAuth(next http.Handler) http.Handler {
+	basic := s.adminBasicAuth(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if want := strings.ToLower(strings.TrimSpace(s.cfg.MetricsTokenHash)); want != "" {
+			// Any other bearer token is checked as an admin API token.
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if ok && subtle.ConstantTimeCompare([]byte(hashClientToken(token)), []byte(want)) == 1 {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		basic.ServeHTTP(w, r)
+	})
+}
+
+type promGauge struct {
+	name  string
+	help  string
+	kind  string // "gauge" or "counter"
+	value func(c *models.ClientWithMetrics) (float64, bool)
+}
+
+// promClientMetrics lists the per-client series exposed on /metrics. Series
+// read from the latest sample are omitted for clients that have none.
+var promClientMetrics = []promGauge{
+	{"machinemon_client_up", "Whether the client is currently checking in (1) or offline (0).", "gauge",
+		func(c *models.ClientWithMetrics) (float64, bool) { return boolFloat(c.IsOnline), true }},
+	{"machinemon_client_last_seen_timestamp_seconds", "Unix time of the client's last check-in.", "gauge",
+		func(c *models.ClientWithMetrics) (float64, bool) { return float64(c.LastSeenAt.Unix()), true }},
+	{"machinemon_client_alerts_muted", "Whether alerts for the client are muted.", "gauge",
+		func(c *models.ClientWithMetrics) (float64, bool) { return boolFloat(c.AlertsMuted), true }},
+	{"machinemon_client_suspended", "Whether the client is suspended (no alerts of any kind).", "gauge",
+		func(c *models.ClientWithMetrics) (float64, bool) { return boolFloat(c.Suspended), true }},
+	{"machinemon_client_cpu_percent", "CPU usage percent from the latest check-in.", "gauge",
+		latestMetric(func(m *models.Metric) float64 { return m.CPUPercent })},
+	{"machinemon_client_memory_percent", "Memory usage percent from the latest check-in.", "gauge",
+		latestMetric(func(m *models.Metric) float64 { return m.MemPercent })},
+	{"machinemon_client_memory_used_bytes", "Memory used in bytes from the latest check-in.", "gauge",
+		latestMetric(func(m *models.Metric) float64 { return float64(m.MemUsedBytes) })},
+	{"machinemon_client_memory_total_bytes", "Total memory in bytes.", "gauge",
+		latestMetric(func(m *models.Metric) float64 { return float64(m.MemTotalBytes) })},
+	{"machinemon_client_swap_percent", "Swap usage percent from the latest check-in (0 without swap).", "gauge",
+		latestMetric(func(m *models.Metric) float64 { return m.SwapPercent })},
+	{"machinemon_client_swap_used_bytes", "Swap used in bytes from the latest check-in.", "gauge",
+		latestMetric(func(m *models.Metric) float64 { return float64(m.SwapUsedBytes) })},
+	{"machinemon_client_swap_total_bytes", "Total swap in bytes.", "gauge",
+		latestMetric(func(m *models.Metric) float64 { return float64(m.SwapTotalBytes) })},
+	{"machinemon_client_cpu_temp_celsius", "CPU temperature from the latest check-in; omitted when the host has no sensor.", "gauge",
+		func(c *models.ClientWithMetrics) (float64, bool) {
+			if c.LatestMetrics == nil || c.LatestMetrics.CPUTempC == nil {
+				return 0, false
+			}
+			return *c.LatestMetrics.CPUTempC, true
+		}},
+	{"machinemon_client_open_fds", "Open file handles system-wide from the latest check-in; omitted where the platform doesn't report it.", "gauge",
+		func(c *models.ClientWithMetrics) (float64, bool) {
+			if c.LatestMetrics == nil || c.LatestMetrics.OpenFDs == nil {
+				return 0, false
+			}
+			return float64(*c.LatestMetrics.OpenFDs), true
+		}},
+	{"machinemon_client_processes", "Running processes from the latest check-in.", "gauge",
+		func(c *models.ClientWithMetrics) (float64, bool) {
+			if c.LatestMetrics == nil || c.LatestMetrics.ProcessCount == nil {
+				return 0, false
+			}
+			return float64(*c.LatestMetrics.ProcessCount), true
+		}},
+	{"machinemon_client_disk_percent", "Root disk usage percent from the latest check-in.", "gauge",
+		latestMetric(func(m *models.Metric) float64 { return m.DiskPercent })},
+	{"machinemon_client_disk_used_bytes", "Root disk used in bytes from the latest check-in.", "gauge",
+		latestMetric(func(m *models.Metric) float64 { return float64(m.DiskUsedBytes) })},
+	{"machinemon_client_disk_total_bytes", "Root disk size in bytes.", "gauge",
+		latestMetric(func(m *models.Metric) float64 { return float64(m.DiskTotalBytes) })},
+	{"machinemon_client_disk_inodes_percent", "Root disk inode usage percent from the latest check-in; omitted where the filesystem doesn't report inodes.", "gauge",
+		func(c *models.ClientWithMetrics) (float64, bool) {
+			if c.LatestMetrics == nil || c.LatestMetrics.DiskInodesUsedPercent == nil {
+				return 0, false
+			}
+			return *c.LatestMetrics.DiskInodesUsedPercent, true
+		}},
+	{"machinemon_client_network_receive_bytes_total", "Bytes received on non-loopback interfaces since boot.", "counter",
+		latestMetric(func(m *models.Metric) float64 { return float64(m.NetRxBytes) })},
+	{"machinemon_client_network_transmit_bytes_total", "Bytes sent on non-loopback interfaces since boot.", "counter",
+		latestMetric(func(m *models.Metric) float64 { return float64(m.NetTxBytes) })},
+}
+
+func latestMetric(get func(m *models.Metric) float64) func(c *models.ClientWithMetrics) (float64, bool) {
+	return func(c *models.ClientWithMetrics) (float64, bool) {
+		if c.LatestMetrics == nil {
+			return 0, false
+		}
+		return get(c.LatestMetrics), true
+	}
+}
+
+func boolFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// handlePrometheusMetrics exposes the latest stored metrics for every client
+// in the Prometheus text exposition format.
+func (s *Server) handlePrometheusMetrics(w http.ResponseWriter, r *http.Request) {
+	clients, _, err := s.store.ListClients(store.ClientFilter{}, 0, 0)
+	if err != nil {
+		s.logger.Error("failed to list clients", "err", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writePrometheusMetrics(w, clients)
+	if stats, err := s.serverStats(); err != nil {
+		s.logger.Error("failed to get server stats for metrics", "err", err)
+	} else {
+		writeServerMetrics(w, stats)
+	}
+}
+
+func writePrometheusMetrics(w io.Writer, clients []models.ClientWithMetrics) {
+	for _, g := range promClientMetrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", g.name, g.help, g.name, g.kind)
+		for i := range clients {
+			c := &clients[i]
+			v, ok := g.value(c)
+			if !ok {
+				continue
+			}
+			name := c.CustomName
+			if name == "" {
+				name = c.Hostname
+			}
+			fmt.Fprintf(w, "%s{client_id=\"%s\",hostname=\"%s\",name=\"%s\"} %g\n",
+				g.name, promLabelValue(c.ID), promLabelValue(c.Hostname), promLabelValue(name), v)
+		}
+	}
+}
+
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func promLabelValue(v string) string {
+	return promLabelEscaper.Replace(v)
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestWritePrometheusMetrics(t *testing.T) {
+	clients := []models.ClientWithMetrics{
+		{
+			Client:        models.Client{ID: "abc", Hostname: "web-1", CustomName: `API "prod"`, IsOnline: true},
+			LatestMetrics: &models.Metric{CPUPercent: 12.5, MemPercent: 40, DiskPercent: 70},
+		},
+		{Client: models.Client{ID: "def", Hostname: "db-1"}},
+	}
+	var b strings.Builder
+	writePrometheusMetrics(&b, clients)
+	out := b.String()
+
+	for _, want := range []string{
+		"# TYPE machinemon_client_up gauge\n",
+		`machinemon_client_up{client_id="abc",hostname="web-1",name="API \"prod\""} 1` + "\n",
+		`machinemon_client_up{client_id="def",hostname="db-1",name="db-1"} 0` + "\n",
+		`machinemon_client_cpu_percent{client_id="abc",hostname="web-1",name="API \"prod\""} 12.5` + "\n",
+		"# TYPE machinemon_client_network_receive_bytes_total counter\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `machinemon_client_cpu_percent{client_id="def"`) {
+		t.Fatalf("client without metrics should have no cpu series:\n%s", out)
+	}
+}
+
+func TestMetricsAuthComparesTokenHash(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.cfg = &Config{MetricsToken: "scrape"}
+	if !s.cfg.hashPlaintextTokens() || s.cfg.MetricsToken != "" || s.cfg.MetricsTokenHash != hashClientToken("scrape") {
+		t.Fatalf("expected the token to be replaced by its hash, got %+v", s.cfg)
+	}
+	s.authLockout = newAuthLockout(3, time.Minute)
+	h := s.metricsAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	scrape := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := scrape("scrape"); code != http.StatusOK {
+		t.Fatalf("metrics token: got %d", code)
+	}
+	if code := scrape(s.cfg.MetricsTokenHash); code != http.StatusUnauthorized {
+		t.Fatalf("the stored hash itself must not authenticate: got %d", code)
+	}
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/alerting"
+	"github.com/machinemon/machinemon/internal/models"
+	"github.com/machinemon/machinemon/internal/store"
+	"github.com/machinemon/machinemon/internal/version"
+)
+
+// serverStats gathers the counts behind /admin/stats. Each is a single
+// aggregate query, so it is cheap enough to poll.
+func (s *Server) serverStats() (*models.ServerStats, error) {
+	clients, err := s.store.CountClients()
+	if err != nil {
+		return nil, err
+	}
+	alerts, err := s.store.CountAlertsBySeverity(time.Now().Add(-24 * time.Hour))
+	if err != nil {
+		return nil, fmt.Errorf("count alerts: %w", err)
+	}
+	// Report the standard severities even when none fired.
+	for _, sev := range []string{models.SeverityCritical, models.SeverityWarning, models.SeverityInfo} {
+		if _, ok := alerts[sev]; !ok {
+			alerts[sev] = 0
+		}
+	}
+	size, err := s.store.DatabaseSize()
+	if err != nil {
+		return nil, err
+	}
+	driver := store.DriverSQLite
+	if !(store.Config{Driver: s.cfg.DatabaseDriver}).IsSQLite() {
+		driver = store.DriverPostgres
+	}
+	return &models.ServerStats{
+		Version:           version.Version,
+		StartedAt:         s.startedAt,
+		UptimeSeconds:     int64(time.Since(s.startedAt).Seconds()),
+		Clients:           *clients,
+		Alerts24h:         alerts,
+		DatabaseDriver:    driver,
+		DatabaseSizeBytes: size,
+		AlertDryRun:       alerting.DryRun(s.store),
+	}, nil
+}
+
+func (s *Server) handleServerStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.serverStats()
+	if err != nil {
+		s.logger.Error("failed to get server stats", "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// writeServerMetrics appends the server-level series to a /metrics scrape.
+func writeServerMetrics(w io.Writer, stats *models.ServerStats) {
+	fmt.Fprintf(w, "# HELP machinemon_server_start_time_seconds Unix time the server started.\n# TYPE machinemon_server_start_time_seconds gauge\n")
+	fmt.Fprintf(w, "machinemon_server_start_time_seconds %d\n", stats.StartedAt.Unix())
+	fmt.Fprintf(w, "# HELP machinemon_database_size_bytes Size of the database in bytes.\n# TYPE machinemon_database_size_bytes gauge\n")
+	fmt.Fprintf(w, "machinemon_database_size_bytes %d\n", stats.DatabaseSizeBytes)
+	fmt.Fprintf(w, "# HELP machinemon_alerts_24h Alerts fired in the last 24 hours.\n# TYPE machinemon_alerts_24h gauge\n")
+	for _, sev := range slices.Sorted(maps.Keys(stats.Alerts24h)) {
+		fmt.Fprintf(w, "machinemon_alerts_24h{severity=\"%s\"} %d\n", promLabelValue(sev), stats.Alerts24h[sev])
+	}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+	"github.com/machinemon/machinemon/internal/store"
+)
+
+func TestServerStats(t *testing.T) {
+	s, st := newTestServer(t)
+	s.cfg = DefaultServerConfig()
+	s.startedAt = time.Now().Add(-time.Hour)
+
+	var ids []string
+	for _, host := range []string{"web-1", "web-2", "db-1"} {
+		res, err := st.UpsertClient(models.CheckInRequest{Hostname: host, SessionID: "boot-a"}, "")
+		if err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+		ids = append(ids, res.ClientID)
+	}
+	if err := st.SetClientOnline(ids[2], false); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SetClientMute(ids[0], true, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, sev := range []string{models.SeverityCritical, models.SeverityWarning, models.SeverityWarning} {
+		if err := st.InsertAlert(&models.Alert{ClientID: ids[0], AlertType: "cpu_warn", Severity: sev, Message: "x"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	s.handleServerStats(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/stats", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	var got models.ServerStats
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := models.ClientCounts{Total: 3, Online: 2, Offline: 1, Muted: 1}
+	if got.Clients != want {
+		t.Errorf("clients = %+v, want %+v", got.Clients, want)
+	}
+	if got.Alerts24h[models.SeverityCritical] != 1 || got.Alerts24h[models.SeverityWarning] != 2 {
+		t.Errorf("alerts_24h = %v", got.Alerts24h)
+	}
+	if v, ok := got.Alerts24h[models.SeverityInfo]; !ok || v != 0 {
+		t.Errorf("expected info severity reported as 0, got %v", got.Alerts24h)
+	}
+	if got.UptimeSeconds < 3600 || got.DatabaseSizeBytes <= 0 || got.DatabaseDriver != store.DriverSQLite {
+		t.Errorf("unexpected stats %+v", got)
+	}
+
+	var b strings.Builder
+	writeServerMetrics(&b, &got)
+	if !strings.Contains(b.String(), `machinemon_alerts_24h{severity="warning"} 2`+"\n") {
+		t.Errorf("metrics output missing alert series:\n%s", b.String())
+	}
+}
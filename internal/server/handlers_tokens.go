@@ -0,0 +1,151 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+type createClientTokenRequest struct {
+	Label string `json:"label"`
+}
+
+func (s *Server) handleListClientTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := s.store.ListClientTokens()
+	if err != nil {
+		s.logger.Error("failed to list client tokens", "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if tokens == nil {
+		tokens = []models.ClientToken{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tokens": tokens})
+}
+
+// handleCreateClientToken mints a token. The plaintext is returned only in
+// this response; the server keeps just its hash.
+func (s *Server) handleCreateClientToken(w http.ResponseWriter, r *http.Request) {
+	var req createClientTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	label := strings.TrimSpace(req.Label)
+	if len(label) > 120 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "label too long (max 120 chars)"})
+		return
+	}
+
+	token, hash, err := newClientToken()
+	if err != nil {
+		s.logger.Error("failed to generate client token", "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	ct, err := s.store.CreateClientToken(hash, label)
+	if err != nil {
+		s.logger.Error("failed to create client token", "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	s.audit(r, "client_token.create", strconv.FormatInt(ct.ID, 10), ct.Label)
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"id":         ct.ID,
+		"label":      ct.Label,
+		"created_at": ct.CreatedAt,
+		"token":      token,
+	})
+}
+
+func (s *Server) handleRevokeClientToken(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid token id"})
+		return
+	}
+	found, err := s.store.RevokeClientToken(id)
+	if err != nil {
+		s.logger.Error("failed to revoke client token", "id", id, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if !found {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "token not found or already revoked"})
+		return
+	}
+	s.audit(r, "client_token.revoke", strconv.FormatInt(id, 10), "")
+	writeJSON(w, http.StatusOK, map[string]string{"status": "revoked"})
+}
+
+func (s *Server) handleListAPITokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := s.store.ListAPITokens()
+	if err != nil {
+		s.logger.Error("failed to list api tokens", "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if tokens == nil {
+		tokens = []models.APIToken{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tokens": tokens})
+}
+
+// handleCreateAPIToken mints an admin API token. As with client tokens, the
+// plaintext is returned only in this response.
+func (s *Server) handleCreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	var req createClientTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	label := strings.TrimSpace(req.Label)
+	if len(label) > 120 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "label too long (max 120 chars)"})
+		return
+	}
+
+	token, hash, err := newAPIToken()
+	if err != nil {
+		s.logger.Error("failed to generate api token", "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	at, err := s.store.CreateAPIToken(hash, label)
+	if err != nil {
+		s.logger.Error("failed to create api token", "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	s.audit(r, "api_token.create", strconv.FormatInt(at.ID, 10), at.Label)
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"id":         at.ID,
+		"label":      at.Label,
+		"created_at": at.CreatedAt,
+		"token":      token,
+	})
+}
+
+func (s *Server) handleRevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid token id"})
+		return
+	}
+	found, err := s.store.RevokeAPIToken(id)
+	if err != nil {
+		s.logger.Error("failed to revoke api token", "id", id, "err", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if !found {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "token not found or already revoked"})
+		return
+	}
+	s.audit(r, "api_token.revoke", strconv.FormatInt(id, 10), "")
+	writeJSON(w, http.StatusOK, map[string]string{"status": "revoked"})
+}
@@ -0,0 +1,25 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHealthzReflectsDatabase(t *testing.T) {
+	s, st := newTestServer(t)
+
+	w := httptest.NewRecorder()
+	s.handleHealthz(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"ok"`) {
+		t.Fatalf("healthy store: got %d %s", w.Code, w.Body.String())
+	}
+
+	st.Close()
+	w = httptest.NewRecorder()
+	s.handleHealthz(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "database unavailable") {
+		t.Fatalf("closed store: got %d %s", w.Code, w.Body.String())
+	}
+}
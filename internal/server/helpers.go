@@ -1,8 +1,10 @@
 package server
 
 import (
+	"compress/gzip"
 	"encoding/json"
 	"net/http"
+	"strings"
 )
 
 func writeJSON(w http.ResponseWriter, status int, v interface{}) {
@@ -10,3 +12,30 @@ func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(v)
 }
+
+// decodeClientBody decodes a JSON body sent by a client, which may be
+// gzip-compressed (Content-Encoding: gzip). limit caps both the bytes read from
+// the wire and the decompressed size. On failure it writes the error response
+// and returns false.
+func decodeClientBody(w http.ResponseWriter, r *http.Request, limit int64, v interface{}) bool {
+	body := http.MaxBytesReader(w, r.Body, limit)
+	switch enc := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); enc {
+	case "", "identity":
+	case "gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+			return false
+		}
+		defer gz.Close()
+		body = http.MaxBytesReader(w, gz, limit)
+	default:
+		writeJSON(w, http.StatusUnsupportedMediaType, map[string]string{"error": "unsupported content encoding: " + enc})
+		return false
+	}
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func gzipBytes(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeClientBody(t *testing.T) {
+	type payload struct {
+		Hostname string `json:"hostname"`
+	}
+	cases := []struct {
+		name       string
+		encoding   string
+		body       []byte
+		limit      int64
+		wantOK     bool
+		wantStatus int
+	}{
+		{"plain", "", []byte(`{"hostname":"web-1"}`), 1024, true, 0},
+		{"gzip", "gzip", gzipBytes(t, `{"hostname":"web-1"}`), 1024, true, 0},
+		{"gzip mislabeled", "gzip", []byte(`{"hostname":"web-1"}`), 1024, false, http.StatusBadRequest},
+		{"unsupported", "br", []byte(`{"hostname":"web-1"}`), 1024, false, http.StatusUnsupportedMediaType},
+		{"decompressed over limit", "gzip", gzipBytes(t, `{"hostname":"`+strings.Repeat("a", 4096)+`"}`), 1024, false, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/checkin", bytes.NewReader(tc.body))
+			if tc.encoding != "" {
+				r.Header.Set("Content-Encoding", tc.encoding)
+			}
+			w := httptest.NewRecorder()
+			var p payload
+			ok := decodeClientBody(w, r, tc.limit, &p)
+			if ok != tc.wantOK {
+				t.Fatalf("decodeClientBody ok = %v, want %v (status %d)", ok, tc.wantOK, w.Code)
+			}
+			if ok && p.Hostname != "web-1" {
+				t.Fatalf("hostname = %q, want web-1", p.Hostname)
+			}
+			if !ok && w.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tc.wantStatus)
+			}
+		})
+	}
+}
@@ -76,14 +76,89 @@ func (rl *rateLimiter) cleanup() {
 
 func (rl *rateLimiter) middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := r.RemoteAddr
-		if fwd := r.Header.Get("X-Real-IP"); fwd != "" {
-			ip = fwd
-		}
-		if !rl.allow(ip) {
+		if !rl.allow(requestIP(r)) {
 			http.Error(w, `{"error":"rate limit exceeded"}`, http.StatusTooManyRequests)
 			return
 		}
 		next.ServeHTTP(w, r)
 	})
 }
+
+// authLockout blocks an IP after repeated failed admin logins.
+type authLockout struct {
+	mu          sync.Mutex
+	failures    map[string]*authFailures
+	maxFailures int
+	window      time.Duration
+}
+
+type authFailures struct {
+	count        int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+// newAuthLockout blocks an IP for window once it reaches maxFailures failed
+// attempts, each within window of the previous one.
+func newAuthLockout(maxFailures int, window time.Duration) *authLockout {
+	l := &authLockout{
+		failures:    make(map[string]*authFailures),
+		maxFailures: maxFailures,
+		window:      window,
+	}
+	go func() {
+		for {
+			time.Sleep(time.Minute)
+			l.cleanup()
+		}
+	}()
+	return l
+}
+
+// blocked reports whether key is locked out and, if so, for how much longer.
+func (l *authLockout) blocked(key string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, ok := l.failures[key]
+	if !ok {
+		return 0, false
+	}
+	remaining := time.Until(f.blockedUntil)
+	return remaining, remaining > 0
+}
+
+// fail records a failed attempt from key.
+func (l *authLockout) fail(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	f, ok := l.failures[key]
+	if !ok || now.Sub(f.lastFailure) > l.window {
+		f = &authFailures{}
+		l.failures[key] = f
+	}
+	f.count++
+	f.lastFailure = now
+	if f.count >= l.maxFailures {
+		f.blockedUntil = now.Add(l.window)
+		f.count = 0
+	}
+}
+
+// succeed clears the failure history for key.
+func (l *authLockout) succeed(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.failures, key)
+}
+
+func (l *authLockout) cleanup() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	for key, f := range l.failures {
+		if now.Sub(f.lastFailure) > l.window && now.After(f.blockedUntil) {
+			delete(l.failures, key)
+		}
+	}
+}
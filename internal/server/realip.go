@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// proxyList is the set of reverse proxies whose forwarding headers are trusted.
+type proxyList []*net.IPNet
+
+// parseTrustedProxies accepts IPs and CIDRs, skipping (and logging) any entry
+// that is neither.
+func parseTrustedProxies(entries []string, logger *slog.Logger) proxyList {
+	var list proxyList
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		if !strings.Contains(e, "/") {
+			if ip := net.ParseIP(e); ip != nil {
+				bits := 128
+				if ip.To4() != nil {
+					ip, bits = ip.To4(), 32
+				}
+				list = append(list, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+				continue
+			}
+		}
+		_, n, err := net.ParseCIDR(e)
+		if err != nil {
+			logger.Warn("ignoring invalid trusted_proxies entry", "entry", e)
+			continue
+		}
+		list = append(list, n)
+	}
+	return list
+}
+
+func (p proxyList) contains(addr string) bool {
+	ip := net.ParseIP(strings.Trim(strings.TrimSpace(addr), "[]"))
+	if ip == nil {
+		return false
+	}
+	for _, n := range p {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// peerInfo is the TCP peer a request arrived from, recorded by
+// realIPMiddleware before it rewrites r.RemoteAddr.
+type peerInfo struct {
+	ip      string
+	trusted bool // listed in trusted_proxies
+}
+
+type peerInfoKey struct{}
+
+// realIPMiddleware rewrites r.RemoteAddr to the originating client address.
+// With no trusted proxies configured it keeps the historical behaviour of
+// believing X-Real-IP / X-Forwarded-For from any peer, and warns once that
+// the login lockout is then counted per proxy rather than per client.
+func realIPMiddleware(trusted proxyList, logger *slog.Logger) func(http.Handler) http.Handler {
+	var warnOnce sync.Once
+	return func(next http.Handler) http.Handler {
+		rewrite := middleware.RealIP(next)
+		if len(trusted) > 0 {
+			rewrite = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r.RemoteAddr = trusted.clientIP(r)
+				next.ServeHTTP(w, r)
+			})
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peer := requestIP(r)
+			if len(trusted) == 0 && r.Header.Get("X-Forwarded-For") != "" {
+				warnOnce.Do(func() {
+					logger.Warn("received X-Forwarded-For but trusted_proxies is empty; failed logins are counted against the proxy address, so one client can lock out everyone behind it",
+						"peer", peer)
+				})
+			}
+			ctx := context.WithValue(r.Context(), peerInfoKey{}, peerInfo{ip: peer, trusted: trusted.contains(peer)})
+			rewrite.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// lockoutIP returns the address failed logins are counted against. Only a
+// trusted proxy's forwarding headers are believed here, even when
+// trusted_proxies is empty, so a client can't dodge the lockout by sending a
+// new X-Forwarded-For with every attempt.
+func lockoutIP(r *http.Request) string {
+	if peer, ok := r.Context().Value(peerInfoKey{}).(peerInfo); ok && !peer.trusted {
+		return peer.ip
+	}
+	return requestIP(r)
+}
+
+// clientIP resolves the client address when the direct peer may be a trusted
+// proxy. X-Forwarded-For is walked from the right, skipping trusted hops; the
+// first untrusted hop is the client, since anything left of it could have
+// been supplied by the client itself.
+func (p proxyList) clientIP(r *http.Request) string {
+	peer := requestIP(r)
+	if !p.contains(peer) {
+		return peer
+	}
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		client := ""
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop == "" {
+				continue
+			}
+			client = hop
+			if !p.contains(hop) {
+				break
+			}
+		}
+		if client != "" {
+			return client
+		}
+	}
+	if real := strings.TrimSpace(r.Header.Get("X-Real-IP")); real != "" {
+		return real
+	}
+	return peer
+}
+
+// requestIP returns the client address of r without the port. It relies on
+// realIPMiddleware having already resolved any proxy headers.
+func requestIP(r *http.Request) string {
+	ip := strings.TrimSpace(r.RemoteAddr)
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		return host
+	}
+	return ip
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrustedProxyClientIP(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	trusted := parseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.5", "not-an-ip"}, logger)
+	if len(trusted) != 2 {
+		t.Fatalf("expected invalid entry to be skipped, got %d entries", len(trusted))
+	}
+
+	cases := []struct {
+		name   string
+		remote string
+		xff    string
+		real   string
+		want   string
+	}{
+		{"untrusted peer ignores headers", "203.0.113.9:5000", "198.51.100.1", "198.51.100.2", "203.0.113.9"},
+		{"single proxy", "192.168.1.5:5000", "198.51.100.1", "", "198.51.100.1"},
+		{"proxy chain skips trusted hops", "10.0.0.2:5000", "198.51.100.1, 10.0.0.7", "", "198.51.100.1"},
+		{"spoofed left entry is not used", "10.0.0.2:5000", "1.2.3.4, 198.51.100.1, 10.0.0.7", "", "198.51.100.1"},
+		{"x-real-ip fallback", "10.0.0.2:5000", "", "198.51.100.3", "198.51.100.3"},
+		{"no headers", "10.0.0.2:5000", "", "", "10.0.0.2"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tc.remote
+		if tc.xff != "" {
+			req.Header.Set("X-Forwarded-For", tc.xff)
+		}
+		if tc.real != "" {
+			req.Header.Set("X-Real-IP", tc.real)
+		}
+		if got := trusted.clientIP(req); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"log/slog"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -14,8 +15,11 @@ import (
 // AlertNotifier is implemented by the alert engine to receive check-in notifications.
 type AlertNotifier interface {
 	NotifyCheckIn(clientID string)
+	NotifyCheckResult(clientID string)
 	NotifyRestart(clientID, hostname string)
+	NotifyIdentityConflict(clientID, previousHostname, hostname string)
 	SendTestAlert(providerID int64) (*models.TestAlertResult, error)
+	NotifySystem(alertType, severity, message string) bool
 }
 
 type Server struct {
@@ -25,19 +29,33 @@ type Server struct {
 	alerts      AlertNotifier
 	logger      *slog.Logger
 	rateLimiter *rateLimiter
+	authLockout *authLockout
+	passwordMu  sync.RWMutex // guards the password hashes in cfg
+	checksums   *checksumCache
+	events      *eventBroker
+	logLevel    *slog.LevelVar // nil when the level can't be changed at runtime
+	startedAt   time.Time
 }
 
 func New(cfg *Config, st store.Store, alerts AlertNotifier, logger *slog.Logger) *Server {
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(realIPMiddleware(parseTrustedProxies(cfg.TrustedProxies, logger), logger))
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Compress(5))
 
 	// Allow 30 check-ins per minute per IP (generous for multi-client hosts)
 	rl := newRateLimiter(2*time.Second, 30)
 
+	if cfg.hashPlaintextTokens() && cfg.path != "" {
+		if err := SaveServerConfig(cfg, cfg.path); err != nil {
+			logger.Error("failed to save hashed tokens", "path", cfg.path, "err", err)
+		} else {
+			logger.Info("replaced plaintext tokens with their hashes", "path", cfg.path)
+		}
+	}
+
 	s := &Server{
 		cfg:         cfg,
 		store:       st,
@@ -45,11 +63,18 @@ func New(cfg *Config, st store.Store, alerts AlertNotifier, logger *slog.Logger)
 		alerts:      alerts,
 		logger:      logger,
 		rateLimiter: rl,
+		authLockout: newAuthLockout(adminMaxFailures, adminLockoutWindow),
+		checksums:   newChecksumCache(),
+		events:      newEventBroker(),
+		startedAt:   time.Now().UTC(),
 	}
 
 	// Client API
 	r.Route("/api/v1", func(r chi.Router) {
 		r.With(rl.middleware, s.clientPasswordAuth).Post("/checkin", s.handleCheckIn)
+		r.With(rl.middleware, s.clientPasswordAuth).Post("/checkin/batch", s.handleCheckInBatch)
+		r.With(rl.middleware, s.clientPasswordAuth).Post("/checkin/check", s.handleExternalCheck)
+		r.With(rl.middleware, s.commandWebhookAuth).Post("/commands", s.handleCommand)
 
 		// Admin API
 		r.Route("/admin", func(r chi.Router) {
@@ -58,19 +83,46 @@ func New(cfg *Config, st store.Store, alerts AlertNotifier, logger *slog.Logger)
 			// Clients
 			r.Get("/clients", s.handleListClients)
 			r.Get("/clients/{id}", s.handleGetClient)
+			r.Get("/client-versions", s.handleListClientVersions)
 			r.Delete("/clients/{id}", s.handleDeleteClient)
+			r.Post("/clients/{id}/merge", s.handleMergeClients)
+			r.Post("/clients/{id}/purge", s.handlePurgeClient)
+			r.Delete("/clients/{id}/metrics", s.handleClearClientMetrics)
 			r.Put("/clients/{id}/thresholds", s.handleSetThresholds)
 			r.Delete("/clients/{id}/thresholds", s.handleClearThresholds)
 			r.Put("/clients/{id}/mute", s.handleSetMute)
 			r.Put("/clients/{id}/mutes", s.handleSetScopedMute)
+			r.Put("/clients/{id}/suspend", s.handleSetSuspended)
+			r.Post("/mute", s.handleBulkMute)
+			r.Post("/unmute", s.handleBulkUnmute)
+			r.Put("/clients/{id}/business-hours", s.handleSetBusinessHours)
+			r.Put("/clients/{id}/check-in-interval", s.handleSetCheckInInterval)
+			r.Get("/clients/{id}/effective-thresholds", s.handleGetEffectiveThresholds)
 			r.Put("/clients/{id}/name", s.handleSetClientName)
+			r.Put("/clients/{id}/tags", s.handleSetClientTags)
+			r.Get("/clients/{id}/maintenance", s.handleListMaintenanceWindows)
+			r.Post("/clients/{id}/maintenance", s.handleCreateMaintenanceWindow)
+			r.Delete("/clients/{id}/maintenance/{windowID}", s.handleDeleteMaintenanceWindow)
 			r.Get("/clients/{id}/metrics", s.handleGetMetrics)
+			r.Get("/clients/{id}/metrics/export", s.handleExportMetrics)
 			r.Get("/clients/{id}/processes", s.handleGetProcesses)
 			r.Delete("/clients/{id}/processes", s.handleDeleteProcess)
+			r.Put("/clients/{id}/processes/{name}", s.handleUpdateProcess)
 			r.Delete("/clients/{id}/checks", s.handleDeleteCheck)
 
+			// Client tokens
+			r.Get("/client-tokens", s.handleListClientTokens)
+			r.Post("/client-tokens", s.handleCreateClientToken)
+			r.Delete("/client-tokens/{id}", s.handleRevokeClientToken)
+
+			// Admin API tokens
+			r.Get("/api-tokens", s.handleListAPITokens)
+			r.Post("/api-tokens", s.handleCreateAPIToken)
+			r.Delete("/api-tokens/{id}", s.handleRevokeAPIToken)
+
 			// Alerts
 			r.Get("/alerts", s.handleListAlerts)
+			r.Post("/alerts/{id}/ack", s.handleAckAlert)
 
 			// Providers
 			r.Get("/providers", s.handleListProviders)
@@ -78,23 +130,45 @@ func New(cfg *Config, st store.Store, alerts AlertNotifier, logger *slog.Logger)
 			r.Put("/providers/{id}", s.handleUpdateProvider)
 			r.Delete("/providers/{id}", s.handleDeleteProvider)
 			r.Post("/providers/{id}/test", s.handleTestProvider)
+			r.Get("/alert-routes", s.handleGetAlertRoutes)
+			r.Put("/alert-routes", s.handleSetAlertRoutes)
 
 			// Settings
 			r.Get("/settings", s.handleGetSettings)
 			r.Put("/settings", s.handleUpdateSettings)
 			r.Put("/password", s.handleChangePassword)
+
+			// Audit log
+			r.Get("/audit", s.handleListAudit)
+
+			// Server health and workload overview
+			r.Get("/stats", s.handleServerStats)
+
+			// Database maintenance
+			r.Get("/database", s.handleDatabaseStats)
+			r.Post("/database/vacuum", s.handleVacuumDatabase)
+
+			// Runtime log level
+			r.Get("/loglevel", s.handleGetLogLevel)
+			r.Put("/loglevel", s.handleSetLogLevel)
+
+			// Live updates
+			r.Get("/events", s.handleEvents)
 		})
 	})
 
 	// Health check (no auth)
-	r.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte(`{"status":"ok"}`))
-	})
+	r.Get("/healthz", s.handleHealthz)
+
+	// Prometheus scrape endpoint
+	r.With(s.metricsAuth).Get("/metrics", s.handlePrometheusMetrics)
 
 	// Binary downloads (no auth — public so install scripts work)
 	r.Route("/download", func(r chi.Router) {
 		r.Get("/", s.handleListDownloads)
 		r.Get("/install.sh", s.handleDownloadInstallScript)
+		r.Get("/install.sh.sha256", s.handleInstallScriptChecksum)
+		r.Get("/"+checksumsFilename, s.handleDownloadChecksums)
 		r.Get("/{filename}", s.handleDownloadBinary)
 	})
 
@@ -9,13 +9,21 @@ import (
 	"crypto/x509/pkix"
 	"encoding/pem"
 	"fmt"
+	"log/slog"
 	"math/big"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/machinemon/machinemon/internal/netinfo"
+	"golang.org/x/crypto/acme"
 	"golang.org/x/crypto/acme/autocert"
 )
 
@@ -29,10 +37,59 @@ func (s *Server) ListenAndServeTLS() error {
 	case "manual":
 		return s.listenManualCert()
 	default:
+		if s.cfg.ClientCAFile != "" {
+			s.logger.Warn("client_ca_file is ignored without TLS; clients must use the client password")
+		}
 		return s.ListenAndServe()
 	}
 }
 
+// applyClientCAs sets up optional client certificate verification on cfg
+// when client_ca_file is configured. Certificates are requested but not
+// required, so browsers and password-based clients keep working.
+func (s *Server) applyClientCAs(cfg *tls.Config) error {
+	if s.cfg.ClientCAFile == "" {
+		return nil
+	}
+	pool, err := loadCertPool(s.cfg.ClientCAFile)
+	if err != nil {
+		return err
+	}
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	s.logger.Info("client certificate authentication enabled", "ca", s.cfg.ClientCAFile)
+	return nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("client CA file %s contains no PEM certificates", path)
+	}
+	return pool, nil
+}
+
+// serveTLS serves s.router over TLS with certFile/keyFile, or with the
+// certificates already in tlsCfg when both are empty.
+func (s *Server) serveTLS(tlsCfg *tls.Config, certFile, keyFile string) error {
+	if tlsCfg == nil {
+		tlsCfg = &tls.Config{}
+	}
+	if err := s.applyClientCAs(tlsCfg); err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Addr:      s.cfg.ListenAddr,
+		Handler:   s.router,
+		TLSConfig: tlsCfg,
+	}
+	return srv.ListenAndServeTLS(certFile, keyFile)
+}
+
 func (s *Server) listenAutocert() error {
 	if s.cfg.Domain == "" {
 		return fmt.Errorf("domain is required for autocert TLS mode")
@@ -46,28 +103,91 @@ func (s *Server) listenAutocert() error {
 		Cache:      autocert.DirCache(s.cfg.CertCacheDir),
 		Prompt:     autocert.AcceptTOS,
 		HostPolicy: autocert.HostWhitelist(s.cfg.Domain),
+		Email:      s.cfg.AutocertEmail,
+	}
+	if s.cfg.AutocertDirectoryURL != "" {
+		m.Client = &acme.Client{DirectoryURL: s.cfg.AutocertDirectoryURL}
+	}
+
+	// The HTTP-01 challenge listener is optional: m.TLSConfig also answers
+	// TLS-ALPN-01 challenges on the HTTPS listener.
+	if addr := s.cfg.autocertChallengeAddr(); addr != "" {
+		go func() {
+			h := m.HTTPHandler(nil)
+			s.logger.Info("starting HTTP challenge listener", "addr", addr)
+			if err := http.ListenAndServe(addr, h); err != nil {
+				s.logger.Error("HTTP challenge listener error", "addr", addr, "err", err)
+			}
+		}()
+	} else {
+		s.logger.Info("HTTP challenge listener disabled; relying on TLS-ALPN challenges")
 	}
 
-	// Start HTTP challenge server on port 80
-	go func() {
-		h := m.HTTPHandler(nil)
-		s.logger.Info("starting HTTP challenge listener on :80")
-		if err := http.ListenAndServe(":80", h); err != nil {
-			s.logger.Error("HTTP challenge listener error", "err", err)
+	// Ask for the certificate a modern (ECDSA-capable) client would get, so
+	// the check doesn't make autocert fetch an extra RSA certificate.
+	hello := &tls.ClientHelloInfo{
+		ServerName:       s.cfg.Domain,
+		SignatureSchemes: []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256},
+		SupportedCurves:  []tls.CurveID{tls.CurveP256},
+		CipherSuites:     []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+	}
+	go s.watchCertExpiry(func() (*x509.Certificate, error) {
+		cert, err := m.GetCertificate(hello)
+		if err != nil {
+			return nil, err
 		}
-	}()
+		return leafOf(cert)
+	})
 
-	srv := &http.Server{
-		Addr:      s.cfg.ListenAddr,
-		Handler:   s.router,
-		TLSConfig: m.TLSConfig(),
+	tlsCfg := m.TLSConfig()
+	if s.cfg.AutocertSelfSignedFallback {
+		certFile, keyFile, err := s.ensureSelfSignedCert()
+		if err != nil {
+			return fmt.Errorf("self-signed fallback cert: %w", err)
+		}
+		fallback, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("load self-signed fallback cert: %w", err)
+		}
+		tlsCfg.GetCertificate = withSelfSignedFallback(tlsCfg.GetCertificate, &fallback, s.logger)
 	}
 
 	s.logger.Info("starting HTTPS server (autocert)",
 		"addr", s.cfg.ListenAddr,
-		"domain", s.cfg.Domain)
+		"domain", s.cfg.Domain,
+		"selfsigned_fallback", s.cfg.AutocertSelfSignedFallback)
+
+	return s.serveTLS(tlsCfg, "", "")
+}
+
+// fallbackLogInterval limits how often serving the fallback certificate is
+// logged; every handshake would otherwise log while ACME is failing.
+const fallbackLogInterval = 10 * time.Minute
 
-	return srv.ListenAndServeTLS("", "")
+// withSelfSignedFallback wraps autocert's GetCertificate so that handshakes
+// get the self-signed certificate whenever ACME can't produce one (rate
+// limits, DNS not ready, no SNI). ACME's own TLS-ALPN challenges still see
+// the original error.
+func withSelfSignedFallback(get func(*tls.ClientHelloInfo) (*tls.Certificate, error), fallback *tls.Certificate, logger *slog.Logger) func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	var mu sync.Mutex
+	var lastLogged time.Time
+	return func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		cert, err := get(hello)
+		if err == nil {
+			return cert, nil
+		}
+		if slices.Contains(hello.SupportedProtos, acme.ALPNProto) {
+			return nil, err
+		}
+		mu.Lock()
+		if time.Since(lastLogged) >= fallbackLogInterval {
+			lastLogged = time.Now()
+			logger.Error("ACME certificate unavailable, serving self-signed fallback; clients without insecure_skip_tls will fail to connect",
+				"server_name", hello.ServerName, "err", err)
+		}
+		mu.Unlock()
+		return fallback, nil
+	}
 }
 
 func (s *Server) listenSelfSigned() error {
@@ -80,7 +200,7 @@ func (s *Server) listenSelfSigned() error {
 		"addr", s.cfg.ListenAddr,
 		"cert", certFile)
 
-	return http.ListenAndServeTLS(s.cfg.ListenAddr, certFile, keyFile, s.router)
+	return s.serveTLS(nil, certFile, keyFile)
 }
 
 func (s *Server) listenManualCert() error {
@@ -88,11 +208,19 @@ func (s *Server) listenManualCert() error {
 		return fmt.Errorf("cert_file and key_file are required for manual TLS mode")
 	}
 
+	// Load the pair here rather than in ListenAndServeTLS so the expiry
+	// check sees exactly the certificate being served.
+	pair, err := tls.LoadX509KeyPair(s.cfg.CertFile, s.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+	go s.watchCertExpiry(func() (*x509.Certificate, error) { return leafOf(&pair) })
+
 	s.logger.Info("starting HTTPS server (manual cert)",
 		"addr", s.cfg.ListenAddr,
 		"cert", s.cfg.CertFile)
 
-	return http.ListenAndServeTLS(s.cfg.ListenAddr, s.cfg.CertFile, s.cfg.KeyFile, s.router)
+	return s.serveTLS(&tls.Config{Certificates: []tls.Certificate{pair}}, "", "")
 }
 
 // ensureSelfSignedCert generates a self-signed cert if one doesn't already exist.
@@ -104,18 +232,26 @@ func (s *Server) ensureSelfSignedCert() (certFile, keyFile string, err error) {
 	certFile = filepath.Join(s.cfg.CertCacheDir, "selfsigned.crt")
 	keyFile = filepath.Join(s.cfg.CertCacheDir, "selfsigned.key")
 
+	hostname, _ := os.Hostname()
+	dnsNames, ipAddrs := selfSignedSANs(hostname, netinfo.InterfaceIPs(), s.cfg.SelfSignedSANs, s.cfg.ExternalURL)
+
 	// Check if certs already exist
 	if _, err := os.Stat(certFile); err == nil {
 		if _, err := os.Stat(keyFile); err == nil {
-			// Verify they're still valid (not expired)
+			// Verify they're still valid (not expired) and cover the
+			// names and addresses clients may use today.
 			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
 			if err == nil {
 				leaf, err := x509.ParseCertificate(cert.Certificate[0])
 				if err == nil && leaf.NotAfter.After(time.Now().Add(24*time.Hour)) {
-					return certFile, keyFile, nil
+					if sameSANs(leaf, dnsNames, ipAddrs) {
+						return certFile, keyFile, nil
+					}
+					s.logger.Info("self-signed certificate names changed, regenerating",
+						"dns_names", dnsNames, "ips", ipAddrs)
 				}
 			}
-			// Invalid or expiring, regenerate
+			// Invalid, expiring, or stale SANs: regenerate
 		}
 	}
 
@@ -139,8 +275,8 @@ func (s *Server) ensureSelfSignedCert() (certFile, keyFile string, err error) {
 		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
 		BasicConstraintsValid: true,
-		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")},
-		DNSNames:              []string{"localhost"},
+		IPAddresses:           ipAddrs,
+		DNSNames:              dnsNames,
 	}
 
 	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
@@ -170,7 +306,76 @@ func (s *Server) ensureSelfSignedCert() (certFile, keyFile string, err error) {
 
 	s.logger.Info("self-signed certificate generated",
 		"cert", certFile,
-		"expires", template.NotAfter.Format("2006-01-02"))
+		"expires", template.NotAfter.Format("2006-01-02"),
+		"dns_names", dnsNames,
+		"ips", ipAddrs)
 
 	return certFile, keyFile, nil
 }
+
+// selfSignedSANs collects the names and addresses the self-signed certificate
+// should cover: loopback, the machine's hostname and interface addresses, the
+// external_url host, and any configured extras. Both lists are sorted and
+// de-duplicated so they can be compared with an existing certificate.
+// Link-local IPv6 addresses are left out; clients can't use them without a
+// zone, and they would force needless regeneration.
+func selfSignedSANs(hostname string, interfaceIPs, extra []string, externalURL string) ([]string, []net.IP) {
+	candidates := []string{"localhost", "127.0.0.1", "::1", hostname}
+	candidates = append(candidates, interfaceIPs...)
+	candidates = append(candidates, extra...)
+	if u, err := url.Parse(externalURL); err == nil && u.Hostname() != "" {
+		candidates = append(candidates, u.Hostname())
+	}
+
+	dnsSeen := make(map[string]bool)
+	ipSeen := make(map[string]bool)
+	var dnsNames []string
+	var ips []net.IP
+	for _, c := range candidates {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if ip := net.ParseIP(c); ip != nil {
+			if ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+				continue
+			}
+			if v4 := ip.To4(); v4 != nil {
+				ip = v4
+			}
+			if !ipSeen[ip.String()] {
+				ipSeen[ip.String()] = true
+				ips = append(ips, ip)
+			}
+			continue
+		}
+		name := strings.ToLower(strings.TrimSuffix(c, "."))
+		if !dnsSeen[name] {
+			dnsSeen[name] = true
+			dnsNames = append(dnsNames, name)
+		}
+	}
+	sort.Strings(dnsNames)
+	sort.Slice(ips, func(i, j int) bool { return ips[i].String() < ips[j].String() })
+	return dnsNames, ips
+}
+
+// sameSANs reports whether cert already carries exactly these SANs.
+func sameSANs(cert *x509.Certificate, dnsNames []string, ips []net.IP) bool {
+	have := append([]string(nil), cert.DNSNames...)
+	sort.Strings(have)
+	if strings.Join(have, ",") != strings.Join(dnsNames, ",") {
+		return false
+	}
+	haveIPs := make([]string, 0, len(cert.IPAddresses))
+	for _, ip := range cert.IPAddresses {
+		haveIPs = append(haveIPs, ip.String())
+	}
+	wantIPs := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		wantIPs = append(wantIPs, ip.String())
+	}
+	sort.Strings(haveIPs)
+	sort.Strings(wantIPs)
+	return strings.Join(haveIPs, ",") == strings.Join(wantIPs, ",")
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/acme"
+)
+
+func TestSelfSignedSANs(t *testing.T) {
+	dns, ips := selfSignedSANs("Web-1", []string{"192.168.1.10", "fe80::1", "192.168.1.10"},
+		[]string{"monitor.lan", " 10.0.0.5 ", ""}, "https://monitor.example.com:8443/mm")
+
+	if got, want := strings.Join(dns, ","), "localhost,monitor.example.com,monitor.lan,web-1"; got != want {
+		t.Errorf("dns names = %q, want %q", got, want)
+	}
+	var gotIPs []string
+	for _, ip := range ips {
+		gotIPs = append(gotIPs, ip.String())
+	}
+	if got, want := strings.Join(gotIPs, ","), "10.0.0.5,127.0.0.1,192.168.1.10,::1"; got != want {
+		t.Errorf("ips = %q, want %q", got, want)
+	}
+}
+
+func TestSelfSignedCertRegeneratesWhenSANsChange(t *testing.T) {
+	cfg := DefaultServerConfig()
+	cfg.CertCacheDir = t.TempDir()
+	cfg.SelfSignedSANs = []string{"monitor.lan"}
+	s := &Server{cfg: cfg, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	leaf := func() *x509.Certificate {
+		t.Helper()
+		certFile, keyFile, err := s.ensureSelfSignedCert()
+		if err != nil {
+			t.Fatalf("ensure cert: %v", err)
+		}
+		pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			t.Fatalf("load cert: %v", err)
+		}
+		c, err := x509.ParseCertificate(pair.Certificate[0])
+		if err != nil {
+			t.Fatalf("parse cert: %v", err)
+		}
+		return c
+	}
+
+	first := leaf()
+	if err := first.VerifyHostname("monitor.lan"); err != nil {
+		t.Fatalf("configured SAN missing: %v", err)
+	}
+	if again := leaf(); again.SerialNumber.Cmp(first.SerialNumber) != 0 {
+		t.Fatalf("certificate regenerated although SANs were unchanged")
+	}
+
+	cfg.SelfSignedSANs = []string{"10.9.8.7"}
+	changed := leaf()
+	if changed.SerialNumber.Cmp(first.SerialNumber) == 0 {
+		t.Fatalf("certificate not regenerated after SANs changed")
+	}
+	if err := changed.VerifyHostname("10.9.8.7"); err != nil {
+		t.Fatalf("new SAN missing: %v", err)
+	}
+}
+
+func TestSelfSignedFallback(t *testing.T) {
+	issued := &tls.Certificate{}
+	fallback := &tls.Certificate{}
+	var acmeErr error
+	get := withSelfSignedFallback(func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if acmeErr != nil {
+			return nil, acmeErr
+		}
+		return issued, nil
+	}, fallback, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if cert, err := get(&tls.ClientHelloInfo{ServerName: "monitor.example.com"}); err != nil || cert != issued {
+		t.Fatalf("ACME working: got %p, %v; want the issued cert", cert, err)
+	}
+
+	acmeErr = errors.New("too many certificates already issued")
+	if cert, err := get(&tls.ClientHelloInfo{ServerName: "monitor.example.com"}); err != nil || cert != fallback {
+		t.Fatalf("ACME failing: got %p, %v; want the fallback cert", cert, err)
+	}
+
+	challenge := &tls.ClientHelloInfo{ServerName: "monitor.example.com", SupportedProtos: []string{acme.ALPNProto}}
+	if _, err := get(challenge); !errors.Is(err, acmeErr) {
+		t.Fatalf("ACME challenge handshake: got %v, want the ACME error", err)
+	}
+}
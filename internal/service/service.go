@@ -19,6 +19,7 @@ const (
 	OpenRC   InitSystem = "openrc"
 	Upstart  InitSystem = "upstart"
 	Launchd  InitSystem = "launchd"
+	Rcd      InitSystem = "rc.d"
 	Unknown  InitSystem = ""
 )
 
@@ -28,6 +29,15 @@ func Detect() InitSystem {
 		return Launchd
 	}
 
+	// FreeBSD rc.d: rc.subr-based scripts managed with service(8)
+	if runtime.GOOS == "freebsd" {
+		if info, err := os.Stat("/etc/rc.d"); err == nil && info.IsDir() {
+			if _, err := exec.LookPath("service"); err == nil {
+				return Rcd
+			}
+		}
+	}
+
 	// systemd: check for systemctl binary
 	if _, err := exec.LookPath("systemctl"); err == nil {
 		return Systemd
@@ -74,6 +84,8 @@ func Install(name, binPath, configPath string) error {
 		return installUpstart(name, binPath, configPath)
 	case Launchd:
 		return installLaunchd(name, binPath, configPath)
+	case Rcd:
+		return installRcd(name, binPath, configPath)
 	default:
 		return fmt.Errorf("could not detect init system — install service manually")
 	}
@@ -96,6 +108,8 @@ func Uninstall(name string) error {
 		return uninstallUpstart(name)
 	case Launchd:
 		return uninstallLaunchd(name)
+	case Rcd:
+		return uninstallRcd(name)
 	default:
 		return fmt.Errorf("could not detect init system — remove service manually")
 	}
@@ -118,6 +132,8 @@ func Start(name string) error {
 		return runPrivileged("start", name)
 	case Launchd:
 		return startLaunchd(name)
+	case Rcd:
+		return runPrivileged("service", rcName(name), "start")
 	default:
 		return fmt.Errorf("could not detect init system")
 	}
@@ -177,6 +193,15 @@ func IsRunning(name string) (bool, error) {
 			return false, err
 		}
 		return strings.Contains(string(out), label), nil
+	case Rcd:
+		cmd := exec.Command("service", rcName(name), "status")
+		if err := cmd.Run(); err != nil {
+			if _, ok := err.(*exec.ExitError); ok {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
 	default:
 		return false, nil
 	}
@@ -196,11 +221,84 @@ func Restart(name string) error {
 		return runPrivileged("restart", name)
 	case Launchd:
 		return restartLaunchd(name)
+	case Rcd:
+		return runPrivileged("service", rcName(name), "restart")
+	default:
+		return fmt.Errorf("could not detect init system")
+	}
+}
+
+// logLines is how much of the service log Logs shows.
+const logLines = "100"
+
+// Status prints the service manager's view of the service under the detected
+// init system. A stopped service is reported by the init system's own output,
+// not as an error.
+func Status(name string) error {
+	initSys := Detect()
+	switch initSys {
+	case Systemd:
+		return runInteractive("systemctl", "status", name, "--no-pager", "-l")
+	case SysVInit:
+		return runInteractive("service", name, "status")
+	case OpenRC:
+		return runInteractive("rc-service", name, "status")
+	case Upstart:
+		return runInteractive("status", name)
+	case Launchd:
+		target, err := launchdTarget()
+		if err != nil {
+			return err
+		}
+		label := "com.machinemon." + strings.TrimPrefix(name, "machinemon-")
+		out, err := runLaunchctl(target, "list", label)
+		if err != nil {
+			if _, ok := err.(*exec.ExitError); ok {
+				fmt.Printf("%s is not loaded\n", label)
+				return nil
+			}
+			return err
+		}
+		os.Stdout.Write(out)
+		return nil
+	case Rcd:
+		return runInteractive("service", rcName(name), "status")
 	default:
 		return fmt.Errorf("could not detect init system")
 	}
 }
 
+// Logs prints the most recent service log output under the detected init system.
+func Logs(name string) error {
+	initSys := Detect()
+	switch initSys {
+	case Systemd:
+		return runPrivileged("journalctl", "-u", name, "-n", logLines, "--no-pager")
+	case SysVInit, OpenRC, Upstart, Rcd:
+		return runPrivileged("tail", "-n", logLines, fmt.Sprintf("/var/log/%s.log", name))
+	case Launchd:
+		return runInteractive("tail", "-n", logLines, fmt.Sprintf("/tmp/%s.log", name))
+	default:
+		return fmt.Errorf("could not detect init system")
+	}
+}
+
+// runInteractive runs a command with its output attached to the terminal.
+// A non-zero exit is not treated as an error: status commands use it to
+// report a stopped service after printing their own explanation.
+func runInteractive(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
 // execLine builds the command line for service files.
 func execLine(binPath, configPath string) string {
 	if configPath != "" {
@@ -472,6 +570,72 @@ func uninstallUpstart(name string) error {
 	return nil
 }
 
+// --- FreeBSD rc.d ---
+
+// rcName maps a service name to an rc.d script name. rc.conf variables are
+// derived from it, so it must be a valid shell identifier.
+func rcName(name string) string {
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+func installRcd(name, binPath, configPath string) error {
+	rc := rcName(name)
+	// daemon(8) supervises the process (restarting it on exit) and writes
+	// its output to the log; the pidfile belongs to the supervisor.
+	script := fmt.Sprintf(`#!/bin/sh
+#
+# PROVIDE: %s
+# REQUIRE: LOGIN NETWORKING
+# KEYWORD: shutdown
+
+. /etc/rc.subr
+
+name="%s"
+rcvar="%s_enable"
+desc="MachineMon %s"
+
+load_rc_config $name
+: ${%s_enable:="NO"}
+
+pidfile="/var/run/${name}.pid"
+logfile="/var/log/%s.log"
+command="/usr/sbin/daemon"
+command_args="-r -R 10 -P ${pidfile} -o ${logfile} %s"
+
+run_rc_command "$1"
+`, rc, rc, rc, serviceLabel(name), rc, name, execLine(binPath, configPath))
+
+	path := fmt.Sprintf("/usr/local/etc/rc.d/%s", rc)
+	if err := writePrivileged(path, script); err != nil {
+		return fmt.Errorf("write rc.d script: %w", err)
+	}
+	if err := runPrivileged("chmod", "755", path); err != nil {
+		return fmt.Errorf("chmod: %w", err)
+	}
+	if err := runPrivileged("sysrc", rc+"_enable=YES"); err != nil {
+		return fmt.Errorf("enable in rc.conf: %w", err)
+	}
+
+	fmt.Printf("rc.d service installed: %s\n", path)
+	fmt.Println()
+	fmt.Printf("  Start now:   sudo service %s start\n", rc)
+	fmt.Printf("  Auto-start:  (already enabled in /etc/rc.conf)\n")
+	fmt.Printf("  Check logs:  tail -f /var/log/%s.log\n", name)
+	return nil
+}
+
+func uninstallRcd(name string) error {
+	rc := rcName(name)
+	_ = runPrivileged("service", rc, "onestop")
+	_ = runPrivileged("sysrc", "-x", rc+"_enable")
+	path := fmt.Sprintf("/usr/local/etc/rc.d/%s", rc)
+	if err := removePrivileged(path); err != nil {
+		return err
+	}
+	fmt.Printf("rc.d service removed: %s\n", name)
+	return nil
+}
+
 // --- launchd ---
 
 type launchdUser struct {
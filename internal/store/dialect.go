@@ -0,0 +1,132 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// dialect selects between the few SQL spellings that differ per backend.
+type dialect int
+
+const (
+	dialectSQLite dialect = iota
+	dialectPostgres
+)
+
+// sqlDB wraps *sql.DB so store queries can use ? placeholders everywhere;
+// for Postgres they are rewritten to $1, $2, ... before reaching the driver.
+type sqlDB struct {
+	*sql.DB
+	dialect dialect
+}
+
+func (d *sqlDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return d.DB.Exec(rebind(d.dialect, query), args...)
+}
+
+func (d *sqlDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return d.DB.Query(rebind(d.dialect, query), args...)
+}
+
+func (d *sqlDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return d.DB.QueryRow(rebind(d.dialect, query), args...)
+}
+
+func (d *sqlDB) Begin() (*sqlTx, error) {
+	tx, err := d.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{Tx: tx, dialect: d.dialect}, nil
+}
+
+// sqlTx is the transaction counterpart of sqlDB.
+type sqlTx struct {
+	*sql.Tx
+	dialect dialect
+}
+
+func (t *sqlTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return t.Tx.Exec(rebind(t.dialect, query), args...)
+}
+
+func (t *sqlTx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return t.Tx.Query(rebind(t.dialect, query), args...)
+}
+
+func (t *sqlTx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return t.Tx.QueryRow(rebind(t.dialect, query), args...)
+}
+
+func (t *sqlTx) Prepare(query string) (*sql.Stmt, error) {
+	return t.Tx.Prepare(rebind(t.dialect, query))
+}
+
+// rebind rewrites ? placeholders to Postgres' numbered form, leaving
+// question marks inside quoted literals alone.
+func rebind(d dialect, query string) string {
+	if d != dialectPostgres || !strings.Contains(query, "?") {
+		return query
+	}
+	var b strings.Builder
+	b.Grow(len(query) + 16)
+	n := 0
+	inQuote := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'':
+			inQuote = !inQuote
+		case c == '?' && !inQuote:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// timeBetween filters col to the range given by the next two placeholders.
+// SQLite keeps timestamps as text in more than one layout, so both sides are
+// normalized through datetime(); Postgres compares timestamps natively.
+func (d *sqlDB) timeBetween(col string) string {
+	return d.timeCompare(col, ">=") + " AND " + d.timeCompare(col, "<=")
+}
+
+// timeCompare compares col against the next placeholder with op, normalizing
+// SQLite timestamps the same way as timeBetween.
+func (d *sqlDB) timeCompare(col, op string) string {
+	if d.dialect == dialectPostgres {
+		return fmt.Sprintf("%s %s ?", col, op)
+	}
+	return fmt.Sprintf("datetime(%s) %s datetime(?)", col, op)
+}
+
+// epochSeconds converts a timestamp column to integer Unix seconds.
+func (d *sqlDB) epochSeconds(col string) string {
+	if d.dialect == dialectPostgres {
+		return fmt.Sprintf("CAST(EXTRACT(EPOCH FROM %s) AS BIGINT)", col)
+	}
+	return fmt.Sprintf("CAST(strftime('%%s', %s) AS INTEGER)", col)
+}
+
+// now is the database's current UTC timestamp.
+func (d *sqlDB) now() string {
+	if d.dialect == dialectPostgres {
+		return "NOW()"
+	}
+	return "datetime('now')"
+}
+
+// secondsAgo returns an expression (with one placeholder) and its argument
+// for the database's current time minus secs.
+func (d *sqlDB) secondsAgo(secs int) (string, interface{}) {
+	if d.dialect == dialectPostgres {
+		return "NOW() - make_interval(secs => ?)", float64(secs)
+	}
+	return "datetime('now', ? || ' seconds')", fmt.Sprintf("-%d", secs)
+}
@@ -0,0 +1,23 @@
+package store
+
+import "testing"
+
+func TestRebindNumbersPlaceholdersForPostgres(t *testing.T) {
+	q := `SELECT id FROM clients WHERE id = ? AND note <> 'why?' AND hostname IN (?, ?)`
+	if got := rebind(dialectSQLite, q); got != q {
+		t.Fatalf("sqlite query should be unchanged, got %q", got)
+	}
+	want := `SELECT id FROM clients WHERE id = $1 AND note <> 'why?' AND hostname IN ($2, $3)`
+	if got := rebind(dialectPostgres, q); got != want {
+		t.Fatalf("rebind = %q, want %q", got, want)
+	}
+}
+
+func TestNewRejectsUnknownDriver(t *testing.T) {
+	if _, err := New(Config{Driver: "mysql"}); err == nil {
+		t.Fatal("expected error for unknown driver")
+	}
+	if _, err := New(Config{Driver: DriverPostgres}); err == nil {
+		t.Fatal("expected error for postgres without a DSN")
+	}
+}
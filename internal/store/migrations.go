@@ -2,6 +2,8 @@ package store
 
 import "database/sql"
 
+// migrations is the SQLite schema history, indexed by PRAGMA user_version.
+// Schema changes also need a step in postgresMigrations.
 var migrations = []func(tx *sql.Tx) error{
 	migrateV1,
 	migrateV2,
@@ -11,6 +13,39 @@ var migrations = []func(tx *sql.Tx) error{
 	migrateV6,
 	migrateV7,
 	migrateV8,
+	migrateV9,
+	migrateV10,
+	migrateV11,
+	migrateV12,
+	migrateV13,
+	migrateV14,
+	migrateV15,
+	migrateV16,
+	migrateV17,
+	migrateV18,
+	migrateV19,
+	migrateV20,
+	migrateV21,
+	migrateV22,
+	migrateV23,
+	migrateV24,
+	migrateV25,
+	migrateV26,
+	migrateV27,
+	migrateV28,
+	migrateV29,
+	migrateV30,
+	migrateV31,
+	migrateV32,
+	migrateV33,
+	migrateV34,
+	migrateV35,
+	migrateV36,
+	migrateV37,
+	migrateV38,
+	migrateV39,
+	migrateV40,
+	migrateV41,
 }
 
 func migrateV1(tx *sql.Tx) error {
@@ -179,3 +214,338 @@ func migrateV8(tx *sql.Tx) error {
 	_, err := tx.Exec(`ALTER TABLE clients ADD COLUMN metric_consecutive_checkins INTEGER`)
 	return err
 }
+
+func migrateV9(tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE alerts ADD COLUMN notify_attempts INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE alerts ADD COLUMN last_notify_attempt_at DATETIME`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateV10(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE clients ADD COLUMN alert_cooldown_seconds INTEGER`)
+	return err
+}
+
+func migrateV11(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE clients ADD COLUMN previous_session_id TEXT`)
+	return err
+}
+
+func migrateV12(tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE clients ADD COLUMN machine_id TEXT`,
+		`CREATE INDEX IF NOT EXISTS idx_clients_machine_id ON clients(machine_id)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateV13(tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE metrics ADD COLUMN net_rx_bytes INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE metrics ADD COLUMN net_tx_bytes INTEGER NOT NULL DEFAULT 0`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateV14(tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE process_snapshots ADD COLUMN num_fds INTEGER`,
+		`ALTER TABLE process_snapshots ADD COLUMN num_threads INTEGER`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateV15(tx *sql.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS disk_mounts (
+		client_id    TEXT NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+		mount_path   TEXT NOT NULL,
+		recorded_at  DATETIME NOT NULL DEFAULT (datetime('now')),
+		total_bytes  INTEGER NOT NULL DEFAULT 0,
+		used_bytes   INTEGER NOT NULL DEFAULT 0,
+		used_pct     REAL NOT NULL DEFAULT 0,
+		warn_pct     REAL,
+		crit_pct     REAL,
+		error        TEXT NOT NULL DEFAULT '',
+		alert_state  TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (client_id, mount_path)
+	)`)
+	return err
+}
+
+func migrateV16(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE check_snapshots ADD COLUMN severity TEXT`)
+	return err
+}
+
+func migrateV17(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE clients ADD COLUMN business_hours TEXT`)
+	return err
+}
+
+func migrateV18(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE clients ADD COLUMN reported_interval_seconds INTEGER`)
+	return err
+}
+
+func migrateV19(tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE alerts ADD COLUMN acked BOOLEAN NOT NULL DEFAULT 0`,
+		`ALTER TABLE alerts ADD COLUMN acked_at DATETIME`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateV20(tx *sql.Tx) error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS maintenance_windows (
+			id          INTEGER PRIMARY KEY AUTOINCREMENT,
+			client_id   TEXT NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+			starts_at   DATETIME NOT NULL,
+			ends_at     DATETIME NOT NULL,
+			reason      TEXT NOT NULL DEFAULT '',
+			created_at  DATETIME NOT NULL DEFAULT (datetime('now'))
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_maintenance_windows_client ON maintenance_windows(client_id, ends_at)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateV21(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE clients ADD COLUMN tags TEXT NOT NULL DEFAULT '[]'`)
+	return err
+}
+
+func migrateV22(tx *sql.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS client_tokens (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		token_hash  TEXT NOT NULL UNIQUE,
+		label       TEXT NOT NULL DEFAULT '',
+		created_at  DATETIME NOT NULL DEFAULT (datetime('now')),
+		revoked     BOOLEAN NOT NULL DEFAULT 0,
+		revoked_at  DATETIME
+	)`)
+	return err
+}
+
+func migrateV23(tx *sql.Tx) error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS audit_log (
+			id          INTEGER PRIMARY KEY AUTOINCREMENT,
+			created_at  DATETIME NOT NULL DEFAULT (datetime('now')),
+			action      TEXT NOT NULL,
+			target      TEXT NOT NULL DEFAULT '',
+			detail      TEXT NOT NULL DEFAULT '',
+			source_ip   TEXT NOT NULL DEFAULT ''
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(created_at)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateV24(tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE metrics ADD COLUMN swap_pct REAL NOT NULL DEFAULT 0`,
+		`ALTER TABLE metrics ADD COLUMN swap_total_bytes INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE metrics ADD COLUMN swap_used_bytes INTEGER NOT NULL DEFAULT 0`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateV25(tx *sql.Tx) error {
+	// Nullable: hosts without a CPU sensor report no temperature at all.
+	_, err := tx.Exec(`ALTER TABLE metrics ADD COLUMN cpu_temp_c REAL`)
+	return err
+}
+
+func migrateV26(tx *sql.Tx) error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS process_restarts (
+			id            INTEGER PRIMARY KEY AUTOINCREMENT,
+			client_id     TEXT NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+			friendly_name TEXT NOT NULL,
+			restarted_at  DATETIME NOT NULL,
+			old_pid       INTEGER,
+			new_pid       INTEGER
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_process_restarts_client_time ON process_restarts(client_id, restarted_at)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateV27(tx *sql.Tx) error {
+	if _, err := tx.Exec(`ALTER TABLE clients ADD COLUMN last_collection_error TEXT NOT NULL DEFAULT ''`); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`ALTER TABLE clients ADD COLUMN last_collection_error_at DATETIME`)
+	return err
+}
+
+func migrateV28(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE clients ADD COLUMN check_in_interval_seconds INTEGER`)
+	return err
+}
+
+func migrateV29(tx *sql.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS api_tokens (
+		id            INTEGER PRIMARY KEY AUTOINCREMENT,
+		token_hash    TEXT NOT NULL UNIQUE,
+		label         TEXT NOT NULL DEFAULT '',
+		created_at    DATETIME NOT NULL DEFAULT (datetime('now')),
+		last_used_at  DATETIME,
+		revoked       BOOLEAN NOT NULL DEFAULT 0,
+		revoked_at    DATETIME
+	)`)
+	return err
+}
+
+func migrateV30(tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE watched_processes ADD COLUMN cpu_alert_pct REAL`,
+		`ALTER TABLE watched_processes ADD COLUMN mem_alert_pct REAL`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateV31(tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE watched_processes ADD COLUMN note TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE watched_processes ADD COLUMN expected_state TEXT NOT NULL DEFAULT 'running'`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateV32(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE clients ADD COLUMN checkin_latency_ms INTEGER`)
+	return err
+}
+
+func migrateV33(tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE clients ADD COLUMN suspended BOOLEAN NOT NULL DEFAULT 0`,
+		`ALTER TABLE clients ADD COLUMN suspend_reason TEXT NOT NULL DEFAULT ''`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateV34(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE alert_providers ADD COLUMN message_template TEXT NOT NULL DEFAULT ''`)
+	return err
+}
+
+func migrateV35(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE check_snapshots ADD COLUMN consecutive_failures INTEGER NOT NULL DEFAULT 0`)
+	return err
+}
+
+func migrateV36(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE watched_processes ADD COLUMN alert_on_start BOOLEAN NOT NULL DEFAULT 0`)
+	return err
+}
+
+func migrateV37(tx *sql.Tx) error {
+	// Nullable: not every platform can report an open file count.
+	stmts := []string{
+		`ALTER TABLE metrics ADD COLUMN open_fds INTEGER`,
+		`ALTER TABLE metrics ADD COLUMN process_count INTEGER`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateV38(tx *sql.Tx) error {
+	// Nullable: Windows and some filesystems have no inode counts.
+	_, err := tx.Exec(`ALTER TABLE metrics ADD COLUMN disk_inodes_pct REAL`)
+	return err
+}
+
+func migrateV39(tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE watched_processes ADD COLUMN min_instances INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE process_snapshots ADD COLUMN instance_count INTEGER NOT NULL DEFAULT 0`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateV40(tx *sql.Tx) error {
+	// Existing alerts keep an empty target and share one cooldown per type.
+	_, err := tx.Exec(`ALTER TABLE alerts ADD COLUMN target TEXT NOT NULL DEFAULT ''`)
+	return err
+}
+
+func migrateV41(tx *sql.Tx) error {
+	// NULL means due now, so alerts that failed before the upgrade get their
+	// next retry on the first pass.
+	_, err := tx.Exec(`ALTER TABLE alerts ADD COLUMN next_notify_attempt_at DATETIME`)
+	return err
+}
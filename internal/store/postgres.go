@@ -0,0 +1,98 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+// PostgresStore keeps MachineMon's data in PostgreSQL, for installations with
+// more clients than a single SQLite file comfortably handles.
+type PostgresStore struct {
+	*sqlStore
+}
+
+// NewPostgresStore connects with a libpq-style DSN or postgres:// URL and
+// applies any pending migrations.
+func NewPostgresStore(dsn string) (*PostgresStore, error) {
+	cfg, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("parse database dsn: %w", err)
+	}
+	// Timestamps written as text (e.g. "2006-01-02 15:04:05") are UTC, as they
+	// are in the SQLite store.
+	cfg.RuntimeParams["timezone"] = "UTC"
+	db := stdlib.OpenDB(*cfg)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ping database: %w", err)
+	}
+	s := &PostgresStore{sqlStore: &sqlStore{db: &sqlDB{DB: db, dialect: dialectPostgres}}}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	return s, nil
+}
+
+func (s *PostgresStore) migrate() error {
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	var current int
+	if err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+	for i := current; i < len(postgresMigrations); i++ {
+		tx, err := s.db.DB.Begin()
+		if err != nil {
+			return fmt.Errorf("begin tx for migration v%d: %w", i+1, err)
+		}
+		if err := postgresMigrations[i](tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration v%d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES ($1)`, i+1); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("record schema version %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("commit migration v%d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// DatabaseSize returns the size of the current database in bytes.
+func (s *PostgresStore) DatabaseSize() (int64, error) {
+	var size int64
+	if err := s.db.QueryRow(`SELECT pg_database_size(current_database())`).Scan(&size); err != nil {
+		return 0, fmt.Errorf("database size: %w", err)
+	}
+	return size, nil
+}
+
+// Vacuum is a no-op: autovacuum already makes space from deleted rows
+// reusable, and VACUUM FULL would lock each table while rewriting it.
+func (s *PostgresStore) Vacuum() error {
+	return nil
+}
+
+func (s *PostgresStore) DatabaseStats() (*models.DatabaseStats, error) {
+	size, err := s.DatabaseSize()
+	if err != nil {
+		return nil, err
+	}
+	stats := &models.DatabaseStats{Driver: DriverPostgres, SizeBytes: size}
+	tables, err := s.tableStats(`SELECT tablename FROM pg_tables WHERE schemaname = current_schema() ORDER BY tablename`)
+	if err != nil {
+		return nil, err
+	}
+	stats.Tables = tables
+	return stats, nil
+}
@@ -0,0 +1,441 @@
+package store
+
+import "database/sql"
+
+// postgresMigrations is the PostgreSQL schema history. It is versioned
+// separately from the SQLite migrations: V1 matches SQLite V18, and later
+// schema changes need a step in both lists.
+var postgresMigrations = []func(tx *sql.Tx) error{
+	migratePostgresV1,
+	migratePostgresV2,
+	migratePostgresV3,
+	migratePostgresV4,
+	migratePostgresV5,
+	migratePostgresV6,
+	migratePostgresV7,
+	migratePostgresV8,
+	migratePostgresV9,
+	migratePostgresV10,
+	migratePostgresV11,
+	migratePostgresV12,
+	migratePostgresV13,
+	migratePostgresV14,
+	migratePostgresV15,
+	migratePostgresV16,
+	migratePostgresV17,
+	migratePostgresV18,
+	migratePostgresV19,
+	migratePostgresV20,
+	migratePostgresV21,
+	migratePostgresV22,
+	migratePostgresV23,
+	migratePostgresV24,
+}
+
+func migratePostgresV1(tx *sql.Tx) error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS clients (
+			id                          TEXT PRIMARY KEY,
+			hostname                    TEXT NOT NULL,
+			custom_name                 TEXT NOT NULL DEFAULT '',
+			os                          TEXT NOT NULL DEFAULT '',
+			arch                        TEXT NOT NULL DEFAULT '',
+			client_version              TEXT NOT NULL DEFAULT '',
+			public_ip                   TEXT NOT NULL DEFAULT '',
+			interface_ips               TEXT NOT NULL DEFAULT '[]',
+			machine_id                  TEXT,
+			session_id                  TEXT,
+			previous_session_id         TEXT,
+			first_seen_at               TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			last_seen_at                TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			session_started_at          TIMESTAMPTZ,
+			is_online                   BOOLEAN NOT NULL DEFAULT TRUE,
+			is_deleted                  BOOLEAN NOT NULL DEFAULT FALSE,
+			cpu_warn_pct                DOUBLE PRECISION,
+			cpu_crit_pct                DOUBLE PRECISION,
+			mem_warn_pct                DOUBLE PRECISION,
+			mem_crit_pct                DOUBLE PRECISION,
+			disk_warn_pct               DOUBLE PRECISION,
+			disk_crit_pct               DOUBLE PRECISION,
+			offline_threshold_seconds   INTEGER,
+			metric_consecutive_checkins INTEGER,
+			alert_cooldown_seconds      INTEGER,
+			business_hours              TEXT,
+			reported_interval_seconds   INTEGER,
+			alerts_muted                BOOLEAN NOT NULL DEFAULT FALSE,
+			muted_until                 TIMESTAMPTZ,
+			mute_reason                 TEXT
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_clients_machine_id ON clients(machine_id)`,
+		`CREATE TABLE IF NOT EXISTS metrics (
+			id               BIGSERIAL PRIMARY KEY,
+			client_id        TEXT NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+			recorded_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			cpu_pct          DOUBLE PRECISION NOT NULL,
+			mem_pct          DOUBLE PRECISION NOT NULL,
+			disk_pct         DOUBLE PRECISION NOT NULL,
+			mem_total_bytes  BIGINT NOT NULL DEFAULT 0,
+			mem_used_bytes   BIGINT NOT NULL DEFAULT 0,
+			disk_total_bytes BIGINT NOT NULL DEFAULT 0,
+			disk_used_bytes  BIGINT NOT NULL DEFAULT 0,
+			net_rx_bytes     BIGINT NOT NULL DEFAULT 0,
+			net_tx_bytes     BIGINT NOT NULL DEFAULT 0
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_metrics_client_time ON metrics(client_id, recorded_at)`,
+		`CREATE TABLE IF NOT EXISTS disk_mounts (
+			client_id    TEXT NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+			mount_path   TEXT NOT NULL,
+			recorded_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			total_bytes  BIGINT NOT NULL DEFAULT 0,
+			used_bytes   BIGINT NOT NULL DEFAULT 0,
+			used_pct     DOUBLE PRECISION NOT NULL DEFAULT 0,
+			warn_pct     DOUBLE PRECISION,
+			crit_pct     DOUBLE PRECISION,
+			error        TEXT NOT NULL DEFAULT '',
+			alert_state  TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (client_id, mount_path)
+		)`,
+		`CREATE TABLE IF NOT EXISTS watched_processes (
+			id            BIGSERIAL PRIMARY KEY,
+			client_id     TEXT NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+			friendly_name TEXT NOT NULL,
+			match_pattern TEXT NOT NULL,
+			match_type    TEXT NOT NULL DEFAULT 'substring',
+			UNIQUE(client_id, friendly_name)
+		)`,
+		`CREATE TABLE IF NOT EXISTS process_snapshots (
+			id              BIGSERIAL PRIMARY KEY,
+			client_id       TEXT NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+			friendly_name   TEXT NOT NULL,
+			recorded_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			uptime_since_at TIMESTAMPTZ,
+			is_running      BOOLEAN NOT NULL,
+			pid             INTEGER,
+			cpu_pct         DOUBLE PRECISION,
+			mem_pct         DOUBLE PRECISION,
+			cmdline         TEXT,
+			num_fds         INTEGER,
+			num_threads     INTEGER
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_process_snap_client_time ON process_snapshots(client_id, recorded_at)`,
+		`CREATE TABLE IF NOT EXISTS check_snapshots (
+			id              BIGSERIAL PRIMARY KEY,
+			client_id       TEXT NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+			friendly_name   TEXT NOT NULL,
+			check_type      TEXT NOT NULL DEFAULT 'script',
+			recorded_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			uptime_since_at TIMESTAMPTZ,
+			healthy         BOOLEAN NOT NULL,
+			message         TEXT,
+			state           TEXT,
+			severity        TEXT
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_check_snap_client_time ON check_snapshots(client_id, recorded_at)`,
+		`CREATE TABLE IF NOT EXISTS alerts (
+			id                     BIGSERIAL PRIMARY KEY,
+			client_id              TEXT NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+			alert_type             TEXT NOT NULL,
+			severity               TEXT NOT NULL,
+			message                TEXT NOT NULL,
+			details                TEXT,
+			fired_at               TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			notified               BOOLEAN NOT NULL DEFAULT FALSE,
+			notified_at            TIMESTAMPTZ,
+			notify_attempts        INTEGER NOT NULL DEFAULT 0,
+			last_notify_attempt_at TIMESTAMPTZ
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_alerts_client_time ON alerts(client_id, fired_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_alerts_unnotified ON alerts(notified) WHERE NOT notified`,
+		`CREATE TABLE IF NOT EXISTS alert_providers (
+			id         BIGSERIAL PRIMARY KEY,
+			type       TEXT NOT NULL,
+			name       TEXT NOT NULL UNIQUE,
+			enabled    BOOLEAN NOT NULL DEFAULT TRUE,
+			config     TEXT NOT NULL DEFAULT '{}',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE TABLE IF NOT EXISTS client_alert_mutes (
+			id         BIGSERIAL PRIMARY KEY,
+			client_id  TEXT NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+			scope      TEXT NOT NULL,
+			target     TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			UNIQUE(client_id, scope, target)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_client_alert_mutes_client ON client_alert_mutes(client_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_client_alert_mutes_scope ON client_alert_mutes(client_id, scope)`,
+		`CREATE TABLE IF NOT EXISTS global_settings (
+			key   TEXT PRIMARY KEY,
+			value TEXT NOT NULL
+		)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migratePostgresV2 matches SQLite V19.
+func migratePostgresV2(tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE alerts ADD COLUMN acked BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE alerts ADD COLUMN acked_at TIMESTAMPTZ`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migratePostgresV3 matches SQLite V20.
+func migratePostgresV3(tx *sql.Tx) error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS maintenance_windows (
+			id         BIGSERIAL PRIMARY KEY,
+			client_id  TEXT NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+			starts_at  TIMESTAMPTZ NOT NULL,
+			ends_at    TIMESTAMPTZ NOT NULL,
+			reason     TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_maintenance_windows_client ON maintenance_windows(client_id, ends_at)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migratePostgresV4 matches SQLite V21.
+func migratePostgresV4(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE clients ADD COLUMN IF NOT EXISTS tags TEXT NOT NULL DEFAULT '[]'`)
+	return err
+}
+
+// migratePostgresV5 matches SQLite V22.
+func migratePostgresV5(tx *sql.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS client_tokens (
+		id          BIGSERIAL PRIMARY KEY,
+		token_hash  TEXT NOT NULL UNIQUE,
+		label       TEXT NOT NULL DEFAULT '',
+		created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		revoked     BOOLEAN NOT NULL DEFAULT FALSE,
+		revoked_at  TIMESTAMPTZ
+	)`)
+	return err
+}
+
+// migratePostgresV6 matches SQLite V23.
+func migratePostgresV6(tx *sql.Tx) error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS audit_log (
+			id          BIGSERIAL PRIMARY KEY,
+			created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			action      TEXT NOT NULL,
+			target      TEXT NOT NULL DEFAULT '',
+			detail      TEXT NOT NULL DEFAULT '',
+			source_ip   TEXT NOT NULL DEFAULT ''
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(created_at)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migratePostgresV7 matches SQLite V24.
+func migratePostgresV7(tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE metrics ADD COLUMN IF NOT EXISTS swap_pct DOUBLE PRECISION NOT NULL DEFAULT 0`,
+		`ALTER TABLE metrics ADD COLUMN IF NOT EXISTS swap_total_bytes BIGINT NOT NULL DEFAULT 0`,
+		`ALTER TABLE metrics ADD COLUMN IF NOT EXISTS swap_used_bytes BIGINT NOT NULL DEFAULT 0`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migratePostgresV8 matches SQLite V25.
+func migratePostgresV8(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE metrics ADD COLUMN IF NOT EXISTS cpu_temp_c DOUBLE PRECISION`)
+	return err
+}
+
+// migratePostgresV9 matches SQLite V26.
+func migratePostgresV9(tx *sql.Tx) error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS process_restarts (
+			id            BIGSERIAL PRIMARY KEY,
+			client_id     TEXT NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+			friendly_name TEXT NOT NULL,
+			restarted_at  TIMESTAMPTZ NOT NULL,
+			old_pid       INTEGER,
+			new_pid       INTEGER
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_process_restarts_client_time ON process_restarts(client_id, restarted_at)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migratePostgresV10 matches SQLite V27.
+func migratePostgresV10(tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE clients ADD COLUMN IF NOT EXISTS last_collection_error TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE clients ADD COLUMN IF NOT EXISTS last_collection_error_at TIMESTAMPTZ`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migratePostgresV11 matches SQLite V28.
+func migratePostgresV11(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE clients ADD COLUMN IF NOT EXISTS check_in_interval_seconds INTEGER`)
+	return err
+}
+
+// migratePostgresV12 matches SQLite V29.
+func migratePostgresV12(tx *sql.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS api_tokens (
+		id            BIGSERIAL PRIMARY KEY,
+		token_hash    TEXT NOT NULL UNIQUE,
+		label         TEXT NOT NULL DEFAULT '',
+		created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		last_used_at  TIMESTAMPTZ,
+		revoked       BOOLEAN NOT NULL DEFAULT FALSE,
+		revoked_at    TIMESTAMPTZ
+	)`)
+	return err
+}
+
+// migratePostgresV13 matches SQLite V30.
+func migratePostgresV13(tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE watched_processes ADD COLUMN IF NOT EXISTS cpu_alert_pct DOUBLE PRECISION`,
+		`ALTER TABLE watched_processes ADD COLUMN IF NOT EXISTS mem_alert_pct DOUBLE PRECISION`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migratePostgresV14 matches SQLite V31.
+func migratePostgresV14(tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE watched_processes ADD COLUMN IF NOT EXISTS note TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE watched_processes ADD COLUMN IF NOT EXISTS expected_state TEXT NOT NULL DEFAULT 'running'`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migratePostgresV15 matches SQLite V32.
+func migratePostgresV15(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE clients ADD COLUMN IF NOT EXISTS checkin_latency_ms INTEGER`)
+	return err
+}
+
+// migratePostgresV16 matches SQLite V33.
+func migratePostgresV16(tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE clients ADD COLUMN IF NOT EXISTS suspended BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE clients ADD COLUMN IF NOT EXISTS suspend_reason TEXT NOT NULL DEFAULT ''`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migratePostgresV17 matches SQLite V34.
+func migratePostgresV17(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE alert_providers ADD COLUMN IF NOT EXISTS message_template TEXT NOT NULL DEFAULT ''`)
+	return err
+}
+
+// migratePostgresV18 matches SQLite V35.
+func migratePostgresV18(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE check_snapshots ADD COLUMN IF NOT EXISTS consecutive_failures INTEGER NOT NULL DEFAULT 0`)
+	return err
+}
+
+// migratePostgresV19 matches SQLite V36.
+func migratePostgresV19(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE watched_processes ADD COLUMN IF NOT EXISTS alert_on_start BOOLEAN NOT NULL DEFAULT FALSE`)
+	return err
+}
+
+// migratePostgresV20 matches SQLite V37.
+func migratePostgresV20(tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE metrics ADD COLUMN IF NOT EXISTS open_fds BIGINT`,
+		`ALTER TABLE metrics ADD COLUMN IF NOT EXISTS process_count BIGINT`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migratePostgresV21 matches SQLite V38.
+func migratePostgresV21(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE metrics ADD COLUMN IF NOT EXISTS disk_inodes_pct DOUBLE PRECISION`)
+	return err
+}
+
+// migratePostgresV22 matches SQLite V39.
+func migratePostgresV22(tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE watched_processes ADD COLUMN IF NOT EXISTS min_instances INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE process_snapshots ADD COLUMN IF NOT EXISTS instance_count INTEGER NOT NULL DEFAULT 0`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migratePostgresV23 matches SQLite V40.
+func migratePostgresV23(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS target TEXT NOT NULL DEFAULT ''`)
+	return err
+}
+
+// migratePostgresV24 matches SQLite V41.
+func migratePostgresV24(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS next_notify_attempt_at TIMESTAMPTZ`)
+	return err
+}
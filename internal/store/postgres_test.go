@@ -0,0 +1,51 @@
+package store
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+// Runs against a scratch database when MACHINEMON_TEST_POSTGRES_DSN is set;
+// the schema is created in place, so don't point it at real data.
+func TestPostgresStoreRoundTrip(t *testing.T) {
+	dsn := os.Getenv("MACHINEMON_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("MACHINEMON_TEST_POSTGRES_DSN not set")
+	}
+	st, err := NewPostgresStore(dsn)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer st.Close()
+
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "pg-1", MachineID: "pg-test"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	t.Cleanup(func() { st.db.Exec(`DELETE FROM clients WHERE id = ?`, res.ClientID) })
+
+	if err := st.InsertMetrics(res.ClientID, models.MetricsPayload{CPUPercent: 42, MemUsedBytes: 1 << 40}); err != nil {
+		t.Fatalf("insert metrics: %v", err)
+	}
+	metrics, err := st.GetMetrics(res.ClientID, time.Now().Add(-time.Hour), time.Now().Add(time.Minute), 10)
+	if err != nil {
+		t.Fatalf("get metrics: %v", err)
+	}
+	if len(metrics) != 1 || metrics[0].CPUPercent != 42 || metrics[0].MemUsedBytes != 1<<40 {
+		t.Fatalf("unexpected metrics: %+v", metrics)
+	}
+
+	a := &models.Alert{ClientID: res.ClientID, AlertType: models.AlertTypeOffline, Severity: models.SeverityCritical, Message: "offline"}
+	if err := st.InsertAlert(a); err != nil || a.ID == 0 {
+		t.Fatalf("insert alert: id=%d err=%v", a.ID, err)
+	}
+	if err := st.MarkAlertNotified(a.ID); err != nil {
+		t.Fatalf("mark notified: %v", err)
+	}
+	if _, err := st.GetStaleOnlineClients(60); err != nil {
+		t.Fatalf("stale clients: %v", err)
+	}
+}
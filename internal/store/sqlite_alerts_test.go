@@ -0,0 +1,236 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestAckAlertAndFilterOutstanding(t *testing.T) {
+	st := newTestStore(t)
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	first := &models.Alert{ClientID: res.ClientID, AlertType: models.AlertTypeOffline, Severity: models.SeverityCritical, Message: "offline"}
+	second := &models.Alert{ClientID: res.ClientID, AlertType: models.AlertTypeOnline, Severity: models.SeverityInfo, Message: "online"}
+	for _, a := range []*models.Alert{first, second} {
+		if err := st.InsertAlert(a); err != nil {
+			t.Fatalf("insert alert: %v", err)
+		}
+	}
+
+	found, err := st.AckAlert(first.ID)
+	if err != nil || !found {
+		t.Fatalf("ack: found=%v err=%v", found, err)
+	}
+	if found, err := st.AckAlert(9999); err != nil || found {
+		t.Fatalf("ack missing alert: found=%v err=%v", found, err)
+	}
+
+	no := false
+	outstanding, total, err := st.ListAlerts(AlertFilter{Acked: &no}, 10, 0)
+	if err != nil || total != 1 || outstanding[0].ID != second.ID || outstanding[0].Acked {
+		t.Fatalf("outstanding: err=%v total=%d %+v", err, total, outstanding)
+	}
+
+	all, _, err := st.ListAlerts(AlertFilter{ClientID: res.ClientID}, 10, 0)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	for _, a := range all {
+		if a.ID == first.ID && (!a.Acked || a.AckedAt == nil) {
+			t.Fatalf("expected first alert acked with timestamp: %+v", a)
+		}
+	}
+}
+
+func TestGetAlertsDueForRetry(t *testing.T) {
+	st := newTestStore(t)
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	insert := func(msg string) *models.Alert {
+		a := &models.Alert{ClientID: res.ClientID, AlertType: models.AlertTypeOffline, Severity: models.SeverityCritical, Message: msg}
+		if err := st.InsertAlert(a); err != nil {
+			t.Fatalf("insert alert: %v", err)
+		}
+		return a
+	}
+	now := time.Now().UTC()
+	fresh := insert("never attempted")
+	due := insert("due")
+	waiting := insert("backing off")
+	exhausted := insert("out of attempts")
+	delivered := insert("delivered")
+	if err := st.RecordAlertNotifyAttempt(due.ID, now.Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.RecordAlertNotifyAttempt(waiting.ID, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := st.RecordAlertNotifyAttempt(exhausted.ID, now.Add(-time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := st.RecordAlertNotifyAttempt(delivered.ID, now.Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.MarkAlertNotified(delivered.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	alerts, err := st.GetAlertsDueForRetry(3, now)
+	if err != nil {
+		t.Fatalf("due for retry: %v", err)
+	}
+	if len(alerts) != 1 || alerts[0].ID != due.ID || alerts[0].NotifyAttempts != 1 {
+		t.Fatalf("want only alert %d due (fresh %d), got %+v", due.ID, fresh.ID, alerts)
+	}
+	if alerts, _ := st.GetAlertsDueForRetry(3, now.Add(2*time.Minute)); len(alerts) != 2 {
+		t.Fatalf("expected the backed-off alert to be due later, got %d alerts", len(alerts))
+	}
+}
+
+func TestListAlertsByTypeAndTimeRange(t *testing.T) {
+	st := newTestStore(t)
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, typ := range []string{models.AlertTypeProcessDied, models.AlertTypeOffline, models.AlertTypeProcessDied, models.AlertTypeProcessDied} {
+		a := &models.Alert{ClientID: res.ClientID, AlertType: typ, Severity: models.SeverityWarning, Message: typ}
+		if err := st.InsertAlert(a); err != nil {
+			t.Fatalf("insert alert: %v", err)
+		}
+		firedAt := base.Add(time.Duration(i) * time.Hour).Format("2006-01-02 15:04:05")
+		if _, err := st.db.Exec(`UPDATE alerts SET fired_at = ? WHERE id = ?`, firedAt, a.ID); err != nil {
+			t.Fatalf("backdate alert: %v", err)
+		}
+	}
+
+	alerts, total, err := st.ListAlerts(AlertFilter{AlertType: models.AlertTypeProcessDied}, 10, 0)
+	if err != nil || total != 3 || len(alerts) != 3 {
+		t.Fatalf("by type: err=%v total=%d %+v", err, total, alerts)
+	}
+
+	alerts, total, err = st.ListAlerts(AlertFilter{
+		AlertType: models.AlertTypeProcessDied,
+		From:      base.Add(time.Hour),
+		To:        base.Add(2 * time.Hour),
+	}, 10, 0)
+	if err != nil || total != 1 || len(alerts) != 1 || !alerts[0].FiredAt.Equal(base.Add(2*time.Hour)) {
+		t.Fatalf("by type and range: err=%v total=%d %+v", err, total, alerts)
+	}
+
+	// Pagination still reports the full count.
+	alerts, total, err = st.ListAlerts(AlertFilter{From: base.Add(time.Hour)}, 1, 1)
+	if err != nil || total != 3 || len(alerts) != 1 || !alerts[0].FiredAt.Equal(base.Add(2*time.Hour)) {
+		t.Fatalf("paged: err=%v total=%d %+v", err, total, alerts)
+	}
+}
+
+func TestListAlertsSince(t *testing.T) {
+	st := newTestStore(t)
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	other, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-2"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, clientID := range []string{res.ClientID, res.ClientID, other.ClientID, res.ClientID} {
+		a := &models.Alert{ClientID: clientID, AlertType: models.AlertTypeOffline, Severity: models.SeverityCritical, Message: "gone"}
+		if err := st.InsertAlert(a); err != nil {
+			t.Fatalf("insert alert: %v", err)
+		}
+		firedAt := base.Add(time.Duration(i) * time.Hour).Format("2006-01-02 15:04:05")
+		if _, err := st.db.Exec(`UPDATE alerts SET fired_at = ? WHERE id = ?`, firedAt, a.ID); err != nil {
+			t.Fatalf("backdate alert: %v", err)
+		}
+	}
+
+	alerts, err := st.ListAlertsSince(res.ClientID, base.Add(time.Hour), 10)
+	if err != nil || len(alerts) != 2 || !alerts[0].FiredAt.Equal(base.Add(3*time.Hour)) {
+		t.Fatalf("since: err=%v %+v", err, alerts)
+	}
+	if alerts, err := st.ListAlertsSince(res.ClientID, base, 1); err != nil || len(alerts) != 1 {
+		t.Fatalf("limit: err=%v %+v", err, alerts)
+	}
+}
+
+func TestMaintenanceWindowsActiveAndExpired(t *testing.T) {
+	st := newTestStore(t)
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	now := time.Now().UTC()
+	windows := []*models.MaintenanceWindow{
+		{ClientID: res.ClientID, StartsAt: now.Add(-3 * time.Hour), EndsAt: now.Add(-2 * time.Hour), Reason: "expired"},
+		{ClientID: res.ClientID, StartsAt: now.Add(-10 * time.Minute), EndsAt: now.Add(20 * time.Minute), Reason: "reboot"},
+		{ClientID: res.ClientID, StartsAt: now.Add(time.Hour), EndsAt: now.Add(2 * time.Hour), Reason: "upgrade"},
+	}
+	for _, w := range windows {
+		if err := st.CreateMaintenanceWindow(w); err != nil {
+			t.Fatalf("create window: %v", err)
+		}
+	}
+
+	active, err := st.GetActiveMaintenanceWindow(res.ClientID, now)
+	if err != nil || active == nil || active.Reason != "reboot" {
+		t.Fatalf("active window: err=%v %+v", err, active)
+	}
+	if w, err := st.GetActiveMaintenanceWindow(res.ClientID, now.Add(30*time.Minute)); err != nil || w != nil {
+		t.Fatalf("expected no window between reboot and upgrade: err=%v %+v", err, w)
+	}
+
+	listed, err := st.ListMaintenanceWindows(res.ClientID)
+	if err != nil || len(listed) != 2 || listed[0].Reason != "reboot" || listed[1].Reason != "upgrade" {
+		t.Fatalf("list should skip expired windows: err=%v %+v", err, listed)
+	}
+
+	if found, err := st.DeleteMaintenanceWindow("other-client", windows[1].ID); err != nil || found {
+		t.Fatalf("delete scoped to client: found=%v err=%v", found, err)
+	}
+	if found, err := st.DeleteMaintenanceWindow(res.ClientID, windows[1].ID); err != nil || !found {
+		t.Fatalf("delete: found=%v err=%v", found, err)
+	}
+	if w, _ := st.GetActiveMaintenanceWindow(res.ClientID, now); w != nil {
+		t.Fatalf("deleted window still active: %+v", w)
+	}
+}
+
+func TestGetRecentCheckSnapshotsNewestFirst(t *testing.T) {
+	st := newTestStore(t)
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	for _, healthy := range []bool{true, false, false} {
+		err := st.InsertCheckSnapshots(res.ClientID, []models.CheckPayload{
+			{FriendlyName: "api", CheckType: models.CheckTypeHTTP, Healthy: healthy, ConsecutiveFailures: 2},
+			{FriendlyName: "db", CheckType: models.CheckTypeScript, Healthy: true},
+		})
+		if err != nil {
+			t.Fatalf("insert checks: %v", err)
+		}
+	}
+
+	snaps, err := st.GetRecentCheckSnapshots(res.ClientID, "api", models.CheckTypeHTTP, 2)
+	if err != nil {
+		t.Fatalf("recent snapshots: %v", err)
+	}
+	if len(snaps) != 2 || snaps[0].Healthy || snaps[1].Healthy {
+		t.Fatalf("want the two newest (failing) snapshots, got %+v", snaps)
+	}
+	if snaps[0].FriendlyName != "api" || snaps[0].ConsecutiveFailures != 2 {
+		t.Fatalf("snapshot = %+v", snaps[0])
+	}
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestAuditLogPaginationAndRetention(t *testing.T) {
+	st := newTestStore(t)
+
+	old := &models.AuditEntry{CreatedAt: time.Now().Add(-48 * time.Hour), Action: "client.delete", Target: "old"}
+	if err := st.InsertAuditEntry(old); err != nil {
+		t.Fatalf("insert old entry: %v", err)
+	}
+	for _, target := range []string{"a", "b", "c"} {
+		e := &models.AuditEntry{Action: "client.rename", Target: target, Detail: "x", SourceIP: "10.0.0.1"}
+		if err := st.InsertAuditEntry(e); err != nil || e.ID == 0 {
+			t.Fatalf("insert entry: id=%d err=%v", e.ID, err)
+		}
+	}
+
+	page, total, err := st.ListAuditEntries(2, 0)
+	if err != nil || total != 4 || len(page) != 2 {
+		t.Fatalf("first page: err=%v total=%d len=%d", err, total, len(page))
+	}
+	if page[0].Target != "c" || page[0].SourceIP != "10.0.0.1" {
+		t.Fatalf("expected newest entry first, got %+v", page[0])
+	}
+
+	day := 24 * time.Hour
+	if _, err := st.PruneOldData(RetentionWindows{Metrics: day, Alerts: day, Audit: day, ProcessRestarts: day, Maintenance: day}); err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	entries, total, err := st.ListAuditEntries(10, 0)
+	if err != nil || total != 3 {
+		t.Fatalf("after prune: err=%v total=%d", err, total)
+	}
+	for _, e := range entries {
+		if e.Target == "old" {
+			t.Fatalf("expected old entry to be pruned")
+		}
+	}
+}
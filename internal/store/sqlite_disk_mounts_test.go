@@ -0,0 +1,54 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestInsertDiskMountsReplacesSetAndKeepsAlertState(t *testing.T) {
+	st := newTestStore(t)
+
+	client, err := st.UpsertClient(models.CheckInRequest{Hostname: "db-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	id := client.ClientID
+
+	if err := st.InsertDiskMounts(id, []models.DiskMountPayload{
+		{Path: "/data", UsedPercent: 91, WarnPct: 85},
+		{Path: "/backup", UsedPercent: 10},
+	}); err != nil {
+		t.Fatalf("insert mounts: %v", err)
+	}
+	if err := st.SetDiskMountAlertState(id, "/data", "warn"); err != nil {
+		t.Fatalf("set alert state: %v", err)
+	}
+
+	// /backup removed from the client config.
+	if err := st.InsertDiskMounts(id, []models.DiskMountPayload{{Path: "/data", UsedPercent: 92, WarnPct: 85}}); err != nil {
+		t.Fatalf("insert mounts: %v", err)
+	}
+
+	mounts, err := st.GetLatestDiskMounts(id)
+	if err != nil {
+		t.Fatalf("get mounts: %v", err)
+	}
+	if len(mounts) != 1 || mounts[0].Path != "/data" {
+		t.Fatalf("expected only /data, got %+v", mounts)
+	}
+	if mounts[0].UsedPercent != 92 || mounts[0].AlertState != "warn" {
+		t.Fatalf("expected updated usage and preserved alert state, got %+v", mounts[0])
+	}
+	if mounts[0].WarnPct == nil || *mounts[0].WarnPct != 85 || mounts[0].CritPct != nil {
+		t.Fatalf("unexpected thresholds: warn=%v crit=%v", mounts[0].WarnPct, mounts[0].CritPct)
+	}
+
+	if err := st.InsertDiskMounts(id, nil); err != nil {
+		t.Fatalf("clear mounts: %v", err)
+	}
+	mounts, _ = st.GetLatestDiskMounts(id)
+	if len(mounts) != 0 {
+		t.Fatalf("expected no mounts after clearing, got %d", len(mounts))
+	}
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestListClientsSearchAndPagination(t *testing.T) {
+	st := newTestStore(t)
+	for _, h := range []string{"web-1", "web-2", "db_1", "cache"} {
+		if _, err := st.UpsertClient(models.CheckInRequest{Hostname: h}, ""); err != nil {
+			t.Fatalf("upsert %s: %v", h, err)
+		}
+	}
+	cache, _, err := st.ListClients(ClientFilter{Query: "cache"}, 0, 0)
+	if err != nil || len(cache) != 1 {
+		t.Fatalf("search cache: %v %+v", err, cache)
+	}
+	if err := st.SetClientCustomName(cache[0].ID, "Redis WEB Cache"); err != nil {
+		t.Fatalf("set custom name: %v", err)
+	}
+
+	all, total, err := st.ListClients(ClientFilter{}, 0, 0)
+	if err != nil || len(all) != 4 || total != 4 {
+		t.Fatalf("list all: err=%v len=%d total=%d", err, len(all), total)
+	}
+
+	web, total, err := st.ListClients(ClientFilter{Query: "Web"}, 0, 0)
+	if err != nil || total != 3 || len(web) != 3 {
+		t.Fatalf("search web (hostname or custom name): err=%v len=%d total=%d", err, len(web), total)
+	}
+
+	// _ must match literally, not as a LIKE wildcard.
+	if _, total, _ := st.ListClients(ClientFilter{Query: "b_"}, 0, 0); total != 1 {
+		t.Fatalf("expected literal underscore match, total=%d", total)
+	}
+
+	page, total, err := st.ListClients(ClientFilter{}, 2, 2)
+	if err != nil || total != 4 || len(page) != 2 {
+		t.Fatalf("page: err=%v len=%d total=%d", err, len(page), total)
+	}
+	if page[0].ID != all[2].ID || page[1].ID != all[3].ID {
+		t.Fatalf("page does not match ordering of full list")
+	}
+}
+
+func TestClientTagsFilter(t *testing.T) {
+	st := newTestStore(t)
+	ids := map[string]string{}
+	for _, h := range []string{"web-1", "web-2", "db-1"} {
+		res, err := st.UpsertClient(models.CheckInRequest{Hostname: h}, "")
+		if err != nil {
+			t.Fatalf("upsert %s: %v", h, err)
+		}
+		ids[h] = res.ClientID
+	}
+	if err := st.SetClientTags(ids["web-1"], []string{"Web", " prod ", "web"}); err != nil {
+		t.Fatalf("set tags: %v", err)
+	}
+	if err := st.SetClientTags(ids["web-2"], []string{"web", "staging"}); err != nil {
+		t.Fatalf("set tags: %v", err)
+	}
+	if err := st.SetClientTags(ids["db-1"], []string{"web_db"}); err != nil {
+		t.Fatalf("set tags: %v", err)
+	}
+
+	c, err := st.GetClient(ids["web-1"])
+	if err != nil {
+		t.Fatalf("get client: %v", err)
+	}
+	if len(c.Tags) != 2 || c.Tags[0] != "prod" || c.Tags[1] != "web" {
+		t.Fatalf("expected normalized tags [prod web], got %v", c.Tags)
+	}
+
+	web, err := st.GetClientsByTag("WEB")
+	if err != nil || len(web) != 2 {
+		t.Fatalf("clients tagged web: err=%v %+v", err, web)
+	}
+
+	// A tag must match whole, not as a substring of another tag.
+	if _, total, _ := st.ListClients(ClientFilter{Tag: "db"}, 0, 0); total != 0 {
+		t.Fatalf("expected no clients tagged db, total=%d", total)
+	}
+	list, total, err := st.ListClients(ClientFilter{Query: "web", Tag: "prod"}, 0, 0)
+	if err != nil || total != 1 || list[0].ID != ids["web-1"] || len(list[0].Tags) != 2 {
+		t.Fatalf("query+tag filter: err=%v total=%d %+v", err, total, list)
+	}
+
+	if err := st.SetClientTags(ids["web-1"], nil); err != nil {
+		t.Fatalf("clear tags: %v", err)
+	}
+	if web, _ := st.GetClientsByTag("web"); len(web) != 1 {
+		t.Fatalf("expected one client tagged web after clearing, got %d", len(web))
+	}
+}
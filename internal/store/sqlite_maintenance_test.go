@@ -0,0 +1,98 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestVacuumReclaimsFreePages(t *testing.T) {
+	st := newTestStore(t)
+
+	client, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	procs := make([]models.ProcessPayload, 50)
+	for i := range procs {
+		procs[i] = models.ProcessPayload{FriendlyName: "p", MatchPattern: "p", Cmdline: string(make([]byte, 512))}
+	}
+	for i := 0; i < 20; i++ {
+		if err := st.InsertProcessSnapshots(client.ClientID, procs); err != nil {
+			t.Fatalf("insert snapshots: %v", err)
+		}
+	}
+	if err := st.ClearClientMetrics(client.ClientID); err != nil {
+		t.Fatalf("clear metrics: %v", err)
+	}
+
+	before, err := st.DatabaseStats()
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if before.Driver != DriverSQLite || before.SizeBytes == 0 || before.FreeBytes == 0 {
+		t.Fatalf("expected free pages after deleting rows, got %+v", before)
+	}
+
+	if err := st.Vacuum(); err != nil {
+		t.Fatalf("vacuum: %v", err)
+	}
+	after, err := st.DatabaseStats()
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if after.FreeBytes != 0 || after.SizeBytes >= before.SizeBytes {
+		t.Fatalf("expected vacuum to shrink the file: before %+v, after %+v", before, after)
+	}
+
+	rows := map[string]int64{}
+	for _, tbl := range after.Tables {
+		rows[tbl.Name] = tbl.Rows
+	}
+	if n, ok := rows["clients"]; !ok || n != 1 {
+		t.Fatalf("expected 1 client row, got %v", rows)
+	}
+	if n, ok := rows["process_snapshots"]; !ok || n != 0 {
+		t.Fatalf("expected no process snapshot rows, got %v", rows)
+	}
+}
+
+func TestPruneOldDataUsesOperationalWindows(t *testing.T) {
+	st := newTestStore(t)
+
+	client, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	id := client.ClientID
+	now := time.Now().UTC()
+	for _, age := range []time.Duration{72 * time.Hour, 12 * time.Hour} {
+		if _, err := st.db.Exec(`INSERT INTO process_restarts (client_id, friendly_name, restarted_at, old_pid, new_pid) VALUES (?, 'api', ?, 1, 2)`, id, now.Add(-age)); err != nil {
+			t.Fatalf("insert restart: %v", err)
+		}
+		w := &models.MaintenanceWindow{ClientID: id, StartsAt: now.Add(-age - time.Hour), EndsAt: now.Add(-age)}
+		if err := st.CreateMaintenanceWindow(w); err != nil {
+			t.Fatalf("create window: %v", err)
+		}
+	}
+
+	year := 365 * 24 * time.Hour
+	deleted, err := st.PruneOldData(RetentionWindows{Metrics: year, Alerts: year, Audit: year,
+		ProcessRestarts: 24 * time.Hour, Maintenance: 48 * time.Hour})
+	if err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if deleted != 2 {
+		t.Fatalf("expected the 3-day-old restart and window to be pruned, deleted %d", deleted)
+	}
+	var restarts, windows int
+	if err := st.db.QueryRow(`SELECT
+		(SELECT COUNT(*) FROM process_restarts),
+		(SELECT COUNT(*) FROM maintenance_windows)`).Scan(&restarts, &windows); err != nil {
+		t.Fatal(err)
+	}
+	if restarts != 1 || windows != 1 {
+		t.Fatalf("expected one recent restart and window left, got %d and %d", restarts, windows)
+	}
+}
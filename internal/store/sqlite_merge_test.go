@@ -0,0 +1,199 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func newTestStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	st, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+func TestMergeClientsMovesHistoryAndSoftDeletesSource(t *testing.T) {
+	st := newTestStore(t)
+
+	source, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert source: %v", err)
+	}
+	target, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert target: %v", err)
+	}
+	sourceID, targetID := source.ClientID, target.ClientID
+
+	if err := st.InsertMetrics(sourceID, models.MetricsPayload{CPUPercent: 10}); err != nil {
+		t.Fatalf("insert metrics: %v", err)
+	}
+	if err := st.InsertAlert(&models.Alert{ClientID: sourceID, AlertType: models.AlertTypeOffline, Severity: models.SeverityCritical, Message: "offline"}); err != nil {
+		t.Fatalf("insert alert: %v", err)
+	}
+	procs := []models.ProcessPayload{{FriendlyName: "api", MatchPattern: "api", IsRunning: true, PID: 42}}
+	if err := st.UpsertWatchedProcesses(sourceID, procs); err != nil {
+		t.Fatalf("upsert watched: %v", err)
+	}
+	if err := st.UpsertWatchedProcesses(targetID, procs); err != nil {
+		t.Fatalf("upsert watched: %v", err)
+	}
+	// A PID change records a restart against the source.
+	for _, pid := range []int32{42, 43} {
+		procs[0].PID = pid
+		if err := st.InsertProcessSnapshots(sourceID, procs); err != nil {
+			t.Fatalf("insert snapshots: %v", err)
+		}
+		if _, err := st.db.Exec(`UPDATE process_snapshots SET recorded_at = datetime(recorded_at, '-1 minute')`); err != nil {
+			t.Fatalf("age snapshots: %v", err)
+		}
+	}
+	if err := st.InsertDiskMounts(sourceID, []models.DiskMountPayload{{Path: "/data", UsedPercent: 40}, {Path: "/", UsedPercent: 10}}); err != nil {
+		t.Fatalf("insert source mounts: %v", err)
+	}
+	if err := st.InsertDiskMounts(targetID, []models.DiskMountPayload{{Path: "/", UsedPercent: 20}}); err != nil {
+		t.Fatalf("insert target mounts: %v", err)
+	}
+
+	if err := st.MergeClients(sourceID, targetID); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+
+	latest, err := st.GetLatestMetrics(targetID)
+	if err != nil || latest == nil {
+		t.Fatalf("expected merged metrics on target, got %v (err %v)", latest, err)
+	}
+	alerts, total, err := st.ListAlerts(AlertFilter{ClientID: targetID}, 10, 0)
+	if err != nil || total != 1 || len(alerts) != 1 {
+		t.Fatalf("expected 1 merged alert, got %d (err %v)", total, err)
+	}
+	watched, err := st.GetWatchedProcesses(targetID)
+	if err != nil || len(watched) != 1 {
+		t.Fatalf("expected 1 watched process on target, got %d (err %v)", len(watched), err)
+	}
+	if counts, err := st.CountProcessRestarts(targetID, time.Now().Add(-time.Hour)); err != nil || counts["api"] != 1 {
+		t.Fatalf("expected the restart moved to target, got %v (err %v)", counts, err)
+	}
+	mounts, err := st.GetLatestDiskMounts(targetID)
+	if err != nil || len(mounts) != 2 {
+		t.Fatalf("expected 2 disk mounts on target, got %+v (err %v)", mounts, err)
+	}
+	for _, m := range mounts {
+		if m.Path == "/" && m.UsedPercent != 20 {
+			t.Fatalf("target's own / mount was overwritten: %+v", m)
+		}
+	}
+	var left int
+	if err := st.db.QueryRow(`SELECT
+		(SELECT COUNT(*) FROM disk_mounts WHERE client_id = ?) +
+		(SELECT COUNT(*) FROM process_restarts WHERE client_id = ?)`, sourceID, sourceID).Scan(&left); err != nil || left != 0 {
+		t.Fatalf("expected nothing left on source, got %d rows (err %v)", left, err)
+	}
+
+	merged, err := st.GetClient(sourceID)
+	if err != nil || merged == nil {
+		t.Fatalf("get source: %v", err)
+	}
+	if !merged.IsDeleted {
+		t.Fatalf("expected source to be soft-deleted")
+	}
+}
+
+func TestMergeClientsMovesMachineID(t *testing.T) {
+	st := newTestStore(t)
+
+	source, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", MachineID: "m-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert source: %v", err)
+	}
+	target, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert target: %v", err)
+	}
+	if err := st.MergeClients(source.ClientID, target.ClientID); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+
+	// A reinstall reporting the same machine must land on the target.
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", MachineID: "m-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert reinstall: %v", err)
+	}
+	if res.ClientID != target.ClientID {
+		t.Fatalf("reinstall attached to %s, want target %s", res.ClientID, target.ClientID)
+	}
+	merged, err := st.GetClient(source.ClientID)
+	if err != nil || merged == nil {
+		t.Fatalf("get source: %v", err)
+	}
+	if !merged.IsDeleted {
+		t.Fatal("the merged source was revived")
+	}
+}
+
+func TestPurgeClientCascadesHistory(t *testing.T) {
+	st := newTestStore(t)
+
+	client, err := st.UpsertClient(models.CheckInRequest{Hostname: "old-host"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	id := client.ClientID
+	if err := st.InsertMetrics(id, models.MetricsPayload{CPUPercent: 10}); err != nil {
+		t.Fatalf("insert metrics: %v", err)
+	}
+	if err := st.InsertAlert(&models.Alert{ClientID: id, AlertType: models.AlertTypeOffline, Severity: models.SeverityCritical, Message: "offline"}); err != nil {
+		t.Fatalf("insert alert: %v", err)
+	}
+
+	if err := st.PurgeClient(id); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if c, err := st.GetClient(id); err != nil || c != nil {
+		t.Fatalf("expected client to be gone, got %+v (err %v)", c, err)
+	}
+	if latest, err := st.GetLatestMetrics(id); err != nil || latest != nil {
+		t.Fatalf("expected metrics to be gone, got %+v (err %v)", latest, err)
+	}
+	if _, total, err := st.ListAlerts(AlertFilter{ClientID: id}, 10, 0); err != nil || total != 0 {
+		t.Fatalf("expected alerts to be gone, got %d (err %v)", total, err)
+	}
+}
+
+func TestClearClientMetricsKeepsClient(t *testing.T) {
+	st := newTestStore(t)
+
+	client, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	id := client.ClientID
+	if err := st.InsertMetrics(id, models.MetricsPayload{CPUPercent: 10}); err != nil {
+		t.Fatalf("insert metrics: %v", err)
+	}
+	if err := st.InsertCheckSnapshots(id, []models.CheckPayload{{FriendlyName: "api", CheckType: models.CheckTypeScript, Healthy: true}}); err != nil {
+		t.Fatalf("insert checks: %v", err)
+	}
+	if err := st.InsertAlert(&models.Alert{ClientID: id, AlertType: models.AlertTypeOffline, Severity: models.SeverityCritical, Message: "offline"}); err != nil {
+		t.Fatalf("insert alert: %v", err)
+	}
+
+	if err := st.ClearClientMetrics(id); err != nil {
+		t.Fatalf("clear metrics: %v", err)
+	}
+	if c, err := st.GetClient(id); err != nil || c == nil {
+		t.Fatalf("expected client to remain, got err %v", err)
+	}
+	if latest, err := st.GetLatestMetrics(id); err != nil || latest != nil {
+		t.Fatalf("expected metrics to be cleared, got %+v (err %v)", latest, err)
+	}
+	if _, total, err := st.ListAlerts(AlertFilter{ClientID: id}, 10, 0); err != nil || total != 1 {
+		t.Fatalf("expected alert history to remain, got %d (err %v)", total, err)
+	}
+}
@@ -0,0 +1,294 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestComputeNetRates(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	metrics := []models.Metric{
+		{RecordedAt: t0, NetRxBytes: 1000, NetTxBytes: 500},
+		{RecordedAt: t0.Add(10 * time.Second), NetRxBytes: 3000, NetTxBytes: 1500},
+		// Counter reset (reboot) must not produce a huge or negative rate.
+		{RecordedAt: t0.Add(20 * time.Second), NetRxBytes: 100, NetTxBytes: 50},
+	}
+	computeNetRates(metrics)
+
+	if metrics[0].NetRxBytesPerSec != 0 {
+		t.Fatalf("expected no rate for first sample, got %v", metrics[0].NetRxBytesPerSec)
+	}
+	if metrics[1].NetRxBytesPerSec != 200 || metrics[1].NetTxBytesPerSec != 100 {
+		t.Fatalf("unexpected rates: rx=%v tx=%v", metrics[1].NetRxBytesPerSec, metrics[1].NetTxBytesPerSec)
+	}
+	if metrics[2].NetRxBytesPerSec != 0 || metrics[2].NetTxBytesPerSec != 0 {
+		t.Fatalf("expected zero rate after counter reset, got rx=%v tx=%v", metrics[2].NetRxBytesPerSec, metrics[2].NetTxBytesPerSec)
+	}
+}
+
+func TestInsertMetricsClampsPercentages(t *testing.T) {
+	st := newTestStore(t)
+	client, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := st.InsertMetrics(client.ClientID, models.MetricsPayload{CPUPercent: 100.7, MemPercent: -0.2, DiskPercent: 42}); err != nil {
+		t.Fatalf("insert metrics: %v", err)
+	}
+	latest, err := st.GetLatestMetrics(client.ClientID)
+	if err != nil || latest == nil {
+		t.Fatalf("get latest metrics: %v", err)
+	}
+	if latest.CPUPercent != 100 || latest.MemPercent != 0 || latest.DiskPercent != 42 {
+		t.Fatalf("expected clamped values, got cpu=%v mem=%v disk=%v", latest.CPUPercent, latest.MemPercent, latest.DiskPercent)
+	}
+}
+
+func TestInsertMetricsAtKeepsOriginalTimestamp(t *testing.T) {
+	st := newTestStore(t)
+	client, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := st.InsertMetrics(client.ClientID, models.MetricsPayload{CPUPercent: 50}); err != nil {
+		t.Fatalf("insert metrics: %v", err)
+	}
+	captured := time.Now().UTC().Add(-30 * time.Minute).Truncate(time.Second)
+	if err := st.InsertMetricsAt(client.ClientID, captured, models.MetricsPayload{CPUPercent: 10}); err != nil {
+		t.Fatalf("insert historical metrics: %v", err)
+	}
+
+	metrics, err := st.GetMetrics(client.ClientID, captured.Add(-time.Minute), time.Now().Add(time.Minute), 10)
+	if err != nil {
+		t.Fatalf("get metrics: %v", err)
+	}
+	if len(metrics) != 2 {
+		t.Fatalf("expected 2 samples, got %d", len(metrics))
+	}
+	if !metrics[0].RecordedAt.Equal(captured) || metrics[0].CPUPercent != 10 {
+		t.Fatalf("expected buffered sample first at %v, got %v cpu=%v", captured, metrics[0].RecordedAt, metrics[0].CPUPercent)
+	}
+
+	latest, err := st.GetLatestMetrics(client.ClientID)
+	if err != nil || latest == nil {
+		t.Fatalf("get latest metrics: %v", err)
+	}
+	if latest.CPUPercent != 50 {
+		t.Fatalf("historical sample must not become the latest, got cpu=%v", latest.CPUPercent)
+	}
+}
+
+func TestForEachMetricStreamsInOrderWithRates(t *testing.T) {
+	st := newTestStore(t)
+	client, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	t0 := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	for i, rx := range []uint64{1000, 3000, 7000} {
+		at := t0.Add(time.Duration(i) * 10 * time.Second)
+		if err := st.InsertMetricsAt(client.ClientID, at, models.MetricsPayload{CPUPercent: float64(i), NetRxBytes: rx}); err != nil {
+			t.Fatalf("insert metrics: %v", err)
+		}
+	}
+
+	var got []models.Metric
+	err = st.ForEachMetric(client.ClientID, t0.Add(-time.Minute), time.Now(), func(m models.Metric) error {
+		got = append(got, m)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("for each metric: %v", err)
+	}
+	if len(got) != 3 || got[0].CPUPercent != 0 || got[2].CPUPercent != 2 {
+		t.Fatalf("expected 3 samples oldest first, got %+v", got)
+	}
+	if got[1].NetRxBytesPerSec != 200 || got[2].NetRxBytesPerSec != 400 {
+		t.Fatalf("unexpected rates: %v, %v", got[1].NetRxBytesPerSec, got[2].NetRxBytesPerSec)
+	}
+}
+
+func TestGetMetricsBucketedAveragesAndPeaks(t *testing.T) {
+	st := newTestStore(t)
+	client, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	start := time.Now().UTC().Add(-2 * time.Hour).Truncate(time.Hour)
+	samples := []struct {
+		offset time.Duration
+		cpu    float64
+	}{
+		{0, 10}, {2 * time.Minute, 30}, {4 * time.Minute, 20}, // first 5m bucket
+		{6 * time.Minute, 90}, // second bucket
+	}
+	for _, s := range samples {
+		if err := st.InsertMetricsAt(client.ClientID, start.Add(s.offset), models.MetricsPayload{CPUPercent: s.cpu, MemUsedBytes: 100}); err != nil {
+			t.Fatalf("insert metrics: %v", err)
+		}
+	}
+
+	buckets, err := st.GetMetricsBucketed(client.ClientID, start.Add(-time.Minute), time.Now(), 5*time.Minute, 0)
+	if err != nil {
+		t.Fatalf("get bucketed metrics: %v", err)
+	}
+	if len(buckets) != 2 {
+		t.Fatalf("expected 2 buckets, got %d: %+v", len(buckets), buckets)
+	}
+	first := buckets[0]
+	if !first.RecordedAt.Equal(start) || first.CPUPercent != 20 || first.CPUPeakPercent != 30 || first.Samples != 3 || first.MemUsedBytes != 100 {
+		t.Fatalf("unexpected first bucket: %+v", first)
+	}
+	if !buckets[1].RecordedAt.Equal(start.Add(5*time.Minute)) || buckets[1].CPUPeakPercent != 90 {
+		t.Fatalf("unexpected second bucket: %+v", buckets[1])
+	}
+}
+
+func TestSwapMetricsRoundTrip(t *testing.T) {
+	st := newTestStore(t)
+	client, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := st.InsertMetrics(client.ClientID, models.MetricsPayload{SwapPercent: 25, SwapTotalBytes: 4096, SwapUsedBytes: 1024}); err != nil {
+		t.Fatalf("insert metrics: %v", err)
+	}
+	latest, err := st.GetLatestMetrics(client.ClientID)
+	if err != nil || latest == nil {
+		t.Fatalf("get latest metrics: %v", err)
+	}
+	if latest.SwapPercent != 25 || latest.SwapTotalBytes != 4096 || latest.SwapUsedBytes != 1024 {
+		t.Fatalf("unexpected swap values: %+v", latest)
+	}
+
+	list, _, err := st.ListClients(ClientFilter{}, 0, 0)
+	if err != nil || len(list) != 1 || list[0].LatestMetrics == nil || list[0].LatestMetrics.SwapUsedBytes != 1024 {
+		t.Fatalf("expected swap in client listing: err=%v %+v", err, list)
+	}
+}
+
+func TestCPUTempStoredAsNullable(t *testing.T) {
+	st := newTestStore(t)
+	client, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := st.InsertMetrics(client.ClientID, models.MetricsPayload{CPUPercent: 10}); err != nil {
+		t.Fatalf("insert metrics: %v", err)
+	}
+	latest, err := st.GetLatestMetrics(client.ClientID)
+	if err != nil || latest == nil {
+		t.Fatalf("get latest metrics: %v", err)
+	}
+	if latest.CPUTempC != nil {
+		t.Fatalf("expected no temperature without a sensor, got %v", *latest.CPUTempC)
+	}
+
+	temp := 61.5
+	if err := st.InsertMetricsAt(client.ClientID, time.Now().Add(time.Minute), models.MetricsPayload{CPUTempC: &temp}); err != nil {
+		t.Fatalf("insert metrics: %v", err)
+	}
+	list, _, err := st.ListClients(ClientFilter{}, 0, 0)
+	if err != nil || len(list) != 1 || list[0].LatestMetrics == nil || list[0].LatestMetrics.CPUTempC == nil {
+		t.Fatalf("expected temperature in client listing: err=%v %+v", err, list)
+	}
+	if got := *list[0].LatestMetrics.CPUTempC; got != 61.5 {
+		t.Fatalf("cpu temp = %v, want 61.5", got)
+	}
+}
+
+func TestFDAndProcessCountsStoredAsNullable(t *testing.T) {
+	st := newTestStore(t)
+	client, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := st.InsertMetrics(client.ClientID, models.MetricsPayload{CPUPercent: 10}); err != nil {
+		t.Fatalf("insert metrics: %v", err)
+	}
+	latest, err := st.GetLatestMetrics(client.ClientID)
+	if err != nil || latest == nil {
+		t.Fatalf("get latest metrics: %v", err)
+	}
+	if latest.OpenFDs != nil || latest.ProcessCount != nil {
+		t.Fatalf("expected unreported counts to stay null, got %v %v", latest.OpenFDs, latest.ProcessCount)
+	}
+
+	fds, procs := int64(2048), int64(312)
+	if err := st.InsertMetricsAt(client.ClientID, time.Now().Add(time.Minute), models.MetricsPayload{OpenFDs: &fds, ProcessCount: &procs}); err != nil {
+		t.Fatalf("insert metrics: %v", err)
+	}
+	latest, err = st.GetLatestMetrics(client.ClientID)
+	if err != nil || latest == nil || latest.OpenFDs == nil || *latest.OpenFDs != 2048 || latest.ProcessCount == nil || *latest.ProcessCount != 312 {
+		t.Fatalf("unexpected counts: %+v (err %v)", latest, err)
+	}
+	list, _, err := st.ListClients(ClientFilter{}, 0, 0)
+	if err != nil || len(list) != 1 || list[0].LatestMetrics == nil || list[0].LatestMetrics.ProcessCount == nil {
+		t.Fatalf("expected counts in client listing: err=%v %+v", err, list)
+	}
+}
+
+func TestInodeUsageStoredAsNullable(t *testing.T) {
+	st := newTestStore(t)
+	client, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := st.InsertMetrics(client.ClientID, models.MetricsPayload{DiskPercent: 40}); err != nil {
+		t.Fatalf("insert metrics: %v", err)
+	}
+	latest, err := st.GetLatestMetrics(client.ClientID)
+	if err != nil || latest == nil {
+		t.Fatalf("get latest metrics: %v", err)
+	}
+	if latest.DiskInodesUsedPercent != nil {
+		t.Fatalf("expected no inode usage when unreported, got %v", *latest.DiskInodesUsedPercent)
+	}
+
+	over := 100.4
+	if err := st.InsertMetricsAt(client.ClientID, time.Now().Add(time.Minute), models.MetricsPayload{DiskInodesUsedPercent: &over}); err != nil {
+		t.Fatalf("insert metrics: %v", err)
+	}
+	list, _, err := st.ListClients(ClientFilter{}, 0, 0)
+	if err != nil || len(list) != 1 || list[0].LatestMetrics == nil || list[0].LatestMetrics.DiskInodesUsedPercent == nil {
+		t.Fatalf("expected inode usage in client listing: err=%v %+v", err, list)
+	}
+	if got := *list[0].LatestMetrics.DiskInodesUsedPercent; got != 100 {
+		t.Fatalf("inode usage = %v, want clamped 100", got)
+	}
+}
+
+func TestGetDiskUsageDelta(t *testing.T) {
+	st := newTestStore(t)
+	client, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	since := now.Add(-time.Hour)
+
+	if d, err := st.GetDiskUsageDelta(client.ClientID, since); err != nil || d != nil {
+		t.Fatalf("expected no delta without samples, got %+v err=%v", d, err)
+	}
+	for _, s := range []struct {
+		ago  time.Duration
+		disk float64
+	}{{2 * time.Hour, 10}, {50 * time.Minute, 60}, {20 * time.Minute, 65}, {5 * time.Minute, 70}} {
+		if err := st.InsertMetricsAt(client.ClientID, now.Add(-s.ago), models.MetricsPayload{DiskPercent: s.disk}); err != nil {
+			t.Fatalf("insert metrics: %v", err)
+		}
+	}
+
+	d, err := st.GetDiskUsageDelta(client.ClientID, since)
+	if err != nil || d == nil {
+		t.Fatalf("get delta: %+v err=%v", d, err)
+	}
+	if d.FromPercent != 60 || d.ToPercent != 70 {
+		t.Fatalf("expected 60 -> 70 within the window, got %+v", d)
+	}
+	if rate := d.PerHour(); rate < 13.3 || rate > 13.4 {
+		t.Fatalf("expected ~13.3%%/h (10 points over 45m), got %v", rate)
+	}
+}
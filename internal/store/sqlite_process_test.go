@@ -0,0 +1,192 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestProcessRestartsRecordedFromSnapshots(t *testing.T) {
+	st := newTestStore(t)
+	client, err := st.UpsertClient(models.CheckInRequest{Hostname: "app-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	id := client.ClientID
+
+	// Each check-in is aged a minute so the next one is unambiguously latest.
+	checkIn := func(procs ...models.ProcessPayload) {
+		t.Helper()
+		if err := st.InsertProcessSnapshots(id, procs); err != nil {
+			t.Fatalf("insert snapshots: %v", err)
+		}
+		if _, err := st.db.Exec(`UPDATE process_snapshots SET recorded_at = datetime(recorded_at, '-1 minute')`); err != nil {
+			t.Fatalf("age snapshots: %v", err)
+		}
+	}
+	// workers is an aggregated group: its lowest PID changes as workers are
+	// recycled, which is not a restart of the group.
+	workers := func(running bool, pid int32) models.ProcessPayload {
+		p := models.ProcessPayload{FriendlyName: "workers", IsRunning: running, PID: pid, MinInstances: 2}
+		if running {
+			p.InstanceCount = 4
+		}
+		return p
+	}
+	checkIn(models.ProcessPayload{FriendlyName: "web", IsRunning: true, PID: 100}, models.ProcessPayload{FriendlyName: "db", IsRunning: true, PID: 50}, workers(true, 200))
+	checkIn(models.ProcessPayload{FriendlyName: "web", IsRunning: true, PID: 101}, models.ProcessPayload{FriendlyName: "db", IsRunning: true, PID: 50}, workers(true, 201))
+	checkIn(models.ProcessPayload{FriendlyName: "web", IsRunning: false}, models.ProcessPayload{FriendlyName: "db", IsRunning: true, PID: 50}, workers(false, 0))
+	checkIn(models.ProcessPayload{FriendlyName: "web", IsRunning: true, PID: 102}, models.ProcessPayload{FriendlyName: "db", IsRunning: true, PID: 50}, workers(true, 300))
+	checkIn(models.ProcessPayload{FriendlyName: "web", IsRunning: true, PID: 102}, models.ProcessPayload{FriendlyName: "db", IsRunning: true, PID: 50}, workers(true, 302))
+
+	counts, err := st.CountProcessRestarts(id, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("count restarts: %v", err)
+	}
+	if counts["web"] != 2 || counts["db"] != 0 || counts["workers"] != 1 {
+		t.Fatalf("unexpected restart counts: %v", counts)
+	}
+	snaps, err := st.GetRecentProcessSnapshots(id, "workers", 2)
+	if err != nil || len(snaps) != 2 {
+		t.Fatalf("recent workers snapshots: %v %v", snaps, err)
+	}
+	if !snaps[0].UptimeSinceAt.Equal(snaps[1].UptimeSinceAt) {
+		t.Fatalf("recycling a worker reset the group's uptime: %v -> %v", snaps[1].UptimeSinceAt, snaps[0].UptimeSinceAt)
+	}
+
+	counts, err = st.CountProcessRestarts(id, time.Now().Add(time.Minute))
+	if err != nil || len(counts) != 0 {
+		t.Fatalf("expected no restarts after the window start: %v %v", counts, err)
+	}
+
+	if err := st.DeleteWatchedProcess(id, "web"); err != nil {
+		t.Fatalf("delete watched process: %v", err)
+	}
+	counts, _ = st.CountProcessRestarts(id, time.Now().Add(-time.Hour))
+	if counts["web"] != 0 {
+		t.Fatalf("expected restarts removed with the process, got %v", counts)
+	}
+}
+
+func TestWatchedProcessAlertThresholds(t *testing.T) {
+	st := newTestStore(t)
+	client, err := st.UpsertClient(models.CheckInRequest{Hostname: "app-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	id := client.ClientID
+
+	if err := st.UpsertWatchedProcesses(id, []models.ProcessPayload{
+		{FriendlyName: "web", MatchPattern: "nginx", CPUAlertPct: 150, MemAlertPct: 40},
+		{FriendlyName: "db", MatchPattern: "postgres"},
+	}); err != nil {
+		t.Fatalf("upsert watched: %v", err)
+	}
+	byName := func() map[string]models.WatchedProcess {
+		t.Helper()
+		watched, err := st.GetWatchedProcesses(id)
+		if err != nil {
+			t.Fatalf("get watched: %v", err)
+		}
+		m := make(map[string]models.WatchedProcess)
+		for _, w := range watched {
+			m[w.FriendlyName] = w
+		}
+		return m
+	}
+	got := byName()
+	if got["web"].CPUAlertPct != 150 || got["web"].MemAlertPct != 40 {
+		t.Fatalf("web thresholds = %+v", got["web"])
+	}
+	if got["db"].CPUAlertPct != 0 || got["db"].MemAlertPct != 0 {
+		t.Fatalf("db thresholds = %+v, want none", got["db"])
+	}
+
+	// Removing a threshold from the client config clears it.
+	if err := st.UpsertWatchedProcesses(id, []models.ProcessPayload{
+		{FriendlyName: "web", MatchPattern: "nginx", MemAlertPct: 50},
+	}); err != nil {
+		t.Fatalf("upsert watched: %v", err)
+	}
+	if w := byName()["web"]; w.CPUAlertPct != 0 || w.MemAlertPct != 50 {
+		t.Fatalf("web thresholds after update = %+v", w)
+	}
+}
+
+func TestUpdateWatchedProcessSurvivesClientSync(t *testing.T) {
+	st := newTestStore(t)
+	client, err := st.UpsertClient(models.CheckInRequest{Hostname: "app-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	id := client.ClientID
+	procs := []models.ProcessPayload{{FriendlyName: "batch worker", MatchPattern: "worker"}}
+	if err := st.UpsertWatchedProcesses(id, procs); err != nil {
+		t.Fatalf("upsert watched: %v", err)
+	}
+
+	found, err := st.UpdateWatchedProcess(id, "batch worker", "only runs overnight", models.ProcessExpectStopped)
+	if err != nil || !found {
+		t.Fatalf("update: found=%v err=%v", found, err)
+	}
+	if found, err := st.UpdateWatchedProcess(id, "missing", "", models.ProcessExpectRunning); err != nil || found {
+		t.Fatalf("update missing: found=%v err=%v", found, err)
+	}
+
+	// The next check-in re-syncs the process list without touching the note.
+	if err := st.UpsertWatchedProcesses(id, procs); err != nil {
+		t.Fatalf("upsert watched: %v", err)
+	}
+	watched, err := st.GetWatchedProcesses(id)
+	if err != nil || len(watched) != 1 {
+		t.Fatalf("get watched: %v %v", watched, err)
+	}
+	if watched[0].Note != "only runs overnight" || watched[0].ExpectedState != models.ProcessExpectStopped {
+		t.Fatalf("unexpected watched process: %+v", watched[0])
+	}
+}
+
+func TestProcessInstanceCounts(t *testing.T) {
+	st := newTestStore(t)
+	client, err := st.UpsertClient(models.CheckInRequest{Hostname: "app-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	id := client.ClientID
+
+	procs := []models.ProcessPayload{
+		{FriendlyName: "workers", MatchPattern: "gunicorn", IsRunning: true, PID: 200, InstanceCount: 8, MinInstances: 6},
+		// Older clients don't send a count.
+		{FriendlyName: "db", MatchPattern: "postgres", IsRunning: true, PID: 50},
+		{FriendlyName: "cron", MatchPattern: "cron"},
+	}
+	if err := st.UpsertWatchedProcesses(id, procs); err != nil {
+		t.Fatalf("upsert watched: %v", err)
+	}
+	if err := st.InsertProcessSnapshots(id, procs); err != nil {
+		t.Fatalf("insert snapshots: %v", err)
+	}
+	watched, err := st.GetWatchedProcesses(id)
+	if err != nil {
+		t.Fatalf("get watched: %v", err)
+	}
+	for _, w := range watched {
+		if want := map[string]int{"workers": 6}[w.FriendlyName]; w.MinInstances != want {
+			t.Fatalf("%s min_instances = %d, want %d", w.FriendlyName, w.MinInstances, want)
+		}
+	}
+	snaps, err := st.GetLatestProcessSnapshots(id)
+	if err != nil {
+		t.Fatalf("get snapshots: %v", err)
+	}
+	want := map[string]int{"workers": 8, "db": 1, "cron": 0}
+	for _, s := range snaps {
+		if s.InstanceCount != want[s.FriendlyName] {
+			t.Fatalf("%s instance_count = %d, want %d", s.FriendlyName, s.InstanceCount, want[s.FriendlyName])
+		}
+	}
+	if len(snaps) != 3 {
+		t.Fatalf("got %d snapshots, want 3", len(snaps))
+	}
+}
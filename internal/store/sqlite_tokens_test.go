@@ -0,0 +1,66 @@
+package store
+
+import "testing"
+
+func TestClientTokenRevocation(t *testing.T) {
+	st := newTestStore(t)
+
+	tok, err := st.CreateClientToken("hash-a", " web-1 ")
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	if tok.ID == 0 || tok.Label != "web-1" {
+		t.Fatalf("unexpected token: %+v", tok)
+	}
+	if ok, err := st.ClientTokenValid("hash-a"); err != nil || !ok {
+		t.Fatalf("expected token to be valid: ok=%v err=%v", ok, err)
+	}
+	if ok, _ := st.ClientTokenValid("hash-b"); ok {
+		t.Fatalf("unknown hash should not be valid")
+	}
+
+	if found, err := st.RevokeClientToken(tok.ID); err != nil || !found {
+		t.Fatalf("revoke: found=%v err=%v", found, err)
+	}
+	if ok, _ := st.ClientTokenValid("hash-a"); ok {
+		t.Fatalf("revoked token should not be valid")
+	}
+	if found, _ := st.RevokeClientToken(tok.ID); found {
+		t.Fatalf("second revoke should report not found")
+	}
+
+	tokens, err := st.ListClientTokens()
+	if err != nil || len(tokens) != 1 || !tokens[0].Revoked || tokens[0].RevokedAt == nil {
+		t.Fatalf("list tokens: err=%v %+v", err, tokens)
+	}
+}
+
+func TestAPITokenUseAndRevocation(t *testing.T) {
+	st := newTestStore(t)
+
+	tok, err := st.CreateAPIToken("hash-a", "ci")
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	tokens, _ := st.ListAPITokens()
+	if len(tokens) != 1 || tokens[0].LastUsedAt != nil {
+		t.Fatalf("new token should be unused: %+v", tokens)
+	}
+	if ok, err := st.UseAPIToken("hash-a"); err != nil || !ok {
+		t.Fatalf("expected token to be accepted: ok=%v err=%v", ok, err)
+	}
+	if ok, _ := st.UseAPIToken("hash-b"); ok {
+		t.Fatalf("unknown hash should not be accepted")
+	}
+	tokens, _ = st.ListAPITokens()
+	if tokens[0].LastUsedAt == nil {
+		t.Fatalf("use should be recorded: %+v", tokens[0])
+	}
+
+	if found, err := st.RevokeAPIToken(tok.ID); err != nil || !found {
+		t.Fatalf("revoke: found=%v err=%v", found, err)
+	}
+	if ok, _ := st.UseAPIToken("hash-a"); ok {
+		t.Fatalf("revoked token should not be accepted")
+	}
+}
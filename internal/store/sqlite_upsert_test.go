@@ -0,0 +1,216 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+func TestUpsertClientRebootIsNotIdentityConflict(t *testing.T) {
+	st := newTestStore(t)
+
+	first, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", ClientID: first.ClientID, SessionID: "boot-b"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if !res.SessionChanged {
+		t.Fatalf("expected session change after reboot")
+	}
+	if res.IdentityConflict {
+		t.Fatalf("did not expect identity conflict for a plain reboot")
+	}
+}
+
+func TestUpsertClientReportsCreation(t *testing.T) {
+	st := newTestStore(t)
+
+	first, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if !first.Created {
+		t.Fatalf("expected first check-in to create the client")
+	}
+	again, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", ClientID: first.ClientID, SessionID: "boot-a"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if again.Created {
+		t.Fatalf("did not expect a repeat check-in to create a client")
+	}
+}
+
+func TestUpsertClientAlternatingSessionsIsIdentityConflict(t *testing.T) {
+	st := newTestStore(t)
+
+	first, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	id := first.ClientID
+	if _, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", ClientID: id, SessionID: "boot-b"}, ""); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", ClientID: id, SessionID: "boot-a"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if !res.IdentityConflict {
+		t.Fatalf("expected identity conflict when session flips back to a previous boot")
+	}
+}
+
+func TestUpsertClientHostnameChangeWithNewSessionIsIdentityConflict(t *testing.T) {
+	st := newTestStore(t)
+
+	first, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-2", ClientID: first.ClientID, SessionID: "boot-x"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if !res.IdentityConflict {
+		t.Fatalf("expected identity conflict for hostname change with new session")
+	}
+	if res.PreviousHostname != "web-1" {
+		t.Fatalf("expected previous hostname web-1, got %q", res.PreviousHostname)
+	}
+}
+
+func TestUpsertClientReattachesByMachineID(t *testing.T) {
+	st := newTestStore(t)
+
+	first, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a", MachineID: "m-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	// Reinstalled host: config (and client_id) is gone, machine ID is the same.
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a", MachineID: "m-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if res.ClientID != first.ClientID {
+		t.Fatalf("expected re-attach to %s, got %s", first.ClientID, res.ClientID)
+	}
+
+	other, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-z", MachineID: "m-2"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if other.ClientID == first.ClientID {
+		t.Fatalf("expected a different machine ID to get its own record")
+	}
+}
+
+func TestSetClientCollectionError(t *testing.T) {
+	st := newTestStore(t)
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := st.SetClientCollectionError(res.ClientID, "metrics: disk usage /: permission denied"); err != nil {
+		t.Fatalf("set collection error: %v", err)
+	}
+	c, err := st.GetClient(res.ClientID)
+	if err != nil || c == nil {
+		t.Fatalf("get client: %v", err)
+	}
+	if c.LastCollectionError != "metrics: disk usage /: permission denied" || c.LastCollectionErrorAt == nil {
+		t.Fatalf("collection error not stored: %q %v", c.LastCollectionError, c.LastCollectionErrorAt)
+	}
+}
+
+func TestClientCheckInIntervalOverride(t *testing.T) {
+	st := newTestStore(t)
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if secs, err := st.GetClientCheckInInterval(res.ClientID); err != nil || secs != nil {
+		t.Fatalf("expected no override by default, got %v (err %v)", secs, err)
+	}
+	thirty := 30
+	if err := st.SetClientCheckInInterval(res.ClientID, &thirty); err != nil {
+		t.Fatalf("set interval: %v", err)
+	}
+	if secs, err := st.GetClientCheckInInterval(res.ClientID); err != nil || secs == nil || *secs != 30 {
+		t.Fatalf("expected 30s override, got %v (err %v)", secs, err)
+	}
+	if c, _ := st.GetClient(res.ClientID); c == nil || c.CheckInIntervalSeconds == nil || *c.CheckInIntervalSeconds != 30 {
+		t.Fatalf("expected override on client record, got %+v", c)
+	}
+	if err := st.SetClientCheckInInterval(res.ClientID, nil); err != nil {
+		t.Fatalf("clear interval: %v", err)
+	}
+	if secs, _ := st.GetClientCheckInInterval(res.ClientID); secs != nil {
+		t.Fatalf("expected override cleared, got %v", *secs)
+	}
+}
+
+func TestUpsertClientKeepsLastCheckInLatency(t *testing.T) {
+	st := newTestStore(t)
+
+	first, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	id := first.ClientID
+	if _, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", ClientID: id, SessionID: "boot-a", CheckInLatencyMs: 42}, ""); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	// A check-in without a measurement leaves the last value in place.
+	if _, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", ClientID: id, SessionID: "boot-a"}, ""); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	c, err := st.GetClient(id)
+	if err != nil {
+		t.Fatalf("get client: %v", err)
+	}
+	if c.CheckInLatencyMs == nil || *c.CheckInLatencyMs != 42 {
+		t.Fatalf("checkin latency = %v, want 42", c.CheckInLatencyMs)
+	}
+}
+
+func TestSetClientSuspended(t *testing.T) {
+	st := newTestStore(t)
+
+	res, err := st.UpsertClient(models.CheckInRequest{Hostname: "web-1", SessionID: "boot-a"}, "")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := st.SetClientSuspended(res.ClientID, true, "awaiting decommission"); err != nil {
+		t.Fatalf("suspend: %v", err)
+	}
+	c, err := st.GetClient(res.ClientID)
+	if err != nil {
+		t.Fatalf("get client: %v", err)
+	}
+	if !c.Suspended || c.SuspendReason != "awaiting decommission" {
+		t.Fatalf("suspended = %v, reason = %q", c.Suspended, c.SuspendReason)
+	}
+
+	online, err := st.GetOnlineClients()
+	if err != nil {
+		t.Fatalf("online clients: %v", err)
+	}
+	if len(online) != 1 || !online[0].Suspended {
+		t.Fatalf("online clients = %+v, want the suspended client", online)
+	}
+
+	if err := st.SetClientSuspended(res.ClientID, false, "ignored"); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	c, err = st.GetClient(res.ClientID)
+	if err != nil {
+		t.Fatalf("get client: %v", err)
+	}
+	if c.Suspended || c.SuspendReason != "" {
+		t.Fatalf("after resume suspended = %v, reason = %q", c.Suspended, c.SuspendReason)
+	}
+}
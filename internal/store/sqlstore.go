@@ -0,0 +1,2445 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/machinemon/machinemon/internal/models"
+)
+
+// sqlStore implements Store on database/sql. Queries are written once with ?
+// placeholders; SQLiteStore and PostgresStore embed it and differ only in how
+// they open and migrate the database (see dialect.go for the few spots where
+// the SQL itself differs).
+type sqlStore struct {
+	db *sqlDB
+}
+
+func encodeInterfaceIPs(ips []string) string {
+	if len(ips) == 0 {
+		return "[]"
+	}
+	seen := make(map[string]struct{}, len(ips))
+	cleaned := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		ip = strings.TrimSpace(ip)
+		if ip == "" {
+			continue
+		}
+		if _, ok := seen[ip]; ok {
+			continue
+		}
+		seen[ip] = struct{}{}
+		cleaned = append(cleaned, ip)
+	}
+	sort.Strings(cleaned)
+	if len(cleaned) == 0 {
+		return "[]"
+	}
+	b, err := json.Marshal(cleaned)
+	if err != nil {
+		return "[]"
+	}
+	return string(b)
+}
+
+// decodeStringList parses a JSON string array column; bad input yields nil.
+func decodeStringList(raw string) []string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	var ips []string
+	if err := json.Unmarshal([]byte(raw), &ips); err != nil {
+		return nil
+	}
+	return ips
+}
+
+// encodeTags normalizes tags to a sorted, de-duplicated, lowercase JSON array.
+func encodeTags(tags []string) string {
+	seen := make(map[string]struct{}, len(tags))
+	cleaned := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" {
+			continue
+		}
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		cleaned = append(cleaned, t)
+	}
+	sort.Strings(cleaned)
+	b, err := json.Marshal(cleaned)
+	if err != nil {
+		return "[]"
+	}
+	return string(b)
+}
+
+func sessionStartAt(now time.Time, bootTimeUnix int64) time.Time {
+	if bootTimeUnix <= 0 {
+		return now
+	}
+	start := time.Unix(bootTimeUnix, 0).UTC()
+	// Guard against obviously invalid future timestamps from skewed client clocks.
+	if start.After(now.Add(5 * time.Minute)) {
+		return now
+	}
+	return start
+}
+
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
+
+// pingTimeout bounds Ping so a wedged database fails health checks instead of
+// hanging them.
+const pingTimeout = 3 * time.Second
+
+// Ping checks that the database accepts connections and answers a query.
+func (s *sqlStore) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	if err := s.db.PingContext(ctx); err != nil {
+		return err
+	}
+	var one int
+	return s.db.QueryRowContext(ctx, `SELECT 1`).Scan(&one)
+}
+
+// --- Client operations ---
+
+func (s *sqlStore) UpsertClient(req models.CheckInRequest, publicIP string) (*UpsertClientResult, error) {
+	now := time.Now().UTC()
+	startedAt := sessionStartAt(now, req.BootTimeUnix)
+	interfaceIPsJSON := encodeInterfaceIPs(req.InterfaceIPs)
+
+	// A reinstalled host loses its client_id but keeps its machine identity,
+	// so fall back to matching on machine_id before creating a new record.
+	clientID := req.ClientID
+	if req.MachineID != "" {
+		var exists bool
+		if clientID != "" {
+			if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM clients WHERE id = ?)", clientID).Scan(&exists); err != nil {
+				return nil, fmt.Errorf("lookup client: %w", err)
+			}
+		}
+		if !exists {
+			var matchedID string
+			err := s.db.QueryRow(`SELECT id FROM clients WHERE machine_id = ?
+				ORDER BY is_deleted ASC, last_seen_at DESC LIMIT 1`, req.MachineID).Scan(&matchedID)
+			if err != nil && err != sql.ErrNoRows {
+				return nil, fmt.Errorf("lookup client by machine_id: %w", err)
+			}
+			if matchedID != "" {
+				clientID = matchedID
+			}
+		}
+	}
+
+	// If client has an ID, try to update it
+	if clientID != "" {
+		var isOnline bool
+		var isDeleted bool
+		var oldHostname string
+		var oldSessionID, prevSessionID, oldMachineID sql.NullString
+		err := s.db.QueryRow("SELECT is_online, is_deleted, hostname, session_id, previous_session_id, machine_id FROM clients WHERE id = ?", clientID).
+			Scan(&isOnline, &isDeleted, &oldHostname, &oldSessionID, &prevSessionID, &oldMachineID)
+		if err == nil {
+			// Client exists - update it
+			res := &UpsertClientResult{
+				ClientID:         clientID,
+				WasOffline:       !isOnline,
+				SessionChanged:   req.SessionID != "" && oldSessionID.Valid && oldSessionID.String != "" && oldSessionID.String != req.SessionID,
+				PreviousHostname: oldHostname,
+			}
+			if res.SessionChanged {
+				// Session IDs are derived from host boot time, so a real machine never
+				// returns to an earlier session. Flipping back, or a new hostname
+				// alongside a new session, points at a shared client_id. When both
+				// sides report a machine_id, that settles it directly.
+				flippedBack := prevSessionID.Valid && prevSessionID.String == req.SessionID
+				if req.MachineID != "" && oldMachineID.String != "" {
+					res.IdentityConflict = flippedBack || oldMachineID.String != req.MachineID
+				} else {
+					res.IdentityConflict = flippedBack || !strings.EqualFold(strings.TrimSpace(oldHostname), strings.TrimSpace(req.Hostname))
+				}
+			}
+			_, err := s.db.Exec(`UPDATE clients SET hostname = ?, os = ?, arch = ?, client_version = ?,
+				last_seen_at = ?, is_online = TRUE, is_deleted = FALSE, session_id = ?, public_ip = ?, interface_ips = ?,
+				session_started_at = CASE WHEN ? THEN ? ELSE COALESCE(session_started_at, ?) END,
+				previous_session_id = CASE WHEN ? THEN ? ELSE previous_session_id END,
+				machine_id = COALESCE(NULLIF(?, ''), machine_id),
+				reported_interval_seconds = COALESCE(NULLIF(?, 0), reported_interval_seconds),
+				checkin_latency_ms = COALESCE(NULLIF(?, 0), checkin_latency_ms)
+				WHERE id = ?`,
+				req.Hostname, req.OS, req.Arch, req.ClientVersion, now, req.SessionID, publicIP, interfaceIPsJSON,
+				res.SessionChanged, startedAt, startedAt,
+				res.SessionChanged, oldSessionID.String,
+				req.MachineID, req.CheckInInterval, req.CheckInLatencyMs,
+				clientID)
+			if err != nil {
+				return nil, fmt.Errorf("update client: %w", err)
+			}
+			return res, nil
+		}
+		// If not found, fall through to create
+	}
+
+	// Create new client
+	id := uuid.New().String()
+	_, err := s.db.Exec(`INSERT INTO clients (id, hostname, os, arch, client_version, first_seen_at, last_seen_at, session_started_at, is_online, session_id, public_ip, interface_ips, machine_id, reported_interval_seconds)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, TRUE, ?, ?, ?, NULLIF(?, ''), NULLIF(?, 0))`,
+		id, req.Hostname, req.OS, req.Arch, req.ClientVersion, now, now, startedAt, req.SessionID, publicIP, interfaceIPsJSON, req.MachineID, req.CheckInInterval)
+	if err != nil {
+		return nil, fmt.Errorf("insert client: %w", err)
+	}
+	return &UpsertClientResult{ClientID: id, Created: true}, nil
+}
+
+func (s *sqlStore) GetClient(id string) (*models.Client, error) {
+	c := &models.Client{}
+	var mutedUntil sql.NullTime
+	var sessionStartedAt sql.NullTime
+	var muteReason sql.NullString
+	var offlineThresholdSecs sql.NullInt64
+	var metricConsecutiveCheckins sql.NullInt64
+	var alertCooldownSecs sql.NullInt64
+	var businessHoursJSON sql.NullString
+	var reportedIntervalSecs sql.NullInt64
+	var collectionErrorAt sql.NullTime
+	var checkInIntervalSecs, checkInLatencyMs sql.NullInt64
+	var interfaceIPsJSON, tagsJSON string
+	err := s.db.QueryRow(`SELECT id, hostname, custom_name, public_ip, interface_ips, tags, os, arch, client_version, first_seen_at, last_seen_at, session_started_at,
+		is_online, is_deleted, cpu_warn_pct, cpu_crit_pct, mem_warn_pct, mem_crit_pct,
+		disk_warn_pct, disk_crit_pct, offline_threshold_seconds, metric_consecutive_checkins, alert_cooldown_seconds,
+		business_hours, reported_interval_seconds, check_in_interval_seconds, checkin_latency_ms,
+		last_collection_error, last_collection_error_at, alerts_muted, muted_until, mute_reason,
+		suspended, suspend_reason
+		FROM clients WHERE id = ?`, id).Scan(
+		&c.ID, &c.Hostname, &c.CustomName, &c.PublicIP, &interfaceIPsJSON, &tagsJSON, &c.OS, &c.Arch, &c.ClientVersion,
+		&c.FirstSeenAt, &c.LastSeenAt, &sessionStartedAt, &c.IsOnline, &c.IsDeleted,
+		&c.CPUWarnPct, &c.CPUCritPct, &c.MemWarnPct, &c.MemCritPct,
+		&c.DiskWarnPct, &c.DiskCritPct, &offlineThresholdSecs, &metricConsecutiveCheckins, &alertCooldownSecs,
+		&businessHoursJSON, &reportedIntervalSecs, &checkInIntervalSecs, &checkInLatencyMs,
+		&c.LastCollectionError, &collectionErrorAt, &c.AlertsMuted, &mutedUntil, &muteReason,
+		&c.Suspended, &c.SuspendReason)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get client: %w", err)
+	}
+	if mutedUntil.Valid {
+		c.MutedUntil = &mutedUntil.Time
+	}
+	if sessionStartedAt.Valid {
+		c.SessionStartedAt = sessionStartedAt.Time
+	} else {
+		c.SessionStartedAt = time.Now().UTC()
+	}
+	if muteReason.Valid {
+		c.MuteReason = muteReason.String
+	}
+	if offlineThresholdSecs.Valid {
+		v := int(offlineThresholdSecs.Int64)
+		c.OfflineThresholdSeconds = &v
+	}
+	if metricConsecutiveCheckins.Valid {
+		v := int(metricConsecutiveCheckins.Int64)
+		c.MetricConsecutiveCheckins = &v
+	}
+	if alertCooldownSecs.Valid {
+		v := int(alertCooldownSecs.Int64)
+		c.AlertCooldownSeconds = &v
+	}
+	if reportedIntervalSecs.Valid {
+		v := int(reportedIntervalSecs.Int64)
+		c.ReportedIntervalSeconds = &v
+	}
+	if checkInIntervalSecs.Valid {
+		v := int(checkInIntervalSecs.Int64)
+		c.CheckInIntervalSeconds = &v
+	}
+	if checkInLatencyMs.Valid {
+		v := int(checkInLatencyMs.Int64)
+		c.CheckInLatencyMs = &v
+	}
+	if collectionErrorAt.Valid {
+		c.LastCollectionErrorAt = &collectionErrorAt.Time
+	}
+	c.BusinessHours = decodeBusinessHours(businessHoursJSON)
+	c.InterfaceIPs = decodeStringList(interfaceIPsJSON)
+	c.Tags = decodeStringList(tagsJSON)
+	return c, nil
+}
+
+// likeEscaper escapes LIKE wildcards in user input (paired with ESCAPE '\').
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (s *sqlStore) ListClients(filter ClientFilter, limit, offset int) ([]models.ClientWithMetrics, int, error) {
+	where := "WHERE c.is_deleted = FALSE"
+	var args []interface{}
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		where += ` AND (LOWER(c.hostname) LIKE ? ESCAPE '\' OR LOWER(c.custom_name) LIKE ? ESCAPE '\')`
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(q)) + "%"
+		args = append(args, pattern, pattern)
+	}
+	if tag := strings.ToLower(strings.TrimSpace(filter.Tag)); tag != "" {
+		// Tags are stored as a normalized JSON array, so a quoted match is exact.
+		where += ` AND c.tags LIKE ? ESCAPE '\'`
+		args = append(args, `%"`+likeEscaper.Replace(tag)+`"%`)
+	}
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM clients c "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count clients: %w", err)
+	}
+
+	page := ""
+	if limit > 0 {
+		if offset < 0 {
+			offset = 0
+		}
+		page = " LIMIT ? OFFSET ?"
+		args = append(args, limit, offset)
+	}
+	rows, err := s.db.Query(`SELECT c.id, c.hostname, c.custom_name, c.public_ip, c.interface_ips, c.tags, c.os, c.arch, c.client_version,
+		c.first_seen_at, c.last_seen_at, c.session_started_at, c.is_online, c.alerts_muted, c.muted_until, c.suspended,
+		c.cpu_warn_pct, c.cpu_crit_pct, c.mem_warn_pct, c.mem_crit_pct,
+		c.disk_warn_pct, c.disk_crit_pct, c.offline_threshold_seconds, c.metric_consecutive_checkins, c.alert_cooldown_seconds,
+		c.business_hours,
+		m.cpu_pct, m.mem_pct, m.disk_pct, m.mem_total_bytes, m.mem_used_bytes,
+		m.disk_total_bytes, m.disk_used_bytes, m.swap_pct, m.swap_total_bytes, m.swap_used_bytes, m.cpu_temp_c,
+		m.open_fds, m.process_count, m.disk_inodes_pct, m.recorded_at,
+		(SELECT COUNT(*) FROM watched_processes wp WHERE wp.client_id = c.id) as proc_count
+		FROM clients c
+		LEFT JOIN metrics m ON m.client_id = c.id AND m.id = (
+			SELECT id FROM metrics WHERE client_id = c.id ORDER BY recorded_at DESC LIMIT 1
+		)
+		`+where+`
+		ORDER BY COALESCE(NULLIF(c.custom_name, ''), c.hostname), c.id`+page, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list clients: %w", err)
+	}
+	defer rows.Close()
+
+	var result []models.ClientWithMetrics
+	for rows.Next() {
+		var cwm models.ClientWithMetrics
+		var mutedUntil sql.NullTime
+		var sessionStartedAt sql.NullTime
+		var cpuPct, memPct, diskPct, swapPct, cpuTemp, inodesPct sql.NullFloat64
+		var memTotal, memUsed, diskTotal, diskUsed, swapTotal, swapUsed sql.NullInt64
+		var openFDs, procCount sql.NullInt64
+		var recordedAt sql.NullTime
+		var offlineThresholdSecs sql.NullInt64
+		var metricConsecutiveCheckins sql.NullInt64
+		var alertCooldownSecs sql.NullInt64
+		var businessHoursJSON sql.NullString
+		var interfaceIPsJSON, tagsJSON string
+
+		err := rows.Scan(
+			&cwm.ID, &cwm.Hostname, &cwm.CustomName, &cwm.PublicIP, &interfaceIPsJSON, &tagsJSON, &cwm.OS, &cwm.Arch, &cwm.ClientVersion,
+			&cwm.FirstSeenAt, &cwm.LastSeenAt, &sessionStartedAt, &cwm.IsOnline, &cwm.AlertsMuted, &mutedUntil, &cwm.Suspended,
+			&cwm.CPUWarnPct, &cwm.CPUCritPct, &cwm.MemWarnPct, &cwm.MemCritPct,
+			&cwm.DiskWarnPct, &cwm.DiskCritPct, &offlineThresholdSecs, &metricConsecutiveCheckins, &alertCooldownSecs,
+			&businessHoursJSON,
+			&cpuPct, &memPct, &diskPct, &memTotal, &memUsed,
+			&diskTotal, &diskUsed, &swapPct, &swapTotal, &swapUsed, &cpuTemp,
+			&openFDs, &procCount, &inodesPct, &recordedAt,
+			&cwm.ProcessCount,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("scan client row: %w", err)
+		}
+		if mutedUntil.Valid {
+			cwm.MutedUntil = &mutedUntil.Time
+		}
+		if sessionStartedAt.Valid {
+			cwm.SessionStartedAt = sessionStartedAt.Time
+		} else {
+			cwm.SessionStartedAt = time.Now().UTC()
+		}
+		if offlineThresholdSecs.Valid {
+			v := int(offlineThresholdSecs.Int64)
+			cwm.OfflineThresholdSeconds = &v
+		}
+		if metricConsecutiveCheckins.Valid {
+			v := int(metricConsecutiveCheckins.Int64)
+			cwm.MetricConsecutiveCheckins = &v
+		}
+		if alertCooldownSecs.Valid {
+			v := int(alertCooldownSecs.Int64)
+			cwm.AlertCooldownSeconds = &v
+		}
+		cwm.BusinessHours = decodeBusinessHours(businessHoursJSON)
+		cwm.InterfaceIPs = decodeStringList(interfaceIPsJSON)
+		cwm.Tags = decodeStringList(tagsJSON)
+		if cpuPct.Valid {
+			cwm.LatestMetrics = &models.Metric{
+				CPUPercent:     cpuPct.Float64,
+				MemPercent:     memPct.Float64,
+				DiskPercent:    diskPct.Float64,
+				MemTotalBytes:  uint64(memTotal.Int64),
+				MemUsedBytes:   uint64(memUsed.Int64),
+				DiskTotalBytes: uint64(diskTotal.Int64),
+				DiskUsedBytes:  uint64(diskUsed.Int64),
+				SwapPercent:    swapPct.Float64,
+				SwapTotalBytes: uint64(swapTotal.Int64),
+				SwapUsedBytes:  uint64(swapUsed.Int64),
+				RecordedAt:     recordedAt.Time,
+			}
+			if cpuTemp.Valid {
+				cwm.LatestMetrics.CPUTempC = &cpuTemp.Float64
+			}
+			if openFDs.Valid {
+				cwm.LatestMetrics.OpenFDs = &openFDs.Int64
+			}
+			if procCount.Valid {
+				cwm.LatestMetrics.ProcessCount = &procCount.Int64
+			}
+			if inodesPct.Valid {
+				cwm.LatestMetrics.DiskInodesUsedPercent = &inodesPct.Float64
+			}
+		}
+		result = append(result, cwm)
+	}
+	return result, total, rows.Err()
+}
+
+func (s *sqlStore) DeleteClient(id string) error {
+	_, err := s.db.Exec("UPDATE clients SET is_deleted = TRUE WHERE id = ?", id)
+	return err
+}
+
+func (s *sqlStore) PurgeClient(id string) error {
+	_, err := s.db.Exec("DELETE FROM clients WHERE id = ?", id)
+	return err
+}
+
+func (s *sqlStore) ClearClientMetrics(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, table := range []string{"metrics", "process_snapshots", "check_snapshots"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE client_id = ?`, id); err != nil {
+			return fmt.Errorf("clear %s: %w", table, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// MergeClients reassigns all history (metrics, snapshots, alerts, watched
+// processes and scoped mutes) from sourceID to targetID and soft-deletes the
+// source client. Rows that would collide with an existing target row (same
+// watched process or mute) keep the target's version.
+func (s *sqlStore) MergeClients(sourceID, targetID string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmts := []struct {
+		name  string
+		query string
+	}{
+		{"metrics", `UPDATE metrics SET client_id = ? WHERE client_id = ?`},
+		{"process snapshots", `UPDATE process_snapshots SET client_id = ? WHERE client_id = ?`},
+		{"process restarts", `UPDATE process_restarts SET client_id = ? WHERE client_id = ?`},
+		{"check snapshots", `UPDATE check_snapshots SET client_id = ? WHERE client_id = ?`},
+		{"alerts", `UPDATE alerts SET client_id = ? WHERE client_id = ?`},
+		{"maintenance windows", `UPDATE maintenance_windows SET client_id = ? WHERE client_id = ?`},
+	}
+	for _, st := range stmts {
+		if _, err := tx.Exec(st.query, targetID, sourceID); err != nil {
+			return fmt.Errorf("merge %s: %w", st.name, err)
+		}
+	}
+	// Move uniquely keyed rows only where the target has no row of its own.
+	if _, err := tx.Exec(`UPDATE watched_processes SET client_id = ? WHERE client_id = ?
+		AND NOT EXISTS (SELECT 1 FROM watched_processes t
+			WHERE t.client_id = ? AND t.friendly_name = watched_processes.friendly_name)`,
+		targetID, sourceID, targetID); err != nil {
+		return fmt.Errorf("merge watched processes: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE client_alert_mutes SET client_id = ? WHERE client_id = ?
+		AND NOT EXISTS (SELECT 1 FROM client_alert_mutes t
+			WHERE t.client_id = ? AND t.scope = client_alert_mutes.scope AND t.target = client_alert_mutes.target)`,
+		targetID, sourceID, targetID); err != nil {
+		return fmt.Errorf("merge alert mutes: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE disk_mounts SET client_id = ? WHERE client_id = ?
+		AND NOT EXISTS (SELECT 1 FROM disk_mounts t
+			WHERE t.client_id = ? AND t.mount_path = disk_mounts.mount_path)`,
+		targetID, sourceID, targetID); err != nil {
+		return fmt.Errorf("merge disk mounts: %w", err)
+	}
+	// Anything left behind collided with an existing target row.
+	if _, err := tx.Exec(`DELETE FROM watched_processes WHERE client_id = ?`, sourceID); err != nil {
+		return fmt.Errorf("merge watched processes: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM client_alert_mutes WHERE client_id = ?`, sourceID); err != nil {
+		return fmt.Errorf("merge alert mutes: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM disk_mounts WHERE client_id = ?`, sourceID); err != nil {
+		return fmt.Errorf("merge disk mounts: %w", err)
+	}
+
+	if _, err := tx.Exec(`UPDATE clients SET
+		first_seen_at = (SELECT MIN(first_seen_at) FROM clients WHERE id IN (?, ?))
+		WHERE id = ?`, sourceID, targetID, targetID); err != nil {
+		return fmt.Errorf("merge first_seen_at: %w", err)
+	}
+	// Hand the source's machine_id to the target, unless it has its own, so a
+	// reinstall re-attaches to the target instead of reviving the source.
+	if _, err := tx.Exec(`UPDATE clients SET
+		machine_id = (SELECT machine_id FROM clients WHERE id = ?)
+		WHERE id = ? AND COALESCE(machine_id, '') = ''`, sourceID, targetID); err != nil {
+		return fmt.Errorf("merge machine_id: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE clients SET is_deleted = TRUE, is_online = FALSE, machine_id = NULL WHERE id = ?`, sourceID); err != nil {
+		return fmt.Errorf("soft-delete source client: %w", err)
+	}
+	return tx.Commit()
+}
+
+// CountClients tallies non-deleted clients by state.
+func (s *sqlStore) CountClients() (*models.ClientCounts, error) {
+	var c models.ClientCounts
+	err := s.db.QueryRow(`SELECT COUNT(*),
+		COALESCE(SUM(CASE WHEN is_online THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN alerts_muted THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN suspended THEN 1 ELSE 0 END), 0)
+		FROM clients WHERE is_deleted = FALSE`).Scan(&c.Total, &c.Online, &c.Muted, &c.Suspended)
+	if err != nil {
+		return nil, fmt.Errorf("count clients: %w", err)
+	}
+	c.Offline = c.Total - c.Online
+	return &c, nil
+}
+
+// ListClientVersions returns each non-deleted client's reported version,
+// ordered by version then hostname.
+func (s *sqlStore) ListClientVersions() ([]models.ClientVersionInfo, error) {
+	rows, err := s.db.Query(`SELECT id, hostname, custom_name, client_version, is_online, last_seen_at
+		FROM clients WHERE is_deleted = FALSE ORDER BY client_version, hostname`)
+	if err != nil {
+		return nil, fmt.Errorf("list client versions: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.ClientVersionInfo
+	for rows.Next() {
+		var v models.ClientVersionInfo
+		if err := rows.Scan(&v.ClientID, &v.Hostname, &v.CustomName, &v.ClientVersion, &v.IsOnline, &v.LastSeenAt); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+func (s *sqlStore) SetClientOnline(id string, online bool) error {
+	_, err := s.db.Exec("UPDATE clients SET is_online = ? WHERE id = ?", online, id)
+	return err
+}
+
+func (s *sqlStore) GetOnlineClients() ([]models.Client, error) {
+	rows, err := s.db.Query(`SELECT id, hostname, custom_name, public_ip, os, arch, last_seen_at, is_online,
+		alerts_muted, muted_until, mute_reason, suspended, offline_threshold_seconds, metric_consecutive_checkins
+		FROM clients WHERE is_online = TRUE AND is_deleted = FALSE`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clients []models.Client
+	for rows.Next() {
+		var c models.Client
+		var mutedUntil sql.NullTime
+		var muteReason sql.NullString
+		var offlineThresholdSecs sql.NullInt64
+		var metricConsecutiveCheckins sql.NullInt64
+		err := rows.Scan(&c.ID, &c.Hostname, &c.CustomName, &c.PublicIP, &c.OS, &c.Arch, &c.LastSeenAt, &c.IsOnline,
+			&c.AlertsMuted, &mutedUntil, &muteReason, &c.Suspended, &offlineThresholdSecs, &metricConsecutiveCheckins)
+		if err != nil {
+			return nil, err
+		}
+		if mutedUntil.Valid {
+			c.MutedUntil = &mutedUntil.Time
+		}
+		if muteReason.Valid {
+			c.MuteReason = muteReason.String
+		}
+		if offlineThresholdSecs.Valid {
+			v := int(offlineThresholdSecs.Int64)
+			c.OfflineThresholdSeconds = &v
+		}
+		if metricConsecutiveCheckins.Valid {
+			v := int(metricConsecutiveCheckins.Int64)
+			c.MetricConsecutiveCheckins = &v
+		}
+		clients = append(clients, c)
+	}
+	return clients, rows.Err()
+}
+
+// GetStaleOnlineClients returns clients marked online whose last_seen_at
+// is older than thresholdSeconds. The cutoff is computed by the database
+// (SQLite's datetime('now'), Postgres' NOW()) to avoid Go/DB timezone mismatches.
+func (s *sqlStore) GetStaleOnlineClients(thresholdSeconds int) ([]models.Client, error) {
+	cutoff, arg := s.db.secondsAgo(thresholdSeconds)
+	rows, err := s.db.Query(`SELECT id, hostname, custom_name, public_ip, os, arch, last_seen_at, is_online,
+		alerts_muted, muted_until, mute_reason, suspended, offline_threshold_seconds, metric_consecutive_checkins
+		FROM clients
+		WHERE is_online = TRUE AND is_deleted = FALSE
+		AND last_seen_at < `+cutoff, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clients []models.Client
+	for rows.Next() {
+		var c models.Client
+		var mutedUntil sql.NullTime
+		var muteReason sql.NullString
+		var offlineThresholdSecs sql.NullInt64
+		var metricConsecutiveCheckins sql.NullInt64
+		err := rows.Scan(&c.ID, &c.Hostname, &c.CustomName, &c.PublicIP, &c.OS, &c.Arch, &c.LastSeenAt, &c.IsOnline,
+			&c.AlertsMuted, &mutedUntil, &muteReason, &c.Suspended, &offlineThresholdSecs, &metricConsecutiveCheckins)
+		if err != nil {
+			return nil, err
+		}
+		if mutedUntil.Valid {
+			c.MutedUntil = &mutedUntil.Time
+		}
+		if muteReason.Valid {
+			c.MuteReason = muteReason.String
+		}
+		if offlineThresholdSecs.Valid {
+			v := int(offlineThresholdSecs.Int64)
+			c.OfflineThresholdSeconds = &v
+		}
+		if metricConsecutiveCheckins.Valid {
+			v := int(metricConsecutiveCheckins.Int64)
+			c.MetricConsecutiveCheckins = &v
+		}
+		clients = append(clients, c)
+	}
+	return clients, rows.Err()
+}
+
+// GetClientCheckInInterval returns the admin-set check-in interval for the
+// client in seconds, or nil when the client should use its own config.
+func (s *sqlStore) GetClientCheckInInterval(id string) (*int, error) {
+	var secs sql.NullInt64
+	err := s.db.QueryRow(`SELECT check_in_interval_seconds FROM clients WHERE id = ?`, id).Scan(&secs)
+	if err == sql.ErrNoRows || (err == nil && !secs.Valid) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	v := int(secs.Int64)
+	return &v, nil
+}
+
+// SetClientCheckInInterval stores the client's check-in interval override;
+// nil clears it.
+func (s *sqlStore) SetClientCheckInInterval(id string, secs *int) error {
+	_, err := s.db.Exec(`UPDATE clients SET check_in_interval_seconds = ? WHERE id = ?`, secs, id)
+	return err
+}
+
+// SetClientCollectionError records the most recent data collection failure
+// the client reported. It is kept until the next failure replaces it.
+func (s *sqlStore) SetClientCollectionError(id, message string) error {
+	_, err := s.db.Exec(`UPDATE clients SET last_collection_error = ?, last_collection_error_at = ? WHERE id = ?`,
+		message, time.Now().UTC(), id)
+	return err
+}
+
+// SetClientBusinessHours stores the client's alerting schedule; nil clears it.
+func (s *sqlStore) SetClientBusinessHours(id string, bh *models.BusinessHours) error {
+	var value interface{}
+	if bh != nil {
+		b, err := json.Marshal(bh)
+		if err != nil {
+			return fmt.Errorf("encode business hours: %w", err)
+		}
+		value = string(b)
+	}
+	_, err := s.db.Exec(`UPDATE clients SET business_hours = ? WHERE id = ?`, value, id)
+	return err
+}
+
+func decodeBusinessHours(raw sql.NullString) *models.BusinessHours {
+	if !raw.Valid || strings.TrimSpace(raw.String) == "" {
+		return nil
+	}
+	var bh models.BusinessHours
+	if err := json.Unmarshal([]byte(raw.String), &bh); err != nil {
+		return nil
+	}
+	return &bh
+}
+
+func (s *sqlStore) SetClientThresholds(id string, t *models.Thresholds) error {
+	if t == nil {
+		_, err := s.db.Exec(`UPDATE clients SET cpu_warn_pct = NULL, cpu_crit_pct = NULL,
+			mem_warn_pct = NULL, mem_crit_pct = NULL, disk_warn_pct = NULL, disk_crit_pct = NULL,
+			offline_threshold_seconds = NULL, metric_consecutive_checkins = NULL, alert_cooldown_seconds = NULL
+			WHERE id = ?`, id)
+		return err
+	}
+	legacyMetricValuesProvided := t.CPUWarnPct != 0 || t.CPUCritPct != 0 || t.MemWarnPct != 0 || t.MemCritPct != 0 || t.DiskWarnPct != 0 || t.DiskCritPct != 0
+	metricClear := t.MetricThresholdsEnabled != nil && !*t.MetricThresholdsEnabled
+	metricSet := (t.MetricThresholdsEnabled != nil && *t.MetricThresholdsEnabled) || (t.MetricThresholdsEnabled == nil && legacyMetricValuesProvided)
+
+	offlineClear := t.OfflineThresholdEnabled != nil && !*t.OfflineThresholdEnabled
+	offlineSet := (t.OfflineThresholdEnabled != nil && *t.OfflineThresholdEnabled) || (t.OfflineThresholdEnabled == nil && t.OfflineThresholdMinutes != nil)
+	offlineThresholdSecs := 0
+	if offlineSet && t.OfflineThresholdMinutes != nil && *t.OfflineThresholdMinutes > 0 {
+		offlineThresholdSecs = *t.OfflineThresholdMinutes * 60
+	}
+
+	consecutiveClear := t.MetricConsecutiveEnabled != nil && !*t.MetricConsecutiveEnabled
+	consecutiveSet := (t.MetricConsecutiveEnabled != nil && *t.MetricConsecutiveEnabled) || (t.MetricConsecutiveEnabled == nil && t.MetricConsecutiveCheckins != nil)
+	consecutiveThreshold := 0
+	if consecutiveSet && t.MetricConsecutiveCheckins != nil && *t.MetricConsecutiveCheckins > 0 {
+		consecutiveThreshold = *t.MetricConsecutiveCheckins
+	}
+
+	cooldownClear := t.AlertCooldownEnabled != nil && !*t.AlertCooldownEnabled
+	cooldownSet := (t.AlertCooldownEnabled != nil && *t.AlertCooldownEnabled) || (t.AlertCooldownEnabled == nil && t.AlertCooldownSeconds != nil)
+	cooldownSecs := 0
+	if cooldownSet && t.AlertCooldownSeconds != nil && *t.AlertCooldownSeconds > 0 {
+		cooldownSecs = *t.AlertCooldownSeconds
+	}
+	_, err := s.db.Exec(`UPDATE clients SET offline_threshold_seconds = CASE
+			WHEN ? THEN NULL
+			WHEN ? THEN NULLIF(?, 0)
+			ELSE offline_threshold_seconds
+		END,
+		metric_consecutive_checkins = CASE
+			WHEN ? THEN NULL
+			WHEN ? THEN NULLIF(?, 0)
+			ELSE metric_consecutive_checkins
+		END,
+		alert_cooldown_seconds = CASE
+			WHEN ? THEN NULL
+			WHEN ? THEN ?
+			ELSE alert_cooldown_seconds
+		END,
+		cpu_warn_pct = CASE
+			WHEN ? THEN NULL
+			WHEN ? THEN ?
+			ELSE cpu_warn_pct
+		END,
+		cpu_crit_pct = CASE
+			WHEN ? THEN NULL
+			WHEN ? THEN ?
+			ELSE cpu_crit_pct
+		END,
+		mem_warn_pct = CASE
+			WHEN ? THEN NULL
+			WHEN ? THEN ?
+			ELSE mem_warn_pct
+		END,
+		mem_crit_pct = CASE
+			WHEN ? THEN NULL
+			WHEN ? THEN ?
+			ELSE mem_crit_pct
+		END,
+		disk_warn_pct = CASE
+			WHEN ? THEN NULL
+			WHEN ? THEN ?
+			ELSE disk_warn_pct
+		END,
+		disk_crit_pct = CASE
+			WHEN ? THEN NULL
+			WHEN ? THEN ?
+			ELSE disk_crit_pct
+		END
+		WHERE id = ?`,
+		// offline_threshold_seconds
+		offlineClear, offlineSet, offlineThresholdSecs,
+		// metric_consecutive_checkins
+		consecutiveClear, consecutiveSet, consecutiveThreshold,
+		// alert_cooldown_seconds (0 is a valid override that disables the cooldown)
+		cooldownClear, cooldownSet, cooldownSecs,
+		// CPU/MEM/DISK metric thresholds
+		metricClear, metricSet, t.CPUWarnPct,
+		metricClear, metricSet, t.CPUCritPct,
+		metricClear, metricSet, t.MemWarnPct,
+		metricClear, metricSet, t.MemCritPct,
+		metricClear, metricSet, t.DiskWarnPct,
+		metricClear, metricSet, t.DiskCritPct,
+		id)
+	return err
+}
+
+func (s *sqlStore) SetClientCustomName(id, customName string) error {
+	_, err := s.db.Exec(`UPDATE clients SET custom_name = ? WHERE id = ?`, strings.TrimSpace(customName), id)
+	return err
+}
+
+func (s *sqlStore) SetClientTags(id string, tags []string) error {
+	_, err := s.db.Exec(`UPDATE clients SET tags = ? WHERE id = ?`, encodeTags(tags), id)
+	return err
+}
+
+func (s *sqlStore) GetClientsByTag(tag string) ([]models.Client, error) {
+	if strings.TrimSpace(tag) == "" {
+		return nil, nil
+	}
+	list, _, err := s.ListClients(ClientFilter{Tag: tag}, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	clients := make([]models.Client, 0, len(list))
+	for _, cwm := range list {
+		clients = append(clients, cwm.Client)
+	}
+	return clients, nil
+}
+
+func (s *sqlStore) SetClientMute(id string, muted bool, until *time.Time, reason string) error {
+	var mutedUntil interface{}
+	if until != nil {
+		mutedUntil = *until
+	}
+	_, err := s.db.Exec(`UPDATE clients SET alerts_muted = ?, muted_until = ?, mute_reason = ? WHERE id = ?`,
+		muted, mutedUntil, reason, id)
+	return err
+}
+
+// SetClientsMute applies the same mute to several clients at once. Nothing is
+// changed unless every id names an existing client; the unknown ids are
+// returned instead.
+func (s *sqlStore) SetClientsMute(ids []string, muted bool, until *time.Time, reason string) ([]string, error) {
+	var mutedUntil interface{}
+	if until != nil {
+		mutedUntil = *until
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var missing []string
+	for _, id := range ids {
+		res, err := tx.Exec(`UPDATE clients SET alerts_muted = ?, muted_until = ?, mute_reason = ?
+			WHERE id = ? AND is_deleted = FALSE`, muted, mutedUntil, reason, id)
+		if err != nil {
+			return nil, fmt.Errorf("mute client %s: %w", id, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return missing, nil
+	}
+	return nil, tx.Commit()
+}
+
+// SetClientSuspended suspends or resumes alerting for a client. Resuming
+// clears the reason.
+func (s *sqlStore) SetClientSuspended(id string, suspended bool, reason string) error {
+	if !suspended {
+		reason = ""
+	}
+	_, err := s.db.Exec(`UPDATE clients SET suspended = ?, suspend_reason = ? WHERE id = ?`, suspended, reason, id)
+	return err
+}
+
+func (s *sqlStore) ListClientAlertMutes(clientID string) ([]models.ClientAlertMute, error) {
+	rows, err := s.db.Query(`SELECT id, client_id, scope, target, created_at
+		FROM client_alert_mutes
+		WHERE client_id = ?
+		ORDER BY scope, target`, clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.ClientAlertMute
+	for rows.Next() {
+		var m models.ClientAlertMute
+		if err := rows.Scan(&m.ID, &m.ClientID, &m.Scope, &m.Target, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+func (s *sqlStore) SetClientAlertMute(clientID, scope, target string, muted bool) error {
+	scope = strings.TrimSpace(scope)
+	target = strings.TrimSpace(target)
+	if muted {
+		_, err := s.db.Exec(`INSERT INTO client_alert_mutes (client_id, scope, target)
+			VALUES (?, ?, ?)
+			ON CONFLICT(client_id, scope, target) DO NOTHING`, clientID, scope, target)
+		return err
+	}
+	_, err := s.db.Exec(`DELETE FROM client_alert_mutes WHERE client_id = ? AND scope = ? AND target = ?`,
+		clientID, scope, target)
+	return err
+}
+
+// --- Maintenance windows ---
+
+// Window bounds are always written and compared as UTC time.Time values, so
+// SQLite's text timestamps order correctly without datetime().
+func (s *sqlStore) CreateMaintenanceWindow(w *models.MaintenanceWindow) error {
+	w.StartsAt = w.StartsAt.UTC()
+	w.EndsAt = w.EndsAt.UTC()
+	w.CreatedAt = time.Now().UTC()
+	return s.db.QueryRow(`INSERT INTO maintenance_windows (client_id, starts_at, ends_at, reason, created_at)
+		VALUES (?, ?, ?, ?, ?) RETURNING id`,
+		w.ClientID, w.StartsAt, w.EndsAt, strings.TrimSpace(w.Reason), w.CreatedAt).Scan(&w.ID)
+}
+
+func (s *sqlStore) ListMaintenanceWindows(clientID string) ([]models.MaintenanceWindow, error) {
+	rows, err := s.db.Query(`SELECT id, client_id, starts_at, ends_at, reason, created_at
+		FROM maintenance_windows
+		WHERE client_id = ? AND ends_at > ?
+		ORDER BY starts_at ASC`, clientID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var windows []models.MaintenanceWindow
+	for rows.Next() {
+		var w models.MaintenanceWindow
+		if err := rows.Scan(&w.ID, &w.ClientID, &w.StartsAt, &w.EndsAt, &w.Reason, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, rows.Err()
+}
+
+func (s *sqlStore) GetActiveMaintenanceWindow(clientID string, at time.Time) (*models.MaintenanceWindow, error) {
+	w := &models.MaintenanceWindow{}
+	at = at.UTC()
+	err := s.db.QueryRow(`SELECT id, client_id, starts_at, ends_at, reason, created_at
+		FROM maintenance_windows
+		WHERE client_id = ? AND starts_at <= ? AND ends_at > ?
+		ORDER BY ends_at DESC LIMIT 1`, clientID, at, at).
+		Scan(&w.ID, &w.ClientID, &w.StartsAt, &w.EndsAt, &w.Reason, &w.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (s *sqlStore) DeleteMaintenanceWindow(clientID string, id int64) (bool, error) {
+	res, err := s.db.Exec(`DELETE FROM maintenance_windows WHERE id = ? AND client_id = ?`, id, clientID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// --- Client tokens ---
+
+func (s *sqlStore) CreateClientToken(tokenHash, label string) (*models.ClientToken, error) {
+	t := &models.ClientToken{Label: strings.TrimSpace(label), CreatedAt: time.Now().UTC()}
+	err := s.db.QueryRow(`INSERT INTO client_tokens (token_hash, label, created_at) VALUES (?, ?, ?) RETURNING id`,
+		tokenHash, t.Label, t.CreatedAt).Scan(&t.ID)
+	if err != nil {
+		return nil, fmt.Errorf("create client token: %w", err)
+	}
+	return t, nil
+}
+
+func (s *sqlStore) ListClientTokens() ([]models.ClientToken, error) {
+	rows, err := s.db.Query(`SELECT id, label, created_at, revoked, revoked_at FROM client_tokens ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []models.ClientToken
+	for rows.Next() {
+		var t models.ClientToken
+		var revokedAt sql.NullTime
+		if err := rows.Scan(&t.ID, &t.Label, &t.CreatedAt, &t.Revoked, &revokedAt); err != nil {
+			return nil, err
+		}
+		if revokedAt.Valid {
+			t.RevokedAt = &revokedAt.Time
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+func (s *sqlStore) RevokeClientToken(id int64) (bool, error) {
+	res, err := s.db.Exec(`UPDATE client_tokens SET revoked = TRUE, revoked_at = ? WHERE id = ? AND revoked = FALSE`,
+		time.Now().UTC(), id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (s *sqlStore) ClientTokenValid(tokenHash string) (bool, error) {
+	var n int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM client_tokens WHERE token_hash = ? AND revoked = FALSE`, tokenHash).Scan(&n)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// --- Admin API tokens ---
+
+func (s *sqlStore) CreateAPIToken(tokenHash, label string) (*models.APIToken, error) {
+	t := &models.APIToken{Label: strings.TrimSpace(label), CreatedAt: time.Now().UTC()}
+	err := s.db.QueryRow(`INSERT INTO api_tokens (token_hash, label, created_at) VALUES (?, ?, ?) RETURNING id`,
+		tokenHash, t.Label, t.CreatedAt).Scan(&t.ID)
+	if err != nil {
+		return nil, fmt.Errorf("create api token: %w", err)
+	}
+	return t, nil
+}
+
+func (s *sqlStore) ListAPITokens() ([]models.APIToken, error) {
+	rows, err := s.db.Query(`SELECT id, label, created_at, last_used_at, revoked, revoked_at FROM api_tokens ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []models.APIToken
+	for rows.Next() {
+		var t models.APIToken
+		var lastUsedAt, revokedAt sql.NullTime
+		if err := rows.Scan(&t.ID, &t.Label, &t.CreatedAt, &lastUsedAt, &t.Revoked, &revokedAt); err != nil {
+			return nil, err
+		}
+		if lastUsedAt.Valid {
+			t.LastUsedAt = &lastUsedAt.Time
+		}
+		if revokedAt.Valid {
+			t.RevokedAt = &revokedAt.Time
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+func (s *sqlStore) RevokeAPIToken(id int64) (bool, error) {
+	res, err := s.db.Exec(`UPDATE api_tokens SET revoked = TRUE, revoked_at = ? WHERE id = ? AND revoked = FALSE`,
+		time.Now().UTC(), id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (s *sqlStore) UseAPIToken(tokenHash string) (bool, error) {
+	res, err := s.db.Exec(`UPDATE api_tokens SET last_used_at = ? WHERE token_hash = ? AND revoked = FALSE`,
+		time.Now().UTC(), tokenHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// --- Audit log ---
+
+func (s *sqlStore) InsertAuditEntry(e *models.AuditEntry) error {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	e.CreatedAt = e.CreatedAt.UTC()
+	return s.db.QueryRow(`INSERT INTO audit_log (created_at, action, target, detail, source_ip)
+		VALUES (?, ?, ?, ?, ?) RETURNING id`,
+		e.CreatedAt, e.Action, e.Target, e.Detail, e.SourceIP).Scan(&e.ID)
+}
+
+func (s *sqlStore) ListAuditEntries(limit, offset int) ([]models.AuditEntry, int, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	var total int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM audit_log`).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count audit entries: %w", err)
+	}
+	rows, err := s.db.Query(`SELECT id, created_at, action, target, detail, source_ip
+		FROM audit_log ORDER BY id DESC LIMIT ? OFFSET ?`, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(&e.ID, &e.CreatedAt, &e.Action, &e.Target, &e.Detail, &e.SourceIP); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, total, rows.Err()
+}
+
+// --- Metrics ---
+
+// InsertMetrics stores a metrics sample. Percentages are clamped to [0,100] so
+// small collector overshoots (rounding, counter glitches) don't skew charts;
+// grossly invalid values are rejected before this by check-in validation.
+func (s *sqlStore) InsertMetrics(clientID string, m models.MetricsPayload) error {
+	m.CPUPercent = ClampPercent(m.CPUPercent)
+	m.MemPercent = ClampPercent(m.MemPercent)
+	m.DiskPercent = ClampPercent(m.DiskPercent)
+	m.SwapPercent = ClampPercent(m.SwapPercent)
+	m.DiskInodesUsedPercent = clampOptionalPercent(m.DiskInodesUsedPercent)
+	_, err := s.db.Exec(`INSERT INTO metrics (client_id, cpu_pct, mem_pct, disk_pct,
+		mem_total_bytes, mem_used_bytes, disk_total_bytes, disk_used_bytes, net_rx_bytes, net_tx_bytes,
+		swap_pct, swap_total_bytes, swap_used_bytes, cpu_temp_c, open_fds, process_count, disk_inodes_pct)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		clientID, m.CPUPercent, m.MemPercent, m.DiskPercent,
+		m.MemTotalBytes, m.MemUsedBytes, m.DiskTotalBytes, m.DiskUsedBytes, m.NetRxBytes, m.NetTxBytes,
+		m.SwapPercent, m.SwapTotalBytes, m.SwapUsedBytes, m.CPUTempC, m.OpenFDs, m.ProcessCount, m.DiskInodesUsedPercent)
+	return err
+}
+
+// InsertMetricsAt stores a metrics sample with an explicit timestamp, used for
+// samples the client buffered while the server was unreachable.
+func (s *sqlStore) InsertMetricsAt(clientID string, recordedAt time.Time, m models.MetricsPayload) error {
+	m.CPUPercent = ClampPercent(m.CPUPercent)
+	m.MemPercent = ClampPercent(m.MemPercent)
+	m.DiskPercent = ClampPercent(m.DiskPercent)
+	m.SwapPercent = ClampPercent(m.SwapPercent)
+	m.DiskInodesUsedPercent = clampOptionalPercent(m.DiskInodesUsedPercent)
+	_, err := s.db.Exec(`INSERT INTO metrics (client_id, recorded_at, cpu_pct, mem_pct, disk_pct,
+		mem_total_bytes, mem_used_bytes, disk_total_bytes, disk_used_bytes, net_rx_bytes, net_tx_bytes,
+		swap_pct, swap_total_bytes, swap_used_bytes, cpu_temp_c, open_fds, process_count, disk_inodes_pct)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		clientID, recordedAt.UTC().Format("2006-01-02 15:04:05"), m.CPUPercent, m.MemPercent, m.DiskPercent,
+		m.MemTotalBytes, m.MemUsedBytes, m.DiskTotalBytes, m.DiskUsedBytes, m.NetRxBytes, m.NetTxBytes,
+		m.SwapPercent, m.SwapTotalBytes, m.SwapUsedBytes, m.CPUTempC, m.OpenFDs, m.ProcessCount, m.DiskInodesUsedPercent)
+	return err
+}
+
+func (s *sqlStore) GetLatestMetrics(clientID string) (*models.Metric, error) {
+	m := &models.Metric{}
+	err := s.db.QueryRow(`SELECT id, client_id, recorded_at, cpu_pct, mem_pct, disk_pct,
+		mem_total_bytes, mem_used_bytes, disk_total_bytes, disk_used_bytes, net_rx_bytes, net_tx_bytes,
+		swap_pct, swap_total_bytes, swap_used_bytes, cpu_temp_c, open_fds, process_count, disk_inodes_pct
+		FROM metrics WHERE client_id = ? ORDER BY recorded_at DESC LIMIT 1`, clientID).Scan(
+		&m.ID, &m.ClientID, &m.RecordedAt, &m.CPUPercent, &m.MemPercent, &m.DiskPercent,
+		&m.MemTotalBytes, &m.MemUsedBytes, &m.DiskTotalBytes, &m.DiskUsedBytes, &m.NetRxBytes, &m.NetTxBytes,
+		&m.SwapPercent, &m.SwapTotalBytes, &m.SwapUsedBytes, &m.CPUTempC, &m.OpenFDs, &m.ProcessCount, &m.DiskInodesUsedPercent)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (s *sqlStore) GetMetrics(clientID string, from, to time.Time, limit int) ([]models.Metric, error) {
+	if limit <= 0 {
+		limit = 500
+	}
+	fromUTC := from.UTC().Format("2006-01-02 15:04:05")
+	toUTC := to.UTC().Format("2006-01-02 15:04:05")
+	rows, err := s.db.Query(`SELECT id, client_id, recorded_at, cpu_pct, mem_pct, disk_pct,
+		mem_total_bytes, mem_used_bytes, disk_total_bytes, disk_used_bytes, net_rx_bytes, net_tx_bytes,
+		swap_pct, swap_total_bytes, swap_used_bytes, cpu_temp_c, open_fds, process_count, disk_inodes_pct
+		FROM metrics
+		WHERE client_id = ?
+			AND `+s.db.timeBetween("recorded_at")+`
+		ORDER BY recorded_at ASC LIMIT ?`, clientID, fromUTC, toUTC, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var metrics []models.Metric
+	for rows.Next() {
+		var m models.Metric
+		err := rows.Scan(&m.ID, &m.ClientID, &m.RecordedAt, &m.CPUPercent, &m.MemPercent, &m.DiskPercent,
+			&m.MemTotalBytes, &m.MemUsedBytes, &m.DiskTotalBytes, &m.DiskUsedBytes, &m.NetRxBytes, &m.NetTxBytes,
+			&m.SwapPercent, &m.SwapTotalBytes, &m.SwapUsedBytes, &m.CPUTempC, &m.OpenFDs, &m.ProcessCount, &m.DiskInodesUsedPercent)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	computeNetRates(metrics)
+	return metrics, nil
+}
+
+func (s *sqlStore) GetMetricsBucketed(clientID string, from, to time.Time, bucket time.Duration, limit int) ([]models.Metric, error) {
+	if limit <= 0 {
+		limit = 500
+	}
+	secs := int64(bucket / time.Second)
+	if secs < 1 {
+		return nil, fmt.Errorf("bucket must be at least one second")
+	}
+	fromUTC := from.UTC().Format("2006-01-02 15:04:05")
+	toUTC := to.UTC().Format("2006-01-02 15:04:05")
+	rows, err := s.db.Query(`SELECT (`+s.db.epochSeconds("recorded_at")+` / ?) * ? AS bucket_start,
+		AVG(cpu_pct), MAX(cpu_pct), AVG(mem_pct), MAX(mem_pct), AVG(disk_pct), MAX(disk_pct),
+		MAX(mem_total_bytes), AVG(mem_used_bytes), MAX(disk_total_bytes), AVG(disk_used_bytes),
+		MAX(net_rx_bytes), MAX(net_tx_bytes), AVG(swap_pct), MAX(swap_total_bytes), AVG(swap_used_bytes), AVG(cpu_temp_c),
+		MAX(open_fds), MAX(process_count), AVG(disk_inodes_pct), COUNT(*)
+		FROM metrics
+		WHERE client_id = ?
+			AND `+s.db.timeBetween("recorded_at")+`
+		GROUP BY bucket_start
+		ORDER BY bucket_start ASC LIMIT ?`, secs, secs, clientID, fromUTC, toUTC, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var metrics []models.Metric
+	for rows.Next() {
+		var m models.Metric
+		var bucketStart int64
+		var memUsed, diskUsed, swapUsed float64
+		var cpuTemp sql.NullFloat64
+		var openFDs, procCount sql.NullInt64
+		var inodesPct sql.NullFloat64
+		err := rows.Scan(&bucketStart, &m.CPUPercent, &m.CPUPeakPercent, &m.MemPercent, &m.MemPeakPercent,
+			&m.DiskPercent, &m.DiskPeakPercent, &m.MemTotalBytes, &memUsed, &m.DiskTotalBytes, &diskUsed,
+			&m.NetRxBytes, &m.NetTxBytes, &m.SwapPercent, &m.SwapTotalBytes, &swapUsed, &cpuTemp,
+			&openFDs, &procCount, &inodesPct, &m.Samples)
+		if err != nil {
+			return nil, err
+		}
+		m.ClientID = clientID
+		m.RecordedAt = time.Unix(bucketStart, 0).UTC()
+		m.MemUsedBytes = uint64(memUsed)
+		m.DiskUsedBytes = uint64(diskUsed)
+		m.SwapUsedBytes = uint64(swapUsed)
+		if cpuTemp.Valid {
+			m.CPUTempC = &cpuTemp.Float64
+		}
+		// Bucket peaks: leaks and runaway forks show up as maxima.
+		if openFDs.Valid {
+			m.OpenFDs = &openFDs.Int64
+		}
+		if procCount.Valid {
+			m.ProcessCount = &procCount.Int64
+		}
+		if inodesPct.Valid {
+			m.DiskInodesUsedPercent = &inodesPct.Float64
+		}
+		metrics = append(metrics, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	computeNetRates(metrics)
+	return metrics, nil
+}
+
+func (s *sqlStore) ForEachMetric(clientID string, from, to time.Time, fn func(models.Metric) error) error {
+	fromUTC := from.UTC().Format("2006-01-02 15:04:05")
+	toUTC := to.UTC().Format("2006-01-02 15:04:05")
+	rows, err := s.db.Query(`SELECT id, client_id, recorded_at, cpu_pct, mem_pct, disk_pct,
+		mem_total_bytes, mem_used_bytes, disk_total_bytes, disk_used_bytes, net_rx_bytes, net_tx_bytes,
+		swap_pct, swap_total_bytes, swap_used_bytes, cpu_temp_c, open_fds, process_count, disk_inodes_pct
+		FROM metrics
+		WHERE client_id = ?
+			AND `+s.db.timeBetween("recorded_at")+`
+		ORDER BY recorded_at ASC`, clientID, fromUTC, toUTC)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var prev *models.Metric
+	for rows.Next() {
+		var m models.Metric
+		err := rows.Scan(&m.ID, &m.ClientID, &m.RecordedAt, &m.CPUPercent, &m.MemPercent, &m.DiskPercent,
+			&m.MemTotalBytes, &m.MemUsedBytes, &m.DiskTotalBytes, &m.DiskUsedBytes, &m.NetRxBytes, &m.NetTxBytes,
+			&m.SwapPercent, &m.SwapTotalBytes, &m.SwapUsedBytes, &m.CPUTempC, &m.OpenFDs, &m.ProcessCount, &m.DiskInodesUsedPercent)
+		if err != nil {
+			return err
+		}
+		if prev != nil {
+			setNetRate(prev, &m)
+		}
+		if err := fn(m); err != nil {
+			return err
+		}
+		prev = &m
+	}
+	return rows.Err()
+}
+
+// computeNetRates fills per-second network rates from consecutive samples
+// (ordered oldest first).
+func computeNetRates(metrics []models.Metric) {
+	for i := 1; i < len(metrics); i++ {
+		setNetRate(&metrics[i-1], &metrics[i])
+	}
+}
+
+// setNetRate derives cur's network rates from the preceding sample. A counter
+// going backwards means the host rebooted or an interface reset, so that
+// sample's rate is left at zero.
+func setNetRate(prev, cur *models.Metric) {
+	secs := cur.RecordedAt.Sub(prev.RecordedAt).Seconds()
+	if secs <= 0 {
+		return
+	}
+	if cur.NetRxBytes >= prev.NetRxBytes {
+		cur.NetRxBytesPerSec = float64(cur.NetRxBytes-prev.NetRxBytes) / secs
+	}
+	if cur.NetTxBytes >= prev.NetTxBytes {
+		cur.NetTxBytesPerSec = float64(cur.NetTxBytes-prev.NetTxBytes) / secs
+	}
+}
+
+func (s *sqlStore) GetDiskUsageDelta(clientID string, since time.Time) (*models.UsageDelta, error) {
+	sinceUTC := since.UTC().Format("2006-01-02 15:04:05")
+	nowUTC := time.Now().UTC().Format("2006-01-02 15:04:05")
+	sample := func(order string) (float64, time.Time, error) {
+		var pct float64
+		var at time.Time
+		err := s.db.QueryRow(`SELECT disk_pct, recorded_at FROM metrics
+			WHERE client_id = ? AND `+s.db.timeBetween("recorded_at")+`
+			ORDER BY recorded_at `+order+` LIMIT 1`, clientID, sinceUTC, nowUTC).Scan(&pct, &at)
+		return pct, at, err
+	}
+
+	d := &models.UsageDelta{}
+	var err error
+	if d.FromPercent, d.FromAt, err = sample("ASC"); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if d.ToPercent, d.ToAt, err = sample("DESC"); err != nil {
+		return nil, err
+	}
+	if !d.ToAt.After(d.FromAt) {
+		return nil, nil
+	}
+	return d, nil
+}
+
+func (s *sqlStore) GetRecentMetrics(clientID string, limit int) ([]models.Metric, error) {
+	if limit <= 0 {
+		return []models.Metric{}, nil
+	}
+	rows, err := s.db.Query(`SELECT id, client_id, recorded_at, cpu_pct, mem_pct, disk_pct,
+		mem_total_bytes, mem_used_bytes, disk_total_bytes, disk_used_bytes, net_rx_bytes, net_tx_bytes,
+		swap_pct, swap_total_bytes, swap_used_bytes, cpu_temp_c, open_fds, process_count, disk_inodes_pct
+		FROM metrics
+		WHERE client_id = ?
+		ORDER BY recorded_at DESC
+		LIMIT ?`, clientID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var metrics []models.Metric
+	for rows.Next() {
+		var m models.Metric
+		err := rows.Scan(&m.ID, &m.ClientID, &m.RecordedAt, &m.CPUPercent, &m.MemPercent, &m.DiskPercent,
+			&m.MemTotalBytes, &m.MemUsedBytes, &m.DiskTotalBytes, &m.DiskUsedBytes, &m.NetRxBytes, &m.NetTxBytes,
+			&m.SwapPercent, &m.SwapTotalBytes, &m.SwapUsedBytes, &m.CPUTempC, &m.OpenFDs, &m.ProcessCount, &m.DiskInodesUsedPercent)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, rows.Err()
+}
+
+// --- Disk mounts ---
+
+// InsertDiskMounts replaces the client's monitored mounts with the reported
+// set, keeping each mount's alert state so transitions survive check-ins.
+func (s *sqlStore) InsertDiskMounts(clientID string, mounts []models.DiskMountPayload) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	keep := make([]interface{}, 0, len(mounts)+1)
+	keep = append(keep, clientID)
+	placeholders := make([]string, 0, len(mounts))
+	now := time.Now().UTC()
+	for _, m := range mounts {
+		if strings.TrimSpace(m.Path) == "" {
+			continue
+		}
+		_, err := tx.Exec(`INSERT INTO disk_mounts (client_id, mount_path, recorded_at, total_bytes, used_bytes, used_pct, warn_pct, crit_pct, error)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(client_id, mount_path) DO UPDATE SET
+				recorded_at = excluded.recorded_at,
+				total_bytes = excluded.total_bytes,
+				used_bytes = excluded.used_bytes,
+				used_pct = excluded.used_pct,
+				warn_pct = excluded.warn_pct,
+				crit_pct = excluded.crit_pct,
+				error = excluded.error`,
+			clientID, m.Path, now, m.TotalBytes, m.UsedBytes, ClampPercent(m.UsedPercent),
+			nullablePositiveFloat(m.WarnPct), nullablePositiveFloat(m.CritPct), m.Error)
+		if err != nil {
+			return fmt.Errorf("upsert disk mount %q: %w", m.Path, err)
+		}
+		keep = append(keep, m.Path)
+		placeholders = append(placeholders, "?")
+	}
+
+	deleteSQL := `DELETE FROM disk_mounts WHERE client_id = ?`
+	if len(placeholders) > 0 {
+		deleteSQL += ` AND mount_path NOT IN (` + strings.Join(placeholders, ",") + `)`
+	}
+	if _, err := tx.Exec(deleteSQL, keep...); err != nil {
+		return fmt.Errorf("prune disk mounts: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) GetLatestDiskMounts(clientID string) ([]models.DiskMount, error) {
+	rows, err := s.db.Query(`SELECT client_id, mount_path, recorded_at, total_bytes, used_bytes, used_pct,
+		warn_pct, crit_pct, error, alert_state
+		FROM disk_mounts WHERE client_id = ? ORDER BY mount_path`, clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mounts []models.DiskMount
+	for rows.Next() {
+		var m models.DiskMount
+		var warnPct, critPct sql.NullFloat64
+		if err := rows.Scan(&m.ClientID, &m.Path, &m.RecordedAt, &m.TotalBytes, &m.UsedBytes, &m.UsedPercent,
+			&warnPct, &critPct, &m.Error, &m.AlertState); err != nil {
+			return nil, err
+		}
+		if warnPct.Valid {
+			v := warnPct.Float64
+			m.WarnPct = &v
+		}
+		if critPct.Valid {
+			v := critPct.Float64
+			m.CritPct = &v
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts, rows.Err()
+}
+
+func (s *sqlStore) SetDiskMountAlertState(clientID, path, state string) error {
+	_, err := s.db.Exec(`UPDATE disk_mounts SET alert_state = ? WHERE client_id = ? AND mount_path = ?`, state, clientID, path)
+	return err
+}
+
+// ClampPercent limits a percentage to [0,100].
+func ClampPercent(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 100 {
+		return 100
+	}
+	return v
+}
+
+// clampOptionalPercent clamps v like ClampPercent, leaving nil readings nil.
+func clampOptionalPercent(v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	c := ClampPercent(*v)
+	return &c
+}
+
+func nullablePositiveFloat(v float64) interface{} {
+	if v <= 0 {
+		return nil
+	}
+	return v
+}
+
+// --- Process tracking ---
+
+func (s *sqlStore) UpsertWatchedProcesses(clientID string, procs []models.ProcessPayload) error {
+	// If the client sends no watched processes, clear them all.
+	if len(procs) == 0 {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if _, err := tx.Exec(`DELETE FROM watched_processes WHERE client_id = ?`, clientID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM process_snapshots WHERE client_id = ?`, clientID); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Remove processes no longer configured by the client.
+	placeholders := make([]string, len(procs))
+	args := make([]interface{}, 0, len(procs)+1)
+	args = append(args, clientID)
+	for i, p := range procs {
+		placeholders[i] = "?"
+		args = append(args, p.FriendlyName)
+	}
+	deleteSQL := fmt.Sprintf(`DELETE FROM watched_processes
+		WHERE client_id = ? AND friendly_name NOT IN (%s)`, strings.Join(placeholders, ","))
+	if _, err := tx.Exec(deleteSQL, args...); err != nil {
+		return fmt.Errorf("delete stale watched processes: %w", err)
+	}
+	deleteSnapshotsSQL := fmt.Sprintf(`DELETE FROM process_snapshots
+		WHERE client_id = ? AND friendly_name NOT IN (%s)`, strings.Join(placeholders, ","))
+	if _, err := tx.Exec(deleteSnapshotsSQL, args...); err != nil {
+		return fmt.Errorf("delete stale process snapshots: %w", err)
+	}
+
+	for _, p := range procs {
+		matchType := p.MatchType
+		if matchType == "" {
+			matchType = "substring"
+		}
+		_, err := tx.Exec(`INSERT INTO watched_processes (client_id, friendly_name, match_pattern, match_type,
+				cpu_alert_pct, mem_alert_pct, alert_on_start, min_instances)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(client_id, friendly_name) DO UPDATE SET
+				match_pattern = excluded.match_pattern,
+				match_type = excluded.match_type,
+				cpu_alert_pct = excluded.cpu_alert_pct,
+				mem_alert_pct = excluded.mem_alert_pct,
+				alert_on_start = excluded.alert_on_start,
+				min_instances = excluded.min_instances`,
+			clientID, p.FriendlyName, p.MatchPattern, matchType,
+			nullablePositiveFloat(p.CPUAlertPct), nullablePositiveFloat(p.MemAlertPct), p.AlertOnStart, p.MinInstances)
+		if err != nil {
+			return fmt.Errorf("upsert watched process %q: %w", p.FriendlyName, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// UpdateWatchedProcess sets the dashboard note and expected state of a watched
+// process. It reports false when the client doesn't watch a process by that
+// name.
+func (s *sqlStore) UpdateWatchedProcess(clientID, friendlyName, note, expectedState string) (bool, error) {
+	res, err := s.db.Exec(`UPDATE watched_processes SET note = ?, expected_state = ?
+		WHERE client_id = ? AND friendly_name = ?`, note, expectedState, clientID, friendlyName)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *sqlStore) DeleteWatchedProcess(clientID, friendlyName string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM watched_processes WHERE client_id = ? AND friendly_name = ?`, clientID, friendlyName); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM process_snapshots WHERE client_id = ? AND friendly_name = ?`, clientID, friendlyName); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM process_restarts WHERE client_id = ? AND friendly_name = ?`, clientID, friendlyName); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) DeleteCheckSnapshots(clientID, friendlyName, checkType string) error {
+	if strings.TrimSpace(checkType) == "" {
+		_, err := s.db.Exec(`DELETE FROM check_snapshots WHERE client_id = ? AND friendly_name = ?`, clientID, friendlyName)
+		return err
+	}
+	_, err := s.db.Exec(`DELETE FROM check_snapshots WHERE client_id = ? AND friendly_name = ? AND check_type = ?`, clientID, friendlyName, checkType)
+	return err
+}
+
+func (s *sqlStore) InsertProcessSnapshots(clientID string, procs []models.ProcessPayload) error {
+	if len(procs) == 0 {
+		return nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	previous, err := getLatestProcessSnapshotStatesTx(tx, clientID)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO process_snapshots (client_id, friendly_name, is_running, pid, cpu_pct, mem_pct, cmdline, uptime_since_at, num_fds, num_threads, instance_count)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+	for _, p := range procs {
+		pidPtr := pidPointer(p.PID)
+		var pid interface{}
+		if pidPtr != nil {
+			pid = *pidPtr
+		}
+		uptimeSince := now
+		if prev, ok := previous[p.FriendlyName]; ok {
+			// An aggregated group reports its lowest PID, which changes
+			// whenever that worker is replaced; only stopping restarts it.
+			grouped := p.MinInstances > 0 || p.InstanceCount > 1 || prev.InstanceCount > 1
+			pidChanged := !grouped && !pidEqual(prev.PID, pidPtr)
+			if prev.IsRunning == p.IsRunning && !pidChanged && prev.UptimeSinceAt.Valid {
+				uptimeSince = prev.UptimeSinceAt.Time.UTC()
+			}
+			// A new instance came up: either it was down before or the PID changed.
+			if p.IsRunning && (!prev.IsRunning || (pidChanged && prev.PID != nil && pidPtr != nil)) {
+				var oldPID interface{}
+				if prev.IsRunning && prev.PID != nil {
+					oldPID = *prev.PID
+				}
+				if _, err := tx.Exec(`INSERT INTO process_restarts (client_id, friendly_name, restarted_at, old_pid, new_pid)
+					VALUES (?, ?, ?, ?, ?)`, clientID, p.FriendlyName, now, oldPID, pid); err != nil {
+					return err
+				}
+			}
+		}
+
+		_, err := stmt.Exec(clientID, p.FriendlyName, p.IsRunning, pid, p.CPUPercent, ClampPercent(p.MemPercent), p.Cmdline, uptimeSince,
+			nullablePositiveInt32(p.NumFDs), nullablePositiveInt32(p.NumThreads), p.InstanceCount)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) GetLatestProcessSnapshots(clientID string) ([]models.ProcessSnapshot, error) {
+	rows, err := s.db.Query(`SELECT ps.id, ps.client_id, ps.friendly_name, ps.recorded_at,
+		ps.uptime_since_at, ps.is_running, ps.pid, ps.cpu_pct, ps.mem_pct, ps.cmdline, ps.num_fds, ps.num_threads, ps.instance_count
+		FROM process_snapshots ps
+		INNER JOIN watched_processes wp ON wp.client_id = ps.client_id AND wp.friendly_name = ps.friendly_name
+		INNER JOIN (
+			SELECT ps2.friendly_name, MAX(ps2.recorded_at) as max_time
+			FROM process_snapshots ps2
+			INNER JOIN watched_processes wp2 ON wp2.client_id = ps2.client_id AND wp2.friendly_name = ps2.friendly_name
+			WHERE ps2.client_id = ?
+			GROUP BY ps2.friendly_name
+		) latest ON ps.friendly_name = latest.friendly_name AND ps.recorded_at = latest.max_time
+		WHERE ps.client_id = ?`, clientID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanProcessSnapshots(rows)
+}
+
+func (s *sqlStore) GetPreviousProcessSnapshots(clientID string) ([]models.ProcessSnapshot, error) {
+	// Get the second-most-recent snapshot for each process
+	rows, err := s.db.Query(`SELECT ps.id, ps.client_id, ps.friendly_name, ps.recorded_at,
+		ps.uptime_since_at, ps.is_running, ps.pid, ps.cpu_pct, ps.mem_pct, ps.cmdline, ps.num_fds, ps.num_threads, ps.instance_count
+		FROM process_snapshots ps
+		INNER JOIN watched_processes wp ON wp.client_id = ps.client_id AND wp.friendly_name = ps.friendly_name
+		INNER JOIN (
+			SELECT ps2.friendly_name, MAX(ps2.recorded_at) as max_time
+			FROM process_snapshots ps2
+			INNER JOIN watched_processes wp2 ON wp2.client_id = ps2.client_id AND wp2.friendly_name = ps2.friendly_name
+			WHERE ps2.client_id = ? AND ps2.recorded_at < (
+				SELECT MAX(ps3.recorded_at) FROM process_snapshots ps3
+				INNER JOIN watched_processes wp3 ON wp3.client_id = ps3.client_id AND wp3.friendly_name = ps3.friendly_name
+				WHERE ps3.client_id = ?
+			)
+			GROUP BY ps2.friendly_name
+		) prev ON ps.friendly_name = prev.friendly_name AND ps.recorded_at = prev.max_time
+		WHERE ps.client_id = ?`, clientID, clientID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanProcessSnapshots(rows)
+}
+
+// GetRecentProcessSnapshots returns up to limit snapshots for one process, newest first.
+func (s *sqlStore) GetRecentProcessSnapshots(clientID, friendlyName string, limit int) ([]models.ProcessSnapshot, error) {
+	if limit <= 0 {
+		return []models.ProcessSnapshot{}, nil
+	}
+	rows, err := s.db.Query(`SELECT ps.id, ps.client_id, ps.friendly_name, ps.recorded_at,
+		ps.uptime_since_at, ps.is_running, ps.pid, ps.cpu_pct, ps.mem_pct, ps.cmdline, ps.num_fds, ps.num_threads, ps.instance_count
+		FROM process_snapshots ps
+		WHERE ps.client_id = ? AND ps.friendly_name = ?
+		ORDER BY ps.recorded_at DESC
+		LIMIT ?`, clientID, friendlyName, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanProcessSnapshots(rows)
+}
+
+func (s *sqlStore) GetWatchedProcesses(clientID string) ([]models.WatchedProcess, error) {
+	rows, err := s.db.Query(`SELECT id, client_id, friendly_name, match_pattern, match_type,
+		cpu_alert_pct, mem_alert_pct, alert_on_start, min_instances, note, expected_state
+		FROM watched_processes WHERE client_id = ?`, clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var procs []models.WatchedProcess
+	for rows.Next() {
+		var p models.WatchedProcess
+		var cpuAlert, memAlert sql.NullFloat64
+		if err := rows.Scan(&p.ID, &p.ClientID, &p.FriendlyName, &p.MatchPattern, &p.MatchType,
+			&cpuAlert, &memAlert, &p.AlertOnStart, &p.MinInstances, &p.Note, &p.ExpectedState); err != nil {
+			return nil, err
+		}
+		p.CPUAlertPct = cpuAlert.Float64
+		p.MemAlertPct = memAlert.Float64
+		procs = append(procs, p)
+	}
+	return procs, rows.Err()
+}
+
+func scanProcessSnapshots(rows *sql.Rows) ([]models.ProcessSnapshot, error) {
+	var snaps []models.ProcessSnapshot
+	for rows.Next() {
+		var ps models.ProcessSnapshot
+		var pid sql.NullInt32
+		var uptimeSince sql.NullTime
+		var cpuPct, memPct sql.NullFloat64
+		var cmdline sql.NullString
+		var numFDs, numThreads sql.NullInt32
+		err := rows.Scan(&ps.ID, &ps.ClientID, &ps.FriendlyName, &ps.RecordedAt,
+			&uptimeSince, &ps.IsRunning, &pid, &cpuPct, &memPct, &cmdline, &numFDs, &numThreads, &ps.InstanceCount)
+		if err != nil {
+			return nil, err
+		}
+		if uptimeSince.Valid {
+			ps.UptimeSinceAt = uptimeSince.Time
+		} else {
+			ps.UptimeSinceAt = ps.RecordedAt
+		}
+		if pid.Valid {
+			v := pid.Int32
+			ps.PID = &v
+		}
+		ps.CPUPercent = cpuPct.Float64
+		ps.MemPercent = memPct.Float64
+		ps.Cmdline = cmdline.String
+		if numFDs.Valid {
+			v := numFDs.Int32
+			ps.NumFDs = &v
+		}
+		if numThreads.Valid {
+			v := numThreads.Int32
+			ps.NumThreads = &v
+		}
+		// Clients that predate instance counting report a single match.
+		if ps.IsRunning && ps.InstanceCount == 0 {
+			ps.InstanceCount = 1
+		}
+		snaps = append(snaps, ps)
+	}
+	return snaps, rows.Err()
+}
+
+// CountProcessRestarts returns how many times each watched process restarted
+// since the given time, keyed by friendly name. Processes that did not
+// restart are absent from the map.
+func (s *sqlStore) CountProcessRestarts(clientID string, since time.Time) (map[string]int, error) {
+	rows, err := s.db.Query(`SELECT friendly_name, COUNT(*) FROM process_restarts
+		WHERE client_id = ? AND restarted_at >= ?
+		GROUP BY friendly_name`, clientID, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var name string
+		var n int
+		if err := rows.Scan(&name, &n); err != nil {
+			return nil, err
+		}
+		counts[name] = n
+	}
+	return counts, rows.Err()
+}
+
+type processSnapshotState struct {
+	IsRunning     bool
+	PID           *int32
+	UptimeSinceAt sql.NullTime
+	InstanceCount int
+}
+
+func getLatestProcessSnapshotStatesTx(tx *sqlTx, clientID string) (map[string]processSnapshotState, error) {
+	rows, err := tx.Query(`SELECT ps.friendly_name, ps.is_running, ps.pid, ps.uptime_since_at, ps.instance_count
+		FROM process_snapshots ps
+		INNER JOIN (
+			SELECT friendly_name, MAX(recorded_at) as max_time
+			FROM process_snapshots
+			WHERE client_id = ?
+			GROUP BY friendly_name
+		) latest ON ps.friendly_name = latest.friendly_name AND ps.recorded_at = latest.max_time
+		WHERE ps.client_id = ?`, clientID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := make(map[string]processSnapshotState)
+	for rows.Next() {
+		var name string
+		var state processSnapshotState
+		var pid sql.NullInt32
+		if err := rows.Scan(&name, &state.IsRunning, &pid, &state.UptimeSinceAt, &state.InstanceCount); err != nil {
+			return nil, err
+		}
+		if pid.Valid {
+			v := pid.Int32
+			state.PID = &v
+		}
+		states[name] = state
+	}
+	return states, rows.Err()
+}
+
+// nullablePositiveInt32 stores 0 (not collected) as NULL.
+func nullablePositiveInt32(v int32) interface{} {
+	if v <= 0 {
+		return nil
+	}
+	return v
+}
+
+func pidPointer(pid int32) *int32 {
+	if pid <= 0 {
+		return nil
+	}
+	v := pid
+	return &v
+}
+
+func pidEqual(a, b *int32) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// --- Checks (extensible typed check system) ---
+
+func (s *sqlStore) InsertCheckSnapshots(clientID string, checks []models.CheckPayload) error {
+	if len(checks) == 0 {
+		return nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	previous, err := getLatestCheckSnapshotStatesTx(tx, clientID)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO check_snapshots (client_id, friendly_name, check_type, healthy, message, state, uptime_since_at, severity, consecutive_failures)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+	for _, c := range checks {
+		uptimeSince := now
+		key := checkSnapshotKey(c.FriendlyName, c.CheckType)
+		if prev, ok := previous[key]; ok {
+			if prev.Healthy == c.Healthy && prev.UptimeSinceAt.Valid {
+				uptimeSince = prev.UptimeSinceAt.Time.UTC()
+			}
+		}
+		_, err := stmt.Exec(clientID, c.FriendlyName, c.CheckType, c.Healthy, c.Message, c.State, uptimeSince, c.Severity, c.ConsecutiveFailures)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) GetLatestCheckSnapshots(clientID string) ([]models.CheckSnapshot, error) {
+	rows, err := s.db.Query(`SELECT cs.id, cs.client_id, cs.friendly_name, cs.check_type,
+		cs.recorded_at, cs.uptime_since_at, cs.healthy, cs.message, cs.state, cs.severity, cs.consecutive_failures
+		FROM check_snapshots cs
+		INNER JOIN (
+			SELECT friendly_name, check_type, MAX(recorded_at) as max_time
+			FROM check_snapshots WHERE client_id = ?
+			GROUP BY friendly_name, check_type
+		) latest ON cs.friendly_name = latest.friendly_name AND cs.check_type = latest.check_type AND cs.recorded_at = latest.max_time
+		WHERE cs.client_id = ?`, clientID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanCheckSnapshots(rows)
+}
+
+func (s *sqlStore) GetPreviousCheckSnapshots(clientID string) ([]models.CheckSnapshot, error) {
+	rows, err := s.db.Query(`SELECT cs.id, cs.client_id, cs.friendly_name, cs.check_type,
+		cs.recorded_at, cs.uptime_since_at, cs.healthy, cs.message, cs.state, cs.severity, cs.consecutive_failures
+		FROM check_snapshots cs
+		INNER JOIN (
+			SELECT friendly_name, check_type, MAX(recorded_at) as max_time
+			FROM check_snapshots
+			WHERE client_id = ? AND recorded_at < (
+				SELECT MAX(recorded_at) FROM check_snapshots WHERE client_id = ?
+			)
+			GROUP BY friendly_name, check_type
+		) prev ON cs.friendly_name = prev.friendly_name AND cs.check_type = prev.check_type AND cs.recorded_at = prev.max_time
+		WHERE cs.client_id = ?`, clientID, clientID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanCheckSnapshots(rows)
+}
+
+// GetRecentCheckSnapshots returns up to limit of the newest snapshots of one
+// check, newest first.
+func (s *sqlStore) GetRecentCheckSnapshots(clientID, friendlyName, checkType string, limit int) ([]models.CheckSnapshot, error) {
+	rows, err := s.db.Query(`SELECT cs.id, cs.client_id, cs.friendly_name, cs.check_type,
+		cs.recorded_at, cs.uptime_since_at, cs.healthy, cs.message, cs.state, cs.severity, cs.consecutive_failures
+		FROM check_snapshots cs
+		WHERE cs.client_id = ? AND cs.friendly_name = ? AND cs.check_type = ?
+		ORDER BY cs.recorded_at DESC, cs.id DESC
+		LIMIT ?`, clientID, friendlyName, checkType, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanCheckSnapshots(rows)
+}
+
+func scanCheckSnapshots(rows *sql.Rows) ([]models.CheckSnapshot, error) {
+	var snaps []models.CheckSnapshot
+	for rows.Next() {
+		var cs models.CheckSnapshot
+		var uptimeSince sql.NullTime
+		var message, state, severity sql.NullString
+		err := rows.Scan(&cs.ID, &cs.ClientID, &cs.FriendlyName, &cs.CheckType,
+			&cs.RecordedAt, &uptimeSince, &cs.Healthy, &message, &state, &severity, &cs.ConsecutiveFailures)
+		if err != nil {
+			return nil, err
+		}
+		if uptimeSince.Valid {
+			cs.UptimeSinceAt = uptimeSince.Time
+		} else {
+			cs.UptimeSinceAt = cs.RecordedAt
+		}
+		cs.Message = message.String
+		cs.State = state.String
+		cs.Severity = severity.String
+		snaps = append(snaps, cs)
+	}
+	return snaps, rows.Err()
+}
+
+type checkSnapshotState struct {
+	Healthy       bool
+	UptimeSinceAt sql.NullTime
+}
+
+func getLatestCheckSnapshotStatesTx(tx *sqlTx, clientID string) (map[string]checkSnapshotState, error) {
+	rows, err := tx.Query(`SELECT cs.friendly_name, cs.check_type, cs.healthy, cs.uptime_since_at
+		FROM check_snapshots cs
+		INNER JOIN (
+			SELECT friendly_name, check_type, MAX(recorded_at) as max_time
+			FROM check_snapshots
+			WHERE client_id = ?
+			GROUP BY friendly_name, check_type
+		) latest ON cs.friendly_name = latest.friendly_name AND cs.check_type = latest.check_type AND cs.recorded_at = latest.max_time
+		WHERE cs.client_id = ?`, clientID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := make(map[string]checkSnapshotState)
+	for rows.Next() {
+		var friendlyName, checkType string
+		var state checkSnapshotState
+		if err := rows.Scan(&friendlyName, &checkType, &state.Healthy, &state.UptimeSinceAt); err != nil {
+			return nil, err
+		}
+		states[checkSnapshotKey(friendlyName, checkType)] = state
+	}
+	return states, rows.Err()
+}
+
+func checkSnapshotKey(friendlyName, checkType string) string {
+	return strings.TrimSpace(friendlyName) + "::" + strings.TrimSpace(checkType)
+}
+
+// --- Alerts ---
+
+func (s *sqlStore) InsertAlert(a *models.Alert) error {
+	return s.db.QueryRow(`INSERT INTO alerts (client_id, alert_type, target, severity, message, details)
+		VALUES (?, ?, ?, ?, ?, ?) RETURNING id`,
+		a.ClientID, a.AlertType, a.Target, a.Severity, a.Message, a.Details).Scan(&a.ID)
+}
+
+func (s *sqlStore) MarkAlertNotified(id int64) error {
+	_, err := s.db.Exec("UPDATE alerts SET notified = TRUE, notified_at = "+s.db.now()+" WHERE id = ?", id)
+	return err
+}
+
+func (s *sqlStore) AckAlert(id int64) (bool, error) {
+	res, err := s.db.Exec(`UPDATE alerts SET acked = TRUE, acked_at = COALESCE(acked_at, ?) WHERE id = ?`,
+		time.Now().UTC(), id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// RecordAlertNotifyAttempt bumps the failed-delivery counter for an alert and
+// holds off the next retry until retryAt.
+func (s *sqlStore) RecordAlertNotifyAttempt(id int64, retryAt time.Time) error {
+	_, err := s.db.Exec(`UPDATE alerts SET notify_attempts = notify_attempts + 1, last_notify_attempt_at = ?,
+		next_notify_attempt_at = ? WHERE id = ?`,
+		time.Now().UTC(), retryAt.UTC(), id)
+	return err
+}
+
+func (s *sqlStore) GetUnnotifiedAlerts() ([]models.Alert, error) {
+	rows, err := s.db.Query(`SELECT id, client_id, alert_type, target, severity, message, details, fired_at,
+		notify_attempts, last_notify_attempt_at, acked, acked_at
+		FROM alerts WHERE notified = FALSE ORDER BY fired_at ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanAlerts(rows)
+}
+
+// GetAlertsDueForRetry returns undelivered alerts that failed at least once
+// but fewer than maxAttempts times and whose retry is due at now, oldest first.
+func (s *sqlStore) GetAlertsDueForRetry(maxAttempts int, now time.Time) ([]models.Alert, error) {
+	rows, err := s.db.Query(`SELECT id, client_id, alert_type, target, severity, message, details, fired_at,
+		notify_attempts, last_notify_attempt_at, acked, acked_at
+		FROM alerts
+		WHERE notified = FALSE AND notify_attempts > 0 AND notify_attempts < ?
+			AND (next_notify_attempt_at IS NULL OR next_notify_attempt_at <= ?)
+		ORDER BY fired_at ASC`, maxAttempts, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanAlerts(rows)
+}
+
+func (s *sqlStore) ListAlertsSince(clientID string, since time.Time, limit int) ([]models.Alert, error) {
+	alerts, _, err := s.ListAlerts(AlertFilter{ClientID: clientID, From: since}, limit, 0)
+	return alerts, err
+}
+
+func (s *sqlStore) ListAlerts(filter AlertFilter, limit, offset int) ([]models.Alert, int, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	var conditions []string
+	var args []interface{}
+
+	if filter.ClientID != "" {
+		conditions = append(conditions, "client_id = ?")
+		args = append(args, filter.ClientID)
+	}
+	if filter.Severity != "" {
+		conditions = append(conditions, "severity = ?")
+		args = append(args, filter.Severity)
+	}
+	if filter.AlertType != "" {
+		conditions = append(conditions, "alert_type = ?")
+		args = append(args, filter.AlertType)
+	}
+	if filter.Acked != nil {
+		conditions = append(conditions, "acked = ?")
+		args = append(args, *filter.Acked)
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, s.db.timeCompare("fired_at", ">="))
+		args = append(args, filter.From.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, s.db.timeCompare("fired_at", "<="))
+		args = append(args, filter.To.UTC().Format("2006-01-02 15:04:05"))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM alerts "+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	queryArgs := append(args, limit, offset)
+	rows, err := s.db.Query(fmt.Sprintf(`SELECT id, client_id, alert_type, target, severity, message, details, fired_at,
+		notify_attempts, last_notify_attempt_at, acked, acked_at
+		FROM alerts %s ORDER BY fired_at DESC LIMIT ? OFFSET ?`, where), queryArgs...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	alerts, err := scanAlerts(rows)
+	return alerts, total, err
+}
+
+// CountAlertsBySeverity counts alerts fired since the given time per severity.
+func (s *sqlStore) CountAlertsBySeverity(since time.Time) (map[string]int, error) {
+	rows, err := s.db.Query(`SELECT severity, COUNT(*) FROM alerts WHERE `+s.db.timeCompare("fired_at", ">=")+`
+		GROUP BY severity`, since.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := make(map[string]int)
+	for rows.Next() {
+		var severity string
+		var n int
+		if err := rows.Scan(&severity, &n); err != nil {
+			return nil, err
+		}
+		counts[severity] = n
+	}
+	return counts, rows.Err()
+}
+
+func (s *sqlStore) GetLastAlertByTypes(clientID string, types ...string) (*models.Alert, error) {
+	if len(types) == 0 {
+		return nil, nil
+	}
+	placeholders := make([]string, len(types))
+	args := []interface{}{clientID}
+	for i, t := range types {
+		placeholders[i] = "?"
+		args = append(args, t)
+	}
+	a := &models.Alert{}
+	var details sql.NullString
+	err := s.db.QueryRow(fmt.Sprintf(`SELECT id, client_id, alert_type, severity, message, details, fired_at
+		FROM alerts WHERE client_id = ? AND alert_type IN (%s)
+		ORDER BY fired_at DESC LIMIT 1`, strings.Join(placeholders, ",")), args...).Scan(
+		&a.ID, &a.ClientID, &a.AlertType, &a.Severity, &a.Message, &details, &a.FiredAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.Details = details.String
+	return a, nil
+}
+
+// GetLastAlertFiredAt returns when an alert of the given type last fired for
+// a client's target, or nil if it never has.
+func (s *sqlStore) GetLastAlertFiredAt(clientID, alertType, target string) (*time.Time, error) {
+	var firedAt time.Time
+	err := s.db.QueryRow(`SELECT fired_at FROM alerts WHERE client_id = ? AND alert_type = ? AND target = ?
+		ORDER BY fired_at DESC LIMIT 1`, clientID, alertType, target).Scan(&firedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &firedAt, nil
+}
+
+func scanAlerts(rows *sql.Rows) ([]models.Alert, error) {
+	var alerts []models.Alert
+	for rows.Next() {
+		var a models.Alert
+		var details sql.NullString
+		var lastAttemptAt, ackedAt sql.NullTime
+		err := rows.Scan(&a.ID, &a.ClientID, &a.AlertType, &a.Target, &a.Severity, &a.Message, &details, &a.FiredAt,
+			&a.NotifyAttempts, &lastAttemptAt, &a.Acked, &ackedAt)
+		if err != nil {
+			return nil, err
+		}
+		a.Details = details.String
+		if lastAttemptAt.Valid {
+			a.LastNotifyAttemptAt = &lastAttemptAt.Time
+		}
+		if ackedAt.Valid {
+			a.AckedAt = &ackedAt.Time
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
+
+// --- Alert providers ---
+
+func (s *sqlStore) ListProviders() ([]models.AlertProvider, error) {
+	rows, err := s.db.Query("SELECT id, type, name, enabled, config, message_template, created_at FROM alert_providers ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanProviders(rows)
+}
+
+func (s *sqlStore) GetProvider(id int64) (*models.AlertProvider, error) {
+	p := &models.AlertProvider{}
+	err := s.db.QueryRow("SELECT id, type, name, enabled, config, message_template, created_at FROM alert_providers WHERE id = ?", id).
+		Scan(&p.ID, &p.Type, &p.Name, &p.Enabled, &p.Config, &p.MessageTemplate, &p.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *sqlStore) CreateProvider(p *models.AlertProvider) error {
+	return s.db.QueryRow("INSERT INTO alert_providers (type, name, enabled, config, message_template) VALUES (?, ?, ?, ?, ?) RETURNING id",
+		p.Type, p.Name, p.Enabled, p.Config, p.MessageTemplate).Scan(&p.ID)
+}
+
+func (s *sqlStore) UpdateProvider(p *models.AlertProvider) error {
+	_, err := s.db.Exec("UPDATE alert_providers SET type = ?, name = ?, enabled = ?, config = ?, message_template = ? WHERE id = ?",
+		p.Type, p.Name, p.Enabled, p.Config, p.MessageTemplate, p.ID)
+	return err
+}
+
+func (s *sqlStore) DeleteProvider(id int64) error {
+	_, err := s.db.Exec("DELETE FROM alert_providers WHERE id = ?", id)
+	return err
+}
+
+func (s *sqlStore) GetEnabledProviders() ([]models.AlertProvider, error) {
+	rows, err := s.db.Query("SELECT id, type, name, enabled, config, message_template, created_at FROM alert_providers WHERE enabled = TRUE")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanProviders(rows)
+}
+
+func scanProviders(rows *sql.Rows) ([]models.AlertProvider, error) {
+	var providers []models.AlertProvider
+	for rows.Next() {
+		var p models.AlertProvider
+		if err := rows.Scan(&p.ID, &p.Type, &p.Name, &p.Enabled, &p.Config, &p.MessageTemplate, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+	return providers, rows.Err()
+}
+
+// --- Settings ---
+
+func (s *sqlStore) GetSetting(key string) (string, error) {
+	var value string
+	err := s.db.QueryRow("SELECT value FROM global_settings WHERE key = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+func (s *sqlStore) SetSetting(key, value string) error {
+	_, err := s.db.Exec(`INSERT INTO global_settings (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value`, key, value)
+	return err
+}
+
+func (s *sqlStore) GetAllSettings() (map[string]string, error) {
+	rows, err := s.db.Query("SELECT key, value FROM global_settings")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := make(map[string]string)
+	for rows.Next() {
+		var k, v string
+		if err := rows.Scan(&k, &v); err != nil {
+			return nil, err
+		}
+		settings[k] = v
+	}
+	return settings, rows.Err()
+}
+
+// --- Maintenance ---
+
+// tableStats counts the rows in each table listed by namesQuery.
+func (s *sqlStore) tableStats(namesQuery string) ([]models.TableStats, error) {
+	rows, err := s.db.Query(namesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("list tables: %w", err)
+	}
+	var tables []models.TableStats
+	for rows.Next() {
+		var t models.TableStats
+		if err := rows.Scan(&t.Name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range tables {
+		// Names come from the catalog, not user input.
+		if err := s.db.QueryRow(`SELECT COUNT(*) FROM "` + tables[i].Name + `"`).Scan(&tables[i].Rows); err != nil {
+			return nil, fmt.Errorf("count %s: %w", tables[i].Name, err)
+		}
+	}
+	return tables, nil
+}
+
+func (s *sqlStore) PruneOldData(keep RetentionWindows) (int64, error) {
+	var totalDeleted int64
+
+	metricsCutoff := time.Now().Add(-keep.Metrics)
+	result, err := s.db.Exec("DELETE FROM metrics WHERE recorded_at < ?", metricsCutoff)
+	if err != nil {
+		return 0, fmt.Errorf("prune metrics: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	totalDeleted += n
+
+	result, err = s.db.Exec("DELETE FROM process_snapshots WHERE recorded_at < ?", metricsCutoff)
+	if err != nil {
+		return totalDeleted, fmt.Errorf("prune process snapshots: %w", err)
+	}
+	n, _ = result.RowsAffected()
+	totalDeleted += n
+
+	result, err = s.db.Exec("DELETE FROM process_restarts WHERE restarted_at < ?", time.Now().Add(-keep.ProcessRestarts).UTC())
+	if err != nil {
+		return totalDeleted, fmt.Errorf("prune process restarts: %w", err)
+	}
+	n, _ = result.RowsAffected()
+	totalDeleted += n
+
+	result, err = s.db.Exec("DELETE FROM check_snapshots WHERE recorded_at < ?", metricsCutoff)
+	if err != nil {
+		return totalDeleted, fmt.Errorf("prune check snapshots: %w", err)
+	}
+	n, _ = result.RowsAffected()
+	totalDeleted += n
+
+	result, err = s.db.Exec("DELETE FROM alerts WHERE fired_at < ?", time.Now().Add(-keep.Alerts))
+	if err != nil {
+		return totalDeleted, fmt.Errorf("prune alerts: %w", err)
+	}
+	n, _ = result.RowsAffected()
+	totalDeleted += n
+
+	result, err = s.db.Exec("DELETE FROM maintenance_windows WHERE ends_at < ?", time.Now().Add(-keep.Maintenance).UTC())
+	if err != nil {
+		return totalDeleted, fmt.Errorf("prune maintenance windows: %w", err)
+	}
+	n, _ = result.RowsAffected()
+	totalDeleted += n
+
+	result, err = s.db.Exec("DELETE FROM audit_log WHERE created_at < ?", time.Now().Add(-keep.Audit).UTC())
+	if err != nil {
+		return totalDeleted, fmt.Errorf("prune audit log: %w", err)
+	}
+	n, _ = result.RowsAffected()
+	totalDeleted += n
+
+	return totalDeleted, nil
+}
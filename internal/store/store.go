@@ -1,41 +1,160 @@
 package store
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/machinemon/machinemon/internal/models"
 )
 
+// UpsertClientResult describes what changed when a client checked in.
+type UpsertClientResult struct {
+	ClientID       string
+	WasOffline     bool
+	SessionChanged bool
+	// IdentityConflict is set when the check-in looks like it came from a
+	// different machine sharing the same client_id (e.g. a cloned image):
+	// the session flipped back to the one before the current session, or the
+	// hostname changed along with the session.
+	IdentityConflict bool
+	PreviousHostname string
+	// Created is set when the check-in registered a new client.
+	Created bool
+}
+
+// AlertFilter narrows ListAlerts; zero values match everything.
+type AlertFilter struct {
+	ClientID  string
+	Severity  string
+	AlertType string
+	Acked     *bool
+	// From and To bound fired_at, inclusive; zero values leave that side open.
+	From time.Time
+	To   time.Time
+}
+
+// RetentionWindows is how long PruneOldData keeps each kind of history.
+type RetentionWindows struct {
+	Metrics         time.Duration // metrics plus process and check snapshots
+	Alerts          time.Duration
+	Audit           time.Duration
+	ProcessRestarts time.Duration
+	Maintenance     time.Duration // counted from the end of a window
+}
+
+// ClientFilter narrows ListClients; zero values match everything.
+type ClientFilter struct {
+	// Query matches hostname or custom name (case-insensitive substring).
+	Query string
+	// Tag matches clients carrying this tag.
+	Tag string
+}
+
 // Store defines the data access interface for MachineMon.
 type Store interface {
 	Close() error
+	// Ping reports whether the database is reachable and answering queries.
+	Ping() error
 
 	// Client operations
-	UpsertClient(req models.CheckInRequest, publicIP string) (clientID string, wasOffline bool, sessionChanged bool, err error)
+	UpsertClient(req models.CheckInRequest, publicIP string) (*UpsertClientResult, error)
 	GetClient(id string) (*models.Client, error)
-	ListClients() ([]models.ClientWithMetrics, error)
+	// ListClients returns non-deleted clients ordered by display name, plus the
+	// total matching count; limit <= 0 returns every match.
+	ListClients(filter ClientFilter, limit, offset int) ([]models.ClientWithMetrics, int, error)
 	DeleteClient(id string) error
+	// PurgeClient hard-deletes the client; its metrics, snapshots, alerts, and
+	// other rows go with it through ON DELETE CASCADE.
+	PurgeClient(id string) error
+	// ClearClientMetrics deletes the client's metric and process/check
+	// snapshot history but keeps the client and its settings.
+	ClearClientMetrics(id string) error
+	MergeClients(sourceID, targetID string) error
 	SetClientOnline(id string, online bool) error
 	GetOnlineClients() ([]models.Client, error)
 	GetStaleOnlineClients(thresholdSeconds int) ([]models.Client, error)
 	SetClientCustomName(id, customName string) error
+	// SetClientTags replaces the client's tags; they are lowercased,
+	// de-duplicated, and sorted.
+	SetClientTags(id string, tags []string) error
+	GetClientsByTag(tag string) ([]models.Client, error)
 	SetClientThresholds(id string, t *models.Thresholds) error
 	SetClientMute(id string, muted bool, until *time.Time, reason string) error
+	SetClientsMute(ids []string, muted bool, until *time.Time, reason string) (missing []string, err error)
+	SetClientSuspended(id string, suspended bool, reason string) error
+	SetClientBusinessHours(id string, bh *models.BusinessHours) error
+	SetClientCollectionError(id, message string) error
+	GetClientCheckInInterval(id string) (*int, error)
+	SetClientCheckInInterval(id string, secs *int) error
 	ListClientAlertMutes(clientID string) ([]models.ClientAlertMute, error)
 	SetClientAlertMute(clientID, scope, target string, muted bool) error
 
+	// Maintenance windows
+	CreateMaintenanceWindow(w *models.MaintenanceWindow) error
+	// ListMaintenanceWindows returns the client's windows that have not yet
+	// ended, soonest first.
+	ListMaintenanceWindows(clientID string) ([]models.MaintenanceWindow, error)
+	// GetActiveMaintenanceWindow returns the window covering at, or nil.
+	GetActiveMaintenanceWindow(clientID string, at time.Time) (*models.MaintenanceWindow, error)
+	// DeleteMaintenanceWindow reports false if the client has no such window.
+	DeleteMaintenanceWindow(clientID string, id int64) (bool, error)
+
+	// Client tokens
+	CreateClientToken(tokenHash, label string) (*models.ClientToken, error)
+	ListClientTokens() ([]models.ClientToken, error)
+	// RevokeClientToken reports false if no unrevoked token has this ID.
+	RevokeClientToken(id int64) (bool, error)
+	// ClientTokenValid reports whether an unrevoked token has this hash.
+	ClientTokenValid(tokenHash string) (bool, error)
+
+	// Admin API tokens
+	CreateAPIToken(tokenHash, label string) (*models.APIToken, error)
+	ListAPITokens() ([]models.APIToken, error)
+	// RevokeAPIToken reports false if no unrevoked token has this ID.
+	RevokeAPIToken(id int64) (bool, error)
+	// UseAPIToken reports whether an unrevoked token has this hash and, if
+	// so, records the use.
+	UseAPIToken(tokenHash string) (bool, error)
+
+	// Audit log
+	InsertAuditEntry(e *models.AuditEntry) error
+	// ListAuditEntries returns entries newest first, plus the total count.
+	ListAuditEntries(limit, offset int) ([]models.AuditEntry, int, error)
+
 	// Metrics
 	InsertMetrics(clientID string, m models.MetricsPayload) error
+	InsertMetricsAt(clientID string, recordedAt time.Time, m models.MetricsPayload) error
 	GetLatestMetrics(clientID string) (*models.Metric, error)
 	GetRecentMetrics(clientID string, limit int) ([]models.Metric, error)
 	GetMetrics(clientID string, from, to time.Time, limit int) ([]models.Metric, error)
+	// GetDiskUsageDelta compares the oldest and newest root disk samples
+	// recorded since the given time. It returns nil with fewer than two samples.
+	GetDiskUsageDelta(clientID string, since time.Time) (*models.UsageDelta, error)
+	// GetMetricsBucketed aggregates samples into fixed time buckets (averages
+	// plus peaks), oldest first, for charting wide ranges.
+	GetMetricsBucketed(clientID string, from, to time.Time, bucket time.Duration, limit int) ([]models.Metric, error)
+	// ForEachMetric calls fn for every sample in [from, to], oldest first,
+	// without loading the range into memory. A non-nil error from fn stops
+	// the iteration and is returned.
+	ForEachMetric(clientID string, from, to time.Time, fn func(models.Metric) error) error
+
+	// Disk mounts
+	InsertDiskMounts(clientID string, mounts []models.DiskMountPayload) error
+	GetLatestDiskMounts(clientID string) ([]models.DiskMount, error)
+	SetDiskMountAlertState(clientID, path, state string) error
 
 	// Process tracking
 	UpsertWatchedProcesses(clientID string, procs []models.ProcessPayload) error
+	// UpdateWatchedProcess sets a watched process's note and expected state;
+	// false means no such process.
+	UpdateWatchedProcess(clientID, friendlyName, note, expectedState string) (bool, error)
 	DeleteWatchedProcess(clientID, friendlyName string) error
 	InsertProcessSnapshots(clientID string, procs []models.ProcessPayload) error
 	GetLatestProcessSnapshots(clientID string) ([]models.ProcessSnapshot, error)
 	GetPreviousProcessSnapshots(clientID string) ([]models.ProcessSnapshot, error)
+	GetRecentProcessSnapshots(clientID, friendlyName string, limit int) ([]models.ProcessSnapshot, error)
+	CountProcessRestarts(clientID string, since time.Time) (map[string]int, error)
 	GetWatchedProcesses(clientID string) ([]models.WatchedProcess, error)
 
 	// Checks (extensible typed check system: script, http, file_touch, ...)
@@ -43,13 +162,25 @@ type Store interface {
 	InsertCheckSnapshots(clientID string, checks []models.CheckPayload) error
 	GetLatestCheckSnapshots(clientID string) ([]models.CheckSnapshot, error)
 	GetPreviousCheckSnapshots(clientID string) ([]models.CheckSnapshot, error)
+	GetRecentCheckSnapshots(clientID, friendlyName, checkType string, limit int) ([]models.CheckSnapshot, error)
 
 	// Alerts
 	InsertAlert(a *models.Alert) error
 	MarkAlertNotified(id int64) error
+	RecordAlertNotifyAttempt(id int64, retryAt time.Time) error
 	GetUnnotifiedAlerts() ([]models.Alert, error)
-	ListAlerts(clientID string, severity string, limit, offset int) ([]models.Alert, int, error)
+	GetAlertsDueForRetry(maxAttempts int, now time.Time) ([]models.Alert, error)
+	ListAlerts(filter AlertFilter, limit, offset int) ([]models.Alert, int, error)
+	// ListAlertsSince returns up to limit of a client's alerts fired at or
+	// after since, newest first.
+	ListAlertsSince(clientID string, since time.Time, limit int) ([]models.Alert, error)
+	// AckAlert marks an alert acknowledged, keeping the first ack time. It
+	// reports false if no such alert exists.
+	AckAlert(id int64) (bool, error)
 	GetLastAlertByTypes(clientID string, types ...string) (*models.Alert, error)
+	// GetLastAlertFiredAt returns when an alert of this type last fired for
+	// the given target ("" for client-wide alerts), or nil if never.
+	GetLastAlertFiredAt(clientID, alertType, target string) (*time.Time, error)
 
 	// Alert providers
 	ListProviders() ([]models.AlertProvider, error)
@@ -65,5 +196,76 @@ type Store interface {
 	GetAllSettings() (map[string]string, error)
 
 	// Maintenance
-	PruneOldData(metricsRetention, alertsRetention time.Duration) (int64, error)
+	PruneOldData(keep RetentionWindows) (int64, error)
+	// Vacuum returns space freed by deleted rows to the operating system.
+	Vacuum() error
+	DatabaseStats() (*models.DatabaseStats, error)
+	DatabaseSize() (int64, error)
+	CountClients() (*models.ClientCounts, error)
+	ListClientVersions() ([]models.ClientVersionInfo, error)
+	CountAlertsBySeverity(since time.Time) (map[string]int, error)
+}
+
+// Supported database drivers.
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// Config selects and locates the database backend.
+type Config struct {
+	Driver string // DriverSQLite (default) or DriverPostgres
+	Path   string // SQLite database file
+	DSN    string // PostgreSQL connection string
+}
+
+// driver returns the canonical driver name, accepting common aliases.
+func (c Config) driver() string {
+	switch d := strings.ToLower(strings.TrimSpace(c.Driver)); d {
+	case "", DriverSQLite, "sqlite3":
+		return DriverSQLite
+	case DriverPostgres, "postgresql", "pgx":
+		return DriverPostgres
+	default:
+		return d
+	}
+}
+
+// IsSQLite reports whether cfg selects the file-based SQLite backend.
+func (c Config) IsSQLite() bool {
+	return c.driver() == DriverSQLite
+}
+
+// Validate reports a config New would reject before connecting.
+func (c Config) Validate() error {
+	switch c.driver() {
+	case DriverSQLite:
+		return nil
+	case DriverPostgres:
+		if strings.TrimSpace(c.DSN) == "" {
+			return fmt.Errorf("database_dsn is required for the %s driver", DriverPostgres)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown database driver %q (want %q or %q)", c.Driver, DriverSQLite, DriverPostgres)
+	}
+}
+
+// New opens the backend named by cfg.Driver.
+func New(cfg Config) (Store, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.IsSQLite() {
+		st, err := NewSQLiteStore(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		return st, nil
+	}
+	st, err := NewPostgresStore(cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
 }
@@ -1,6 +1,10 @@
 package version
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 // Set via ldflags at build time.
 var (
@@ -12,3 +16,55 @@ var (
 func String() string {
 	return fmt.Sprintf("machinemon %s (commit %s, built %s)", Version, Commit, BuildTime)
 }
+
+// Compare orders two release versions such as "v1.4.2" or "1.4.2-3-gabc123"
+// (git describe output) by their dotted numeric prefix. It returns -1, 0 or 1
+// and ok=false when either side has no numeric prefix, e.g. "dev" builds.
+func Compare(a, b string) (cmp int, ok bool) {
+	pa, okA := numericParts(a)
+	pb, okB := numericParts(b)
+	if !okA || !okB {
+		return 0, false
+	}
+	for i := 0; i < max(len(pa), len(pb)); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	return 0, true
+}
+
+// numericParts parses the leading "1.4.2" of a version, ignoring a "v"
+// prefix and anything after the last numeric component.
+func numericParts(v string) ([]int, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	var parts []int
+	for _, field := range strings.Split(v, ".") {
+		end := 0
+		for end < len(field) && field[end] >= '0' && field[end] <= '9' {
+			end++
+		}
+		if end == 0 {
+			break
+		}
+		n, err := strconv.Atoi(field[:end])
+		if err != nil {
+			return nil, false
+		}
+		parts = append(parts, n)
+		if end < len(field) {
+			break
+		}
+	}
+	return parts, len(parts) > 0
+}
@@ -0,0 +1,26 @@
+package version
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+		ok   bool
+	}{
+		{"v1.4.2", "1.4.2", 0, true},
+		{"v1.4.2", "v1.10.0", -1, true},
+		{"v2.0", "v1.9.9", 1, true},
+		{"v1.4", "v1.4.0", 0, true},
+		{"v1.4.2-3-gabc123-dirty", "v1.4.2", 0, true},
+		{"v1.4.1-rc1", "v1.4.2", -1, true},
+		{"dev", "v1.4.2", 0, false},
+		{"v1.4.2", "", 0, false},
+	}
+	for _, c := range cases {
+		got, ok := Compare(c.a, c.b)
+		if got != c.want || ok != c.ok {
+			t.Errorf("Compare(%q, %q) = %d, %v; want %d, %v", c.a, c.b, got, ok, c.want, c.ok)
+		}
+	}
+}
@@ -126,7 +126,7 @@ main() {
     else
         echo "  2. Install as service: sudo machinemon-client --service-install"
     fi
-    echo "     (auto-detects systemd, sysvinit, openrc, upstart, or launchd)"
+    echo "     (auto-detects systemd, sysvinit, openrc, upstart, launchd, or FreeBSD rc.d)"
 }
 
 main "$@"
@@ -104,7 +104,7 @@ main() {
     echo "Next steps:"
     echo "  1. Run setup:          machinemon-server --setup"
     echo "  2. Install as service: sudo machinemon-server --service-install"
-    echo "     (auto-detects systemd, sysvinit, openrc, upstart, or launchd)"
+    echo "     (auto-detects systemd, sysvinit, openrc, upstart, launchd, or FreeBSD rc.d)"
 }
 
 main "$@"
@@ -1,4 +1,4 @@
-import type { ClientWithMetrics, Client, Metrics, ProcessSnapshot, CheckSnapshot, ClientAlertMute, Alert, Thresholds, AlertProvider, TestAlertResult } from '../types';
+import type { ClientWithMetrics, Client, Metrics, ProcessSnapshot, CheckSnapshot, ClientAlertMute, MaintenanceWindow, ClientToken, APIToken, AuditEntry, DatabaseStats, ServerStats, LiveEvent, Alert, Thresholds, EffectiveThresholds, AlertProvider, AlertRoute, TestAlertResult } from '../types';
 
 function normalizeBasePath(path: string): string {
   if (!path) return '';
@@ -84,6 +84,46 @@ async function fetchJSON<T>(path: string, init?: RequestInit): Promise<T> {
   return res.json();
 }
 
+// subscribeEvents streams live check-in/alert events. EventSource can't send
+// the Authorization header, so this reads the SSE stream via fetch and
+// reconnects after errors. Returns a function that stops the stream.
+export function subscribeEvents(onEvent: (ev: LiveEvent) => void): () => void {
+  const controller = new AbortController();
+
+  const run = async () => {
+    while (!controller.signal.aborted) {
+      try {
+        const res = await fetch(`${API_BASE}/events`, { headers: getAuthHeaders(), signal: controller.signal });
+        if (res.status === 401) {
+          clearAuth();
+          return;
+        }
+        if (!res.ok || !res.body) throw new Error(`events ${res.status}`);
+        const reader = res.body.getReader();
+        const decoder = new TextDecoder();
+        let buf = '';
+        for (;;) {
+          const { done, value } = await reader.read();
+          if (done) break;
+          buf += decoder.decode(value, { stream: true });
+          let idx;
+          while ((idx = buf.indexOf('\n\n')) >= 0) {
+            const chunk = buf.slice(0, idx);
+            buf = buf.slice(idx + 2);
+            const data = chunk.split('\n').filter(l => l.startsWith('data: ')).map(l => l.slice(6)).join('\n');
+            if (data) onEvent(JSON.parse(data));
+          }
+        }
+      } catch {
+        if (controller.signal.aborted) return;
+      }
+      await new Promise(resolve => setTimeout(resolve, 5000));
+    }
+  };
+  run();
+  return () => controller.abort();
+}
+
 export function setAuth(username: string, password: string) {
   localStorage.setItem(AUTH_KEY, btoa(`${username}:${password}`));
   localStorage.setItem(AUTH_EXP_KEY, String(Date.now() + AUTH_TTL_MS));
@@ -104,11 +144,12 @@ export function authChangeEventName(): string {
   return AUTH_EVENT;
 }
 
-export async function validateAdminAuth(username: string, password: string): Promise<'ok' | 'invalid'> {
+export async function validateAdminAuth(username: string, password: string): Promise<'ok' | 'invalid' | 'locked'> {
   const res = await fetch(`${API_BASE}/clients`, {
     headers: { 'Authorization': `Basic ${btoa(`${username}:${password}`)}` },
   });
   if (res.status === 401) return 'invalid';
+  if (res.status === 429) return 'locked';
   if (!res.ok) {
     throw new Error(`API error ${res.status}`);
   }
@@ -116,19 +157,47 @@ export async function validateAdminAuth(username: string, password: string): Pro
 }
 
 // Clients
-export async function fetchClients(): Promise<ClientWithMetrics[]> {
-  const data = await fetchJSON<{ clients: ClientWithMetrics[] }>('/clients');
+export async function fetchClients(opts?: { q?: string; tag?: string; limit?: number; offset?: number }): Promise<ClientWithMetrics[]> {
+  const params = new URLSearchParams();
+  if (opts?.q) params.set('q', opts.q);
+  if (opts?.tag) params.set('tag', opts.tag);
+  if (opts?.limit) params.set('limit', String(opts.limit));
+  if (opts?.offset) params.set('offset', String(opts.offset));
+  const qs = params.toString();
+  const data = await fetchJSON<{ clients: ClientWithMetrics[]; total: number }>(qs ? `/clients?${qs}` : '/clients');
   return data.clients;
 }
 
-export async function fetchClient(id: string): Promise<{ client: Client; metrics: Metrics | null; processes: ProcessSnapshot[]; checks?: CheckSnapshot[]; alert_mutes?: ClientAlertMute[] }> {
+export async function fetchClient(id: string): Promise<{ client: Client; metrics: Metrics | null; processes: ProcessSnapshot[]; checks?: CheckSnapshot[]; alert_mutes?: ClientAlertMute[]; maintenance_windows?: MaintenanceWindow[] }> {
   return fetchJSON(`/clients/${id}`);
 }
 
+export async function setClientTags(id: string, tags: string[]): Promise<void> {
+  await fetchJSON(`/clients/${id}/tags`, {
+    method: 'PUT',
+    body: JSON.stringify({ tags }),
+  });
+}
+
+export async function setCheckInInterval(id: string, seconds: number | null): Promise<void> {
+  await fetchJSON(`/clients/${id}/check-in-interval`, {
+    method: 'PUT',
+    body: JSON.stringify({ check_in_interval_seconds: seconds }),
+  });
+}
+
 export async function deleteClient(id: string): Promise<void> {
   await fetchJSON(`/clients/${id}`, { method: 'DELETE' });
 }
 
+export async function purgeClient(id: string): Promise<void> {
+  await fetchJSON(`/clients/${id}/purge`, { method: 'POST' });
+}
+
+export async function clearClientMetrics(id: string): Promise<void> {
+  await fetchJSON(`/clients/${id}/metrics`, { method: 'DELETE' });
+}
+
 export async function setThresholds(id: string, thresholds: Thresholds): Promise<void> {
   await fetchJSON(`/clients/${id}/thresholds`, {
     method: 'PUT',
@@ -136,6 +205,10 @@ export async function setThresholds(id: string, thresholds: Thresholds): Promise
   });
 }
 
+export async function fetchEffectiveThresholds(id: string): Promise<EffectiveThresholds> {
+  return fetchJSON(`/clients/${id}/effective-thresholds`);
+}
+
 export async function clearThresholds(id: string): Promise<void> {
   await fetchJSON(`/clients/${id}/thresholds`, {
     method: 'DELETE',
@@ -156,6 +229,13 @@ export async function setMute(id: string, muted: boolean, durationMinutes?: numb
   });
 }
 
+export async function setSuspended(id: string, suspended: boolean, reason?: string): Promise<void> {
+  await fetchJSON(`/clients/${id}/suspend`, {
+    method: 'PUT',
+    body: JSON.stringify({ suspended, reason: reason || '' }),
+  });
+}
+
 export async function setScopedMute(id: string, scope: 'cpu' | 'memory' | 'disk' | 'process' | 'check', target: string, muted: boolean): Promise<void> {
   await fetchJSON(`/clients/${id}/mutes`, {
     method: 'PUT',
@@ -163,10 +243,75 @@ export async function setScopedMute(id: string, scope: 'cpu' | 'memory' | 'disk'
   });
 }
 
-export async function fetchMetrics(id: string, from?: string, to?: string): Promise<Metrics[]> {
+export async function fetchMaintenanceWindows(id: string): Promise<MaintenanceWindow[]> {
+  const data = await fetchJSON<{ maintenance_windows: MaintenanceWindow[] }>(`/clients/${id}/maintenance`);
+  return data.maintenance_windows;
+}
+
+export async function createMaintenanceWindow(id: string, window: { starts_at?: string; ends_at?: string; duration_minutes?: number; reason?: string }): Promise<MaintenanceWindow> {
+  return fetchJSON(`/clients/${id}/maintenance`, {
+    method: 'POST',
+    body: JSON.stringify(window),
+  });
+}
+
+export async function deleteMaintenanceWindow(id: string, windowID: number): Promise<void> {
+  await fetchJSON(`/clients/${id}/maintenance/${windowID}`, { method: 'DELETE' });
+}
+
+export async function fetchClientTokens(): Promise<ClientToken[]> {
+  const data = await fetchJSON<{ tokens: ClientToken[] }>('/client-tokens');
+  return data.tokens;
+}
+
+export async function createClientToken(label: string): Promise<ClientToken & { token: string }> {
+  return fetchJSON('/client-tokens', {
+    method: 'POST',
+    body: JSON.stringify({ label }),
+  });
+}
+
+export async function revokeClientToken(id: number): Promise<void> {
+  await fetchJSON(`/client-tokens/${id}`, { method: 'DELETE' });
+}
+
+export async function fetchAPITokens(): Promise<APIToken[]> {
+  const data = await fetchJSON<{ tokens: APIToken[] }>('/api-tokens');
+  return data.tokens;
+}
+
+export async function createAPIToken(label: string): Promise<APIToken & { token: string }> {
+  return fetchJSON('/api-tokens', {
+    method: 'POST',
+    body: JSON.stringify({ label }),
+  });
+}
+
+export async function revokeAPIToken(id: number): Promise<void> {
+  await fetchJSON(`/api-tokens/${id}`, { method: 'DELETE' });
+}
+
+export async function fetchAuditLog(limit = 100, offset = 0): Promise<{ entries: AuditEntry[]; total: number }> {
+  return fetchJSON(`/audit?limit=${limit}&offset=${offset}`);
+}
+
+export async function fetchServerStats(): Promise<ServerStats> {
+  return fetchJSON('/stats');
+}
+
+export async function fetchDatabaseStats(): Promise<DatabaseStats> {
+  return fetchJSON('/database');
+}
+
+export async function vacuumDatabase(): Promise<DatabaseStats> {
+  return fetchJSON('/database/vacuum', { method: 'POST' });
+}
+
+export async function fetchMetrics(id: string, from?: string, to?: string, bucket?: string): Promise<Metrics[]> {
   const params = new URLSearchParams();
   if (from) params.set('from', from);
   if (to) params.set('to', to);
+  if (bucket) params.set('bucket', bucket);
   const data = await fetchJSON<{ metrics: Metrics[] }>(`/clients/${id}/metrics?${params}`);
   return data.metrics;
 }
@@ -180,19 +325,37 @@ export async function deleteWatchedProcess(id: string, friendlyName: string): Pr
   await fetchJSON(`/clients/${id}/processes?${params.toString()}`, { method: 'DELETE' });
 }
 
+export async function updateWatchedProcess(
+  id: string,
+  friendlyName: string,
+  note: string,
+  expectedState: 'running' | 'stopped',
+): Promise<void> {
+  await fetchJSON(`/clients/${id}/processes/${encodeURIComponent(friendlyName)}`, {
+    method: 'PUT',
+    body: JSON.stringify({ note, expected_state: expectedState }),
+  });
+}
+
 export async function deleteCheckSnapshot(id: string, friendlyName: string, checkType: string): Promise<void> {
   const params = new URLSearchParams({ friendly_name: friendlyName, check_type: checkType });
   await fetchJSON(`/clients/${id}/checks?${params.toString()}`, { method: 'DELETE' });
 }
 
 // Alerts
-export async function fetchAlerts(clientId?: string, severity?: string, limit = 100, offset = 0): Promise<{ alerts: Alert[]; total: number }> {
+export async function fetchAlerts(clientId?: string, severity?: string, limit = 100, offset = 0, acked?: boolean, since?: 'session'): Promise<{ alerts: Alert[]; total: number }> {
   const params = new URLSearchParams({ limit: String(limit), offset: String(offset) });
   if (clientId) params.set('client_id', clientId);
   if (severity) params.set('severity', severity);
+  if (acked !== undefined) params.set('acked', String(acked));
+  if (since) params.set('since', since);
   return fetchJSON(`/alerts?${params}`);
 }
 
+export async function ackAlert(id: number): Promise<void> {
+  await fetchJSON(`/alerts/${id}/ack`, { method: 'POST' });
+}
+
 // Providers
 export async function fetchProviders(): Promise<AlertProvider[]> {
   const data = await fetchJSON<{ providers: AlertProvider[] }>('/providers');
@@ -215,6 +378,15 @@ export async function testProvider(id: number): Promise<{ status: string; result
   return fetchJSON(`/providers/${id}/test`, { method: 'POST' });
 }
 
+export async function fetchAlertRoutes(): Promise<AlertRoute[]> {
+  const data = await fetchJSON<{ routes: AlertRoute[] }>('/alert-routes');
+  return data.routes;
+}
+
+export async function updateAlertRoutes(routes: AlertRoute[]): Promise<void> {
+  await fetchJSON('/alert-routes', { method: 'PUT', body: JSON.stringify({ routes }) });
+}
+
 // Settings
 export async function fetchSettings(): Promise<Record<string, string>> {
   return fetchJSON('/settings');
@@ -1,5 +1,5 @@
 import { useState, useEffect } from 'react';
-import { fetchAlerts } from '../api/client';
+import { fetchAlerts, ackAlert } from '../api/client';
 import type { Alert } from '../types';
 
 const severityColors: Record<string, string> = {
@@ -11,8 +11,13 @@ const severityColors: Record<string, string> = {
 const alertTypeLabels: Record<string, string> = {
   offline: 'Offline',
   online: 'Online',
+  client_outdated: 'Client Outdated',
+  client_updated: 'Client Updated',
   pid_change: 'PID Change',
   process_died: 'Process Died',
+  process_started: 'Process Started',
+  process_instances_low: 'Too Few Instances',
+  process_instances_recovered: 'Instances Recovered',
   check_failed: 'Check Failed',
   check_recovered: 'Check Recovered',
   cpu_warn: 'CPU Warning',
@@ -31,13 +36,14 @@ export default function Alerts() {
   const [total, setTotal] = useState(0);
   const [offset, setOffset] = useState(0);
   const [severity, setSeverity] = useState('');
+  const [outstandingOnly, setOutstandingOnly] = useState(false);
   const [loading, setLoading] = useState(true);
   const limit = 50;
 
   const load = async () => {
     setLoading(true);
     try {
-      const data = await fetchAlerts(undefined, severity, limit, offset);
+      const data = await fetchAlerts(undefined, severity, limit, offset, outstandingOnly ? false : undefined);
       setAlerts(data.alerts);
       setTotal(data.total);
     } catch {
@@ -47,13 +53,30 @@ export default function Alerts() {
     }
   };
 
-  useEffect(() => { load(); }, [severity, offset]);
+  useEffect(() => { load(); }, [severity, offset, outstandingOnly]);
+
+  const handleAck = async (id: number) => {
+    try {
+      await ackAlert(id);
+      await load();
+    } catch {
+      // ignore
+    }
+  };
 
   return (
     <div>
       <div className="flex items-center justify-between mb-6">
         <h1 className="text-2xl font-bold text-gray-900">Alerts</h1>
         <div className="flex gap-2">
+          <label className="flex items-center gap-1 mr-2 text-sm text-gray-500">
+            <input
+              type="checkbox"
+              checked={outstandingOnly}
+              onChange={e => { setOutstandingOnly(e.target.checked); setOffset(0); }}
+            />
+            Unacknowledged only
+          </label>
           {['', 'critical', 'warning', 'info'].map(s => (
             <button
               key={s}
@@ -80,6 +103,7 @@ export default function Alerts() {
                   <th className="px-4 py-3">Severity</th>
                   <th className="px-4 py-3">Type</th>
                   <th className="px-4 py-3">Message</th>
+                  <th className="px-4 py-3"></th>
                 </tr>
               </thead>
               <tbody>
@@ -97,6 +121,15 @@ export default function Alerts() {
                       {alertTypeLabels[a.alert_type] || a.alert_type}
                     </td>
                     <td className="px-4 py-3 text-gray-700">{a.message}</td>
+                    <td className="px-4 py-3 text-right whitespace-nowrap">
+                      {a.acked ? (
+                        <span className="text-xs text-gray-400">Acked</span>
+                      ) : (
+                        <button onClick={() => handleAck(a.id)} className="text-xs text-blue-600 hover:underline">
+                          Ack
+                        </button>
+                      )}
+                    </td>
                   </tr>
                 ))}
               </tbody>
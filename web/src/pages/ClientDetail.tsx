@@ -1,11 +1,11 @@
 import { useState, useEffect } from 'react';
 import { useParams, useNavigate } from 'react-router-dom';
-import { fetchClient, deleteClient, deleteWatchedProcess, deleteCheckSnapshot, setMute, setScopedMute, fetchMetrics, fetchAlerts, setThresholds, setClientName, fetchSettings } from '../api/client';
-import type { Client, Metrics, ProcessSnapshot, CheckSnapshot, ClientAlertMute, Alert, Thresholds } from '../types';
+import { fetchClient, deleteClient, purgeClient, clearClientMetrics, deleteWatchedProcess, updateWatchedProcess, deleteCheckSnapshot, setMute, setSuspended, setScopedMute, fetchMetrics, fetchAlerts, setThresholds, setClientName, setClientTags, setCheckInInterval, fetchSettings, fetchEffectiveThresholds, deleteMaintenanceWindow } from '../api/client';
+import type { Client, Metrics, ProcessSnapshot, CheckSnapshot, ClientAlertMute, MaintenanceWindow, Alert, Thresholds, EffectiveThresholds } from '../types';
 import MetricGauge from '../components/MetricGauge';
 import StatusDot from '../components/StatusDot';
 import { AreaChart, Area, XAxis, YAxis, CartesianGrid, Tooltip, ResponsiveContainer } from 'recharts';
-import { Trash2, VolumeX, Volume2, ArrowLeft, RefreshCw, Pencil, ChevronRight, ChevronDown } from 'lucide-react';
+import { Trash2, Eraser, Flame, VolumeX, Volume2, PauseCircle, PlayCircle, ArrowLeft, RefreshCw, Pencil, ChevronRight, ChevronDown } from 'lucide-react';
 import { clientVersionLabel } from '../utils/clientVersion';
 
 function formatBytes(bytes: number): string {
@@ -77,6 +77,7 @@ export default function ClientDetail() {
   const [history, setHistory] = useState<Metrics[]>([]);
   const [alerts, setAlerts] = useState<Alert[]>([]);
   const [alertMutes, setAlertMutes] = useState<ClientAlertMute[]>([]);
+  const [maintenance, setMaintenance] = useState<MaintenanceWindow[]>([]);
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState('');
   const [status, setStatus] = useState('');
@@ -84,14 +85,20 @@ export default function ClientDetail() {
   const [nameInput, setNameInput] = useState('');
   const [thresholdsForm, setThresholdsForm] = useState<Thresholds>(FALLBACK_THRESHOLDS);
   const [thresholdDefaults, setThresholdDefaults] = useState<Thresholds>(FALLBACK_THRESHOLDS);
+  const [effective, setEffective] = useState<EffectiveThresholds | null>(null);
   const [customMetricThresholds, setCustomMetricThresholds] = useState(false);
   const [customOfflineDelay, setCustomOfflineDelay] = useState(false);
   const [customMetricDelay, setCustomMetricDelay] = useState(false);
   const [renameOpen, setRenameOpen] = useState(false);
+  const [tagsOpen, setTagsOpen] = useState(false);
+  const [tagsInput, setTagsInput] = useState('');
+  const [intervalOpen, setIntervalOpen] = useState(false);
+  const [intervalInput, setIntervalInput] = useState('');
   const [deleteTarget, setDeleteTarget] = useState<{ kind: 'process' | 'check'; friendlyName: string; checkType?: string } | null>(null);
   const [deleteBusy, setDeleteBusy] = useState(false);
   const [showThresholds, setShowThresholds] = useState(false);
   const [showAlerts, setShowAlerts] = useState(false);
+  const [alertsThisBoot, setAlertsThisBoot] = useState(false);
 
   const parseSettingNumber = (settings: Record<string, string>, key: string, fallback: number): number => {
     const raw = settings[key];
@@ -110,6 +117,7 @@ export default function ClientDetail() {
       setProcesses(data.processes || []);
       setChecks(data.checks || []);
       setAlertMutes(data.alert_mutes || []);
+      setMaintenance(data.maintenance_windows || []);
       setNameInput(data.client.custom_name || '');
 
       const defaults: Thresholds = {
@@ -154,12 +162,15 @@ export default function ClientDetail() {
 
       const rangeHours = range === '1h' ? 1 : range === '6h' ? 6 : range === '7d' ? 168 : range === '14d' ? 336 : 24;
       const from = new Date(Date.now() - rangeHours * 3600000).toISOString();
-      const [historyData, alertsData] = await Promise.all([
-        fetchMetrics(id, from),
-        fetchAlerts(id, undefined, 20),
+      const bucket = range === '7d' ? '30m' : range === '14d' ? '1h' : undefined;
+      const [historyData, alertsData, effectiveData] = await Promise.all([
+        fetchMetrics(id, from, undefined, bucket),
+        fetchAlerts(id, undefined, 20, 0, undefined, alertsThisBoot ? 'session' : undefined),
+        fetchEffectiveThresholds(id),
       ]);
       setHistory(historyData);
       setAlerts(alertsData.alerts);
+      setEffective(effectiveData);
     } catch (err) {
       if (err instanceof Error) {
         setError(err.message);
@@ -171,7 +182,7 @@ export default function ClientDetail() {
     }
   };
 
-  useEffect(() => { loadData(); }, [id, range]);
+  useEffect(() => { loadData(); }, [id, range, alertsThisBoot]);
 
   const handleDelete = async () => {
     if (!id || !confirm('Delete this client? It will reappear if it checks in again.')) return;
@@ -179,12 +190,36 @@ export default function ClientDetail() {
     navigate('/');
   };
 
+  const handlePurge = async () => {
+    if (!id || !confirm('Permanently delete this client and all of its history? This cannot be undone.')) return;
+    await purgeClient(id);
+    navigate('/');
+  };
+
+  const handleClearMetrics = async () => {
+    if (!id || !confirm('Delete all metric, process, and check history for this client? The client and its settings are kept.')) return;
+    await clearClientMetrics(id);
+    loadData();
+  };
+
   const handleToggleMute = async () => {
     if (!id || !client) return;
     await setMute(id, !client.alerts_muted, 0, '');
     loadData();
   };
 
+  const handleToggleSuspended = async () => {
+    if (!id || !client) return;
+    if (client.suspended) {
+      await setSuspended(id, false);
+    } else {
+      const reason = prompt('Suspend all alerts for this client until resumed. Reason (optional):');
+      if (reason === null) return;
+      await setSuspended(id, true, reason);
+    }
+    loadData();
+  };
+
   const checkMuteTarget = (friendlyName: string, checkType: string): string => `${friendlyName.trim()}::${checkType.trim()}`;
 
   const isScopedMuted = (scope: ClientAlertMute['scope'], target = ''): boolean =>
@@ -201,6 +236,42 @@ export default function ClientDetail() {
     }
   };
 
+  const handleEndMaintenance = async (windowID: number) => {
+    if (!id) return;
+    try {
+      await deleteMaintenanceWindow(id, windowID);
+      await loadData();
+    } catch (err: any) {
+      setStatus(`Error: ${err.message}`);
+    }
+  };
+
+  const handleSaveTags = async () => {
+    if (!id) return;
+    const tags = tagsInput.split(/[\s,]+/).map(t => t.trim()).filter(Boolean);
+    try {
+      await setClientTags(id, tags);
+      setStatus('Client tags updated');
+      setTagsOpen(false);
+      loadData();
+    } catch (err: any) {
+      setStatus(`Error: ${err.message}`);
+    }
+  };
+
+  const handleSaveInterval = async () => {
+    if (!id) return;
+    const trimmed = intervalInput.trim();
+    try {
+      await setCheckInInterval(id, trimmed === '' ? null : Number(trimmed));
+      setStatus(trimmed === '' ? 'Check-in interval reset to client config' : 'Check-in interval updated');
+      setIntervalOpen(false);
+      loadData();
+    } catch (err: any) {
+      setStatus(`Error: ${err.message}`);
+    }
+  };
+
   const handleRename = async () => {
     if (!id) return;
     try {
@@ -236,6 +307,29 @@ export default function ClientDetail() {
     }
   };
 
+  const handleEditProcessNote = async (p: ProcessSnapshot) => {
+    if (!id) return;
+    const note = prompt(`Note for ${p.friendly_name}`, p.note || '');
+    if (note === null) return;
+    try {
+      await updateWatchedProcess(id, p.friendly_name, note, p.expected_state || 'running');
+      await loadData();
+    } catch (err: any) {
+      setStatus(`Error: ${err.message}`);
+    }
+  };
+
+  const handleToggleExpectedState = async (p: ProcessSnapshot) => {
+    if (!id) return;
+    const next = p.expected_state === 'stopped' ? 'running' : 'stopped';
+    try {
+      await updateWatchedProcess(id, p.friendly_name, p.note || '', next);
+      await loadData();
+    } catch (err: any) {
+      setStatus(`Error: ${err.message}`);
+    }
+  };
+
   const handleDeleteProcess = (friendlyName: string) => {
     setDeleteTarget({ kind: 'process', friendlyName });
   };
@@ -295,15 +389,42 @@ export default function ClientDetail() {
             </button>
           </div>
           <div className="mt-2 flex flex-wrap gap-2">
+            {client.suspended && (
+              <span className="text-xs text-amber-700 bg-amber-50 px-2 py-1 rounded" title={client.suspend_reason || undefined}>
+                suspended{client.suspend_reason ? `: ${client.suspend_reason}` : ''}
+              </span>
+            )}
             <span className="text-xs text-gray-400 bg-gray-100 px-2 py-1 rounded">
               {client.os}/{client.arch} • {clientVersionLabel(client.client_version)}
             </span>
             <span className="text-xs text-gray-500 bg-gray-100 px-2 py-1 rounded" title={isoTooltip(client.session_started_at)}>
               uptime {formatFriendlyDuration(client.session_started_at)}
             </span>
+            <button
+              onClick={() => { setIntervalInput(client.check_in_interval_seconds ? String(client.check_in_interval_seconds) : ''); setIntervalOpen(true); }}
+              className={`text-xs px-2 py-1 rounded ${client.check_in_interval_seconds ? 'text-blue-700 bg-blue-50 hover:bg-blue-100' : 'text-gray-500 bg-gray-100 hover:bg-gray-200'}`}
+              title="Set check-in interval"
+            >
+              every {client.check_in_interval_seconds ?? client.reported_interval_seconds ?? 120}s
+            </button>
+            {client.checkin_latency_ms != null && (
+              <span className="text-xs text-gray-500 bg-gray-100 px-2 py-1 rounded" title="Round-trip time of the client's last check-in">
+                latency {client.checkin_latency_ms}ms
+              </span>
+            )}
             {client.public_ip && (
               <span className="text-xs text-gray-500 bg-gray-100 px-2 py-1 rounded font-mono">public {client.public_ip}</span>
             )}
+            {(client.tags || []).map(tag => (
+              <span key={tag} className="text-xs text-blue-700 bg-blue-50 px-2 py-1 rounded">#{tag}</span>
+            ))}
+            <button
+              onClick={() => { setTagsInput((client.tags || []).join(', ')); setTagsOpen(true); }}
+              className="text-xs text-gray-400 hover:text-gray-600 px-2 py-1 rounded hover:bg-gray-100"
+              title="Edit tags"
+            >
+              {client.tags && client.tags.length > 0 ? 'edit tags' : '+ tags'}
+            </button>
           </div>
           {client.interface_ips && client.interface_ips.length > 0 && (
             <div className="mt-2">
@@ -329,9 +450,22 @@ export default function ClientDetail() {
           >
             {client.alerts_muted ? <Volume2 size={18} /> : <VolumeX size={18} />}
           </button>
+          <button
+            onClick={handleToggleSuspended}
+            className={`p-2 rounded-md ${client.suspended ? 'text-amber-600 hover:text-amber-700 bg-amber-50 hover:bg-amber-100' : 'text-gray-400 hover:text-gray-600 hover:bg-gray-100'}`}
+            title={client.suspended ? 'Resume alerts' : 'Suspend'}
+          >
+            {client.suspended ? <PlayCircle size={18} /> : <PauseCircle size={18} />}
+          </button>
+          <button onClick={handleClearMetrics} className="p-2 text-gray-400 hover:text-gray-600 rounded-md hover:bg-gray-100" title="Clear history">
+            <Eraser size={18} />
+          </button>
           <button onClick={handleDelete} className="p-2 text-red-400 hover:text-red-600 rounded-md hover:bg-red-50" title="Delete">
             <Trash2 size={18} />
           </button>
+          <button onClick={handlePurge} className="p-2 text-red-400 hover:text-red-600 rounded-md hover:bg-red-50" title="Purge permanently">
+            <Flame size={18} />
+          </button>
         </div>
       </div>
 
@@ -342,6 +476,31 @@ export default function ClientDetail() {
         </div>
       )}
 
+      {maintenance.map(m => {
+        const active = new Date(m.starts_at).getTime() <= Date.now();
+        return (
+          <div key={m.id} className={`mb-4 px-4 py-2 rounded text-sm flex items-center justify-between ${active ? 'bg-amber-50 text-amber-800' : 'bg-gray-50 text-gray-700'}`}>
+            <span>
+              {active ? 'In maintenance' : `Maintenance scheduled from ${new Date(m.starts_at).toLocaleString()}`} until {new Date(m.ends_at).toLocaleString()}
+              {m.reason ? ` — ${m.reason}` : ''}
+            </span>
+            <button onClick={() => handleEndMaintenance(m.id)} className="ml-2 text-xs underline">
+              {active ? 'End now' : 'Cancel'}
+            </button>
+          </div>
+        );
+      })}
+
+      {client.last_collection_error && (
+        <div className="mb-4 px-4 py-2 bg-red-50 text-red-700 rounded text-sm">
+          Last collection error
+          {client.last_collection_error_at && (
+            <span title={isoTooltip(client.last_collection_error_at)}> ({formatFriendlyDuration(client.last_collection_error_at)} ago)</span>
+          )}
+          : <span className="font-mono">{client.last_collection_error}</span>
+        </div>
+      )}
+
       {/* Metrics (top section) */}
       {metrics && (
         <div className="grid grid-cols-1 sm:grid-cols-3 gap-4 mb-6">
@@ -349,12 +508,30 @@ export default function ClientDetail() {
             {
               scope: 'cpu' as const,
               label: 'CPU',
-              gauge: <MetricGauge label="CPU" value={metrics.cpu_pct} size="lg" />,
+              gauge: (
+                <div>
+                  <MetricGauge label="CPU" value={metrics.cpu_pct} size="lg" />
+                  {metrics.cpu_temp_c != null && (
+                    <div className="mt-1 text-xs text-gray-500">
+                      Temperature {metrics.cpu_temp_c.toFixed(1)}°C
+                    </div>
+                  )}
+                </div>
+              ),
             },
             {
               scope: 'memory' as const,
               label: 'Memory',
-              gauge: <MetricGauge label="Memory" value={metrics.mem_pct} size="lg" unit={`% (${formatBytes(metrics.mem_used_bytes)}/${formatBytes(metrics.mem_total_bytes)})`} />,
+              gauge: (
+                <div>
+                  <MetricGauge label="Memory" value={metrics.mem_pct} size="lg" unit={`% (${formatBytes(metrics.mem_used_bytes)}/${formatBytes(metrics.mem_total_bytes)})`} />
+                  {!!metrics.swap_total_bytes && (
+                    <div className="mt-1 text-xs text-gray-500">
+                      Swap {(metrics.swap_pct || 0).toFixed(1)}% ({formatBytes(metrics.swap_used_bytes || 0)}/{formatBytes(metrics.swap_total_bytes)})
+                    </div>
+                  )}
+                </div>
+              ),
             },
             {
               scope: 'disk' as const,
@@ -404,18 +581,37 @@ export default function ClientDetail() {
               <tbody>
                 {processes.map(p => (
                   <tr key={`proc:${p.friendly_name}`} className="border-b">
-                    <td className="py-2 font-medium">{p.friendly_name}</td>
+                    <td className="py-2 font-medium">
+                      {p.friendly_name}
+                      {p.note && <div className="text-xs font-normal text-gray-500">{p.note}</div>}
+                    </td>
                     <td className="py-2 text-gray-500">process</td>
                     <td className="py-2">
-                      <span className={`inline-flex items-center gap-1 px-2 py-0.5 rounded text-xs ${p.is_running ? 'bg-green-100 text-green-700' : 'bg-red-100 text-red-700'}`}>
-                        {p.is_running ? 'Running' : 'Stopped'}
+                      <span className={`inline-flex items-center gap-1 px-2 py-0.5 rounded text-xs ${
+                        p.is_running ? 'bg-green-100 text-green-700'
+                          : p.expected_state === 'stopped' ? 'bg-gray-100 text-gray-600'
+                          : 'bg-red-100 text-red-700'
+                      }`}>
+                        {p.is_running ? 'Running' : p.expected_state === 'stopped' ? 'Stopped (expected)' : 'Stopped'}
                       </span>
                     </td>
-                    <td className="py-2 text-gray-500 font-mono">{p.pid || '-'}</td>
+                    <td className="py-2 text-gray-500 font-mono">
+                      {p.pid || '-'}
+                      {(p.instance_count ?? 0) > 1 && (
+                        <span className="ml-1 text-xs font-sans" title="Matching processes; usage is the total">
+                          (×{p.instance_count})
+                        </span>
+                      )}
+                    </td>
                     <td className="py-2 text-gray-500">{p.cpu_pct?.toFixed(1)}%</td>
                     <td className="py-2 text-gray-500">{p.mem_pct?.toFixed(1)}%</td>
                     <td className="py-2 text-gray-500" title={isoTooltip(p.uptime_since_at)}>
                       {formatFriendlyDuration(p.uptime_since_at)}
+                      {!!p.restarts_24h && (
+                        <span className="ml-1 text-xs text-amber-600" title="Restarts in the last 24 hours">
+                          ({p.restarts_24h} restart{p.restarts_24h === 1 ? '' : 's'} / 24h)
+                        </span>
+                      )}
                     </td>
                     <td className="py-2 text-right">
                       <button
@@ -429,6 +625,20 @@ export default function ClientDetail() {
                       >
                         {isScopedMuted('process', p.friendly_name) ? <Volume2 size={12} /> : <VolumeX size={12} />} {isScopedMuted('process', p.friendly_name) ? 'Unmute' : 'Mute'}
                       </button>
+                      <button
+                        onClick={() => handleEditProcessNote(p)}
+                        className="inline-flex items-center gap-1 px-2 py-1 text-xs text-gray-600 hover:bg-gray-50 rounded mr-1"
+                        title="Add a note shown to everyone using the dashboard"
+                      >
+                        <Pencil size={12} /> Note
+                      </button>
+                      <button
+                        onClick={() => handleToggleExpectedState(p)}
+                        className="inline-flex items-center gap-1 px-2 py-1 text-xs text-gray-600 hover:bg-gray-50 rounded mr-1"
+                        title={p.expected_state === 'stopped' ? 'Alert again when this process stops' : "Don't alert when this process stops"}
+                      >
+                        {p.expected_state === 'stopped' ? 'Expect running' : 'Expect stopped'}
+                      </button>
                       <button
                         onClick={() => handleDeleteProcess(p.friendly_name)}
                         className="inline-flex items-center gap-1 px-2 py-1 text-xs text-red-600 hover:bg-red-50 rounded"
@@ -653,6 +863,44 @@ export default function ClientDetail() {
                 Global default: {thresholdDefaults.metric_consecutive_checkins} check-in(s)
               </p>
             </div>
+            {effective && (
+              <div className="mt-3 border rounded-lg p-3 bg-gray-50">
+                <h3 className="text-sm font-semibold text-gray-700 mb-2">Effective Values</h3>
+                <p className="text-xs text-gray-500 mb-2">
+                  What the alert engine currently applies, and where each value comes from.
+                </p>
+                <table className="w-full text-sm">
+                  <tbody>
+                    {([
+                      ['CPU warning / critical', effective.cpu_warn_pct, effective.cpu_crit_pct, '%'],
+                      ['Memory warning / critical', effective.mem_warn_pct, effective.mem_crit_pct, '%'],
+                      ['Disk warning / critical', effective.disk_warn_pct, effective.disk_crit_pct, '%'],
+                    ] as const).map(([label, warn, crit, unit]) => (
+                      <tr key={label} className="border-b last:border-0">
+                        <td className="py-1 text-gray-600">{label}</td>
+                        <td className="py-1">
+                          {warn.value}{unit} <span className="text-xs text-gray-400">({warn.source})</span>
+                          {' / '}
+                          {crit.value}{unit} <span className="text-xs text-gray-400">({crit.source})</span>
+                        </td>
+                      </tr>
+                    ))}
+                    {([
+                      ['Offline alert delay', effective.offline_threshold_seconds, 's'],
+                      ['Consecutive check-ins', effective.metric_consecutive_checkins, ''],
+                      ['Alert cooldown', effective.alert_cooldown_seconds, 's'],
+                    ] as const).map(([label, v, unit]) => (
+                      <tr key={label} className="border-b last:border-0">
+                        <td className="py-1 text-gray-600">{label}</td>
+                        <td className="py-1">
+                          {v.value}{unit} <span className="text-xs text-gray-400">({v.source})</span>
+                        </td>
+                      </tr>
+                    ))}
+                  </tbody>
+                </table>
+              </div>
+            )}
             <div className="flex flex-col sm:flex-row gap-2 mt-4">
               <button onClick={handleSaveThresholds} className="px-4 py-2 bg-blue-600 text-white rounded text-sm hover:bg-blue-700">
                 Save Thresholds
@@ -673,7 +921,11 @@ export default function ClientDetail() {
         </button>
         {showAlerts && (
           <div className="mt-4">
-            {alerts.length === 0 && <p className="text-sm text-gray-400">No recent alerts.</p>}
+            <label className="flex items-center gap-2 text-sm text-gray-600 mb-3">
+              <input type="checkbox" checked={alertsThisBoot} onChange={e => setAlertsThisBoot(e.target.checked)} />
+              Only this boot
+            </label>
+            {alerts.length === 0 && <p className="text-sm text-gray-400">{alertsThisBoot ? 'No alerts since this boot.' : 'No recent alerts.'}</p>}
             {alerts.length > 0 && (
               <div className="space-y-2">
                 {alerts.map(a => (
@@ -717,6 +969,54 @@ export default function ClientDetail() {
         </div>
       )}
 
+      {tagsOpen && (
+        <div className="fixed inset-0 z-50 bg-black/40 flex items-center justify-center px-4">
+          <div className="w-full max-w-md bg-white rounded-lg border shadow-lg p-4">
+            <h2 className="font-semibold text-gray-800 mb-2">Edit Tags</h2>
+            <p className="text-sm text-gray-500 mb-3">Comma-separated, e.g. web, prod</p>
+            <input
+              value={tagsInput}
+              onChange={e => setTagsInput(e.target.value)}
+              className="w-full px-3 py-2 border rounded text-sm"
+              placeholder="No tags"
+            />
+            <div className="flex justify-end gap-2 mt-4">
+              <button onClick={() => setTagsOpen(false)} className="px-4 py-2 border rounded text-sm hover:bg-gray-50">
+                Cancel
+              </button>
+              <button onClick={handleSaveTags} className="px-4 py-2 bg-blue-600 text-white rounded text-sm hover:bg-blue-700">
+                Save
+              </button>
+            </div>
+          </div>
+        </div>
+      )}
+
+      {intervalOpen && (
+        <div className="fixed inset-0 z-50 bg-black/40 flex items-center justify-center px-4">
+          <div className="w-full max-w-md bg-white rounded-lg border shadow-lg p-4">
+            <h2 className="font-semibold text-gray-800 mb-2">Check-in Interval</h2>
+            <p className="text-sm text-gray-500 mb-3">Seconds between check-ins (10-86400). Leave empty to use the client's own config. Applies after its next check-in.</p>
+            <input
+              type="number"
+              min={10}
+              value={intervalInput}
+              onChange={e => setIntervalInput(e.target.value)}
+              className="w-full px-3 py-2 border rounded text-sm"
+              placeholder="Client config"
+            />
+            <div className="flex justify-end gap-2 mt-4">
+              <button onClick={() => setIntervalOpen(false)} className="px-4 py-2 border rounded text-sm hover:bg-gray-50">
+                Cancel
+              </button>
+              <button onClick={handleSaveInterval} className="px-4 py-2 bg-blue-600 text-white rounded text-sm hover:bg-blue-700">
+                Save
+              </button>
+            </div>
+          </div>
+        </div>
+      )}
+
       {/* Delete Process/Check Modal */}
       {deleteTarget && (
         <div className="fixed inset-0 z-50 bg-black/40 flex items-center justify-center px-4">
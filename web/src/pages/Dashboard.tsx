@@ -1,7 +1,7 @@
 import { useState, useEffect } from 'react';
 import { Link } from 'react-router-dom';
-import { fetchClients, AuthError } from '../api/client';
-import type { ClientWithMetrics } from '../types';
+import { fetchClients, fetchServerStats, subscribeEvents, AuthError } from '../api/client';
+import type { ClientWithMetrics, ServerStats } from '../types';
 import StatusDot from '../components/StatusDot';
 import MetricGauge from '../components/MetricGauge';
 import { Server, Clock } from 'lucide-react';
@@ -90,12 +90,15 @@ export default function Dashboard() {
   const [clients, setClients] = useState<ClientWithMetrics[]>([]);
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState('');
+  const [stats, setStats] = useState<ServerStats | null>(null);
 
   const loadClients = async () => {
     try {
       const data = await fetchClients();
       setClients(data);
       setError('');
+      // The overview is a nice-to-have; keep the client list if it fails.
+      fetchServerStats().then(setStats).catch(() => {});
     } catch (err) {
       if (err instanceof AuthError) return;
       setError('Failed to load clients');
@@ -107,7 +110,20 @@ export default function Dashboard() {
   useEffect(() => {
     loadClients();
     const interval = setInterval(loadClients, 30000);
-    return () => clearInterval(interval);
+    // Refresh on live events, coalescing bursts of check-ins into one reload.
+    let pending: ReturnType<typeof setTimeout> | null = null;
+    const unsubscribe = subscribeEvents(() => {
+      if (pending) return;
+      pending = setTimeout(() => {
+        pending = null;
+        loadClients();
+      }, 1000);
+    });
+    return () => {
+      clearInterval(interval);
+      unsubscribe();
+      if (pending) clearTimeout(pending);
+    };
   }, []);
 
   if (loading) {
@@ -160,8 +176,16 @@ export default function Dashboard() {
     <div>
       <div className="flex items-center justify-between mb-6">
         <h1 className="text-2xl font-bold text-gray-900">Dashboard</h1>
-        <div className="text-sm text-gray-500">
-          {onlineCount}/{clients.length} online
+        <div className="text-sm text-gray-500 text-right">
+          <div>{onlineCount}/{clients.length} online</div>
+          {stats?.alert_dry_run && (
+            <div className="text-xs font-medium text-amber-600">Dry run: notifications are off</div>
+          )}
+          {stats && (
+            <div className="text-xs" title={`Server up since ${localTooltip(stats.started_at)}`}>
+              {stats.alerts_24h.critical || 0} critical, {stats.alerts_24h.warning || 0} warning alerts in 24h
+            </div>
+          )}
         </div>
       </div>
       <div className="space-y-4">
@@ -19,6 +19,12 @@ export default function Login({ onLogin }: { onLogin: () => void }) {
         setLoading(false);
         return;
       }
+      if (authResult === 'locked') {
+        clearAuth();
+        setError('Too many failed attempts — try again in a minute');
+        setLoading(false);
+        return;
+      }
       setAuth('admin', password);
       onLogin();
     } catch {
@@ -1,22 +1,27 @@
 import { useState, useEffect } from 'react';
-import { fetchProviders, createProvider, updateProvider, deleteProvider, testProvider, changePassword, fetchSettings, updateSettings } from '../api/client';
-import type { AlertProvider } from '../types';
+import { fetchProviders, createProvider, updateProvider, deleteProvider, testProvider, fetchAlertRoutes, updateAlertRoutes, changePassword, fetchSettings, updateSettings, fetchDatabaseStats, vacuumDatabase } from '../api/client';
+import type { AlertProvider, DatabaseStats } from '../types';
 import { Trash2, Send, Plus, Pencil } from 'lucide-react';
 
 export default function Settings() {
   const [providers, setProviders] = useState<AlertProvider[]>([]);
   const [showAddProvider, setShowAddProvider] = useState(false);
-  const [providerType, setProviderType] = useState<'twilio' | 'pushover' | 'smtp'>('pushover');
+  const [providerType, setProviderType] = useState<'twilio' | 'pushover' | 'smtp' | 'pagerduty' | 'gotify'>('pushover');
   const [providerName, setProviderName] = useState('');
   const [providerConfig, setProviderConfig] = useState('{}');
+  const [providerTemplate, setProviderTemplate] = useState('');
   const [editingProviderId, setEditingProviderId] = useState<number | null>(null);
   const [editProviderName, setEditProviderName] = useState('');
   const [editProviderConfig, setEditProviderConfig] = useState('{}');
+  const [editProviderTemplate, setEditProviderTemplate] = useState('');
   const [editProviderEnabled, setEditProviderEnabled] = useState(true);
   const [settings, setSettings] = useState<Record<string, string>>({});
   const [adminPw, setAdminPw] = useState('');
   const [clientPw, setClientPw] = useState('');
   const [message, setMessage] = useState('');
+  const [dbStats, setDbStats] = useState<DatabaseStats | null>(null);
+  const [vacuuming, setVacuuming] = useState(false);
+  const [routesJSON, setRoutesJSON] = useState('[]');
 
   const offlineMinutes = (() => {
     const secs = Number(settings['offline_threshold_seconds'] || '240');
@@ -34,6 +39,8 @@ export default function Settings() {
       const [p, s] = await Promise.all([fetchProviders(), fetchSettings()]);
       setProviders(p);
       setSettings(s);
+      setRoutesJSON(JSON.stringify(await fetchAlertRoutes(), null, 2));
+      setDbStats(await fetchDatabaseStats());
     } catch {
       // ignore
     }
@@ -44,10 +51,11 @@ export default function Settings() {
   const handleAddProvider = async (e: React.FormEvent) => {
     e.preventDefault();
     try {
-      await createProvider({ type: providerType, name: providerName, enabled: true, config: providerConfig });
+      await createProvider({ type: providerType, name: providerName, enabled: true, config: providerConfig, message_template: providerTemplate });
       setShowAddProvider(false);
       setProviderName('');
       setProviderConfig('{}');
+      setProviderTemplate('');
       loadData();
       setMessage('Provider added');
     } catch (err: any) {
@@ -69,6 +77,7 @@ export default function Settings() {
     setEditingProviderId(provider.id);
     setEditProviderName(provider.name);
     setEditProviderConfig(provider.config || '{}');
+    setEditProviderTemplate(provider.message_template || '');
     setEditProviderEnabled(provider.enabled);
   };
 
@@ -76,6 +85,7 @@ export default function Settings() {
     setEditingProviderId(null);
     setEditProviderName('');
     setEditProviderConfig('{}');
+    setEditProviderTemplate('');
     setEditProviderEnabled(true);
   };
 
@@ -86,6 +96,7 @@ export default function Settings() {
         name: editProviderName,
         enabled: editProviderEnabled,
         config: editProviderConfig,
+        message_template: editProviderTemplate,
       });
       setMessage('Provider updated');
       cancelEditProvider();
@@ -110,6 +121,15 @@ export default function Settings() {
     }
   };
 
+  const handleSaveRoutes = async () => {
+    try {
+      await updateAlertRoutes(JSON.parse(routesJSON || '[]'));
+      setMessage('Alert routing saved');
+    } catch (err: any) {
+      setMessage(`Error: ${err.message}`);
+    }
+  };
+
   const handleSaveThresholds = async () => {
     try {
       await updateSettings(settings);
@@ -132,10 +152,26 @@ export default function Settings() {
     }
   };
 
+  const handleVacuum = async () => {
+    setVacuuming(true);
+    try {
+      const before = dbStats?.size_bytes ?? 0;
+      const after = await vacuumDatabase();
+      setDbStats(after);
+      setMessage(`Database compacted: ${formatSize(before)} → ${formatSize(after.size_bytes)}`);
+    } catch (err: any) {
+      setMessage(`Error: ${err.message}`);
+    } finally {
+      setVacuuming(false);
+    }
+  };
+
   const configTemplates: Record<string, string> = {
     pushover: JSON.stringify({ app_token: '', user_key: '' }, null, 2),
     twilio: JSON.stringify({ account_sid: '', auth_token: '', from_number: '', to_number: '' }, null, 2),
-    smtp: JSON.stringify({ host: '', port: 587, username: '', password: '', from: '', to: '', use_tls: false }, null, 2),
+    smtp: JSON.stringify({ host: '', port: 587, username: '', password: '', from: '', to: '', use_tls: false, use_starttls: true, html: false }, null, 2),
+    pagerduty: JSON.stringify({ routing_key: '' }, null, 2),
+    gotify: JSON.stringify({ server_url: '', app_token: '' }, null, 2),
   };
 
   return (
@@ -221,6 +257,19 @@ export default function Settings() {
             CPU, memory, disk, process, check, and alert history older than this is automatically deleted daily.
           </p>
         </div>
+        <div className="mt-4">
+          <label className="text-sm text-gray-600 flex items-center gap-2">
+            <input
+              type="checkbox"
+              checked={settings['alert_dry_run'] === 'true'}
+              onChange={e => setSettings({ ...settings, alert_dry_run: String(e.target.checked) })}
+            />
+            Dry run
+          </label>
+          <p className="text-xs text-gray-500 mt-1">
+            Record alerts in the dashboard without sending any notifications, e.g. while tuning thresholds.
+          </p>
+        </div>
         <button onClick={handleSaveThresholds} className="mt-4 px-4 py-2 bg-blue-600 text-white rounded text-sm hover:bg-blue-700">
           Save Thresholds
         </button>
@@ -276,6 +325,15 @@ export default function Settings() {
                     rows={5}
                   />
                 </div>
+                <div className="mt-3">
+                  <label className="block text-sm text-gray-600 mb-1">Message template (optional)</label>
+                  <input
+                    value={editProviderTemplate}
+                    onChange={e => setEditProviderTemplate(e.target.value)}
+                    className="w-full px-3 py-1.5 border rounded text-sm font-mono"
+                    placeholder="{{.Name}} ({{.PublicIP}}): {{.Message}}"
+                  />
+                </div>
                 <div className="flex gap-2 mt-3">
                   <button type="button" onClick={() => handleSaveProviderEdit(p)} className="px-4 py-2 bg-blue-600 text-white rounded text-sm hover:bg-blue-700">
                     Save
@@ -331,6 +389,8 @@ export default function Settings() {
                   <option value="pushover">Pushover</option>
                   <option value="twilio">Twilio SMS</option>
                   <option value="smtp">SMTP Email</option>
+                  <option value="pagerduty">PagerDuty</option>
+                  <option value="gotify">Gotify</option>
                 </select>
               </div>
             </div>
@@ -343,6 +403,15 @@ export default function Settings() {
                 rows={5}
               />
             </div>
+            <div className="mt-3">
+              <label className="block text-sm text-gray-600 mb-1">Message template (optional)</label>
+              <input
+                value={providerTemplate}
+                onChange={e => setProviderTemplate(e.target.value)}
+                className="w-full px-3 py-1.5 border rounded text-sm font-mono"
+                placeholder="{{.Name}} ({{.PublicIP}}): {{.Message}}"
+              />
+            </div>
             <div className="flex gap-2 mt-3">
               <button type="submit" className="px-4 py-2 bg-blue-600 text-white rounded text-sm hover:bg-blue-700">Add</button>
               <button type="button" onClick={() => setShowAddProvider(false)} className="px-4 py-2 border rounded text-sm hover:bg-gray-50">Cancel</button>
@@ -351,6 +420,23 @@ export default function Settings() {
         )}
       </section>
 
+      {/* Alert routing */}
+      <section className="bg-white rounded-lg border p-4 mb-6">
+        <h2 className="font-semibold text-gray-700 mb-2">Alert Routing</h2>
+        <p className="text-xs text-gray-500 mb-3">
+          Leave empty (<code>[]</code>) to send every alert to every enabled provider. Otherwise each alert goes to the providers of every
+          matching route, e.g. <code>{'[{"min_severity":"critical","provider_ids":[1]},{"alert_type":"disk_*","provider_ids":[2]}]'}</code>.
+          Provider IDs: {providers.map(p => `${p.id} = ${p.name}`).join(', ') || 'none'}.
+        </p>
+        <textarea
+          value={routesJSON}
+          onChange={e => setRoutesJSON(e.target.value)}
+          className="w-full px-3 py-1.5 border rounded text-sm font-mono"
+          rows={5}
+        />
+        <button onClick={handleSaveRoutes} className="mt-3 px-4 py-2 bg-blue-600 text-white rounded text-sm hover:bg-blue-700">Save routing</button>
+      </section>
+
       {/* Passwords */}
       <section className="bg-white rounded-lg border p-4">
         <h2 className="font-semibold text-gray-700 mb-4">Passwords</h2>
@@ -387,6 +473,46 @@ export default function Settings() {
           </div>
         </div>
       </section>
+
+      {/* Database */}
+      {dbStats && (
+        <section className="bg-white rounded-lg border p-4 mt-6">
+          <div className="flex items-center justify-between mb-4">
+            <h2 className="font-semibold text-gray-700">Database</h2>
+            {dbStats.driver === 'sqlite' && (
+              <button onClick={handleVacuum} disabled={vacuuming} className="px-4 py-1.5 bg-gray-800 text-white rounded text-sm hover:bg-gray-900 disabled:opacity-50">
+                {vacuuming ? 'Compacting…' : 'Compact now'}
+              </button>
+            )}
+          </div>
+          <p className="text-sm text-gray-600 mb-3">
+            {formatSize(dbStats.size_bytes)} ({dbStats.driver})
+            {dbStats.driver === 'sqlite' && <> · {formatSize(dbStats.free_bytes)} reclaimable · WAL {formatSize(dbStats.wal_bytes)}</>}
+          </p>
+          <table className="w-full text-sm">
+            <tbody>
+              {dbStats.tables.map(t => (
+                <tr key={t.name} className="border-t">
+                  <td className="py-1 font-mono text-gray-700">{t.name}</td>
+                  <td className="py-1 text-right text-gray-600">{t.rows.toLocaleString()}</td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        </section>
+      )}
     </div>
   );
 }
+
+function formatSize(bytes: number): string {
+  if (bytes < 1024) return `${bytes} B`;
+  const units = ['KB', 'MB', 'GB', 'TB'];
+  let value = bytes / 1024;
+  let i = 0;
+  while (value >= 1024 && i < units.length - 1) {
+    value /= 1024;
+    i++;
+  }
+  return `${value.toFixed(1)} ${units[i]}`;
+}
@@ -4,6 +4,7 @@ export interface Client {
   custom_name?: string;
   public_ip?: string;
   interface_ips?: string[];
+  tags?: string[];
   os: string;
   arch: string;
   client_version: string;
@@ -13,6 +14,8 @@ export interface Client {
   is_online: boolean;
   alerts_muted: boolean;
   muted_until: string | null;
+  suspended: boolean;
+  suspend_reason?: string;
   mute_reason: string;
   cpu_warn_pct: number | null;
   cpu_crit_pct: number | null;
@@ -22,6 +25,19 @@ export interface Client {
   disk_crit_pct: number | null;
   offline_threshold_seconds?: number | null;
   metric_consecutive_checkins?: number | null;
+  business_hours?: BusinessHours | null;
+  reported_interval_seconds?: number | null;
+  check_in_interval_seconds?: number | null;
+  checkin_latency_ms?: number | null;
+  last_collection_error?: string;
+  last_collection_error_at?: string | null;
+}
+
+export interface BusinessHours {
+  start: string;
+  end: string;
+  timezone?: string;
+  days?: string[];
 }
 
 export interface ClientWithMetrics {
@@ -39,6 +55,7 @@ export interface ClientWithMetrics {
   is_online: boolean;
   alerts_muted: boolean;
   muted_until: string | null;
+  suspended: boolean;
   offline_threshold_seconds?: number | null;
   metric_consecutive_checkins?: number | null;
   latest_metrics: Metrics | null;
@@ -53,6 +70,21 @@ export interface Metrics {
   mem_used_bytes: number;
   disk_total_bytes: number;
   disk_used_bytes: number;
+  net_rx_bytes: number;
+  net_tx_bytes: number;
+  net_rx_bytes_per_sec: number;
+  net_tx_bytes_per_sec: number;
+  swap_pct?: number;
+  swap_total_bytes?: number;
+  swap_used_bytes?: number;
+  cpu_temp_c?: number;
+  open_fds?: number | null;
+  process_count?: number | null;
+  disk_inodes_pct?: number | null;
+  cpu_peak_pct?: number;
+  mem_peak_pct?: number;
+  disk_peak_pct?: number;
+  samples?: number;
   recorded_at: string;
 }
 
@@ -63,8 +95,14 @@ export interface ProcessSnapshot {
   cpu_pct: number;
   mem_pct: number;
   cmdline: string;
+  num_fds?: number | null;
+  num_threads?: number | null;
+  instance_count?: number;
   recorded_at: string;
   uptime_since_at: string;
+  restarts_24h?: number;
+  note?: string;
+  expected_state?: 'running' | 'stopped';
 }
 
 export interface CheckSnapshot {
@@ -82,15 +120,79 @@ export interface ClientAlertMute {
   target: string;
 }
 
+export interface MaintenanceWindow {
+  id: number;
+  client_id: string;
+  starts_at: string;
+  ends_at: string;
+  reason?: string;
+  created_at: string;
+}
+
+export interface ClientToken {
+  id: number;
+  label: string;
+  created_at: string;
+  revoked: boolean;
+  revoked_at?: string;
+}
+
+export interface ServerStats {
+  version: string;
+  started_at: string;
+  uptime_seconds: number;
+  clients: { total: number; online: number; offline: number; muted: number; suspended: number };
+  alerts_24h: Record<string, number>;
+  database_driver: string;
+  database_size_bytes: number;
+  alert_dry_run: boolean;
+}
+
+export interface DatabaseStats {
+  driver: string;
+  size_bytes: number;
+  free_bytes: number;
+  wal_bytes: number;
+  tables: { name: string; rows: number }[];
+}
+
+export interface APIToken {
+  id: number;
+  label: string;
+  created_at: string;
+  last_used_at?: string;
+  revoked: boolean;
+  revoked_at?: string;
+}
+
+export interface AuditEntry {
+  id: number;
+  created_at: string;
+  action: string;
+  target?: string;
+  detail?: string;
+  source_ip?: string;
+}
+
+export interface LiveEvent {
+  type: 'checkin' | 'alert';
+  client_id?: string;
+  alert?: Alert;
+  time: string;
+}
+
 export interface Alert {
   id: number;
   client_id: string;
   alert_type: string;
+  target?: string;
   severity: 'info' | 'warning' | 'critical';
   message: string;
   details: string;
   fired_at: string;
   notified: boolean;
+  acked: boolean;
+  acked_at?: string;
 }
 
 export interface Thresholds {
@@ -107,12 +209,36 @@ export interface Thresholds {
   metric_consecutive_enabled?: boolean;
 }
 
+export interface ResolvedThreshold {
+  value: number;
+  source: 'default' | 'global' | 'client';
+}
+
+export interface EffectiveThresholds {
+  cpu_warn_pct: ResolvedThreshold;
+  cpu_crit_pct: ResolvedThreshold;
+  mem_warn_pct: ResolvedThreshold;
+  mem_crit_pct: ResolvedThreshold;
+  disk_warn_pct: ResolvedThreshold;
+  disk_crit_pct: ResolvedThreshold;
+  offline_threshold_seconds: ResolvedThreshold;
+  metric_consecutive_checkins: ResolvedThreshold;
+  alert_cooldown_seconds: ResolvedThreshold;
+}
+
+export interface AlertRoute {
+  alert_type?: string;
+  min_severity?: 'info' | 'warning' | 'critical';
+  provider_ids: number[];
+}
+
 export interface AlertProvider {
   id: number;
-  type: 'twilio' | 'pushover' | 'smtp';
+  type: 'twilio' | 'pushover' | 'smtp' | 'pagerduty' | 'gotify';
   name: string;
   enabled: boolean;
   config: string;
+  message_template?: string;
   created_at: string;
 }
 
@@ -125,7 +251,9 @@ export interface TestAlertResult {
 
 export type AlertType =
   | 'offline' | 'online'
-  | 'pid_change' | 'process_died'
+  | 'client_outdated' | 'client_updated'
+  | 'pid_change' | 'process_died' | 'process_started'
+  | 'process_instances_low' | 'process_instances_recovered'
   | 'check_failed' | 'check_recovered'
   | 'cpu_warn' | 'cpu_crit' | 'cpu_recover'
   | 'mem_warn' | 'mem_crit' | 'mem_recover'